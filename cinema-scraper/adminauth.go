@@ -0,0 +1,46 @@
+package main
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ===========================
+// 模块：管理接口鉴权（ADMIN_TOKEN）
+// 职责：
+// - /api/admin 下的全部接口（软删除 / 恢复、状态覆盖、标签、单馆刷新、维护模式开关、as_of 时间旅行等）
+//   要求请求头 Authorization: Bearer <ADMIN_TOKEN>，缺少或不匹配时返回 401
+// - 未设置 ADMIN_TOKEN 时管理接口整体关闭（403），不会因为忘记配置而对能访问端口的任何人开放
+// 说明：令牌按常量时间比较；--print-config 中打码显示。命令行工具（purge-deleted、set-tmdb-id 等）直接读写数据库，不经过这里。
+// 环境变量：
+//   ADMIN_TOKEN   管理接口令牌（默认空 = 关闭管理接口）
+// ===========================
+
+// bearerToken 取 Authorization: Bearer 后的令牌（纯函数），格式不符时返回空串。
+func bearerToken(header string) string {
+	scheme, token, ok := strings.Cut(strings.TrimSpace(header), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
+
+// adminAuthMiddleware 管理接口鉴权：token 为空时拒绝全部请求，否则要求 Bearer 令牌一致。
+func adminAuthMiddleware(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if token == "" {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "admin api is disabled, set ADMIN_TOKEN to enable it"})
+			return
+		}
+		got := bearerToken(c.GetHeader("Authorization"))
+		if got == "" || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			c.Header("WWW-Authenticate", `Bearer realm="admin"`)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid or missing admin token"})
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestAdminAuth 管理接口鉴权：缺少或错误的令牌 401，未配置 ADMIN_TOKEN 时整体 403。
+func TestAdminAuth(t *testing.T) {
+	newTestRouter(t)
+	setTestClock(t, beforeMidnight)
+
+	send := func(router http.Handler, method, path, authorization string) testResponse {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, nil)
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		router.ServeHTTP(rec, req)
+		return testResponse{Status: rec.Code, Header: rec.Header(), Body: rec.Body.Bytes()}
+	}
+	router := setupRouter()
+	missing := send(router, http.MethodDelete, "/api/movies/1", "")
+	wrong := send(router, http.MethodPost, "/api/admin/maintenance", "Bearer nope")
+	ok := send(router, http.MethodGet, "/api/admin/slow-queries", "bearer "+testAdminToken)
+	public := send(router, http.MethodGet, "/api/health", "")
+
+	prev := appConfig.AdminToken
+	appConfig.AdminToken = ""
+	disabled := send(setupRouter(), http.MethodGet, "/api/admin/slow-queries", "Bearer ")
+	appConfig.AdminToken = prev
+
+	var movie Movie
+	if err := db.First(&movie, 1).Error; err != nil {
+		t.Fatalf("movie 1 should not be deleted: %v", err)
+	}
+	cfg, err := loadConfig(func(name string) (string, bool) {
+		if name == "ADMIN_TOKEN" {
+			return " s3cret-token ", true
+		}
+		return "", false
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := firstError(
+		expectStatus(missing, http.StatusUnauthorized),
+		expectEqual("challenge", missing.Header.Get("WWW-Authenticate"), `Bearer realm="admin"`),
+		expectStatus(wrong, http.StatusUnauthorized),
+		expectEqual("maintenance untouched", isReadOnly(), false),
+		expectStatus(ok, http.StatusOK),
+		expectStatus(public, http.StatusOK),
+		expectStatus(disabled, http.StatusForbidden),
+		expectEqual("bearer parse", [3]string{bearerToken("Bearer  abc "), bearerToken("Basic abc"), bearerToken("abc")}, [3]string{"abc", "", ""}),
+		expectEqual("env", [2]string{cfg.AdminToken, defaultConfig().AdminToken}, [2]string{"s3cret-token", ""})); err != nil {
+		t.Fatal(err)
+	}
+}
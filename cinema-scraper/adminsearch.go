@@ -0,0 +1,564 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/text/width"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ===========================
+// 模块：管理端统一搜索（策展 UI 的搜索框）
+// 职责：
+// - GET /api/admin/search?q= 一次搜索影片（任意片名片段：日 / 英 / 中文标题与别名）、
+//   影院（名称、读音或地址）与排片（“影院 + 日期”，如「新文芸坐 1/27」）
+// - 每条结果带类型、深链路径与编辑相关字段（状态、锁定截止日、TMDB / IMDb 待补全、复核原因等），
+//   按 完全一致 > 前缀 > 包含 排序后截取 limit 条
+// - 匹配键统一使用 NormalizeForSearch（全半角、大小写、片平假名、空白与标点差异都忽略）
+// 说明：要能逐键调用，不能对 movies / cinemas 做 LIKE '%q%' 全表扫描。
+//       另建 search_grams 倒排表（匹配键的二元组 + 末字单字，gram 上有索引）：
+//       查询先按 gram 索引取出包含全部二元组的候选，再在内存中核对是否真的连续包含。
+//       SQLite 驱动未启用 FTS5（trigram 分词也不支持两字查询），因此自建 n-gram 表。
+// - 影片 / 影院保存（Create / Save / 按列 Updates 文本字段）时由 AfterSave 钩子重建该行的 gram；
+//   UpdateColumn(s) 不触发钩子，这类路径改不到标题 / 名称 / 地址；
+//   表为空时（新增此表的旧库）启动自动全量重建，也可手动 go run . reindex-search
+// 调用方式：
+//   GET /api/admin/search?q=ケーン
+//   GET /api/admin/search?q=新文芸坐 2026-01-27&limit=10
+// ===========================
+
+const (
+	adminSearchDefaultLimit = 20
+	adminSearchMaxLimit     = 50
+	// adminSearchMaxCandidates 每类实体从 gram 索引取出的候选上限（单字查询可能命中很多行）
+	adminSearchMaxCandidates = 500
+	// adminSearchScheduleCinemas “影院 + 日期”查询最多展开的影院数
+	adminSearchScheduleCinemas = 5
+)
+
+// 搜索实体类型。
+const (
+	SearchEntityMovie    = "movie"
+	SearchEntityCinema   = "cinema"
+	SearchEntitySchedule = "schedule"
+)
+
+// 匹配程度，数值越小排序越靠前。
+const (
+	searchMatchExact = iota
+	searchMatchPrefix
+	searchMatchSubstring
+	searchMatchAddress // 只有地址包含查询词的影院
+)
+
+var searchMatchNames = []string{"exact", "prefix", "substring", "address"}
+
+// SearchGram 搜索倒排表的一行：实体的某个匹配键中出现过的二元组（或末字单字）。
+// 主键为 (entity, gram, entity_id)，查询直接走主键（覆盖索引）；按实体重建时走 idx_search_gram_entity。
+type SearchGram struct {
+	Entity   string `gorm:"primaryKey;index:idx_search_gram_entity,priority:1"` // movie / cinema
+	Gram     string `gorm:"primaryKey"`
+	EntityID uint   `gorm:"primaryKey;autoIncrement:false;index:idx_search_gram_entity,priority:2"`
+}
+
+// AdminSearchResult /api/admin/search 的一项。
+// schedule 类型的 id 为影院 ID，日期与场次数在 fields 中。
+type AdminSearchResult struct {
+	Type   string                 `json:"type"` // movie / cinema / schedule
+	ID     uint                   `json:"id"`
+	Label  string                 `json:"label"`
+	Path   string                 `json:"path"`  // 管理端深链
+	Match  string                 `json:"match"` // exact / prefix / substring / address
+	Fields map[string]interface{} `json:"fields"`
+
+	rank int
+}
+
+// movieSearchKeys 影片的匹配键：日 / 英 / 中文标题与日文别名。
+func movieSearchKeys(m Movie) []string {
+	return searchKeys(append([]string{m.TitleJP, m.TitleEN, m.TitleCN}, parseAltTitles(m.AltTitlesJP)...)...)
+}
+
+// cinemaNameKeys / cinemaSearchKeys 影院名称类匹配键（名称、读音）与全部匹配键（再加地址）。
+func cinemaNameKeys(cn Cinema) []string {
+	return searchKeys(cn.NameJP, cn.NameKana)
+}
+
+func cinemaSearchKeys(cn Cinema) []string {
+	return append(cinemaNameKeys(cn), searchKeys(cn.Address)...)
+}
+
+// searchKeys 规范化并去掉空键。
+func searchKeys(texts ...string) []string {
+	keys := make([]string, 0, len(texts))
+	for _, t := range texts {
+		if k := NormalizeForSearch(t); k != "" {
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
+
+// searchGrams 匹配键的 gram（纯函数）：每个相邻二元组，外加末字单字（单字查询按前缀命中任意位置的字）。
+func searchGrams(keys []string) []string {
+	seen := make(map[string]bool)
+	grams := make([]string, 0)
+	add := func(g string) {
+		if !seen[g] {
+			seen[g] = true
+			grams = append(grams, g)
+		}
+	}
+	for _, k := range keys {
+		r := []rune(k)
+		for i := 0; i+1 < len(r); i++ {
+			add(string(r[i : i+2]))
+		}
+		if len(r) > 0 {
+			add(string(r[len(r)-1]))
+		}
+	}
+	return grams
+}
+
+// searchMatchOf 查询键相对一组匹配键的最佳匹配程度；都不包含时返回 -1（纯函数）。
+func searchMatchOf(q string, keys []string) int {
+	best := -1
+	for _, k := range keys {
+		m := -1
+		switch {
+		case k == q:
+			m = searchMatchExact
+		case strings.HasPrefix(k, q):
+			m = searchMatchPrefix
+		case strings.Contains(k, q):
+			m = searchMatchSubstring
+		}
+		if m >= 0 && (best < 0 || m < best) {
+			best = m
+		}
+	}
+	return best
+}
+
+// writeSearchGrams 重建一个实体的 gram 行。
+func writeSearchGrams(tx *gorm.DB, entity string, id uint, keys []string) error {
+	if err := tx.Where("entity = ? AND entity_id = ?", entity, id).Delete(&SearchGram{}).Error; err != nil {
+		return err
+	}
+	grams := searchGrams(keys)
+	if len(grams) == 0 {
+		return nil
+	}
+	rows := make([]SearchGram, 0, len(grams))
+	for _, g := range grams {
+		rows = append(rows, SearchGram{Entity: entity, EntityID: id, Gram: g})
+	}
+	return tx.Clauses(clause.OnConflict{DoNothing: true}).CreateInBatches(rows, 200).Error
+}
+
+// searchHookMode 钩子该如何重建：reindex 是否需要重建，reload 是否要从库里重新读取该行。
+// - Create / Save 整个结构体：直接用钩子接收者上的值
+// - 按列 Updates(map)：只有改到 columns 时才重建，接收者上的值不完整，需要重读
+// - 其他更新（Updates(另一个结构体) 等）：接收者上的值不可靠，一律重读后重建
+func searchHookMode(tx *gorm.DB, columns ...string) (reindex bool, reload bool) {
+	switch dest := tx.Statement.Dest.(type) {
+	case map[string]interface{}:
+		for _, col := range columns {
+			if _, ok := dest[col]; ok {
+				return true, true
+			}
+		}
+		return false, true
+	case *Movie, []Movie, *[]Movie, []*Movie, *Cinema, []Cinema, *[]Cinema, []*Cinema:
+		return true, false
+	default:
+		return true, true
+	}
+}
+
+// AfterSave 保存影片后重建其 gram；按列更新只有改到标题类字段时才重建（从库里重新读取该行）。
+func (m *Movie) AfterSave(tx *gorm.DB) error {
+	if m.ID == 0 {
+		return nil
+	}
+	reindex, reload := searchHookMode(tx, "title_jp", "title_en", "title_cn", "alt_titles_jp")
+	if !reindex {
+		return nil
+	}
+	conn := tx.Session(&gorm.Session{NewDB: true})
+	movie := *m
+	if reload {
+		if err := conn.Unscoped().Select("id", "title_jp", "title_en", "title_cn", "alt_titles_jp").
+			First(&movie, m.ID).Error; err != nil {
+			return err
+		}
+	}
+	return writeSearchGrams(conn, SearchEntityMovie, movie.ID, movieSearchKeys(movie))
+}
+
+// AfterSave 保存影院后重建其 gram，规则同影片。
+func (cn *Cinema) AfterSave(tx *gorm.DB) error {
+	if cn.ID == 0 {
+		return nil
+	}
+	reindex, reload := searchHookMode(tx, "name_jp", "name_kana", "address")
+	if !reindex {
+		return nil
+	}
+	conn := tx.Session(&gorm.Session{NewDB: true})
+	cinema := *cn
+	if reload {
+		if err := conn.Select("id", "name_jp", "name_kana", "address").First(&cinema, cn.ID).Error; err != nil {
+			return err
+		}
+	}
+	return writeSearchGrams(conn, SearchEntityCinema, cinema.ID, cinemaSearchKeys(cinema))
+}
+
+// rebuildSearchIndex 全量重建 search_grams（含软删除的影片，恢复后无需重建），返回影片数与影院数。
+func rebuildSearchIndex(conn *gorm.DB) (int, int, error) {
+	var movies []Movie
+	var cinemas []Cinema
+	err := conn.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("1 = 1").Delete(&SearchGram{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Select("id", "title_jp", "title_en", "title_cn", "alt_titles_jp").Find(&movies).Error; err != nil {
+			return err
+		}
+		for _, m := range movies {
+			if err := writeSearchGrams(tx, SearchEntityMovie, m.ID, movieSearchKeys(m)); err != nil {
+				return err
+			}
+		}
+		if err := tx.Select("id", "name_jp", "name_kana", "address").Find(&cinemas).Error; err != nil {
+			return err
+		}
+		for _, cn := range cinemas {
+			if err := writeSearchGrams(tx, SearchEntityCinema, cn.ID, cinemaSearchKeys(cn)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return len(movies), len(cinemas), err
+}
+
+// migrateSearchIndex search_grams 为空而库里已有影片或影院时（刚加入此表的旧库）全量重建一次。
+func migrateSearchIndex(conn *gorm.DB) error {
+	var grams, movies, cinemas int64
+	if err := conn.Model(&SearchGram{}).Limit(1).Count(&grams).Error; err != nil {
+		return err
+	}
+	if grams > 0 {
+		return nil
+	}
+	conn.Unscoped().Model(&Movie{}).Count(&movies)
+	conn.Model(&Cinema{}).Count(&cinemas)
+	if movies == 0 && cinemas == 0 {
+		return nil
+	}
+	nm, nc, err := rebuildSearchIndex(conn)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("🔎 已建立管理端搜索索引：影片 %d 部、影院 %d 家\n", nm, nc)
+	return nil
+}
+
+// searchCandidateQuery 从 gram 索引取出包含查询键全部二元组的实体 ID 的查询（单字查询按 gram 前缀范围），
+// 可直接作为 id IN (?) 的子查询；候选还需按匹配键核对是否真的连续包含。
+// GROUP BY +entity_id：一元加号让 SQLite 不为分组去选 idx_search_gram_entity，仍按主键查 gram；
+// 主键保证同一实体的 gram 不重复，COUNT(*) 即命中的二元组数。
+func searchCandidateQuery(entity, q string) *gorm.DB {
+	tx := db.Model(&SearchGram{}).Where("entity = ?", entity)
+	if utf8.RuneCountInString(q) == 1 {
+		return tx.Where("gram >= ? AND gram < ?", q, q+string(utf8.MaxRune)).Distinct("entity_id")
+	}
+	grams := searchGrams([]string{q})
+	bigrams := grams[:len(grams)-1] // 去掉末字单字
+	return tx.Where("gram IN ?", bigrams).
+		Clauses(clause.GroupBy{Columns: []clause.Column{{Name: "+entity_id", Raw: true}}}).
+		Having("COUNT(*) = ?", len(bigrams)).Select("entity_id")
+}
+
+// searchCandidateIDs 取出最多 adminSearchMaxCandidates 个候选实体 ID。
+func searchCandidateIDs(entity, q string) ([]uint, error) {
+	ids := make([]uint, 0)
+	err := searchCandidateQuery(entity, q).Limit(adminSearchMaxCandidates).Pluck("entity_id", &ids).Error
+	return ids, err
+}
+
+// searchMovies 匹配影片（不含已删除的）。
+func searchMovies(q string) ([]AdminSearchResult, error) {
+	ids, err := searchCandidateIDs(SearchEntityMovie, q)
+	if err != nil || len(ids) == 0 {
+		return nil, err
+	}
+	var movies []Movie
+	if err := db.Where("id IN ?", ids).Find(&movies).Error; err != nil {
+		return nil, err
+	}
+	results := make([]AdminSearchResult, 0, len(movies))
+	for _, m := range movies {
+		match := searchMatchOf(q, movieSearchKeys(m))
+		if match < 0 {
+			continue
+		}
+		var pinned interface{}
+		if m.StatusPinnedUntil != nil {
+			pinned = m.StatusPinnedUntil.Format("2006-01-02")
+		}
+		results = append(results, AdminSearchResult{
+			Type:  SearchEntityMovie,
+			ID:    m.ID,
+			Label: movieDisplayTitle(m),
+			Path:  fmt.Sprintf("/api/admin/movies/%d", m.ID),
+			Match: searchMatchNames[match],
+			rank:  match,
+			Fields: map[string]interface{}{
+				"title_jp":            m.TitleJP,
+				"title_en":            m.TitleEN,
+				"title_cn":            m.TitleCN,
+				"kind":                movieKindOrDefault(m),
+				"status":              m.Status,
+				"status_pinned_until": pinned,
+				"tmdb_id":             m.TMDBID,
+				"imdb_id":             m.IMDBID,
+				"eiga_id":             m.EigaID,
+				"tmdb_pending":        m.TMDBPending,
+				"imdb_pending":        m.IMDBPending,
+				"review_reason":       m.ReviewReason,
+			},
+		})
+	}
+	return results, nil
+}
+
+// searchCinemas 匹配影院：名称 / 读音按匹配程度排序，只有地址命中的排在最后。
+func searchCinemas(q string) ([]AdminSearchResult, error) {
+	ids, err := searchCandidateIDs(SearchEntityCinema, q)
+	if err != nil || len(ids) == 0 {
+		return nil, err
+	}
+	var cinemas []Cinema
+	if err := db.Where("id IN ?", ids).Find(&cinemas).Error; err != nil {
+		return nil, err
+	}
+	results := make([]AdminSearchResult, 0, len(cinemas))
+	for _, cn := range cinemas {
+		match := searchMatchOf(q, cinemaNameKeys(cn))
+		if match < 0 {
+			if searchMatchOf(q, searchKeys(cn.Address)) < 0 {
+				continue
+			}
+			match = searchMatchAddress
+		}
+		results = append(results, AdminSearchResult{
+			Type:  SearchEntityCinema,
+			ID:    cn.ID,
+			Label: cn.NameJP,
+			Path:  fmt.Sprintf("/api/admin/cinemas/%d", cn.ID),
+			Match: searchMatchNames[match],
+			rank:  match,
+			Fields: map[string]interface{}{
+				"name_kana":      cn.NameKana,
+				"address":        cn.Address,
+				"district":       cn.District,
+				"source":         cn.Source,
+				"geo_status":     cn.GeoStatus,
+				"geocode_failed": cn.GeocodeFailed,
+			},
+		})
+	}
+	return results, nil
+}
+
+// searchDateRe 查询中的日期：2026-01-27 / 2026/1/27 / 1/27 / 1月27日。
+var searchDateRe = regexp.MustCompile(`(?:(\d{4})[-/])?(\d{1,2})[-/](\d{1,2})|(\d{1,2})月(\d{1,2})日`)
+
+// searchRelativeDays 相对日期关键字。
+var searchRelativeDays = map[string]int{
+	"今日": 0, "今天": 0, "today": 0,
+	"明日": 1, "明天": 1, "tomorrow": 1,
+}
+
+// splitSearchDate 从查询中拆出日期（纯函数）：返回去掉日期后的文本与 YYYY-MM-DD；没有日期时 date 为空串。
+// 不带年份的日期取今年，已过去一个月以上的视为明年（年末搜 1/5 指的是下个月）。
+func splitSearchDate(raw string, today time.Time) (string, string) {
+	s := width.Fold.String(raw)
+	for _, field := range strings.Fields(s) {
+		if days, ok := searchRelativeDays[strings.ToLower(field)]; ok {
+			rest := strings.Join(strings.Fields(strings.Replace(s, field, " ", 1)), " ")
+			return rest, today.AddDate(0, 0, days).Format("2006-01-02")
+		}
+	}
+	loc := searchDateRe.FindStringSubmatchIndex(s)
+	if loc == nil {
+		return raw, ""
+	}
+	m := searchDateRe.FindStringSubmatch(s)
+	year, month, day := 0, m[2], m[3]
+	if m[4] != "" {
+		month, day = m[4], m[5]
+	}
+	if m[1] != "" {
+		year, _ = strconv.Atoi(m[1])
+	}
+	mo, _ := strconv.Atoi(month)
+	d, _ := strconv.Atoi(day)
+	if mo < 1 || mo > 12 || d < 1 || d > 31 {
+		return raw, ""
+	}
+	explicitYear := year != 0
+	if !explicitYear {
+		year = today.Year()
+	}
+	date := time.Date(year, time.Month(mo), d, 0, 0, 0, 0, today.Location())
+	if date.Day() != d {
+		return raw, "" // 2/30 之类不存在的日期
+	}
+	if !explicitYear && date.Before(today.AddDate(0, -1, 0)) {
+		date = date.AddDate(1, 0, 0)
+	}
+	rest := strings.Join(strings.Fields(s[:loc[0]]+" "+s[loc[1]:]), " ")
+	return rest, date.Format("2006-01-02")
+}
+
+// searchSchedules “影院 + 日期”：对名称命中最好的几家影院，给出当天的场次数与影片数（当天没有排片的不返回）。
+func searchSchedules(q, date string) ([]AdminSearchResult, error) {
+	cinemas, err := searchCinemas(q)
+	if err != nil || len(cinemas) == 0 {
+		return nil, err
+	}
+	sortAdminSearchResults(cinemas)
+	if len(cinemas) > adminSearchScheduleCinemas {
+		cinemas = cinemas[:adminSearchScheduleCinemas]
+	}
+	ids := make([]uint, 0, len(cinemas))
+	for _, cn := range cinemas {
+		ids = append(ids, cn.ID)
+	}
+	var counts []struct {
+		CinemaID  uint
+		Showtimes int
+		Movies    int
+	}
+	if err := db.Model(&Schedule{}).
+		Select("cinema_id, COUNT(*) AS showtimes, COUNT(DISTINCT movie_id) AS movies").
+		Where("cinema_id IN ? AND date(play_date) = ?", ids, date).
+		Group("cinema_id").Scan(&counts).Error; err != nil {
+		return nil, err
+	}
+	byCinema := make(map[uint]int, len(counts))
+	for i, c := range counts {
+		byCinema[c.CinemaID] = i
+	}
+	results := make([]AdminSearchResult, 0, len(counts))
+	for _, cn := range cinemas {
+		i, ok := byCinema[cn.ID]
+		if !ok {
+			continue
+		}
+		results = append(results, AdminSearchResult{
+			Type:  SearchEntitySchedule,
+			ID:    cn.ID,
+			Label: cn.Label + " " + date,
+			Path:  fmt.Sprintf("/api/admin/cinemas/%d?date=%s", cn.ID, date),
+			Match: cn.Match,
+			rank:  cn.rank,
+			Fields: map[string]interface{}{
+				"cinema_id": cn.ID,
+				"date":      date,
+				"showtimes": counts[i].Showtimes,
+				"movies":    counts[i].Movies,
+			},
+		})
+	}
+	return results, nil
+}
+
+// sortAdminSearchResults 按匹配程度、标签长度（越短越接近查询词）、类型、ID 排序。
+func sortAdminSearchResults(results []AdminSearchResult) {
+	sort.SliceStable(results, func(i, j int) bool {
+		a, b := results[i], results[j]
+		if a.rank != b.rank {
+			return a.rank < b.rank
+		}
+		if la, lb := utf8.RuneCountInString(a.Label), utf8.RuneCountInString(b.Label); la != lb {
+			return la < lb
+		}
+		if a.Type != b.Type {
+			return a.Type < b.Type
+		}
+		return a.ID < b.ID
+	})
+}
+
+// adminSearch 执行一次搜索（测试直接调用）：带日期时排片结果排在最前，
+// 影片与影院按去掉日期后的文本匹配（只有日期时按原文），按 sortAdminSearchResults 排序。
+func adminSearch(raw string, today time.Time, limit int) ([]AdminSearchResult, error) {
+	results := make([]AdminSearchResult, 0)
+	q := NormalizeForSearch(raw)
+	rest, date := splitSearchDate(raw, today)
+	if restKey := NormalizeForSearch(rest); date != "" && restKey != "" {
+		schedules, err := searchSchedules(restKey, date)
+		if err != nil {
+			return nil, err
+		}
+		sortAdminSearchResults(schedules)
+		results = append(results, schedules...)
+		q = restKey
+	}
+	movies, err := searchMovies(q)
+	if err != nil {
+		return nil, err
+	}
+	cinemas, err := searchCinemas(q)
+	if err != nil {
+		return nil, err
+	}
+	others := append(movies, cinemas...)
+	sortAdminSearchResults(others)
+	results = append(results, others...)
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	return results, nil
+}
+
+// adminSearchHandler 管理端统一搜索：GET /api/admin/search?q=&limit=
+func adminSearchHandler(c *gin.Context) {
+	raw := strings.TrimSpace(c.Query("q"))
+	if NormalizeForSearch(raw) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "q is required"})
+		return
+	}
+	limit := adminSearchDefaultLimit
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit, expected a positive integer"})
+			return
+		}
+		limit = min(n, adminSearchMaxLimit)
+	}
+	ref := referenceTime(c)
+	today := time.Date(ref.Year(), ref.Month(), ref.Day(), 0, 0, 0, 0, ref.Location())
+	results, err := adminSearch(raw, today, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to search"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"q": raw, "items": results, "total": len(results)})
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// TestAdminSearch 管理端搜索：匹配方式与排序。
+func TestAdminSearch(t *testing.T) {
+	today := nowJST()
+	date := func(offset int) string { return today.AddDate(0, 0, offset).Format("2006-01-02") }
+	runHTTPCases(t, []httpCase{
+		{"管理端搜索：英文片名前缀（忽略大小写与空白）", "/api/admin/search?q=fixture%20MOVIE%200", func(r testResponse) error {
+			var body adminSearchBody
+			if err := expectJSON(r, &body); err != nil {
+				return err
+			}
+			return firstError(
+				expectEqual("results", body.summary(), "movie:1:prefix,movie:2:prefix,movie:3:prefix,movie:4:prefix,movie:5:prefix,movie:6:prefix,movie:7:prefix,movie:8:prefix,movie:9:prefix"),
+				expectEqual("path", body.Items[0].Path, "/api/admin/movies/1"),
+				expectEqual("tmdb_pending", fmt.Sprint(body.Items[0].Fields["tmdb_pending"]), "false"))
+		}},
+		{"管理端搜索：完全一致排在包含之前", "/api/admin/search?q=" + url.QueryEscape("ﾃｽﾄ映画01"), func(r testResponse) error {
+			var body adminSearchBody
+			if err := expectJSON(r, &body); err != nil {
+				return err
+			}
+			return expectEqual("results", body.summary(), "movie:1:exact")
+		}},
+		{"管理端搜索：平假名命中影院读音", "/api/admin/search?q=" + url.QueryEscape("しぶや"), func(r testResponse) error {
+			var body adminSearchBody
+			if err := expectJSON(r, &body); err != nil {
+				return err
+			}
+			return expectEqual("results", body.summary(), "cinema:2:prefix")
+		}},
+		{"管理端搜索：只有地址命中的影院排在名称命中之后", "/api/admin/search?q=" + url.QueryEscape("新宿"), func(r testResponse) error {
+			var body adminSearchBody
+			if err := expectJSON(r, &body); err != nil {
+				return err
+			}
+			return expectEqual("results", body.summary(), "cinema:1:prefix,cinema:4:address")
+		}},
+		{"管理端搜索：影院 + 日期给出当天排片", "/api/admin/search?q=" + url.QueryEscape(fmt.Sprintf("渋谷テスト座 %d/%d", today.Month(), today.Day())), func(r testResponse) error {
+			var body adminSearchBody
+			if err := expectJSON(r, &body); err != nil {
+				return err
+			}
+			if len(body.Items) == 0 {
+				return fmt.Errorf("no results")
+			}
+			first := body.Items[0]
+			return firstError(
+				expectEqual("results", body.summary(), "schedule:2:exact,cinema:2:exact"),
+				expectEqual("path", first.Path, "/api/admin/cinemas/2?date="+date(0)),
+				expectEqual("showtimes", fmt.Sprint(first.Fields["showtimes"]), fmt.Sprint(len(fixtureShowTimes))))
+		}},
+		{"管理端搜索：缺少 q", "/api/admin/search?q=%20", func(r testResponse) error {
+			return expectStatus(r, http.StatusBadRequest)
+		}},
+	})
+}
+
+// TestAdminSearchReindex 管理端搜索：改名后索引随保存更新，按列更新非标题字段不重建。
+func TestAdminSearchReindex(t *testing.T) {
+	newTestRouter(t)
+	now := testNoon
+	setTestClock(t, now)
+
+	movie := Movie{TitleJP: "検索テスト旧題", Status: "showing"}
+	if err := db.Create(&movie).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Model(&movie).Updates(map[string]interface{}{"title_jp": "検索テスト新題"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Model(&movie).Updates(map[string]interface{}{"status": "incoming"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	found := func(q string) string {
+		results, err := adminSearch(q, now, adminSearchDefaultLimit)
+		if err != nil {
+			return err.Error()
+		}
+		return adminSearchBody{Items: results}.summary()
+	}
+	want := fmt.Sprintf("movie:%d:exact", movie.ID)
+	if err := firstError(
+		expectEqual("old title", found("検索テスト旧題"), ""),
+		expectEqual("new title (kanji)", found("検索テスト新題"), want)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// adminSearchBody /api/admin/search 的响应体。
+type adminSearchBody struct {
+	Items []AdminSearchResult `json:"items"`
+}
+
+// summary 结果摘要：type:id:match，逗号分隔。
+func (b adminSearchBody) summary() string {
+	parts := make([]string, 0, len(b.Items))
+	for _, it := range b.Items {
+		parts = append(parts, fmt.Sprintf("%s:%d:%s", it.Type, it.ID, it.Match))
+	}
+	return strings.Join(parts, ",")
+}
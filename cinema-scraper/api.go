@@ -4,10 +4,13 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 // ===========================
@@ -17,17 +20,138 @@ import (
 
 // setupRouter 初始化 Gin 引擎与所有对外暴露的 API 路由。
 func setupRouter() *gin.Engine {
-	r := gin.Default()
+	r := gin.New()
+	r.Use(requestIDMiddleware(), gin.LoggerWithFormatter(requestLogFormatter), recoveryMiddleware(), corsMiddleware(appConfig.corsOrigins()), queryBudgetMiddleware(), maintenanceMiddleware(), languageMiddleware())
 
 	api := r.Group("/api")
 	{
+		// 健康检查：数据库连通性与维护模式状态
+		api.GET("/health", healthHandler)
+
 		// 影院相关接口：地图 / 影院详情
 		api.GET("/cinemas", listCinemasHandler)
+		api.GET("/cinemas/nearby", nearbyCinemasHandler)
 		api.GET("/cinemas/:id", getCinemaHandler)
+		api.GET("/cinemas/by-eiga/:eiga_id", getCinemaByEigaHandler)
+		api.GET("/cinemas/:id/recommended-movies", recommendedMoviesHandler)
+		api.GET("/cinemas/:id/photo", cinemaPhotoHandler)
 
 		// 影片相关接口：Now / Soon 列表与详情
 		api.GET("/movies", listMoviesHandler)
 		api.GET("/movies/:id", getMovieHandler)
+		api.GET("/movies/:id/share", shareMovieHandler)
+		api.GET("/movies/:id/poster", moviePosterHandler)
+		api.GET("/movies/by-tmdb/:tmdb_id", getMovieByTmdbHandler)
+		api.GET("/movies/by-imdb/:imdb_id", getMovieByImdbHandler)
+		// 软删除误抓 / 重复残留的影片：与公开接口同一路径，但需要管理令牌（见 moviedelete.go）
+		api.DELETE("/movies/:id", adminAuthMiddleware(appConfig.AdminToken), deleteMovieHandler)
+
+		// 排片列表：某天全东京的场次（可按特别场次筛选）
+		api.GET("/schedules", listSchedulesHandler)
+		api.GET("/schedules/:id", getScheduleHandler)
+		// 全东京当日时间表（所有影院 + daily_movies，带 ETag / gzip），见 citytimetable.go
+		api.GET("/timetable", cityTimetableHandler)
+
+		// 首页：Now / Soon 两个标签页一次返回
+		api.GET("/home", homeHandler)
+
+		// 策展标签：标签列表与在映影片数（影片按 /api/movies?tag= 过滤），见 movietags.go
+		api.GET("/tags", listTagsHandler)
+
+		// 今晚推荐：现在到午夜之间开场的最佳场次
+		api.GET("/tonight", tonightHandler)
+
+		// 此刻附近：T 时刻前后、指定坐标附近开场的场次
+		api.GET("/now-near", nowNearHandler)
+
+		// 数据导出：地图应用可导入的影院坐标（KML / GPX）
+		api.GET("/export/cinemas.kml", exportCinemasKMLHandler)
+		api.GET("/export/cinemas.gpx", exportCinemasGPXHandler)
+		// 开放数据集：抓取后生成的磁盘缓存（见 dataset.go）
+		api.GET("/export/dataset.json", datasetHandler)
+
+		// 数据概况：规模与外部数据源状态
+		api.GET("/stats", statsHandler)
+
+		// 变更报告：对比相邻两次抓取快照
+		api.GET("/changes", listChangesHandler)
+
+		// 影片事件流（新片发现 / 补全完成），见 movieevents.go
+		api.GET("/events/stream", eventStreamHandler)
+
+		// 收藏（按 X-Device-Token）与“最后机会”提醒，见 favorites.go
+		api.GET("/favorites", listFavoritesHandler)
+		api.PUT("/favorites/:movie_id", addFavoriteHandler)
+		api.DELETE("/favorites/:movie_id", removeFavoriteHandler)
+		api.GET("/favorites/ending", favoritesEndingHandler)
+		api.POST("/favorites/ending/ack", ackFavoritesEndingHandler)
+	}
+
+	// 服务端渲染页面：无需前端 SPA 即可浏览的上映时间表
+	r.GET("/timetable", timetablePageHandler)
+
+	// 站点地图：供搜索引擎收录前端公开页面
+	r.GET("/sitemap.xml", sitemapHandler)
+	r.GET("/sitemaps/:page", sitemapPageHandler)
+
+	// 按内容寻址的缓存图片（海报），可永久缓存（见 imagecache.go）
+	r.GET("/img/:hash", imageHandler)
+
+	// 管理接口：需要 Authorization: Bearer <ADMIN_TOKEN>，未配置令牌时整体关闭（见 adminauth.go）
+	admin := api.Group("/admin", adminAuthMiddleware(appConfig.AdminToken))
+	{
+		// 数据排查：字段来源追踪
+		admin.GET("/movies/:id/provenance", getMovieProvenanceHandler)
+
+		// 时间旅行：与公开接口相同，但支持 as_of 按指定日期重放“今天”相关的计算
+		admin.GET("/home", asOfMiddleware(), homeHandler)
+		admin.GET("/movies", asOfMiddleware(), listMoviesHandler)
+		admin.GET("/movies/:id", asOfMiddleware(), getMovieHandler)
+		admin.GET("/cinemas", asOfMiddleware(), listCinemasHandler)
+		admin.GET("/cinemas/:id", asOfMiddleware(), getCinemaHandler)
+		admin.GET("/cinemas/:id/recommended-movies", asOfMiddleware(), recommendedMoviesHandler)
+
+		// 状态人工覆盖：单部 / 批量设置并可锁定到指定日期
+		admin.PATCH("/movies/status", patchMoviesStatusBulkHandler)
+		admin.PATCH("/movies/:id/status", patchMovieStatusHandler)
+		admin.GET("/movies/:id/status-history", getMovieStatusHistoryHandler)
+
+		// 字幕 / 吹替默认版本：影院没有标注时 audio_hint 的推断依据
+		admin.PATCH("/movies/:id/audio-default", patchMovieAudioDefaultHandler)
+
+		// 预告片：手动指定后重新补全不再覆盖
+		admin.PATCH("/movies/:id/trailer", patchMovieTrailerHandler)
+
+		// 策展标签：单部影片增删、按标签批量增删影片、删除整个标签
+		admin.POST("/movies/:id/tags", addMovieTagsHandler)
+		admin.DELETE("/movies/:id/tags/:tag", removeMovieTagHandler)
+		admin.POST("/tags/:tag/movies", addTagMoviesHandler)
+		admin.DELETE("/tags/:tag/movies", removeTagMoviesHandler)
+		admin.DELETE("/tags/:tag", deleteTagHandler)
+
+		// 匹配质量：TMDB 匹配可疑、待人工复核的影片
+		admin.GET("/movies/review", listReviewMoviesHandler)
+
+		// 恢复软删除的影片（删除为 DELETE /api/movies/:id，同样需要管理令牌），保留期内可恢复
+		admin.POST("/movies/:id/restore", restoreMovieHandler)
+
+		// 单馆强制刷新：重抓 eiga.com 页面、重新定位、补全建筑照片，返回逐字段变更
+		admin.POST("/cinemas/:id/refresh", refreshCinemaHandler)
+
+		// 只读维护模式开关（维护模式下仍可调用）
+		admin.POST("/maintenance", setMaintenanceHandler)
+
+		// 慢查询：最近超过阈值的 SQL（参数已脱敏）
+		admin.GET("/slow-queries", listSlowQueriesHandler)
+
+		// 抓取中发现但不在范围内的影院链接（邻县 / 特殊会场），用于决定扩展哪些地区
+		admin.GET("/discovered-venues", listDiscoveredVenuesHandler)
+
+		// 抓取状态：定时抓取的最近一次运行与排片数据新鲜度，见 crawlscheduler.go
+		admin.GET("/crawl-status", crawlStatusHandler)
+
+		// 策展 UI 的统一搜索：影片 / 影院 / “影院 + 日期”排片（见 adminsearch.go）
+		admin.GET("/search", asOfMiddleware(), adminSearchHandler)
 	}
 
 	return r
@@ -43,73 +167,181 @@ type CinemaItem struct {
 	ID            uint     `json:"id"`
 	Name          string   `json:"name"`
 	NameEN        string   `json:"en"`
+	Kana          string   `json:"kana"`
 	District      string   `json:"district"`
 	Lat           float64  `json:"lat"`
 	Lng           float64  `json:"lng"`
+	Located       bool     `json:"located"` // false 时坐标不可信（定位失败，lat/lng 为 0），地图不应标出
 	Tags          []string `json:"tags"`
 	Website       string   `json:"website"`
 	Desc          string   `json:"desc"`
 	BuildingPhoto string   `json:"building_photo"`
+	PhotoURL      string   `json:"photo_url"`             // 建筑照片的同源地址（/img/:hash 或 /api/cinemas/:id/photo），没有照片时为空
+	ScreenCount   int      `json:"screen_count"`          // 0 表示未知
+	Intensity     float64  `json:"programming_intensity"` // 每块银幕日均场次（最近 7 天）
 }
 
 // DailyMovie 用于单个影院详情中的每日排片展示。
 type DailyMovie struct {
 	ID     uint     `json:"id"`
+	EigaID string   `json:"eiga_id"` // eiga.com 影片编号，未知时为空
 	Title  string   `json:"title"`
 	Times  []string `json:"times"`
 	Rating string   `json:"rating"`
+	// Showtimes 与 Times 一一对应，额外携带余票状态等场次级信息
+	Showtimes []Showtime `json:"showtimes"`
+}
+
+// Showtime 单个场次（开场时间 + 余票状态 + 特别场次类型）。
+type Showtime struct {
+	Time         string `json:"time"`
+	Availability string `json:"availability"` // unknown / available / few / soldout
+	EventType    string `json:"event_type"`   // 舞台挨拶 / 先行上映 等；普通场次为空
+	Format       string `json:"format"`       // subbed / dubbed；没有标注时为空
+	Note         string `json:"note"`         // 场次脚注说明（如 この回は英語字幕付き）；没有时为空
+	MembersOnly  bool   `json:"members_only"` // 会員限定场次；只有 include_members_only=true 时才会出现 true
+	// 字幕 / 吹替推断：subbed / dubbed（影院标注）/ likely_subbed / likely_dubbed / original / unknown，见 audiohint.go
+	AudioHint string `json:"audio_hint"`
+}
+
+// scheduleToShowtime 将 Schedule 转为 Showtime，旧数据的空状态按 unknown 输出。
+func scheduleToShowtime(s Schedule) Showtime {
+	availability := s.Availability
+	if availability == "" {
+		availability = AvailabilityUnknown
+	}
+	return Showtime{Time: s.StartTime, Availability: availability, EventType: s.EventType, Format: s.Format, Note: s.Note, MembersOnly: s.MembersOnly}
 }
 
 // CinemaDetail 用于 /api/cinemas/:id 详情视图（包含 daily_movies）。
+// ScheduleDays 为从 date 起连续 days 天的排片（含没有场次的日期），DailyMovies 等于其中第一天。
 type CinemaDetail struct {
 	CinemaItem
+	DailyMovies  []DailyMovie        `json:"daily_movies"`
+	ScheduleDays []CinemaScheduleDay `json:"schedule_days"`
+	ScheduleFreshness
+}
+
+// CinemaScheduleDay 影院某一天的排片。
+type CinemaScheduleDay struct {
+	Date        string       `json:"date"` // YYYY-MM-DD
 	DailyMovies []DailyMovie `json:"daily_movies"`
 }
 
+// 影院详情默认 / 最多返回的排片天数。
+const (
+	cinemaScheduleDefaultDays = 1
+	cinemaScheduleMaxDays     = 14
+)
+
 // MovieItem 用于 /api/movies 列表（Now/Soon）。
 type MovieItem struct {
-	ID           uint    `json:"id"`
-	TitleCN      string  `json:"title_cn"`
-	TitleEN      string  `json:"title_en"`
-	Director     string  `json:"director"`
-	Year         string  `json:"year"`
-	TMDBRating   float64 `json:"tmdb_rating"`
-	IMDBRating   float64 `json:"imdb_rating"`
-	DoubanRating float64 `json:"douban_rating"`
-	Status       string  `json:"status"`
-	ReleaseDate  string  `json:"release_date"` // YYYY-MM-DD（全球首映日期，来自TMDB）
-	EarliestScheduleDate string `json:"earliest_schedule_date"` // YYYY-MM-DD（最早排片日期，用于incoming状态显示）
-	CinemaCount  int     `json:"cinema_count"`           // 参与放映的影院数量
-	PrimaryCinemaName string `json:"primary_cinema_name"` // 当只有一个影院时，显示该影院名称
-	Genre        string  `json:"genre"`
-	Runtime      int     `json:"runtime"`      // 片长（分钟）
-	Poster       string  `json:"poster"`       // 海报 URL
-	CuratorNote  string  `json:"curator_note"`
+	ID       uint   `json:"id"`
+	Title    string `json:"title"` // 默认展示标题：按 lang / Accept-Language 选择，缺省为中文优先（见 lang.go）
+	TitleCN  string `json:"title_cn"`
+	TitleEN  string `json:"title_en"`
+	Director string `json:"director"`
+	Year     string `json:"year"`
+	// 扁平评分字段保留兼容，已废弃：请使用 ratings（带来源、投票数与抓取时间）
+	TMDBRating           float64       `json:"tmdb_rating"`
+	IMDBRating           float64       `json:"imdb_rating"`
+	DoubanRating         float64       `json:"douban_rating"`
+	IMDBVotes            int           `json:"imdb_votes"` // OMDb imdbVotes，0 表示未知
+	RTRating             int           `json:"rt_rating"`  // Rotten Tomatoes 新鲜度（百分比），0 表示未知
+	Metacritic           int           `json:"metacritic"` // Metacritic 分数（满分 100），0 表示未知
+	Ratings              []RatingEntry `json:"ratings"`
+	Status               string        `json:"status"`
+	ReleaseDate          string        `json:"release_date"`           // YYYY-MM-DD（全球首映日期，来自TMDB；incoming 影片有日本上映日期时为日本上映日期）
+	JPReleaseDate        string        `json:"jp_release_date"`        // YYYY-MM-DD（日本院线上映日期，来自TMDB release_dates）；未知时为空串
+	ReleaseDatePrecision string        `json:"release_date_precision"` // day / year（year 表示仅按年份兜底的近似日期）
+	EarliestScheduleDate string        `json:"earliest_schedule_date"` // YYYY-MM-DD（最早排片日期，用于incoming状态显示）
+	CinemaCount          int           `json:"cinema_count"`           // 参与放映的影院数量（历史累计，已废弃，请使用 cinema_count_current / cinema_count_total）
+	CinemaCountCurrent   int           `json:"cinema_count_current"`   // 今天及以后仍有排片的影院数量
+	CinemaCountTotal     int           `json:"cinema_count_total"`     // 历史上放映过该片的影院数量
+	PrimaryCinemaName    string        `json:"primary_cinema_name"`    // 当只有一个影院时，显示该影院名称
+	Genre                string        `json:"genre"`
+	Runtime              int           `json:"runtime"` // 片长（分钟）
+	Poster               string        `json:"poster"`  // 海报 URL
+	CuratorNote          string        `json:"curator_note"`
+	Kind                 string        `json:"kind"`             // film / event（直播、中继等活动），见 moviekind.go
+	WeeksInRelease       int           `json:"weeks_in_release"` // 从首次排片至今（已停映则至最后排片）的上映周数，首周为 1
+	LongRun              bool          `json:"long_run"`         // 长映标记：上映周数达到阈值且仍在排片
 }
 
 // Person 用于影片详情中的演职员信息。
+// Order 为 TMDB 署名顺序（0 为主演）；旧数据只有 name/role/img 三个字段，读取时按数组下标补齐。
 type Person struct {
-	Name string `json:"name"`
-	Role string `json:"role"`
-	Img  string `json:"img"`
+	Name  string `json:"name"`
+	Role  string `json:"role"`
+	Img   string `json:"img"`
+	Order int    `json:"order"`
+}
+
+// parseCastJSON 解析 CastJSON 并按署名顺序排序；JSON 损坏时返回空数组。
+// 兼容旧的三字段格式：所有 order 都为 0 时视为旧数据，沿用数组原有顺序。
+func parseCastJSON(raw string) []Person {
+	cast := []Person{}
+	if raw == "" {
+		return cast
+	}
+	if err := json.Unmarshal([]byte(raw), &cast); err != nil {
+		return []Person{}
+	}
+	legacy := true
+	for _, p := range cast {
+		if p.Order != 0 {
+			legacy = false
+			break
+		}
+	}
+	if legacy {
+		for i := range cast {
+			cast[i].Order = i
+		}
+		return cast
+	}
+	sort.SliceStable(cast, func(i, j int) bool { return cast[i].Order < cast[j].Order })
+	return cast
+}
+
+// ScheduleDay 某一天的场次列表。
+// Date 为 YYYY-MM-DD（跨年的排片也能正确排序），Label 为前端直接展示的短日期，例如 "1/23 (金)"。
+type ScheduleDay struct {
+	Date      string     `json:"date"`
+	Label     string     `json:"label"`
+	Times     []string   `json:"times"`
+	Showtimes []Showtime `json:"showtimes"`
 }
 
 // MovieCinemaSchedule 用于影片详情中的“多馆排片切换”结构。
+// PastOnly 为 true 表示该影院只有已过期的排片（前端可置灰），此时 Schedule 为空。
+// HasMoreDates 为 true 表示日期窗口之后还有排片（前端可提供“查看完整日历”）。
 type MovieCinemaSchedule struct {
-	ID       uint `json:"id"`
-	Name     string `json:"name"`
-	Schedule []struct {
-		Date  string   `json:"date"`
-		Times []string `json:"times"`
-	} `json:"schedule"`
+	ID           uint          `json:"id"`
+	Name         string        `json:"name"`
+	PastOnly     bool          `json:"past_only"`
+	HasMoreDates bool          `json:"has_more_dates"`
+	FirstDate    string        `json:"first_date"` // 该影院的首次排片日期（YYYY-MM-DD）
+	LastDate     string        `json:"last_date"`  // 该影院的最后排片日期（YYYY-MM-DD）
+	Schedule     []ScheduleDay `json:"schedule"`
 }
 
+// 影片详情中多馆排片的日期窗口（天）。
+const (
+	movieScheduleDefaultDays = 7
+	movieScheduleMaxDays     = 120
+)
+
 // MovieDetail 用于 /api/movies/:id 影片详情视图。
 type MovieDetail struct {
 	MovieItem
-	Synopsis string                `json:"synopsis"`
-	Cast     []Person              `json:"cast"`
-	Cinemas  []MovieCinemaSchedule `json:"cinemas"`
+	Synopsis   string                `json:"synopsis"`
+	TrailerURL string                `json:"trailer_url"` // YouTube 预告片，没有时为空串，见 trailer.go
+	Cast       []Person              `json:"cast"`
+	Cinemas    []MovieCinemaSchedule `json:"cinemas"`
+	RunSummary RunSummary            `json:"run_summary"`
+	Tags       []string              `json:"tags"` // 策展标签，见 movietags.go
+	ScheduleFreshness
 }
 
 // ===========================
@@ -120,26 +352,66 @@ type MovieDetail struct {
 // listCinemasHandler 影院列表接口：
 // - 用于前端地图 Marker 和影院列表的基础数据来源。
 // - 当前阶段：从 Cinemas 表中读取所有影院记录，部分字段使用占位/推导值。
+// - 支持 sort=kana|name|district|intensity 排序（intensity 为排片密度降序）；group=kana 时额外按五十音行分组输出 groups。
+// - movie_id=（可选 date=，默认今天）只返回放映该片的影院，并内联当天场次。
+// - district=新宿区 只返回该区的影院（未知区返回空列表）；page / page_size 分页，total 为过滤后的总数。
 func listCinemasHandler(c *gin.Context) {
+	sortKey := c.Query("sort")
+	group := c.Query("group")
+	if group == "kana" {
+		// 分组依赖五十音顺序，强制按 kana 排序
+		sortKey = "kana"
+	}
+
+	// movie_id：只返回放映该片的影院并内联场次（见 moviecinemas.go）
+	if movieID := c.Query("movie_id"); movieID != "" {
+		listCinemasForMovie(c, movieID, sortKey)
+		return
+	}
+
+	page, err := parseCinemaPage(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	var cinemas []Cinema
-	if err := db.Find(&cinemas).Error; err != nil {
+	query := db.Order("id")
+	if district := strings.TrimSpace(c.Query("district")); district != "" {
+		query = query.Where("district = ?", district)
+	}
+	if err := query.Find(&cinemas).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to query cinemas"})
 		return
 	}
+	sortCinemas(cinemas, sortKey)
+	total := len(cinemas)
+	cinemas = page.slice(cinemas)
 
 	items := make([]CinemaItem, 0, len(cinemas))
 	for _, cin := range cinemas {
 		items = append(items, mapCinemaToItem(cin))
 	}
 
-	c.JSON(http.StatusOK, gin.H{
+	resp := gin.H{
 		"items": items,
-	})
+		"total": total,
+	}
+	if page.Size > 0 {
+		resp["page"] = page.Page
+		resp["page_size"] = page.Size
+	}
+	if group == "kana" {
+		resp["groups"] = groupCinemasByKana(items)
+	}
+	c.JSON(http.StatusOK, resp)
 }
 
 // getCinemaHandler 单个影院详情接口：
 // - 用于前端 Bottom Sheet 展示影院详情与 Daily Schedule。
 // - 支持可选的 date 查询参数（YYYY-MM-DD），不传则默认使用今天。
+// - days=N（默认 1，最多 14）时 schedule_days 返回从 date 起连续 N 天的排片，供前端一次渲染一周的日期标签。
+// - archive=true 时包含已归档的历史排片。
 func getCinemaHandler(c *gin.Context) {
 	id := c.Param("id")
 
@@ -149,17 +421,53 @@ func getCinemaHandler(c *gin.Context) {
 		return
 	}
 
+	renderCinemaDetail(c, cinema)
+}
+
+// getCinemaByEigaHandler 按 eiga.com 影院编号（Cinema.EigaSlug，走唯一索引）查询影院详情：/api/cinemas/by-eiga/:eiga_id
+func getCinemaByEigaHandler(c *gin.Context) {
+	slug := strings.TrimSpace(c.Param("eiga_id"))
+
+	var cinema Cinema
+	if slug == "" || db.Where("eiga_slug = ?", slug).First(&cinema).Error != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "cinema not found"})
+		return
+	}
+
+	renderCinemaDetail(c, cinema)
+}
+
+// renderCinemaDetail 输出影院详情（供按内部 ID / eiga.com 编号查询的接口共用）。
+func renderCinemaDetail(c *gin.Context, cinema Cinema) {
 	// 解析可选的 date 参数（YYYY-MM-DD）。不传则默认使用服务器当前日期。
 	// 这里直接用 date 字符串做 SQL 的 date(play_date)=? 过滤，避免时区导致“明明有排片但查不到”的问题。
 	dateStr := c.Query("date")
 	if dateStr == "" {
-		dateStr = time.Now().Format("2006-01-02")
+		dateStr = referenceTime(c).Format("2006-01-02")
+	}
+	from, err := time.Parse("2006-01-02", dateStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid date, expected YYYY-MM-DD"})
+		return
+	}
+	days := cinemaScheduleDefaultDays
+	if raw := c.Query("days"); raw != "" {
+		v, err := strconv.Atoi(raw)
+		if err != nil || v <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid days, expected a positive integer"})
+			return
+		}
+		days = min(v, cinemaScheduleMaxDays)
 	}
 
-	// 查询该影院相关的所有排片，并聚合为 DailyMovies 结构。
+	// 一次查询该影院窗口内的排片，按日期聚合为 DailyMovies 结构。
+	// archive=true 时同时查询归档表，用于回看已清理的历史排片；include_members_only=true 时含会員限定场次。
+	scheduleDays := buildScheduleDaysForCinema(cinema.ID, from, days, requestLang(c), c.Query("archive") == "true", includeMembersOnly(c))
 	detail := CinemaDetail{
-		CinemaItem:  mapCinemaToItem(cinema),
-		DailyMovies: buildDailyMoviesForCinema(cinema.ID, dateStr),
+		CinemaItem:        mapCinemaToItem(cinema),
+		DailyMovies:       scheduleDays[0].DailyMovies,
+		ScheduleDays:      scheduleDays,
+		ScheduleFreshness: scheduleFreshness(),
 	}
 
 	c.JSON(http.StatusOK, detail)
@@ -172,14 +480,23 @@ func getCinemaHandler(c *gin.Context) {
 
 // listMoviesHandler 影片列表接口：
 // - 支持通过 query 参数按状态 / 排序键 / 搜索关键字过滤。
+// - q 与日 / 英 / 中文标题及日文别名两侧都用 NormalizeForSearch 比较（见 adminsearch.go 的 search_grams）。
 func listMoviesHandler(c *gin.Context) {
 	status := c.Query("status") // showing / incoming
 	sortKey := c.Query("sort")  // imdb_rating / douban_rating
-	query := c.Query("q")
+	query := NormalizeForSearch(c.Query("q"))
 	dateStr := c.Query("date") // YYYY-MM-DD，上层 Soon 日期筛选使用
+	today := referenceTime(c).Format("2006-01-02")
+	asOf := hasAsOf(c)
+	kind := c.DefaultQuery("kind", MovieKindFilm) // film / event / all
+	if kind != MovieKindFilm && kind != MovieKindEvent && kind != "all" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid kind, expected film, event or all"})
+		return
+	}
 
 	var movies []Movie
-	tx := db
+	// 默认只列出电影，直播 / 中继等活动需 ?kind=event 或 all（见 moviekind.go）
+	tx := applyKindFilter(db, kind)
 
 	// 1) 基于 Schedule 做“真排片过滤”
 	// 策略调整：
@@ -187,7 +504,8 @@ func listMoviesHandler(c *gin.Context) {
 	// - 当不传 date 时，只按 status（showing/incoming）过滤，让列表尽可能展示所有可用影片，避免前期数据不全时列表为空。
 	if status != "" && dateStr != "" {
 		var schedules []Schedule
-		schedTx := db.Model(&Schedule{})
+		// 会員限定场次不算公开排片（见 membersonly.go）
+		schedTx := applyMembersOnlyFilter(db.Model(&Schedule{}), false)
 
 		// 解析目标日期
 		var targetDate *time.Time
@@ -230,90 +548,166 @@ func listMoviesHandler(c *gin.Context) {
 		// 按状态过滤：
 		// - showing：兼容早期抓取时未正确写入 status 的记录（'' / NULL 也视为 showing）。
 		// - incoming：只保留显式标记为 incoming 的影片。
-		if status == "showing" {
-			tx = tx.Where("id IN ?", ids).Where("(status = ? OR status = '' OR status IS NULL)", status)
-		} else {
-			tx = tx.Where("id IN ?", ids).Where("status = ?", status)
-		}
+		tx = applyStatusFilter(tx.Where("id IN ?", ids), status, today, asOf)
 	} else if status != "" {
 		// 没有 date 参数时，仅按状态做基础过滤：
 		// - showing：所有正在上映的片 + 早期未写入 status 的记录
 		// - incoming：所有明确标记为 incoming 的片
-		if status == "showing" {
-			tx = tx.Where("(status = ? OR status = '' OR status IS NULL)", status)
-		} else {
-			tx = tx.Where("status = ?", status)
+		tx = applyStatusFilter(tx, status, today, asOf)
+	}
+
+	// 长映筛选：基于 first_seen / last_seen 摘要
+	if c.Query("long_run") == "true" {
+		tx = applyLongRunFilter(tx, today)
+	}
+
+	// 策展标签筛选（见 movietags.go）
+	if tag := c.Query("tag"); tag != "" {
+		normalized, ok := normalizeMovieTag(tag)
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid tag"})
+			return
 		}
+		tx = applyTagFilter(tx, normalized)
 	}
 
-	// 2) 搜索：按中/英文标题模糊匹配（修正列名为 title_cn / title_en）
+	// 2) 搜索：先按 search_grams 索引（规范化后的片名二元组）取候选，取出后再核对规范化片名是否连续包含 q
 	if query != "" {
-		pattern := "%" + query + "%"
-		tx = tx.Where("title_cn LIKE ? OR title_en LIKE ?", pattern, pattern)
+		tx = tx.Where("id IN (?)", searchCandidateQuery(SearchEntityMovie, query))
 	}
 
-	// 3) 排序：按 IMDb 或豆瓣评分倒序
+	// 3) 排序：按 IMDb 或豆瓣评分倒序；id 兜底保证同分时顺序稳定
 	if sortKey == "imdb_rating" {
 		tx = tx.Order("imdb_rating DESC")
 	} else if sortKey == "douban_rating" {
 		tx = tx.Order("douban_rating DESC")
 	}
+	tx = tx.Order("id")
 
 	if err := tx.Find(&movies).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to query movies"})
 		return
 	}
 
-	// 对于 showing 状态的电影，额外过滤：必须至少有一个今天或未来的排片
-	today := time.Now().Format("2006-01-02")
-	filteredMovies := make([]Movie, 0, len(movies))
+	// 排片聚合一次查出（最早排片日期、历史 / 当前影院数、唯一在映影院名），避免逐部影片查询
+	movieIDs := make([]uint, 0, len(movies))
+	matched := movies[:0]
 	for _, m := range movies {
-		if status == "showing" {
-			// 检查是否有今天或未来的排片
-			var futureScheduleCount int64
-			db.Model(&Schedule{}).Where("movie_id = ? AND date(play_date) >= ?", m.ID, today).Count(&futureScheduleCount)
-			if futureScheduleCount == 0 {
-				// 没有今天或未来的排片，跳过（可能是状态未及时更新）
-				continue
-			}
+		if query != "" && searchMatchOf(query, movieSearchKeys(m)) < 0 {
+			continue
 		}
-		filteredMovies = append(filteredMovies, m)
+		matched = append(matched, m)
+		movieIDs = append(movieIDs, m.ID)
+	}
+	movies = matched
+	stats, err := loadMovieScheduleStats(movieIDs, today)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to query schedules"})
+		return
 	}
 
-	items := make([]MovieItem, 0, len(filteredMovies))
-	for _, m := range filteredMovies {
-		item := mapMovieToItem(m)
-		
-		// 统计该影片参与放映的影院数量 + 最早排片日期
-		var firstSchedule Schedule
-		if err := db.Where("movie_id = ?", m.ID).Order("play_date ASC").First(&firstSchedule).Error; err == nil {
-			item.EarliestScheduleDate = firstSchedule.PlayDate.Format("2006-01-02")
+	items := make([]MovieItem, 0, len(movies))
+	refTime := referenceTime(c)
+	for _, m := range movies {
+		st := stats[m.ID]
+		// 对于 showing 状态的电影，额外过滤：必须至少有一个今天或未来的排片（可能是状态未及时更新）
+		if status == "showing" && st.CurrentCount == 0 {
+			continue
 		}
 
-		var cinemaCount int64
-		if err := db.Model(&Schedule{}).
-			Where("movie_id = ?", m.ID).
-			Distinct("cinema_id").
-			Count(&cinemaCount).Error; err == nil {
-			item.CinemaCount = int(cinemaCount)
-
-			// 当只有一个影院参与放映时，查出该影院名称，供前端展示
-			if cinemaCount == 1 {
-				var cin Cinema
-				if err := db.First(&cin, firstSchedule.CinemaID).Error; err == nil {
-					item.PrimaryCinemaName = cin.NameJP
-				}
-			}
+		item := mapMovieToItem(m, requestLang(c))
+		// 上映周数按请求的参考时间计算（支持 as_of）
+		item.WeeksInRelease = weeksInRelease(m.FirstSeen, m.LastSeen, refTime)
+		item.LongRun = isLongRun(m.FirstSeen, m.LastSeen, refTime)
+
+		// 最早排片日期；影院数量分两种口径：历史累计（total）与今天及以后仍在放映（current）
+		item.EarliestScheduleDate = st.EarliestDate
+		item.CinemaCount = int(st.TotalCount)
+		item.CinemaCountTotal = int(st.TotalCount)
+		item.CinemaCountCurrent = int(st.CurrentCount)
+		// 当只有一个影院仍在放映时，给出该影院名称，供前端展示
+		if st.CurrentCount == 1 {
+			item.PrimaryCinemaName = st.CinemaName
 		}
-		
+
 		items = append(items, item)
 	}
 
 	c.JSON(http.StatusOK, gin.H{"items": items})
 }
 
+// movieCinemaCount 按影片聚合的参与影院数；CinemaCount 为 1 时 CinemaID 即唯一的那家影院。
+type movieCinemaCount struct {
+	MovieID     uint
+	CinemaCount int64
+	CinemaID    uint
+}
+
+// countCinemasByMovie 用一条 GROUP BY 统计每部影片的参与影院数。
+// fromDate 非空时只统计该日期及以后的排片（YYYY-MM-DD）；会員限定场次不计入。
+func countCinemasByMovie(movieIDs []uint, fromDate string) map[uint]movieCinemaCount {
+	out := make(map[uint]movieCinemaCount, len(movieIDs))
+	if len(movieIDs) == 0 {
+		return out
+	}
+	tx := db.Model(&Schedule{}).
+		Select("movie_id, COUNT(DISTINCT cinema_id) AS cinema_count, MIN(cinema_id) AS cinema_id").
+		Where("movie_id IN ? AND members_only = ?", movieIDs, false)
+	if fromDate != "" {
+		tx = tx.Where("date(play_date) >= ?", fromDate)
+	}
+	var rows []movieCinemaCount
+	if err := tx.Group("movie_id").Scan(&rows).Error; err != nil {
+		return out
+	}
+	for _, r := range rows {
+		out[r.MovieID] = r
+	}
+	return out
+}
+
+// movieScheduleStats 按影片聚合的排片概况。
+type movieScheduleStats struct {
+	MovieID      uint
+	EarliestDate string // 最早排片日期（YYYY-MM-DD）
+	TotalCount   int64  // 历史参与影院数
+	CurrentCount int64  // 今天及以后仍在放映的影院数
+	CinemaID     uint   // CurrentCount 为 1 时即唯一的那家影院
+	CinemaName   string
+}
+
+// loadMovieScheduleStats 用一条 GROUP BY（连同影院名）聚合每部影片的排片概况，today 为 YYYY-MM-DD。
+// play_date 按存储的文本取前 10 位作为日期，与 Schedule.PlayDate.Format("2006-01-02") 一致；会員限定场次不计入。
+// 查询失败时返回错误，调用方不能把空结果当作“没有排片”。
+func loadMovieScheduleStats(movieIDs []uint, today string) (map[uint]movieScheduleStats, error) {
+	out := make(map[uint]movieScheduleStats, len(movieIDs))
+	if len(movieIDs) == 0 {
+		return out, nil
+	}
+	grouped := db.Model(&Schedule{}).
+		Select(`movie_id,
+			substr(MIN(play_date), 1, 10) AS earliest_date,
+			COUNT(DISTINCT cinema_id) AS total_count,
+			COUNT(DISTINCT CASE WHEN date(play_date) >= ? THEN cinema_id END) AS current_count,
+			MIN(CASE WHEN date(play_date) >= ? THEN cinema_id END) AS cinema_id`, today, today).
+		Where("movie_id IN ? AND members_only = ?", movieIDs, false).
+		Group("movie_id")
+	var rows []movieScheduleStats
+	if err := db.Table("(?) AS s", grouped).
+		Select("s.*, CASE WHEN s.current_count = 1 THEN cinemas.name_jp ELSE '' END AS cinema_name").
+		Joins("LEFT JOIN cinemas ON cinemas.id = s.cinema_id").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	for _, r := range rows {
+		out[r.MovieID] = r
+	}
+	return out, nil
+}
+
 // getMovieHandler 单个影片详情接口：
 // - 返回影片的基础元数据 + 简要剧情 + 多馆排片信息。
+// - cast 按署名顺序返回（最多 20 位），?cast_limit=N 只取前 N 位。
 func getMovieHandler(c *gin.Context) {
 	id := c.Param("id")
 
@@ -323,20 +717,100 @@ func getMovieHandler(c *gin.Context) {
 		return
 	}
 
-	// 解析 CastJSON 为 Person 数组
-	var cast []Person
-	if movie.CastJSON != "" {
-		if err := json.Unmarshal([]byte(movie.CastJSON), &cast); err != nil {
-			// JSON 解析失败时，cast 保持为空数组
-			cast = []Person{}
+	renderMovieDetail(c, movie)
+}
+
+// getMovieByTmdbHandler 按 TMDB ID 查询影片详情：/api/movies/by-tmdb/:tmdb_id
+func getMovieByTmdbHandler(c *gin.Context) {
+	tmdbID, err := strconv.Atoi(c.Param("tmdb_id"))
+	if err != nil || tmdbID <= 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "movie not found"})
+		return
+	}
+
+	var movie Movie
+	if err := db.Where("tmdb_id = ?", tmdbID).First(&movie).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "movie not found"})
+		return
+	}
+
+	renderMovieDetail(c, movie)
+}
+
+// getMovieByImdbHandler 按 IMDb ID（tt 开头）查询影片详情：/api/movies/by-imdb/:imdb_id
+func getMovieByImdbHandler(c *gin.Context) {
+	imdbID := strings.TrimSpace(c.Param("imdb_id"))
+
+	var movie Movie
+	if imdbID == "" || db.Where("imdb_id = ?", imdbID).First(&movie).Error != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "movie not found"})
+		return
+	}
+
+	renderMovieDetail(c, movie)
+}
+
+// renderMovieDetail 输出影片详情（供按内部 ID / 外部 ID 查询的接口共用）。
+func renderMovieDetail(c *gin.Context, movie Movie) {
+	// 解析 CastJSON 为 Person 数组；cast_limit=N 只返回署名前 N 位
+	cast := parseCastJSON(movie.CastJSON)
+	if n, err := strconv.Atoi(c.Query("cast_limit")); err == nil && n >= 0 && n < len(cast) {
+		cast = cast[:n]
+	}
+
+	// 默认返回 [from, from+days) 窗口内的排片：from 默认今天，days 默认 7。
+	// archive=true：返回 [from, to] 历史窗口内的排片（含归档），默认最近 30 天。
+	today := referenceTime(c).Format("2006-01-02")
+	// format=legacy：旧客户端仍按 "1/23" 解析 schedule[].date，保留一个版本后移除
+	legacyDates := false
+	switch c.Query("format") {
+	case "":
+	case "legacy":
+		legacyDates = true
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid format, expected legacy"})
+		return
+	}
+	var cinemas []MovieCinemaSchedule
+	if c.Query("archive") != "true" {
+		from := c.DefaultQuery("from", today)
+		if _, err := time.Parse("2006-01-02", from); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid from, expected YYYY-MM-DD"})
+			return
+		}
+		days := movieScheduleDefaultDays
+		if raw := c.Query("days"); raw != "" {
+			v, err := strconv.Atoi(raw)
+			if err != nil || v <= 0 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid days, expected a positive integer"})
+				return
+			}
+			days = min(v, movieScheduleMaxDays)
+		}
+		cinemas = buildCinemasForMovie(movie.ID, today, from, days, includeMembersOnly(c))
+	} else {
+		to := c.DefaultQuery("to", today)
+		from := c.Query("from")
+		if from == "" {
+			if t, err := time.Parse("2006-01-02", to); err == nil {
+				from = t.AddDate(0, 0, -30).Format("2006-01-02")
+			}
 		}
+		cinemas = buildArchivedCinemasForMovie(movie.ID, from, to, includeMembersOnly(c))
+	}
+	if legacyDates {
+		useLegacyScheduleDates(cinemas)
 	}
 
 	detail := MovieDetail{
-		MovieItem: mapMovieToItem(movie),
-		Synopsis:  movie.Synopsis,
-		Cast:      cast,
-		Cinemas:   buildCinemasForMovie(movie.ID),
+		MovieItem:         mapMovieToItem(movie, requestLang(c)),
+		Synopsis:          movie.Synopsis,
+		TrailerURL:        movie.TrailerURL,
+		Cast:              cast,
+		Cinemas:           cinemas,
+		RunSummary:        buildRunSummary(movie, today),
+		Tags:              loadMovieTags(movie.ID),
+		ScheduleFreshness: scheduleFreshness(),
 	}
 
 	c.JSON(http.StatusOK, detail)
@@ -351,22 +825,38 @@ func getMovieHandler(c *gin.Context) {
 // 说明：
 // - Name 使用抓取到的日文名（NameJP）。
 // - District 尝试从 Address 中截取“**区”，若失败则置空。
-// - Tags / Website / Desc 暂时使用占位，后续可通过额外字段或人工策展填充。
+// - Desc 为人工策展简介，或 enrich-cinemas 从官网 meta 补全的候选简介。
 func mapCinemaToItem(cn Cinema) CinemaItem {
 	return CinemaItem{
 		ID:            cn.ID,
 		Name:          cn.NameJP,
 		NameEN:        "", // 预留：后续可在数据库中补充英文名
+		Kana:          cn.NameKana,
 		District:      extractDistrict(cn.Address),
 		Lat:           cn.Latitude,
 		Lng:           cn.Longitude,
-		Tags:          []string{}, // 预留：如 #2本立 / #名画座 等
+		Located:       cinemaCoordsResolved(cn),
+		Tags:          splitTags(cn.Tags), // 如 2本立 / 名画座 等
 		Website:       cn.Website,
-		Desc:          "",
+		Desc:          cn.Desc,
 		BuildingPhoto: cn.BuildingPhoto,
+		PhotoURL:      ownedImageURL(cn.BuildingPhoto, cn.BuildingPhotoHash, fmt.Sprintf("/api/cinemas/%d/photo", cn.ID)),
+		ScreenCount:   cn.ScreenCount,
+		Intensity:     cn.ProgrammingIntensity,
 	}
 }
 
+// splitTags 将逗号分隔的标签拆为数组（始终返回非 nil 切片）。
+func splitTags(raw string) []string {
+	tags := []string{}
+	for _, t := range strings.Split(raw, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			tags = append(tags, t)
+		}
+	}
+	return tags
+}
+
 // extractDistrict 从完整地址中尝试提取“XX区”片段，例如：
 // - "東京都新宿区新宿3-15-15 新宿ピカデリー内" -> "新宿区"
 func extractDistrict(address string) string {
@@ -380,8 +870,9 @@ func extractDistrict(address string) string {
 	// 找到“区”前面的一个日文汉字起点（简单切分：从都/道/府/县后开始）
 	// 示例："東京都新宿区" -> 从 "東" 之后去掉 "東京都" 留下 "新宿区"
 	// 这里采用简化版：直接从最后一个 "都/道/府/県" 后一位开始截取到 "区"。
+	// 只看“区”之前的部分，避免地址后面的交通说明（如「表参道駅」）干扰
 	start := 0
-	for i, r := range address {
+	for i, r := range address[:idx] {
 		if r == '都' || r == '道' || r == '府' || r == '県' {
 			start = i + len("都")
 		}
@@ -392,27 +883,50 @@ func extractDistrict(address string) string {
 	return strings.TrimSpace(address[start : idx+len("区")])
 }
 
-// buildDailyMoviesForCinema 将某个影院的 Schedule + Movie 聚合成前端需要的 DailyMovie 列表。
-// targetDate：要展示的日期（从 getCinemaHandler 的 query 参数传入，默认今天）。
-func buildDailyMoviesForCinema(cinemaID uint, dateStr string) []DailyMovie {
-	var schedules []Schedule
+// buildScheduleDaysForCinema 某个影院从 from 起连续 days 天的排片：一次查询窗口内的场次，再按日期分组。
+// 每一天都有一项（没有场次时 DailyMovies 为空数组）；archive 为 true 时同时查询归档表；
+// membersOnly 为 false 时不含会員限定场次。
+func buildScheduleDaysForCinema(cinemaID uint, from time.Time, days int, lang string, archive, membersOnly bool) []CinemaScheduleDay {
 	// 直接在 SQL 层用 date(play_date) 过滤，避免 time.Location 不一致导致的日期偏移
-	if err := db.Where("cinema_id = ? AND date(play_date) = ?", cinemaID, dateStr).Find(&schedules).Error; err != nil {
-		return []DailyMovie{}
+	scope := func(tx *gorm.DB) *gorm.DB {
+		return applyMembersOnlyFilter(tx, membersOnly).Where("cinema_id = ? AND date(play_date) >= ? AND date(play_date) < ?",
+			cinemaID, from.Format("2006-01-02"), from.AddDate(0, 0, days).Format("2006-01-02"))
 	}
-	if len(schedules) == 0 {
-		return []DailyMovie{}
+	var schedules []Schedule
+	var err error
+	if archive {
+		schedules, err = loadSchedulesWithArchive(scope)
+	} else {
+		err = scope(db).Find(&schedules).Error
+	}
+	if err != nil {
+		schedules = nil
 	}
 
-	// 加载涉及到的影片信息。
-	movieIDs := make(map[uint]struct{})
+	byDate := make(map[string][]Schedule)
 	for _, s := range schedules {
-		movieIDs[s.MovieID] = struct{}{}
+		date := s.PlayDate.Format("2006-01-02")
+		byDate[date] = append(byDate[date], s)
 	}
-	if len(movieIDs) == 0 {
-		return []DailyMovie{}
+	movieMap := loadMoviesForSchedules(schedules)
+	out := make([]CinemaScheduleDay, 0, days)
+	for i := 0; i < days; i++ {
+		date := from.AddDate(0, 0, i).Format("2006-01-02")
+		out = append(out, CinemaScheduleDay{Date: date, DailyMovies: groupDailyMovies(byDate[date], movieMap, lang)})
 	}
+	return out
+}
 
+// loadMoviesForSchedules 加载排片涉及到的影片信息（ID -> Movie）。
+func loadMoviesForSchedules(schedules []Schedule) map[uint]Movie {
+	movieMap := make(map[uint]Movie)
+	if len(schedules) == 0 {
+		return movieMap
+	}
+	movieIDs := make(map[uint]struct{})
+	for _, s := range schedules {
+		movieIDs[s.MovieID] = struct{}{}
+	}
 	ids := make([]uint, 0, len(movieIDs))
 	for id := range movieIDs {
 		ids = append(ids, id)
@@ -420,14 +934,22 @@ func buildDailyMoviesForCinema(cinemaID uint, dateStr string) []DailyMovie {
 
 	var movies []Movie
 	if err := db.Where("id IN ?", ids).Find(&movies).Error; err != nil {
-		return []DailyMovie{}
+		return movieMap
 	}
-	movieMap := make(map[uint]Movie)
 	for _, m := range movies {
 		movieMap[m.ID] = m
 	}
+	return movieMap
+}
 
-	// 聚合同一影片的多个时间场次。
+// groupDailyMovies 按影片聚合同一天的排片（纯函数）；movieMap 中没有的影片跳过。
+func groupDailyMovies(schedules []Schedule, movieMap map[uint]Movie, lang string) []DailyMovie {
+	if len(schedules) == 0 {
+		return []DailyMovie{}
+	}
+
+	// 聚合同一影片的多个时间场次（先按开场时间排序，Times / Showtimes 随之有序）。
+	sortSchedulesByStartTime(schedules)
 	dailyMap := make(map[uint]*DailyMovie)
 	for _, s := range schedules {
 		mv, ok := movieMap[s.MovieID]
@@ -435,52 +957,190 @@ func buildDailyMoviesForCinema(cinemaID uint, dateStr string) []DailyMovie {
 			continue
 		}
 		if _, exists := dailyMap[mv.ID]; !exists {
-			// 标题兜底：CN -> EN -> JP -> "Movie #ID"
-			title := strings.TrimSpace(mv.TitleCN)
-			if title == "" {
-				title = strings.TrimSpace(mv.TitleEN)
-			}
-			if title == "" {
-				title = strings.TrimSpace(mv.TitleJP)
-			}
-			if title == "" {
-				title = fmt.Sprintf("Movie #%d", mv.ID)
-			}
+			title := movieDisplayTitleLang(mv, lang)
 
-			// 评分优先级：豆瓣 > IMDb > TMDB
-			rating := mv.DoubanRating
-			if rating == 0 {
-				rating = mv.IMDBRating
-			}
-			if rating == 0 {
-				rating = mv.TMDBRating
+			// 评分优先级：豆瓣 > IMDb > TMDB（见 ratings.go 的 ratingPriority）
+			rating := 0.0
+			if r := primaryRating(buildRatings(mv)); r != nil {
+				rating = r.Score
 			}
 			dailyMap[mv.ID] = &DailyMovie{
-				ID:     mv.ID,
-				Title:  title,
-				Rating: fmt.Sprintf("%.1f", rating),
-				Times:  []string{},
+				ID:        mv.ID,
+				EigaID:    mv.EigaID,
+				Title:     title,
+				Rating:    fmt.Sprintf("%.1f", rating),
+				Times:     []string{},
+				Showtimes: []Showtime{},
 			}
 		}
 		dailyMap[mv.ID].Times = append(dailyMap[mv.ID].Times, s.StartTime)
+		dailyMap[mv.ID].Showtimes = append(dailyMap[mv.ID].Showtimes, movieShowtime(s, mv))
 	}
 
+	// map 遍历顺序不固定：按首场时间排序，同一时间按影片 ID
 	result := make([]DailyMovie, 0, len(dailyMap))
 	for _, dm := range dailyMap {
 		result = append(result, *dm)
 	}
+	sort.Slice(result, func(i, j int) bool {
+		fi, fj := startTimeMinutes(result[i].Times[0]), startTimeMinutes(result[j].Times[0])
+		if fi != fj {
+			return fi < fj
+		}
+		return result[i].ID < result[j].ID
+	})
 	return result
 }
 
+// startTimeMinutes 将 "H:mm" / "HH:mm" 场次时间换算为当天分钟数，解析失败返回 -1。
+// 说明：eiga.com 的早场常写作 "9:00"（无前导零），不能直接按字符串比较。
+func startTimeMinutes(t string) int {
+	parts := strings.SplitN(strings.TrimSpace(t), ":", 2)
+	if len(parts) != 2 {
+		return -1
+	}
+	h, err1 := strconv.Atoi(parts[0])
+	m, err2 := strconv.Atoi(parts[1])
+	if err1 != nil || err2 != nil {
+		return -1
+	}
+	return h*60 + m
+}
+
+// sortSchedulesByStartTime 按放映日期、实际开场时刻排序，最后以 ID 兜底，保证输出稳定。
+func sortSchedulesByStartTime(schedules []Schedule) {
+	sort.SliceStable(schedules, func(i, j int) bool {
+		a, b := schedules[i], schedules[j]
+		if !a.PlayDate.Equal(b.PlayDate) {
+			return a.PlayDate.Before(b.PlayDate)
+		}
+		if ma, mb := startTimeMinutes(a.StartTime), startTimeMinutes(b.StartTime); ma != mb {
+			return ma < mb
+		}
+		return a.ID < b.ID
+	})
+}
+
+// sortStartTimes 按实际时刻对场次时间排序。
+func sortStartTimes(times []string) {
+	sort.SliceStable(times, func(i, j int) bool {
+		return startTimeMinutes(times[i]) < startTimeMinutes(times[j])
+	})
+}
+
+// movieDisplayTitle 单行展示用标题，兜底顺序：CN -> EN -> JP -> "Movie #ID"。
+func movieDisplayTitle(mv Movie) string {
+	return movieDisplayTitleLang(mv, "")
+}
+
+// movieDisplayTitleLang 按请求语言选择单行展示标题（见 lang.go），全部为空时为 "Movie #ID"。
+func movieDisplayTitleLang(mv Movie, lang string) string {
+	if title := localizedTitle(mv, lang); title != "" {
+		return title
+	}
+	return fmt.Sprintf("Movie #%d", mv.ID)
+}
+
 // buildCinemasForMovie 将某部影片的 Schedule + Cinema 聚合成前端 DetailView 需要的结构。
-// 只返回今天及未来的排片（已过期的排片不显示）。
-func buildCinemasForMovie(movieID uint) []MovieCinemaSchedule {
-	today := time.Now().Format("2006-01-02")
+// 只返回 [from, from+days) 窗口内的排片（在 SQL 中过滤）；窗口之后还有排片的影院标记 has_more_dates，
+// 窗口内没有场次、只在之后有排片的影院同样列出（schedule 为空）。
+// 今天（today）及以后都没有排片、只放映过的影院追加在末尾并标记 past_only。
+// 每家影院都带 first_date / last_date（该影院的首末排片日期）；last_date 已过的影院排在仍在放映的影院之后。
+// membersOnly 为 false 时不含会員限定场次（放映跨度汇总本身只统计公开场次）。
+func buildCinemasForMovie(movieID uint, today, from string, days int, membersOnly bool) []MovieCinemaSchedule {
+	start, err := time.Parse("2006-01-02", from)
+	if err != nil {
+		return []MovieCinemaSchedule{}
+	}
+	to := start.AddDate(0, 0, days-1).Format("2006-01-02")
+
 	var schedules []Schedule
-	// 只查询今天及未来的排片
-	if err := db.Where("movie_id = ? AND date(play_date) >= ?", movieID, today).Find(&schedules).Error; err != nil {
+	if err := applyMembersOnlyFilter(db, membersOnly).Where("movie_id = ? AND date(play_date) >= ? AND date(play_date) <= ?", movieID, from, to).
+		Order("id").Find(&schedules).Error; err != nil {
 		return []MovieCinemaSchedule{}
 	}
+	out := cinemasFromSchedules(schedules)
+
+	// 各影院的放映跨度（含已清理的排片，见 runrollup.go）：窗口之后仍有排片（has_more_dates）与只剩过去排片（past_only）都据此判断
+	runs := loadMovieRunSummaries(movieID)
+	listed := make(map[uint]struct{}, len(out))
+	for i := range out {
+		listed[out[i].ID] = struct{}{}
+		if runs[out[i].ID].LastDate > to {
+			out[i].HasMoreDates = true
+		}
+	}
+	var laterIDs, pastIDs []uint
+	for id, run := range runs {
+		if _, ok := listed[id]; ok {
+			continue
+		}
+		if run.LastDate > to {
+			laterIDs = append(laterIDs, id)
+		} else if run.LastDate < today {
+			pastIDs = append(pastIDs, id)
+		}
+	}
+	for _, cin := range loadCinemasByID(laterIDs) {
+		out = append(out, MovieCinemaSchedule{
+			ID:           cin.ID,
+			Name:         cin.NameJP,
+			HasMoreDates: true,
+			Schedule:     []ScheduleDay{},
+		})
+	}
+	for _, cin := range loadCinemasByID(pastIDs) {
+		out = append(out, MovieCinemaSchedule{
+			ID:       cin.ID,
+			Name:     cin.NameJP,
+			PastOnly: true,
+			Schedule: []ScheduleDay{},
+		})
+	}
+
+	applyCinemaRuns(out, runs)
+	// 窗口起点在过去时，窗口内也可能有已结束放映的影院：整体稳定排序，仍在放映的在前
+	sort.SliceStable(out, func(i, j int) bool {
+		return out[i].LastDate >= today && out[j].LastDate < today
+	})
+	return out
+}
+
+// cinemaRun 某部影片在一家影院的排片跨度（YYYY-MM-DD）。
+type cinemaRun struct {
+	CinemaID  uint
+	FirstDate string
+	LastDate  string
+}
+
+// applyCinemaRuns 填充每家影院的 first_date / last_date。
+func applyCinemaRuns(cinemas []MovieCinemaSchedule, runs map[uint]cinemaRun) {
+	for i := range cinemas {
+		run := runs[cinemas[i].ID]
+		cinemas[i].FirstDate, cinemas[i].LastDate = run.FirstDate, run.LastDate
+	}
+}
+
+// loadCinemasByID 按 ID 升序加载影院；ids 为空时不查询。
+func loadCinemasByID(ids []uint) []Cinema {
+	if len(ids) == 0 {
+		return nil
+	}
+	var cinemas []Cinema
+	if err := db.Where("id IN ?", ids).Order("id").Find(&cinemas).Error; err != nil {
+		return nil
+	}
+	return cinemas
+}
+
+// cinemasFromSchedules 将同一部影片的排片按影院 + 日期聚合为 MovieCinemaSchedule 列表。
+func cinemasFromSchedules(schedules []Schedule) []MovieCinemaSchedule {
+	// 场次的 audio_hint 需要影片的原始语言 / 类型 / 策展默认版本
+	var movie Movie
+	if len(schedules) > 0 {
+		db.Select("id", "original_language", "genre", "audio_default").Limit(1).Find(&movie, schedules[0].MovieID)
+	}
+
 	if len(schedules) == 0 {
 		return []MovieCinemaSchedule{}
 	}
@@ -508,21 +1168,34 @@ func buildCinemasForMovie(movieID uint) []MovieCinemaSchedule {
 		cinemaMap[c.ID] = c
 	}
 
-	// 先按影院 + 日期聚合所有场次。
+	// 先按影院 + 日期聚合所有场次；排片预先排好序，分组后的场次按时间先后。
+	// day 使用可排序的 YYYY-MM-DD，直接作为输出的 date。
+	sortSchedulesByStartTime(schedules)
 	type key struct {
 		cinemaID uint
-		date     string
+		day      string
 	}
-	grouped := make(map[key][]string)
+	grouped := make(map[key][]Schedule)
+	keys := make([]key, 0)
 	for _, s := range schedules {
-		date := s.PlayDate.Format("1/2") // 与前端 mock 保持类似格式，例如 "1/23"
-		k := key{cinemaID: s.CinemaID, date: date}
-		grouped[k] = append(grouped[k], s.StartTime)
+		k := key{cinemaID: s.CinemaID, day: s.PlayDate.Format("2006-01-02")}
+		if _, exists := grouped[k]; !exists {
+			keys = append(keys, k)
+		}
+		grouped[k] = append(grouped[k], s)
 	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].cinemaID != keys[j].cinemaID {
+			return keys[i].cinemaID < keys[j].cinemaID
+		}
+		return keys[i].day < keys[j].day
+	})
 
-	// 再按影院组装成 MovieCinemaSchedule。
+	// 再按影院组装成 MovieCinemaSchedule（影院按 ID、日期按先后）。
 	cinemaSchedules := make(map[uint]*MovieCinemaSchedule)
-	for k, times := range grouped {
+	order := make([]uint, 0)
+	for _, k := range keys {
+		daySchedules := grouped[k]
 		cin, ok := cinemaMap[k.cinemaID]
 		if !ok {
 			continue
@@ -532,29 +1205,60 @@ func buildCinemasForMovie(movieID uint) []MovieCinemaSchedule {
 				ID:   cin.ID,
 				Name: cin.NameJP,
 			}
+			order = append(order, cin.ID)
+		}
+		entry := ScheduleDay{
+			Date:      k.day,
+			Label:     scheduleDayLabel(daySchedules[0].PlayDate),
+			Times:     make([]string, 0, len(daySchedules)),
+			Showtimes: make([]Showtime, 0, len(daySchedules)),
 		}
-		entry := struct {
-			Date  string   `json:"date"`
-			Times []string `json:"times"`
-		}{
-			Date:  k.date,
-			Times: times,
+		for _, s := range daySchedules {
+			entry.Times = append(entry.Times, s.StartTime)
+			entry.Showtimes = append(entry.Showtimes, movieShowtime(s, movie))
 		}
 		cinemaSchedules[cin.ID].Schedule = append(cinemaSchedules[cin.ID].Schedule, entry)
 	}
 
-	out := make([]MovieCinemaSchedule, 0, len(cinemaSchedules))
-	for _, cs := range cinemaSchedules {
-		out = append(out, *cs)
+	out := make([]MovieCinemaSchedule, 0, len(order))
+	for _, id := range order {
+		out = append(out, *cinemaSchedules[id])
 	}
 	return out
 }
 
-// mapMovieToItem 将 Movie 模型转换为前端的 MovieItem。
-func mapMovieToItem(m Movie) MovieItem {
+// scheduleDayLabel 排片日期的短标签（纯函数）："1/23 (金)"，星期固定为日文。
+func scheduleDayLabel(day time.Time) string {
+	return fmt.Sprintf("%s (%s)", day.Format("1/2"), formatWeekday(day.Weekday(), LangJA))
+}
+
+// useLegacyScheduleDates format=legacy：把 schedule[].date 改回旧的 "1/23" 格式（label 不变）。
+func useLegacyScheduleDates(cinemas []MovieCinemaSchedule) {
+	for i := range cinemas {
+		for j := range cinemas[i].Schedule {
+			day := &cinemas[i].Schedule[j]
+			if t, err := time.Parse("2006-01-02", day.Date); err == nil {
+				day.Date = t.Format("1/2")
+			}
+		}
+	}
+}
+
+// mapMovieToItem 将 Movie 模型转换为前端的 MovieItem；lang 决定默认展示标题（空串为中文优先）。
+func mapMovieToItem(m Movie, lang string) MovieItem {
 	releaseDateStr := ""
+	precision := ""
 	if !m.ReleaseDate.IsZero() {
 		releaseDateStr = m.ReleaseDate.Format("2006-01-02")
+		precision = m.ReleaseDatePrecision
+		if precision == "" {
+			precision = ReleaseDatePrecisionDay
+		}
+	}
+	// Soon 影片展示的是日本上映日期：已知时优先于全球首映日期
+	jpRelease := jpReleaseDateString(m)
+	if m.Status == "incoming" && jpRelease != "" {
+		releaseDateStr, precision = jpRelease, ReleaseDatePrecisionDay
 	}
 
 	// 标题回退策略：
@@ -573,23 +1277,32 @@ func mapMovieToItem(m Movie) MovieItem {
 	}
 
 	return MovieItem{
-		ID:           m.ID,
-		TitleCN:      titleCN,
-		TitleEN:      titleEN,
-		Director:     m.Director,
-		Year:         m.Year,
-		TMDBRating:   m.TMDBRating,
-		IMDBRating:   m.IMDBRating,
-		DoubanRating: m.DoubanRating,
-		Status:       m.Status,
-		ReleaseDate:  releaseDateStr,
+		ID:                   m.ID,
+		Title:                localizedTitle(m, lang),
+		TitleCN:              titleCN,
+		TitleEN:              titleEN,
+		Director:             m.Director,
+		Year:                 m.Year,
+		TMDBRating:           m.TMDBRating,
+		IMDBRating:           m.IMDBRating,
+		DoubanRating:         m.DoubanRating,
+		IMDBVotes:            m.IMDBVotes,
+		RTRating:             m.RTRating,
+		Metacritic:           m.Metacritic,
+		Ratings:              buildRatings(m),
+		Status:               m.Status,
+		ReleaseDate:          releaseDateStr,
+		ReleaseDatePrecision: precision,
+		JPReleaseDate:        jpRelease,
 		EarliestScheduleDate: "", // 由调用方填充
-		CinemaCount:  0,          // 由调用方填充
-		PrimaryCinemaName: "",
-		Genre:        m.Genre,
-		Runtime:      m.Runtime,
-		Poster:       m.Poster,
-		CuratorNote:  m.CuratorNote,
+		CinemaCount:          0,  // 由调用方填充
+		PrimaryCinemaName:    "",
+		Genre:                m.Genre,
+		Runtime:              m.Runtime,
+		Poster:               m.Poster,
+		CuratorNote:          m.CuratorNote,
+		Kind:                 movieKindOrDefault(m),
+		WeeksInRelease:       weeksInRelease(m.FirstSeen, m.LastSeen, nowJST()),
+		LongRun:              isLongRun(m.FirstSeen, m.LastSeen, nowJST()),
 	}
 }
-
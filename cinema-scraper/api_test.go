@@ -0,0 +1,698 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestCinemaList 影院列表：过滤、分页、五十音与排片密度排序。
+func TestCinemaList(t *testing.T) {
+	runHTTPCases(t, []httpCase{
+		{"影院列表：全部", "/api/cinemas", func(r testResponse) error {
+			var body testCinemaList
+			if err := expectJSON(r, &body); err != nil {
+				return err
+			}
+			return firstError(expectEqual("total", body.Total, fixtureCinemaCount), expectEqual("len(items)", len(body.Items), fixtureCinemaCount))
+		}},
+		{"影院列表：定位失败的影院 located=false 且不带坐标", "/api/cinemas", func(r testResponse) error {
+			var body testCinemaList
+			if err := expectJSON(r, &body); err != nil {
+				return err
+			}
+			unlocated := make([]string, 0)
+			for _, it := range body.Items {
+				if !it.Located {
+					if it.Lat != 0 || it.Lng != 0 {
+						return fmt.Errorf("cinema %d is not located but has coordinates %v,%v", it.ID, it.Lat, it.Lng)
+					}
+					unlocated = append(unlocated, it.Name)
+				}
+			}
+			return expectEqual("unlocated", fmt.Sprint(unlocated), "[早稲田テスト劇場]")
+		}},
+		{"影院列表：按区过滤", "/api/cinemas?district=新宿区", func(r testResponse) error {
+			var body testCinemaList
+			if err := expectJSON(r, &body); err != nil {
+				return err
+			}
+			for _, it := range body.Items {
+				if it.District != "新宿区" {
+					return fmt.Errorf("cinema %d district = %q", it.ID, it.District)
+				}
+			}
+			return expectEqual("total", body.Total, 2)
+		}},
+		{"影院列表：未知区返回空数组", "/api/cinemas?district=不存在区", func(r testResponse) error {
+			if err := expectStatus(r, http.StatusOK); err != nil {
+				return err
+			}
+			var raw map[string]json.RawMessage
+			if err := r.decode(&raw); err != nil {
+				return err
+			}
+			return expectEqual("items", string(raw["items"]), "[]")
+		}},
+		{"影院列表：分页最后一页", "/api/cinemas?page=3&page_size=2", func(r testResponse) error {
+			var body testCinemaList
+			if err := expectJSON(r, &body); err != nil {
+				return err
+			}
+			return firstError(expectEqual("total", body.Total, fixtureCinemaCount), expectEqual("page", body.Page, 3),
+				expectEqual("len(items)", len(body.Items), 1))
+		}},
+		{"影院列表：非法 page_size", "/api/cinemas?page_size=0", func(r testResponse) error {
+			return expectStatus(r, http.StatusBadRequest)
+		}},
+		{"影院列表：按五十音排序", "/api/cinemas?sort=kana", func(r testResponse) error {
+			var body testCinemaList
+			if err := expectJSON(r, &body); err != nil {
+				return err
+			}
+			if !sort.SliceIsSorted(body.Items, func(i, j int) bool { return body.Items[i].Kana < body.Items[j].Kana }) {
+				return fmt.Errorf("items are not sorted by kana")
+			}
+			return nil
+		}},
+		{"影院列表：按排片密度排序（按银幕数归一化）", "/api/cinemas?sort=intensity", func(r testResponse) error {
+			var body testCinemaList
+			if err := expectJSON(r, &body); err != nil {
+				return err
+			}
+			if err := expectEqual("len(items)", len(body.Items), fixtureCinemaCount); err != nil {
+				return err
+			}
+			// 每家每天 6 场：3 厅的影院为 2.0；单厅与未填银幕数（场次不重叠，估算为 1 厅）的影院为 6.0
+			intensity := make(map[uint]float64)
+			for _, it := range body.Items {
+				intensity[it.ID] = it.Intensity
+			}
+			last := body.Items[len(body.Items)-1]
+			return firstError(expectEqual("last", last.ID, uint(1)), expectEqual("cinema 1", intensity[1], 2.0),
+				expectEqual("cinema 2", intensity[2], 6.0), expectEqual("cinema 5 (screens unknown)", intensity[5], 6.0))
+		}},
+	})
+}
+
+// TestCinemaDetail 影院详情：当天 / 指定日期 / 多日排片与参数校验。
+func TestCinemaDetail(t *testing.T) {
+	today := nowJST()
+	date := func(offset int) string { return today.AddDate(0, 0, offset).Format("2006-01-02") }
+	runHTTPCases(t, []httpCase{
+		{"影院详情：不存在", "/api/cinemas/9999", func(r testResponse) error {
+			return expectStatus(r, http.StatusNotFound)
+		}},
+		{"影院详情：今天的场次（深夜场排在最后）", "/api/cinemas/1", func(r testResponse) error {
+			var body CinemaDetail
+			if err := expectJSON(r, &body); err != nil {
+				return err
+			}
+			var times []string
+			for _, dm := range body.DailyMovies {
+				times = append(times, dm.Times...)
+			}
+			if err := expectEqual("showtimes today", len(times), len(fixtureShowTimes)); err != nil {
+				return err
+			}
+			late := fixtureShowTimes[len(fixtureShowTimes)-1]
+			for _, dm := range body.DailyMovies {
+				for i, t := range dm.Times {
+					if t == late && i != len(dm.Times)-1 {
+						return fmt.Errorf("late show %s is not last in %v", late, dm.Times)
+					}
+				}
+			}
+			return nil
+		}},
+		{"影院详情：指定过去日期", "/api/cinemas/1?date=" + date(-fixturePastDays), func(r testResponse) error {
+			var body CinemaDetail
+			if err := expectJSON(r, &body); err != nil {
+				return err
+			}
+			if len(body.DailyMovies) == 0 {
+				return fmt.Errorf("no daily movies on %s", date(-fixturePastDays))
+			}
+			return nil
+		}},
+		{"影院详情：连续 7 天排片", "/api/cinemas/2?days=7", func(r testResponse) error {
+			var body CinemaDetail
+			if err := expectJSON(r, &body); err != nil {
+				return err
+			}
+			if err := expectEqual("len(schedule_days)", len(body.ScheduleDays), 7); err != nil {
+				return err
+			}
+			for i, day := range body.ScheduleDays {
+				if err := expectEqual("schedule_days date", day.Date, date(i)); err != nil {
+					return err
+				}
+				n := 0
+				for _, dm := range day.DailyMovies {
+					n += len(dm.Times)
+				}
+				if err := expectEqual("showtimes on "+day.Date, n, len(fixtureShowTimes)); err != nil {
+					return err
+				}
+			}
+			return expectEqual("len(daily_movies)", len(body.DailyMovies), len(body.ScheduleDays[0].DailyMovies))
+		}},
+		{"影院详情：非法 days", "/api/cinemas/1?days=0", func(r testResponse) error {
+			return expectStatus(r, http.StatusBadRequest)
+		}},
+		{"影院详情：排片范围之外的日期", "/api/cinemas/1?date=" + date(fixtureFutureDays+1), func(r testResponse) error {
+			var body CinemaDetail
+			if err := expectJSON(r, &body); err != nil {
+				return err
+			}
+			return expectEqual("len(daily_movies)", len(body.DailyMovies), 0)
+		}},
+	})
+}
+
+// TestMovieList 影片列表：状态、类型、标签过滤与排序、搜索。
+func TestMovieList(t *testing.T) {
+	today := nowJST()
+	date := func(offset int) string { return today.AddDate(0, 0, offset).Format("2006-01-02") }
+	runHTTPCases(t, []httpCase{
+		{"影片列表：showing 排除已下映影片", "/api/movies?status=showing", func(r testResponse) error {
+			var body testMovieList
+			if err := expectJSON(r, &body); err != nil {
+				return err
+			}
+			for _, m := range body.Items {
+				if m.ID == 20 {
+					return fmt.Errorf("movie %d has no current schedules but is listed", m.ID)
+				}
+			}
+			return expectEqual("len(items)", len(body.Items), 15)
+		}},
+		{"影片列表：kind=all 包含全部影片、样例数据中没有活动", "/api/movies?kind=all", func(r testResponse) error {
+			var body testMovieList
+			if err := expectJSON(r, &body); err != nil {
+				return err
+			}
+			return expectEqual("len(items)", len(body.Items), fixtureMovieCount)
+		}},
+		{"影片列表：按策展标签过滤", "/api/movies?tag=" + url.QueryEscape("小津安二郎特集"), func(r testResponse) error {
+			var body testMovieList
+			if err := expectJSON(r, &body); err != nil {
+				return err
+			}
+			ids := make([]uint, 0, len(body.Items))
+			for _, it := range body.Items {
+				ids = append(ids, it.ID)
+			}
+			return expectEqual("ids", fmt.Sprint(ids), "[1 2 20]")
+		}},
+		{"影片列表：空白标签", "/api/movies?tag=%20", func(r testResponse) error {
+			return expectStatus(r, http.StatusBadRequest)
+		}},
+		{"影片列表：非法 kind", "/api/movies?kind=concert", func(r testResponse) error {
+			return expectStatus(r, http.StatusBadRequest)
+		}},
+		{"影片列表：incoming", "/api/movies?status=incoming", func(r testResponse) error {
+			var body testMovieList
+			if err := expectJSON(r, &body); err != nil {
+				return err
+			}
+			return expectEqual("ids", movieItemIDs(body.Items), []uint{15, 16, 17, 18})
+		}},
+		{"影片列表：incoming 在开映前一天没有排片", "/api/movies?status=incoming&date=" + date(fixtureIncomingDay-1), func(r testResponse) error {
+			var body testMovieList
+			if err := expectJSON(r, &body); err != nil {
+				return err
+			}
+			return expectEqual("len(items)", len(body.Items), 0)
+		}},
+		{"影片列表：按 IMDb 评分倒序", "/api/movies?sort=imdb_rating", func(r testResponse) error {
+			var body testMovieList
+			if err := expectJSON(r, &body); err != nil {
+				return err
+			}
+			if err := expectEqual("len(items)", len(body.Items), fixtureMovieCount); err != nil {
+				return err
+			}
+			if !sort.SliceIsSorted(body.Items, func(i, j int) bool { return body.Items[i].IMDBRating > body.Items[j].IMDBRating }) {
+				return fmt.Errorf("items are not sorted by imdb_rating desc")
+			}
+			return nil
+		}},
+		{"影片列表：标题搜索", "/api/movies?q=movie%2007", func(r testResponse) error {
+			var body testMovieList
+			if err := expectJSON(r, &body); err != nil {
+				return err
+			}
+			return expectEqual("ids", movieItemIDs(body.Items), []uint{7})
+		}},
+		// 查询与片名两侧都规范化：平假名 / 全角数字命中日文标题，大小写与标点差异忽略
+		{"影片列表：日文标题规范化搜索", "/api/movies?q=" + url.QueryEscape("てすと映画０７"), func(r testResponse) error {
+			var body testMovieList
+			if err := expectJSON(r, &body); err != nil {
+				return err
+			}
+			return expectEqual("ids", movieItemIDs(body.Items), []uint{7})
+		}},
+		{"影片列表：英文标题忽略大小写与标点", "/api/movies?q=" + url.QueryEscape("FIXTURE・MOVIE-07"), func(r testResponse) error {
+			var body testMovieList
+			if err := expectJSON(r, &body); err != nil {
+				return err
+			}
+			return expectEqual("ids", movieItemIDs(body.Items), []uint{7})
+		}},
+		{"影片列表：中文标题搜索", "/api/movies?q=" + url.QueryEscape("测试影片07"), func(r testResponse) error {
+			var body testMovieList
+			if err := expectJSON(r, &body); err != nil {
+				return err
+			}
+			return expectEqual("ids", movieItemIDs(body.Items), []uint{7})
+		}},
+		{"影片列表：二元组都在但不连续时不命中", "/api/movies?q=" + url.QueryEscape("映画07テスト"), func(r testResponse) error {
+			var body testMovieList
+			if err := expectJSON(r, &body); err != nil {
+				return err
+			}
+			return expectEqual("ids", movieItemIDs(body.Items), []uint{})
+		}},
+		{"影片列表：唯一在映影院名", "/api/movies?status=incoming", func(r testResponse) error {
+			var body testMovieList
+			if err := expectJSON(r, &body); err != nil {
+				return err
+			}
+			for _, m := range body.Items {
+				if m.CinemaCountCurrent == 1 && m.PrimaryCinemaName == "" {
+					return fmt.Errorf("movie %d has one current cinema but no primary_cinema_name", m.ID)
+				}
+			}
+			return nil
+		}},
+	})
+}
+
+// TestScheduleList 全城排片：过滤与排序。
+func TestScheduleList(t *testing.T) {
+	today := nowJST()
+	date := func(offset int) string { return today.AddDate(0, 0, offset).Format("2006-01-02") }
+	runHTTPCases(t, []httpCase{
+		{"全城排片：按区过滤并按开场时间排序", "/api/schedules?district=新宿区", func(r testResponse) error {
+			var body struct {
+				Items []ScheduleEntry `json:"items"`
+			}
+			if err := expectJSON(r, &body); err != nil {
+				return err
+			}
+			for _, it := range body.Items {
+				if it.CinemaDistrict != "新宿区" {
+					return fmt.Errorf("schedule %d district = %q", it.ID, it.CinemaDistrict)
+				}
+			}
+			if !sort.SliceIsSorted(body.Items, func(i, j int) bool {
+				return startTimeMinutes(body.Items[i].StartTime) < startTimeMinutes(body.Items[j].StartTime)
+			}) {
+				return fmt.Errorf("items are not sorted by start_time")
+			}
+			return expectEqual("len(items)", len(body.Items), 2*len(fixtureShowTimes))
+		}},
+		{"全城排片：按影片过滤", "/api/schedules?movie_id=15&date=" + date(fixtureIncomingDay), func(r testResponse) error {
+			var body struct {
+				Items []ScheduleEntry `json:"items"`
+			}
+			if err := expectJSON(r, &body); err != nil {
+				return err
+			}
+			if len(body.Items) == 0 {
+				return fmt.Errorf("no schedules for movie 15")
+			}
+			for _, it := range body.Items {
+				if it.MovieID != 15 {
+					return fmt.Errorf("schedule %d movie_id = %d", it.ID, it.MovieID)
+				}
+				// 样例影片没有原始语言，也没有格式标注：只能是 unknown
+				if it.AudioHint != AudioHintUnknown {
+					return fmt.Errorf("schedule %d audio_hint = %q", it.ID, it.AudioHint)
+				}
+			}
+			return nil
+		}},
+		{"全城排片：非法 movie_id", "/api/schedules?movie_id=abc", func(r testResponse) error {
+			return expectStatus(r, http.StatusBadRequest)
+		}},
+	})
+}
+
+// TestMovieDetail 影片详情：排片窗口、格式与参数校验。
+func TestMovieDetail(t *testing.T) {
+	today := nowJST()
+	date := func(offset int) string { return today.AddDate(0, 0, offset).Format("2006-01-02") }
+	runHTTPCases(t, []httpCase{
+		{"影片详情：不存在", "/api/movies/9999", func(r testResponse) error {
+			return expectStatus(r, http.StatusNotFound)
+		}},
+		{"影片详情：非数字 ID", "/api/movies/abc", func(r testResponse) error {
+			return expectStatus(r, http.StatusNotFound)
+		}},
+		{"影片详情：默认 7 天窗口", "/api/movies/1", func(r testResponse) error {
+			var body MovieDetail
+			if err := expectJSON(r, &body); err != nil {
+				return err
+			}
+			if len(body.Cinemas) == 0 {
+				return fmt.Errorf("no cinemas")
+			}
+			// ScheduleDay.Date 为 YYYY-MM-DD，Label 为 "1/23 (金)"
+			window := make(map[string]string)
+			for i := 0; i < movieScheduleDefaultDays; i++ {
+				window[date(i)] = scheduleDayLabel(today.AddDate(0, 0, i))
+			}
+			for _, cin := range body.Cinemas {
+				for _, day := range cin.Schedule {
+					label, ok := window[day.Date]
+					if !ok {
+						return fmt.Errorf("cinema %d schedule date %s outside window", cin.ID, day.Date)
+					}
+					if err := expectEqual("label of "+day.Date, day.Label, label); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		}},
+		{"影片详情：format=legacy 的日期仍为 1/2 格式", "/api/movies/1?format=legacy", func(r testResponse) error {
+			var body MovieDetail
+			if err := expectJSON(r, &body); err != nil {
+				return err
+			}
+			if len(body.Cinemas) == 0 || len(body.Cinemas[0].Schedule) == 0 {
+				return fmt.Errorf("no schedule")
+			}
+			for _, cin := range body.Cinemas {
+				for _, day := range cin.Schedule {
+					if !strings.HasPrefix(day.Label, day.Date+" (") || strings.Contains(day.Date, "-") {
+						return fmt.Errorf("cinema %d: date %q, label %q", cin.ID, day.Date, day.Label)
+					}
+				}
+			}
+			return nil
+		}},
+		{"影片详情：非法 format", "/api/movies/1?format=short", func(r testResponse) error {
+			return expectStatus(r, http.StatusBadRequest)
+		}},
+		{"影片详情：已下映影片只有过去的影院", "/api/movies/20", func(r testResponse) error {
+			var body MovieDetail
+			if err := expectJSON(r, &body); err != nil {
+				return err
+			}
+			if len(body.Cinemas) == 0 {
+				return fmt.Errorf("no cinemas")
+			}
+			for _, cin := range body.Cinemas {
+				if !cin.PastOnly {
+					return fmt.Errorf("cinema %d past_only = false", cin.ID)
+				}
+			}
+			return nil
+		}},
+		{"影片详情：续映影院在前、已结束的影院在后", fmt.Sprintf("/api/movies/%d", fixtureHoldoverMovie), func(r testResponse) error {
+			var body MovieDetail
+			if err := expectJSON(r, &body); err != nil {
+				return err
+			}
+			if len(body.Cinemas) < 2 {
+				return fmt.Errorf("want an ongoing cinema and at least one past-only cinema, got %d cinemas", len(body.Cinemas))
+			}
+			ongoing := body.Cinemas[0]
+			if err := firstError(
+				expectEqual("cinemas[0].id", ongoing.ID, 1),
+				expectEqual("cinemas[0].past_only", ongoing.PastOnly, false),
+				expectEqual("cinemas[0].last_date", ongoing.LastDate, date(fixtureFutureDays)),
+			); err != nil {
+				return err
+			}
+			if ongoing.FirstDate == "" || ongoing.FirstDate > date(0) {
+				return fmt.Errorf("cinemas[0].first_date = %q, want on or before %s", ongoing.FirstDate, date(0))
+			}
+			for _, cin := range body.Cinemas[1:] {
+				if !cin.PastOnly || cin.LastDate >= date(0) || cin.FirstDate > cin.LastDate {
+					return fmt.Errorf("cinema %d: past_only=%v first_date=%s last_date=%s", cin.ID, cin.PastOnly, cin.FirstDate, cin.LastDate)
+				}
+			}
+			return nil
+		}},
+		{"影片详情：非法 from", "/api/movies/1?from=2026-13-01", func(r testResponse) error {
+			return expectStatus(r, http.StatusBadRequest)
+		}},
+		{"影片详情：非法 days", "/api/movies/1?days=0", func(r testResponse) error {
+			return expectStatus(r, http.StatusBadRequest)
+		}},
+		{"影片详情：按 TMDB ID 查询", "/api/movies/by-tmdb/1003", func(r testResponse) error {
+			var body MovieDetail
+			if err := expectJSON(r, &body); err != nil {
+				return err
+			}
+			return expectEqual("id", body.ID, 3)
+		}},
+	})
+}
+
+// TestScheduleSorting 排序：乱序的场次按实际时刻、日期按先后、影院按 ID 输出（9:00 排在 10:40 之前，1/9 排在 1/31 之前，12/31 排在 1/1 之前）。
+func TestScheduleSorting(t *testing.T) {
+	newTestRouter(t)
+	setTestClock(t, beforeMidnight)
+
+	d := func(m time.Month, day int) time.Time { return time.Date(2026, m, day, 0, 0, 0, 0, time.UTC) }
+	shuffled := []Schedule{
+		{ID: 1, MovieID: 1, CinemaID: 3, PlayDate: d(2, 1), StartTime: "18:20"},
+		{ID: 2, MovieID: 1, CinemaID: 1, PlayDate: d(1, 31), StartTime: "25:10"},
+		{ID: 3, MovieID: 1, CinemaID: 1, PlayDate: d(1, 31), StartTime: "10:40"},
+		{ID: 4, MovieID: 1, CinemaID: 3, PlayDate: d(1, 9), StartTime: "9:00"},
+		{ID: 5, MovieID: 1, CinemaID: 1, PlayDate: d(1, 9), StartTime: "18:20"},
+		{ID: 6, MovieID: 1, CinemaID: 1, PlayDate: d(1, 31), StartTime: "9:00"},
+		{ID: 7, MovieID: 2, CinemaID: 1, PlayDate: d(1, 31), StartTime: "12:00"},
+	}
+	// 跨年：12/31 与次年 1/1 按日期先后，label 不带年份
+	var yearEnd []string
+	for _, cin := range cinemasFromSchedules([]Schedule{
+		{ID: 8, MovieID: 1, CinemaID: 2, PlayDate: d(1, 1).AddDate(1, 0, 0), StartTime: "10:00"},
+		{ID: 9, MovieID: 1, CinemaID: 2, PlayDate: d(12, 31), StartTime: "10:00"},
+	}) {
+		for _, day := range cin.Schedule {
+			yearEnd = append(yearEnd, day.Date+" "+day.Label)
+		}
+	}
+	var cinemaIDs, cinemaDates []string
+	for _, cin := range cinemasFromSchedules(append([]Schedule(nil), shuffled[:6]...)) {
+		cinemaIDs = append(cinemaIDs, fmt.Sprint(cin.ID))
+		for _, day := range cin.Schedule {
+			cinemaDates = append(cinemaDates, day.Date+" "+strings.Join(day.Times, "/"))
+		}
+	}
+	movies := map[uint]Movie{1: {ID: 1, TitleCN: "甲"}, 2: {ID: 2, TitleCN: "乙"}}
+	var daily []string
+	for _, dm := range groupDailyMovies([]Schedule{shuffled[1], shuffled[6], shuffled[2], shuffled[5]}, movies, "") {
+		daily = append(daily, fmt.Sprintf("%d:%s", dm.ID, strings.Join(dm.Times, "/")))
+	}
+	if err := firstError(
+		expectEqual("cinemas", strings.Join(cinemaIDs, ","), "1,3"),
+		expectEqual("dates and times", strings.Join(cinemaDates, " | "), "2026-01-09 18:20 | 2026-01-31 9:00/10:40/25:10 | 2026-01-09 9:00 | 2026-02-01 18:20"),
+		expectEqual("year boundary", strings.Join(yearEnd, " | "), "2026-12-31 12/31 (木) | 2027-01-01 1/1 (金)"),
+		expectEqual("daily movies", strings.Join(daily, " "), "1:9:00/10:40/25:10 2:12:00")); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestMovieListStatsMatchLegacy 影片列表排片聚合：最早排片日、影院数与唯一在映影院名与逐部查询的旧写法逐项一致（今天、过去 + 未来、会員限定、多馆、无排片）。
+func TestMovieListStatsMatchLegacy(t *testing.T) {
+	newTestRouter(t)
+	now := testNoon
+	setTestClock(t, now)
+
+	day := func(d int) time.Time { return time.Date(2026, 1, d, 0, 0, 0, 0, time.UTC) }
+	movies := make([]Movie, 5)
+	for i := range movies {
+		movies[i] = Movie{TitleJP: fmt.Sprintf("テスト聚合%d", i+1), Status: "showing"}
+		if err := db.Create(&movies[i]).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	seed := []Schedule{
+		{MovieID: movies[0].ID, CinemaID: 1, PlayDate: day(27), StartTime: "20:00"},                    // 只有今天一馆
+		{MovieID: movies[1].ID, CinemaID: 2, PlayDate: day(20), StartTime: "10:00"},                    // 过去在另一馆
+		{MovieID: movies[1].ID, CinemaID: 3, PlayDate: day(30), StartTime: "10:00"},                    // 未来只剩一馆
+		{MovieID: movies[2].ID, CinemaID: 1, PlayDate: day(29), StartTime: "10:00", MembersOnly: true}, // 会員限定不计入
+		{MovieID: movies[2].ID, CinemaID: 2, PlayDate: day(21), StartTime: "10:00"},
+		{MovieID: movies[3].ID, CinemaID: 1, PlayDate: day(28), StartTime: "10:00"}, // 两馆在映
+		{MovieID: movies[3].ID, CinemaID: 2, PlayDate: day(31), StartTime: "10:00"},
+	}
+	if err := db.Create(&seed).Error; err != nil {
+		t.Fatal(err)
+	}
+	ids := make([]uint, 0, len(movies))
+	for _, m := range movies {
+		ids = append(ids, m.ID)
+	}
+	today := todayJST()
+	stats, err := loadMovieScheduleStats(ids, today)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(rec)
+	c.Request = httptest.NewRequest(http.MethodGet, "/api/movies?kind=all", nil)
+	listMoviesHandler(c)
+	var body testMovieList
+	if err := expectJSON(testResponse{Status: rec.Code, Body: rec.Body.Bytes()}, &body); err != nil {
+		t.Fatal(err)
+	}
+	items := make(map[uint]MovieItem, len(body.Items))
+	for _, it := range body.Items {
+		items[it.ID] = it
+	}
+	format := func(earliest string, total, current int64, name string) string {
+		return fmt.Sprintf("%s/%d/%d/%s", earliest, total, current, name)
+	}
+	old := make([]string, 0, len(ids))
+	aggregated := make([]string, 0, len(ids))
+	served := make([]string, 0, len(ids))
+	for _, id := range ids {
+		want, got, it := legacyMovieStats(id, today), stats[id], items[id]
+		old = append(old, format(want.EarliestDate, want.TotalCount, want.CurrentCount, want.CinemaName))
+		aggregated = append(aggregated, format(got.EarliestDate, got.TotalCount, got.CurrentCount, got.CinemaName))
+		served = append(served, format(it.EarliestScheduleDate, int64(it.CinemaCountTotal), int64(it.CinemaCountCurrent), it.PrimaryCinemaName))
+	}
+	var names [4]string
+	for i, id := range []uint{1, 2, 3} {
+		var cin Cinema
+		db.First(&cin, id)
+		names[i+1] = cin.NameJP
+	}
+	if err := firstError(
+		expectEqual("old", strings.Join(old, ","), strings.Join([]string{
+			format("2026-01-27", 1, 1, names[1]), format("2026-01-20", 2, 1, names[3]), format("2026-01-21", 1, 0, ""),
+			format("2026-01-28", 2, 2, ""), format("", 0, 0, "")}, ",")),
+		expectEqual("aggregated", strings.Join(aggregated, ","), strings.Join(old, ",")),
+		expectEqual("served", strings.Join(served, ","), strings.Join(old, ","))); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestCinemaDetailByEigaID 影院详情：按 eiga.com 影院编号查询，响应与按 ID 查询一致，未知编号返回 404。
+func TestCinemaDetailByEigaID(t *testing.T) {
+	newTestRouter(t)
+	now := testNoon
+	setTestClock(t, now)
+
+	cinema := Cinema{NameJP: "テスト外部ID館", EigaSlug: "990458", EigaURL: "https://eiga.com/theater/13/130201/990458/"}
+	if err := db.Create(&cinema).Error; err != nil {
+		t.Fatal(err)
+	}
+	get := func(handler gin.HandlerFunc, path string, params gin.Params) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(rec)
+		c.Request = httptest.NewRequest(http.MethodGet, path, nil)
+		c.Params = params
+		handler(c)
+		return rec
+	}
+	byID := get(getCinemaHandler, fmt.Sprintf("/api/cinemas/%d?date=2026-03-01", cinema.ID), gin.Params{{Key: "id", Value: fmt.Sprint(cinema.ID)}})
+	bySlug := get(getCinemaByEigaHandler, "/api/cinemas/by-eiga/990458?date=2026-03-01", gin.Params{{Key: "eiga_id", Value: "990458"}})
+	unknown := get(getCinemaByEigaHandler, "/api/cinemas/by-eiga/990459", gin.Params{{Key: "eiga_id", Value: "990459"}})
+	if err := firstError(
+		expectEqual("by slug status", bySlug.Code, http.StatusOK),
+		expectEqual("same body", bySlug.Body.String(), byID.Body.String()),
+		expectEqual("unknown", unknown.Code, http.StatusNotFound)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// legacyMovieStats 聚合查询之前影片列表的逐部查法（最早排片、历史 / 当前影院数、唯一在映影院名），
+// 只用来对比 loadMovieScheduleStats 的结果；会員限定场次同样不计入。
+func legacyMovieStats(movieID uint, today string) movieScheduleStats {
+	st := movieScheduleStats{MovieID: movieID}
+	var first Schedule
+	if err := db.Where("movie_id = ? AND members_only = ?", movieID, false).Order("play_date ASC").First(&first).Error; err == nil {
+		st.EarliestDate = first.PlayDate.Format("2006-01-02")
+	}
+	db.Model(&Schedule{}).Where("movie_id = ? AND members_only = ?", movieID, false).Distinct("cinema_id").Count(&st.TotalCount)
+	db.Model(&Schedule{}).Where("movie_id = ? AND members_only = ? AND date(play_date) >= ?", movieID, false, today).Distinct("cinema_id").Count(&st.CurrentCount)
+	if st.CurrentCount == 1 {
+		var current Schedule
+		var cin Cinema
+		if db.Where("movie_id = ? AND members_only = ? AND date(play_date) >= ?", movieID, false, today).First(&current).Error == nil &&
+			db.First(&cin, current.CinemaID).Error == nil {
+			st.CinemaID, st.CinemaName = cin.ID, cin.NameJP
+		}
+	}
+	return st
+}
+
+// stableOrderPaths 列表类接口：样例数据下重复请求必须返回逐字节相同的 JSON（map 遍历与 SQL 默认顺序都不能泄漏到输出）。
+var stableOrderPaths = []string{
+	"/api/cinemas",
+	"/api/cinemas?sort=kana&group=kana",
+	"/api/cinemas?sort=intensity",
+	"/api/cinemas/1?days=7",
+	"/api/cinemas/nearby?lat=35.69&lng=139.70",
+	"/api/cinemas/1/recommended-movies",
+	"/api/movies",
+	"/api/movies?sort=imdb_rating",
+	"/api/movies/1",
+	"/api/schedules",
+	"/api/timetable",
+	"/api/tags",
+	"/api/admin/search?q=%E6%96%B0%E5%AE%BF",
+}
+
+// stableOrderRepeats 每个接口重复请求的次数；Go 的 map 遍历顺序每次随机，几次之内就会暴露未排序的输出。
+const stableOrderRepeats = 5
+
+// TestStableOrder 重复请求列表接口，响应体逐字节一致。
+func TestStableOrder(t *testing.T) {
+	for _, path := range stableOrderPaths {
+		t.Run(path, func(t *testing.T) {
+			router := newTestRouter(t)
+			first := testGet(router, path)
+			if err := expectStatus(first, http.StatusOK); err != nil {
+				t.Fatal(err)
+			}
+			for i := 1; i < stableOrderRepeats; i++ {
+				if again := testGet(router, path); string(again.Body) != string(first.Body) {
+					t.Fatalf("response %d differs from the first one", i+1)
+				}
+			}
+		})
+	}
+}
+
+// TestCinemaListPagination 影院列表逐页拼接等于不分页的结果。
+func TestCinemaListPagination(t *testing.T) {
+	router := newTestRouter(t)
+	var full testCinemaList
+	if err := expectJSON(testGet(router, "/api/cinemas?sort=kana"), &full); err != nil {
+		t.Fatal(err)
+	}
+	var paged []uint
+	for page := 1; page <= (fixtureCinemaCount+1)/2; page++ {
+		var body testCinemaList
+		if err := expectJSON(testGet(router, fmt.Sprintf("/api/cinemas?sort=kana&page=%d&page_size=2", page)), &body); err != nil {
+			t.Fatal(err)
+		}
+		for _, it := range body.Items {
+			paged = append(paged, it.ID)
+		}
+	}
+	want := make([]uint, 0, len(full.Items))
+	for _, it := range full.Items {
+		want = append(want, it.ID)
+	}
+	if err := expectEqual("paged ids", paged, want); err != nil {
+		t.Fatal(err)
+	}
+}
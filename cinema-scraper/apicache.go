@@ -0,0 +1,114 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ===========================
+// 模块：外部接口响应缓存（ApiCache）
+// 职责：
+// - 重复补全时不再为同样的请求消耗 TMDB / OMDb 配额：TMDB 搜索、各语言详情与 OMDb 查询的成功响应原样存入 api_caches，
+//   有效期（API_CACHE_TTL_DAYS，默认 30 天）内直接使用缓存，过期后重新请求并覆盖
+// - 缓存键不含 API Key（如 tmdb / search:ja-JP:ゴジラ），换 Key 后缓存仍可用
+// - 只缓存成功的响应：5xx / 429、OMDb 配额耗尽与 "Response":"False" 都不写入，下次照常重试
+// - 统计本次运行的命中 / 未命中次数，enrich-movies 结束时打印
+// 说明：命中缓存时不经过 TMDB 熔断器、也不检查 OMDb 配额，预先写好缓存即可离线跑补全流程。
+// ===========================
+
+// 缓存的接口提供方。
+const (
+	apiCacheProviderTMDB = "tmdb"
+	apiCacheProviderOMDb = "omdb"
+)
+
+// ApiCache 一条外部接口响应缓存。
+type ApiCache struct {
+	ID        uint      `gorm:"primaryKey"`
+	Provider  string    `gorm:"uniqueIndex:idx_api_cache_key"` // tmdb / omdb
+	Key       string    `gorm:"uniqueIndex:idx_api_cache_key"` // 请求的规范化描述，不含 API Key
+	Payload   string    `gorm:"type:text"`                     // 响应体（JSON 原文）
+	FetchedAt time.Time `gorm:"index"`
+}
+
+// apiCacheStats 本次运行的缓存命中 / 未命中次数（补全并发执行，使用原子计数）。
+var apiCacheStats struct {
+	hits, misses atomic.Int64
+}
+
+// apiCacheFresh 缓存是否仍在有效期内（纯函数）；ttlDays 为 0 时总是过期。
+func apiCacheFresh(fetchedAt, now time.Time, ttlDays int) bool {
+	return now.Before(fetchedAt.AddDate(0, 0, ttlDays))
+}
+
+// apiCacheGet 读取有效期内的缓存并计数；查询失败按未命中处理。
+func apiCacheGet(provider, key string, now time.Time) (string, bool) {
+	var entry ApiCache
+	err := db.Where("provider = ? AND key = ?", provider, key).Take(&entry).Error
+	if err == nil && apiCacheFresh(entry.FetchedAt, now, appConfig.APICacheTTLDays) {
+		apiCacheStats.hits.Add(1)
+		return entry.Payload, true
+	}
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		fmt.Printf("⚠️ 读取接口缓存失败 [%s %s]: %v\n", provider, key, err)
+	}
+	apiCacheStats.misses.Add(1)
+	return "", false
+}
+
+// apiCachePut 写入或刷新一条缓存；失败只打印日志，不影响补全。
+func apiCachePut(provider, key, payload string, now time.Time) {
+	entry := ApiCache{Provider: provider, Key: key, Payload: payload, FetchedAt: now}
+	err := db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "provider"}, {Name: "key"}},
+		DoUpdates: clause.AssignmentColumns([]string{"payload", "fetched_at"}),
+	}).Create(&entry).Error
+	if err != nil {
+		fmt.Printf("⚠️ 写入接口缓存失败 [%s %s]: %v\n", provider, key, err)
+	}
+}
+
+// tmdbGetCached 带缓存的 TMDB GET：命中时直接返回缓存的响应体（状态视为 200）；
+// 未命中时经熔断器请求（见 tmdbbreaker.go），5xx / 429 返回错误，200 的响应写入缓存，其他状态原样返回不缓存。
+func tmdbGetCached(key, apiURL, userAgent string) ([]byte, int, error) {
+	now := time.Now()
+	if payload, ok := apiCacheGet(apiCacheProviderTMDB, key, now); ok {
+		return []byte(payload), http.StatusOK, nil
+	}
+	resp, err := tmdbGet(apiURL, userAgent)
+	if err != nil {
+		return nil, 0, err
+	}
+	if resp == nil {
+		return nil, 0, errors.New("empty response")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests {
+		return nil, resp.StatusCode, fmt.Errorf("tmdb returned status %d", resp.StatusCode)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, resp.StatusCode, err
+	}
+	if resp.StatusCode == http.StatusOK {
+		apiCachePut(apiCacheProviderTMDB, key, string(body), now)
+	}
+	return body, resp.StatusCode, nil
+}
+
+// printApiCacheSummary 打印本次运行的缓存命中情况。
+func printApiCacheSummary() {
+	hits, misses := apiCacheStats.hits.Load(), apiCacheStats.misses.Load()
+	rate := 0.0
+	if total := hits + misses; total > 0 {
+		rate = float64(hits) * 100 / float64(total)
+	}
+	fmt.Printf("📊 接口缓存：命中 %d 次，未命中 %d 次（命中率 %.0f%%，有效期 %d 天）\n", hits, misses, rate, appConfig.APICacheTTLDays)
+}
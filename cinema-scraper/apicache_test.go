@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// TestAPICacheOfflineEnrichment 接口缓存：预先写入 TMDB / OMDb 响应即可离线补全，过期条目重新请求。
+func TestAPICacheOfflineEnrichment(t *testing.T) {
+	newTestRouter(t)
+	now := testNoon
+	setTestClock(t, now)
+
+	const title, tmdbID, imdbID = "テスト離線補完", 990001, "tt9900001"
+	fetched := time.Now()
+	detail := func(name, extra string) string {
+		return fmt.Sprintf(`{"imdb_id":%q,"title":%q,"overview":"x","release_date":"2025-10-03","runtime":101,
+					"vote_average":7.4,"vote_count":820,"original_language":"ja",
+					"credits":{"cast":[],"crew":[{"name":"監督太郎","job":"Director"}]},
+					"videos":{"results":[{"key":"trailerKey","site":"YouTube","type":"Trailer","official":true,"iso_639_1":"ja"}]}%s}`,
+			imdbID, name, extra)
+	}
+	entries := map[string]string{
+		"search:ja-JP:" + title:                              fmt.Sprintf(`{"results":[{"id":%d,"title":%q,"original_title":%q,"release_date":"2025-10-03"}]}`, tmdbID, title, title),
+		fmt.Sprintf("movie:%d:zh-CN:credits,videos", tmdbID): detail("离线补全", ""),
+		fmt.Sprintf("movie:%d:ja-JP:credits,videos,release_dates", tmdbID): detail(title,
+			`,"release_dates":{"results":[{"iso_3166_1":"JP","release_dates":[{"release_date":"2025-11-14T00:00:00.000Z","type":3}]}]}`),
+		fmt.Sprintf("movie:%d:en-US:credits,videos", tmdbID): detail("Offline Enrichment", ""),
+	}
+	for key, payload := range entries {
+		apiCachePut(apiCacheProviderTMDB, key, payload, fetched)
+	}
+	apiCachePut(apiCacheProviderOMDb, imdbID, `{"Response":"True","imdbRating":"7.2","imdbVotes":"1,024",
+				"Ratings":[{"Source":"Rotten Tomatoes","Value":"91%"}]}`, fetched)
+
+	hitsBefore, missesBefore := apiCacheStats.hits.Load(), apiCacheStats.misses.Load()
+	movie := Movie{TitleJP: title, Status: "incoming"}
+	if err := db.Create(&movie).Error; err != nil {
+		t.Fatal(err)
+	}
+	enrichMovieRatings(appConfig, &movie, false)
+	hits, misses := apiCacheStats.hits.Load()-hitsBefore, apiCacheStats.misses.Load()-missesBefore
+
+	ttl := appConfig.APICacheTTLDays
+	if err := firstError(
+		expectEqual("cache traffic", [2]int64{hits, misses}, [2]int64{5, 0}),
+		expectEqual("titles", [3]string{movie.TitleCN, movie.TitleJP, movie.TitleEN}, [3]string{"离线补全", title, "Offline Enrichment"}),
+		expectEqual("tmdb", [2]int{movie.TMDBID, movie.Runtime}, [2]int{tmdbID, 101}),
+		expectEqual("jp release", jpReleaseDateString(movie), "2025-11-14"),
+		expectEqual("omdb", [2]int{movie.IMDBVotes, movie.RTRating}, [2]int{1024, 91}),
+		expectEqual("trailer", movie.TrailerURL, "https://www.youtube.com/watch?v=trailerKey"),
+		expectEqual("fresh", apiCacheFresh(now.AddDate(0, 0, -ttl+1), now, ttl), true),
+		expectEqual("expired", apiCacheFresh(now.AddDate(0, 0, -ttl), now, ttl), false),
+		expectEqual("ttl 0", apiCacheFresh(now, now, 0), false)); err != nil {
+		t.Fatal(err)
+	}
+}
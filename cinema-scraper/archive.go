@@ -0,0 +1,171 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ===========================
+// 模块：历史排片归档（ScheduleArchive）
+// 职责：
+// - 清理旧排片时先把行搬进归档表，而不是直接删除
+// - 为 archive=true 模式提供“热表 + 归档表”合并查询，回答“某影院一月份放过什么”
+// - prune-schedules 命令：把早于 N 天前（JST）的排片移出热表，随后按剩余排片更新影片状态
+// 说明：归档行与 Schedule 字段一致，序列化复用同一套聚合函数。
+// 调用方式：
+//   go run . prune-schedules --keep-days 14 [--dry-run] [--force]
+// ===========================
+
+// defaultScheduleKeepDays prune-schedules 默认保留的天数。
+const defaultScheduleKeepDays = 14
+
+// ScheduleArchive 归档排片表：结构与 Schedule 相同，额外记录原始 ID 与归档时间。
+type ScheduleArchive struct {
+	ID           uint      `gorm:"primaryKey"`
+	OriginalID   uint      `gorm:"index"` // 原 Schedule.ID
+	MovieID      uint      `gorm:"index"`
+	CinemaID     uint      `gorm:"index"`
+	PlayDate     time.Time `gorm:"index"`
+	StartTime    string
+	Availability string
+	EventType    string
+	Format       string
+	Note         string
+	MembersOnly  bool `gorm:"default:false"`
+	CreatedAt    time.Time
+	ArchivedAt   time.Time
+	DeletedAt    gorm.DeletedAt `gorm:"index"` // 随影片一起软删除 / 恢复
+}
+
+// toSchedule 将归档行还原为 Schedule，便于复用聚合逻辑。
+func (a ScheduleArchive) toSchedule() Schedule {
+	return Schedule{
+		ID:           a.OriginalID,
+		MovieID:      a.MovieID,
+		CinemaID:     a.CinemaID,
+		PlayDate:     a.PlayDate,
+		StartTime:    a.StartTime,
+		Availability: a.Availability,
+		EventType:    a.EventType,
+		Format:       a.Format,
+		Note:         a.Note,
+		MembersOnly:  a.MembersOnly,
+		CreatedAt:    a.CreatedAt,
+	}
+}
+
+// archiveSchedulesBefore 将 play_date 早于 cutoff（YYYY-MM-DD）的排片搬入归档表并从热表删除，
+// 同时更新放映跨度汇总，在同一事务内完成，返回归档条数。
+func archiveSchedulesBefore(cutoff string) (int64, error) {
+	var archived int64
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var schedules []Schedule
+		if err := tx.Where("date(play_date) < ?", cutoff).Find(&schedules).Error; err != nil {
+			return err
+		}
+		if len(schedules) == 0 {
+			return nil
+		}
+
+		now := time.Now()
+		rows := make([]ScheduleArchive, 0, len(schedules))
+		ids := make([]uint, 0, len(schedules))
+		for _, s := range schedules {
+			rows = append(rows, ScheduleArchive{
+				OriginalID:   s.ID,
+				MovieID:      s.MovieID,
+				CinemaID:     s.CinemaID,
+				PlayDate:     s.PlayDate,
+				StartTime:    s.StartTime,
+				Availability: s.Availability,
+				EventType:    s.EventType,
+				Format:       s.Format,
+				Note:         s.Note,
+				MembersOnly:  s.MembersOnly,
+				CreatedAt:    s.CreatedAt,
+				ArchivedAt:   now,
+			})
+			ids = append(ids, s.ID)
+		}
+		if err := tx.CreateInBatches(rows, 500).Error; err != nil {
+			return err
+		}
+		res := tx.Unscoped().Where("id IN ?", ids).Delete(&Schedule{})
+		if res.Error != nil {
+			return res.Error
+		}
+		archived = res.RowsAffected
+		// 被清理的场次计入放映跨度汇总（见 runrollup.go）
+		return finalizeRunSummaries(tx, schedules)
+	})
+	return archived, err
+}
+
+// parseKeepDaysFlag 解析 --keep-days（默认 defaultScheduleKeepDays）：至少 1 天，且不少于下映宽限期，
+// 否则刚下映、仍算 showing 的影片会因排片被清理而提前变成 unplanned。
+func parseKeepDaysFlag(args []string, leavingDays int) (int, error) {
+	days := defaultScheduleKeepDays
+	if v, ok := flagValue(args, "--keep-days"); ok {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return 0, fmt.Errorf("--keep-days 应为正整数: %q", v)
+		}
+		days = n
+	}
+	if days < 1 {
+		return 0, fmt.Errorf("--keep-days 至少为 1，当前为 %d", days)
+	}
+	if days < leavingDays {
+		return 0, fmt.Errorf("--keep-days=%d 小于下映宽限期 leaving_days=%d", days, leavingDays)
+	}
+	return days, nil
+}
+
+// scheduleKeepCutoff 保留 keepDays 天时的截止日期（YYYY-MM-DD，JST）：早于该日期的排片被清理。
+func scheduleKeepCutoff(today string, keepDays int) string {
+	t, err := time.Parse("2006-01-02", today)
+	if err != nil {
+		return today
+	}
+	return t.AddDate(0, 0, -keepDays).Format("2006-01-02")
+}
+
+// countSchedulesBefore prune-schedules --dry-run：统计早于 cutoff 的排片数。
+func countSchedulesBefore(cutoff string) (int64, error) {
+	var n int64
+	err := db.Model(&Schedule{}).Where("date(play_date) < ?", cutoff).Count(&n).Error
+	return n, err
+}
+
+// loadSchedulesWithArchive 用同一组条件同时查询热表与归档表，合并返回。
+func loadSchedulesWithArchive(scope func(tx *gorm.DB) *gorm.DB) ([]Schedule, error) {
+	var schedules []Schedule
+	if err := scope(db.Model(&Schedule{})).Order("id").Find(&schedules).Error; err != nil {
+		return nil, err
+	}
+	var archived []ScheduleArchive
+	if err := scope(db.Model(&ScheduleArchive{})).Order("id").Find(&archived).Error; err != nil {
+		return nil, err
+	}
+	for _, a := range archived {
+		schedules = append(schedules, a.toSchedule())
+	}
+	return schedules, nil
+}
+
+// buildArchivedCinemasForMovie archive 模式下影片在 [from, to] 窗口内的多馆排片（含已归档的历史场次）。
+// first_date / last_date 取自放映跨度汇总（含已清理的排片）；membersOnly 为 false 时不含会員限定场次。
+func buildArchivedCinemasForMovie(movieID uint, from, to string, membersOnly bool) []MovieCinemaSchedule {
+	schedules, err := loadSchedulesWithArchive(func(tx *gorm.DB) *gorm.DB {
+		return applyMembersOnlyFilter(tx, membersOnly).Where("movie_id = ? AND date(play_date) >= ? AND date(play_date) <= ?", movieID, from, to)
+	})
+	if err != nil {
+		return []MovieCinemaSchedule{}
+	}
+	out := cinemasFromSchedules(schedules)
+	applyCinemaRuns(out, loadMovieRunSummaries(movieID))
+	return out
+}
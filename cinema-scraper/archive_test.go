@@ -0,0 +1,25 @@
+package main
+
+import (
+	"testing"
+)
+
+// TestParseKeepDaysFlag prune-schedules：--keep-days 至少 1 天且不少于下映宽限期，截止日期按 JST 的今天回推。
+func TestParseKeepDaysFlag(t *testing.T) {
+	setTestClock(t, beforeMidnight)
+
+	def, _ := parseKeepDaysFlag(nil, 0)
+	keep, _ := parseKeepDaysFlag([]string{"--keep-days", "7"}, 3)
+	_, zeroErr := parseKeepDaysFlag([]string{"--keep-days=0"}, 0)
+	_, graceErr := parseKeepDaysFlag([]string{"--keep-days=2"}, 3)
+	_, badErr := parseKeepDaysFlag([]string{"--keep-days=two"}, 0)
+	if err := firstError(
+		expectEqual("default", def, defaultScheduleKeepDays),
+		expectEqual("explicit", keep, 7),
+		expectEqual("zero rejected", zeroErr != nil, true),
+		expectEqual("below grace rejected", graceErr != nil, true),
+		expectEqual("not a number", badErr != nil, true),
+		expectEqual("cutoff", scheduleKeepCutoff("2026-03-01", 14), "2026-02-15")); err != nil {
+		t.Fatal(err)
+	}
+}
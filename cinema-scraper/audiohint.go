@@ -0,0 +1,123 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ===========================
+// 模块：字幕 / 吹替推断（audio_hint）
+// 职责：
+// - 很多影院不标注 字幕 / 吹替，而动画、合家欢的外语片两个版本往往同时上映
+// - 场次没有格式标注时，按影片原始语言（TMDB original_language）推断 audio_hint，随场次一起输出
+// - 策展人可以为影片设置默认版本（Movie.AudioDefault），推断优先采用；但仍只输出 likely_*，不冒充影院的标注
+// 说明：只有影院明确标注（Schedule.Format）时才输出确定的 subbed / dubbed；其余都是推测或 unknown。
+// 调用方式：
+//   PATCH /api/admin/movies/:id/audio-default  {"audio_default":"subbed"}（空串清除）
+// ===========================
+
+// audio_hint 取值。
+const (
+	AudioHintSubbed       = "subbed"        // 影院标注 字幕
+	AudioHintDubbed       = "dubbed"        // 影院标注 吹替
+	AudioHintLikelySubbed = "likely_subbed" // 未标注，推测为字幕版
+	AudioHintLikelyDubbed = "likely_dubbed" // 未标注，策展人默认吹替版
+	AudioHintOriginal     = "original"      // 日语原声作品，不涉及字幕 / 吹替
+	AudioHintUnknown      = "unknown"       // 无法判断（原始语言未知，或动画 / 合家欢等两版并行的外语片）
+)
+
+// dualVersionGenres 外语片中字幕版与吹替版经常同时上映的类型（TMDB 各语言的类型名）。
+var dualVersionGenres = []string{"动画", "家庭", "アニメーション", "ファミリー", "Animation", "Family"}
+
+// hasDualVersionGenre 影片类型（逗号分隔）是否包含 dualVersionGenres 之一（纯函数）。
+func hasDualVersionGenre(genre string) bool {
+	for _, g := range strings.Split(genre, ",") {
+		g = strings.TrimSpace(g)
+		for _, dv := range dualVersionGenres {
+			if strings.EqualFold(g, dv) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// inferAudioHint 推断场次的 audio_hint（纯函数），依次：
+//  1. 影院标注了格式：原样输出 subbed / dubbed
+//  2. 日语原声：original（策展默认对日语片没有意义，不采用）
+//  3. 策展人设置了默认版本：likely_subbed / likely_dubbed
+//  4. 原始语言未知：unknown
+//  5. 外语片：动画 / 合家欢为 unknown，其余为 likely_subbed（东京的外语片绝大多数以字幕版上映）
+func inferAudioHint(format, originalLanguage, genre, curatorDefault string) string {
+	switch format {
+	case FormatSubbed:
+		return AudioHintSubbed
+	case FormatDubbed:
+		return AudioHintDubbed
+	}
+	lang := strings.ToLower(strings.TrimSpace(originalLanguage))
+	if lang == "ja" {
+		return AudioHintOriginal
+	}
+	switch curatorDefault {
+	case FormatSubbed:
+		return AudioHintLikelySubbed
+	case FormatDubbed:
+		return AudioHintLikelyDubbed
+	}
+	if lang == "" {
+		return AudioHintUnknown
+	}
+	if hasDualVersionGenre(genre) {
+		return AudioHintUnknown
+	}
+	return AudioHintLikelySubbed
+}
+
+// movieShowtime 带影片信息的场次：在 scheduleToShowtime 的基础上补充 audio_hint。
+func movieShowtime(s Schedule, m Movie) Showtime {
+	st := scheduleToShowtime(s)
+	st.AudioHint = inferAudioHint(s.Format, m.OriginalLanguage, m.Genre, m.AudioDefault)
+	return st
+}
+
+// audioDefaultRequest PATCH /api/admin/movies/:id/audio-default 的请求体。
+type audioDefaultRequest struct {
+	AudioDefault *string `json:"audio_default"`
+}
+
+// patchMovieAudioDefaultHandler 设置影片的默认版本（字幕 / 吹替），供没有格式标注的场次推断 audio_hint：
+// - PATCH /api/admin/movies/:id/audio-default  {"audio_default":"dubbed"}，空串清除
+func patchMovieAudioDefaultHandler(c *gin.Context) {
+	var req audioDefaultRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.AudioDefault == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+	value := strings.TrimSpace(*req.AudioDefault)
+	if value != "" && value != FormatSubbed && value != FormatDubbed {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid audio_default, expected subbed, dubbed or empty"})
+		return
+	}
+	var movie Movie
+	if err := db.First(&movie, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "movie not found"})
+		return
+	}
+	recordProvenance(&movie.ProvenanceJSON, SourceManual, "audio_default")
+	if err := db.Model(&movie).Updates(map[string]interface{}{
+		"audio_default":   value,
+		"provenance_json": movie.ProvenanceJSON,
+	}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update audio default"})
+		return
+	}
+	movie.AudioDefault = value
+	c.JSON(http.StatusOK, gin.H{
+		"id":                movie.ID,
+		"audio_default":     movie.AudioDefault,
+		"original_language": movie.OriginalLanguage,
+	})
+}
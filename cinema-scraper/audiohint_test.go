@@ -0,0 +1,23 @@
+package main
+
+import (
+	"testing"
+)
+
+// TestInferAudioHint 字幕 / 吹替推断：标注优先，其次策展默认，外语动画 / 合家欢不下结论。
+func TestInferAudioHint(t *testing.T) {
+	setTestClock(t, beforeMidnight)
+
+	if err := firstError(
+		expectEqual("badged subbed", inferAudioHint(FormatSubbed, "en", "动画", FormatDubbed), AudioHintSubbed),
+		expectEqual("badged dubbed", inferAudioHint(FormatDubbed, "", "", ""), AudioHintDubbed),
+		expectEqual("japanese", inferAudioHint("", "ja", "动画", FormatDubbed), AudioHintOriginal),
+		expectEqual("curator dubbed", inferAudioHint("", "en", "动画, 家庭", FormatDubbed), AudioHintLikelyDubbed),
+		expectEqual("curator subbed", inferAudioHint("", "", "", FormatSubbed), AudioHintLikelySubbed),
+		expectEqual("foreign drama", inferAudioHint("", "fr", "剧情", ""), AudioHintLikelySubbed),
+		expectEqual("foreign animation", inferAudioHint("", "en", "冒险, 动画", ""), AudioHintUnknown),
+		expectEqual("foreign family en", inferAudioHint("", "en", "Family", ""), AudioHintUnknown),
+		expectEqual("unknown language", inferAudioHint("", "", "剧情", ""), AudioHintUnknown)); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gocolly/colly/v2"
+)
+
+// ===========================
+// 模块：影院官网信息补全（enrich-cinemas）
+// 职责：
+// - 访问影院官网首页，取 og:description / meta description 作为影院简介（Desc）候选
+// - 取 og:image 作为建筑照片的兜底（仅在 BuildingPhoto 为空时写入）
+// - 人工策展的简介（来源为 manual，或没有来源记录的已有文本）永远不覆盖
+// 说明：官网是任意第三方站点，必须遵守 robots.txt、设置超时，单站失败不影响其他影院。
+// 调用方式：
+//   go run . enrich-cinemas
+// ===========================
+
+const (
+	cinemaSiteTimeout     = 15 * time.Second
+	cinemaSiteMaxBody     = 2 * 1024 * 1024 // 只需要 <head>，限制下载体积
+	cinemaDescMaxRunes    = 300
+	cinemaSiteRequestWait = 1 * time.Second // 站点之间的间隔，避免短时间内集中发起请求
+)
+
+// cinemaSiteMeta 从官网首页提取的候选信息。
+type cinemaSiteMeta struct {
+	Desc  string
+	Image string
+}
+
+// cleanSiteDescription 折叠空白并截断过长的简介（纯函数）。
+func cleanSiteDescription(s string) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if utf8.RuneCountInString(s) > cinemaDescMaxRunes {
+		s = string([]rune(s)[:cinemaDescMaxRunes]) + "…"
+	}
+	return s
+}
+
+// fetchCinemaSiteMeta 抓取官网首页的 meta 信息；每次使用独立的 Collector，互不影响。
+func fetchCinemaSiteMeta(website string) (cinemaSiteMeta, error) {
+	var meta cinemaSiteMeta
+	u, err := url.Parse(website)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return meta, fmt.Errorf("invalid website url %q", website)
+	}
+
+	c := colly.NewCollector()
+	c.IgnoreRobotsTxt = false
+	c.MaxBodySize = cinemaSiteMaxBody
+	c.UserAgent = "TokyoCinePath/1.1 (enrich-cinemas)"
+	c.SetRequestTimeout(cinemaSiteTimeout)
+
+	c.OnHTML("head", func(e *colly.HTMLElement) {
+		desc := e.ChildAttr(`meta[property="og:description"]`, "content")
+		if strings.TrimSpace(desc) == "" {
+			desc = e.ChildAttr(`meta[name="description"]`, "content")
+		}
+		meta.Desc = cleanSiteDescription(desc)
+		if img := strings.TrimSpace(e.ChildAttr(`meta[property="og:image"]`, "content")); img != "" {
+			meta.Image = e.Request.AbsoluteURL(img)
+		}
+	})
+	if err := c.Visit(website); err != nil {
+		return meta, err
+	}
+	return meta, nil
+}
+
+// siteMetaUpdates 决定需要写入的列（纯函数）：
+// - Desc：当前为空，或上次同样来自官网时才写入
+// - BuildingPhoto：当前为空时才用 og:image 兜底
+func siteMetaUpdates(cinema Cinema, meta cinemaSiteMeta) map[string]interface{} {
+	updates := make(map[string]interface{})
+	prov := parseProvenance(cinema.ProvenanceJSON)
+	if meta.Desc != "" && meta.Desc != cinema.Desc {
+		if cinema.Desc == "" || prov["desc"].Source == SourceWebsite {
+			updates["desc"] = meta.Desc
+		}
+	}
+	if meta.Image != "" && cinema.BuildingPhoto == "" && prov["building_photo"].Source != SourceManual {
+		updates["building_photo"] = meta.Image
+	}
+	return updates
+}
+
+// enrichCinemasFromWebsites 遍历有官网的影院补全简介与照片，返回（更新数，失败数）。
+func enrichCinemasFromWebsites() (int, int, error) {
+	var cinemas []Cinema
+	if err := db.Where("website <> ''").Order("id").Find(&cinemas).Error; err != nil {
+		return 0, 0, err
+	}
+	fmt.Printf("ℹ️ 共有 %d 家影院登记了官网。\n", len(cinemas))
+
+	updated, failed := 0, 0
+	for i := range cinemas {
+		cinema := &cinemas[i]
+		if i > 0 {
+			time.Sleep(cinemaSiteRequestWait)
+		}
+		ok := func() (ok bool) {
+			// 第三方站点返回异常内容导致 panic 时，只跳过这一家
+			defer recoverAndLog("官网补全 " + cinema.NameJP)
+			meta, err := fetchCinemaSiteMeta(cinema.Website)
+			if err != nil {
+				fmt.Printf("⚠️ 官网抓取失败 [%s] %s: %v\n", cinema.NameJP, cinema.Website, err)
+				return false
+			}
+			updates := siteMetaUpdates(*cinema, meta)
+			if len(updates) == 0 {
+				return true
+			}
+			fields := make([]string, 0, len(updates))
+			for column := range updates {
+				fields = append(fields, column)
+			}
+			sort.Strings(fields)
+			recordProvenance(&cinema.ProvenanceJSON, SourceWebsite, fields...)
+			updates["provenance_json"] = cinema.ProvenanceJSON
+			if err := db.Model(cinema).Updates(updates).Error; err != nil {
+				fmt.Printf("⚠️ 保存官网信息失败 [%s]: %v\n", cinema.NameJP, err)
+				return false
+			}
+			updated++
+			fmt.Printf("   🏛️ 已补全 [%s]: %s\n", cinema.NameJP, strings.Join(fields, ", "))
+			return true
+		}()
+		if !ok {
+			failed++
+		}
+	}
+	return updated, failed, nil
+}
@@ -0,0 +1,91 @@
+package main
+
+import "sort"
+
+// ===========================
+// 模块：影院重新抓取时的字段合并策略
+// 职责：
+// - 本次抓取为空的字段不覆盖已有的非空值（例如页面偶尔缺图时保留原建筑照片）
+// - 坐标只有在定位质量（GeoStatus）提升时才更新，避免上个月找到的精确坐标被近似坐标覆盖；
+//   定位失败（0/0）永远不会覆盖已有坐标
+// - 来源为 manual 的字段（人工修正）永远保留
+// ===========================
+
+// 坐标定位质量。
+const (
+	GeoStatusExact  = "exact"  // 按清洗后的地址命中
+	GeoStatusApprox = "approx" // 按“区 + 影院名”命中
+	GeoStatusFailed = "failed" // 未命中，不写坐标（0/0），同时 GeocodeFailed = true
+	// 旧版本在未命中时写入东京站附近的随机坐标；启动时会清除为 failed（见 geofix.go）
+	GeoStatusRandom = "random"
+)
+
+// geoStatusRank 定位质量排序；旧数据（空值）质量未知，介于 failed 与 approx 之间。
+func geoStatusRank(status string) int {
+	switch status {
+	case GeoStatusExact:
+		return 3
+	case GeoStatusApprox:
+		return 2
+	case GeoStatusFailed, GeoStatusRandom:
+		return 0
+	}
+	return 1
+}
+
+// mergeCinemaFields 对比已有记录与本次抓取结果，返回需要更新的列（纯函数）。
+// 返回的 map 可直接传给 db.Model(&existing).Updates(...)；为空表示无需更新。
+func mergeCinemaFields(existing, scraped Cinema) map[string]interface{} {
+	manual := make(map[string]bool)
+	for field, entry := range parseProvenance(existing.ProvenanceJSON) {
+		if entry.Source == SourceManual {
+			manual[field] = true
+		}
+	}
+
+	updates := make(map[string]interface{})
+	mergeString := func(column, oldVal, newVal string) {
+		if manual[column] || newVal == "" || newVal == oldVal {
+			return
+		}
+		updates[column] = newVal
+	}
+	mergeString("name_kana", existing.NameKana, scraped.NameKana)
+	mergeString("address", existing.Address, scraped.Address)
+	mergeString("building_photo", existing.BuildingPhoto, scraped.BuildingPhoto)
+	mergeString("website", existing.Website, scraped.Website)
+	mergeString("eiga_url", existing.EigaURL, scraped.EigaURL)
+	mergeString("eiga_slug", existing.EigaSlug, scraped.EigaSlug)
+	// 按影院编号命中时日文名可能已在 eiga.com 改动；按日文名命中时两者相同，不产生更新
+	mergeString("name_jp", existing.NameJP, scraped.NameJP)
+
+	// 坐标：人工修正过的不动；没有坐标时直接写入；否则仅在定位质量提升时更新
+	if !manual["latitude"] && !manual["longitude"] && (scraped.Latitude != 0 || scraped.Longitude != 0) {
+		hasCoords := existing.Latitude != 0 || existing.Longitude != 0
+		if !hasCoords || geoStatusRank(scraped.GeoStatus) > geoStatusRank(existing.GeoStatus) {
+			updates["latitude"] = scraped.Latitude
+			updates["longitude"] = scraped.Longitude
+			updates["geo_status"] = scraped.GeoStatus
+			updates["geo_provider"] = scraped.GeoProvider
+			if existing.GeocodeFailed {
+				updates["geocode_failed"] = false
+			}
+		}
+	}
+	return updates
+}
+
+// mergedCinemaFields 按来源拆分更新的字段，用于记录 provenance：页面字段来自 eiga.com，坐标字段来自地理编码。
+func mergedCinemaFields(updates map[string]interface{}) (eigaFields, geoFields []string) {
+	for column := range updates {
+		switch column {
+		case "latitude", "longitude", "geo_status", "geocode_failed", "geo_provider":
+			geoFields = append(geoFields, column)
+		default:
+			eigaFields = append(eigaFields, column)
+		}
+	}
+	sort.Strings(eigaFields)
+	sort.Strings(geoFields)
+	return eigaFields, geoFields
+}
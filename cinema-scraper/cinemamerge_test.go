@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// TestMergeCinemaFields 影院字段合并：空值不覆盖，坐标只在定位质量提升时更新，人工修正的字段保留。
+func TestMergeCinemaFields(t *testing.T) {
+	setTestClock(t, beforeMidnight)
+
+	summary := func(updates map[string]interface{}) string {
+		parts := make([]string, 0, len(updates))
+		for column, v := range updates {
+			parts = append(parts, fmt.Sprintf("%s=%v", column, v))
+		}
+		sort.Strings(parts)
+		return strings.Join(parts, ",")
+	}
+	existing := Cinema{NameJP: "テスト座", Address: "東京都新宿区1-1", BuildingPhoto: "old.jpg", Website: "https://old.example",
+		Latitude: 35.69, Longitude: 139.70, GeoStatus: GeoStatusApprox, GeoProvider: "nominatim"}
+	manual := existing
+	recordProvenance(&manual.ProvenanceJSON, SourceManual, "website", "latitude")
+	failed := Cinema{NameJP: "テスト座", GeoStatus: GeoStatusFailed, GeocodeFailed: true}
+	tests := []struct {
+		name              string
+		existing, scraped Cinema
+		want              string
+	}{
+		{"empty scraped fields keep values", existing, Cinema{NameJP: "テスト座"}, ""},
+		{"changed fields", existing, Cinema{NameJP: "テスト座", Address: "東京都新宿区2-2", BuildingPhoto: "new.jpg"}, "address=東京都新宿区2-2,building_photo=new.jpg"},
+		{"approx does not replace approx", existing, Cinema{Latitude: 35.7, Longitude: 139.71, GeoStatus: GeoStatusApprox}, ""},
+		{"exact replaces approx", existing, Cinema{Latitude: 35.7, Longitude: 139.71, GeoStatus: GeoStatusExact, GeoProvider: "gsi"},
+			"geo_provider=gsi,geo_status=exact,latitude=35.7,longitude=139.71"},
+		{"failed geocode never replaces", existing, Cinema{GeoStatus: GeoStatusFailed}, ""},
+		{"first coordinates clear the failure", failed, Cinema{Latitude: 35.7, Longitude: 139.71, GeoStatus: GeoStatusApprox, GeoProvider: "nominatim"},
+			"geo_provider=nominatim,geo_status=approx,geocode_failed=false,latitude=35.7,longitude=139.71"},
+		{"manual fields kept", manual, Cinema{Website: "https://new.example", Address: "東京都新宿区3-3", Latitude: 35.7, Longitude: 139.71, GeoStatus: GeoStatusExact},
+			"address=東京都新宿区3-3"},
+		{"renamed on eiga.com", existing, Cinema{NameJP: "新テスト座", EigaSlug: "3001"}, "eiga_slug=3001,name_jp=新テスト座"},
+	}
+	for _, tt := range tests {
+		if err := expectEqual(tt.name, summary(mergeCinemaFields(tt.existing, tt.scraped)), tt.want); err != nil {
+			t.Fatal(err)
+		}
+	}
+	eigaFields, geoFields := mergedCinemaFields(map[string]interface{}{"website": "x", "latitude": 1.0, "address": "y", "geo_status": "exact"})
+	if err := firstError(
+		expectEqual("eiga fields", eigaFields, []string{"address", "website"}),
+		expectEqual("geo fields", geoFields, []string{"geo_status", "latitude"})); err != nil {
+		t.Fatal(err)
+	}
+}
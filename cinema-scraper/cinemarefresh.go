@@ -0,0 +1,299 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gocolly/colly/v2"
+)
+
+// ===========================
+// 模块：单馆强制刷新（POST /api/admin/cinemas/:id/refresh）
+// 职责：把针对单家影院的几项修复合并为一次运维操作，同步执行并逐步报告结果
+// - eiga：重新抓取该馆的 eiga.com 详情页（parseEigaCinemaPage），按 cinemamerge.go 的策略合并
+// - geocode：地址变化或坐标未解析（定位失败）时重新定位
+// - photo：仍然没有建筑照片时，用官网 og:image 兜底
+// 说明：来源为 manual 的字段（人工锁定）一律不改；单步失败不影响后续步骤，整体受 cinemaRefreshTimeout 限制。
+// ===========================
+
+const cinemaRefreshTimeout = 60 * time.Second
+
+// 刷新步骤的执行结果。
+const (
+	RefreshStepOK      = "ok"
+	RefreshStepSkipped = "skipped"
+	RefreshStepFailed  = "failed"
+)
+
+// CinemaRefreshStep 单个步骤的结果；Message 说明跳过或失败的原因。
+type CinemaRefreshStep struct {
+	Step    string `json:"step"`
+	Status  string `json:"status"`
+	Message string `json:"message,omitempty"`
+}
+
+// CinemaFieldChange 字段变更：Old / New 为刷新前后的值。
+type CinemaFieldChange struct {
+	Field  string      `json:"field"`
+	Old    interface{} `json:"old"`
+	New    interface{} `json:"new"`
+	Source string      `json:"source"`
+}
+
+// cinemaRefresh 一次刷新过程中累积的更新（列名 -> 新值）与来源。
+type cinemaRefresh struct {
+	original Cinema
+	working  Cinema // 已应用前面步骤更新的影院，供后续步骤判断
+	updates  map[string]interface{}
+	sources  map[string]string
+	steps    []CinemaRefreshStep
+	deadline time.Time
+}
+
+// set 记录一列的新值并同步到 working。
+func (r *cinemaRefresh) set(source string, updates map[string]interface{}) {
+	for column, value := range updates {
+		r.updates[column] = value
+		r.sources[column] = source
+		switch column {
+		case "name_kana":
+			r.working.NameKana = value.(string)
+		case "address":
+			r.working.Address = value.(string)
+		case "building_photo":
+			r.working.BuildingPhoto = value.(string)
+		case "website":
+			r.working.Website = value.(string)
+		case "eiga_url":
+			r.working.EigaURL = value.(string)
+		case "eiga_slug":
+			r.working.EigaSlug = value.(string)
+		case "latitude":
+			r.working.Latitude = value.(float64)
+		case "longitude":
+			r.working.Longitude = value.(float64)
+		case "geo_status":
+			r.working.GeoStatus = value.(string)
+		case "geocode_failed":
+			r.working.GeocodeFailed = value.(bool)
+		case "geo_provider":
+			r.working.GeoProvider = value.(string)
+		}
+	}
+}
+
+// run 执行一个步骤：超过整体期限时直接跳过；fn 返回 (跳过原因, 错误)。
+func (r *cinemaRefresh) run(step string, fn func() (string, error)) {
+	if time.Now().After(r.deadline) {
+		r.steps = append(r.steps, CinemaRefreshStep{Step: step, Status: RefreshStepSkipped, Message: "refresh timed out"})
+		return
+	}
+	result := CinemaRefreshStep{Step: step, Status: RefreshStepOK}
+	func() {
+		// 第三方页面异常导致 panic 时，只记为该步骤失败
+		defer func() {
+			if rec := recover(); rec != nil {
+				result.Status, result.Message = RefreshStepFailed, fmt.Sprintf("panic: %v", rec)
+			}
+		}()
+		skipped, err := fn()
+		switch {
+		case err != nil:
+			result.Status, result.Message = RefreshStepFailed, err.Error()
+		case skipped != "":
+			result.Status, result.Message = RefreshStepSkipped, skipped
+		}
+	}()
+	r.steps = append(r.steps, result)
+}
+
+// lockedCinemaFields 来源为 manual 的列（按列名排序）。
+func lockedCinemaFields(cin Cinema) []string {
+	locked := make([]string, 0)
+	for field, entry := range parseProvenance(cin.ProvenanceJSON) {
+		if entry.Source == SourceManual {
+			locked = append(locked, field)
+		}
+	}
+	sort.Strings(locked)
+	return locked
+}
+
+// fetchEigaCinemaPage 抓取并解析单个 eiga.com 影院详情页。
+func fetchEigaCinemaPage(pageURL string) (eigaCinemaPage, error) {
+	var page eigaCinemaPage
+	found := false
+	c := colly.NewCollector(colly.AllowedDomains("eiga.com"))
+	c.SetRequestTimeout(cinemaSiteTimeout)
+	c.OnHTML("main", func(e *colly.HTMLElement) {
+		if p, ok := parseEigaCinemaPage(e); ok && !found {
+			page, found = p, true
+		}
+	})
+	if err := c.Visit(pageURL); err != nil {
+		return page, err
+	}
+	if !found {
+		return page, errors.New("cinema details not found on eiga.com page")
+	}
+	return page, nil
+}
+
+// refreshEigaStep 重新抓取 eiga.com 详情页并合并页面字段（坐标交给 geocode 步骤）。
+func (r *cinemaRefresh) refreshEigaStep() (string, error) {
+	if r.working.EigaURL == "" {
+		return "", errors.New("eiga.com page url unknown, run crawl-cinemas once to record it")
+	}
+	page, err := fetchEigaCinemaPage(r.working.EigaURL)
+	if err != nil {
+		return "", err
+	}
+	if page.NameJP != r.working.NameJP {
+		return "", fmt.Errorf("eiga.com page is for %q, not %q", page.NameJP, r.working.NameJP)
+	}
+	r.set(SourceEiga, mergeCinemaFields(r.working, Cinema{
+		NameKana:      page.NameKana,
+		Address:       page.Address,
+		BuildingPhoto: page.BuildingPhoto,
+		Website:       page.Website,
+		EigaSlug:      eigaTheaterSlug(r.working.EigaURL),
+	}))
+	return "", nil
+}
+
+// refreshGeocodeStep 地址变化或坐标未解析时重新定位；新地址的坐标即使质量不升也要替换旧坐标。
+func (r *cinemaRefresh) refreshGeocodeStep() (string, error) {
+	_, addressChanged := r.updates["address"]
+	if !addressChanged && cinemaCoordsResolved(r.working) {
+		return "address unchanged and coordinates resolved", nil
+	}
+	locked := parseProvenance(r.working.ProvenanceJSON)
+	if locked["latitude"].Source == SourceManual || locked["longitude"].Source == SourceManual {
+		return "coordinates are locked", nil
+	}
+	if r.working.Address == "" {
+		return "", errors.New("cinema has no address")
+	}
+	if osmContactEmail() == "" {
+		_ = configureOSMContact(nil) // 服务模式下未配置时从 OSM_CONTACT_EMAIL 读取
+	}
+
+	lat, lng, status, provider := getCoordsFromOSMWithRetry(cleanAddressForGeo(r.working.Address), r.working.NameJP)
+	if status == GeoStatusFailed {
+		if osmContactEmail() == "" {
+			return "", errOSMContactMissing
+		}
+		return "", errors.New("no geocoding results")
+	}
+	hasCoords := r.working.Latitude != 0 || r.working.Longitude != 0
+	if !addressChanged && hasCoords && geoStatusRank(status) <= geoStatusRank(r.working.GeoStatus) {
+		return "no better coordinates found", nil
+	}
+	r.set(geoProviderSource(provider), map[string]interface{}{
+		"latitude": lat, "longitude": lng, "geo_status": status, "geocode_failed": false, "geo_provider": provider,
+	})
+	return "", nil
+}
+
+// refreshPhotoStep 没有建筑照片时用官网 og:image 兜底（规则同 enrich-cinemas）。
+func (r *cinemaRefresh) refreshPhotoStep() (string, error) {
+	if r.working.BuildingPhoto != "" {
+		return "building photo present", nil
+	}
+	if r.working.Website == "" {
+		return "cinema has no website", nil
+	}
+	meta, err := fetchCinemaSiteMeta(r.working.Website)
+	if err != nil {
+		return "", err
+	}
+	photo, ok := siteMetaUpdates(r.working, meta)["building_photo"]
+	if !ok {
+		return "no og:image on website", nil
+	}
+	r.set(SourceWebsite, map[string]interface{}{"building_photo": photo})
+	return "", nil
+}
+
+// changes 按列名排序的字段变更列表。
+func (r *cinemaRefresh) changes() []CinemaFieldChange {
+	old := map[string]interface{}{
+		"name_kana":      r.original.NameKana,
+		"address":        r.original.Address,
+		"building_photo": r.original.BuildingPhoto,
+		"website":        r.original.Website,
+		"eiga_url":       r.original.EigaURL,
+		"eiga_slug":      r.original.EigaSlug,
+		"latitude":       r.original.Latitude,
+		"longitude":      r.original.Longitude,
+		"geo_status":     r.original.GeoStatus,
+		"geocode_failed": r.original.GeocodeFailed,
+		"geo_provider":   r.original.GeoProvider,
+	}
+	out := make([]CinemaFieldChange, 0, len(r.updates))
+	for column, value := range r.updates {
+		out = append(out, CinemaFieldChange{Field: column, Old: old[column], New: value, Source: r.sources[column]})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Field < out[j].Field })
+	return out
+}
+
+// refreshCinemaHandler 处理 POST /api/admin/cinemas/:id/refresh
+func refreshCinemaHandler(c *gin.Context) {
+	var cinema Cinema
+	if err := db.First(&cinema, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "cinema not found"})
+		return
+	}
+
+	r := &cinemaRefresh{
+		original: cinema,
+		working:  cinema,
+		updates:  make(map[string]interface{}),
+		sources:  make(map[string]string),
+		deadline: time.Now().Add(cinemaRefreshTimeout),
+	}
+	r.run("eiga", r.refreshEigaStep)
+	r.run("geocode", r.refreshGeocodeStep)
+	r.run("photo", r.refreshPhotoStep)
+
+	changes := r.changes()
+	if len(changes) > 0 {
+		bySource := make(map[string][]string)
+		for _, ch := range changes {
+			bySource[ch.Source] = append(bySource[ch.Source], ch.Field)
+		}
+		provenance := cinema.ProvenanceJSON
+		for source, fields := range bySource {
+			recordProvenance(&provenance, source, fields...)
+		}
+		updates := make(map[string]interface{}, len(r.updates)+2)
+		for column, value := range r.updates {
+			updates[column] = value
+		}
+		updates["provenance_json"] = provenance
+		updates["updated_at"] = time.Now()
+		if err := db.Model(&cinema).Updates(updates).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save cinema"})
+			return
+		}
+	}
+
+	ok := true
+	for _, step := range r.steps {
+		if step.Status == RefreshStepFailed {
+			ok = false
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"cinema_id":     cinema.ID,
+		"ok":            ok,
+		"steps":         r.steps,
+		"changes":       changes,
+		"locked_fields": lockedCinemaFields(cinema),
+	})
+}
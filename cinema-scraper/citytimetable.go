@@ -0,0 +1,285 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/sync/singleflight"
+	"gorm.io/gorm"
+)
+
+// ===========================
+// 模块：全东京当日时间表（/api/timetable）
+// 职责：
+// - 一次请求返回某天所有影院及各自的 daily_movies，供重度用户离线浏览；/timetable HTML 页面共用同一份数据
+// - 只用一次查询：影院 LEFT JOIN 当天的公开场次 LEFT JOIN 影片，没有场次的影院也各占一行
+// - 响应体较大（全东京约数百 KB），按 (date, district, lang) 缓存序列化结果与 gzip 结果，
+//   提供强 ETag（If-None-Match 命中返回 304）与 gzip 压缩；gzip 响应的 ETag 带 -gz 后缀，与未压缩的区分
+// 说明：缓存以“当天场次的版本”（行数 + 最大 ID + 最大 updated_at + 最近一次成功抓取）为准，场次有变化
+//       或抓取结束（schedules_as_of 改变）时立即重建；影片标题、评分等非场次数据的变化最迟在
+//       cityTimetableCacheTTL 后生效。重建在锁外进行，同一 key 的并发请求经 singleflight 只查询一次。
+// ===========================
+
+const (
+	cityTimetableCacheTTL     = 10 * time.Minute
+	cityTimetableCacheEntries = 64 // 超过后整体清空，避免日期 × 区 × 语言的组合无限增长
+	cityTimetableMaxAge       = 300
+)
+
+// CinemaTimetable 单个影院当天的排片。
+type CinemaTimetable struct {
+	CinemaItem
+	EigaURL     string       `json:"eiga_url"` // eiga.com 影院详情页，未抓到时为空
+	DailyMovies []DailyMovie `json:"daily_movies"`
+}
+
+// cityTimetableEntry 缓存的一份响应。
+type cityTimetableEntry struct {
+	version string
+	body    []byte
+	gzipped []byte
+	etag    string
+	gzipTag string // gzip 响应的 ETag：内容编码不同，强 ETag 也必须不同
+	builtAt time.Time
+}
+
+// cityTimetableCache 进程内缓存（多个请求并发读取，需加锁）。
+var cityTimetableCache struct {
+	sync.Mutex
+	entries map[string]*cityTimetableEntry
+}
+
+// cityTimetableBuilds 合并同一 key 的并发重建。
+var cityTimetableBuilds singleflight.Group
+
+// cityTimetableRow JOIN 查询的一行：影院与（可能为空的）场次、影片，列名分别带 c_ / s_ / m_ 前缀。
+type cityTimetableRow struct {
+	Cinema   Cinema   `gorm:"embedded;embeddedPrefix:c_"`
+	Schedule Schedule `gorm:"embedded;embeddedPrefix:s_"`
+	Movie    Movie    `gorm:"embedded;embeddedPrefix:m_"`
+}
+
+// prefixedColumns 模型全部列的 SELECT 片段（table.col AS prefixcol），与 cityTimetableRow 的前缀对应。
+func prefixedColumns(model interface{}, table, prefix string) (string, error) {
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(model); err != nil {
+		return "", err
+	}
+	cols := make([]string, 0, len(stmt.Schema.Fields))
+	for _, field := range stmt.Schema.Fields {
+		if field.DBName != "" {
+			cols = append(cols, table+"."+field.DBName+" AS "+prefix+field.DBName)
+		}
+	}
+	return strings.Join(cols, ", "), nil
+}
+
+// loadCityTimetable date 当天（可按区过滤）全部影院的排片，影院按区、名称排序；没有场次的影院 DailyMovies 为空数组。
+// 软删除与会員限定的过滤写在 LEFT JOIN 的 ON 条件里，这样被过滤掉场次的影院仍会出现。
+func loadCityTimetable(date, district, lang string) ([]CinemaTimetable, error) {
+	selects := make([]string, 0, 3)
+	for _, part := range []struct {
+		model         interface{}
+		table, prefix string
+	}{{&Cinema{}, "cinemas", "c_"}, {&Schedule{}, "schedules", "s_"}, {&Movie{}, "movies", "m_"}} {
+		cols, err := prefixedColumns(part.model, part.table, part.prefix)
+		if err != nil {
+			return nil, err
+		}
+		selects = append(selects, cols)
+	}
+	query := db.Table("cinemas").Select(strings.Join(selects, ", ")).
+		Joins("LEFT JOIN schedules ON schedules.cinema_id = cinemas.id AND date(schedules.play_date) = ? AND schedules.members_only = ? AND schedules.deleted_at IS NULL", date, false).
+		Joins("LEFT JOIN movies ON movies.id = schedules.movie_id AND movies.deleted_at IS NULL").
+		Order("cinemas.id")
+	if district != "" {
+		query = query.Where("cinemas.district = ?", district)
+	}
+	var rows []cityTimetableRow
+	if err := query.Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	var cinemas []Cinema
+	byCinema := make(map[uint][]Schedule)
+	movieMap := make(map[uint]Movie)
+	for _, r := range rows {
+		if len(cinemas) == 0 || cinemas[len(cinemas)-1].ID != r.Cinema.ID {
+			cinemas = append(cinemas, r.Cinema)
+		}
+		// 没有场次（或影片已删除）时 JOIN 出的列为 NULL，扫描后 ID 为 0
+		if r.Schedule.ID == 0 || r.Movie.ID == 0 {
+			continue
+		}
+		byCinema[r.Cinema.ID] = append(byCinema[r.Cinema.ID], r.Schedule)
+		movieMap[r.Movie.ID] = r.Movie
+	}
+	sortCinemas(cinemas, "district")
+
+	out := make([]CinemaTimetable, 0, len(cinemas))
+	for _, cin := range cinemas {
+		out = append(out, CinemaTimetable{
+			CinemaItem:  mapCinemaToItem(cin),
+			EigaURL:     cin.EigaURL,
+			DailyMovies: groupDailyMovies(byCinema[cin.ID], movieMap, lang),
+		})
+	}
+	return out, nil
+}
+
+// cityTimetableVersion 当天场次的版本标识：场次增删改或新的成功抓取都会改变它。
+func cityTimetableVersion(date string) (string, error) {
+	var v struct {
+		RowCount  int64
+		MaxID     uint
+		UpdatedAt string
+	}
+	err := db.Model(&Schedule{}).
+		Select("COUNT(*) AS row_count, COALESCE(MAX(id), 0) AS max_id, COALESCE(MAX(updated_at), '') AS updated_at").
+		Where("date(play_date) = ?", date).Scan(&v).Error
+	var runID uint
+	if id := scheduleFreshness().CrawlRunID; id != nil {
+		runID = *id
+	}
+	return fmt.Sprintf("%d/%d/%s/%d", v.RowCount, v.MaxID, v.UpdatedAt, runID), err
+}
+
+// buildCityTimetableEntry 生成一份响应并计算 ETag 与 gzip 结果。
+func buildCityTimetableEntry(date, district, lang, version string) (*cityTimetableEntry, error) {
+	cinemas, err := loadCityTimetable(date, district, lang)
+	if err != nil {
+		return nil, err
+	}
+	body, err := json.Marshal(withFreshness(gin.H{
+		"date":     date,
+		"district": district,
+		"total":    len(cinemas),
+		"cinemas":  cinemas,
+	}))
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(body); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	etag := strongETag(body)
+	return &cityTimetableEntry{
+		version: version,
+		body:    body,
+		gzipped: buf.Bytes(),
+		etag:    etag,
+		gzipTag: strings.TrimSuffix(etag, `"`) + `-gz"`,
+		builtAt: time.Now(),
+	}, nil
+}
+
+// cachedCityTimetable 返回缓存的响应；场次版本变化或超过 TTL 时重建。
+func cachedCityTimetable(date, district, lang string) (*cityTimetableEntry, error) {
+	version, err := cityTimetableVersion(date)
+	if err != nil {
+		return nil, err
+	}
+	key := date + "|" + district + "|" + lang
+
+	cityTimetableCache.Lock()
+	e, ok := cityTimetableCache.entries[key]
+	cityTimetableCache.Unlock()
+	if ok && e.version == version && time.Since(e.builtAt) < cityTimetableCacheTTL {
+		return e, nil
+	}
+	// 重建不持有缓存锁：其他 key 的请求照常读缓存，同一 key 同一版本的并发请求共用一次重建
+	v, err, _ := cityTimetableBuilds.Do(key+"|"+version, func() (interface{}, error) {
+		e, err := buildCityTimetableEntry(date, district, lang, version)
+		if err != nil {
+			return nil, err
+		}
+		cityTimetableCache.Lock()
+		if cityTimetableCache.entries == nil || len(cityTimetableCache.entries) >= cityTimetableCacheEntries {
+			cityTimetableCache.entries = make(map[string]*cityTimetableEntry)
+		}
+		cityTimetableCache.entries[key] = e
+		cityTimetableCache.Unlock()
+		return e, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.(*cityTimetableEntry), nil
+}
+
+// invalidateCityTimetable 清空缓存，下一个请求重建（抓取结束后调用，见 crawlrun.go / crawlscheduler.go）。
+func invalidateCityTimetable() {
+	cityTimetableCache.Lock()
+	cityTimetableCache.entries = nil
+	cityTimetableCache.Unlock()
+}
+
+// strongETag 由响应内容计算强 ETag（sha256 前 16 字节的十六进制，带引号）。
+func strongETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// etagMatches If-None-Match 是否包含 etag（支持逗号分隔的多个值与 *）。
+func etagMatches(ifNoneMatch, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == etag || candidate == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// cityTimetableHandler 全东京当日时间表：
+// - GET /api/timetable?date=YYYY-MM-DD&district=新宿区（date 默认今天，格式错误返回 400）
+// - 支持 If-None-Match（304）与 Accept-Encoding: gzip；gzip 与未压缩的响应 ETag 不同，均带 Vary: Accept-Encoding
+func cityTimetableHandler(c *gin.Context) {
+	date := c.Query("date")
+	if date == "" {
+		date = referenceTime(c).Format("2006-01-02")
+	} else if _, err := time.Parse("2006-01-02", date); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid date, expected YYYY-MM-DD"})
+		return
+	}
+	district := strings.TrimSpace(c.Query("district"))
+
+	entry, err := cachedCityTimetable(date, district, requestLang(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to build timetable"})
+		return
+	}
+
+	gzipped := strings.Contains(c.GetHeader("Accept-Encoding"), "gzip")
+	etag := entry.etag
+	if gzipped {
+		etag = entry.gzipTag
+	}
+	c.Header("ETag", etag)
+	c.Header("Cache-Control", fmt.Sprintf("public, max-age=%d", cityTimetableMaxAge))
+	c.Writer.Header().Add("Vary", "Accept-Encoding") // Accept-Language 已由 languageMiddleware 加上
+	if etagMatches(c.GetHeader("If-None-Match"), etag) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	if gzipped {
+		c.Header("Content-Encoding", "gzip")
+		c.Data(http.StatusOK, "application/json; charset=utf-8", entry.gzipped)
+		return
+	}
+	c.Data(http.StatusOK, "application/json; charset=utf-8", entry.body)
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestCityTimetable 全东京时间表：按区过滤与日期校验。
+func TestCityTimetable(t *testing.T) {
+	runHTTPCases(t, []httpCase{
+		{"全东京时间表：按区过滤", "/api/timetable?district=新宿区", func(r testResponse) error {
+			var body struct {
+				Total   int               `json:"total"`
+				Cinemas []CinemaTimetable `json:"cinemas"`
+			}
+			if err := expectJSON(r, &body); err != nil {
+				return err
+			}
+			if body.Total == 0 || body.Total != len(body.Cinemas) {
+				return fmt.Errorf("total = %d, len(cinemas) = %d", body.Total, len(body.Cinemas))
+			}
+			shows := 0
+			for _, cin := range body.Cinemas {
+				if cin.District != "新宿区" {
+					return fmt.Errorf("cinema %d district = %q", cin.ID, cin.District)
+				}
+				for _, dm := range cin.DailyMovies {
+					shows += len(dm.Times)
+				}
+			}
+			return expectEqual("shows", shows, 2*len(fixtureShowTimes))
+		}},
+		{"全东京时间表：非法日期", "/api/timetable?date=2026-13-01", func(r testResponse) error {
+			return expectStatus(r, http.StatusBadRequest)
+		}},
+	})
+}
+
+// TestCityTimetableCache 全东京时间表缓存：gzip 与未压缩的响应 ETag 不同且都带 Vary，抓取结束后缓存失效，新的成功抓取改变 ETag。
+func TestCityTimetableCache(t *testing.T) {
+	router := newTestRouter(t)
+	now := testNoon
+	setTestClock(t, now)
+
+	get := func(acceptEncoding, ifNoneMatch string) testResponse {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/api/timetable", nil)
+		if acceptEncoding != "" {
+			req.Header.Set("Accept-Encoding", acceptEncoding)
+		}
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		router.ServeHTTP(rec, req)
+		return testResponse{Status: rec.Code, Header: rec.Header(), Body: rec.Body.Bytes()}
+	}
+	plain, gzipped := get("", ""), get("gzip, deflate", "")
+	plainTag, gzipTag := plain.Header.Get("ETag"), gzipped.Header.Get("ETag")
+	gzipHit, crossed := get("gzip", gzipTag), get("gzip", plainTag)
+
+	run := CrawlRun{Kind: "schedules", Status: "running", StartedAt: now}
+	if err := db.Create(&run).Error; err != nil {
+		t.Fatal(err)
+	}
+	completeScheduleCrawl(&run, errInterrupted, defaultCrawlMinRatio)
+	resetCrawlCounters()
+	cityTimetableCache.Lock()
+	cachedAfterCrawl := len(cityTimetableCache.entries)
+	cityTimetableCache.Unlock()
+
+	// 另一个进程完成的抓取：schedules_as_of 改变后不再返回旧的缓存
+	success := CrawlRun{Kind: "schedules", Status: "success", StartedAt: now, FinishedAt: now.Add(time.Minute)}
+	if err := db.Create(&success).Error; err != nil {
+		t.Fatal(err)
+	}
+	invalidateScheduleFreshness()
+	after := get("", "")
+	var body struct {
+		CrawlRunID *uint `json:"crawl_run_id"`
+	}
+	if err := expectJSON(after, &body); err != nil {
+		t.Fatal(err)
+	}
+	if err := firstError(
+		expectStatus(plain, http.StatusOK),
+		expectEqual("gzip encoding", gzipped.Header.Get("Content-Encoding"), "gzip"),
+		expectEqual("gzip etag", gzipTag, strings.TrimSuffix(plainTag, `"`)+`-gz"`),
+		expectEqual("vary", [2]bool{strings.Contains(strings.Join(plain.Header.Values("Vary"), ","), "Accept-Encoding"),
+			strings.Contains(strings.Join(gzipped.Header.Values("Vary"), ","), "Accept-Encoding")}, [2]bool{true, true}),
+		expectEqual("gzip revalidated", gzipHit.Status, http.StatusNotModified),
+		expectEqual("plain etag does not match gzip", crossed.Status, http.StatusOK),
+		expectEqual("cache cleared after crawl", cachedAfterCrawl, 0),
+		expectEqual("etag changed after new crawl", after.Header.Get("ETag") != plainTag, true),
+		expectEqual("crawl_run_id", body.CrawlRunID != nil && *body.CrawlRunID == success.ID, true)); err != nil {
+		t.Fatal(err)
+	}
+}
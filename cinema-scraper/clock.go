@@ -0,0 +1,145 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ===========================
+// 模块：参考时间（东京时间 / 时间旅行调试）
+// 职责：
+// - “今天”一律按东京时间计算：服务器部署在 UTC 主机上时，time.Now() 的日期要到 JST 9:00 才切换
+// - 命令与后台任务用 nowJST() / todayJST()；handler 通过 referenceTime(c) 取得参考时刻，不直接调用 time.Now()
+// - 管理接口支持 as_of=YYYY-MM-DD（或 RFC3339 时刻），按该时刻重放列表与详情，排查“昨天为什么显示了 X”
+// 说明：公开接口忽略 as_of，只有挂在 /api/admin 下的同名路由才会解析它。
+// ===========================
+
+const referenceTimeKey = "reference_time"
+
+// tokyoLocation 东京时区；加载失败（容器缺少 tzdata）时退化为固定 +9 偏移。
+var tokyoLocation = func() *time.Location {
+	if loc, err := time.LoadLocation("Asia/Tokyo"); err == nil {
+		return loc
+	}
+	return time.FixedZone("JST", 9*60*60)
+}()
+
+// clockNow 当前时刻的来源；测试用固定时刻替换它来验证日期边界。
+var clockNow = time.Now
+
+// nowJST 返回东京时间的当前时刻。
+func nowJST() time.Time {
+	return clockNow().In(tokyoLocation)
+}
+
+// todayJST 东京时间的今天（YYYY-MM-DD）。
+func todayJST() string {
+	return nowJST().Format("2006-01-02")
+}
+
+// asOfMiddleware 解析 as_of 并写入请求上下文；非法值返回 400。
+// 只传日期时保留当前的东京时间时分，方便对比“昨天此刻”。
+func asOfMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		raw := c.Query("as_of")
+		if raw == "" {
+			c.Next()
+			return
+		}
+		ref, err := parseAsOf(raw, nowJST())
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "invalid as_of, expected YYYY-MM-DD or RFC3339"})
+			return
+		}
+		c.Set(referenceTimeKey, ref)
+		c.Header("X-As-Of", ref.Format(time.RFC3339))
+		c.Next()
+	}
+}
+
+// parseAsOf 解析 as_of：RFC3339 时刻原样使用；日期则沿用 now 的时分（东京时间）。
+func parseAsOf(raw string, now time.Time) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return t.In(tokyoLocation), nil
+	}
+	day, err := time.ParseInLocation("2006-01-02", raw, tokyoLocation)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Date(day.Year(), day.Month(), day.Day(), now.Hour(), now.Minute(), now.Second(), 0, tokyoLocation), nil
+}
+
+// referenceTime 当前请求的参考时刻：管理接口传入 as_of 时为该时刻，否则为东京时间的当前时刻。
+func referenceTime(c *gin.Context) time.Time {
+	if v, ok := c.Get(referenceTimeKey); ok {
+		if t, ok := v.(time.Time); ok {
+			return t
+		}
+	}
+	return nowJST()
+}
+
+// hasAsOf 当前请求是否处于时间旅行模式。
+func hasAsOf(c *gin.Context) bool {
+	_, ok := c.Get(referenceTimeKey)
+	return ok
+}
+
+// movieIDsWithStatusAsOf 时间旅行模式下按公开排片（不含会員限定）重新推算状态，返回 today 当天处于 status 的影片 ID。
+func movieIDsWithStatusAsOf(status, today string) ([]uint, error) {
+	var rows []struct {
+		MovieID   uint
+		FirstDate string
+		LastDate  string
+	}
+	if err := db.Model(&Schedule{}).
+		Select("movie_id, MIN(date(play_date)) AS first_date, MAX(date(play_date)) AS last_date").
+		Where("members_only = ?", false).
+		Group("movie_id").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	// 日本院线上映日期修正先行上映（见 statusrules.go 的 statusWithJPRelease）
+	var releases []struct {
+		ID            uint
+		JPReleaseDate string
+	}
+	if err := db.Model(&Movie{}).Select("id, substr(jp_release_date, 1, 10) AS jp_release_date").
+		Where("jp_release_date IS NOT NULL").Scan(&releases).Error; err != nil {
+		return nil, err
+	}
+	jpRelease := make(map[uint]string, len(releases))
+	for _, r := range releases {
+		jpRelease[r.ID] = r.JPReleaseDate
+	}
+	ids := []uint{}
+	for _, r := range rows {
+		computed, err := statusFromScheduleRange(r.FirstDate, r.LastDate, today)
+		if err != nil {
+			return nil, err
+		}
+		if statusWithJPRelease(computed, jpRelease[r.MovieID], today) == status {
+			ids = append(ids, r.MovieID)
+		}
+	}
+	return ids, nil
+}
+
+// applyStatusFilter 按状态过滤影片：正常模式使用库中的 status（showing 兼容旧数据的空状态）；
+// 时间旅行模式按排片在 today 当天重新推算。
+func applyStatusFilter(tx *gorm.DB, status, today string, asOf bool) *gorm.DB {
+	if asOf {
+		ids, err := movieIDsWithStatusAsOf(status, today)
+		if err != nil {
+			ids = []uint{}
+		}
+		return tx.Where("id IN ?", ids)
+	}
+	if status == "showing" {
+		return tx.Where("(status = ? OR status = '' OR status IS NULL)", status)
+	}
+	return tx.Where("status = ?", status)
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// TestTodayJST “今天”按东京时间切换：UTC 主机上 JST 23:59 仍是当天，00:01 已是次日。
+func TestTodayJST(t *testing.T) {
+	tests := []struct {
+		name string
+		now  time.Time
+		want string
+	}{
+		{"JST 23:59 时今天仍是当天", beforeMidnight, "2026-01-27"},
+		{"JST 00:01 时今天已切到次日", afterMidnight, "2026-01-28"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setTestClock(t, tt.now)
+			if got := todayJST(); got != tt.want {
+				t.Fatalf("todayJST = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestScheduleListDefaultDate /api/schedules 不带 date 时取东京时间的今天。
+func TestScheduleListDefaultDate(t *testing.T) {
+	tests := []struct {
+		name string
+		now  time.Time
+		want string
+	}{
+		{"JST 23:59", beforeMidnight, "2026-01-27"},
+		{"JST 00:01", afterMidnight, "2026-01-28"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := newTestRouter(t)
+			setTestClock(t, tt.now)
+			var body struct {
+				Date string `json:"date"`
+			}
+			if err := firstError(expectJSON(testGet(router, "/api/schedules"), &body), expectEqual("date", body.Date, tt.want)); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
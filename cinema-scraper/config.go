@@ -0,0 +1,259 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ===========================
+// 模块：运行配置（环境变量）
+// 职责：
+// - 启动时从环境变量读取 API Key、数据库路径、端口等配置；API Key 没有默认值，其余项未设置时取默认值
+// - 外部数据源（TMDB / OMDb / 豆瓣）的调用显式接收 Config，不再直接引用全局常量
+// - 需要 TMDB 的命令在 Key 为空时直接退出并提示，而不是每部影片都请求失败一次
+// - `go run . --print-config` 打印生效的配置（密钥打码）后退出
+// 环境变量：
+//   TMDB_API_KEY / OMDB_API_KEY   外部接口密钥（没有默认值，未设置或为空串表示不使用；需要 TMDB 的命令会直接报错退出）
+//   DB_PATH                       SQLite 数据库文件（默认 tokyo_cinepath.db）
+//   PORT                          API 监听端口（默认 8080）
+//   ENABLE_DOUBAN_RATING          是否在补全时抓取豆瓣评分（true / false，默认 false，避免触发豆瓣风控）
+//   CRAWL_AREA                    eiga.com 的都道府县代码（默认 13 = 东京都），可用逗号列出多个，如 13,14,11；
+//                                 crawl-cinemas / crawl-schedules 的 --area 参数可临时覆盖（见 prefecture.go）
+//   API_CACHE_TTL_DAYS            TMDB / OMDb 响应缓存的有效天数（默认 30，0 表示每次都重新请求，见 apicache.go）
+//   CORS_ALLOWED_ORIGINS          允许跨域调用 /api 公开接口的来源，逗号分隔（默认 * = 放行全部来源；设为空串 = 不放行，见 cors.go）
+//   ADMIN_TOKEN                   /api/admin 管理接口的 Bearer 令牌（默认空 = 关闭管理接口，见 adminauth.go）
+//   CRAWL_INTERVAL                API 运行期间定时抓取排片的间隔（Go 时长，如 6h；默认不启用，最短 30m，见 crawlscheduler.go）
+// ===========================
+
+// 默认值：方便本地开发与演示；上线时请通过环境变量覆盖。密钥不设默认值，不进入源码。
+const (
+	defaultDBPath       = "tokyo_cinepath.db"
+	defaultPort         = "8080"
+	defaultCrawlArea    = "13"
+	defaultEnableDouban = false
+	defaultAPICacheTTL  = 30
+	defaultCORSOrigins  = "*"
+)
+
+// Config 生效的运行配置。
+type Config struct {
+	TMDBAPIKey         string
+	OMDBAPIKey         string
+	DBPath             string
+	Port               string
+	EnableDoubanRating bool
+	CrawlArea          string
+	APICacheTTLDays    int
+	CORSAllowedOrigins string          // 默认 * 放行全部来源，空表示不放行跨域
+	AdminToken         string          // 空表示关闭管理接口
+	CrawlInterval      time.Duration   // 0 表示不启用定时抓取
+	fromEnv            map[string]bool // 哪些项来自环境变量（--print-config 显示用）
+}
+
+// appConfig 进程的配置，main 启动时加载；测试等未加载时即为默认值。
+var appConfig = defaultConfig()
+
+// crawlAreaRe 都道府县代码：01 ~ 47。
+var crawlAreaRe = regexp.MustCompile(`^(0[1-9]|[1-3][0-9]|4[0-7])$`)
+
+// defaultConfig 全部取默认值的配置。
+func defaultConfig() Config {
+	return Config{
+		DBPath:             defaultDBPath,
+		Port:               defaultPort,
+		EnableDoubanRating: defaultEnableDouban,
+		CrawlArea:          defaultCrawlArea,
+		APICacheTTLDays:    defaultAPICacheTTL,
+		CORSAllowedOrigins: defaultCORSOrigins,
+		fromEnv:            map[string]bool{},
+	}
+}
+
+// loadConfig 由 lookup（通常为 os.LookupEnv）构造配置并校验（纯函数）。
+// 已设置但为空串的 Key 视为“不使用”、CORS_ALLOWED_ORIGINS 视为不放行，其余项为空时取默认值。
+func loadConfig(lookup func(string) (string, bool)) (Config, error) {
+	cfg := defaultConfig()
+	get := func(name string) (string, bool) {
+		v, ok := lookup(name)
+		if ok {
+			cfg.fromEnv[name] = true
+		}
+		return strings.TrimSpace(v), ok
+	}
+	if v, ok := get("TMDB_API_KEY"); ok {
+		cfg.TMDBAPIKey = v
+	}
+	if v, ok := get("OMDB_API_KEY"); ok {
+		cfg.OMDBAPIKey = v
+	}
+	if v, ok := get("DB_PATH"); ok && v != "" {
+		cfg.DBPath = v
+	}
+	if v, ok := get("PORT"); ok && v != "" {
+		if n, err := strconv.Atoi(v); err != nil || n < 1 || n > 65535 {
+			return cfg, fmt.Errorf("invalid PORT %q, expected 1-65535", v)
+		}
+		cfg.Port = v
+	}
+	if v, ok := get("ENABLE_DOUBAN_RATING"); ok && v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return cfg, fmt.Errorf("invalid ENABLE_DOUBAN_RATING %q, expected true or false", v)
+		}
+		cfg.EnableDoubanRating = b
+	}
+	if v, ok := get("CRAWL_AREA"); ok && v != "" {
+		areas, err := parseCrawlAreas(v)
+		if err != nil {
+			return cfg, fmt.Errorf("invalid CRAWL_AREA: %v", err)
+		}
+		cfg.CrawlArea = strings.Join(areas, ",")
+	}
+	if v, ok := get("API_CACHE_TTL_DAYS"); ok && v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return cfg, fmt.Errorf("invalid API_CACHE_TTL_DAYS %q, expected a non-negative integer", v)
+		}
+		cfg.APICacheTTLDays = n
+	}
+	if v, ok := get("CRAWL_INTERVAL"); ok && v != "" {
+		d, err := parseCrawlInterval(v)
+		if err != nil {
+			return cfg, fmt.Errorf("invalid CRAWL_INTERVAL: %v", err)
+		}
+		cfg.CrawlInterval = d
+	}
+	if v, ok := get("ADMIN_TOKEN"); ok {
+		cfg.AdminToken = v
+	}
+	if v, ok := get("CORS_ALLOWED_ORIGINS"); ok && v == "" {
+		cfg.CORSAllowedOrigins = ""
+	} else if ok {
+		origins, err := parseCORSOrigins(v)
+		if err != nil {
+			return cfg, fmt.Errorf("invalid CORS_ALLOWED_ORIGINS: %v", err)
+		}
+		cfg.CORSAllowedOrigins = strings.Join(origins, ",")
+	}
+	return cfg, nil
+}
+
+// parseCrawlAreas 解析逗号分隔的都道府县代码（纯函数）：去空白、去重并保持顺序，至少一个。
+func parseCrawlAreas(s string) ([]string, error) {
+	areas := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		if !crawlAreaRe.MatchString(part) {
+			return nil, fmt.Errorf("%q is not a prefecture code 01-47", part)
+		}
+		if !seen[part] {
+			seen[part] = true
+			areas = append(areas, part)
+		}
+	}
+	if len(areas) == 0 {
+		return nil, errors.New("expected at least one prefecture code 01-47")
+	}
+	return areas, nil
+}
+
+// crawlAreas 生效的抓取地区列表；CrawlArea 已在加载时校验，这里不会为空。
+func (c Config) crawlAreas() []string {
+	areas, err := parseCrawlAreas(c.CrawlArea)
+	if err != nil {
+		return []string{defaultCrawlArea}
+	}
+	return areas
+}
+
+// databaseDSN SQLite 连接串；_busy_timeout 让并发写入时等待锁释放，而不是直接返回 database is locked。
+func (c Config) databaseDSN() string {
+	return c.DBPath + "?_busy_timeout=5000"
+}
+
+// eigaAreaURL eiga.com 上某个地区的影院列表页。
+func eigaAreaURL(area string) string {
+	return "https://eiga.com/theater/" + area + "/"
+}
+
+// eigaAreaURLs 抓取地区（CrawlArea）的全部影院列表页。
+func (c Config) eigaAreaURLs() []string {
+	areas := c.crawlAreas()
+	urls := make([]string, 0, len(areas))
+	for _, area := range areas {
+		urls = append(urls, eigaAreaURL(area))
+	}
+	return urls
+}
+
+// requireTMDB 需要 TMDB 补全的命令开始前调用：Key 为空时返回明确的错误。
+func (c Config) requireTMDB() error {
+	if c.TMDBAPIKey == "" {
+		return errTMDBKeyMissing
+	}
+	return nil
+}
+
+// errTMDBKeyMissing TMDB Key 为空时补全无法进行。
+var errTMDBKeyMissing = errors.New("TMDB_API_KEY is empty: set it in the environment (https://www.themoviedb.org/settings/api) before running enrichment")
+
+// maskSecret 密钥打码（纯函数）：只保留末 4 位；8 位以下全部打码，空串显示 (empty)。
+func maskSecret(s string) string {
+	if s == "" {
+		return "(empty)"
+	}
+	if len(s) < 8 {
+		return strings.Repeat("*", len(s))
+	}
+	return strings.Repeat("*", len(s)-4) + s[len(s)-4:]
+}
+
+// configLines --print-config 的输出（纯函数），按变量名排序，密钥打码。
+func configLines(c Config) []string {
+	values := map[string]string{
+		"TMDB_API_KEY":         maskSecret(c.TMDBAPIKey),
+		"OMDB_API_KEY":         maskSecret(c.OMDBAPIKey),
+		"DB_PATH":              c.DBPath,
+		"PORT":                 c.Port,
+		"ENABLE_DOUBAN_RATING": strconv.FormatBool(c.EnableDoubanRating),
+		"CRAWL_AREA":           c.CrawlArea,
+		"API_CACHE_TTL_DAYS":   strconv.Itoa(c.APICacheTTLDays),
+		"CORS_ALLOWED_ORIGINS": describeCORSOrigins(c.CORSAllowedOrigins),
+		"CRAWL_INTERVAL":       describeCrawlInterval(c.CrawlInterval),
+		"ADMIN_TOKEN":          maskSecret(c.AdminToken),
+	}
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	lines := make([]string, 0, len(names))
+	for _, name := range names {
+		source := "default"
+		if c.fromEnv[name] {
+			source = "env"
+		}
+		lines = append(lines, fmt.Sprintf("%-21s = %s (%s)", name, values[name], source))
+	}
+	return lines
+}
+
+// mustLoadConfig main 启动时加载配置，配置非法时直接退出。
+func mustLoadConfig() Config {
+	cfg, err := loadConfig(os.LookupEnv)
+	if err != nil {
+		fmt.Printf("❌ 配置无效: %v\n", err)
+		os.Exit(1)
+	}
+	return cfg
+}
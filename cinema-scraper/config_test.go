@@ -0,0 +1,39 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+// TestLoadConfig 配置：环境变量覆盖默认值、空 Key 表示不使用、非法值报错、密钥打码。
+func TestLoadConfig(t *testing.T) {
+	newTestRouter(t)
+	setTestClock(t, beforeMidnight)
+
+	env := map[string]string{"TMDB_API_KEY": "", "PORT": "9090", "CRAWL_AREA": "14, 13,14", "ENABLE_DOUBAN_RATING": "true"}
+	cfg, err := loadConfig(func(name string) (string, bool) { v, ok := env[name]; return v, ok })
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, portErr := loadConfig(func(name string) (string, bool) {
+		if name == "PORT" {
+			return "http", true
+		}
+		return "", false
+	})
+	if err := firstError(
+		expectEqual("tmdb key", cfg.TMDBAPIKey, ""),
+		expectEqual("require tmdb", cfg.requireTMDB(), errTMDBKeyMissing),
+		expectEqual("omdb key has no default", cfg.OMDBAPIKey, ""),
+		expectEqual("default config requires tmdb", defaultConfig().requireTMDB(), errTMDBKeyMissing),
+		expectEqual("db path default", cfg.DBPath, defaultDBPath),
+		expectEqual("port", cfg.Port, "9090"),
+		expectEqual("areas", cfg.CrawlArea, "14,13"),
+		expectEqual("area urls", fmt.Sprint(cfg.eigaAreaURLs()), "[https://eiga.com/theater/14/ https://eiga.com/theater/13/]"),
+		expectEqual("douban", cfg.EnableDoubanRating, true),
+		expectEqual("invalid port rejected", portErr != nil, true),
+		expectEqual("mask", maskSecret("0123abcd"), "****abcd"),
+		expectEqual("mask short", maskSecret("abc"), "***")); err != nil {
+		t.Fatal(err)
+	}
+}
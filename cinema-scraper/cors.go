@@ -0,0 +1,140 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ===========================
+// 模块：跨域访问（CORS）
+// 职责：
+// - 前端开发服务器（Vite :5173）或单独部署的静态站点不经代理直接调用 /api 时，按 CORS_ALLOWED_ORIGINS 放行；
+//   默认放行全部来源（*；公开接口不使用 Cookie，见下方说明）；需要限制时显式列出来源，设为空串则不放行
+// - /api/admin 下的管理接口永远不加 CORS 头：浏览器里其他站点的脚本无法跨域调用（预检落到路由上得不到 204）
+// - 预检请求（OPTIONS + Access-Control-Request-Method）在这里直接返回 204，不进入维护模式、语言解析等后续中间件，
+//   也不需要为每个路由单独注册 OPTIONS
+// - 放行的来源为 * 时返回 Access-Control-Allow-Origin: *；列出具体来源时回写请求的 Origin 并加 Vary: Origin
+// 说明：
+// - 接口不使用 Cookie（收藏用 X-Device-Token 头），因此不发送 Access-Control-Allow-Credentials
+// - 不在放行列表中的来源：预检返回 403，普通请求照常处理但不带 CORS 头（浏览器会拦截响应）
+// 环境变量：
+//   CORS_ALLOWED_ORIGINS   逗号分隔的来源，如 https://cinepath.example,http://localhost:5173；默认 * = 放行全部；设为空串 = 不放行
+// ===========================
+
+const (
+	// 公开接口用到的方法（收藏的 PUT / DELETE、提醒确认的 POST）；PATCH 只有管理接口使用，不放行
+	corsAllowMethods = "GET, POST, PUT, DELETE, OPTIONS"
+	// 前端会发送的非简单请求头：JSON 请求体、语言、条件请求、SSE 断点续传、请求 ID、设备令牌
+	corsAllowHeaders = "Content-Type, Accept-Language, If-None-Match, Last-Event-ID, X-Request-ID, X-Device-Token"
+	// 允许前端脚本读取的响应头（Content-Language 等 CORS 安全头无需列出）
+	corsExposeHeaders = "X-Request-ID, ETag, Retry-After, X-As-Of, X-Maintenance"
+	corsMaxAge        = "600" // 预检结果缓存 10 分钟
+)
+
+// parseCORSOrigins 解析逗号分隔的来源列表（纯函数）：去空白、去掉末尾的 /、去重并保持顺序；
+// 每项须为 * 或 scheme://host[:port]（不带路径），至少一个。
+func parseCORSOrigins(s string) ([]string, error) {
+	origins := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSuffix(strings.TrimSpace(part), "/")
+		if part == "" {
+			continue
+		}
+		if part != "*" {
+			u, err := url.Parse(part)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.Path != "" || u.RawQuery != "" {
+				return nil, fmt.Errorf("%q is not an origin like https://example.com or http://localhost:5173", part)
+			}
+		}
+		if !seen[part] {
+			seen[part] = true
+			origins = append(origins, part)
+		}
+	}
+	if len(origins) == 0 {
+		return nil, errors.New("expected * or at least one origin")
+	}
+	return origins, nil
+}
+
+// corsOrigins 生效的放行来源；CORSAllowedOrigins 已在加载时校验，未设置时为 *，设为空串时为空（不放行任何来源）。
+func (c Config) corsOrigins() []string {
+	if c.CORSAllowedOrigins == "" {
+		return nil
+	}
+	origins, err := parseCORSOrigins(c.CORSAllowedOrigins)
+	if err != nil {
+		return nil
+	}
+	return origins
+}
+
+// describeCORSOrigins --print-config 中的放行来源（纯函数），不放行时为 (none)。
+func describeCORSOrigins(s string) string {
+	if s == "" {
+		return "(none)"
+	}
+	return s
+}
+
+// isCORSPath 是否对该路径应用 CORS（纯函数）：/api 下除 /api/admin 以外的接口。
+func isCORSPath(path string) bool {
+	if path != "/api" && !strings.HasPrefix(path, "/api/") {
+		return false
+	}
+	return path != "/api/admin" && !strings.HasPrefix(path, "/api/admin/")
+}
+
+// corsMiddleware 为 /api 下的公开接口加 CORS 头并应答预检；不带 Origin 的请求（同源、curl）与管理接口不受影响。
+// origins 为空时不放行任何来源（预检 403）。
+func corsMiddleware(origins []string) gin.HandlerFunc {
+	allowAll := false
+	allowed := make(map[string]bool, len(origins))
+	for _, o := range origins {
+		if o == "*" {
+			allowAll = true
+		}
+		allowed[o] = true
+	}
+	return func(c *gin.Context) {
+		origin := c.GetHeader("Origin")
+		if origin == "" || !isCORSPath(c.Request.URL.Path) {
+			c.Next()
+			return
+		}
+		preflight := c.Request.Method == http.MethodOptions && c.GetHeader("Access-Control-Request-Method") != ""
+		if !allowAll {
+			// 响应随 Origin 变化，缓存需要区分
+			c.Writer.Header().Add("Vary", "Origin")
+		}
+		if !allowAll && !allowed[origin] {
+			if preflight {
+				c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "origin not allowed"})
+				return
+			}
+			c.Next()
+			return
+		}
+
+		if allowAll {
+			c.Header("Access-Control-Allow-Origin", "*")
+		} else {
+			c.Header("Access-Control-Allow-Origin", origin)
+		}
+		if preflight {
+			c.Header("Access-Control-Allow-Methods", corsAllowMethods)
+			c.Header("Access-Control-Allow-Headers", corsAllowHeaders)
+			c.Header("Access-Control-Max-Age", corsMaxAge)
+			c.AbortWithStatus(http.StatusNoContent)
+			return
+		}
+		c.Header("Access-Control-Expose-Headers", corsExposeHeaders)
+		c.Next()
+	}
+}
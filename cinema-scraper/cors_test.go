@@ -0,0 +1,99 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestCORS CORS：预检直接返回 204 与允许的方法 / 请求头，具体来源回写 Origin，未放行的来源预检 403，默认放行全部（*）、设为空串不放行，管理接口不加 CORS。
+func TestCORS(t *testing.T) {
+	newTestRouter(t)
+	setTestClock(t, beforeMidnight)
+
+	send := func(router http.Handler, method, path, origin, requestMethod string) testResponse {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, nil)
+		if origin != "" {
+			req.Header.Set("Origin", origin)
+		}
+		if requestMethod != "" {
+			req.Header.Set("Access-Control-Request-Method", requestMethod)
+			req.Header.Set("Access-Control-Request-Headers", "content-type, x-device-token")
+		}
+		router.ServeHTTP(rec, req)
+		return testResponse{Status: rec.Code, Header: rec.Header(), Body: rec.Body.Bytes()}
+	}
+	// 默认配置（*）时的真实路由：没有注册 OPTIONS 的接口也能通过预检；管理接口始终不加 CORS
+	open := setupRouter()
+	pre := send(open, http.MethodOptions, "/api/favorites/1", "http://localhost:5173", http.MethodPut)
+	get := send(open, http.MethodGet, "/api/health", "http://localhost:5173", "")
+	sameOrigin := send(open, http.MethodGet, "/api/health", "", "")
+	adminPre := send(open, http.MethodOptions, "/api/admin/movies/1", "http://localhost:5173", http.MethodDelete)
+	adminGet := send(open, http.MethodGet, "/api/admin/slow-queries", "http://localhost:5173", "")
+	// CORS_ALLOWED_ORIGINS 设为空串：不放行任何来源
+	prevOrigins := appConfig.CORSAllowedOrigins
+	appConfig.CORSAllowedOrigins = ""
+	closed := setupRouter()
+	appConfig.CORSAllowedOrigins = prevOrigins
+	closedPre := send(closed, http.MethodOptions, "/api/favorites/1", "http://localhost:5173", http.MethodPut)
+
+	restricted := gin.New()
+	restricted.Use(corsMiddleware([]string{"https://cinepath.example"}), languageMiddleware())
+	restricted.GET("/api/ping", func(c *gin.Context) { c.JSON(http.StatusOK, gin.H{"ok": true}) })
+	allowedPre := send(restricted, http.MethodOptions, "/api/ping", "https://cinepath.example", http.MethodGet)
+	allowedGet := send(restricted, http.MethodGet, "/api/ping", "https://cinepath.example", "")
+	deniedPre := send(restricted, http.MethodOptions, "/api/ping", "https://evil.example", http.MethodGet)
+	deniedGet := send(restricted, http.MethodGet, "/api/ping", "https://evil.example", "")
+
+	origins, err := parseCORSOrigins(" http://localhost:5173/, https://cinepath.example,http://localhost:5173")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, pathErr := parseCORSOrigins("https://cinepath.example/app")
+	_, emptyErr := parseCORSOrigins(" , ")
+	cfg, err := loadConfig(func(name string) (string, bool) {
+		if name == "CORS_ALLOWED_ORIGINS" {
+			return "https://cinepath.example", true
+		}
+		return "", false
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	disabled, err := loadConfig(func(name string) (string, bool) { return "", name == "CORS_ALLOWED_ORIGINS" })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := firstError(
+		expectStatus(pre, http.StatusNoContent),
+		expectEqual("preflight origin", pre.Header.Get("Access-Control-Allow-Origin"), "*"),
+		expectEqual("preflight methods", [2]bool{strings.Contains(pre.Header.Get("Access-Control-Allow-Methods"), "PUT"), strings.Contains(pre.Header.Get("Access-Control-Allow-Methods"), "PATCH")}, [2]bool{true, false}),
+		expectEqual("preflight headers", strings.Contains(pre.Header.Get("Access-Control-Allow-Headers"), "X-Device-Token"), true),
+		expectEqual("preflight max age", pre.Header.Get("Access-Control-Max-Age"), corsMaxAge),
+		expectStatus(get, http.StatusOK),
+		expectEqual("simple request", [2]string{get.Header.Get("Access-Control-Allow-Origin"), get.Header.Get("Access-Control-Expose-Headers")}, [2]string{"*", corsExposeHeaders}),
+		expectEqual("same origin untouched", sameOrigin.Header.Get("Access-Control-Allow-Origin"), ""),
+		expectEqual("admin preflight rejected", adminPre.Status == http.StatusNoContent, false),
+		expectEqual("admin no cors", [2]string{adminPre.Header.Get("Access-Control-Allow-Origin"), adminGet.Header.Get("Access-Control-Allow-Origin")}, [2]string{"", ""}),
+		expectStatus(closedPre, http.StatusForbidden),
+		expectEqual("closed no header", closedPre.Header.Get("Access-Control-Allow-Origin"), ""),
+		expectEqual("cors paths", [4]bool{isCORSPath("/api/movies"), isCORSPath("/api/admin"), isCORSPath("/api/administer"), isCORSPath("/timetable")}, [4]bool{true, false, true, false}),
+		expectStatus(allowedPre, http.StatusNoContent),
+		expectEqual("echo origin", [2]string{allowedPre.Header.Get("Access-Control-Allow-Origin"), allowedGet.Header.Get("Access-Control-Allow-Origin")}, [2]string{"https://cinepath.example", "https://cinepath.example"}),
+		expectEqual("vary", allowedGet.Header.Values("Vary"), []string{"Origin", "Accept-Language"}),
+		expectStatus(deniedPre, http.StatusForbidden),
+		expectStatus(deniedGet, http.StatusOK),
+		expectEqual("denied no header", deniedGet.Header.Get("Access-Control-Allow-Origin"), ""),
+		expectEqual("parse", origins, []string{"http://localhost:5173", "https://cinepath.example"}),
+		expectEqual("path rejected", pathErr != nil, true),
+		expectEqual("empty rejected", emptyErr != nil, true),
+		expectEqual("env", cfg.corsOrigins(), []string{"https://cinepath.example"}),
+		expectEqual("default allows all", defaultConfig().corsOrigins(), []string{"*"}),
+		expectEqual("empty env disables", [2]interface{}{len(disabled.corsOrigins()), describeCORSOrigins(disabled.CORSAllowedOrigins)}, [2]interface{}{0, "(none)"})); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// ===========================
+// 模块：异常抓取保护
+// 职责：
+// - 一次失败的抓取（页面改版导致 0 场次）不应连锁触发状态批量更新、把所有影片标为 unplanned
+// - 本次解析出的场次数低于上次的 minRatio 时，把本次 CrawlRun 标为 abnormal，不生成快照（中途报错的抓取同样检查）
+// - 异常或被中断的抓取都不据此更新影片状态，继续使用上一次的数据：抓取收尾时的状态重算、
+//   update-status、prune-schedules 之后的状态更新与 API 定时抓取统一按 statusUpdateBlocked 判断
+// 调用方式：
+//   go run . crawl-schedules --min-ratio=0.5
+//   go run . update-status --force   # 确认无误后强制执行
+// ===========================
+
+// defaultCrawlMinRatio 默认阈值：本次场次数低于上次的 50% 视为异常。
+const defaultCrawlMinRatio = 0.5
+
+// CrawlRunStatusAbnormal 抓取完成但结果异常。
+const CrawlRunStatusAbnormal = "abnormal"
+
+// crawlParsedShowtimes 本次运行成功写入的场次数（爬虫回调中累加）。
+var crawlParsedShowtimes atomic.Int64
+
+// abnormalCrawlError 抓取结果异常。
+type abnormalCrawlError struct {
+	Parsed   int
+	Previous int
+	MinRatio float64
+}
+
+func (e *abnormalCrawlError) Error() string {
+	return fmt.Sprintf("abnormal crawl: parsed %d showtimes, previous run had %d (min ratio %.2f)", e.Parsed, e.Previous, e.MinRatio)
+}
+
+// isAbnormalCrawl 判断错误是否为异常抓取。
+func isAbnormalCrawl(err error) bool {
+	var target *abnormalCrawlError
+	return errors.As(err, &target)
+}
+
+// flagValue 读取命令行中 --name=value 或 --name value 形式的参数。
+func flagValue(args []string, name string) (string, bool) {
+	for i, arg := range args {
+		if v, ok := strings.CutPrefix(arg, name+"="); ok {
+			return v, true
+		}
+		if arg == name && i+1 < len(args) {
+			return args[i+1], true
+		}
+	}
+	return "", false
+}
+
+// parseMinRatioFlag 解析 --min-ratio，非法值回退到默认阈值。
+func parseMinRatioFlag(args []string) float64 {
+	if v, ok := flagValue(args, "--min-ratio"); ok {
+		if r, err := strconv.ParseFloat(v, 64); err == nil && r >= 0 && r <= 1 {
+			return r
+		}
+	}
+	return defaultCrawlMinRatio
+}
+
+// checkCrawlHealth 对比本次与上次的场次数（纯函数）；上次没有数据时总是视为正常。
+func checkCrawlHealth(parsed, previous int, minRatio float64) error {
+	if previous <= 0 {
+		return nil
+	}
+	if float64(parsed) < float64(previous)*minRatio {
+		return &abnormalCrawlError{Parsed: parsed, Previous: previous, MinRatio: minRatio}
+	}
+	return nil
+}
+
+// previousParsedCount 上一次成功抓取的场次数；旧记录没有 ParsedCount 时用快照中的排片总数兜底。
+func previousParsedCount() int {
+	var run CrawlRun
+	if err := db.Where("kind = ? AND status = ?", "schedules", "success").
+		Order("id DESC").First(&run).Error; err != nil {
+		return 0
+	}
+	if run.ParsedCount > 0 {
+		return run.ParsedCount
+	}
+	snap, err := parseCrawlSnapshot(run)
+	if err != nil {
+		return 0
+	}
+	total := 0
+	for _, n := range snap.CinemaCounts {
+		total += n
+	}
+	return total
+}
+
+// crawlRunStatusOf 抓取结果对应的 CrawlRun 状态（纯函数）：success / abnormal / interrupted / failed。
+func crawlRunStatusOf(err error) string {
+	switch {
+	case err == nil:
+		return "success"
+	case isAbnormalCrawl(err):
+		return CrawlRunStatusAbnormal
+	case errors.Is(err, errInterrupted):
+		return CrawlRunStatusInterrupted
+	}
+	return "failed"
+}
+
+// statusUpdateBlocked 该状态的抓取是否禁止据此更新影片状态（纯函数）：异常（场次数骤减）或被中断（只覆盖部分影院）。
+func statusUpdateBlocked(status string) bool {
+	return status == CrawlRunStatusAbnormal || status == CrawlRunStatusInterrupted
+}
+
+// latestCrawlBlocksStatusUpdate 最近一次结束的排片抓取是否禁止更新影片状态，同时返回该次抓取（没有时为 nil）。
+func latestCrawlBlocksStatusUpdate() (bool, *CrawlRun) {
+	var run CrawlRun
+	if err := db.Where("kind = ? AND status <> ?", "schedules", "running").
+		Order("id DESC").Limit(1).Find(&run).Error; err != nil || run.ID == 0 {
+		return false, nil
+	}
+	return statusUpdateBlocked(run.Status), &run
+}
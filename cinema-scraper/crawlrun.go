@@ -0,0 +1,442 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ===========================
+// 模块：抓取批次记录（CrawlRun）与变更快照
+// 职责：
+// - 每次 crawl-schedules 记录一条 CrawlRun（开始 / 结束时间、结果）
+// - 成功结束时对聚合状态做快照（影片集合、各影院未来排片数、未来排片 ID），并刷新开放数据集（见 dataset.go）
+// - 对比相邻两次快照，回答“昨天到今天发生了什么变化”：GET /api/changes，
+//   以及每次成功抓取后发出的 crawl.changes 事件（webhook / SSE，见 movieevents.go）
+// ===========================
+
+// CrawlRun 抓取批次表。
+type CrawlRun struct {
+	ID           uint      `gorm:"primaryKey"`
+	Kind         string    // schedules / cinemas
+	Status       string    // running / success / failed / abnormal / interrupted
+	StartedAt    time.Time // 开始时间
+	FinishedAt   time.Time // 结束时间（running 时为零值）
+	Error        string    // 失败原因
+	SnapshotJSON string    `gorm:"type:text"` // 结束时的聚合快照，见 CrawlSnapshot
+	// 解析异常列表（JSON），见 parsedebug.go
+	AnomaliesJSON string `gorm:"type:text"`
+	// 本次写入的场次数，用于异常抓取判断（见 crawlguard.go）
+	ParsedCount int
+}
+
+// CrawlSnapshot 某次抓取结束时的聚合状态。
+type CrawlSnapshot struct {
+	Date           string            `json:"date"`             // 快照当天（YYYY-MM-DD）
+	MovieIDs       []uint            `json:"movie_ids"`        // 全部影片
+	ActiveMovieIDs []uint            `json:"active_movie_ids"` // 有今天及未来排片的影片
+	CinemaCounts   map[uint]int      `json:"cinema_counts"`    // 影院 -> 今天及未来排片数
+	Schedules      map[string][]uint `json:"schedules"`        // 日期 -> 今天及未来的排片 ID
+}
+
+// cinemaCountChangeRatio 影院排片数变化超过该比例才视为“显著变化”。
+const cinemaCountChangeRatio = 0.3
+
+// startCrawlRun 新建一条 running 状态的 CrawlRun。
+func startCrawlRun(kind string) (*CrawlRun, error) {
+	run := &CrawlRun{
+		Kind:      kind,
+		Status:    "running",
+		StartedAt: time.Now(),
+	}
+	if err := db.Create(run).Error; err != nil {
+		return nil, err
+	}
+	return run, nil
+}
+
+// resetCrawlCounters 清空上一次抓取在进程内累计的计数与解析异常；API 进程内定时抓取（见 crawlscheduler.go）
+// 会多次运行，每次开始前需要重置。
+func resetCrawlCounters() {
+	crawlParsedShowtimes.Store(0)
+	crawlPrunedShowtimes.Store(0)
+	parseAnomalies.Lock()
+	parseAnomalies.items = nil
+	parseAnomalies.Unlock()
+	// 上一次被判定异常 / 中断而没有重算状态的影片不带入本次
+	crawlTouchedMovies.Lock()
+	crawlTouchedMovies.ids = make(map[uint]bool)
+	crawlTouchedMovies.Unlock()
+}
+
+// runScheduleCrawl 完整执行一次排片抓取：记录 CrawlRun，补投失败事件，抓取 eiga.com，再由 completeScheduleCrawl 收尾。
+// crawl-schedules 命令与 API 进程内定时抓取共用。
+// 返回本次的 CrawlRun（开始记录失败时为 nil）与抓取结果（异常 / 中断 / 失败时非 nil）。
+func runScheduleCrawl(ctx context.Context, minRatio float64) (*CrawlRun, error) {
+	resetCrawlCounters()
+	run, err := startCrawlRun("schedules")
+	if err != nil {
+		return nil, err
+	}
+	if n := redeliverPendingEvents(); n > 0 {
+		fmt.Printf("📣 已补投 %d 个之前投递失败的事件\n", n)
+	}
+	return run, completeScheduleCrawl(run, syncSchedulesFromEiga(ctx), minRatio)
+}
+
+// completeScheduleCrawl 抓取结束后的收尾：去重 / 合并影片，按 minRatio 检查场次数是否异常，
+// 正常时按全部影院的排片重算状态，最后结束 CrawlRun；返回最终的抓取结果。
+func completeScheduleCrawl(run *CrawlRun, syncErr error, minRatio float64) error {
+	runEigaDedupeAfterCrawl()
+	runAutoMergeAfterCrawl()
+	run.ParsedCount = int(crawlParsedShowtimes.Load())
+	// 中途报错的抓取也检查场次数：场次骤减时记为异常，而不是普通失败；被中断的抓取本来就不完整，不参与比较
+	if !errors.Is(syncErr, errInterrupted) {
+		if health := checkCrawlHealth(run.ParsedCount, previousParsedCount(), minRatio); health != nil {
+			syncErr = errors.Join(health, syncErr)
+		}
+	}
+	// 全部影院抓完（并合并重复影片）后再统一重算状态，见 statusrules.go；
+	// 与 update-status 使用同一判断：异常或被中断的抓取只有部分数据，不据此改状态，继续使用上一次的结果
+	if status := crawlRunStatusOf(syncErr); statusUpdateBlocked(status) {
+		fmt.Printf("⏸️ 本次抓取为 %s，跳过影片状态更新\n", status)
+	} else if n := refreshCrawledMovieStatuses(); n > 0 {
+		fmt.Printf("🔄 已按全部影院的排片更新 %d 部影片的状态\n", n)
+	}
+	finishCrawlRun(run, syncErr)
+	// 同一进程内的缓存（schedules_as_of、全东京时间表）立即失效，不等 TTL
+	invalidateScheduleFreshness()
+	invalidateCityTimetable()
+	if schedulePruneEnabled {
+		fmt.Printf("🧹 共清理 %d 个已从 eiga.com 消失的场次\n", crawlPrunedShowtimes.Load())
+	} else {
+		fmt.Println("⏸️ 已指定 --no-prune，本次未清理消失的场次")
+	}
+	if isAbnormalCrawl(syncErr) {
+		fmt.Println("🚨🚨🚨 [crawl-schedules] 本次抓取结果异常，已跳过快照；update-status 将拒绝执行，继续使用上一次的数据。")
+		fmt.Println("🚨 请检查 debug/ 下的 HTML 快照，确认无误后可用 `go run . update-status --force` 强制更新状态。")
+	} else if errors.Is(syncErr, errInterrupted) {
+		fmt.Println("⏸️ [crawl-schedules] 本次抓取被中断，update-status 将拒绝执行，直到下一次完整抓取（或使用 --force）。")
+	}
+	return syncErr
+}
+
+// finishCrawlRun 结束一次抓取：成功时写入快照并刷新开放数据集，失败时记录错误。
+func finishCrawlRun(run *CrawlRun, runErr error) {
+	run.FinishedAt = time.Now()
+	run.AnomaliesJSON = parseAnomaliesJSON()
+	// 异常抓取不生成快照，变更报告与后续对比继续以上一次成功抓取为基准；
+	// 被中断的抓取只覆盖了部分影院，同样不生成快照（见 shutdown.go）
+	run.Status = crawlRunStatusOf(runErr)
+	if runErr != nil {
+		run.Error = runErr.Error()
+	} else {
+		if snap, err := takeCrawlSnapshot(); err == nil {
+			if b, err := json.Marshal(snap); err == nil {
+				run.SnapshotJSON = string(b)
+			}
+		} else {
+			fmt.Printf("⚠️ 生成抓取快照失败: %v\n", err)
+		}
+	}
+	if err := db.Save(run).Error; err != nil {
+		fmt.Printf("⚠️ 保存 CrawlRun 失败: %v\n", err)
+	}
+	if run.Status == "success" {
+		exportDatasetAfterCrawl(run)
+		emitCrawlChangesEvent(*run)
+	}
+}
+
+// takeCrawlSnapshot 从当前数据库聚合出快照。
+func takeCrawlSnapshot() (CrawlSnapshot, error) {
+	today := todayJST()
+	snap := CrawlSnapshot{
+		Date:         today,
+		CinemaCounts: map[uint]int{},
+		Schedules:    map[string][]uint{},
+	}
+
+	if err := db.Model(&Movie{}).Order("id").Pluck("id", &snap.MovieIDs).Error; err != nil {
+		return snap, err
+	}
+
+	var schedules []Schedule
+	if err := db.Where("date(play_date) >= ?", today).Order("id").Find(&schedules).Error; err != nil {
+		return snap, err
+	}
+	active := make(map[uint]struct{})
+	for _, s := range schedules {
+		active[s.MovieID] = struct{}{}
+		snap.CinemaCounts[s.CinemaID]++
+		date := s.PlayDate.Format("2006-01-02")
+		snap.Schedules[date] = append(snap.Schedules[date], s.ID)
+	}
+	snap.ActiveMovieIDs = sortedIDs(active)
+	return snap, nil
+}
+
+// CinemaCountChange 单个影院未来排片数的显著变化。
+type CinemaCountChange struct {
+	CinemaID uint `json:"cinema_id"`
+	Before   int  `json:"before"`
+	After    int  `json:"after"`
+}
+
+// CrawlDiff 两次快照之间的差异。
+type CrawlDiff struct {
+	NewMovieIDs        []uint              `json:"new_movie_ids"`        // 新增的影片
+	LeftMovieIDs       []uint              `json:"left_movie_ids"`       // 不再有未来排片的影片
+	CinemaChanges      []CinemaCountChange `json:"cinema_changes"`       // 排片数显著变化的影院
+	RemovedScheduleIDs []uint              `json:"removed_schedule_ids"` // 被撤下的排片
+}
+
+// diffCrawlSnapshots 计算 prev -> curr 的变化。
+// 说明：prev 中日期早于 curr.Date 的排片属于自然过期，不计入“被撤下”。
+func diffCrawlSnapshots(prev, curr CrawlSnapshot) CrawlDiff {
+	diff := CrawlDiff{
+		NewMovieIDs:        []uint{},
+		LeftMovieIDs:       []uint{},
+		CinemaChanges:      []CinemaCountChange{},
+		RemovedScheduleIDs: []uint{},
+	}
+
+	prevMovies := idSet(prev.MovieIDs)
+	for _, id := range curr.MovieIDs {
+		if _, ok := prevMovies[id]; !ok {
+			diff.NewMovieIDs = append(diff.NewMovieIDs, id)
+		}
+	}
+
+	currActive := idSet(curr.ActiveMovieIDs)
+	for _, id := range prev.ActiveMovieIDs {
+		if _, ok := currActive[id]; !ok {
+			diff.LeftMovieIDs = append(diff.LeftMovieIDs, id)
+		}
+	}
+
+	cinemaIDs := make(map[uint]struct{})
+	for id := range prev.CinemaCounts {
+		cinemaIDs[id] = struct{}{}
+	}
+	for id := range curr.CinemaCounts {
+		cinemaIDs[id] = struct{}{}
+	}
+	for _, id := range sortedIDs(cinemaIDs) {
+		before, after := prev.CinemaCounts[id], curr.CinemaCounts[id]
+		if isSignificantCountChange(before, after) {
+			diff.CinemaChanges = append(diff.CinemaChanges, CinemaCountChange{CinemaID: id, Before: before, After: after})
+		}
+	}
+
+	currSchedules := make(map[uint]struct{})
+	for _, ids := range curr.Schedules {
+		for _, id := range ids {
+			currSchedules[id] = struct{}{}
+		}
+	}
+	removed := make(map[uint]struct{})
+	for date, ids := range prev.Schedules {
+		if date < curr.Date {
+			continue
+		}
+		for _, id := range ids {
+			if _, ok := currSchedules[id]; !ok {
+				removed[id] = struct{}{}
+			}
+		}
+	}
+	diff.RemovedScheduleIDs = sortedIDs(removed)
+	return diff
+}
+
+// isSignificantCountChange 判断排片数变化是否显著：从无到有 / 从有到无，或变化比例超过阈值。
+func isSignificantCountChange(before, after int) bool {
+	if before == after {
+		return false
+	}
+	if before == 0 || after == 0 {
+		return true
+	}
+	delta := float64(after - before)
+	if delta < 0 {
+		delta = -delta
+	}
+	return delta/float64(before) >= cinemaCountChangeRatio
+}
+
+// idSet 将 ID 切片转为集合。
+func idSet(ids []uint) map[uint]struct{} {
+	set := make(map[uint]struct{}, len(ids))
+	for _, id := range ids {
+		set[id] = struct{}{}
+	}
+	return set
+}
+
+// sortedIDs 将 ID 集合转为升序切片。
+func sortedIDs(set map[uint]struct{}) []uint {
+	out := make([]uint, 0, len(set))
+	for id := range set {
+		out = append(out, id)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i] < out[j] })
+	return out
+}
+
+// parseCrawlSnapshot 解析 CrawlRun 上的快照。
+func parseCrawlSnapshot(run CrawlRun) (CrawlSnapshot, error) {
+	var snap CrawlSnapshot
+	if run.SnapshotJSON == "" {
+		return snap, errors.New("crawl run has no snapshot")
+	}
+	err := json.Unmarshal([]byte(run.SnapshotJSON), &snap)
+	return snap, err
+}
+
+// findBaselineRun 解析 since 参数：纯数字视为 CrawlRun ID，否则视为日期（YYYY-MM-DD），
+// 取该日期之前最后一次成功的抓取；不传时取最新一次之前的那次成功抓取。
+func findBaselineRun(since string, latest CrawlRun) (CrawlRun, error) {
+	var run CrawlRun
+	tx := db.Where("kind = ? AND status = ? AND snapshot_json <> ''", latest.Kind, "success")
+	if since == "" {
+		return run, tx.Where("id < ?", latest.ID).Order("id DESC").First(&run).Error
+	}
+	if id, err := strconv.ParseUint(since, 10, 64); err == nil {
+		return run, tx.Where("id = ?", id).First(&run).Error
+	}
+	day, err := time.ParseInLocation("2006-01-02", since, tokyoLocation)
+	if err != nil {
+		return run, err
+	}
+	return run, tx.Where("finished_at < ?", day).Order("finished_at DESC").First(&run).Error
+}
+
+// ChangedMovie 变更报告中的影片摘要。
+type ChangedMovie struct {
+	ID    uint   `json:"id"`
+	Title string `json:"title"`
+}
+
+// CrawlChangeReport 两次成功抓取之间的变更报告：/api/changes 的响应，也是 crawl.changes 事件的 changes 字段。
+type CrawlChangeReport struct {
+	FromRunID        uint                `json:"from_run_id"`
+	ToRunID          uint                `json:"to_run_id"`
+	From             time.Time           `json:"from"`
+	To               time.Time           `json:"to"`
+	NewMovies        []ChangedMovie      `json:"new_movies"`
+	LeftMovies       []ChangedMovie      `json:"left_movies"`
+	CinemaChanges    []CinemaCountChange `json:"cinema_changes"`
+	RemovedSchedules int                 `json:"removed_schedules"`
+	ParseAnomalies   []ParseAnomaly      `json:"parse_anomalies"`
+}
+
+// buildChangeReport 由基准与最新两次抓取的快照生成变更报告。
+func buildChangeReport(baseline, latest CrawlRun, prev, curr CrawlSnapshot) CrawlChangeReport {
+	diff := diffCrawlSnapshots(prev, curr)
+	return CrawlChangeReport{
+		FromRunID:        baseline.ID,
+		ToRunID:          latest.ID,
+		From:             baseline.FinishedAt,
+		To:               latest.FinishedAt,
+		NewMovies:        loadChangedMovies(diff.NewMovieIDs),
+		LeftMovies:       loadChangedMovies(diff.LeftMovieIDs),
+		CinemaChanges:    diff.CinemaChanges,
+		RemovedSchedules: len(diff.RemovedScheduleIDs),
+		ParseAnomalies:   parseRunAnomalies(latest),
+	}
+}
+
+// latestChangeReport 刚结束的抓取 latest 相对上一次成功抓取的变更报告；没有上一次（首次抓取）时返回 false。
+func latestChangeReport(latest CrawlRun) (CrawlChangeReport, bool, error) {
+	baseline, err := findBaselineRun("", latest)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return CrawlChangeReport{}, false, nil
+	}
+	if err != nil {
+		return CrawlChangeReport{}, false, err
+	}
+	prev, err := parseCrawlSnapshot(baseline)
+	if err != nil {
+		return CrawlChangeReport{}, false, err
+	}
+	curr, err := parseCrawlSnapshot(latest)
+	if err != nil {
+		return CrawlChangeReport{}, false, err
+	}
+	return buildChangeReport(baseline, latest, prev, curr), true, nil
+}
+
+// listChangesHandler 变更报告接口：
+// - GET /api/changes?since=<run-id|date>
+// - 对比基准抓取与最近一次成功抓取的快照，返回新增 / 下映影片、显著变化的影院、被撤下的排片。
+func listChangesHandler(c *gin.Context) {
+	var latest CrawlRun
+	if err := db.Where("kind = ? AND status = ? AND snapshot_json <> ''", "schedules", "success").
+		Order("id DESC").First(&latest).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "no successful crawl run"})
+		return
+	}
+
+	baseline, err := findBaselineRun(c.Query("since"), latest)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "baseline crawl run not found"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid since parameter"})
+		return
+	}
+
+	prev, err := parseCrawlSnapshot(baseline)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to read baseline snapshot"})
+		return
+	}
+	curr, err := parseCrawlSnapshot(latest)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to read latest snapshot"})
+		return
+	}
+
+	c.JSON(http.StatusOK, buildChangeReport(baseline, latest, prev, curr))
+}
+
+// parseRunAnomalies 解析 CrawlRun 上记录的解析异常。
+func parseRunAnomalies(run CrawlRun) []ParseAnomaly {
+	out := []ParseAnomaly{}
+	if run.AnomaliesJSON != "" {
+		json.Unmarshal([]byte(run.AnomaliesJSON), &out)
+	}
+	return out
+}
+
+// loadChangedMovies 按 ID 加载影片标题（已删除的影片保留 ID，标题兜底）。
+func loadChangedMovies(ids []uint) []ChangedMovie {
+	out := make([]ChangedMovie, 0, len(ids))
+	if len(ids) == 0 {
+		return out
+	}
+	var movies []Movie
+	db.Where("id IN ?", ids).Find(&movies)
+	byID := make(map[uint]Movie, len(movies))
+	for _, m := range movies {
+		byID[m.ID] = m
+	}
+	for _, id := range ids {
+		m, ok := byID[id]
+		if !ok {
+			m = Movie{ID: id}
+		}
+		out = append(out, ChangedMovie{ID: id, Title: movieDisplayTitle(m)})
+	}
+	return out
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestDiffCrawlSnapshots 变化报告：连续三次抓取的快照两两对比，新增 / 下映影片、显著变化的影院与被撤下的排片，自然过期不计。
+func TestDiffCrawlSnapshots(t *testing.T) {
+	setTestClock(t, beforeMidnight)
+
+	runs := []CrawlSnapshot{
+		{Date: "2026-01-26", MovieIDs: []uint{1, 2, 3}, ActiveMovieIDs: []uint{1, 2, 3},
+			CinemaCounts: map[uint]int{1: 10, 2: 10, 3: 4},
+			Schedules:    map[string][]uint{"2026-01-26": {100, 101}, "2026-01-27": {102, 103}, "2026-01-28": {104}}},
+		{Date: "2026-01-27", MovieIDs: []uint{1, 2, 3, 4}, ActiveMovieIDs: []uint{1, 3, 4},
+			CinemaCounts: map[uint]int{1: 10, 2: 4, 4: 3},
+			Schedules:    map[string][]uint{"2026-01-27": {102}, "2026-01-28": {104, 105}}},
+		{Date: "2026-01-28", MovieIDs: []uint{1, 2, 3, 4}, ActiveMovieIDs: []uint{1, 3, 4},
+			CinemaCounts: map[uint]int{1: 11, 2: 4, 4: 3},
+			Schedules:    map[string][]uint{"2026-01-28": {104, 105}}},
+	}
+	first := diffCrawlSnapshots(CrawlSnapshot{}, runs[0])
+	second := diffCrawlSnapshots(runs[0], runs[1])
+	third := diffCrawlSnapshots(runs[1], runs[2])
+	quiet, err := json.Marshal(third)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := firstError(
+		expectEqual("first run new", first.NewMovieIDs, []uint{1, 2, 3}),
+		expectEqual("first run cinemas", first.CinemaChanges, []CinemaCountChange{{1, 0, 10}, {2, 0, 10}, {3, 0, 4}}),
+		expectEqual("new", second.NewMovieIDs, []uint{4}),
+		expectEqual("left", second.LeftMovieIDs, []uint{2}),
+		expectEqual("cinemas", second.CinemaChanges, []CinemaCountChange{{2, 10, 4}, {3, 4, 0}, {4, 0, 3}}),
+		expectEqual("removed", second.RemovedScheduleIDs, []uint{103}),
+		expectEqual("quiet run", string(quiet), `{"new_movie_ids":[],"left_movie_ids":[],"cinema_changes":[],"removed_schedule_ids":[]}`),
+		expectEqual("threshold", [3]bool{isSignificantCountChange(10, 13), isSignificantCountChange(10, 12), isSignificantCountChange(0, 0)}, [3]bool{true, false, false})); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestCompleteScheduleCrawl 抓取收尾：先检查场次数（中途报错的抓取也检查），异常或被中断的抓取不重算状态，update-status 同样拒绝。
+func TestCompleteScheduleCrawl(t *testing.T) {
+	newTestRouter(t)
+	now := testNoon
+	setTestClock(t, now)
+
+	movie := Movie{TitleJP: "テスト異常抓取", Status: "incoming"}
+	if err := db.Create(&movie).Error; err != nil {
+		t.Fatal(err)
+	}
+	show := Schedule{MovieID: movie.ID, CinemaID: 6, PlayDate: time.Date(2026, 1, 27, 0, 0, 0, 0, time.UTC), StartTime: "18:30"}
+	previous := CrawlRun{Kind: "schedules", Status: "success", StartedAt: now.Add(-time.Hour), FinishedAt: now.Add(-time.Hour), ParsedCount: 100}
+	if err := firstError(db.Create(&show).Error, db.Create(&previous).Error); err != nil {
+		t.Fatal(err)
+	}
+	results := make([]string, 0, 3)
+	for _, syncErr := range []error{nil, errors.New("visit https://eiga.com/theater/13/: dial tcp"), errInterrupted} {
+		resetCrawlCounters()
+		crawlParsedShowtimes.Store(3) // 上次 100 个场次，低于默认阈值
+		markCrawlTouchedMovie(movie.ID)
+		run := CrawlRun{Kind: "schedules", Status: "running", StartedAt: now}
+		if err := db.Create(&run).Error; err != nil {
+			t.Fatal(err)
+		}
+		completeScheduleCrawl(&run, syncErr, defaultCrawlMinRatio)
+		var after Movie
+		if err := db.First(&after, movie.ID).Error; err != nil {
+			t.Fatal(err)
+		}
+		results = append(results, run.Status+":"+after.Status)
+	}
+	resetCrawlCounters()
+	blocked, latest := latestCrawlBlocksStatusUpdate()
+	if err := firstError(
+		expectEqual("status kept", strings.Join(results, ","), "abnormal:incoming,abnormal:incoming,interrupted:incoming"),
+		expectEqual("touched set reset", refreshCrawledMovieStatuses(), 0),
+		expectEqual("update-status blocked", blocked && latest != nil && latest.Status == CrawlRunStatusInterrupted, true),
+		expectEqual("gate", [4]bool{statusUpdateBlocked("success"), statusUpdateBlocked("failed"), statusUpdateBlocked(CrawlRunStatusAbnormal), statusUpdateBlocked(CrawlRunStatusInterrupted)},
+			[4]bool{false, false, true, true})); err != nil {
+		t.Fatal(err)
+	}
+}
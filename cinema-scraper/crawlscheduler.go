@@ -0,0 +1,267 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ===========================
+// 模块：API 进程内定时抓取（CRAWL_INTERVAL / --with-scheduler）
+// 职责：
+// - 不再依赖外部 cron：API 运行期间每隔 CRAWL_INTERVAL 在后台 goroutine 中执行一次排片抓取（与 crawl-schedules 相同，
+//   见 crawlrun.go 的 runScheduleCrawl），随后执行 update-status（最近一次抓取异常或被中断时与命令一样不执行）
+// - 抓取由 crawlRunMu 互斥：上一次还没结束时本轮直接跳过（计入 skipped），不排队
+// - 首次运行时间按最近一次结束的排片抓取推算：从未抓取或距今已超过间隔时启动后立即抓取，否则等到“上次结束 + 间隔”
+// - 只读维护模式下跳过（抓取会写库）；收到退出信号后当前抓取写完当前页面即结束，服务等它收尾后再退出
+// - GET /api/admin/crawl-status：定时器状态、本进程最近一次定时运行的开始时间 / 耗时 / 结果，
+//   以及数据库中最近一次结束的排片抓取（含外部 cron 触发的）与排片数据新鲜度
+// 说明：互斥只在本进程内有效；启用定时抓取后请停掉外部 cron，避免两个进程同时抓取。
+// 调用方式：
+//   CRAWL_INTERVAL=6h go run .
+//   go run . --with-scheduler          未设置 CRAWL_INTERVAL 时按默认间隔 6h
+// ===========================
+
+const (
+	defaultCrawlInterval = 6 * time.Hour
+	minCrawlInterval     = 30 * time.Minute // eiga.com 一轮抓取本身要十几分钟，间隔再短没有意义
+)
+
+// ScheduledCrawlResult 本进程最近一次定时运行的结果（/api/admin/crawl-status 的 last_run）。
+type ScheduledCrawlResult struct {
+	CrawlRunID      *uint     `json:"crawl_run_id"`
+	StartedAt       time.Time `json:"started_at"`
+	FinishedAt      time.Time `json:"finished_at"`
+	DurationSeconds float64   `json:"duration_seconds"`
+	Result          string    `json:"result"` // success / failed / abnormal / interrupted，同 CrawlRun.Status
+	Error           string    `json:"error,omitempty"`
+	StatusUpdated   bool      `json:"status_updated"` // 抓取后是否执行了 update-status
+}
+
+// crawlScheduler 定时器状态（定时 goroutine 与接口并发读写，需加锁）。
+var crawlScheduler struct {
+	sync.Mutex
+	enabled   bool
+	interval  time.Duration
+	running   bool
+	nextRunAt *time.Time
+	runs      int // 实际执行的次数
+	skipped   int // 因上一次未结束或只读模式跳过的次数
+	lastRun   *ScheduledCrawlResult
+}
+
+// crawlRunMu 进程内的抓取互斥：TryLock 失败说明上一次抓取还在运行。
+var crawlRunMu sync.Mutex
+
+// crawlSchedulerWG 定时 goroutine 与正在进行的抓取，服务退出前等待它们结束。
+var crawlSchedulerWG sync.WaitGroup
+
+// scheduledCrawl 定时运行实际执行的抓取；测试替换为不访问网络的版本。
+var scheduledCrawl = func(ctx context.Context) (*CrawlRun, error) {
+	return runScheduleCrawl(ctx, defaultCrawlMinRatio)
+}
+
+// parseCrawlInterval 解析 CRAWL_INTERVAL（纯函数）：Go 时长（如 6h、90m），0 / off 表示不启用；启用时不得短于 minCrawlInterval。
+func parseCrawlInterval(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if s == "0" || strings.EqualFold(s, "off") {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a duration like 6h or 90m", s)
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("%q must not be negative", s)
+	}
+	if d > 0 && d < minCrawlInterval {
+		return 0, fmt.Errorf("%q is shorter than the minimum %s", s, minCrawlInterval)
+	}
+	return d, nil
+}
+
+// describeCrawlInterval --print-config 与接口中的间隔（纯函数），不启用时为 disabled。
+func describeCrawlInterval(d time.Duration) string {
+	if d <= 0 {
+		return "disabled"
+	}
+	return d.String()
+}
+
+// schedulerInterval 生效的定时抓取间隔：CRAWL_INTERVAL 优先，只指定 --with-scheduler 时取默认间隔，都没有时为 0。
+func schedulerInterval(cfg Config, args []string) time.Duration {
+	if cfg.CrawlInterval > 0 {
+		return cfg.CrawlInterval
+	}
+	if hasFlag(args, "--with-scheduler") {
+		return defaultCrawlInterval
+	}
+	return 0
+}
+
+// firstScheduledCrawl 首次定时抓取的时间（纯函数）：从未抓取或上次结束已超过间隔时为 now，否则为上次结束 + 间隔。
+func firstScheduledCrawl(lastFinished *time.Time, now time.Time, interval time.Duration) time.Time {
+	if lastFinished == nil {
+		return now
+	}
+	if next := lastFinished.Add(interval); next.After(now) {
+		return next
+	}
+	return now
+}
+
+// latestScheduleCrawl 数据库中最近一次结束的排片抓取（不论结果），没有时返回 nil。
+func latestScheduleCrawl() *CrawlRun {
+	var run CrawlRun
+	if err := db.Where("kind = ? AND status <> ?", "schedules", "running").
+		Order("id DESC").Limit(1).Find(&run).Error; err != nil || run.ID == 0 {
+		return nil
+	}
+	return &run
+}
+
+// noteCrawlSkipped 记录一次跳过。
+func noteCrawlSkipped(reason string) {
+	crawlScheduler.Lock()
+	crawlScheduler.skipped++
+	crawlScheduler.Unlock()
+	fmt.Printf("⏭️ [scheduler] 跳过本轮定时抓取：%s\n", reason)
+}
+
+// runScheduledCrawlOnce 执行一次定时抓取 + update-status；上一次还在运行或处于只读模式时跳过并返回 false。
+func runScheduledCrawlOnce(ctx context.Context) bool {
+	if !crawlRunMu.TryLock() {
+		noteCrawlSkipped("上一次抓取尚未结束")
+		return false
+	}
+	defer crawlRunMu.Unlock()
+	if isReadOnly() {
+		noteCrawlSkipped("只读维护模式")
+		return false
+	}
+
+	started := time.Now()
+	crawlScheduler.Lock()
+	crawlScheduler.running = true
+	crawlScheduler.runs++
+	crawlScheduler.Unlock()
+	fmt.Printf("⏰ [scheduler] 开始定时抓取排片（%s）\n", started.In(tokyoLocation).Format("2006-01-02 15:04"))
+
+	run, err := scheduledCrawl(ctx)
+	result := ScheduledCrawlResult{StartedAt: started, Result: crawlRunStatusOf(err)}
+	if run != nil {
+		id := run.ID
+		result.CrawlRunID = &id
+	}
+	if err != nil {
+		result.Error = err.Error()
+	}
+	// 与 update-status 命令相同：最近一次抓取异常或被中断时不更新状态，留给下一轮
+	if blocked, _ := latestCrawlBlocksStatusUpdate(); !blocked && !statusUpdateBlocked(result.Result) {
+		if err := updateMovieStatusFromSchedules(); err != nil {
+			result.Error = strings.TrimPrefix(result.Error+"; update-status: "+err.Error(), "; ")
+		} else {
+			result.StatusUpdated = true
+		}
+	}
+	result.FinishedAt = time.Now()
+	result.DurationSeconds = result.FinishedAt.Sub(started).Seconds()
+	invalidateScheduleFreshness()
+	invalidateCityTimetable()
+
+	crawlScheduler.Lock()
+	crawlScheduler.running = false
+	crawlScheduler.lastRun = &result
+	crawlScheduler.Unlock()
+	fmt.Printf("⏰ [scheduler] 定时抓取结束：%s，用时 %s，update-status=%v\n",
+		result.Result, result.FinishedAt.Sub(started).Round(time.Second), result.StatusUpdated)
+	return true
+}
+
+// startCrawlScheduler 启动定时抓取：到点后在单独的 goroutine 中运行，上一次未结束时由 crawlRunMu 跳过；ctx 取消后停止。
+func startCrawlScheduler(ctx context.Context, interval time.Duration) {
+	var lastFinished *time.Time
+	if run := latestScheduleCrawl(); run != nil {
+		lastFinished = &run.FinishedAt
+	}
+	next := firstScheduledCrawl(lastFinished, time.Now(), interval)
+
+	crawlScheduler.Lock()
+	crawlScheduler.enabled = true
+	crawlScheduler.interval = interval
+	crawlScheduler.Unlock()
+
+	crawlSchedulerWG.Add(1)
+	go func() {
+		defer crawlSchedulerWG.Done()
+		for {
+			at := next
+			crawlScheduler.Lock()
+			crawlScheduler.nextRunAt = &at
+			crawlScheduler.Unlock()
+
+			timer := time.NewTimer(time.Until(next))
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+			next = next.Add(interval)
+			crawlSchedulerWG.Add(1)
+			go func() {
+				defer crawlSchedulerWG.Done()
+				defer recoverAndLog("定时抓取")
+				runScheduledCrawlOnce(ctx)
+			}()
+		}
+	}()
+}
+
+// waitCrawlScheduler 服务退出前等待定时 goroutine 与正在进行的抓取收尾。
+func waitCrawlScheduler() {
+	crawlSchedulerWG.Wait()
+}
+
+// crawlStatusHandler 抓取状态：GET /api/admin/crawl-status
+func crawlStatusHandler(c *gin.Context) {
+	crawlScheduler.Lock()
+	scheduler := gin.H{
+		"enabled":     crawlScheduler.enabled,
+		"interval":    describeCrawlInterval(crawlScheduler.interval),
+		"running":     crawlScheduler.running,
+		"next_run_at": crawlScheduler.nextRunAt,
+		"runs":        crawlScheduler.runs,
+		"skipped":     crawlScheduler.skipped,
+	}
+	lastRun := crawlScheduler.lastRun
+	crawlScheduler.Unlock()
+
+	var latest gin.H
+	if run := latestScheduleCrawl(); run != nil {
+		latest = gin.H{
+			"id":               run.ID,
+			"status":           run.Status,
+			"started_at":       run.StartedAt,
+			"finished_at":      run.FinishedAt,
+			"duration_seconds": run.FinishedAt.Sub(run.StartedAt).Seconds(),
+			"parsed_count":     run.ParsedCount,
+			"error":            run.Error,
+		}
+	}
+	// 管理接口不走缓存，直接查询
+	f := loadScheduleFreshness()
+	c.JSON(http.StatusOK, gin.H{
+		"scheduler":        scheduler,
+		"last_run":         lastRun,
+		"latest_crawl_run": latest,
+		"schedules_as_of":  f.SchedulesAsOf,
+		"crawl_run_id":     f.CrawlRunID,
+		"freshness":        freshnessState(f, time.Now()),
+	})
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestScheduledCrawl 定时抓取：重叠的运行被跳过，异常抓取后不更新状态，crawl-status 返回最近一次运行。
+func TestScheduledCrawl(t *testing.T) {
+	newTestRouter(t)
+	now := testNoon
+	setTestClock(t, now)
+
+	original := scheduledCrawl
+	defer func() { scheduledCrawl = original }()
+	fake := func(status string, runErr error, entered, release chan struct{}) func(context.Context) (*CrawlRun, error) {
+		return func(context.Context) (*CrawlRun, error) {
+			if entered != nil {
+				close(entered)
+				<-release
+			}
+			started := time.Now()
+			run := CrawlRun{Kind: "schedules", Status: status, StartedAt: started, FinishedAt: started.Add(time.Second), Error: fmt.Sprint(runErr)}
+			if err := db.Create(&run).Error; err != nil {
+				return nil, err
+			}
+			return &run, runErr
+		}
+	}
+	crawlStatus := func() (map[string]json.RawMessage, ScheduledCrawlResult, error) {
+		rec := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(rec)
+		c.Request = httptest.NewRequest(http.MethodGet, "/api/admin/crawl-status", nil)
+		crawlStatusHandler(c)
+		var body map[string]json.RawMessage
+		var last ScheduledCrawlResult
+		if err := expectJSON(testResponse{Status: rec.Code, Body: rec.Body.Bytes()}, &body); err != nil {
+			return nil, last, err
+		}
+		err := json.Unmarshal(body["last_run"], &last)
+		return body, last, err
+	}
+
+	abnormalErr := checkCrawlHealth(10, 100, defaultCrawlMinRatio)
+	scheduledCrawl = fake(CrawlRunStatusAbnormal, abnormalErr, nil, nil)
+	runScheduledCrawlOnce(context.Background())
+	_, abnormal, err := crawlStatus()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entered, release := make(chan struct{}), make(chan struct{})
+	scheduledCrawl = fake("success", nil, entered, release)
+	done := make(chan bool)
+	go func() { done <- runScheduledCrawlOnce(context.Background()) }()
+	<-entered
+	crawlScheduler.Lock()
+	skippedBefore, runningDuring := crawlScheduler.skipped, crawlScheduler.running
+	crawlScheduler.Unlock()
+	overlapped := runScheduledCrawlOnce(context.Background())
+	close(release)
+	ran := <-done
+	body, last, err := crawlStatus()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var scheduler struct {
+		Running bool `json:"running"`
+		Skipped int  `json:"skipped"`
+	}
+	var latest struct {
+		ID     uint   `json:"id"`
+		Status string `json:"status"`
+	}
+	if err := firstError(json.Unmarshal(body["scheduler"], &scheduler), json.Unmarshal(body["latest_crawl_run"], &latest)); err != nil {
+		t.Fatal(err)
+	}
+
+	lastFinished := time.Date(2026, 1, 27, 0, 0, 0, 0, time.UTC)
+	interval, shortErr := parseCrawlInterval("6h")
+	_, tooShort := parseCrawlInterval("10m")
+	off, offErr := parseCrawlInterval("0")
+	_, badErr := parseCrawlInterval("daily")
+	if err := firstError(
+		expectEqual("abnormal", [2]interface{}{abnormal.Result, abnormal.StatusUpdated}, [2]interface{}{CrawlRunStatusAbnormal, false}),
+		expectEqual("running during crawl", runningDuring, true),
+		expectEqual("overlap skipped", [3]interface{}{overlapped, ran, scheduler.Skipped}, [3]interface{}{false, true, skippedBefore + 1}),
+		expectEqual("last run", [3]interface{}{last.Result, last.StatusUpdated, last.CrawlRunID != nil && *last.CrawlRunID == latest.ID}, [3]interface{}{"success", true, true}),
+		expectEqual("latest crawl run", latest.Status, "success"),
+		expectEqual("not running", scheduler.Running, false),
+		expectEqual("interval", [2]interface{}{interval, shortErr}, [2]interface{}{6 * time.Hour, nil}),
+		expectEqual("interval rejected", [2]bool{tooShort != nil, badErr != nil}, [2]bool{true, true}),
+		expectEqual("interval off", [2]interface{}{off, offErr}, [2]interface{}{time.Duration(0), nil}),
+		expectEqual("flag", [3]time.Duration{schedulerInterval(Config{}, []string{"--with-scheduler"}), schedulerInterval(Config{CrawlInterval: time.Hour}, nil), schedulerInterval(Config{}, nil)},
+			[3]time.Duration{defaultCrawlInterval, time.Hour, 0}),
+		expectEqual("first run", [3]time.Time{firstScheduledCrawl(nil, now, interval), firstScheduledCrawl(&lastFinished, now, interval), firstScheduledCrawl(&lastFinished, now.Add(4*time.Hour), interval)},
+			[3]time.Time{now, lastFinished.Add(interval), now.Add(4 * time.Hour)}),
+		expectEqual("result of", [4]string{crawlRunStatusOf(nil), crawlRunStatusOf(errInterrupted), crawlRunStatusOf(errors.New("dial")), crawlRunStatusOf(errors.Join(abnormalErr, errors.New("dial")))},
+			[4]string{"success", CrawlRunStatusInterrupted, "failed", CrawlRunStatusAbnormal})); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,457 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gocolly/colly/v2"
+)
+
+// ===========================
+// 模块：官网排片抓取插件（crawl-custom）
+// 职责：
+// - 手动补录的影院在 eiga.com 上没有页面，排片只能从影院官网获取
+// - 每家影院通过 Cinema.SourceConfig（JSON）选择一种 ScheduleSource：CSS 选择器 或 iCal 订阅
+// - 抓到的场次与 eiga 抓取共用 findOrCreateMovieByTitle / upsertShowtime 写入，
+//   并清理本次覆盖日期内官网上已消失的未来场次
+// 调用方式：
+//   go run . crawl-custom
+// 配置示例：
+//   {"type":"selector","url":"https://example.jp/schedule","item":".schedule-item",
+//    "title":".title","date":".date","date_format":"1/2","time":".time span"}
+//   {"type":"ical","url":"https://example.jp/schedule.ics"}
+// ===========================
+
+// ParsedShowtime 插件解析出的单个场次。
+type ParsedShowtime struct {
+	Title        string    // 原始片名（写入前由 NormalizeTitle 规范化）
+	PlayDate     time.Time // 放映日期（UTC 零点，与 eiga 抓取一致）
+	StartTime    string    // 开始时间 HH:MM
+	Availability string
+	EventType    string
+	MembersOnly  bool // 会員限定场次（见 membersonly.go）
+}
+
+// ScheduleSource 官网排片来源插件。
+type ScheduleSource interface {
+	Fetch(cinema Cinema) ([]ParsedShowtime, error)
+}
+
+// SourceConfig 影院官网排片配置，存放在 Cinema.SourceConfig 列。
+type SourceConfig struct {
+	Type string `json:"type"` // selector / ical
+	URL  string `json:"url"`
+
+	// selector 类型：item 为每个“日期 + 影片”块，其余选择器相对于 item
+	Item       string `json:"item,omitempty"`
+	Title      string `json:"title,omitempty"`
+	Date       string `json:"date,omitempty"`
+	DateAttr   string `json:"date_attr,omitempty"`   // 日期取属性值（如 data-date），为空时取文本
+	DateFormat string `json:"date_format,omitempty"` // Go 时间格式，默认 2006-01-02；不含年份时自动补全
+	Time       string `json:"time,omitempty"`        // 每个匹配元素为一个场次
+}
+
+// 插件类型。
+const (
+	SourceTypeSelector = "selector"
+	SourceTypeICal     = "ical"
+)
+
+// scheduleSourceFactories 插件注册表：新增插件时在此登记。
+var scheduleSourceFactories = map[string]func(cfg SourceConfig) (ScheduleSource, error){
+	SourceTypeSelector: newSelectorSource,
+	SourceTypeICal:     newICalSource,
+}
+
+// parseSourceConfig 解析并校验影院的 SourceConfig（纯函数）。
+func parseSourceConfig(raw string) (SourceConfig, error) {
+	var cfg SourceConfig
+	if err := json.Unmarshal([]byte(raw), &cfg); err != nil {
+		return cfg, fmt.Errorf("invalid source config: %w", err)
+	}
+	if cfg.URL == "" {
+		return cfg, errors.New("source config: url is required")
+	}
+	return cfg, nil
+}
+
+// scheduleSourceFor 根据配置构造对应插件。
+func scheduleSourceFor(cfg SourceConfig) (ScheduleSource, error) {
+	factory, ok := scheduleSourceFactories[cfg.Type]
+	if !ok {
+		return nil, fmt.Errorf("source config: unknown type %q", cfg.Type)
+	}
+	return factory(cfg)
+}
+
+// clockTimeRe 场次时间，如 "9:30" / "18:05"。
+var clockTimeRe = regexp.MustCompile(`(\d{1,2}):(\d{2})`)
+
+// normalizeClockTime 提取文本中的第一个时间并补零为 HH:MM，没有时返回空串。
+func normalizeClockTime(text string) string {
+	m := clockTimeRe.FindStringSubmatch(text)
+	if m == nil {
+		return ""
+	}
+	h, _ := strconv.Atoi(m[1])
+	if h > 29 { // 深夜场可能写成 25:30
+		return ""
+	}
+	return fmt.Sprintf("%02d:%s", h, m[2])
+}
+
+// ---------------------------
+// selector 插件：按 CSS 选择器解析官网 HTML
+// ---------------------------
+
+type selectorSource struct {
+	cfg SourceConfig
+}
+
+func newSelectorSource(cfg SourceConfig) (ScheduleSource, error) {
+	if cfg.Item == "" || cfg.Title == "" || cfg.Date == "" || cfg.Time == "" {
+		return nil, errors.New("selector source: item, title, date and time selectors are required")
+	}
+	if cfg.DateFormat == "" {
+		cfg.DateFormat = "2006-01-02"
+	}
+	return selectorSource{cfg: cfg}, nil
+}
+
+// parseCustomDate 按配置格式解析日期；格式不含年份时取离 now 最近的年份（跨年时排片多为“明年一月”）。
+func parseCustomDate(text, layout string, now time.Time) (time.Time, bool) {
+	text = strings.TrimSpace(text)
+	if idx := strings.IndexAny(text, "(（"); idx != -1 {
+		text = strings.TrimSpace(text[:idx]) // 去掉星期，如 "1/28(水)"
+	}
+	t, err := time.Parse(layout, text)
+	if err != nil {
+		return time.Time{}, false
+	}
+	if t.Year() == 0 {
+		t = time.Date(now.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+		if now.Sub(t) > 180*24*time.Hour {
+			t = t.AddDate(1, 0, 0)
+		}
+	}
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC), true
+}
+
+// parseSelectorItem 解析单个 item 块（不访问网络，便于用 HTML 夹具验证）。
+func parseSelectorItem(e *colly.HTMLElement, cfg SourceConfig, now time.Time) []ParsedShowtime {
+	rawTitle := strings.TrimSpace(e.ChildText(cfg.Title))
+	if rawTitle == "" {
+		return nil
+	}
+	dateText := e.ChildText(cfg.Date)
+	if cfg.DateAttr != "" {
+		dateText = e.ChildAttr(cfg.Date, cfg.DateAttr)
+	}
+	playDate, ok := parseCustomDate(dateText, cfg.DateFormat, now)
+	if !ok {
+		return nil
+	}
+
+	var out []ParsedShowtime
+	e.ForEach(cfg.Time, func(_ int, el *colly.HTMLElement) {
+		text := strings.TrimSpace(el.Text)
+		start := normalizeClockTime(text)
+		if start == "" {
+			return
+		}
+		out = append(out, ParsedShowtime{
+			Title:        rawTitle,
+			PlayDate:     playDate,
+			StartTime:    start,
+			Availability: parseAvailability(text, el.Attr("class")),
+			EventType:    parseEventType(text+" "+el.Attr("title"), rawTitle),
+			MembersOnly:  isMembersOnlyShowtime(text+" "+el.Attr("title"), rawTitle),
+		})
+	})
+	return out
+}
+
+func (s selectorSource) Fetch(cinema Cinema) ([]ParsedShowtime, error) {
+	c := colly.NewCollector()
+	c.SetRequestTimeout(20 * time.Second)
+	c.UserAgent = "TokyoCinePath/1.1 (crawl-custom)"
+
+	now := nowJST()
+	var out []ParsedShowtime
+	c.OnHTML(s.cfg.Item, func(e *colly.HTMLElement) {
+		defer recoverAndLog("官网排片块 " + cinema.NameJP)
+		out = append(out, parseSelectorItem(e, s.cfg, now)...)
+	})
+	if err := c.Visit(s.cfg.URL); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ---------------------------
+// ical 插件：解析官网提供的 .ics 订阅
+// ---------------------------
+
+type icalSource struct {
+	cfg SourceConfig
+}
+
+func newICalSource(cfg SourceConfig) (ScheduleSource, error) {
+	return icalSource{cfg: cfg}, nil
+}
+
+func (s icalSource) Fetch(cinema Cinema) ([]ParsedShowtime, error) {
+	client := &http.Client{Timeout: 20 * time.Second}
+	req, _ := http.NewRequest("GET", s.cfg.URL, nil)
+	req.Header.Set("User-Agent", "TokyoCinePath/1.1 (crawl-custom)")
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("ical source: unexpected status %d", resp.StatusCode)
+	}
+	return parseICalShowtimes(resp.Body)
+}
+
+// unfoldICalLines 读取 iCal 文本并展开折行（以空格 / Tab 开头的行接到上一行）。
+func unfoldICalLines(r io.Reader) ([]string, error) {
+	var lines []string
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	for sc.Scan() {
+		line := strings.TrimRight(sc.Text(), "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines, sc.Err()
+}
+
+// unescapeICalText 还原 iCal TEXT 转义。
+func unescapeICalText(s string) string {
+	return strings.NewReplacer(`\n`, " ", `\N`, " ", `\,`, ",", `\;`, ";", `\\`, `\`).Replace(s)
+}
+
+// parseICalDateTime 解析 DTSTART：支持 UTC（Z 结尾）、TZID 与浮动时间（按东京时间处理）；
+// 全天事件（VALUE=DATE）没有开场时间，返回 false。
+func parseICalDateTime(params, value string) (time.Time, bool) {
+	if strings.Contains(params, "VALUE=DATE") && !strings.Contains(params, "VALUE=DATE-TIME") {
+		return time.Time{}, false
+	}
+	loc := tokyoLocation
+	if idx := strings.Index(params, "TZID="); idx != -1 {
+		tzid := strings.Trim(strings.SplitN(params[idx+5:], ";", 2)[0], `"`)
+		if l, err := time.LoadLocation(tzid); err == nil {
+			loc = l
+		}
+	}
+	if strings.HasSuffix(value, "Z") {
+		t, err := time.Parse("20060102T150405Z", value)
+		if err != nil {
+			return time.Time{}, false
+		}
+		return t.In(tokyoLocation), true
+	}
+	t, err := time.ParseInLocation("20060102T150405", value, loc)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t.In(tokyoLocation), true
+}
+
+// parseICalShowtimes 将 VEVENT 转为场次（纯函数，便于用 .ics 夹具验证）：
+// SUMMARY 为片名，DTSTART 为开场时间，DESCRIPTION 仅用于识别特别场次。
+func parseICalShowtimes(r io.Reader) ([]ParsedShowtime, error) {
+	lines, err := unfoldICalLines(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var out []ParsedShowtime
+	inEvent := false
+	var summary, description string
+	var start time.Time
+	hasStart := false
+	for _, line := range lines {
+		switch line {
+		case "BEGIN:VEVENT":
+			inEvent = true
+			summary, description, hasStart = "", "", false
+			continue
+		case "END:VEVENT":
+			if inEvent && summary != "" && hasStart {
+				ev := matchKnownEvent(description)
+				if ev == "" {
+					ev = parseEventType("", summary)
+				}
+				out = append(out, ParsedShowtime{
+					Title:        summary,
+					PlayDate:     time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, time.UTC),
+					StartTime:    start.Format("15:04"),
+					Availability: AvailabilityUnknown,
+					EventType:    ev,
+					MembersOnly:  isMembersOnlyShowtime(summary, description),
+				})
+			}
+			inEvent = false
+			continue
+		}
+		if !inEvent {
+			continue
+		}
+
+		colon := strings.Index(line, ":")
+		if colon == -1 {
+			continue
+		}
+		name, value := line[:colon], line[colon+1:]
+		params := ""
+		if semi := strings.Index(name, ";"); semi != -1 {
+			name, params = name[:semi], name[semi+1:]
+		}
+		switch strings.ToUpper(name) {
+		case "SUMMARY":
+			summary = strings.TrimSpace(unescapeICalText(value))
+		case "DESCRIPTION":
+			description = unescapeICalText(value)
+		case "DTSTART":
+			start, hasStart = parseICalDateTime(params, strings.TrimSpace(value))
+		}
+	}
+	return out, nil
+}
+
+// ---------------------------
+// crawl-custom：遍历配置了 SourceConfig 的影院
+// ---------------------------
+
+// crawlCustomCinema 抓取并写入单家影院的官网排片，返回写入的场次数与清理的过期场次数。
+func crawlCustomCinema(cinema Cinema) (int, int64, error) {
+	cfg, err := parseSourceConfig(cinema.SourceConfig)
+	if err != nil {
+		return 0, 0, err
+	}
+	source, err := scheduleSourceFor(cfg)
+	if err != nil {
+		return 0, 0, err
+	}
+	showtimes, err := source.Fetch(cinema)
+	if err != nil {
+		return 0, 0, err
+	}
+	if len(showtimes) == 0 {
+		// 0 个场次多半是选择器失效，不做清理，避免误删已有排片
+		parseAnomalies.Lock()
+		parseAnomalies.items = append(parseAnomalies.items, ParseAnomaly{
+			CinemaID: cinema.ID,
+			Cinema:   cinema.NameJP,
+			URL:      cfg.URL,
+			Reason:   "no showtimes parsed from custom source",
+		})
+		parseAnomalies.Unlock()
+		fmt.Printf("🧐 官网排片解析为空 [%s]，跳过清理\n", cinema.NameJP)
+		return 0, 0, nil
+	}
+
+	movies := make(map[string]Movie)
+	keep := make(map[uint]bool)
+	dates := make(map[string]bool)
+	written := 0
+	for _, st := range showtimes {
+		titleJP := NormalizeTitle(st.Title)
+		if titleJP == "" {
+			continue
+		}
+		movie, ok := movies[titleJP]
+		if !ok {
+			movie, err = findOrCreateMovieByTitle(st.Title, SourceCustom)
+			if errors.Is(err, errMovieDeleted) {
+				continue
+			}
+			if err != nil {
+				fmt.Printf("⚠️ 查询或创建影片失败 [%s]: %v\n", titleJP, err)
+				continue
+			}
+			// 只建影片行，资料由 enrich-movies 补全（见 enrichmovies.go）
+			movies[titleJP] = movie
+		}
+
+		sched, err := upsertShowtime(movie.ID, cinema.ID, st.PlayDate, st.StartTime, st.Availability, st.EventType, parseScreeningFormat(st.Title), "", st.MembersOnly)
+		if err != nil {
+			fmt.Printf("⚠️ 写入排片失败 [%s @ %s %s]: %v\n", titleJP, cinema.NameJP, st.StartTime, err)
+			continue
+		}
+		keep[sched.ID] = true
+		dates[st.PlayDate.Format("2006-01-02")] = true
+		written++
+	}
+
+	// 清理：本次覆盖的日期中（仅今天及以后），官网已不再列出的场次
+	today := todayJST()
+	var covered []string
+	for d := range dates {
+		if d >= today {
+			covered = append(covered, d)
+		}
+	}
+	var removed int64
+	if len(covered) > 0 && len(keep) > 0 {
+		keepIDs := make([]uint, 0, len(keep))
+		for id := range keep {
+			keepIDs = append(keepIDs, id)
+		}
+		res := db.Unscoped().Where("cinema_id = ? AND date(play_date) IN ? AND id NOT IN ?", cinema.ID, covered, keepIDs).
+			Delete(&Schedule{})
+		if res.Error != nil {
+			return written, 0, res.Error
+		}
+		removed = res.RowsAffected
+	}
+	if err := refreshRunSummaries(cinema.ID, nil); err != nil {
+		fmt.Printf("⚠️ 刷新放映跨度汇总失败 [%s]: %v\n", cinema.NameJP, err)
+	}
+	return written, removed, nil
+}
+
+// syncCustomSchedules 抓取所有配置了官网排片来源的影院；单家失败不影响其他影院。
+func syncCustomSchedules() (int, error) {
+	var cinemas []Cinema
+	if err := db.Where("source_config <> ''").Order("id").Find(&cinemas).Error; err != nil {
+		return 0, err
+	}
+	if len(cinemas) == 0 {
+		fmt.Println("ℹ️ 没有配置官网排片来源（source_config）的影院。")
+		return 0, nil
+	}
+
+	total := 0
+	failed := 0
+	for _, cinema := range cinemas {
+		fmt.Printf("🎬 抓取官网排片: %s\n", cinema.NameJP)
+		written, removed, err := crawlCustomCinema(cinema)
+		if err != nil {
+			failed++
+			fmt.Printf("⚠️ 官网排片抓取失败 [%s]: %v\n", cinema.NameJP, err)
+			continue
+		}
+		total += written
+		crawlParsedShowtimes.Add(int64(written))
+		fmt.Printf("   ✅ 写入 %d 个场次，清理 %d 个已下架场次\n", written, removed)
+	}
+	if failed == len(cinemas) {
+		return total, fmt.Errorf("all %d custom sources failed", failed)
+	}
+	return total, nil
+}
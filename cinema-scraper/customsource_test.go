@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestCustomSourceParsers 官网排片插件：selector 与 iCal 夹具解析出片名、日期、开场时间、余票与特别场次。
+func TestCustomSourceParsers(t *testing.T) {
+	setTestClock(t, beforeMidnight)
+
+	summary := func(showtimes []ParsedShowtime) string {
+		parts := make([]string, 0, len(showtimes))
+		for _, s := range showtimes {
+			parts = append(parts, fmt.Sprintf("%s|%s|%s|%s|%s|%v", s.Title, s.PlayDate.Format("2006-01-02"), s.StartTime, s.Availability, s.EventType, s.MembersOnly))
+		}
+		return strings.Join(parts, "\n")
+	}
+	const htmlFixture = `<html><body>
+<div class="film"><h3>ルックバック</h3><p class="date">1/28(水)</p>
+  <ul><li>9:30</li><li class="soldout">18:30 満席</li><li>25:10</li><li>30:00</li><li>未定</li></ul></div>
+<div class="film"><h3>テスト映画【舞台挨拶付き】</h3><p class="date">1/29(木)</p><ul><li>14:00</li></ul></div>
+<div class="film"><h3></h3><p class="date">1/29(木)</p><ul><li>16:00</li></ul></div>
+</body></html>`
+	const icsFixture = "BEGIN:VCALENDAR\r\nVERSION:2.0\r\n" +
+		"BEGIN:VEVENT\r\nSUMMARY:ルックバック\r\nDTSTART;TZID=Asia/Tokyo:20260128T183000\r\nEND:VEVENT\r\n" +
+		"BEGIN:VEVENT\r\nSUMMARY:テスト映画\\, 特別版\r\nDESCRIPTION:上映後に舞台挨拶あり\r\nDTSTART:20260128T150000Z\r\nEND:VEVENT\r\n" +
+		"BEGIN:VEVENT\r\nSUMMARY:全日イベント\r\nDTSTART;VALUE=DATE:20260130\r\nEND:VEVENT\r\n" +
+		"BEGIN:VEVENT\r\nSUMMARY:長いタイトルの\r\n 映画\r\nDTSTART:20260131T100000\r\nEND:VEVENT\r\n" +
+		"END:VCALENDAR\r\n"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, ".ics") {
+			w.Header().Set("Content-Type", "text/calendar")
+			io.WriteString(w, icsFixture)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		io.WriteString(w, htmlFixture)
+	}))
+	defer srv.Close()
+
+	fetch := func(raw string) (string, error) {
+		cfg, err := parseSourceConfig(raw)
+		if err != nil {
+			return "", err
+		}
+		source, err := scheduleSourceFor(cfg)
+		if err != nil {
+			return "", err
+		}
+		showtimes, err := source.Fetch(Cinema{NameJP: "テスト官网座"})
+		return summary(showtimes), err
+	}
+	selector, err := fetch(fmt.Sprintf(`{"type":"selector","url":%q,"item":".film","title":"h3","date":"p.date","date_format":"1/2","time":"li"}`, srv.URL+"/schedule"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ical, err := fetch(fmt.Sprintf(`{"type":"ical","url":%q}`, srv.URL+"/schedule.ics"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, missingURL := parseSourceConfig(`{"type":"ical"}`)
+	_, unknown := scheduleSourceFor(SourceConfig{Type: "rss", URL: "https://example.jp"})
+	_, incomplete := scheduleSourceFor(SourceConfig{Type: SourceTypeSelector, URL: "https://example.jp", Item: ".film"})
+	newYear, _ := parseCustomDate("1/3(土)", "1/2", time.Date(2026, 12, 28, 0, 0, 0, 0, time.UTC))
+	if err := firstError(
+		expectEqual("selector", selector, "ルックバック|2026-01-28|09:30|unknown||false\n"+
+			"ルックバック|2026-01-28|18:30|soldout||false\n"+
+			"ルックバック|2026-01-28|25:10|unknown||false\n"+
+			"テスト映画【舞台挨拶付き】|2026-01-29|14:00|unknown|舞台挨拶|false"),
+		expectEqual("ical", ical, "ルックバック|2026-01-28|18:30|unknown||false\n"+
+			"テスト映画, 特別版|2026-01-29|00:00|unknown|舞台挨拶|false\n"+
+			"長いタイトルの映画|2026-01-31|10:00|unknown||false"),
+		expectEqual("config errors", [3]bool{missingURL != nil, unknown != nil, incomplete != nil}, [3]bool{true, true, true}),
+		expectEqual("year rollover", newYear.Format("2006-01-02"), "2027-01-03")); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,325 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ===========================
+// 模块：开放数据导出（GET /api/export/dataset.json）
+// 职责：
+// - 影片公开信息、影院、今天及以后的排片打包为一份带版本号的 JSON，附 metadata（生成时间、抓取批次、记录数、许可协议）
+// - 每次抓取成功结束时生成并写入磁盘，接口只发送文件，不按请求现算
+// - API 进程发现文件缺失、版本过旧或落后于最近一次成功抓取时，在后台重新生成
+// 说明：导出字段由下面每个结构体的列白名单决定（只查询、只输出白名单中的列），
+//       策展文案、字段来源、复核原因、抓取配置等内部字段不会进入数据集；新增列默认不导出。
+// ===========================
+
+const (
+	datasetVersion        = 1
+	defaultDatasetPath    = "exports/dataset.json"
+	defaultDatasetLicense = "CC BY-NC 4.0"
+	datasetRetryAfter     = "30" // 数据集尚未生成时建议的重试间隔（秒）
+)
+
+// datasetPath 数据集文件路径，可由 DATASET_PATH 配置。
+func datasetPath() string {
+	if p := strings.TrimSpace(os.Getenv("DATASET_PATH")); p != "" {
+		return p
+	}
+	return defaultDatasetPath
+}
+
+// datasetLicense 数据集许可协议，可由 DATASET_LICENSE 配置。
+func datasetLicense() string {
+	if l := strings.TrimSpace(os.Getenv("DATASET_LICENSE")); l != "" {
+		return l
+	}
+	return defaultDatasetLicense
+}
+
+// DatasetMetadata 数据集元信息。
+type DatasetMetadata struct {
+	Version     int            `json:"version"`
+	GeneratedAt time.Time      `json:"generated_at"`
+	CrawlRunID  *uint          `json:"crawl_run_id"` // 生成时最近一次成功抓取；从未抓取时为 null
+	Counts      map[string]int `json:"counts"`
+	License     string         `json:"license"`
+}
+
+// Dataset 完整数据集。
+type Dataset struct {
+	Metadata  DatasetMetadata   `json:"metadata"`
+	Movies    []DatasetMovie    `json:"movies"`
+	Cinemas   []DatasetCinema   `json:"cinemas"`
+	Schedules []DatasetSchedule `json:"schedules"`
+}
+
+// datasetMovieColumns 影片导出列白名单（不含简介 / 海报等第三方版权内容与内部字段）。
+var datasetMovieColumns = []string{
+	"id", "tmdb_id", "imdb_id", "title_jp", "title_en", "title_cn", "director", "year",
+	"runtime", "genre", "status", "release_date", "tmdb_rating", "imdb_rating", "douban_rating",
+}
+
+// DatasetMovie 数据集中的影片。
+type DatasetMovie struct {
+	ID           uint    `json:"id"`
+	TMDBID       int     `json:"tmdb_id"`
+	IMDBID       string  `json:"imdb_id"`
+	TitleJP      string  `json:"title_jp"`
+	TitleEN      string  `json:"title_en"`
+	TitleCN      string  `json:"title_cn"`
+	Director     string  `json:"director"`
+	Year         string  `json:"year"`
+	Runtime      int     `json:"runtime"`
+	Genre        string  `json:"genre"`
+	Status       string  `json:"status"`
+	ReleaseDate  string  `json:"release_date"` // YYYY-MM-DD；未知时为空
+	TMDBRating   float64 `json:"tmdb_rating"`
+	IMDBRating   float64 `json:"imdb_rating"`
+	DoubanRating float64 `json:"douban_rating"`
+}
+
+// datasetCinemaColumns 影院导出列白名单（不含简介 / 照片与抓取配置）。
+var datasetCinemaColumns = []string{
+	"id", "name_jp", "name_kana", "address", "latitude", "longitude", "geo_status", "geo_provider", "website", "tags",
+}
+
+// DatasetCinema 数据集中的影院。
+type DatasetCinema struct {
+	ID          uint     `json:"id"`
+	Name        string   `json:"name"`
+	Kana        string   `json:"kana"`
+	Address     string   `json:"address"`
+	District    string   `json:"district"`
+	Lat         float64  `json:"lat"`
+	Lng         float64  `json:"lng"`
+	GeoStatus   string   `json:"geo_status"`
+	GeoProvider string   `json:"geo_provider"` // nominatim / gsi；人工录入或未定位时为空串
+	Website     string   `json:"website"`
+	Tags        []string `json:"tags"`
+}
+
+// datasetScheduleColumns 排片导出列白名单。
+var datasetScheduleColumns = []string{
+	"id", "movie_id", "cinema_id", "play_date", "start_time", "availability", "event_type", "format", "note",
+}
+
+// DatasetSchedule 数据集中的场次。
+type DatasetSchedule struct {
+	ID           uint   `json:"id"`
+	MovieID      uint   `json:"movie_id"`
+	CinemaID     uint   `json:"cinema_id"`
+	PlayDate     string `json:"play_date"`
+	StartTime    string `json:"start_time"`
+	Availability string `json:"availability"`
+	EventType    string `json:"event_type"`
+	Format       string `json:"format"`
+	Note         string `json:"note"`
+}
+
+// buildDataset 按白名单从数据库组装数据集：today 及以后的排片。
+func buildDataset(today string, crawlRunID *uint) (Dataset, error) {
+	var movies []Movie
+	if err := db.Select(datasetMovieColumns).Order("id").Find(&movies).Error; err != nil {
+		return Dataset{}, fmt.Errorf("查询影片失败: %v", err)
+	}
+	var cinemas []Cinema
+	if err := db.Select(datasetCinemaColumns).Order("id").Find(&cinemas).Error; err != nil {
+		return Dataset{}, fmt.Errorf("查询影院失败: %v", err)
+	}
+	var schedules []Schedule
+	if err := db.Select(datasetScheduleColumns).Where("date(play_date) >= ?", today).
+		Order("play_date, cinema_id, start_time, id").Find(&schedules).Error; err != nil {
+		return Dataset{}, fmt.Errorf("查询排片失败: %v", err)
+	}
+
+	ds := Dataset{
+		Movies:    make([]DatasetMovie, 0, len(movies)),
+		Cinemas:   make([]DatasetCinema, 0, len(cinemas)),
+		Schedules: make([]DatasetSchedule, 0, len(schedules)),
+	}
+	for _, m := range movies {
+		release := ""
+		if !m.ReleaseDate.IsZero() {
+			release = m.ReleaseDate.Format("2006-01-02")
+		}
+		ds.Movies = append(ds.Movies, DatasetMovie{
+			ID: m.ID, TMDBID: m.TMDBID, IMDBID: m.IMDBID,
+			TitleJP: m.TitleJP, TitleEN: m.TitleEN, TitleCN: m.TitleCN,
+			Director: m.Director, Year: m.Year, Runtime: m.Runtime, Genre: m.Genre,
+			Status: m.Status, ReleaseDate: release,
+			TMDBRating: m.TMDBRating, IMDBRating: m.IMDBRating, DoubanRating: m.DoubanRating,
+		})
+	}
+	for _, cin := range cinemas {
+		ds.Cinemas = append(ds.Cinemas, DatasetCinema{
+			ID: cin.ID, Name: cin.NameJP, Kana: cin.NameKana,
+			Address: cin.Address, District: extractDistrict(cin.Address),
+			Lat: cin.Latitude, Lng: cin.Longitude, GeoStatus: cin.GeoStatus,
+			GeoProvider: cin.GeoProvider, Website: cin.Website, Tags: splitTags(cin.Tags),
+		})
+	}
+	for _, s := range schedules {
+		st := scheduleToShowtime(s)
+		ds.Schedules = append(ds.Schedules, DatasetSchedule{
+			ID: s.ID, MovieID: s.MovieID, CinemaID: s.CinemaID,
+			PlayDate: s.PlayDate.Format("2006-01-02"), StartTime: s.StartTime,
+			Availability: st.Availability, EventType: s.EventType, Format: s.Format, Note: s.Note,
+		})
+	}
+	ds.Metadata = DatasetMetadata{
+		Version:     datasetVersion,
+		GeneratedAt: time.Now().In(tokyoLocation),
+		CrawlRunID:  crawlRunID,
+		Counts: map[string]int{
+			"movies":    len(ds.Movies),
+			"cinemas":   len(ds.Cinemas),
+			"schedules": len(ds.Schedules),
+		},
+		License: datasetLicense(),
+	}
+	return ds, nil
+}
+
+// latestSuccessfulCrawlRunID 最近一次成功抓取（schedules / custom）的 ID，没有时为 nil。
+func latestSuccessfulCrawlRunID() *uint {
+	var run CrawlRun
+	if err := db.Select("id").Where("status = ?", "success").Order("id DESC").First(&run).Error; err != nil {
+		return nil
+	}
+	return &run.ID
+}
+
+// writeDatasetDump 生成数据集并原子地写入磁盘（先写临时文件再改名，读取方不会看到半个文件）。
+func writeDatasetDump(crawlRunID *uint) (DatasetMetadata, error) {
+	ds, err := buildDataset(todayJST(), crawlRunID)
+	if err != nil {
+		return DatasetMetadata{}, err
+	}
+	body, err := json.Marshal(ds)
+	if err != nil {
+		return DatasetMetadata{}, err
+	}
+	path := datasetPath()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return DatasetMetadata{}, err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".dataset-*.json")
+	if err != nil {
+		return DatasetMetadata{}, err
+	}
+	if _, err := tmp.Write(body); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return DatasetMetadata{}, err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return DatasetMetadata{}, err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return DatasetMetadata{}, err
+	}
+	return ds.Metadata, nil
+}
+
+// exportDatasetAfterCrawl 抓取成功结束后刷新数据集；失败只记日志，不影响抓取结果。
+func exportDatasetAfterCrawl(run *CrawlRun) {
+	id := run.ID
+	meta, err := writeDatasetDump(&id)
+	if err != nil {
+		fmt.Printf("⚠️ 生成开放数据集失败: %v\n", err)
+		return
+	}
+	fmt.Printf("📦 开放数据集已更新: %s（影片 %d / 影院 %d / 排片 %d）\n",
+		datasetPath(), meta.Counts["movies"], meta.Counts["cinemas"], meta.Counts["schedules"])
+}
+
+// datasetMetaCache 已读取的数据集元信息，按文件修改时间失效。
+var datasetMetaCache struct {
+	sync.Mutex
+	modTime time.Time
+	meta    DatasetMetadata
+}
+
+// datasetRegenerating 后台生成进行中（同一时间只跑一个）。
+var datasetRegenerating atomic.Bool
+
+// readDatasetMetadata 读取磁盘上数据集的元信息；文件不存在时返回错误。
+func readDatasetMetadata(path string) (DatasetMetadata, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return DatasetMetadata{}, err
+	}
+	datasetMetaCache.Lock()
+	defer datasetMetaCache.Unlock()
+	if info.ModTime().Equal(datasetMetaCache.modTime) {
+		return datasetMetaCache.meta, nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return DatasetMetadata{}, err
+	}
+	defer f.Close()
+	var head struct {
+		Metadata DatasetMetadata `json:"metadata"`
+	}
+	if err := json.NewDecoder(f).Decode(&head); err != nil {
+		return DatasetMetadata{}, err
+	}
+	datasetMetaCache.modTime, datasetMetaCache.meta = info.ModTime(), head.Metadata
+	return head.Metadata, nil
+}
+
+// datasetOutdated 数据集是否需要重新生成：版本过旧，或落后于最近一次成功抓取。
+func datasetOutdated(meta DatasetMetadata, latestRunID *uint) bool {
+	if meta.Version != datasetVersion {
+		return true
+	}
+	if latestRunID == nil {
+		return false
+	}
+	return meta.CrawlRunID == nil || *meta.CrawlRunID != *latestRunID
+}
+
+// regenerateDatasetAsync 在后台重新生成数据集；已有生成任务时直接返回。
+func regenerateDatasetAsync(latestRunID *uint) {
+	if !datasetRegenerating.CompareAndSwap(false, true) {
+		return
+	}
+	go func() {
+		defer datasetRegenerating.Store(false)
+		defer recoverAndLog("生成开放数据集")
+		if _, err := writeDatasetDump(latestRunID); err != nil {
+			fmt.Printf("⚠️ 后台生成开放数据集失败: %v\n", err)
+		}
+	}()
+}
+
+// datasetHandler 处理 GET /api/export/dataset.json：发送磁盘上的数据集，过期时先发旧文件并在后台更新。
+func datasetHandler(c *gin.Context) {
+	path := datasetPath()
+	latest := latestSuccessfulCrawlRunID()
+	meta, err := readDatasetMetadata(path)
+	if err != nil || datasetOutdated(meta, latest) {
+		regenerateDatasetAsync(latest)
+	}
+	if err != nil {
+		c.Header("Retry-After", datasetRetryAfter)
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "dataset is being generated, retry later"})
+		return
+	}
+	c.Header("Cache-Control", "public, max-age=3600")
+	c.File(path)
+}
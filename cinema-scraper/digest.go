@@ -0,0 +1,367 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ===========================
+// 模块：每周通讯草稿（digest）
+// 职责：从 Schedule / Movie 表生成一周摘要，作为周报的自动初稿
+// - 本周新片：首个排片日落在本周的影片（附评分与上映影院）
+// - 最后机会：本周之前已开映、最后排片日落在本周的影片
+// - 经典重映：本周有排片、上映年份早于 RevivalYears 年前的影片（阈值见 statusrules.go）
+// - 各区统计：本周各区的影院数与场次数
+// 说明：筛选逻辑为纯函数（selectOpenings / selectClosings / selectRevivals），查询只负责装载数据；
+// 排片范围同时统计热表与归档表，回看过去的周也能得到正确的首末排片日。
+// Markdown 按 --lang=ja|en|zh 本地化日期与文案（见 i18n.go），默认中文。
+// 调用方式：
+//   go run . digest --week 2026-W05 [--format=md|json] [--lang=zh] [--out=digest.md]
+// ===========================
+
+// DigestMovie 周报中的一部影片。
+type DigestMovie struct {
+	ID        uint         `json:"id"`
+	Title     string       `json:"title"`
+	TitleJP   string       `json:"title_jp"`
+	Year      string       `json:"year"`
+	Rating    *RatingEntry `json:"rating"` // 主评分，没有评分时为 null
+	FirstDate string       `json:"first_date"`
+	LastDate  string       `json:"last_date"`
+	Cinemas   []string     `json:"cinemas"`   // 本周上映的影院
+	Showtimes int          `json:"showtimes"` // 本周场次数
+}
+
+// DistrictCount 某区本周的排片规模。
+type DistrictCount struct {
+	District  string `json:"district"`
+	Cinemas   int    `json:"cinemas"`
+	Showtimes int    `json:"showtimes"`
+}
+
+// WeeklyDigest 一周摘要。
+type WeeklyDigest struct {
+	Week      string          `json:"week"` // ISO 周，如 2026-W05
+	From      string          `json:"from"` // 周一
+	To        string          `json:"to"`   // 周日
+	Openings  []DigestMovie   `json:"openings"`
+	Closings  []DigestMovie   `json:"closings"`
+	Revivals  []DigestMovie   `json:"revivals"`
+	Districts []DistrictCount `json:"districts"`
+}
+
+// parseISOWeek 解析 "2026-W05"，返回该周周一与周日（纯函数）。
+func parseISOWeek(s string) (time.Time, time.Time, error) {
+	yearStr, weekStr, ok := strings.Cut(strings.ToUpper(strings.TrimSpace(s)), "-W")
+	year, err1 := strconv.Atoi(yearStr)
+	week, err2 := strconv.Atoi(weekStr)
+	if !ok || err1 != nil || err2 != nil || week < 1 || week > 53 {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid week %q, expected YYYY-Www", s)
+	}
+	// 1 月 4 日总在第 1 周内，由此找到第 1 周的周一
+	jan4 := time.Date(year, time.January, 4, 0, 0, 0, 0, time.UTC)
+	offset := (int(jan4.Weekday()) + 6) % 7
+	monday := jan4.AddDate(0, 0, -offset+(week-1)*7)
+	if y, w := monday.ISOWeek(); y != year || w != week {
+		return time.Time{}, time.Time{}, fmt.Errorf("week %q does not exist", s)
+	}
+	return monday, monday.AddDate(0, 0, 6), nil
+}
+
+// currentISOWeek 东京时间当前所在的 ISO 周。
+func currentISOWeek() string {
+	y, w := nowJST().ISOWeek()
+	return fmt.Sprintf("%d-W%02d", y, w)
+}
+
+// selectOpenings 首个排片日在 [from, to] 内的影片（纯函数）。
+func selectOpenings(ranges []movieScheduleRange, from, to string) []uint {
+	var ids []uint
+	for _, r := range ranges {
+		if r.First >= from && r.First <= to {
+			ids = append(ids, r.MovieID)
+		}
+	}
+	return ids
+}
+
+// selectClosings 本周之前已开映、最后排片日在 [from, to] 内的影片（纯函数）。
+// 本周首映且本周结束的影片算作新片，不重复列入。
+func selectClosings(ranges []movieScheduleRange, from, to string) []uint {
+	var ids []uint
+	for _, r := range ranges {
+		if r.First < from && r.Last >= from && r.Last <= to {
+			ids = append(ids, r.MovieID)
+		}
+	}
+	return ids
+}
+
+// selectRevivals 本周有排片且上映年份早于 weekYear-RevivalYears 的影片。
+func selectRevivals(playing []uint, movies map[uint]Movie, weekYear int) []uint {
+	var ids []uint
+	for _, id := range playing {
+		if isRevivalYear(movies[id].Year, weekYear) {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// loadMovieScheduleRanges 与 [from, to] 有交集的影片排片首末日期（热表 + 归档表，见 longrun.go）。
+func loadMovieScheduleRanges(from, to string) ([]movieScheduleRange, error) {
+	all, err := loadAllMovieScheduleRanges()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]movieScheduleRange, 0, len(all))
+	for _, r := range all {
+		if r.Last >= from && r.First <= to {
+			out = append(out, r)
+		}
+	}
+	return out, nil
+}
+
+// buildWeeklyDigest 生成指定 ISO 周的摘要。
+func buildWeeklyDigest(week string) (WeeklyDigest, error) {
+	monday, sunday, err := parseISOWeek(week)
+	if err != nil {
+		return WeeklyDigest{}, err
+	}
+	from, to := monday.Format("2006-01-02"), sunday.Format("2006-01-02")
+	digest := WeeklyDigest{Week: week, From: from, To: to}
+
+	ranges, err := loadMovieScheduleRanges(from, to)
+	if err != nil {
+		return digest, err
+	}
+	rangeByID := make(map[uint]movieScheduleRange, len(ranges))
+	for _, r := range ranges {
+		rangeByID[r.MovieID] = r
+	}
+
+	// 本周的全部场次：用于“在哪里上映”与各区统计
+	weekSchedules, err := loadSchedulesWithArchive(func(tx *gorm.DB) *gorm.DB {
+		return tx.Where("date(play_date) >= ? AND date(play_date) <= ?", from, to)
+	})
+	if err != nil {
+		return digest, err
+	}
+	playingSet := make(map[uint]struct{})
+	cinemaSet := make(map[uint]struct{})
+	for _, s := range weekSchedules {
+		playingSet[s.MovieID] = struct{}{}
+		cinemaSet[s.CinemaID] = struct{}{}
+	}
+	playing := sortedIDs(playingSet)
+
+	var movies []Movie
+	if len(playing) > 0 {
+		if err := db.Where("id IN ?", playing).Find(&movies).Error; err != nil {
+			return digest, err
+		}
+	}
+	movieMap := make(map[uint]Movie, len(movies))
+	for _, m := range movies {
+		movieMap[m.ID] = m
+	}
+	var cinemas []Cinema
+	if len(cinemaSet) > 0 {
+		if err := db.Where("id IN ?", sortedIDs(cinemaSet)).Find(&cinemas).Error; err != nil {
+			return digest, err
+		}
+	}
+	cinemaMap := make(map[uint]Cinema, len(cinemas))
+	for _, cin := range cinemas {
+		cinemaMap[cin.ID] = cin
+	}
+
+	// 每部影片本周的影院与场次数
+	movieCinemas := make(map[uint]map[string]bool)
+	movieShowtimes := make(map[uint]int)
+	districtCinemas := make(map[string]map[uint]bool)
+	districtShowtimes := make(map[string]int)
+	for _, s := range weekSchedules {
+		cin := cinemaMap[s.CinemaID]
+		if movieCinemas[s.MovieID] == nil {
+			movieCinemas[s.MovieID] = make(map[string]bool)
+		}
+		movieCinemas[s.MovieID][cin.NameJP] = true
+		movieShowtimes[s.MovieID]++
+
+		district := extractDistrict(cin.Address)
+		if district == "" {
+			district = "其他"
+		}
+		if districtCinemas[district] == nil {
+			districtCinemas[district] = make(map[uint]bool)
+		}
+		districtCinemas[district][s.CinemaID] = true
+		districtShowtimes[district]++
+	}
+
+	toDigestMovies := func(ids []uint) []DigestMovie {
+		out := make([]DigestMovie, 0, len(ids))
+		for _, id := range ids {
+			m, ok := movieMap[id]
+			if !ok {
+				continue
+			}
+			names := make([]string, 0, len(movieCinemas[id]))
+			for name := range movieCinemas[id] {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			out = append(out, DigestMovie{
+				ID:        m.ID,
+				Title:     movieDisplayTitle(m),
+				TitleJP:   m.TitleJP,
+				Year:      m.Year,
+				Rating:    primaryRating(buildRatings(m)),
+				FirstDate: rangeByID[id].First,
+				LastDate:  rangeByID[id].Last,
+				Cinemas:   names,
+				Showtimes: movieShowtimes[id],
+			})
+		}
+		return out
+	}
+	ratingOf := func(dm DigestMovie) float64 {
+		if dm.Rating == nil {
+			return 0
+		}
+		return dm.Rating.Score
+	}
+
+	// 新片按评分降序；最后机会按结束日期升序（越早结束越靠前）；重映按年份升序
+	digest.Openings = toDigestMovies(selectOpenings(ranges, from, to))
+	sort.SliceStable(digest.Openings, func(i, j int) bool {
+		return ratingOf(digest.Openings[i]) > ratingOf(digest.Openings[j])
+	})
+	digest.Closings = toDigestMovies(selectClosings(ranges, from, to))
+	sort.SliceStable(digest.Closings, func(i, j int) bool {
+		return digest.Closings[i].LastDate < digest.Closings[j].LastDate
+	})
+	digest.Revivals = toDigestMovies(selectRevivals(playing, movieMap, monday.Year()))
+	sort.SliceStable(digest.Revivals, func(i, j int) bool {
+		return digest.Revivals[i].Year < digest.Revivals[j].Year
+	})
+
+	digest.Districts = make([]DistrictCount, 0, len(districtShowtimes))
+	for district, n := range districtShowtimes {
+		digest.Districts = append(digest.Districts, DistrictCount{
+			District:  district,
+			Cinemas:   len(districtCinemas[district]),
+			Showtimes: n,
+		})
+	}
+	sort.Slice(digest.Districts, func(i, j int) bool {
+		if digest.Districts[i].Showtimes != digest.Districts[j].Showtimes {
+			return digest.Districts[i].Showtimes > digest.Districts[j].Showtimes
+		}
+		return digest.Districts[i].District < digest.Districts[j].District
+	})
+	return digest, nil
+}
+
+// renderDigestMarkdown 将摘要渲染为 Markdown 周报草稿；lang 不支持时按中文输出。
+func renderDigestMarkdown(d WeeklyDigest, lang string) string {
+	lang = i18nLang(lang, LangZH)
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n", translate(lang, "digest.title",
+		d.Week, formatISODateHeading(d.From, lang), formatISODateHeading(d.To, lang)))
+
+	writeMovies := func(title string, movies []DigestMovie, line func(DigestMovie) string) {
+		fmt.Fprintf(&b, "## %s%s\n\n", translate(lang, title), translate(lang, "paren", strconv.Itoa(len(movies))))
+		if len(movies) == 0 {
+			fmt.Fprintf(&b, "_%s_\n\n", translate(lang, "digest.none"))
+			return
+		}
+		for _, m := range movies {
+			fmt.Fprintf(&b, "- **%s**", m.Title)
+			if m.Year != "" {
+				b.WriteString(translate(lang, "paren", m.Year))
+			}
+			if m.Rating != nil {
+				fmt.Fprintf(&b, " ⭐ %.1f%s", m.Rating.Score, translate(lang, "paren", m.Rating.Source))
+			}
+			fmt.Fprintf(&b, " — %s\n", line(m))
+			if len(m.Cinemas) > 0 {
+				fmt.Fprintf(&b, "  - %s\n", translate(lang, "digest.cinemas", formatShowingAt(len(m.Cinemas), lang),
+					strings.Join(m.Cinemas, translate(lang, "digest.list_sep"))))
+			}
+		}
+		b.WriteString("\n")
+	}
+
+	writeMovies("digest.openings", d.Openings, func(m DigestMovie) string {
+		return translate(lang, "digest.opening_line", formatISODateHeading(m.FirstDate, lang), formatShowtimeCount(m.Showtimes, lang))
+	})
+	writeMovies("digest.closings", d.Closings, func(m DigestMovie) string {
+		return translate(lang, "digest.closing_line", formatISODateHeading(m.LastDate, lang), formatShowtimeCount(m.Showtimes, lang))
+	})
+	writeMovies("digest.revivals", d.Revivals, func(m DigestMovie) string {
+		return translate(lang, "digest.revival_line", formatShowtimeCount(m.Showtimes, lang))
+	})
+
+	fmt.Fprintf(&b, "## %s\n\n", translate(lang, "digest.districts"))
+	if len(d.Districts) == 0 {
+		fmt.Fprintf(&b, "_%s_\n", translate(lang, "digest.none"))
+		return b.String()
+	}
+	b.WriteString(translate(lang, "digest.table_header") + "\n|---|---:|---:|\n")
+	for _, dc := range d.Districts {
+		fmt.Fprintf(&b, "| %s | %d | %d |\n", dc.District, dc.Cinemas, dc.Showtimes)
+	}
+	return b.String()
+}
+
+// runDigestCommand digest 命令入口：--week 默认本周，--format=md|json，--lang=ja|en|zh（默认 zh），--out 为空时输出到 stdout。
+func runDigestCommand(args []string) error {
+	week, ok := flagValue(args, "--week")
+	if !ok || week == "" {
+		week = currentISOWeek()
+	}
+	lang := LangZH
+	if raw, ok := flagValue(args, "--lang"); ok {
+		if lang = normalizeLangTag(raw); lang == "" {
+			return fmt.Errorf("unsupported lang %q, expected ja, en or zh", raw)
+		}
+	}
+	digest, err := buildWeeklyDigest(week)
+	if err != nil {
+		return err
+	}
+
+	var out string
+	switch format, _ := flagValue(args, "--format"); format {
+	case "", "md", "markdown":
+		out = renderDigestMarkdown(digest, lang)
+	case "json":
+		b, err := json.MarshalIndent(digest, "", "  ")
+		if err != nil {
+			return err
+		}
+		out = string(b) + "\n"
+	default:
+		return fmt.Errorf("unknown format %q, expected md or json", format)
+	}
+
+	if path, ok := flagValue(args, "--out"); ok && path != "" {
+		if err := os.WriteFile(path, []byte(out), 0o644); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "📝 周报已写入 %s\n", path)
+		return nil
+	}
+	fmt.Print(out)
+	return nil
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"testing"
+)
+
+// TestDigestSelection 周报筛选：新片 / 最后机会 / 经典重映分别按首末排片日与上映年份挑选，ISO 周边界。
+func TestDigestSelection(t *testing.T) {
+	setTestClock(t, beforeMidnight)
+
+	ranges := []movieScheduleRange{
+		{1, "2026-01-26", "2026-02-10"}, // 周一开映
+		{2, "2026-01-10", "2026-01-28"}, // 本周结束
+		{3, "2026-01-28", "2026-01-30"}, // 本周首映且本周结束：只算新片
+		{4, "2026-01-10", "2026-02-15"}, // 跨过本周
+		{5, "2026-02-02", "2026-02-09"}, // 下周开映
+		{6, "2026-01-01", "2026-01-25"}, // 上周已结束
+		{7, "2026-02-01", "2026-02-01"}, // 周日开映
+		{8, "2026-01-20", "2026-02-01"}, // 周日结束
+	}
+	movies := map[uint]Movie{1: {Year: "2016"}, 2: {Year: "2017"}, 3: {}, 4: {Year: "1954"}}
+	monday, sunday, err := parseISOWeek("2026-W05")
+	if err != nil {
+		t.Fatal(err)
+	}
+	from, to := monday.Format("2006-01-02"), sunday.Format("2006-01-02")
+	last, _, lastErr := parseISOWeek("2026-w53")
+	_, _, missing := parseISOWeek("2025-W53")
+	_, _, malformed := parseISOWeek("2026-05")
+	if err := firstError(
+		expectEqual("week", from+"~"+to, "2026-01-26~2026-02-01"),
+		expectEqual("openings", selectOpenings(ranges, from, to), []uint{1, 3, 7}),
+		expectEqual("closings", selectClosings(ranges, from, to), []uint{2, 8}),
+		expectEqual("revivals", selectRevivals([]uint{1, 2, 3}, movies, monday.Year()), []uint{1}),
+		lastErr,
+		expectEqual("week 53", last.Format("2006-01-02"), "2026-12-28"),
+		expectEqual("invalid weeks", [2]bool{missing != nil, malformed != nil}, [2]bool{true, true})); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm/clause"
+)
+
+// ===========================
+// 模块：抓取中发现的影院链接（扩展范围的依据）
+// 职责：
+// - eiga.com 东京列表页上也有邻县影院与特殊会场的链接，抓取只处理 CRAWL_AREA / --area 所选地区（默认 /theater/13/）下的影院，其余原先被直接丢弃
+// - 两个抓取命令（crawl-cinemas / crawl-schedules）遇到的每个影院链接都写入 DiscoveredVenue：
+//   URL、地区代码、链接文字（影院名）、首次 / 最近发现时间，以及当前是否在抓取范围内
+// - GET /api/admin/discovered-venues 按地区汇总，供决定下一步开放哪些地区
+// 说明：地区代码为 eiga.com 影院 URL 的第一段（都道府县代码，如 13 = 东京、14 = 神奈川），无法解析时为空串。
+// ===========================
+
+// eigaTheaterLinkRe eiga.com 影院链接，如 https://eiga.com/theater/13/130201/3015/。
+var eigaTheaterLinkRe = regexp.MustCompile(`^https?://eiga\.com/theater/(\d+)/`)
+
+// DiscoveredVenue 抓取时发现的影院链接（不论是否抓取）。
+type DiscoveredVenue struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	URL       string    `gorm:"uniqueIndex" json:"url"`
+	AreaCode  string    `gorm:"index" json:"area_code"`
+	Name      string    `json:"name"` // 列表页上的链接文字，取不到时为空串
+	InScope   bool      `json:"in_scope"`
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+}
+
+// newDiscoveredVenue 由链接与链接文字构造记录（纯函数）：解析地区代码并判断是否在抓取地区 crawlAreas 内。
+func newDiscoveredVenue(link, name string, crawlAreas []string, now time.Time) DiscoveredVenue {
+	area := eigaAreaCode(link)
+	return DiscoveredVenue{
+		URL:       link,
+		AreaCode:  area,
+		Name:      strings.Join(strings.Fields(name), " "),
+		InScope:   area != "" && slices.Contains(crawlAreas, area),
+		FirstSeen: now,
+		LastSeen:  now,
+	}
+}
+
+// recordDiscoveredVenue 记录一个影院链接并返回它是否在抓取范围内。
+// 已记录过的链接只刷新名称（非空时）、范围标记与 LastSeen；写入失败只打印，不影响抓取。
+func recordDiscoveredVenue(link, name string) bool {
+	v := newDiscoveredVenue(link, name, appConfig.crawlAreas(), time.Now())
+	updates := []string{"area_code", "in_scope", "last_seen"}
+	if v.Name != "" {
+		updates = append(updates, "name")
+	}
+	err := db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "url"}},
+		DoUpdates: clause.AssignmentColumns(updates),
+	}).Create(&v).Error
+	if err != nil {
+		fmt.Printf("⚠️ 记录发现的影院链接失败 [%s]: %v\n", link, err)
+	}
+	return v.InScope
+}
+
+// DiscoveredArea 按地区汇总的发现数量。
+type DiscoveredArea struct {
+	AreaCode string `json:"area_code"`
+	Total    int    `json:"total"`
+	InScope  int    `json:"in_scope"`
+}
+
+// summarizeDiscoveredAreas 按地区汇总（纯函数），按数量降序、地区代码升序。
+func summarizeDiscoveredAreas(venues []DiscoveredVenue) []DiscoveredArea {
+	byArea := make(map[string]*DiscoveredArea)
+	for _, v := range venues {
+		a, ok := byArea[v.AreaCode]
+		if !ok {
+			a = &DiscoveredArea{AreaCode: v.AreaCode}
+			byArea[v.AreaCode] = a
+		}
+		a.Total++
+		if v.InScope {
+			a.InScope++
+		}
+	}
+	out := make([]DiscoveredArea, 0, len(byArea))
+	for _, a := range byArea {
+		out = append(out, *a)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Total != out[j].Total {
+			return out[i].Total > out[j].Total
+		}
+		return out[i].AreaCode < out[j].AreaCode
+	})
+	return out
+}
+
+// listDiscoveredVenuesHandler 发现的影院链接：
+// - GET /api/admin/discovered-venues?area=14&in_scope=false
+// - areas 为过滤前的地区汇总；items 按地区代码、URL 排序
+func listDiscoveredVenuesHandler(c *gin.Context) {
+	var venues []DiscoveredVenue
+	if err := db.Order("area_code, url").Find(&venues).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to query discovered venues"})
+		return
+	}
+
+	area, hasArea := c.GetQuery("area")
+	inScopeFilter := c.Query("in_scope")
+	if inScopeFilter != "" && inScopeFilter != "true" && inScopeFilter != "false" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid in_scope, expected true or false"})
+		return
+	}
+	items := make([]DiscoveredVenue, 0, len(venues))
+	for _, v := range venues {
+		if hasArea && v.AreaCode != area {
+			continue
+		}
+		if inScopeFilter != "" && v.InScope != (inScopeFilter == "true") {
+			continue
+		}
+		items = append(items, v)
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"crawl_area_codes": appConfig.crawlAreas(),
+		"areas":            summarizeDiscoveredAreas(venues),
+		"total":            len(items),
+		"items":            items,
+	})
+}
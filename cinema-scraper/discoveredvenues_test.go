@@ -0,0 +1,42 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+// TestDiscoveredVenues 发现的影院：按地区汇总与过滤。
+func TestDiscoveredVenues(t *testing.T) {
+	runHTTPCases(t, []httpCase{
+		{"发现的影院：按地区汇总并标记抓取范围", "/api/admin/discovered-venues", func(r testResponse) error {
+			var body struct {
+				Areas []DiscoveredArea  `json:"areas"`
+				Items []DiscoveredVenue `json:"items"`
+			}
+			if err := expectJSON(r, &body); err != nil {
+				return err
+			}
+			areas := make([]string, 0, len(body.Areas))
+			for _, a := range body.Areas {
+				areas = append(areas, fmt.Sprintf("%s:%d/%d", a.AreaCode, a.InScope, a.Total))
+			}
+			return firstError(
+				expectEqual("areas", fmt.Sprint(areas), "[13:2/2 14:0/2 :0/1]"),
+				expectEqual("len(items)", len(body.Items), 5),
+				expectEqual("special venue name", body.Items[0].Name, "特設会場"))
+		}},
+		{"发现的影院：只看范围外的神奈川", "/api/admin/discovered-venues?area=14&in_scope=false", func(r testResponse) error {
+			var body struct {
+				Total int `json:"total"`
+			}
+			if err := expectJSON(r, &body); err != nil {
+				return err
+			}
+			return expectEqual("total", body.Total, 2)
+		}},
+		{"发现的影院：非法 in_scope", "/api/admin/discovered-venues?in_scope=maybe", func(r testResponse) error {
+			return expectStatus(r, http.StatusBadRequest)
+		}},
+	})
+}
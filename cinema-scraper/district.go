@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ===========================
+// 模块：影院所在区（District）与影院列表分页
+// 职责：
+// - Cinema.District 持久化 extractDistrict(address) 的结果，/api/cinemas?district= 直接在 SQL 中过滤
+// - 地址变化时由 BeforeSave 钩子同步（结构体保存与按列 Updates 都会经过）；旧数据由版本化迁移补齐一次（见 migrations.go）
+// - 结构体保存时顺带推导为空的 Prefecture（见 prefecture.go）
+// - /api/cinemas 的 page / page_size 分页参数解析
+// ===========================
+
+const maxCinemaPageSize = 200
+
+// BeforeSave 保存影院时按地址同步 District。
+// 按列更新（Updates(map)）时只有更新了 address 才需要同步，其余情况保持原值。
+func (cn *Cinema) BeforeSave(tx *gorm.DB) error {
+	if updates, ok := tx.Statement.Dest.(map[string]interface{}); ok {
+		if address, ok := updates["address"].(string); ok {
+			updates["district"] = extractDistrict(address)
+		}
+		return nil
+	}
+	cn.District = extractDistrict(cn.Address)
+	if cn.Prefecture == "" {
+		cn.Prefecture = derivePrefecture(cn.EigaURL, cn.Address)
+	}
+	return nil
+}
+
+// backfillCinemaDistricts 补齐 District 与地址不一致的影院，返回更新数（在迁移事务 conn 上执行）。
+func backfillCinemaDistricts(conn *gorm.DB) (int, error) {
+	var cinemas []Cinema
+	if err := conn.Select("id", "address", "district").Find(&cinemas).Error; err != nil {
+		return 0, err
+	}
+	updated := 0
+	for _, cin := range cinemas {
+		district := extractDistrict(cin.Address)
+		if district == cin.District {
+			continue
+		}
+		if err := conn.Model(&Cinema{}).Where("id = ?", cin.ID).UpdateColumn("district", district).Error; err != nil {
+			return updated, err
+		}
+		updated++
+	}
+	return updated, nil
+}
+
+// cinemaPage 影院列表的分页参数；Size 为 0 表示不分页。
+type cinemaPage struct {
+	Page int
+	Size int
+}
+
+// parseCinemaPage 解析 page / page_size：只传 page 时按最大页大小分页；都不传时不分页。
+func parseCinemaPage(c *gin.Context) (cinemaPage, error) {
+	p := cinemaPage{Page: 1}
+	rawPage, rawSize := c.Query("page"), c.Query("page_size")
+	if rawPage != "" {
+		v, err := strconv.Atoi(rawPage)
+		if err != nil || v <= 0 {
+			return p, fmt.Errorf("invalid page, expected a positive integer")
+		}
+		p.Page = v
+		p.Size = maxCinemaPageSize
+	}
+	if rawSize != "" {
+		v, err := strconv.Atoi(rawSize)
+		if err != nil || v <= 0 {
+			return p, fmt.Errorf("invalid page_size, expected a positive integer")
+		}
+		p.Size = min(v, maxCinemaPageSize)
+	}
+	return p, nil
+}
+
+// slice 取出当前页（纯函数）；超出范围时为空。
+func (p cinemaPage) slice(cinemas []Cinema) []Cinema {
+	if p.Size == 0 {
+		return cinemas
+	}
+	start := (p.Page - 1) * p.Size
+	if start >= len(cinemas) {
+		return nil
+	}
+	return cinemas[start:min(start+p.Size, len(cinemas))]
+}
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// ===========================
+// 模块：运行环境诊断（doctor）
+// 职责：逐项检查运行所需的环境，打印通过 / 失败与修复建议，有失败项时以非零状态退出
+// - 数据库文件可写、表结构与当前模型一致
+// - TMDB / OMDb API Key 有效（各发一次最便宜的请求）
+// - 到 eiga.com 与 Nominatim 的外网连通性（含 OSM 联系邮箱配置）
+// - Asia/Tokyo 时区数据可用
+// 说明：每项检查都是独立函数（doctorCheck），新增检查只需追加到 doctorChecks。
+// 调用方式：
+//   go run . doctor
+// ===========================
+
+const doctorHTTPTimeout = 10 * time.Second
+
+// doctorResult 单项检查结果：Hint 为失败时的修复建议。
+type doctorResult struct {
+	OK     bool
+	Detail string
+	Hint   string
+}
+
+// doctorCheck 一项独立的检查。
+type doctorCheck struct {
+	Name string
+	Run  func() doctorResult
+}
+
+// doctorChecks 按顺序执行的检查。
+var doctorChecks = []doctorCheck{
+	{"数据库文件可写", checkDatabaseWritable},
+	{"数据库表结构", checkDatabaseMigrated},
+	{"TMDB API Key", checkTMDBKey},
+	{"OMDb API Key", checkOMDbKey},
+	{"eiga.com 连通性", checkEigaReachable},
+	{"OSM 联系邮箱", checkOSMContact},
+	{"Nominatim 连通性", checkNominatimReachable},
+	{"Asia/Tokyo 时区数据", checkTokyoTimezone},
+}
+
+// doctorPass / doctorFail 构造检查结果。
+func doctorPass(detail string) doctorResult { return doctorResult{OK: true, Detail: detail} }
+
+func doctorFail(detail, hint string) doctorResult { return doctorResult{Detail: detail, Hint: hint} }
+
+// runDoctor 执行全部检查，返回进程退出码：全部通过为 0，否则为 1。
+// 数据库以 mode=rw 打开：不迁移，文件不存在时也不会顺手创建一个空库。
+func runDoctor() int {
+	fmt.Println("🩺 [doctor] 开始检查运行环境...")
+	conn, err := gorm.Open(sqlite.Open("file:"+appConfig.databaseDSN()+"&mode=rw"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err == nil {
+		db = conn
+	}
+
+	failed := 0
+	for _, check := range doctorChecks {
+		result := runDoctorCheck(check)
+		if result.OK {
+			fmt.Printf("✅ %s：%s\n", check.Name, result.Detail)
+			continue
+		}
+		failed++
+		fmt.Printf("❌ %s：%s\n", check.Name, result.Detail)
+		if result.Hint != "" {
+			fmt.Printf("   💡 %s\n", result.Hint)
+		}
+	}
+
+	if failed > 0 {
+		fmt.Printf("⚠️ [doctor] %d / %d 项检查未通过。\n", failed, len(doctorChecks))
+		return 1
+	}
+	fmt.Printf("✅ [doctor] 全部 %d 项检查通过。\n", len(doctorChecks))
+	return 0
+}
+
+// runDoctorCheck 执行单项检查；检查本身 panic 时记为失败，不影响其他检查。
+func runDoctorCheck(check doctorCheck) (result doctorResult) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			result = doctorFail(fmt.Sprintf("检查异常: %v", rec), "")
+		}
+	}()
+	return check.Run()
+}
+
+// checkDatabaseWritable 数据库文件与所在目录都可写（SQLite 需要在同目录创建日志文件）。
+func checkDatabaseWritable() doctorResult {
+	abs, _ := filepath.Abs(appConfig.DBPath)
+	if _, err := os.Stat(appConfig.DBPath); os.IsNotExist(err) {
+		return doctorFail(abs+" 不存在", "在项目目录下运行（cd cinema-scraper），或先启动一次 API 创建数据库")
+	}
+	f, err := os.OpenFile(appConfig.DBPath, os.O_WRONLY, 0)
+	if err != nil {
+		return doctorFail(fmt.Sprintf("%s 无法写入: %v", abs, err), "确认文件存在且当前用户有写权限（chmod u+w）")
+	}
+	f.Close()
+	probe, err := os.CreateTemp(filepath.Dir(abs), ".doctor-*")
+	if err != nil {
+		return doctorFail(fmt.Sprintf("%s 所在目录无法写入: %v", abs, err), "SQLite 需要在数据库所在目录创建 -journal / -wal 文件，请为目录授予写权限")
+	}
+	probe.Close()
+	os.Remove(probe.Name())
+	return doctorPass(abs)
+}
+
+// checkDatabaseMigrated 每个模型的表与列都已存在。
+func checkDatabaseMigrated() doctorResult {
+	if db == nil {
+		return doctorFail("数据库未能打开", "先解决“数据库文件可写”一项")
+	}
+	var missing []string
+	for _, model := range migratedModels {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(model); err != nil {
+			return doctorFail(fmt.Sprintf("解析模型失败: %v", err), "")
+		}
+		table := stmt.Schema.Table
+		if !db.Migrator().HasTable(model) {
+			missing = append(missing, table)
+			continue
+		}
+		for _, field := range stmt.Schema.Fields {
+			if field.DBName != "" && !db.Migrator().HasColumn(model, field.DBName) {
+				missing = append(missing, table+"."+field.DBName)
+			}
+		}
+	}
+	if len(missing) > 0 {
+		return doctorFail("缺少 "+strings.Join(missing, ", "), "运行任意命令（如 go run . update-status）或启动 API 一次，即会自动迁移")
+	}
+	return doctorPass(fmt.Sprintf("%d 张表均为最新", len(migratedModels)))
+}
+
+// doctorGet 发起一次 GET，返回状态码与响应体（最多 64KB）。
+func doctorGet(rawURL, userAgent string) (int, string, error) {
+	client := &http.Client{Timeout: doctorHTTPTimeout}
+	req, err := http.NewRequest("GET", rawURL, nil)
+	if err != nil {
+		return 0, "", err
+	}
+	if userAgent != "" {
+		req.Header.Set("User-Agent", userAgent)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	return resp.StatusCode, string(body), nil
+}
+
+// checkTMDBKey 请求 TMDB 的 /configuration（不消耗业务配额）。
+func checkTMDBKey() doctorResult {
+	if appConfig.TMDBAPIKey == "" {
+		return doctorFail("未设置 API Key", "设置环境变量 TMDB_API_KEY（https://www.themoviedb.org/settings/api），见 config.go")
+	}
+	status, _, err := doctorGet("https://api.themoviedb.org/3/configuration?api_key="+appConfig.TMDBAPIKey, "")
+	switch {
+	case err != nil:
+		return doctorFail(fmt.Sprintf("请求失败: %v", err), "检查到 api.themoviedb.org 的网络连接或代理设置")
+	case status == http.StatusUnauthorized:
+		return doctorFail("API Key 无效（401）", "设置环境变量 TMDB_API_KEY（https://www.themoviedb.org/settings/api），见 config.go")
+	case status != http.StatusOK:
+		return doctorFail(fmt.Sprintf("返回状态 %d", status), "稍后重试；持续失败时查看 https://status.themoviedb.org")
+	}
+	return doctorPass("API Key 有效")
+}
+
+// checkOMDbKey 用一个固定的 IMDb ID 查询一次 OMDb。
+func checkOMDbKey() doctorResult {
+	if appConfig.OMDBAPIKey == "" {
+		return doctorFail("未设置 API Key", "设置环境变量 OMDB_API_KEY（https://www.omdbapi.com/apikey.aspx），见 config.go")
+	}
+	status, body, err := doctorGet("http://www.omdbapi.com/?i=tt0111161&apikey="+appConfig.OMDBAPIKey, "")
+	if err != nil {
+		return doctorFail(fmt.Sprintf("请求失败: %v", err), "检查到 www.omdbapi.com 的网络连接或代理设置")
+	}
+	if isOmdbLimitResponse(body) {
+		return doctorFail("今日配额已用完", "OMDb 免费 Key 每天 1000 次，次日自动恢复；补全会在恢复后继续（见 omdb.go）")
+	}
+	var data struct {
+		Response string `json:"Response"`
+		Error    string `json:"Error"`
+	}
+	if json.Unmarshal([]byte(body), &data) != nil || data.Response != "True" {
+		detail := fmt.Sprintf("返回状态 %d", status)
+		if data.Error != "" {
+			detail = data.Error
+		}
+		return doctorFail(detail, "设置环境变量 OMDB_API_KEY（https://www.omdbapi.com/apikey.aspx），见 config.go")
+	}
+	return doctorPass("API Key 有效")
+}
+
+// checkEigaReachable eiga.com 首页可以访问。
+func checkEigaReachable() doctorResult {
+	status, _, err := doctorGet("https://eiga.com/", "")
+	if err != nil {
+		return doctorFail(fmt.Sprintf("请求失败: %v", err), "检查外网连接；排片与影院抓取都依赖 eiga.com")
+	}
+	if status >= 400 {
+		return doctorFail(fmt.Sprintf("返回状态 %d", status), "eiga.com 可能暂时不可用或拒绝了本机 IP，稍后重试")
+	}
+	return doctorPass(fmt.Sprintf("状态 %d", status))
+}
+
+// checkOSMContact Nominatim 要求的联系邮箱已配置且格式有效。
+func checkOSMContact() doctorResult {
+	if err := configureOSMContact(nil); err != nil {
+		return doctorFail(err.Error(), "设置环境变量 OSM_CONTACT_EMAIL（或抓取时传 --osm-email=），否则无法地理编码")
+	}
+	return doctorPass(osmContactEmail())
+}
+
+// checkNominatimReachable 请求 Nominatim 的 /status（不计入搜索配额）。
+func checkNominatimReachable() doctorResult {
+	userAgent := "TokyoCinePath/1.1 (doctor)"
+	if email := osmContactEmail(); email != "" {
+		userAgent = fmt.Sprintf("TokyoCinePath/1.1 (%s)", email)
+	}
+	status, _, err := doctorGet("https://nominatim.openstreetmap.org/status?format=json", userAgent)
+	if err != nil {
+		return doctorFail(fmt.Sprintf("请求失败: %v", err), "检查到 nominatim.openstreetmap.org 的网络连接或代理设置")
+	}
+	if status != http.StatusOK {
+		return doctorFail(fmt.Sprintf("返回状态 %d", status), "403 通常表示 User-Agent 被封禁，确认 OSM_CONTACT_EMAIL 有效并降低请求频率")
+	}
+	return doctorPass("服务正常")
+}
+
+// checkTokyoTimezone 系统时区数据中有 Asia/Tokyo（缺失时程序退化为固定 +9 偏移）。
+func checkTokyoTimezone() doctorResult {
+	if _, err := time.LoadLocation("Asia/Tokyo"); err != nil {
+		return doctorFail(fmt.Sprintf("无法加载: %v", err), "安装 tzdata（如 apk add tzdata / apt-get install tzdata），或构建时加 -tags timetzdata")
+	}
+	return doctorPass("已加载")
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+// ===========================
+// 模块：eiga.com 稳定标识（Cinema.EigaSlug / Movie.EigaID）
+// 职责：
+// - 影院详情页 URL 末段的影院编号（/theater/13/130201/3015/ → 3015）存为 Cinema.EigaSlug，
+//   影片区块 section#m102345 的编号存为 Movie.EigaID，两者各有唯一索引（空值不参与）
+// - 两个爬虫先按标识匹配，找不到时才按日文名 / 规范化片名匹配旧数据，并顺手写入标识；
+//   eiga.com 改了片名（加 ★、空白不同、追加【IMAX】）也不会再建出重复影片或丢失排片
+// - 启动时由已记录的 EigaURL 补齐影院标识；影片标识没有本地来源，在下一次 crawl-schedules 按片名命中时补齐
+// - 抓取结束后的去重：本次与某 EigaID 一起出现过的片名，如果还对应着没有标识的旧影片，把旧影片并入持有该标识的影片
+// 说明：按片名命中的影片已有另一个 EigaID 时（合并过的同片异名），沿用该影片，不新建。
+// ===========================
+
+// eigaTheaterSlugRe 影院详情页（含翻页后的日期路径）中的影院编号。
+var eigaTheaterSlugRe = regexp.MustCompile(`^https?://eiga\.com/theater/\d+/\d+/(\d+)/`)
+
+// eigaMovieSectionRe / eigaMovieLinkRe 影片区块 id（m102345）与片名链接（/movie/102345/）中的影片编号。
+var (
+	eigaMovieSectionRe = regexp.MustCompile(`^m(\d+)$`)
+	eigaMovieLinkRe    = regexp.MustCompile(`/movie/(\d+)/?`)
+)
+
+// eigaTheaterSlug 从影院详情页 URL 取出影院编号（纯函数），不是详情页时为空串。
+func eigaTheaterSlug(link string) string {
+	if m := eigaTheaterSlugRe.FindStringSubmatch(link); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+// eigaMovieID 从影片区块的 id 取出影片编号（纯函数），取不到时退回片名链接；都没有时为空串。
+func eigaMovieID(sectionID, href string) string {
+	if m := eigaMovieSectionRe.FindStringSubmatch(strings.TrimSpace(sectionID)); m != nil {
+		return m[1]
+	}
+	if m := eigaMovieLinkRe.FindStringSubmatch(href); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+// migrateEigaKeys 建立 eiga.com 标识的唯一索引（空串不参与，兼容尚未补齐的旧数据），并由 EigaURL 补齐影院标识。
+func migrateEigaKeys(conn *gorm.DB) error {
+	var cinemas []Cinema
+	if err := conn.Select("id", "eiga_url").Where("(eiga_slug IS NULL OR eiga_slug = '') AND eiga_url <> ''").
+		Order("id").Find(&cinemas).Error; err != nil {
+		return err
+	}
+	taken := make(map[string]bool)
+	var existing []string
+	if err := conn.Model(&Cinema{}).Where("eiga_slug <> ''").Pluck("eiga_slug", &existing).Error; err != nil {
+		return err
+	}
+	for _, slug := range existing {
+		taken[slug] = true
+	}
+	for _, cin := range cinemas {
+		slug := eigaTheaterSlug(cin.EigaURL)
+		if slug == "" || taken[slug] {
+			continue
+		}
+		if err := conn.Model(&Cinema{}).Where("id = ?", cin.ID).UpdateColumn("eiga_slug", slug).Error; err != nil {
+			return err
+		}
+		taken[slug] = true
+	}
+	for _, stmt := range []string{
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_cinemas_eiga_slug ON cinemas(eiga_slug) WHERE eiga_slug <> ''`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_movies_eiga_id ON movies(eiga_id) WHERE eiga_id <> ''`,
+	} {
+		if err := conn.Exec(stmt).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// findEigaCinema 排片抓取时找到详情页对应的影院：先按影院编号，再按日文名（旧数据，命中后写入编号）。
+func findEigaCinema(slug, nameJP string) (Cinema, error) {
+	var cinema Cinema
+	if slug != "" {
+		if err := db.Where("eiga_slug = ?", slug).Limit(1).Find(&cinema).Error; err != nil {
+			return cinema, err
+		}
+		if cinema.ID != 0 {
+			return cinema, nil
+		}
+	}
+	if err := db.Where("name_jp = ?", nameJP).First(&cinema).Error; err != nil {
+		return cinema, err
+	}
+	if slug != "" && cinema.EigaSlug == "" {
+		if err := db.Model(&cinema).UpdateColumn("eiga_slug", slug).Error; err != nil {
+			fmt.Printf("⚠️ 写入影院编号失败 [%s → %s]: %v\n", cinema.NameJP, slug, err)
+		} else {
+			cinema.EigaSlug = slug
+		}
+	}
+	return cinema, nil
+}
+
+// eigaTitleSightings 本次抓取中每个 EigaID 出现时的规范化片名，抓取结束后用于去重。
+var eigaTitleSightings = struct {
+	sync.Mutex
+	byID map[string]map[string]bool
+}{byID: make(map[string]map[string]bool)}
+
+// recordEigaTitleSighting 记录一次 (EigaID, 片名)。
+func recordEigaTitleSighting(eigaID, titleJP string) {
+	if eigaID == "" || titleJP == "" {
+		return
+	}
+	eigaTitleSightings.Lock()
+	defer eigaTitleSightings.Unlock()
+	if eigaTitleSightings.byID[eigaID] == nil {
+		eigaTitleSightings.byID[eigaID] = make(map[string]bool)
+	}
+	eigaTitleSightings.byID[eigaID][titleJP] = true
+}
+
+// findOrCreateEigaMovie eiga.com 排片中的影片：先按 EigaID，再按片名（旧数据，命中后写入 EigaID），都没有时新建。
+// 已被软删除的影片返回 errMovieDeleted（与 findOrCreateMovieByTitle 相同）。
+func findOrCreateEigaMovie(eigaID, rawTitle string) (Movie, error) {
+	recordEigaTitleSighting(eigaID, NormalizeTitle(rawTitle))
+	if eigaID == "" {
+		return findOrCreateMovieByTitle(rawTitle, SourceEiga)
+	}
+	var movie Movie
+	if err := db.Unscoped().Where("eiga_id = ?", eigaID).Limit(1).Find(&movie).Error; err != nil {
+		return movie, err
+	}
+	if movie.ID != 0 {
+		if movie.DeletedAt.Valid {
+			return movie, errMovieDeleted
+		}
+		return movie, nil
+	}
+
+	movie, err := findMovieByTitle(rawTitle)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return createMovieFromTitle(rawTitle, SourceEiga, eigaID)
+	}
+	if err != nil {
+		return movie, err
+	}
+	if movie.EigaID == "" {
+		recordProvenance(&movie.ProvenanceJSON, SourceEiga, "eiga_id")
+		if err := db.Model(&movie).UpdateColumns(map[string]interface{}{
+			"eiga_id":         eigaID,
+			"provenance_json": movie.ProvenanceJSON,
+		}).Error; err != nil {
+			fmt.Printf("⚠️ 写入影片编号失败 [%s → %s]: %v\n", movie.TitleJP, eigaID, err)
+		} else {
+			movie.EigaID = eigaID
+		}
+	}
+	if movie.DeletedAt.Valid {
+		return movie, errMovieDeleted
+	}
+	return movie, nil
+}
+
+// collapseEigaDuplicates 抓取结束后的去重：与某 EigaID 一起出现过的片名若还对应没有 EigaID 的旧影片，
+// 把旧影片并入持有该 EigaID 的影片。同一片名出现在多个 EigaID 下（重名的不同作品）时跳过，
+// eiga.com 片长相矛盾时也跳过。返回合并掉的影片数。
+func collapseEigaDuplicates() (int, error) {
+	eigaTitleSightings.Lock()
+	sightings := eigaTitleSightings.byID
+	eigaTitleSightings.byID = make(map[string]map[string]bool)
+	eigaTitleSightings.Unlock()
+
+	idsByTitle := make(map[string]int)
+	for _, titles := range sightings {
+		for title := range titles {
+			idsByTitle[title]++
+		}
+	}
+	merged := 0
+	for eigaID, titles := range sightings {
+		var keep Movie
+		if err := db.Where("eiga_id = ?", eigaID).Limit(1).Find(&keep).Error; err != nil {
+			return merged, err
+		}
+		if keep.ID == 0 {
+			continue
+		}
+		for title := range titles {
+			if idsByTitle[title] > 1 {
+				continue
+			}
+			var dups []Movie
+			if err := db.Where("(eiga_id IS NULL OR eiga_id = '') AND title_jp = ? AND id <> ?", title, keep.ID).
+				Order("id").Find(&dups).Error; err != nil {
+				return merged, err
+			}
+			for _, drop := range dups {
+				if conflict := eigaRuntimeConflict([]Movie{keep, drop}); conflict != "" {
+					fmt.Printf("⚠️ eiga:%s 片名相同但存在矛盾（%s），跳过去重： [%d %s] [%d %s]\n", eigaID, conflict, keep.ID, keep.TitleJP, drop.ID, drop.TitleJP)
+					continue
+				}
+				res, err := mergeMovieInto(&keep, drop)
+				if err != nil {
+					return merged, fmt.Errorf("合并影片 %d -> %d 失败: %v", drop.ID, keep.ID, err)
+				}
+				merged++
+				fmt.Printf("🔗 合并同一 eiga.com 影片（eiga:%s）：[%d %s] -> [%d %s]，迁移排片 %d 条（重复场次丢弃 %d 条）、归档排片 %d 条\n",
+					eigaID, drop.ID, drop.TitleJP, keep.ID, keep.TitleJP, res.Schedules, res.DroppedSchedules, res.Archived)
+			}
+		}
+	}
+	return merged, nil
+}
+
+// runEigaDedupeAfterCrawl crawl-schedules 结束后执行 collapseEigaDuplicates：失败只记录，不影响抓取结果。
+func runEigaDedupeAfterCrawl() {
+	n, err := collapseEigaDuplicates()
+	if err != nil {
+		fmt.Printf("⚠️ 按 eiga.com 影片编号去重失败: %v\n", err)
+	}
+	if n > 0 {
+		fmt.Printf("🔗 已按 eiga.com 影片编号合并 %d 部重复影片\n", n)
+	}
+}
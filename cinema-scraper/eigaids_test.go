@@ -0,0 +1,61 @@
+package main
+
+import (
+	"testing"
+)
+
+// TestEigaIDsFromPage eiga.com 标识：影院编号取自详情页 URL，影片编号取自区块 id，其次片名链接。
+func TestEigaIDsFromPage(t *testing.T) {
+	setTestClock(t, beforeMidnight)
+
+	if err := firstError(
+		expectEqual("theater slug", eigaTheaterSlug("https://eiga.com/theater/13/130201/3015/date/20260201/"), "3015"),
+		expectEqual("area page", eigaTheaterSlug("https://eiga.com/theater/13/"), ""),
+		expectEqual("section id", eigaMovieID("m102345", "/movie/999/"), "102345"),
+		expectEqual("link fallback", eigaMovieID("", "/movie/102345/"), "102345"),
+		expectEqual("no id", eigaMovieID("main", "/special/"), "")); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestFindOrCreateEigaMovie eiga.com 影片编号：按片名命中旧影片时写入编号，改名后仍命中同一部，抓取后合并同编号的旧影片。
+func TestFindOrCreateEigaMovie(t *testing.T) {
+	newTestRouter(t)
+	now := testNoon
+	setTestClock(t, now)
+
+	legacy := Movie{TitleJP: "テスト映画甲", Status: "showing"}
+	renamed := Movie{TitleJP: "テスト映画乙", Status: "showing"}
+	for _, m := range []*Movie{&legacy, &renamed} {
+		if err := db.Create(m).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	first, err := findOrCreateEigaMovie("900001", "テスト映画甲")
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := findOrCreateEigaMovie("900001", "テスト映画乙")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fresh, err := findOrCreateEigaMovie("900002", "テスト新作")
+	if err != nil {
+		t.Fatal(err)
+	}
+	merged, err := collapseEigaDuplicates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var remaining int64
+	db.Model(&Movie{}).Where("id = ?", renamed.ID).Count(&remaining)
+	if err := firstError(
+		expectEqual("legacy backfilled", first.ID, legacy.ID),
+		expectEqual("legacy eiga id", first.EigaID, "900001"),
+		expectEqual("renamed title reuses movie", again.ID, legacy.ID),
+		expectEqual("new movie eiga id", fresh.EigaID, "900002"),
+		expectEqual("collapsed", merged, 1),
+		expectEqual("renamed legacy merged away", remaining, int64(0))); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync/atomic"
+)
+
+// ===========================
+// 模块：清理从 eiga.com 消失的场次
+// 职责：
+// - crawl-schedules 原先只增不删：影院取消场次或把开场时间挪了 10 分钟，旧场次会一直留在库里，影院详情出现“幽灵场次”
+// - 每家影院的各周排片表解析完后，收集页面上出现过的 (影片, 日期, 开始时间)，
+//   删除该影院在本次覆盖日期内（仅今天及以后）未再出现的场次，并按影院打印清理数
+// - 任一影片区块查询或写入失败、panic 被跳过，或本次解析结果被判定为异常时不清理，以免误删
+// 说明：与 crawl-custom 清理官网已下架场次的做法一致（见 customsource.go），删除为物理删除，
+//       清理后按热表重算放映跨度汇总；这些场次没有真正放映过，不计入已清理（归档）部分。
+// 调用方式：
+//   go run . crawl-schedules --no-prune   # 谨慎运行：只写入，不清理
+// ===========================
+
+// schedulePruneEnabled 本次 crawl-schedules 是否清理消失的场次，--no-prune 时关闭。
+var schedulePruneEnabled = true
+
+// crawlPrunedShowtimes 本次运行清理掉的场次数。
+var crawlPrunedShowtimes atomic.Int64
+
+// eigaCinemaSlots 同一影院各周次共享的抓取结果（保存在请求 Context 中）。
+type eigaCinemaSlots struct {
+	seen       map[string]bool // 页面上出现过的场次槽位（scheduleSlotKey）
+	incomplete bool            // 有影片区块查询或写入失败、或 panic 被跳过，本影院不清理
+}
+
+// errSlotsIncomplete 本影院的抓取结果不完整，拒绝清理。
+var errSlotsIncomplete = errors.New("cinema slots are incomplete, refusing to prune")
+
+// scheduleSlotKey 场次槽位的键：影片 + 日期（YYYY-MM-DD）+ 开始时间。
+func scheduleSlotKey(movieID uint, date, startTime string) string {
+	return fmt.Sprintf("%d|%s|%s", movieID, date, startTime)
+}
+
+// vanishedScheduleIDs 从现有场次中挑出页面上没有再出现的场次 ID（纯函数），按 ID 排序。
+func vanishedScheduleIDs(existing []Schedule, seen map[string]bool) []uint {
+	ids := make([]uint, 0)
+	for _, s := range existing {
+		if !seen[scheduleSlotKey(s.MovieID, s.PlayDate.Format("2006-01-02"), s.StartTime)] {
+			ids = append(ids, s.ID)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// pruneVanishedShowtimes 删除影院在 dates 中（今天之前的日期忽略）没有出现在 slots.seen 里的场次，返回删除数；
+// slots 不完整时不删除，返回 errSlotsIncomplete。
+func pruneVanishedShowtimes(cinemaID uint, dates []string, slots *eigaCinemaSlots, today string) (int, error) {
+	if slots.incomplete {
+		return 0, errSlotsIncomplete
+	}
+	covered := make([]string, 0, len(dates))
+	for _, d := range dates {
+		if d >= today {
+			covered = append(covered, d)
+		}
+	}
+	if len(covered) == 0 {
+		return 0, nil
+	}
+	var existing []Schedule
+	if err := db.Select("id", "movie_id", "play_date", "start_time").
+		Where("cinema_id = ? AND date(play_date) IN ?", cinemaID, covered).Find(&existing).Error; err != nil {
+		return 0, err
+	}
+	ids := vanishedScheduleIDs(existing, slots.seen)
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	if err := db.Unscoped().Where("id IN ?", ids).Delete(&Schedule{}).Error; err != nil {
+		return 0, err
+	}
+	if err := refreshRunSummaries(cinemaID, nil); err != nil {
+		fmt.Printf("⚠️ 刷新放映跨度汇总失败 [cinema %d]: %v\n", cinemaID, err)
+	}
+	return len(ids), nil
+}
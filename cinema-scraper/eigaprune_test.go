@@ -0,0 +1,58 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestPruneVanishedShowtimes 消失场次清理：只删除覆盖日期内（今天及以后）页面上没有再出现的场次，有影片区块 panic 时整家影院不清理。
+func TestPruneVanishedShowtimes(t *testing.T) {
+	newTestRouter(t)
+	now := testNoon
+	setTestClock(t, now)
+
+	movie := Movie{TitleJP: "テスト幽霊上映", Status: "showing"}
+	if err := db.Create(&movie).Error; err != nil {
+		t.Fatal(err)
+	}
+	day := func(d int) time.Time { return time.Date(2026, 3, d, 0, 0, 0, 0, time.UTC) } // 同一影院这两天没有其他场次
+	past := time.Date(2026, 1, 20, 0, 0, 0, 0, time.UTC)
+	shows := []Schedule{
+		{MovieID: movie.ID, CinemaID: 1, PlayDate: day(5), StartTime: "10:00"},
+		{MovieID: movie.ID, CinemaID: 1, PlayDate: day(5), StartTime: "14:00"}, // 挪到了 14:10
+		{MovieID: movie.ID, CinemaID: 1, PlayDate: day(6), StartTime: "10:00"}, // 不在本次覆盖的日期内
+		{MovieID: movie.ID, CinemaID: 1, PlayDate: past, StartTime: "10:00"},   // 过去的日期不清理
+	}
+	if err := db.Create(&shows).Error; err != nil {
+		t.Fatal(err)
+	}
+	seen := map[string]bool{
+		scheduleSlotKey(movie.ID, "2026-03-05", "10:00"): true,
+		scheduleSlotKey(movie.ID, "2026-03-05", "14:10"): true,
+	}
+	dates := []string{"2026-01-20", "2026-03-05"}
+	// 同一影院另一部影片的区块 panic：它的场次没有记入 seen，不能据此清理
+	partial := &eigaCinemaSlots{seen: seen}
+	func() {
+		defer recoverAndMark("测试影片区块", func() { partial.incomplete = true })
+		panic("broken section")
+	}()
+	skipped, skipErr := pruneVanishedShowtimes(1, dates, partial, "2026-01-27")
+	var kept int64
+	db.Model(&Schedule{}).Where("movie_id = ?", movie.ID).Count(&kept)
+	removed, err := pruneVanishedShowtimes(1, dates, &eigaCinemaSlots{seen: seen}, "2026-01-27")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var left []string
+	db.Model(&Schedule{}).Where("movie_id = ?", movie.ID).Order("play_date, start_time").
+		Pluck("date(play_date) || ' ' || start_time", &left)
+	if err := firstError(
+		expectEqual("incomplete refused", [3]interface{}{skipped, errors.Is(skipErr, errSlotsIncomplete), kept}, [3]interface{}{0, true, int64(4)}),
+		expectEqual("removed", removed, 1),
+		expectEqual("remaining", strings.Join(left, ","), "2026-01-20 10:00,2026-03-05 10:00,2026-03-06 10:00")); err != nil {
+		t.Fatal(err)
+	}
+}
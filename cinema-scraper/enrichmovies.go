@@ -0,0 +1,166 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ===========================
+// 模块：影片补全命令（enrich-movies）
+// 职责：
+// - crawl-schedules / crawl-custom 只建影片行（日文片名、eiga.com 编号、片长与制作年份），不在抓取回调里请求
+//   TMDB / OMDb / 豆瓣：一次慢请求不再拖住整个抓取，重复抓取也不会对同一批影片反复发请求
+// - enrich-movies 先补上次因 OMDb 配额跳过的 IMDb 评分，再补全资料不全的影片（判断条件见 movieNeedsEnrichment，
+//   因 TMDB 熔断推迟的影片排在最前），以 --workers 个并发执行
+// - 补全后合并 TMDB / IMDb ID 相同的重复影片，并重算补全过的影片的状态（日本上映日期可能改变 incoming / showing）
+// 调用方式：
+//   go run . enrich-movies                建议在 crawl-schedules 之后执行
+//   go run . enrich-movies --workers=5    并发数（默认 3，最多 8）
+//   go run . enrich-movies --force        资料齐全的影片也重新请求外部接口
+//   go run . enrich-movies --id 42        只处理一部影片，便于调试（不论资料是否齐全）
+// ===========================
+
+const (
+	defaultEnrichWorkers = 3
+	maxEnrichWorkers     = 8 // 每部影片要发 4-5 个请求，并发再高容易触发 TMDB / OMDb 限流
+)
+
+// movieNeedsEnrichment 影片资料是否仍不完整（纯函数）：中 / 英标题、TMDB 评分、上映日期任一缺失。
+// 注意：之前有一版逻辑没有考虑 ReleaseDate，可能导致字段齐全但上映日期为 0001-01-01 的旧数据。
+func movieNeedsEnrichment(m Movie) bool {
+	return m.TitleCN == "" || m.TitleEN == "" || m.TMDBRating <= 0 || m.ReleaseDate.IsZero()
+}
+
+// parseEnrichWorkers 解析 --workers=N，限制在 [1, maxEnrichWorkers]；非法值使用默认值。
+func parseEnrichWorkers(args []string) int {
+	v, ok := flagValue(args, "--workers")
+	if !ok {
+		return defaultEnrichWorkers
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 1 {
+		return defaultEnrichWorkers
+	}
+	return min(n, maxEnrichWorkers)
+}
+
+// selectMoviesToEnrich 待补全的影片：指定 id 时只取这一部；否则为资料不全（force 时为全部）的非活动影片，
+// TMDB 熔断推迟的排在前面，其余按 ID。非 force 时跳过近期补全失败、尚未到重试时间的影片（见 enrichretry.go）。
+func selectMoviesToEnrich(force bool, id uint) ([]Movie, error) {
+	if id != 0 {
+		var m Movie
+		if err := db.First(&m, id).Error; err != nil {
+			return nil, fmt.Errorf("movie %d: %w", id, err)
+		}
+		return []Movie{m}, nil
+	}
+	var all []Movie
+	if err := db.Order("id").Find(&all).Error; err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	out := make([]Movie, 0, len(all))
+	backoff := 0
+	for _, m := range all {
+		if isEventMovie(m) {
+			continue
+		}
+		if !force && !m.TMDBPending && !enrichRetryDue(m, now) {
+			backoff++
+			continue
+		}
+		if force || m.TMDBPending || movieNeedsEnrichment(m) {
+			out = append(out, m)
+		}
+	}
+	if backoff > 0 {
+		fmt.Printf("⏭️ %d 部影片近期补全失败，未到重试时间（list-unenriched 查看）\n", backoff)
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].TMDBPending && !out[j].TMDBPending })
+	return out, nil
+}
+
+// enrichMoviesConcurrently 以 workers 个并发补全影片，返回补全后资料已齐全的数量。
+// 各外部接口的限流 / 熔断状态本身是并发安全的（见 tmdbbreaker.go、omdb.go）。
+func enrichMoviesConcurrently(movies []Movie, workers int, force bool) int {
+	var done atomic.Int64
+	var completed atomic.Int64
+	jobs := make(chan *Movie)
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for m := range jobs {
+				enrichMovieRatings(appConfig, m, force)
+				if !movieNeedsEnrichment(*m) {
+					completed.Add(1)
+				}
+				if n := done.Add(1); n%20 == 0 {
+					fmt.Printf("   ⏳ 已处理 %d / %d 部影片\n", n, len(movies))
+				}
+			}
+		}()
+	}
+	for i := range movies {
+		jobs <- &movies[i]
+	}
+	close(jobs)
+	wg.Wait()
+	return int(completed.Load())
+}
+
+// runEnrichMovies enrich-movies 命令。
+func runEnrichMovies(args []string) error {
+	force := hasFlag(args, "--force")
+	var id uint
+	if v, ok := flagValue(args, "--id"); ok {
+		n, err := strconv.ParseUint(v, 10, 64)
+		if err != nil || n == 0 {
+			return fmt.Errorf("--id=%q 无效，应为正整数", v)
+		}
+		id = uint(n)
+	}
+	workers := parseEnrichWorkers(args)
+
+	if id == 0 {
+		if n := resumePendingImdbRatings(); n > 0 {
+			fmt.Printf("⭐ 已补全 %d 部待补全的 IMDb 评分\n", n)
+		}
+	}
+	movies, err := selectMoviesToEnrich(force, id)
+	if err != nil {
+		return err
+	}
+	if len(movies) == 0 {
+		fmt.Println("ℹ️ 没有需要补全的影片。")
+		return nil
+	}
+	// 指定 --id 时不论资料是否齐全都重新补全
+	force = force || id != 0
+	fmt.Printf("🎞️ [enrich-movies] 补全 %d 部影片（并发 %d，force=%v）...\n", len(movies), workers, force)
+	completed := enrichMoviesConcurrently(movies, workers, force)
+
+	// 补全后才有 TMDB / IMDb ID，合并重复影片；被合并掉的影片不再重算状态
+	runAutoMergeAfterCrawl()
+	ids := make([]uint, 0, len(movies))
+	for _, m := range movies {
+		ids = append(ids, m.ID)
+	}
+	var remaining []Movie
+	if err := db.Where("id IN ?", ids).Order("id").Find(&remaining).Error; err != nil {
+		return err
+	}
+	if n := refreshMovieStatuses(remaining, nowJST(), StatusSourceUpdate); n > 0 {
+		fmt.Printf("🔄 已按补全后的上映日期更新 %d 部影片的状态\n", n)
+	}
+	printOmdbSummary()
+	printTmdbSummary()
+	printApiCacheSummary()
+	fmt.Printf("✅ [enrich-movies] 完成：%d / %d 部影片资料已齐全，程序退出。\n", completed, len(movies))
+	return nil
+}
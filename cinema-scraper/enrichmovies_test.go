@@ -0,0 +1,54 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// TestEnrichMoviesSelection enrich-movies：只挑资料不全的影片，熔断推迟的在前，活动不补全，--id 只取一部。
+func TestEnrichMoviesSelection(t *testing.T) {
+	newTestRouter(t)
+	now := testNoon
+	setTestClock(t, now)
+
+	released := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	complete := Movie{TitleJP: "テスト補完済み", TitleCN: "已补全", TitleEN: "Done", TMDBRating: 7, ReleaseDate: released}
+	bare := Movie{TitleJP: "テスト未補完"}
+	deferred := Movie{TitleJP: "テスト保留", TMDBPending: true}
+	event := Movie{TitleJP: "テストライブビューイング", Kind: MovieKindEvent}
+	for _, m := range []*Movie{&complete, &bare, &deferred, &event} {
+		if err := db.Create(m).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	position := func(list []Movie) map[uint]int {
+		out := make(map[uint]int, len(list))
+		for i, m := range list {
+			out[m.ID] = i + 1
+		}
+		return out
+	}
+	normal, err := selectMoviesToEnrich(false, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	forced, err := selectMoviesToEnrich(true, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	single, err := selectMoviesToEnrich(false, complete.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pos, forcedPos := position(normal), position(forced)
+	if err := firstError(
+		expectEqual("pending first", pos[deferred.ID], 1),
+		expectEqual("bare included", pos[bare.ID] > 0, true),
+		expectEqual("complete skipped", pos[complete.ID], 0),
+		expectEqual("event skipped", pos[event.ID]+forcedPos[event.ID], 0),
+		expectEqual("force includes complete", forcedPos[complete.ID] > 0, true),
+		expectEqual("single", len(single) == 1 && single[0].ID == complete.ID, true),
+		expectEqual("workers", [3]int{parseEnrichWorkers(nil), parseEnrichWorkers([]string{"--workers=20"}), parseEnrichWorkers([]string{"--workers", "x"})}, [3]int{3, 8, 3})); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ===========================
+// 模块：补全失败重试队列（list-unenriched / set-tmdb-id）
+// 职责：
+// - TMDB 搜不到影片（或搜索请求失败）时，在影片行上记下失败次数、原因与时间（EnrichAttempts / LastEnrichError / LastEnrichAt），
+//   enrich-movies 按退避间隔跳过近期失败过的影片：第 n 次失败后等待 1 天 × 2^(n-1)，最长 tmdbMissTTL；
+//   拿到 TMDB ID 后清空失败记录
+// - list-unenriched：列出失败过且资料仍不全的影片（日文片名、次数、原因、下次重试时间），便于修改片名或手动指定 TMDB ID
+// - set-tmdb-id：手动指定 TMDB ID（来源记为 manual），之后补全直接使用该 ID、不再按片名搜索，命令随即强制补全一次
+// 说明：补全只填写空字段，set-tmdb-id 用于纠正错误匹配时，之前匹配写入的资料不会被覆盖。
+// 调用方式：
+//   go run . list-unenriched
+//   go run . set-tmdb-id 42 12345     影片 ID 42 使用 TMDB 电影 12345
+// ===========================
+
+const (
+	enrichRetryBase         = 24 * time.Hour
+	enrichErrNoResults      = "tmdb search: no results"
+	enrichErrKnownNoResults = "tmdb search: no results (cached)"
+)
+
+// enrichRetryBackoff 第 attempts 次失败后到下次重试的等待时间（纯函数）：1 天起每次翻倍，最长 tmdbMissTTL。
+func enrichRetryBackoff(attempts int) time.Duration {
+	if attempts <= 0 {
+		return 0
+	}
+	d := enrichRetryBase
+	for i := 1; i < attempts && d < tmdbMissTTL; i++ {
+		d *= 2
+	}
+	return min(d, tmdbMissTTL)
+}
+
+// enrichRetryDue 影片是否可以再次尝试补全（纯函数）：没有失败记录，或距上次失败已超过退避时间。
+func enrichRetryDue(m Movie, now time.Time) bool {
+	if m.EnrichAttempts == 0 || m.LastEnrichAt == nil {
+		return true
+	}
+	return !now.Before(m.LastEnrichAt.Add(enrichRetryBackoff(m.EnrichAttempts)))
+}
+
+// manualTmdbID 策展人手动指定的 TMDB ID（tmdb_id 来源为 manual），没有时返回 0。
+func manualTmdbID(m Movie) int {
+	if m.TMDBID > 0 && parseProvenance(m.ProvenanceJSON)["tmdb_id"].Source == SourceManual {
+		return m.TMDBID
+	}
+	return 0
+}
+
+// recordEnrichFailure 记录一次补全失败并立即写库（失败路径不会走到补全末尾的 Save）。
+func recordEnrichFailure(m *Movie, reason string, now time.Time) {
+	m.EnrichAttempts++
+	m.LastEnrichError = reason
+	m.LastEnrichAt = &now
+	if err := db.Model(m).Updates(map[string]interface{}{
+		"enrich_attempts":   m.EnrichAttempts,
+		"last_enrich_error": m.LastEnrichError,
+		"last_enrich_at":    m.LastEnrichAt,
+	}).Error; err != nil {
+		fmt.Printf("⚠️ 记录补全失败出错 [%s]: %v\n", m.TitleJP, err)
+	}
+}
+
+// clearEnrichFailure 拿到 TMDB ID 后清空失败记录（随补全结果一起保存）。
+func clearEnrichFailure(m *Movie) {
+	m.EnrichAttempts = 0
+	m.LastEnrichError = ""
+	m.LastEnrichAt = nil
+}
+
+// listUnenrichedMovies 补全失败过且资料仍不全的非活动影片，按 ID 排序。
+func listUnenrichedMovies() ([]Movie, error) {
+	var movies []Movie
+	if err := db.Where("enrich_attempts > 0").Order("id").Find(&movies).Error; err != nil {
+		return nil, err
+	}
+	out := make([]Movie, 0, len(movies))
+	for _, m := range movies {
+		if !isEventMovie(m) && movieNeedsEnrichment(m) {
+			out = append(out, m)
+		}
+	}
+	return out, nil
+}
+
+// runListUnenriched list-unenriched 命令。
+func runListUnenriched() error {
+	movies, err := listUnenrichedMovies()
+	if err != nil {
+		return err
+	}
+	if len(movies) == 0 {
+		fmt.Println("ℹ️ 没有补全失败的影片。")
+		return nil
+	}
+	now := time.Now()
+	fmt.Printf("%-6s  %-4s  %-16s  %-16s  %-32s  %s\n", "ID", "次数", "上次失败", "下次重试", "原因", "TitleJP")
+	for _, m := range movies {
+		last, next := "-", "-"
+		if m.LastEnrichAt != nil {
+			last = m.LastEnrichAt.In(tokyoLocation).Format("2006-01-02 15:04")
+			if enrichRetryDue(m, now) {
+				next = "下次 enrich-movies"
+			} else {
+				next = m.LastEnrichAt.Add(enrichRetryBackoff(m.EnrichAttempts)).In(tokyoLocation).Format("2006-01-02 15:04")
+			}
+		}
+		fmt.Printf("%-6d  %-4d  %-16s  %-16s  %-32s  %s\n", m.ID, m.EnrichAttempts, last, next, m.LastEnrichError, m.TitleJP)
+	}
+	fmt.Printf("共 %d 部。修改片名后运行 enrich-movies --id <ID>，或用 set-tmdb-id <ID> <TMDB ID> 手动指定。\n", len(movies))
+	return nil
+}
+
+// parseSetTmdbIDArgs 解析 set-tmdb-id 的两个位置参数（纯函数）：影片 ID 与 TMDB ID，均须为正整数。
+func parseSetTmdbIDArgs(args []string) (uint, int, error) {
+	var pos []string
+	for _, a := range args {
+		if !strings.HasPrefix(a, "--") {
+			pos = append(pos, a)
+		}
+	}
+	if len(pos) != 2 {
+		return 0, 0, errors.New("用法：go run . set-tmdb-id <影片 ID> <TMDB ID>")
+	}
+	movieID, err := strconv.ParseUint(pos[0], 10, 64)
+	if err != nil || movieID == 0 {
+		return 0, 0, fmt.Errorf("影片 ID %q 无效，应为正整数", pos[0])
+	}
+	tmdbID, err := strconv.Atoi(pos[1])
+	if err != nil || tmdbID <= 0 {
+		return 0, 0, fmt.Errorf("TMDB ID %q 无效，应为正整数", pos[1])
+	}
+	return uint(movieID), tmdbID, nil
+}
+
+// setManualTmdbID 为影片手动指定 TMDB ID 并清空失败记录，返回更新后的影片。
+func setManualTmdbID(movieID uint, tmdbID int) (Movie, error) {
+	var m Movie
+	if err := db.First(&m, movieID).Error; err != nil {
+		return m, fmt.Errorf("movie %d: %w", movieID, err)
+	}
+	if isEventMovie(m) {
+		return m, fmt.Errorf("movie %d 是活动（kind=event），不查 TMDB", movieID)
+	}
+	if m.TMDBID != 0 && m.TMDBID != tmdbID {
+		fmt.Printf("ℹ️ 影片 %d 原 TMDB ID %d 改为 %d（已有资料不会被覆盖）\n", m.ID, m.TMDBID, tmdbID)
+	}
+	m.TMDBID = tmdbID
+	recordProvenance(&m.ProvenanceJSON, SourceManual, "tmdb_id")
+	clearEnrichFailure(&m)
+	if err := db.Model(&m).Updates(map[string]interface{}{
+		"tmdb_id":           m.TMDBID,
+		"provenance_json":   m.ProvenanceJSON,
+		"enrich_attempts":   0,
+		"last_enrich_error": "",
+		"last_enrich_at":    nil,
+	}).Error; err != nil {
+		return m, err
+	}
+	return m, nil
+}
+
+// runSetTmdbID set-tmdb-id 命令：保存手动 TMDB ID 后立即强制补全，再合并重复影片并重算状态。
+func runSetTmdbID(args []string) error {
+	movieID, tmdbID, err := parseSetTmdbIDArgs(args)
+	if err != nil {
+		return err
+	}
+	m, err := setManualTmdbID(movieID, tmdbID)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("📌 影片 %d [%s] 已指定 TMDB ID %d\n", m.ID, m.TitleJP, tmdbID)
+	if err := appConfig.requireTMDB(); err != nil {
+		fmt.Printf("⚠️ 未补全（%v），配置后运行 enrich-movies --id %d\n", err, m.ID)
+		return nil
+	}
+	enrichMovieRatings(appConfig, &m, true)
+
+	// 手动指定的 ID 可能与已有影片相同，合并后本片可能已不存在
+	runAutoMergeAfterCrawl()
+	var after Movie
+	if err := db.First(&after, m.ID).Error; err == nil {
+		refreshMovieStatuses([]Movie{after}, nowJST(), StatusSourceUpdate)
+	}
+	return nil
+}
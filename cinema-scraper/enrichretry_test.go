@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// TestEnrichRetry 补全重试：TMDB 搜不到时记录失败并按退避跳过，set-tmdb-id 指定 ID 后直接补全。
+func TestEnrichRetry(t *testing.T) {
+	newTestRouter(t)
+	now := testNoon
+	setTestClock(t, now)
+
+	const title, tmdbID = "テスト検索不能", 990002
+	fetched := time.Now()
+	detail := func(name string) string {
+		return fmt.Sprintf(`{"title":%q,"release_date":"2024-06-01","vote_average":6.8,"vote_count":40}`, name)
+	}
+	entries := map[string]string{
+		"search:ja-JP:" + title:                                            `{"results":[]}`,
+		fmt.Sprintf("movie:%d:zh-CN:credits,videos", tmdbID):               detail("检索不能"),
+		fmt.Sprintf("movie:%d:ja-JP:credits,videos,release_dates", tmdbID): detail(title),
+		fmt.Sprintf("movie:%d:en-US:credits,videos", tmdbID):               detail("Unsearchable"),
+	}
+	for key, payload := range entries {
+		apiCachePut(apiCacheProviderTMDB, key, payload, fetched)
+	}
+	movie := Movie{TitleJP: title}
+	if err := db.Create(&movie).Error; err != nil {
+		t.Fatal(err)
+	}
+	enrichMovieRatings(appConfig, &movie, false)
+	first := movie.EnrichAttempts
+	normal, err := selectMoviesToEnrich(false, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	forced, err := selectMoviesToEnrich(true, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	contains := func(list []Movie) bool {
+		for _, m := range list {
+			if m.ID == movie.ID {
+				return true
+			}
+		}
+		return false
+	}
+	// 强制重试时标题已在无结果缓存中，不再请求但仍计一次失败
+	enrichMovieRatings(appConfig, &movie, true)
+	var stored Movie
+	db.First(&stored, movie.ID)
+	listed, err := listUnenrichedMovies()
+	if err != nil {
+		t.Fatal(err)
+	}
+	wasListed := contains(listed)
+
+	_, _, badArgs := parseSetTmdbIDArgs([]string{"42"})
+	gotMovie, gotTmdb, argsErr := parseSetTmdbIDArgs([]string{fmt.Sprint(movie.ID), fmt.Sprint(tmdbID)})
+	manual, err := setManualTmdbID(gotMovie, gotTmdb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	enrichMovieRatings(appConfig, &manual, true)
+	var after Movie
+	db.First(&after, movie.ID)
+	listed, err = listUnenrichedMovies()
+	if err != nil {
+		t.Fatal(err)
+	}
+	last := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	backedOff := Movie{EnrichAttempts: 3, LastEnrichAt: &last}
+	if err := firstError(
+		expectEqual("first failure", [2]int{first, int(stored.EnrichAttempts)}, [2]int{1, 2}),
+		expectEqual("last error", stored.LastEnrichError, enrichErrKnownNoResults),
+		expectEqual("skipped while backing off", contains(normal), false),
+		expectEqual("force ignores backoff", contains(forced), true),
+		expectEqual("listed", wasListed, true),
+		expectEqual("args", [3]bool{badArgs != nil, argsErr == nil, gotTmdb == tmdbID}, [3]bool{true, true, true}),
+		expectEqual("enriched by manual id", [2]string{after.TitleCN, after.TitleEN}, [2]string{"检索不能", "Unsearchable"}),
+		expectEqual("manual provenance", [2]interface{}{after.TMDBID, parseProvenance(after.ProvenanceJSON)["tmdb_id"].Source}, [2]interface{}{tmdbID, SourceManual}),
+		expectEqual("failure cleared", [2]interface{}{after.EnrichAttempts, after.LastEnrichAt == nil}, [2]interface{}{0, true}),
+		expectEqual("unlisted", contains(listed), false),
+		expectEqual("backoff", [4]time.Duration{enrichRetryBackoff(0), enrichRetryBackoff(1), enrichRetryBackoff(3), enrichRetryBackoff(30)},
+			[4]time.Duration{0, 24 * time.Hour, 96 * time.Hour, tmdbMissTTL}),
+		expectEqual("due", [2]bool{enrichRetryDue(backedOff, last.Add(95*time.Hour)), enrichRetryDue(backedOff, last.Add(96*time.Hour))}, [2]bool{false, true})); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+// ===========================
+// 模块：特别场次识别（舞台挨拶 / 先行上映 等）
+// 职责：
+// - 从场次单元格与片名注释中识别特别场次，写入 Schedule.EventType
+// - 已知类型归一为固定关键词；无法归类的注释原样保留，留待后续人工归类
+// - 同时识别放映版本（字幕版 / 吹替版），写入 Schedule.Format
+// ===========================
+
+// 已知的特别场次类型（按匹配优先级排列）。
+const (
+	EventStageGreeting = "舞台挨拶"
+	EventPreview       = "先行上映"
+	EventTalkShow      = "トークショー"
+	EventTeachIn       = "ティーチイン"
+	EventCheering      = "応援上映"
+	EventEveScreening  = "前夜祭"
+)
+
+var knownEventTypes = []string{
+	EventStageGreeting, EventPreview, EventTalkShow, EventTeachIn, EventCheering, EventEveScreening,
+}
+
+// showtimeRangeRe 场次单元格中的时间部分，如 "18:05～20:00" / "9:30"。
+var showtimeRangeRe = regexp.MustCompile(`\d{1,2}:\d{2}(\s*[～~〜-]\s*\d{1,2}:\d{2})?`)
+
+// availabilityMarks 余票标记，由 parseAvailability 处理，不视为场次注释。
+var availabilityMarks = []string{"満席", "完売", "残りわずか", "空席あり", "×", "△", "○", "◎"}
+
+// matchKnownEvent 返回文本中包含的已知特别场次类型，没有则返回空串。
+func matchKnownEvent(text string) string {
+	for _, ev := range knownEventTypes {
+		if strings.Contains(text, ev) {
+			return ev
+		}
+	}
+	return ""
+}
+
+// extractShowtimeAnnotation 去掉时间与余票标记后，返回场次单元格中剩余的注释文本。
+func extractShowtimeAnnotation(text string) string {
+	text = showtimeRangeRe.ReplaceAllString(text, " ")
+	for _, mark := range availabilityMarks {
+		text = strings.ReplaceAll(text, mark, " ")
+	}
+	text = strings.Trim(strings.Join(strings.Fields(text), " "), "【】[]（）()・～~〜 ")
+	return text
+}
+
+// parseEventType 识别单个场次的特别场次类型：
+// - 场次单元格或片名注释中出现已知关键词 -> 对应类型
+// - 场次单元格有其他注释 -> 原样保留
+// - 否则为空串（普通场次）
+func parseEventType(showtimeText, rawTitle string) string {
+	annotation := extractShowtimeAnnotation(showtimeText)
+	if ev := matchKnownEvent(annotation); ev != "" {
+		return ev
+	}
+	for _, bracket := range annotationBracketRe.FindAllString(rawTitle, -1) {
+		if ev := matchKnownEvent(bracket); ev != "" {
+			return ev
+		}
+	}
+	return annotation
+}
+
+// 放映版本：片名或场次注释中的 字幕 / 吹替；没有标注时为空串。
+const (
+	FormatSubbed = "subbed"
+	FormatDubbed = "dubbed"
+)
+
+// parseScreeningFormat 识别放映版本（纯函数）：吹替优先（“日本語吹替・字幕付き”等按吹替计）。
+func parseScreeningFormat(texts ...string) string {
+	joined := strings.Join(texts, " ")
+	switch {
+	case strings.Contains(joined, "吹替") || strings.Contains(joined, "吹き替え"):
+		return FormatDubbed
+	case strings.Contains(joined, "字幕"):
+		return FormatSubbed
+	}
+	return ""
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// TestCrawlChangesWebhook 抓取变更事件：成功抓取后把相对上一次的变更报告发到 webhook（crawl.changes），首次抓取不发，同一次抓取只发一次。
+func TestCrawlChangesWebhook(t *testing.T) {
+	newTestRouter(t)
+	now := testNoon
+	setTestClock(t, now)
+
+	var mu sync.Mutex
+	var received []EventPayload
+	var types []string
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var p EventPayload
+		json.NewDecoder(r.Body).Decode(&p)
+		mu.Lock()
+		received = append(received, p)
+		types = append(types, r.Header.Get("X-Event-Type"))
+		mu.Unlock()
+	}))
+	defer hook.Close()
+	prevURL, hadURL := os.LookupEnv("EVENT_WEBHOOK_URL")
+	os.Setenv("EVENT_WEBHOOK_URL", hook.URL)
+	defer func() {
+		if hadURL {
+			os.Setenv("EVENT_WEBHOOK_URL", prevURL)
+		} else {
+			os.Unsetenv("EVENT_WEBHOOK_URL")
+		}
+	}()
+
+	stay := Movie{TitleJP: "テスト変更残留", Status: "showing"}
+	added := Movie{TitleJP: "テスト変更新作", Status: "showing"}
+	if err := firstError(db.Create(&stay).Error, db.Create(&added).Error); err != nil {
+		t.Fatal(err)
+	}
+	// 独立的 Kind，不与其他检查写入的抓取记录互为基准
+	newRun := func(snap CrawlSnapshot) (CrawlRun, error) {
+		b, _ := json.Marshal(snap)
+		run := CrawlRun{Kind: "test-events", Status: "success", StartedAt: now, FinishedAt: now, SnapshotJSON: string(b)}
+		return run, db.Create(&run).Error
+	}
+	first, err := newRun(CrawlSnapshot{Date: "2026-01-27", MovieIDs: []uint{stay.ID}, ActiveMovieIDs: []uint{stay.ID},
+		CinemaCounts: map[uint]int{1: 10}, Schedules: map[string][]uint{"2026-01-28": {990001, 990002}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	emitCrawlChangesEvent(first)
+	afterFirst := len(received)
+	second, err := newRun(CrawlSnapshot{Date: "2026-01-27", MovieIDs: []uint{stay.ID, added.ID}, ActiveMovieIDs: []uint{added.ID},
+		CinemaCounts: map[uint]int{1: 2}, Schedules: map[string][]uint{"2026-01-28": {990001}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	emitCrawlChangesEvent(second)
+	emitCrawlChangesEvent(second)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(received) != 1 {
+		t.Fatalf("webhook received %d events, want 1 (after first run: %d)", len(received), afterFirst)
+	}
+	got := received[0]
+	if got.Changes == nil {
+		t.Fatalf("payload has no changes")
+	}
+	ch := got.Changes
+	titles := func(ms []ChangedMovie) string {
+		parts := make([]string, 0, len(ms))
+		for _, m := range ms {
+			parts = append(parts, fmt.Sprint(m.ID))
+		}
+		return strings.Join(parts, ",")
+	}
+	if err := firstError(
+		expectEqual("first run", afterFirst, 0),
+		expectEqual("type", got.Type+" "+types[0], EventCrawlChanges+" "+EventCrawlChanges),
+		expectEqual("no movie", got.Movie == nil, true),
+		expectEqual("runs", fmt.Sprint(ch.FromRunID, "->", ch.ToRunID), fmt.Sprint(first.ID, "->", second.ID)),
+		expectEqual("new", titles(ch.NewMovies), fmt.Sprint(added.ID)),
+		expectEqual("left", titles(ch.LeftMovies), fmt.Sprint(stay.ID)),
+		expectEqual("cinema changes", fmt.Sprint(ch.CinemaChanges), "[{1 10 2}]"),
+		expectEqual("removed", ch.RemovedSchedules, 1)); err != nil {
+		t.Fatal(err)
+	}
+}
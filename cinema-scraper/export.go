@@ -0,0 +1,192 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ===========================
+// 模块：影院数据导出（KML / GPX）
+// 职责：把有坐标的影院导出为地图应用可直接导入的文件，方便离线规划“影院巡礼”路线
+// 说明：
+// - 支持 district / tag / chain 过滤，例如 chain=false 只导出小剧场
+// - XML 由 encoding/xml 生成，日文名称与地址自动转义
+// ===========================
+
+// exportCinema 导出用的影院信息。
+type exportCinema struct {
+	Item        CinemaItem
+	MoviesToday int
+}
+
+// loadExportCinemas 按查询参数过滤出需要导出的影院（仅包含已有坐标的影院）。
+func loadExportCinemas(c *gin.Context) ([]exportCinema, error) {
+	var cinemas []Cinema
+	if err := db.Where("latitude <> 0 OR longitude <> 0").Order("id").Find(&cinemas).Error; err != nil {
+		return nil, err
+	}
+
+	district := c.Query("district")
+	tag := c.Query("tag")
+	chain := c.Query("chain") // true：只导出连锁影城；false：只导出小剧场
+
+	// 今天各影院的上映影片数（一条 GROUP BY）
+	var rows []struct {
+		CinemaID   uint
+		MovieCount int
+	}
+	db.Model(&Schedule{}).
+		Select("cinema_id, COUNT(DISTINCT movie_id) AS movie_count").
+		Where("date(play_date) = ?", todayJST()).
+		Group("cinema_id").
+		Scan(&rows)
+	moviesToday := make(map[uint]int, len(rows))
+	for _, r := range rows {
+		moviesToday[r.CinemaID] = r.MovieCount
+	}
+
+	out := make([]exportCinema, 0, len(cinemas))
+	for _, cin := range cinemas {
+		item := mapCinemaToItem(cin)
+		if district != "" && !strings.Contains(item.District, district) {
+			continue
+		}
+		if tag != "" && !containsString(item.Tags, tag) {
+			continue
+		}
+		if chain == "true" && isMiniTheater(cin.NameJP) {
+			continue
+		}
+		if chain == "false" && !isMiniTheater(cin.NameJP) {
+			continue
+		}
+		out = append(out, exportCinema{Item: item, MoviesToday: moviesToday[cin.ID]})
+	}
+	return out, nil
+}
+
+// containsString 判断切片中是否包含 s。
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// exportDescription 导出文件中的影院说明文字。
+func exportDescription(ec exportCinema) string {
+	lines := []string{}
+	if ec.Item.District != "" {
+		lines = append(lines, ec.Item.District)
+	}
+	if ec.Item.Website != "" {
+		lines = append(lines, ec.Item.Website)
+	}
+	lines = append(lines, fmt.Sprintf("今日上映 %d 部", ec.MoviesToday))
+	return strings.Join(lines, "\n")
+}
+
+// kmlDocument KML 根节点。
+type kmlDocument struct {
+	XMLName  xml.Name `xml:"kml"`
+	Xmlns    string   `xml:"xmlns,attr"`
+	Document struct {
+		Name       string         `xml:"name"`
+		Placemarks []kmlPlacemark `xml:"Placemark"`
+	} `xml:"Document"`
+}
+
+type kmlPlacemark struct {
+	Name        string `xml:"name"`
+	Description string `xml:"description"`
+	Point       struct {
+		Coordinates string `xml:"coordinates"` // 经度,纬度
+	} `xml:"Point"`
+}
+
+// gpxDocument GPX 根节点。
+type gpxDocument struct {
+	XMLName   xml.Name      `xml:"gpx"`
+	Xmlns     string        `xml:"xmlns,attr"`
+	Version   string        `xml:"version,attr"`
+	Creator   string        `xml:"creator,attr"`
+	Waypoints []gpxWaypoint `xml:"wpt"`
+}
+
+type gpxWaypoint struct {
+	Lat  float64 `xml:"lat,attr"`
+	Lon  float64 `xml:"lon,attr"`
+	Name string  `xml:"name"`
+	Desc string  `xml:"desc"`
+	Link *struct {
+		Href string `xml:"href,attr"`
+	} `xml:"link,omitempty"`
+}
+
+// writeXMLDownload 输出 XML 并设置下载文件名。
+func writeXMLDownload(c *gin.Context, contentType, filename string, doc interface{}) {
+	body, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to encode export"})
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	c.Data(http.StatusOK, contentType, append([]byte(xml.Header), body...))
+}
+
+// exportCinemasKMLHandler 影院 KML 导出：GET /api/export/cinemas.kml?district=&tag=&chain=
+func exportCinemasKMLHandler(c *gin.Context) {
+	cinemas, err := loadExportCinemas(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to query cinemas"})
+		return
+	}
+
+	var doc kmlDocument
+	doc.Xmlns = "http://www.opengis.net/kml/2.2"
+	doc.Document.Name = "Tokyo CinePath"
+	for _, ec := range cinemas {
+		var pm kmlPlacemark
+		pm.Name = ec.Item.Name
+		pm.Description = exportDescription(ec)
+		pm.Point.Coordinates = fmt.Sprintf("%f,%f", ec.Item.Lng, ec.Item.Lat)
+		doc.Document.Placemarks = append(doc.Document.Placemarks, pm)
+	}
+	writeXMLDownload(c, "application/vnd.google-earth.kml+xml; charset=utf-8", "tokyo-cinemas.kml", doc)
+}
+
+// exportCinemasGPXHandler 影院 GPX 导出：GET /api/export/cinemas.gpx?district=&tag=&chain=
+func exportCinemasGPXHandler(c *gin.Context) {
+	cinemas, err := loadExportCinemas(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to query cinemas"})
+		return
+	}
+
+	doc := gpxDocument{
+		Xmlns:   "http://www.topografix.com/GPX/1/1",
+		Version: "1.1",
+		Creator: "Tokyo CinePath",
+	}
+	for _, ec := range cinemas {
+		wpt := gpxWaypoint{
+			Lat:  ec.Item.Lat,
+			Lon:  ec.Item.Lng,
+			Name: ec.Item.Name,
+			Desc: exportDescription(ec),
+		}
+		if ec.Item.Website != "" {
+			wpt.Link = &struct {
+				Href string `xml:"href,attr"`
+			}{Href: ec.Item.Website}
+		}
+		doc.Waypoints = append(doc.Waypoints, wpt)
+	}
+	writeXMLDownload(c, "application/gpx+xml; charset=utf-8", "tokyo-cinemas.gpx", doc)
+}
@@ -0,0 +1,285 @@
+package main
+
+import (
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ===========================
+// 模块：收藏与“最后机会”提醒（/api/favorites）
+// 职责：
+// - 没有账号系统：客户端生成设备令牌，放在 X-Device-Token 头里，收藏按令牌保存在 Favorite 表
+// - GET /api/favorites/ending：收藏中最后一场公开场次在今天起 N 天内（含今天，last_chance），
+//   或最近 N 天内刚失去全部未来场次（ended）的影片，客户端据此提醒“再不看就下映了”
+// - 每条收藏带 notified_at：客户端提醒后调用 POST /api/favorites/ending/ack 标记，
+//   之后轮询不再重复提醒；影片加映（最后排片日期变化）后标记失效，可再次提醒
+// 说明：最后排片日期按热表中的公开场次（不含会員限定）计算，“今天”与状态计算同样取东京日期。
+// ===========================
+
+const (
+	deviceTokenHeader      = "X-Device-Token"
+	favoritesEndingDefault = 3  // 默认提醒窗口（天）
+	favoritesEndingMaxDays = 14 // 不超过排片清理的默认保留期，窗口内的过去场次都还在热表
+	favoriteKindLastChance = "last_chance"
+	favoriteKindEnded      = "ended"
+)
+
+// deviceTokenRe 设备令牌：16-128 位字母、数字、- 与 _。
+var deviceTokenRe = regexp.MustCompile(`^[A-Za-z0-9_-]{16,128}$`)
+
+// Favorite 某个设备收藏的一部影片。
+type Favorite struct {
+	ID          uint   `gorm:"primaryKey"`
+	DeviceToken string `gorm:"uniqueIndex:idx_favorite_device_movie"`
+	MovieID     uint   `gorm:"uniqueIndex:idx_favorite_device_movie;index"`
+	// 最近一次确认提醒的时间与当时的最后排片日期（YYYY-MM-DD）；最后排片日期变化后需要重新提醒
+	NotifiedAt       *time.Time
+	NotifiedLastDate string
+	CreatedAt        time.Time
+}
+
+// FavoriteEndingItem /api/favorites/ending 的一项。
+type FavoriteEndingItem struct {
+	Movie            MovieItem  `json:"movie"`
+	Kind             string     `json:"kind"`               // last_chance / ended
+	LastScheduleDate string     `json:"last_schedule_date"` // YYYY-MM-DD
+	DaysLeft         int        `json:"days_left"`          // 距最后一场的天数：今天为 0，ended 为负数
+	NotifiedAt       *time.Time `json:"notified_at"`        // 已就当前的最后排片日期提醒过时为确认时间，否则为 null
+}
+
+// favoriteAckRequest POST /api/favorites/ending/ack 的请求体。
+type favoriteAckRequest struct {
+	MovieIDs []uint `json:"movie_ids"`
+}
+
+// deviceToken 读取并校验设备令牌，缺失或非法时直接返回 400。
+func deviceToken(c *gin.Context) (string, bool) {
+	token := strings.TrimSpace(c.GetHeader(deviceTokenHeader))
+	if !deviceTokenRe.MatchString(token) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "missing or invalid X-Device-Token"})
+		return "", false
+	}
+	return token, true
+}
+
+// favoriteEndingKind 按最后排片日期判断是否需要提醒（纯函数）：返回 kind 与距今天数，不需要提醒时 kind 为空。
+func favoriteEndingKind(lastDate, today string, days int) (string, int) {
+	last, err1 := time.Parse("2006-01-02", lastDate)
+	now, err2 := time.Parse("2006-01-02", today)
+	if err1 != nil || err2 != nil {
+		return "", 0
+	}
+	left := int(last.Sub(now).Hours() / 24)
+	switch {
+	case left >= 0 && left < days:
+		return favoriteKindLastChance, left
+	case left < 0 && -left <= days:
+		return favoriteKindEnded, left
+	}
+	return "", left
+}
+
+// lastPublicScheduleDates 各影片最后一场公开场次的日期（YYYY-MM-DD）；没有场次的影片不在结果中。
+func lastPublicScheduleDates(movieIDs []uint) (map[uint]string, error) {
+	out := make(map[uint]string, len(movieIDs))
+	if len(movieIDs) == 0 {
+		return out, nil
+	}
+	var rows []struct {
+		MovieID  uint
+		LastDate string
+	}
+	if err := db.Model(&Schedule{}).
+		Select("movie_id, MAX(date(play_date)) AS last_date").
+		Where("movie_id IN ? AND members_only = ?", movieIDs, false).
+		Group("movie_id").Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	for _, r := range rows {
+		out[r.MovieID] = r.LastDate
+	}
+	return out, nil
+}
+
+// listFavoritesHandler 本设备的收藏：GET /api/favorites
+func listFavoritesHandler(c *gin.Context) {
+	token, ok := deviceToken(c)
+	if !ok {
+		return
+	}
+	ids := make([]uint, 0)
+	if err := db.Model(&Favorite{}).Where("device_token = ?", token).
+		Where("movie_id IN (?)", db.Model(&Movie{}).Select("id")).
+		Order("movie_id").Pluck("movie_id", &ids).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to query favorites"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"movie_ids": ids})
+}
+
+// addFavoriteHandler 收藏影片：PUT /api/favorites/:movie_id（重复收藏不报错）
+func addFavoriteHandler(c *gin.Context) {
+	token, ok := deviceToken(c)
+	if !ok {
+		return
+	}
+	var movie Movie
+	if err := db.First(&movie, c.Param("movie_id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "movie not found"})
+		return
+	}
+	fav := Favorite{DeviceToken: token, MovieID: movie.ID}
+	if err := db.Where("device_token = ? AND movie_id = ?", token, movie.ID).FirstOrCreate(&fav).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save favorite"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"movie_id": movie.ID, "favorited": true})
+}
+
+// removeFavoriteHandler 取消收藏：DELETE /api/favorites/:movie_id
+func removeFavoriteHandler(c *gin.Context) {
+	token, ok := deviceToken(c)
+	if !ok {
+		return
+	}
+	movieID, err := strconv.ParseUint(c.Param("movie_id"), 10, 64)
+	if err != nil || movieID == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid movie_id, expected a positive integer"})
+		return
+	}
+	if err := db.Where("device_token = ? AND movie_id = ?", token, movieID).Delete(&Favorite{}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to remove favorite"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"movie_id": movieID, "favorited": false})
+}
+
+// favoritesEndingHandler 即将下映 / 刚下映的收藏：GET /api/favorites/ending?days=3
+// 按最后排片日期升序（ended 在前），同一天按影片 ID。
+func favoritesEndingHandler(c *gin.Context) {
+	token, ok := deviceToken(c)
+	if !ok {
+		return
+	}
+	days := favoritesEndingDefault
+	if raw := c.Query("days"); raw != "" {
+		v, err := strconv.Atoi(raw)
+		if err != nil || v <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid days, expected a positive integer"})
+			return
+		}
+		days = min(v, favoritesEndingMaxDays)
+	}
+	var favs []Favorite
+	if err := db.Where("device_token = ?", token).Find(&favs).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to query favorites"})
+		return
+	}
+	byMovie := make(map[uint]Favorite, len(favs))
+	ids := make([]uint, 0, len(favs))
+	for _, f := range favs {
+		byMovie[f.MovieID] = f
+		ids = append(ids, f.MovieID)
+	}
+	lastDates, err := lastPublicScheduleDates(ids)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to query schedules"})
+		return
+	}
+
+	today := referenceTime(c).Format("2006-01-02")
+	type pending struct {
+		kind, last string
+		left       int
+	}
+	hits := make(map[uint]pending)
+	hitIDs := make([]uint, 0)
+	for id, last := range lastDates {
+		if kind, left := favoriteEndingKind(last, today, days); kind != "" {
+			hits[id] = pending{kind, last, left}
+			hitIDs = append(hitIDs, id)
+		}
+	}
+	var movies []Movie
+	if len(hitIDs) > 0 {
+		if err := db.Where("id IN ?", hitIDs).Find(&movies).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to query movies"})
+			return
+		}
+	}
+	lang := requestLang(c)
+	items := make([]FavoriteEndingItem, 0, len(movies))
+	for _, m := range movies {
+		h, fav := hits[m.ID], byMovie[m.ID]
+		item := FavoriteEndingItem{
+			Movie:            mapMovieToItem(m, lang),
+			Kind:             h.kind,
+			LastScheduleDate: h.last,
+			DaysLeft:         h.left,
+		}
+		if fav.NotifiedAt != nil && fav.NotifiedLastDate == h.last {
+			item.NotifiedAt = fav.NotifiedAt
+		}
+		items = append(items, item)
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].LastScheduleDate != items[j].LastScheduleDate {
+			return items[i].LastScheduleDate < items[j].LastScheduleDate
+		}
+		return items[i].Movie.ID < items[j].Movie.ID
+	})
+	c.JSON(http.StatusOK, gin.H{"date": today, "days": days, "items": items})
+}
+
+// ackFavoritesEndingHandler 客户端已提醒：POST /api/favorites/ending/ack {"movie_ids":[1,2]}
+// 记下确认时间与当前的最后排片日期；不在收藏中的影片忽略。
+func ackFavoritesEndingHandler(c *gin.Context) {
+	token, ok := deviceToken(c)
+	if !ok {
+		return
+	}
+	var req favoriteAckRequest
+	if err := c.ShouldBindJSON(&req); err != nil || len(req.MovieIDs) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "movie_ids is required"})
+		return
+	}
+	lastDates, err := lastPublicScheduleDates(req.MovieIDs)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to query schedules"})
+		return
+	}
+	now := time.Now()
+	acked := 0
+	err = db.Transaction(func(tx *gorm.DB) error {
+		for _, id := range req.MovieIDs {
+			res := tx.Model(&Favorite{}).Where("device_token = ? AND movie_id = ?", token, id).
+				Updates(map[string]interface{}{"notified_at": now, "notified_last_date": lastDates[id]})
+			if res.Error != nil {
+				return res.Error
+			}
+			acked += int(res.RowsAffected)
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update favorites"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"acknowledged": acked, "notified_at": now})
+}
+
+// mergeFavoritesTx 影片合并时把 drop 的收藏并入 keep：同一设备两边都收藏时只保留 keep 的那条。
+func mergeFavoritesTx(tx *gorm.DB, dropID, keepID uint) error {
+	if err := tx.Where(`movie_id = ? AND device_token IN (SELECT device_token FROM favorites WHERE movie_id = ?)`, dropID, keepID).
+		Delete(&Favorite{}).Error; err != nil {
+		return err
+	}
+	return tx.Model(&Favorite{}).Where("movie_id = ?", dropID).Update("movie_id", keepID).Error
+}
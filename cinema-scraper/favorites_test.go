@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestFavoritesEnding 收藏提醒：最后一场在 N 天内或刚下映的收藏，确认后不再重复提醒，加映后重新提醒。
+func TestFavoritesEnding(t *testing.T) {
+	newTestRouter(t)
+	now := testNoon
+	setTestClock(t, now)
+
+	const token = "test-device-0001"
+	day := func(d int) time.Time { return time.Date(2026, 1, d, 0, 0, 0, 0, time.UTC) }
+	lastChance := Movie{TitleJP: "テスト最終上映", Status: "showing"}
+	ended := Movie{TitleJP: "テスト終映", Status: "unplanned"}
+	running := Movie{TitleJP: "テスト続映", Status: "showing"}
+	for _, m := range []*Movie{&lastChance, &ended, &running} {
+		if err := db.Create(m).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	shows := []Schedule{
+		{MovieID: lastChance.ID, CinemaID: 5, PlayDate: day(26), StartTime: "10:00"},
+		{MovieID: lastChance.ID, CinemaID: 5, PlayDate: day(28), StartTime: "10:00"},
+		{MovieID: ended.ID, CinemaID: 5, PlayDate: day(25), StartTime: "12:00"},
+		{MovieID: running.ID, CinemaID: 5, PlayDate: time.Date(2026, 2, 20, 0, 0, 0, 0, time.UTC), StartTime: "14:00"},
+		// 会員限定场次不延长放映
+		{MovieID: ended.ID, CinemaID: 5, PlayDate: day(29), StartTime: "12:00", MembersOnly: true},
+	}
+	if err := db.Create(&shows).Error; err != nil {
+		t.Fatal(err)
+	}
+	call := func(handler gin.HandlerFunc, method, path, movieID, body, tok string) testResponse {
+		rec := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(rec)
+		c.Request = httptest.NewRequest(method, path, strings.NewReader(body))
+		c.Request.Header.Set("Content-Type", "application/json")
+		c.Request.Header.Set(deviceTokenHeader, tok)
+		c.Params = gin.Params{{Key: "movie_id", Value: movieID}}
+		handler(c)
+		return testResponse{Status: rec.Code, Body: rec.Body.Bytes()}
+	}
+	for _, m := range []Movie{lastChance, ended, running} {
+		if r := call(addFavoriteHandler, http.MethodPut, "/api/favorites/x", fmt.Sprint(m.ID), "", token); r.Status != http.StatusOK {
+			t.Fatalf("favorite %d: status %d", m.ID, r.Status)
+		}
+	}
+	type endingBody struct {
+		Items []FavoriteEndingItem `json:"items"`
+	}
+	summary := func() string {
+		var body endingBody
+		if err := expectJSON(call(favoritesEndingHandler, http.MethodGet, "/api/favorites/ending?days=3", "", "", token), &body); err != nil {
+			return err.Error()
+		}
+		parts := make([]string, 0, len(body.Items))
+		for _, it := range body.Items {
+			parts = append(parts, fmt.Sprintf("%d:%s:%d:%v", it.Movie.ID, it.Kind, it.DaysLeft, it.NotifiedAt != nil))
+		}
+		return strings.Join(parts, ",")
+	}
+	before := summary()
+	ack := call(ackFavoritesEndingHandler, http.MethodPost, "/api/favorites/ending/ack", "",
+		fmt.Sprintf(`{"movie_ids":[%d]}`, lastChance.ID), token)
+	afterAck := summary()
+	extra := Schedule{MovieID: lastChance.ID, CinemaID: 5, PlayDate: day(29), StartTime: "10:00"}
+	if err := db.Create(&extra).Error; err != nil {
+		t.Fatal(err)
+	}
+	afterExtension := summary()
+	other := call(favoritesEndingHandler, http.MethodGet, "/api/favorites/ending", "", "", "test-device-0002")
+	bad := call(favoritesEndingHandler, http.MethodGet, "/api/favorites/ending", "", "", "short")
+	if err := firstError(
+		expectEqual("ending", before, fmt.Sprintf("%d:ended:-2:false,%d:last_chance:1:false", ended.ID, lastChance.ID)),
+		expectStatus(ack, http.StatusOK),
+		expectEqual("after ack", afterAck, fmt.Sprintf("%d:ended:-2:false,%d:last_chance:1:true", ended.ID, lastChance.ID)),
+		expectEqual("after extension", afterExtension, fmt.Sprintf("%d:ended:-2:false,%d:last_chance:2:false", ended.ID, lastChance.ID)),
+		expectEqual("other device", string(other.Body), `{"date":"2026-01-27","days":3,"items":[]}`),
+		expectStatus(bad, http.StatusBadRequest)); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ===========================
+// 模块：样例数据（fixtures）
+// 职责：为测试构造一套可预期的数据：5 家影院、20 部影片、300 个场次
+// - 场次覆盖过去 3 天 / 今天 / 未来 6 天，每家影院每天 6 场，最后一场为深夜场（25:10）
+// - 影片 1-14 为 showing 且每天都有排片；15-18 为 incoming，只在 3 天后开始排片；
+//   19-20 仍标记为 showing，过去几天在多家影院有排片；其中 19 在第一家影院续映（今天起每天 10:00 一场），
+//   20 已全部下映
+// 说明：日期都相对传入的 today 生成，任何一天运行结果都一致；与 --seed 的开发用种子数据（seeddata.go）互不相干。
+// ===========================
+
+const (
+	fixtureCinemaCount = 5
+	fixtureMovieCount  = 20
+	fixturePastDays    = 3 // 今天之前的排片天数
+	fixtureFutureDays  = 6 // 今天之后的排片天数
+	fixtureIncomingDay = 3 // incoming 影片从第几天开始排片
+
+	fixtureHoldoverMovie = 19 // 只在第一家影院续映的影片
+)
+
+// fixtureShowTimes 每家影院每天的场次，最后一场是跨午夜的深夜场。
+var fixtureShowTimes = []string{"10:00", "12:30", "15:00", "17:30", "20:45", "25:10"}
+
+// fixtureCinemas 样例影院：两家在新宿区，一家在区部以外（District 为空）；
+// 早稲田标记为定位失败（无坐标），附近影院查询应排除它；只有前两家填写了银幕数，其余按场次重叠估算。
+func fixtureCinemas() []Cinema {
+	return []Cinema{
+		{NameJP: "新宿テストシネマ", NameKana: "しんじゅくてすとしねま", Address: "東京都新宿区新宿3-1-1", Latitude: 35.6909, Longitude: 139.7036, ScreenCount: 3, Tags: "シネコン"},
+		{NameJP: "渋谷テスト座", NameKana: "しぶやてすとざ", Address: "東京都渋谷区道玄坂2-2-2", Latitude: 35.6586, Longitude: 139.6982, ScreenCount: 1, Tags: "ミニシアター"},
+		{NameJP: "神保町テストホール", NameKana: "じんぼうちょうてすとほーる", Address: "東京都千代田区神田神保町1-3", Latitude: 35.6960, Longitude: 139.7577, Tags: "名画座,2本立"},
+		{NameJP: "早稲田テスト劇場", NameKana: "わせだてすとげきじょう", Address: "東京都新宿区高田馬場1-4-4", GeoStatus: GeoStatusFailed, GeocodeFailed: true, Tags: "名画座"},
+		{NameJP: "吉祥寺テストシアター", NameKana: "きちじょうじてすとしあたー", Address: "東京都武蔵野市吉祥寺本町1-5-5", Latitude: 35.7033, Longitude: 139.5797},
+	}
+}
+
+// fixtureMovies 样例影片：评分打散，避免按 ID 与按评分排序结果相同。
+func fixtureMovies(today time.Time) []Movie {
+	movies := make([]Movie, 0, fixtureMovieCount)
+	for i := 1; i <= fixtureMovieCount; i++ {
+		status, release := "showing", today.AddDate(0, 0, -30+i)
+		if i >= 15 && i <= 18 {
+			status, release = "incoming", today.AddDate(0, 0, fixtureIncomingDay)
+		}
+		movies = append(movies, Movie{
+			TMDBID:       1000 + i,
+			IMDBID:       fmt.Sprintf("tt90000%02d", i),
+			TitleCN:      fmt.Sprintf("测试影片%02d", i),
+			TitleEN:      fmt.Sprintf("Fixture Movie %02d", i),
+			TitleJP:      fmt.Sprintf("テスト映画%02d", i),
+			Director:     "Fixture Director",
+			Year:         "2026",
+			Runtime:      90 + i,
+			IMDBRating:   5 + float64(i*7%20)/5,
+			DoubanRating: 6 + float64(i*3%10)/4,
+			TMDBRating:   5.5 + float64(i%7)/2,
+			Status:       status,
+			ReleaseDate:  release,
+		})
+	}
+	return movies
+}
+
+// fixtureMoviePool 某天（相对今天的偏移）参与排片的影片序号（1 起）。
+func fixtureMoviePool(offset int) []int {
+	var pool []int
+	for i := 1; i <= 14; i++ {
+		pool = append(pool, i)
+	}
+	switch {
+	case offset < 0:
+		pool = append(pool, 19, 20)
+	case offset >= fixtureIncomingDay:
+		pool = append(pool, 15, 16, 17, 18)
+	}
+	return pool
+}
+
+// loadFixtureData 在空库 conn 中写入样例数据，today 为 JST 的今天（只取日期）。
+func loadFixtureData(conn *gorm.DB, today time.Time) error {
+	day := time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, time.UTC)
+
+	cinemas := fixtureCinemas()
+	if err := conn.Create(&cinemas).Error; err != nil {
+		return fmt.Errorf("create fixture cinemas: %w", err)
+	}
+	movies := fixtureMovies(day)
+	if err := conn.Create(&movies).Error; err != nil {
+		return fmt.Errorf("create fixture movies: %w", err)
+	}
+
+	var schedules []Schedule
+	for offset := -fixturePastDays; offset <= fixtureFutureDays; offset++ {
+		pool := fixtureMoviePool(offset)
+		for ci, cin := range cinemas {
+			for si, start := range fixtureShowTimes {
+				n := pool[((offset+fixturePastDays)*7+ci*len(fixtureShowTimes)+si)%len(pool)]
+				if offset >= 0 && ci == 0 && si == 0 {
+					n = fixtureHoldoverMovie
+				}
+				schedules = append(schedules, Schedule{
+					MovieID:      movies[n-1].ID,
+					CinemaID:     cin.ID,
+					PlayDate:     day.AddDate(0, 0, offset),
+					StartTime:    start,
+					Availability: AvailabilityAvailable,
+				})
+			}
+		}
+	}
+	if err := conn.CreateInBatches(&schedules, 100).Error; err != nil {
+		return fmt.Errorf("create fixture schedules: %w", err)
+	}
+
+	// 策展标签：在映的 1、2 与只剩过去排片的 20 属于同一特集；即将上映的 15 单独一个标签
+	tags := []MovieTag{
+		{MovieID: movies[0].ID, Tag: "小津安二郎特集"},
+		{MovieID: movies[1].ID, Tag: "小津安二郎特集"},
+		{MovieID: movies[19].ID, Tag: "小津安二郎特集"},
+		{MovieID: movies[14].ID, Tag: "映画祭"},
+	}
+	if err := conn.Create(&tags).Error; err != nil {
+		return fmt.Errorf("create fixture movie tags: %w", err)
+	}
+
+	// 东京列表页上发现的影院链接：两家东京影院、两家神奈川影院、一个无法解析地区的特殊会场
+	now := time.Now()
+	venues := []DiscoveredVenue{
+		newDiscoveredVenue("https://eiga.com/theater/13/130201/3015/", "早稲田テスト劇場", []string{defaultCrawlArea}, now),
+		newDiscoveredVenue("https://eiga.com/theater/13/130301/3016/", "テスト名画座", []string{defaultCrawlArea}, now),
+		newDiscoveredVenue("https://eiga.com/theater/14/140101/3101/", "横浜テストシネマ", []string{defaultCrawlArea}, now),
+		newDiscoveredVenue("https://eiga.com/theater/14/140201/3102/", "川崎テストシネマ", []string{defaultCrawlArea}, now),
+		newDiscoveredVenue("https://eiga.com/special/venue/", "  特設会場  ", []string{defaultCrawlArea}, now),
+	}
+	if err := conn.Create(&venues).Error; err != nil {
+		return fmt.Errorf("create fixture discovered venues: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gocolly/colly/v2"
+	"golang.org/x/text/width"
+)
+
+// ===========================
+// 模块：场次脚注（※）
+// 职责：
+// - eiga.com 排片单元格里的 ※ / ※1 标记，在排片表下方给出说明（如「この回は英語字幕付き」「トークイベント有り」）
+// - 按影片区块解析脚注说明，把标记解析为说明文本写入 Schedule.Note
+// 说明：各影院的写法并不统一——编号有全角 / 半角，单元格写 ※ 而说明写 ※1（或反之），
+//       标记可能在场次 span 之外；只有一条说明时，任何标记都指向它。
+// ===========================
+
+const maxFootnoteRunes = 100 // 单条说明的最大长度（说明与后续文字连在一起时截断）
+
+// footnoteMarkRe 脚注标记：※ 后可跟编号（半角或全角数字）。
+var footnoteMarkRe = regexp.MustCompile(`※\s*([0-9０-９]*)`)
+
+// footnoteKey 标记编号归一为半角，不带编号为空串。
+func footnoteKey(num string) string {
+	return width.Fold.String(num)
+}
+
+// parseFootnoteDefinitions 解析排片表之外的文字中的脚注说明（纯函数）：编号 -> 说明，不带编号的 ※ 键为空串。
+// 一行内有多个标记时各自截到下一个标记为止；同一编号以第一次出现的说明为准。
+func parseFootnoteDefinitions(text string) map[string]string {
+	defs := make(map[string]string)
+	for _, line := range strings.Split(text, "\n") {
+		locs := footnoteMarkRe.FindAllStringSubmatchIndex(line, -1)
+		for i, loc := range locs {
+			end := len(line)
+			if i+1 < len(locs) {
+				end = locs[i+1][0]
+			}
+			body := strings.Join(strings.Fields(line[loc[1]:end]), " ")
+			body = strings.Trim(body, " :：・-－")
+			if body == "" {
+				continue
+			}
+			if utf8.RuneCountInString(body) > maxFootnoteRunes {
+				body = string([]rune(body)[:maxFootnoteRunes]) + "…"
+			}
+			key := footnoteKey(line[loc[2]:loc[3]])
+			if _, ok := defs[key]; !ok {
+				defs[key] = body
+			}
+		}
+	}
+	return defs
+}
+
+// footnoteMarks 单元格文字中的脚注编号（按出现顺序去重，不带编号为空串）。
+func footnoteMarks(cellText string) []string {
+	var keys []string
+	seen := make(map[string]bool)
+	for _, m := range footnoteMarkRe.FindAllStringSubmatch(cellText, -1) {
+		key := footnoteKey(m[1])
+		if !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// resolveFootnoteNote 把单元格中的标记解析为说明文本（纯函数），多条用 " / " 连接；没有标记或无法对应时为空串。
+// 编号对不上时，只要区块内只有一条说明就用它（※ 对 ※1、※1 对 ※ 的写法差异）。
+func resolveFootnoteNote(cellText string, defs map[string]string) string {
+	var notes []string
+	for _, key := range footnoteMarks(cellText) {
+		note, ok := defs[key]
+		if !ok && len(defs) == 1 {
+			for _, only := range defs {
+				note, ok = only, true
+			}
+		}
+		if ok && !containsString(notes, note) {
+			notes = append(notes, note)
+		}
+	}
+	return strings.Join(notes, " / ")
+}
+
+// stripFootnoteMarks 去掉场次文字中的脚注标记，避免混入开始时间与场次注释。
+func stripFootnoteMarks(text string) string {
+	return strings.TrimSpace(footnoteMarkRe.ReplaceAllString(text, ""))
+}
+
+// sectionFootnoteText 影片区块中排片表之外的文字（脚注说明所在）。
+func sectionFootnoteText(sec *colly.HTMLElement) string {
+	outside := sec.DOM.Clone()
+	outside.Find("table.weekly-schedule").Remove()
+	return outside.Text()
+}
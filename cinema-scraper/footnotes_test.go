@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gocolly/colly/v2"
+)
+
+// TestScheduleFootnotes 场次脚注：排片页夹具中的 ※ 标记按区块对应到说明（全半角编号、标记在 span 外、编号对不上、没有说明）。
+func TestScheduleFootnotes(t *testing.T) {
+	newTestRouter(t)
+	now := testNoon
+	setTestClock(t, now)
+
+	cinema := Cinema{NameJP: "テスト脚注座"}
+	if err := db.Create(&cinema).Error; err != nil {
+		t.Fatal(err)
+	}
+	section := func(id int, cells, notes string) string {
+		return fmt.Sprintf(`<section id="m%d"><h2><a href="/movie/%d/">脚注テスト映画%d</a></h2>`+
+			`<table class="weekly-schedule"><tr>%s</tr></table>%s</section>`, id, id, id, cells, notes)
+	}
+	page := `<html><body><main><h1 class="page-title">` + cinema.NameJP + `</h1>` +
+		// 编号写法全半角混用，说明写在同一行
+		section(990301, `<td data-date="20260312"><span>10:00※1</span><span>13:00※２</span><span>16:00</span><span>18:00※1※2</span></td>`,
+			`<p>※1 この回は英語字幕付き ※2 トークイベント有り</p>`) +
+		// 单元格只有一个场次、标记在 span 之外；单元格写 ※ 而说明写 ※1
+		section(990302, `<td data-date="20260312"><span>11:00</span>※</td>`, `<div class="note">※1：上映後に監督トークあり</div>`) +
+		// 有标记但区块内没有说明
+		section(990303, `<td data-date="20260312"><span>12:00※</span></td>`, ``) +
+		`</main></body></html>`
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		io.WriteString(w, page)
+	}))
+	defer srv.Close()
+	defer resetCrawlCounters()
+	c := colly.NewCollector()
+	c.OnHTML("main", func(e *colly.HTMLElement) { handleEigaSchedulePage(e, map[uint]int{}) })
+	if err := c.Visit(srv.URL + "/theater/notes/"); err != nil {
+		t.Fatal(err)
+	}
+	var schedules []Schedule
+	db.Where("cinema_id = ? AND play_date = ?", cinema.ID, time.Date(2026, 3, 12, 0, 0, 0, 0, time.UTC)).Order("start_time").Find(&schedules)
+	parts := make([]string, 0, len(schedules))
+	for _, s := range schedules {
+		parts = append(parts, s.StartTime+"="+s.Note)
+	}
+	if err := expectEqual("notes", strings.Join(parts, ","),
+		"10:00=この回は英語字幕付き,11:00=上映後に監督トークあり,12:00=,13:00=トークイベント有り,16:00=,18:00=この回は英語字幕付き / トークイベント有り"); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ===========================
+// 模块：排片数据新鲜度
+// 职责：
+// - 所有带排片的接口返回 schedules_as_of（最近一次成功的 schedules 抓取完成时间）与 crawl_run_id
+// - 前端据此显示“时间表 2 小时前更新”，问题反馈也能精确引用某一批数据
+// - 没有成功抓取时两个字段为 null，/api/health 标记为 degraded
+// 说明：抓取在独立进程中运行，这里按 freshnessCacheTTL 缓存查询结果，避免每个请求都查 CrawlRun 表。
+// ===========================
+
+const (
+	freshnessCacheTTL = time.Minute
+	freshnessStaleAge = 48 * time.Hour // 超过该时长未成功抓取视为 stale
+)
+
+// ScheduleFreshness 排片数据版本信息，可嵌入响应结构体。
+type ScheduleFreshness struct {
+	SchedulesAsOf *time.Time `json:"schedules_as_of"`
+	CrawlRunID    *uint      `json:"crawl_run_id"`
+}
+
+// freshnessCache 进程内缓存（多个请求并发读取，需加锁）。
+var freshnessCache struct {
+	sync.Mutex
+	value    ScheduleFreshness
+	loadedAt time.Time
+}
+
+// loadScheduleFreshness 查询最近一次成功的 schedules 抓取。
+func loadScheduleFreshness() ScheduleFreshness {
+	var run CrawlRun
+	if err := db.Where("kind = ? AND status = ?", "schedules", "success").
+		Order("finished_at DESC").First(&run).Error; err != nil {
+		return ScheduleFreshness{}
+	}
+	finished := run.FinishedAt.In(tokyoLocation)
+	id := run.ID
+	return ScheduleFreshness{SchedulesAsOf: &finished, CrawlRunID: &id}
+}
+
+// scheduleFreshness 返回缓存的新鲜度信息，过期时重新查询。
+func scheduleFreshness() ScheduleFreshness {
+	freshnessCache.Lock()
+	defer freshnessCache.Unlock()
+	if freshnessCache.loadedAt.IsZero() || time.Since(freshnessCache.loadedAt) > freshnessCacheTTL {
+		freshnessCache.value = loadScheduleFreshness()
+		freshnessCache.loadedAt = time.Now()
+	}
+	return freshnessCache.value
+}
+
+// invalidateScheduleFreshness 清空缓存，下一个请求重新查询（API 进程内的定时抓取结束后调用，见 crawlscheduler.go）。
+func invalidateScheduleFreshness() {
+	freshnessCache.Lock()
+	freshnessCache.loadedAt = time.Time{}
+	freshnessCache.Unlock()
+}
+
+// withFreshness 为 gin.H 响应补上 schedules_as_of / crawl_run_id。
+func withFreshness(h gin.H) gin.H {
+	f := scheduleFreshness()
+	h["schedules_as_of"] = f.SchedulesAsOf
+	h["crawl_run_id"] = f.CrawlRunID
+	return h
+}
+
+// freshnessState 供 /api/health 使用：none（从未成功抓取）/ stale / fresh。
+func freshnessState(f ScheduleFreshness, now time.Time) string {
+	if f.SchedulesAsOf == nil {
+		return "none"
+	}
+	if now.Sub(*f.SchedulesAsOf) > freshnessStaleAge {
+		return "stale"
+	}
+	return "fresh"
+}
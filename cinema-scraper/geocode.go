@@ -0,0 +1,275 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ===========================
+// 模块：Nominatim（OSM）地理编码礼仪
+// 职责：遵守 OSM 使用政策
+// - User-Agent 必须带有效联系邮箱：从 OSM_CONTACT_EMAIL（或 --osm-email=）读取，缺失时拒绝定位
+// - 全局 1 次/秒：所有请求都经过同一个 osmLimiter，与调用方无关
+// - 结果缓存到 GeocodeCache 表（按来源 + 查询串，查询串即清洗后的地址），按响应的 Cache-Control / Expires 决定有效期并原样记录；
+//   地址没变的影院重新抓取时直接命中缓存，不再请求外部接口。crawl-cinemas --refresh-geo 跳过缓存读取（仍写回）
+// - Nominatim 查不到的地址再用国土地理院（GSI）地址检索兜底，见 gsi.go；两者共用缓存表
+// - 定位失败时不再生成随机兜底坐标：坐标保持 0/0 并标记 GeocodeFailed，由 fix-geocode 重试（见 geofix.go）
+// ===========================
+
+// 地理编码来源：GeocodeCache.Provider 与 Cinema.GeoProvider 的取值。
+const (
+	geocodeProviderNominatim = "nominatim"
+	geocodeProviderGSI       = "gsi" // 国土地理院地址检索，见 gsi.go
+)
+
+// geocodeCacheIndex 缓存表的唯一索引（来源 + 查询串）；旧版本只按查询串唯一，见 migrateGeocodeCacheIndex。
+const geocodeCacheIndex = "idx_geocode_provider_query"
+
+const (
+	osmMinInterval     = time.Second         // Nominatim 要求每秒不超过 1 次请求
+	osmCacheDefaultTTL = 30 * 24 * time.Hour // 响应没有缓存头时的默认有效期
+)
+
+// errOSMContactMissing 未配置联系邮箱时拒绝请求 Nominatim。
+var errOSMContactMissing = errors.New("OSM contact email is not configured (set OSM_CONTACT_EMAIL or --osm-email=)")
+
+// GeocodeCache 地理编码缓存：同一来源的同一查询串在有效期内不再请求。
+type GeocodeCache struct {
+	ID           uint   `gorm:"primaryKey"`
+	Provider     string `gorm:"uniqueIndex:idx_geocode_provider_query"` // nominatim / gsi
+	Query        string `gorm:"uniqueIndex:idx_geocode_provider_query"`
+	Found        bool   // 未命中也缓存，避免反复查询同一个无结果的地址
+	Latitude     float64
+	Longitude    float64
+	CacheControl string    // 响应的 Cache-Control 原文
+	Expires      string    // 响应的 Expires 原文
+	FetchedAt    time.Time // 请求时间
+	ExpiresAt    time.Time `gorm:"index"` // 计算出的过期时间
+}
+
+// rateLimiter 串行化的最小间隔限速器：Wait 持锁睡眠，任意并发调用之间的间隔都不小于 interval。
+type rateLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	last     time.Time
+}
+
+func newRateLimiter(interval time.Duration) *rateLimiter {
+	return &rateLimiter{interval: interval}
+}
+
+// Wait 阻塞到距离上一次放行至少 interval 后再放行。
+func (l *rateLimiter) Wait() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.last.IsZero() {
+		if d := l.interval - time.Since(l.last); d > 0 {
+			time.Sleep(d)
+		}
+	}
+	l.last = time.Now()
+}
+
+// osmLimiter 进程内唯一的 Nominatim 限速器；requestOSM 是唯一发起请求的地方。
+var osmLimiter = newRateLimiter(osmMinInterval)
+
+// osmContact 联系邮箱配置（命令行参数优先于环境变量）。
+var osmContact struct {
+	sync.Mutex
+	email string
+}
+
+// configureOSMContact 从 --osm-email= 或 OSM_CONTACT_EMAIL 读取联系邮箱并校验。
+func configureOSMContact(args []string) error {
+	email, ok := flagValue(args, "--osm-email")
+	if !ok {
+		email = os.Getenv("OSM_CONTACT_EMAIL")
+	}
+	email = strings.TrimSpace(email)
+	if email == "" {
+		return errOSMContactMissing
+	}
+	if !validContactEmail(email) {
+		return fmt.Errorf("invalid OSM contact email %q", email)
+	}
+	osmContact.Lock()
+	osmContact.email = email
+	osmContact.Unlock()
+	return nil
+}
+
+// validContactEmail 粗略校验邮箱格式（纯函数）。
+func validContactEmail(email string) bool {
+	local, domain, ok := strings.Cut(email, "@")
+	return ok && local != "" && strings.Contains(domain, ".") && !strings.ContainsAny(email, " ()")
+}
+
+// osmContactEmail 已配置的联系邮箱，未配置时为空串。
+func osmContactEmail() string {
+	osmContact.Lock()
+	defer osmContact.Unlock()
+	return osmContact.email
+}
+
+// cacheExpiry 按 Cache-Control / Expires 计算缓存过期时间（纯函数）。
+// 返回 false 表示响应要求不缓存（no-store / no-cache / max-age=0）。
+func cacheExpiry(cacheControl, expires string, now time.Time) (time.Time, bool) {
+	for _, directive := range strings.Split(cacheControl, ",") {
+		directive = strings.ToLower(strings.TrimSpace(directive))
+		switch {
+		case directive == "no-store" || directive == "no-cache":
+			return time.Time{}, false
+		case strings.HasPrefix(directive, "max-age="):
+			secs, err := strconv.Atoi(strings.TrimPrefix(directive, "max-age="))
+			if err != nil {
+				continue
+			}
+			if secs <= 0 {
+				return time.Time{}, false
+			}
+			return now.Add(time.Duration(secs) * time.Second), true
+		}
+	}
+	if expires != "" {
+		if t, err := http.ParseTime(expires); err == nil {
+			if !t.After(now) {
+				return time.Time{}, false
+			}
+			return t, true
+		}
+	}
+	return now.Add(osmCacheDefaultTTL), true
+}
+
+// geocodeRefresh 为 true 时跳过缓存读取、总是请求 Nominatim（crawl-cinemas --refresh-geo）。
+var geocodeRefresh atomic.Bool
+
+// 本进程内的缓存命中 / 实际请求次数，抓取结束时打印。
+var geocodeCacheHits, osmRequests, gsiRequests atomic.Int64
+
+// migrateGeocodeCacheIndex 旧库的缓存只按查询串唯一（idx_geocode_caches_query）：删除该索引，
+// 否则 GSI 无法缓存同一地址；旧行的来源补为 nominatim。在 AutoMigrate 之后调用。
+func migrateGeocodeCacheIndex(conn *gorm.DB) error {
+	m := conn.Migrator()
+	if m.HasIndex(&GeocodeCache{}, "idx_geocode_caches_query") {
+		if err := m.DropIndex(&GeocodeCache{}, "idx_geocode_caches_query"); err != nil {
+			return fmt.Errorf("删除旧的地理编码缓存索引失败: %w", err)
+		}
+	}
+	return conn.Model(&GeocodeCache{}).Where("provider = '' OR provider IS NULL").
+		UpdateColumn("provider", geocodeProviderNominatim).Error
+}
+
+// lookupGeocodeCache 查询未过期的缓存。
+func lookupGeocodeCache(provider, query string, now time.Time) (GeocodeCache, bool) {
+	var entry GeocodeCache
+	if err := db.Where("provider = ? AND query = ? AND expires_at > ?", provider, query, now).First(&entry).Error; err != nil {
+		return entry, false
+	}
+	return entry, true
+}
+
+// requestOSM 向 Nominatim 发起一次搜索（经过全局限速），返回结果与响应头。
+func requestOSM(query string) (GeocodeCache, http.Header, error) {
+	entry := GeocodeCache{Query: query}
+	email := osmContactEmail()
+	if email == "" {
+		return entry, nil, errOSMContactMissing
+	}
+	apiURL := fmt.Sprintf("https://nominatim.openstreetmap.org/search?q=%s&format=json&limit=1", url.QueryEscape(query))
+
+	osmLimiter.Wait()
+	client := &http.Client{Timeout: 10 * time.Second}
+	req, _ := http.NewRequest("GET", apiURL, nil)
+	req.Header.Set("User-Agent", fmt.Sprintf("TokyoCinePath/1.1 (%s)", email))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return entry, nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return entry, resp.Header, fmt.Errorf("nominatim status %d", resp.StatusCode)
+	}
+
+	var results []struct {
+		Lat string `json:"lat"`
+		Lon string `json:"lon"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&results); err != nil {
+		return entry, resp.Header, err
+	}
+	if len(results) > 0 {
+		entry.Latitude, _ = strconv.ParseFloat(results[0].Lat, 64)
+		entry.Longitude, _ = strconv.ParseFloat(results[0].Lon, 64)
+		entry.Found = true
+	}
+	return entry, resp.Header, nil
+}
+
+// callOSM 用 Nominatim 地理编码（经过缓存）。
+func callOSM(query string) (float64, float64, error) {
+	return cachedGeocode(geocodeProviderNominatim, query, &osmRequests, requestOSM)
+}
+
+// cachedGeocode 地理编码：先查缓存（--refresh-geo 时跳过），未命中时调用 request 并按响应缓存头写回缓存。
+// requests 为该来源的请求计数。
+func cachedGeocode(provider, query string, requests *atomic.Int64, request func(string) (GeocodeCache, http.Header, error)) (float64, float64, error) {
+	now := time.Now()
+	if !geocodeRefresh.Load() {
+		if entry, ok := lookupGeocodeCache(provider, query, now); ok {
+			geocodeCacheHits.Add(1)
+			if !entry.Found {
+				return 0, 0, fmt.Errorf("no results (cached)")
+			}
+			return entry.Latitude, entry.Longitude, nil
+		}
+	}
+
+	requests.Add(1)
+	entry, header, err := request(query)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	entry.Provider = provider
+	entry.FetchedAt = now
+	entry.CacheControl = header.Get("Cache-Control")
+	entry.Expires = header.Get("Expires")
+	if expiresAt, cacheable := cacheExpiry(entry.CacheControl, entry.Expires, now); cacheable {
+		entry.ExpiresAt = expiresAt
+		var existing GeocodeCache
+		if db.Where("provider = ? AND query = ?", provider, query).First(&existing).Error == nil {
+			entry.ID = existing.ID
+		}
+		if err := db.Save(&entry).Error; err != nil {
+			fmt.Printf("⚠️ 写入地理编码缓存失败 [%s]: %v\n", query, err)
+		}
+	}
+
+	if !entry.Found {
+		return 0, 0, fmt.Errorf("no results")
+	}
+	return entry.Latitude, entry.Longitude, nil
+}
+
+// printGeocodeSummary 打印本次运行的地理编码缓存命中情况。
+func printGeocodeSummary() {
+	mode := "读取缓存"
+	if geocodeRefresh.Load() {
+		mode = "--refresh-geo，跳过缓存"
+	}
+	fmt.Printf("📊 地理编码：缓存命中 %d 次，请求 Nominatim %d 次、GSI %d 次（%s）\n",
+		geocodeCacheHits.Load(), osmRequests.Load(), gsiRequests.Load(), mode)
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestRateLimiterConcurrent Nominatim 限速：并发调用共用一个限速器，任意两次放行间隔都不小于最小间隔。
+func TestRateLimiterConcurrent(t *testing.T) {
+	setTestClock(t, beforeMidnight)
+
+	const interval = 40 * time.Millisecond
+	const callers = 6
+	limiter := newRateLimiter(interval)
+	var mu sync.Mutex
+	var released []time.Time
+	var wg sync.WaitGroup
+	start := time.Now()
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			limiter.Wait()
+			mu.Lock()
+			released = append(released, time.Now())
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+	slices.SortFunc(released, func(a, b time.Time) int { return a.Compare(b) })
+	// 放行后再记录时刻会有少量调度抖动，间隔按 interval 减去容差判断
+	shortest := time.Duration(1<<63 - 1)
+	for i := 1; i < len(released); i++ {
+		shortest = min(shortest, released[i].Sub(released[i-1]))
+	}
+	if shortest < interval-10*time.Millisecond {
+		t.Fatalf("shortest gap = %v, want >= %v", shortest, interval)
+	}
+	if elapsed < (callers-1)*interval {
+		t.Fatalf("elapsed = %v, want >= %v", elapsed, (callers-1)*interval)
+	}
+	if err := firstError(
+		expectEqual("released", len(released), callers),
+		expectEqual("shared osm limiter", osmLimiter.interval, osmMinInterval)); err != nil {
+		t.Fatal(err)
+	}
+}
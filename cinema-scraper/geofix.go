@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ===========================
+// 模块：定位失败的影院（geocode_failed）
+// 职责：
+// - 定位失败时坐标保持 0/0 并标记 Cinema.GeocodeFailed，API 中 located=false，地图不标出
+// - 版本化迁移（见 migrations.go）清除旧版本写入的兜底坐标，统一改为定位失败：geo_status=random 的行，以及更早没有 geo_status 时
+//   写入的行（落在兜底范围内且只有 4 位小数，Nominatim 返回的真实坐标有 7 位小数）
+// - fix-geocode 命令只对定位失败的影院重新定位；人工锁定坐标的影院不处理
+// 说明：Nominatim 的“无结果”也会缓存（见 geocode.go），重试前先丢弃这些影院的无结果缓存，否则重试只会命中缓存。
+// 调用方式：
+//   go run . fix-geocode [--osm-email=you@example.com]
+// ===========================
+
+// 旧版本兜底坐标的范围：基准点加 [0, 0.01) 度的偏移。
+const (
+	legacyFallbackLat    = 35.6895
+	legacyFallbackLng    = 139.6917
+	legacyFallbackSpread = 0.01
+)
+
+// looksLikeLegacyFallback 没有 geo_status 的旧数据是否为兜底坐标（纯函数）：
+// 落在兜底范围内，且经纬度都不超过 4 位小数。
+func looksLikeLegacyFallback(lat, lng float64) bool {
+	inBox := lat >= legacyFallbackLat && lat < legacyFallbackLat+legacyFallbackSpread &&
+		lng >= legacyFallbackLng && lng < legacyFallbackLng+legacyFallbackSpread
+	coarse := func(v float64) bool { return math.Abs(v*1e4-math.Round(v*1e4)) < 1e-6 }
+	return inBox && coarse(lat) && coarse(lng)
+}
+
+// clearRandomFallbackCoords 把兜底坐标与缺失坐标的影院统一标记为定位失败，返回更新的影院数。
+// 人工锁定的坐标不动；使用 UpdateColumns，不改 UpdatedAt。在迁移事务 conn 上执行。
+func clearRandomFallbackCoords(conn *gorm.DB) (int64, error) {
+	var cinemas []Cinema
+	// 旧库新增 geocode_failed 列时已有行为 NULL
+	if err := conn.Where("geocode_failed IS NULL OR geocode_failed = ?", false).Find(&cinemas).Error; err != nil {
+		return 0, err
+	}
+	ids := make([]uint, 0)
+	for _, cin := range cinemas {
+		locked := parseProvenance(cin.ProvenanceJSON)
+		if locked["latitude"].Source == SourceManual || locked["longitude"].Source == SourceManual {
+			continue
+		}
+		missing := cin.Latitude == 0 && cin.Longitude == 0
+		legacy := cin.GeoStatus == "" && looksLikeLegacyFallback(cin.Latitude, cin.Longitude)
+		if missing || legacy || cin.GeoStatus == GeoStatusRandom {
+			ids = append(ids, cin.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	res := conn.Model(&Cinema{}).Where("id IN ?", ids).UpdateColumns(map[string]interface{}{
+		"latitude":       0,
+		"longitude":      0,
+		"geo_status":     GeoStatusFailed,
+		"geocode_failed": true,
+	})
+	return res.RowsAffected, res.Error
+}
+
+// fixFailedGeocodes 对定位失败的影院重新定位，返回成功与仍失败的数量。
+func fixFailedGeocodes() (fixed, failed int, err error) {
+	var cinemas []Cinema
+	if err := db.Where("geocode_failed = ?", true).Order("id").Find(&cinemas).Error; err != nil {
+		return 0, 0, fmt.Errorf("查询定位失败的影院失败: %v", err)
+	}
+	for _, cin := range cinemas {
+		locked := parseProvenance(cin.ProvenanceJSON)
+		if locked["latitude"].Source == SourceManual || locked["longitude"].Source == SourceManual {
+			fmt.Printf("   📌 [%s] 坐标已人工锁定，跳过\n", cin.NameJP)
+			continue
+		}
+		if cin.Address == "" {
+			fmt.Printf("   ⚠️ [%s] 没有地址，跳过\n", cin.NameJP)
+			failed++
+			continue
+		}
+		address := cleanAddressForGeo(cin.Address)
+		exactQuery, approxQuery := geocodeQueries(address, cin.NameJP)
+		if err := db.Where("query IN ? AND found = ?", []string{exactQuery, approxQuery}, false).Delete(&GeocodeCache{}).Error; err != nil {
+			return fixed, failed, fmt.Errorf("清除无结果缓存失败: %v", err)
+		}
+
+		lat, lng, status, provider := getCoordsFromOSMWithRetry(address, cin.NameJP)
+		if status == GeoStatusFailed {
+			fmt.Printf("   ❌ [%s] 仍然无法定位：%s\n", cin.NameJP, address)
+			failed++
+			continue
+		}
+		provenance := cin.ProvenanceJSON
+		recordProvenance(&provenance, geoProviderSource(provider), "latitude", "longitude", "geo_status", "geocode_failed", "geo_provider")
+		if err := db.Model(&cin).Updates(map[string]interface{}{
+			"latitude":        lat,
+			"longitude":       lng,
+			"geo_status":      status,
+			"geocode_failed":  false,
+			"geo_provider":    provider,
+			"provenance_json": provenance,
+			"updated_at":      time.Now(),
+		}).Error; err != nil {
+			return fixed, failed, fmt.Errorf("写入影院 %d 坐标失败: %v", cin.ID, err)
+		}
+		fmt.Printf("   📍 [%s] 已定位（%s，%s）：%.5f, %.5f\n", cin.NameJP, status, provider, lat, lng)
+		fixed++
+	}
+	return fixed, failed, nil
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// ===========================
+// 模块：国土地理院（GSI）地址检索
+// 职责：Nominatim 经常查不到“〇丁目〇番地”格式的日本地址（正是 cleanAddressForGeo 的输出），
+//       此时用 GSI 的免费地址检索接口兜底：https://msearch.gsi.go.jp/address-search/AddressSearch?q=...
+// - 响应为 GeoJSON Feature 数组，coordinates 为 [经度, 纬度]，取第一条
+// - 与 Nominatim 共用 GeocodeCache（provider = gsi），同样 1 次/秒限速
+// - 坐标由哪个来源给出记录在 Cinema.GeoProvider 与字段来源（provenance）中，便于之后核查准确度
+// ===========================
+
+const gsiMinInterval = time.Second
+
+// gsiLimiter GSI 请求的限速器（与 Nominatim 分开计）。
+var gsiLimiter = newRateLimiter(gsiMinInterval)
+
+// gsiFeature GSI 地址检索结果中的一条。
+type gsiFeature struct {
+	Geometry struct {
+		Coordinates []float64 `json:"coordinates"` // [lng, lat]
+	} `json:"geometry"`
+	Properties struct {
+		Title string `json:"title"`
+	} `json:"properties"`
+}
+
+// parseGSIResponse 解析 GSI 地址检索响应（纯函数），返回第一条结果的坐标；没有结果时 found 为 false。
+func parseGSIResponse(body []byte) (lat, lng float64, found bool, err error) {
+	var features []gsiFeature
+	if err := json.Unmarshal(body, &features); err != nil {
+		return 0, 0, false, err
+	}
+	for _, f := range features {
+		if len(f.Geometry.Coordinates) < 2 {
+			continue
+		}
+		lng, lat = f.Geometry.Coordinates[0], f.Geometry.Coordinates[1]
+		if lat == 0 && lng == 0 {
+			continue
+		}
+		return lat, lng, true, nil
+	}
+	return 0, 0, false, nil
+}
+
+// requestGSI 向 GSI 发起一次地址检索（经过限速），返回结果与响应头。
+func requestGSI(query string) (GeocodeCache, http.Header, error) {
+	entry := GeocodeCache{Query: query}
+	apiURL := "https://msearch.gsi.go.jp/address-search/AddressSearch?q=" + url.QueryEscape(query)
+
+	gsiLimiter.Wait()
+	client := &http.Client{Timeout: 10 * time.Second}
+	req, _ := http.NewRequest("GET", apiURL, nil)
+	req.Header.Set("User-Agent", "TokyoCinePath/1.1 (gsi-address-search)")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return entry, nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return entry, resp.Header, fmt.Errorf("gsi status %d", resp.StatusCode)
+	}
+	var raw json.RawMessage
+	if err := json.NewDecoder(resp.Body).Decode(&raw); err != nil {
+		return entry, resp.Header, err
+	}
+	lat, lng, found, err := parseGSIResponse(raw)
+	if err != nil {
+		return entry, resp.Header, err
+	}
+	entry.Latitude, entry.Longitude, entry.Found = lat, lng, found
+	return entry, resp.Header, nil
+}
+
+// callGSI 用 GSI 地理编码（经过缓存）。
+func callGSI(query string) (float64, float64, error) {
+	return cachedGeocode(geocodeProviderGSI, query, &gsiRequests, requestGSI)
+}
+
+// geoProviderSource 地理编码来源对应的字段来源标识（provenance）。
+func geoProviderSource(provider string) string {
+	if provider == geocodeProviderGSI {
+		return SourceGSI
+	}
+	return SourceOSM
+}
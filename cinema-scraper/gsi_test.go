@@ -0,0 +1,26 @@
+package main
+
+import (
+	"testing"
+)
+
+// TestParseGSIResponse 地理编码：GSI 响应的坐标顺序为 [经度, 纬度]，无结果时 found=false。
+func TestParseGSIResponse(t *testing.T) {
+	setTestClock(t, beforeMidnight)
+
+	lat, lng, found, err := parseGSIResponse([]byte(`[{"geometry":{"coordinates":[139.703835,35.712605],"type":"Point"},"type":"Feature","properties":{"title":"東京都新宿区高田馬場一丁目"}}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _, emptyFound, err := parseGSIResponse([]byte(`[]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := firstError(
+		expectEqual("found", found, true),
+		expectEqual("lat", lat, 35.712605),
+		expectEqual("lng", lng, 139.703835),
+		expectEqual("empty found", emptyFound, false)); err != nil {
+		t.Fatal(err)
+	}
+}
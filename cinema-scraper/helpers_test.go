@@ -0,0 +1,239 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// ===========================
+// 模块：测试辅助
+// 职责：每个测试独立的内存数据库与路由，以及请求 / 断言工具
+// - newTestDB：空的内存库（已迁移），替换包级 db，测试结束时恢复
+// - newTestRouter：在 newTestDB 上载入样例数据（见 fixtures_test.go），挂载 setupRouter
+// - setTestClock：固定 clockNow，模拟部署在 UTC 主机上的某个时刻
+// 说明：handler 通过包级 db、clockNow、appConfig 等访问状态，测试会替换它们，因此不调用 t.Parallel()。
+// ===========================
+
+// testDSN 内存数据库；连接池限制为单连接（每个连接都会得到一个独立的空内存库）。
+const testDSN = "file::memory:"
+
+// testAdminToken 测试时的管理接口令牌；/api/admin 下的请求自动带上。
+const testAdminToken = "test-admin-token"
+
+// testHostLocation 日期边界测试模拟的服务器时区：部署在 UTC 主机上。
+var testHostLocation = time.UTC
+
+// 日期边界的两个时刻（UTC）：14:59Z = JST 23:59（仍是 1/27），15:01Z = JST 次日 00:01（已是 1/28）。
+var (
+	beforeMidnight = time.Date(2026, 1, 27, 14, 59, 0, 0, time.UTC)
+	afterMidnight  = time.Date(2026, 1, 27, 15, 1, 0, 0, time.UTC)
+)
+
+// testNoon 多数写库测试使用的时刻：JST 2026-01-27 正午。
+var testNoon = time.Date(2026, 1, 27, 3, 0, 0, 0, time.UTC)
+
+// testResponse 一次请求的结果。
+type testResponse struct {
+	Status int
+	Header http.Header
+	Body   []byte
+}
+
+// decode 将响应体解析到 v。
+func (r testResponse) decode(v interface{}) error {
+	if err := json.Unmarshal(r.Body, v); err != nil {
+		return fmt.Errorf("invalid JSON body: %v", err)
+	}
+	return nil
+}
+
+// newTestDB 打开一个新的内存数据库并替换包级 db；进程内缓存、计数与可变配置一并重置，测试结束时恢复。
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	conn, err := openDatabase(testDSN)
+	if err != nil {
+		t.Fatalf("open memory database: %v", err)
+	}
+	sqlDB, err := conn.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	prevDB, prevConfig, prevClock := db, appConfig, clockNow
+	prevStatusConfig, prevCrawl := statusConfig, scheduledCrawl
+	t.Cleanup(func() {
+		db, appConfig, clockNow = prevDB, prevConfig, prevClock
+		statusConfig, scheduledCrawl = prevStatusConfig, prevCrawl
+		queryCountHeaderEnabled = false
+		resetTestCaches()
+	})
+	resetTestCaches()
+	// 404 等用例本身会产生 record not found，测试时不打印 SQL 日志
+	db = conn.Session(&gorm.Session{Logger: logger.Default.LogMode(logger.Silent)})
+	return db
+}
+
+// resetTestCaches 清空进程内按数据库内容缓存的结果与抓取计数，避免上一个测试的内存库泄漏到下一个。
+func resetTestCaches() {
+	cityTimetableCache.Lock()
+	cityTimetableCache.entries = nil
+	cityTimetableCache.Unlock()
+	freshnessCache.Lock()
+	freshnessCache.value, freshnessCache.loadedAt = ScheduleFreshness{}, time.Time{}
+	freshnessCache.Unlock()
+	sitemapCache.Lock()
+	sitemapCache.urls, sitemapCache.generatedAt = nil, time.Time{}
+	sitemapCache.Unlock()
+	datasetMetaCache.Lock()
+	datasetMetaCache.modTime, datasetMetaCache.meta = time.Time{}, DatasetMetadata{}
+	datasetMetaCache.Unlock()
+	eigaTitleSightings.Lock()
+	eigaTitleSightings.byID = make(map[string]map[string]bool)
+	eigaTitleSightings.Unlock()
+	omdbState.Lock()
+	omdbState.calls, omdbState.blocked = 0, false
+	omdbState.Unlock()
+	tmdbBreaker.Lock()
+	tmdbBreaker.state, tmdbBreaker.failures, tmdbBreaker.openedAt = "", 0, time.Time{}
+	tmdbBreaker.probing, tmdbBreaker.transitions = false, 0
+	tmdbBreaker.Unlock()
+	tmdbDeferred.Store(0)
+	readOnlyMode.Store(false)
+	resetCrawlCounters()
+}
+
+// newTestRouter 在新的内存库上载入样例数据（以真实的今天为基准）并挂载路由。
+func newTestRouter(t *testing.T) http.Handler {
+	t.Helper()
+	newTestDB(t)
+	today := nowJST()
+	if err := loadFixtureData(db, today); err != nil {
+		t.Fatalf("load fixtures: %v", err)
+	}
+	if _, err := recomputeProgrammingIntensity(today); err != nil {
+		t.Fatalf("recompute intensity: %v", err)
+	}
+	// 样例排片直接写入热表，不经过抓取，放映跨度汇总需要补建
+	if _, err := backfillRunSummaries(); err != nil {
+		t.Fatalf("backfill run summaries: %v", err)
+	}
+
+	gin.SetMode(gin.ReleaseMode)
+	gin.DefaultWriter = io.Discard // 请求日志对测试没有意义
+	queryCountHeaderEnabled = true // 按 X-DB-Queries 断言主要接口的查询数（见 querybudget.go）
+	appConfig.AdminToken = testAdminToken
+	// 密钥没有默认值；补全相关的测试走预先写入的接口缓存或本地假服务，只需要非空的 Key
+	appConfig.TMDBAPIKey, appConfig.OMDBAPIKey = "test-tmdb-key", "test-omdb-key"
+	return setupRouter()
+}
+
+// setTestClock 固定时钟：返回以 testHostLocation 表示的 now，不修改全局 time.Local
+// （其他 goroutine，如 net/http 的连接，会并发读取 time.Local）。测试结束时恢复。
+func setTestClock(t *testing.T, now time.Time) {
+	t.Helper()
+	prev := clockNow
+	clockNow = func() time.Time { return now.In(testHostLocation) }
+	t.Cleanup(func() { clockNow = prev })
+}
+
+// testGet 对路由发起一次 GET 请求（不经过网络）。
+func testGet(router http.Handler, path string) testResponse {
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	if strings.HasPrefix(path, "/api/admin/") {
+		req.Header.Set("Authorization", "Bearer "+testAdminToken)
+	}
+	router.ServeHTTP(rec, req)
+	return testResponse{Status: rec.Code, Header: rec.Header(), Body: rec.Body.Bytes()}
+}
+
+// httpCase 表驱动的接口测试：对 path 发一次 GET，check 返回 nil 表示通过。
+type httpCase struct {
+	name  string
+	path  string
+	check func(r testResponse) error
+}
+
+// runHTTPCases 每项一个子测试，各自使用新的内存库与样例数据。
+func runHTTPCases(t *testing.T, cases []httpCase) {
+	t.Helper()
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			router := newTestRouter(t)
+			if err := tc.check(testGet(router, tc.path)); err != nil {
+				t.Fatalf("GET %s: %v", tc.path, err)
+			}
+		})
+	}
+}
+
+// expectStatus 校验状态码，失败时附上响应体便于排查。
+func expectStatus(r testResponse, want int) error {
+	if r.Status != want {
+		body := []rune(string(r.Body))
+		if len(body) > 200 {
+			body = append(body[:200], '…')
+		}
+		return fmt.Errorf("status = %d, want %d: %s", r.Status, want, string(body))
+	}
+	return nil
+}
+
+// expectEqual 按 %v 比较期望值。
+func expectEqual(what string, got, want interface{}) error {
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		return fmt.Errorf("%s = %v, want %v", what, got, want)
+	}
+	return nil
+}
+
+// firstError 依次返回第一个非 nil 的错误。
+func firstError(errs ...error) error {
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// expectJSON 校验 200 并解析响应体。
+func expectJSON(r testResponse, v interface{}) error {
+	if err := expectStatus(r, http.StatusOK); err != nil {
+		return err
+	}
+	return r.decode(v)
+}
+
+// testCinemaList / testMovieList 列表接口的响应外壳。
+type testCinemaList struct {
+	Items    []CinemaItem `json:"items"`
+	Total    int          `json:"total"`
+	Page     int          `json:"page"`
+	PageSize int          `json:"page_size"`
+}
+
+type testMovieList struct {
+	Items []MovieItem `json:"items"`
+}
+
+// movieItemIDs 影片列表的 ID（保持响应顺序）。
+func movieItemIDs(items []MovieItem) []uint {
+	ids := make([]uint, 0, len(items))
+	for _, m := range items {
+		ids = append(ids, m.ID)
+	}
+	return ids
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ===========================
+// 模块：首页聚合接口（/api/home）
+// 职责：一次返回首页 Now / Soon 两个标签页的数据，日期口径统一按东京时间
+// 说明：
+// - now：今天有排片的影片，按综合评分降序
+// - soon：标记为 incoming 的影片，按最早的未来排片日期（没有则用上映日期）升序
+// - 影院数量、最早排片、接下来的场次都用聚合查询一次取回，避免逐片查询
+// ===========================
+
+// homeNextShowtimesLimit 每部影片附带的“接下来的场次”数量。
+const homeNextShowtimesLimit = 3
+
+// NextShowtime 首页卡片上展示的即将开场的场次。
+type NextShowtime struct {
+	CinemaID   uint   `json:"cinema_id"`
+	CinemaName string `json:"cinema_name"`
+	Date       string `json:"date"`
+	Time       string `json:"time"`
+}
+
+// HomeMovie 首页影片卡片：列表字段 + 接下来的场次。
+type HomeMovie struct {
+	MovieItem
+	NextShowtimes []NextShowtime `json:"next_showtimes"`
+}
+
+// homeHandler 首页聚合接口：GET /api/home
+func homeHandler(c *gin.Context) {
+	now := referenceTime(c)
+	today := now.Format("2006-01-02")
+
+	// 1) 今天及以后的全部公开排片（不含会員限定），一次取回后在内存中按影片分组
+	var schedules []Schedule
+	if err := db.Where("date(play_date) >= ? AND members_only = ?", today, false).Order("id").Find(&schedules).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to query schedules"})
+		return
+	}
+	byMovie := make(map[uint][]Schedule)
+	showingToday := make(map[uint]struct{})
+	cinemaIDs := make(map[uint]struct{})
+	for _, s := range schedules {
+		byMovie[s.MovieID] = append(byMovie[s.MovieID], s)
+		cinemaIDs[s.CinemaID] = struct{}{}
+		if s.PlayDate.Format("2006-01-02") == today {
+			showingToday[s.MovieID] = struct{}{}
+		}
+	}
+
+	// 2) Now：今天有排片的影片；Soon：incoming 状态的影片
+	var nowMovies []Movie
+	if len(showingToday) > 0 {
+		if err := db.Where("id IN ?", sortedIDs(showingToday)).Find(&nowMovies).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to query movies"})
+			return
+		}
+	}
+	var soonMovies []Movie
+	if err := db.Where("status = ?", "incoming").Find(&soonMovies).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to query movies"})
+		return
+	}
+
+	// 3) 聚合：影院名称、影院数量、最早排片日期
+	cinemaNames := make(map[uint]string)
+	if len(cinemaIDs) > 0 {
+		var cinemas []Cinema
+		db.Select("id", "name_jp").Where("id IN ?", sortedIDs(cinemaIDs)).Find(&cinemas)
+		for _, cin := range cinemas {
+			cinemaNames[cin.ID] = cin.NameJP
+		}
+	}
+	movieIDs := make([]uint, 0, len(nowMovies)+len(soonMovies))
+	for _, m := range nowMovies {
+		movieIDs = append(movieIDs, m.ID)
+	}
+	for _, m := range soonMovies {
+		movieIDs = append(movieIDs, m.ID)
+	}
+	totalCounts := countCinemasByMovie(movieIDs, "")
+	currentCounts := countCinemasByMovie(movieIDs, today)
+	earliest := earliestScheduleDates(movieIDs)
+
+	hydrate := func(m Movie) HomeMovie {
+		item := mapMovieToItem(m, requestLang(c))
+		item.EarliestScheduleDate = earliest[m.ID]
+		total, current := totalCounts[m.ID], currentCounts[m.ID]
+		item.CinemaCount = int(total.CinemaCount)
+		item.CinemaCountTotal = int(total.CinemaCount)
+		item.CinemaCountCurrent = int(current.CinemaCount)
+		if current.CinemaCount == 1 {
+			item.PrimaryCinemaName = cinemaNames[current.CinemaID]
+		}
+		return HomeMovie{
+			MovieItem:     item,
+			NextShowtimes: nextShowtimes(byMovie[m.ID], cinemaNames, now, homeNextShowtimesLimit),
+		}
+	}
+
+	nowItems := make([]HomeMovie, 0, len(nowMovies))
+	sort.SliceStable(nowMovies, func(i, j int) bool {
+		ri, rj := combinedRating(nowMovies[i]), combinedRating(nowMovies[j])
+		if ri != rj {
+			return ri > rj
+		}
+		return nowMovies[i].ID < nowMovies[j].ID
+	})
+	for _, m := range nowMovies {
+		nowItems = append(nowItems, hydrate(m))
+	}
+
+	// Soon 排序键：最早的未来排片日期，没有排片时退回上映日期（日本上映日期优先）；都没有的排最后
+	soonKey := func(m Movie) string {
+		if d := earliestFrom(byMovie[m.ID], today); d != "" {
+			return d
+		}
+		if d := jpReleaseDateString(m); d != "" {
+			return d
+		}
+		if !m.ReleaseDate.IsZero() {
+			return m.ReleaseDate.Format("2006-01-02")
+		}
+		return "9999-12-31"
+	}
+	sort.SliceStable(soonMovies, func(i, j int) bool {
+		ki, kj := soonKey(soonMovies[i]), soonKey(soonMovies[j])
+		if ki != kj {
+			return ki < kj
+		}
+		return soonMovies[i].ID < soonMovies[j].ID
+	})
+	soonItems := make([]HomeMovie, 0, len(soonMovies))
+	for _, m := range soonMovies {
+		soonItems = append(soonItems, hydrate(m))
+	}
+
+	c.JSON(http.StatusOK, withFreshness(gin.H{
+		"date": today,
+		"now":  nowItems,
+		"soon": soonItems,
+	}))
+}
+
+// earliestScheduleDates 用一条 GROUP BY 查询每部影片的最早公开排片日期（YYYY-MM-DD）。
+func earliestScheduleDates(movieIDs []uint) map[uint]string {
+	out := make(map[uint]string, len(movieIDs))
+	if len(movieIDs) == 0 {
+		return out
+	}
+	var rows []struct {
+		MovieID   uint
+		FirstDate string
+	}
+	db.Model(&Schedule{}).
+		Select("movie_id, MIN(date(play_date)) AS first_date").
+		Where("movie_id IN ? AND members_only = ?", movieIDs, false).
+		Group("movie_id").
+		Scan(&rows)
+	for _, r := range rows {
+		out[r.MovieID] = r.FirstDate
+	}
+	return out
+}
+
+// earliestFrom 返回 schedules 中不早于 from 的最早日期，没有则为空串。
+func earliestFrom(schedules []Schedule, from string) string {
+	first := ""
+	for _, s := range schedules {
+		d := s.PlayDate.Format("2006-01-02")
+		if d >= from && (first == "" || d < first) {
+			first = d
+		}
+	}
+	return first
+}
+
+// nextShowtimes 从影片的未来排片中挑出最近的 limit 个尚未开场的场次。
+func nextShowtimes(schedules []Schedule, cinemaNames map[uint]string, now time.Time, limit int) []NextShowtime {
+	today := now.Format("2006-01-02")
+	nowMinutes := now.Hour()*60 + now.Minute()
+
+	upcoming := make([]Schedule, 0, len(schedules))
+	for _, s := range schedules {
+		if s.PlayDate.Format("2006-01-02") == today && startTimeMinutes(s.StartTime) < nowMinutes {
+			continue
+		}
+		if s.Availability == AvailabilitySoldOut {
+			continue
+		}
+		upcoming = append(upcoming, s)
+	}
+	sort.SliceStable(upcoming, func(i, j int) bool {
+		di, dj := upcoming[i].PlayDate.Format("2006-01-02"), upcoming[j].PlayDate.Format("2006-01-02")
+		if di != dj {
+			return di < dj
+		}
+		return startTimeMinutes(upcoming[i].StartTime) < startTimeMinutes(upcoming[j].StartTime)
+	})
+	if len(upcoming) > limit {
+		upcoming = upcoming[:limit]
+	}
+
+	out := make([]NextShowtime, 0, len(upcoming))
+	for _, s := range upcoming {
+		out = append(out, NextShowtime{
+			CinemaID:   s.CinemaID,
+			CinemaName: cinemaNames[s.CinemaID],
+			Date:       s.PlayDate.Format("2006-01-02"),
+			Time:       s.StartTime,
+		})
+	}
+	return out
+}
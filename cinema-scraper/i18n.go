@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// ===========================
+// 模块：本地化格式（日期 / 星期 / 计数与界面文案）
+// 职责：
+// - 服务端渲染的时间表页面与周报按 ja / en / zh 输出日期标题（1月25日(土)）、星期与计数（3館で上映中）
+// - 固定文案集中在 i18nMessages，模板通过 t 函数取用
+// 说明：语言由 languageMiddleware 解析（?lang= / Accept-Language），周报命令用 --lang=；
+//       所有函数都是纯函数，未知语言回退到调用方给出的默认语言。
+// ===========================
+
+// weekdayNames 各语言的星期名（time.Sunday 起）。
+var weekdayNames = map[string][7]string{
+	LangJA: {"日", "月", "火", "水", "木", "金", "土"},
+	LangZH: {"周日", "周一", "周二", "周三", "周四", "周五", "周六"},
+	LangEN: {"Sun", "Mon", "Tue", "Wed", "Thu", "Fri", "Sat"},
+}
+
+// i18nMessages 界面文案：键 -> 语言 -> 文本（可带 fmt 占位符）。
+var i18nMessages = map[string]map[string]string{
+	"timetable.title":     {LangJA: "上映時間表", LangZH: "上映时间表", LangEN: "Showtimes"},
+	"timetable.no_shows":  {LangJA: "本日の上映はありません", LangZH: "今日没有放映", LangEN: "No screenings today"},
+	"timetable.no_cinema": {LangJA: "該当する映画館がありません", LangZH: "没有符合条件的影院", LangEN: "No matching cinemas"},
+	"timetable.other":     {LangJA: "その他", LangZH: "其他", LangEN: "Other"},
+
+	"digest.title":        {LangJA: "東京映画館ウィークリー %s（%s〜%s）", LangZH: "东京影院周报 %s（%s ~ %s）", LangEN: "Tokyo Cinema Weekly %s (%s – %s)"},
+	"digest.openings":     {LangJA: "今週の新作", LangZH: "本周新片", LangEN: "New this week"},
+	"digest.closings":     {LangJA: "見逃し注意", LangZH: "最后机会", LangEN: "Last chance"},
+	"digest.revivals":     {LangJA: "名作リバイバル", LangZH: "经典重映", LangEN: "Revivals"},
+	"digest.districts":    {LangJA: "区別の上映状況", LangZH: "各区排片", LangEN: "By ward"},
+	"digest.none":         {LangJA: "なし", LangZH: "暂无", LangEN: "None"},
+	"digest.cinemas":      {LangJA: "%s：%s", LangZH: "%s：%s", LangEN: "%s: %s"},
+	"digest.opening_line": {LangJA: "%sから上映、今週%s", LangZH: "%s 起上映，本周 %s", LangEN: "opens %s, %s this week"},
+	"digest.closing_line": {LangJA: "最終上映 %s、今週%s", LangZH: "最后排片 %s，本周 %s", LangEN: "last screening %s, %s this week"},
+	"digest.revival_line": {LangJA: "今週%s", LangZH: "本周 %s", LangEN: "%s this week"},
+	"digest.table_header": {LangJA: "| 区 | 映画館 | 上映回数 |", LangZH: "| 区 | 影院 | 场次 |", LangEN: "| Ward | Cinemas | Showtimes |"},
+	"digest.list_sep":     {LangJA: "、", LangZH: "、", LangEN: ", "},
+	"paren":               {LangJA: "（%s）", LangZH: "（%s）", LangEN: " (%s)"},
+}
+
+// i18nLang 支持的语言原样返回，否则返回 fallback。
+func i18nLang(lang, fallback string) string {
+	if _, ok := weekdayNames[lang]; ok {
+		return lang
+	}
+	return fallback
+}
+
+// translate 取文案并代入参数；缺少该语言时依次回退到 ja / zh / en，键不存在时返回键本身。
+func translate(lang, key string, args ...interface{}) string {
+	entry, ok := i18nMessages[key]
+	if !ok {
+		return key
+	}
+	text, ok := entry[lang]
+	for _, fallback := range []string{LangJA, LangZH, LangEN} {
+		if ok {
+			break
+		}
+		text, ok = entry[fallback]
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf(text, args...)
+	}
+	return text
+}
+
+// formatWeekday 星期名：ja 土 / zh 周六 / en Sat。
+func formatWeekday(wd time.Weekday, lang string) string {
+	return weekdayNames[i18nLang(lang, LangJA)][wd]
+}
+
+// formatDateHeading 日期标题：ja 1月25日(土) / zh 1月25日（周六） / en Sat, Jan 25。
+func formatDateHeading(t time.Time, lang string) string {
+	switch i18nLang(lang, LangJA) {
+	case LangZH:
+		return fmt.Sprintf("%d月%d日（%s）", int(t.Month()), t.Day(), formatWeekday(t.Weekday(), LangZH))
+	case LangEN:
+		return fmt.Sprintf("%s, %s %d", formatWeekday(t.Weekday(), LangEN), t.Month().String()[:3], t.Day())
+	}
+	return fmt.Sprintf("%d月%d日(%s)", int(t.Month()), t.Day(), formatWeekday(t.Weekday(), LangJA))
+}
+
+// formatISODateHeading 把 YYYY-MM-DD 格式化为日期标题；无法解析时原样返回。
+func formatISODateHeading(date, lang string) string {
+	t, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return date
+	}
+	return formatDateHeading(t, lang)
+}
+
+// englishPlural 英文计数：1 cinema / 3 cinemas。
+func englishPlural(n int, singular, plural string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, singular)
+	}
+	return fmt.Sprintf("%d %s", n, plural)
+}
+
+// formatCinemaCount 影院数：ja 84館 / zh 84 家影院 / en 84 cinemas。
+func formatCinemaCount(n int, lang string) string {
+	switch i18nLang(lang, LangJA) {
+	case LangZH:
+		return fmt.Sprintf("%d 家影院", n)
+	case LangEN:
+		return englishPlural(n, "cinema", "cinemas")
+	}
+	return fmt.Sprintf("%d館", n)
+}
+
+// formatShowingAt 上映范围：ja 3館で上映中 / zh 3 家影院上映中 / en Showing at 3 cinemas。
+func formatShowingAt(n int, lang string) string {
+	switch i18nLang(lang, LangJA) {
+	case LangZH:
+		return fmt.Sprintf("%d 家影院上映中", n)
+	case LangEN:
+		return "Showing at " + englishPlural(n, "cinema", "cinemas")
+	}
+	return fmt.Sprintf("%d館で上映中", n)
+}
+
+// formatShowtimeCount 场次数：ja 12回 / zh 12 场 / en 12 showtimes。
+func formatShowtimeCount(n int, lang string) string {
+	switch i18nLang(lang, LangJA) {
+	case LangZH:
+		return fmt.Sprintf("%d 场", n)
+	case LangEN:
+		return englishPlural(n, "showtime", "showtimes")
+	}
+	return fmt.Sprintf("%d回", n)
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestLocalizedFormats 本地化格式：日期标题、星期与计数按 ja / zh / en 输出，未知语言回退日文，文案三种语言齐全且占位符一致。
+func TestLocalizedFormats(t *testing.T) {
+	setTestClock(t, beforeMidnight)
+
+	saturday := time.Date(2026, 1, 31, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		lang string
+		want [8]string // 日期标题、ISO 日期标题、1 / 3 家影院、1 / 3 家上映中、0 场、时间表标题
+	}{
+		{LangJA, [8]string{"1月31日(土)", "12月25日(金)", "1館", "3館", "1館で上映中", "3館で上映中", "0回", "上映時間表"}},
+		{LangZH, [8]string{"1月31日（周六）", "12月25日（周五）", "1 家影院", "3 家影院", "1 家影院上映中", "3 家影院上映中", "0 场", "上映时间表"}},
+		{LangEN, [8]string{"Sat, Jan 31", "Fri, Dec 25", "1 cinema", "3 cinemas", "Showing at 1 cinema", "Showing at 3 cinemas", "0 showtimes", "Showtimes"}},
+		{"", [8]string{"1月31日(土)", "12月25日(金)", "1館", "3館", "1館で上映中", "3館で上映中", "0回", "上映時間表"}},
+		{"ko", [8]string{"1月31日(土)", "12月25日(金)", "1館", "3館", "1館で上映中", "3館で上映中", "0回", "上映時間表"}},
+	}
+	for _, tt := range tests {
+		got := [8]string{
+			formatDateHeading(saturday, tt.lang), formatISODateHeading("2026-12-25", tt.lang),
+			formatCinemaCount(1, tt.lang), formatCinemaCount(3, tt.lang),
+			formatShowingAt(1, tt.lang), formatShowingAt(3, tt.lang),
+			formatShowtimeCount(0, tt.lang), translate(tt.lang, "timetable.title"),
+		}
+		if err := expectEqual(fmt.Sprintf("lang %q", tt.lang), got, tt.want); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for key, entry := range i18nMessages {
+		for _, lang := range supportedLangs {
+			text, ok := entry[lang]
+			if !ok {
+				t.Fatalf("message %q has no %s text", key, lang)
+			}
+			if n, want := strings.Count(text, "%s"), strings.Count(entry[LangJA], "%s"); n != want {
+				t.Fatalf("message %q (%s) has %d placeholders, ja has %d", key, lang, n, want)
+			}
+		}
+	}
+	if err := firstError(
+		expectEqual("weekdays", [3]string{formatWeekday(time.Sunday, LangJA), formatWeekday(time.Sunday, LangZH), formatWeekday(time.Sunday, LangEN)}, [3]string{"日", "周日", "Sun"}),
+		expectEqual("invalid date", formatISODateHeading("2026-13-01", LangEN), "2026-13-01"),
+		expectEqual("with args", translate(LangEN, "digest.revival_line", formatShowtimeCount(1, LangEN)), "1 showtime this week"),
+		expectEqual("unknown key", translate(LangJA, "no.such.key"), "no.such.key")); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,337 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ===========================
+// 模块：图片缓存（cached_images 与 /img/:hash）
+// 职责：
+// - 影片海报（Movie.Poster）与影院建筑照片（Cinema.BuildingPhoto）首次被请求时从上游取图，按内容 sha256 存入
+//   cached_images（同时记下上游的 ETag / Last-Modified），并把哈希写回所属行（poster_hash / building_photo_hash）；
+//   之后 /api/movies/:id/poster、/api/cinemas/:id/photo 与 /img/:hash 都直接读本地缓存
+// - 缓存超过 imageRevalidateAfter 后向上游发条件请求（If-None-Match / If-Modified-Since）：
+//   304 只刷新检查时间；内容变化时新增一行缓存，并在同一事务里把引用旧哈希的影片 / 影院改指向新哈希
+// - /img/:hash 按内容寻址，响应可永久缓存（immutable）；旧哈希的缓存行不删除，已发出的旧地址不会失效
+// 调用方式：
+// - 请求路径上按需取图 / 重新校验；`go run . refresh-images` 批量重新校验所有已缓存的图片
+// 说明：缓存行按（上游地址, 内容哈希）唯一，不同地址返回相同内容时各自记录上游头信息；
+//       海报 / 照片地址改变后，由下一次 /api/.../poster|photo 请求或 refresh-images 取新图并改指向，此前 /img/:hash 仍返回旧图；
+//       不再被引用的旧图片暂不清理。
+// ===========================
+
+const (
+	imageRevalidateAfter  = 24 * time.Hour
+	imageHashCacheControl = "public, max-age=31536000, immutable"
+)
+
+// CachedImage 一张按内容寻址的缓存图片。
+type CachedImage struct {
+	ID           uint   `gorm:"primaryKey"`
+	SourceURL    string `gorm:"uniqueIndex:idx_cached_image_source"`       // 上游地址
+	Hash         string `gorm:"uniqueIndex:idx_cached_image_source;index"` // 图片内容的 sha256（hex）
+	ContentType  string
+	Data         []byte
+	ETag         string `gorm:"column:etag"` // 上游返回的 ETag，条件请求时原样带回
+	LastModified string // 上游返回的 Last-Modified
+	FetchedAt    time.Time
+	CheckedAt    time.Time // 最近一次向上游确认（取图或 304）的时间
+}
+
+// imageOwner 引用缓存图片的表：urlColumn 为上游地址列，hashColumn 为缓存哈希列。
+type imageOwner struct {
+	model      interface{}
+	urlColumn  string
+	hashColumn string
+}
+
+var (
+	moviePosterImages = imageOwner{&Movie{}, "poster", "poster_hash"}
+	cinemaPhotoImages = imageOwner{&Cinema{}, "building_photo", "building_photo_hash"}
+	imageOwners       = []imageOwner{moviePosterImages, cinemaPhotoImages}
+)
+
+var errImageTooLarge = errors.New("image too large")
+
+// imageContentHash 图片内容哈希（纯函数），同时作为 /img/:hash 的地址与 ETag。
+func imageContentHash(body []byte) string {
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
+
+// imageETag 按内容哈希生成的强 ETag。
+func imageETag(hash string) string {
+	return `"` + hash + `"`
+}
+
+// imagePath 缓存图片的对外地址。
+func imagePath(hash string) string {
+	return "/img/" + hash
+}
+
+// fetchImage 从上游取图；img 非空时带上它的 ETag / Last-Modified 做条件请求，上游返回 304 时 notModified 为 true。
+// 只接受 image/*，超过 posterProxyMaxBytes 返回 errImageTooLarge。
+func fetchImage(sourceURL string, img *CachedImage) (fetched CachedImage, notModified bool, err error) {
+	if !strings.HasPrefix(sourceURL, "http://") && !strings.HasPrefix(sourceURL, "https://") {
+		return CachedImage{}, false, fmt.Errorf("unsupported image url %q", sourceURL)
+	}
+	req, err := http.NewRequest(http.MethodGet, sourceURL, nil)
+	if err != nil {
+		return CachedImage{}, false, err
+	}
+	if img != nil {
+		if img.ETag != "" {
+			req.Header.Set("If-None-Match", img.ETag)
+		}
+		if img.LastModified != "" {
+			req.Header.Set("If-Modified-Since", img.LastModified)
+		}
+	}
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return CachedImage{}, false, err
+	}
+	defer resp.Body.Close()
+	if img != nil && resp.StatusCode == http.StatusNotModified {
+		return CachedImage{}, true, nil
+	}
+	contentType := resp.Header.Get("Content-Type")
+	if resp.StatusCode != http.StatusOK || !strings.HasPrefix(contentType, "image/") {
+		return CachedImage{}, false, fmt.Errorf("unexpected response %d %q", resp.StatusCode, contentType)
+	}
+	if resp.ContentLength > posterProxyMaxBytes {
+		return CachedImage{}, false, errImageTooLarge
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, posterProxyMaxBytes+1))
+	if err != nil {
+		return CachedImage{}, false, err
+	}
+	if len(body) > posterProxyMaxBytes {
+		return CachedImage{}, false, errImageTooLarge
+	}
+	return CachedImage{
+		Hash:         imageContentHash(body),
+		SourceURL:    sourceURL,
+		ContentType:  contentType,
+		Data:         body,
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+	}, false, nil
+}
+
+// storeImage 在一个事务里写入新图片（同一地址、同一内容已存在时只刷新上游头信息），并把引用改指向它：
+// 只更新地址仍为 img.SourceURL、且当前指向 oldHash 的行，取图期间地址被改掉的行不受影响。
+// owner 为 nil 时更新所有表中符合条件的行（重新校验），否则只更新 owner 表中 ID 为 id 的行。
+func storeImage(img CachedImage, oldHash string, owner *imageOwner, id uint, now time.Time) error {
+	img.FetchedAt, img.CheckedAt = now, now
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "source_url"}, {Name: "hash"}},
+			DoUpdates: clause.AssignmentColumns([]string{"etag", "last_modified", "checked_at"}),
+		}).Create(&img).Error; err != nil {
+			return err
+		}
+		owners := imageOwners
+		if owner != nil {
+			owners = []imageOwner{*owner}
+		}
+		for _, o := range owners {
+			q := tx.Model(o.model).Where(o.urlColumn+" = ? AND "+o.hashColumn+" = ?", img.SourceURL, oldHash)
+			if owner != nil {
+				q = q.Where("id = ?", id)
+			}
+			if err := q.UpdateColumn(o.hashColumn, img.Hash).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// loadCachedImage 按哈希读取缓存图片（内容相同的行任取一行）。
+func loadCachedImage(hash string) (CachedImage, error) {
+	var img CachedImage
+	err := db.Where("hash = ?", hash).Order("id").Take(&img).Error
+	return img, err
+}
+
+// loadSourceImage 读取某个上游地址下指定内容的缓存图片。
+func loadSourceImage(sourceURL, hash string) (CachedImage, error) {
+	var img CachedImage
+	err := db.Where("source_url = ? AND hash = ?", sourceURL, hash).Take(&img).Error
+	return img, err
+}
+
+// revalidateImage 向上游条件请求一张已缓存的图片：未变化时刷新检查时间并返回原图；
+// 内容变化时写入新图片，把引用旧哈希的影片 / 影院改指向新哈希后返回新图。
+func revalidateImage(img CachedImage, now time.Time) (CachedImage, error) {
+	fetched, notModified, err := fetchImage(img.SourceURL, &img)
+	if err != nil {
+		return img, err
+	}
+	if notModified || fetched.Hash == img.Hash {
+		updates := map[string]interface{}{"checked_at": now}
+		if !notModified {
+			updates["etag"], updates["last_modified"] = fetched.ETag, fetched.LastModified
+			img.ETag, img.LastModified = fetched.ETag, fetched.LastModified
+		}
+		img.CheckedAt = now
+		return img, db.Model(&CachedImage{}).Where("id = ?", img.ID).Updates(updates).Error
+	}
+	if err := storeImage(fetched, img.Hash, nil, 0, now); err != nil {
+		return img, err
+	}
+	return loadSourceImage(fetched.SourceURL, fetched.Hash)
+}
+
+// ownedImage 返回某行引用的缓存图片：没有缓存（或地址已变）时取图写入并改写该行的哈希，
+// 缓存过期时重新校验；重新校验失败时继续使用旧缓存。
+func ownedImage(owner imageOwner, id uint, sourceURL, hash string, now time.Time) (CachedImage, error) {
+	if hash != "" {
+		if img, err := loadSourceImage(sourceURL, hash); err == nil {
+			if now.Sub(img.CheckedAt) < imageRevalidateAfter {
+				return img, nil
+			}
+			fresh, err := revalidateImage(img, now)
+			if err != nil {
+				fmt.Printf("⚠️ 图片重新校验失败 [%s]: %v\n", sourceURL, err)
+				return img, nil
+			}
+			return fresh, nil
+		}
+	}
+	fetched, _, err := fetchImage(sourceURL, nil)
+	if err != nil {
+		return CachedImage{}, err
+	}
+	if err := storeImage(fetched, hash, &owner, id, now); err != nil {
+		return CachedImage{}, err
+	}
+	return loadSourceImage(fetched.SourceURL, fetched.Hash)
+}
+
+// refreshCachedImages 重新校验所有被影片 / 影院引用、且超过 maxAge 未确认的图片（地址已改的行直接取新图），
+// 返回改指向新图片的次数。
+func refreshCachedImages(now time.Time, maxAge time.Duration) (changed int, err error) {
+	type reference struct {
+		SourceURL string
+		Hash      string
+	}
+	seen := make(map[reference]bool)
+	var refs []reference
+	for _, o := range imageOwners {
+		var rows []reference
+		if err := db.Model(o.model).Distinct(o.urlColumn+" AS source_url", o.hashColumn+" AS hash").
+			Where(o.hashColumn + " <> ''").Order("source_url").Scan(&rows).Error; err != nil {
+			return 0, err
+		}
+		for _, r := range rows {
+			if !seen[r] {
+				seen[r] = true
+				refs = append(refs, r)
+			}
+		}
+	}
+	for _, r := range refs {
+		img, err := loadSourceImage(r.SourceURL, r.Hash)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// 地址已改但哈希还是旧图片的：重新取图并改指向
+			fetched, _, err := fetchImage(r.SourceURL, nil)
+			if err == nil {
+				err = storeImage(fetched, r.Hash, nil, 0, now)
+			}
+			if err != nil {
+				fmt.Printf("⚠️ 图片取图失败 [%s]: %v\n", r.SourceURL, err)
+				continue
+			}
+			changed++
+			continue
+		}
+		if err != nil || now.Sub(img.CheckedAt) < maxAge {
+			continue
+		}
+		fresh, err := revalidateImage(img, now)
+		if err != nil {
+			fmt.Printf("⚠️ 图片重新校验失败 [%s]: %v\n", img.SourceURL, err)
+			continue
+		}
+		if fresh.Hash != img.Hash {
+			changed++
+		}
+	}
+	return changed, nil
+}
+
+// ownedImageURL 图片的同源地址（纯函数）：已缓存时为 /img/:hash，否则为 fallback（首次请求时取图），没有图片时为空。
+func ownedImageURL(sourceURL, hash, fallback string) string {
+	switch {
+	case sourceURL == "":
+		return ""
+	case hash != "":
+		return imagePath(hash)
+	default:
+		return fallback
+	}
+}
+
+// serveOwnedImage 取出某行引用的缓存图片并输出；只接受 http(s) 地址，取图失败或超过上限返回 502。
+func serveOwnedImage(c *gin.Context, owner imageOwner, id uint, sourceURL, hash, notFound string) {
+	if !strings.HasPrefix(sourceURL, "http://") && !strings.HasPrefix(sourceURL, "https://") {
+		c.JSON(http.StatusNotFound, gin.H{"error": notFound})
+		return
+	}
+	img, err := ownedImage(owner, id, sourceURL, hash, time.Now())
+	if errors.Is(err, errImageTooLarge) {
+		c.JSON(http.StatusBadGateway, gin.H{"error": "image too large"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": "failed to fetch image"})
+		return
+	}
+	serveCachedImage(c, img, posterProxyCacheControl)
+}
+
+// serveCachedImage 输出缓存图片，ETag 为内容哈希，If-None-Match 命中时返回 304。
+func serveCachedImage(c *gin.Context, img CachedImage, cacheControl string) {
+	etag := imageETag(img.Hash)
+	c.Header("ETag", etag)
+	c.Header("Cache-Control", cacheControl)
+	if etagMatches(c.GetHeader("If-None-Match"), etag) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	c.Data(http.StatusOK, img.ContentType, img.Data)
+}
+
+// imageHandler 处理 GET /img/:hash：按内容哈希返回缓存图片，内容不变，可永久缓存。
+func imageHandler(c *gin.Context) {
+	img, err := loadCachedImage(strings.ToLower(c.Param("hash")))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "image not found"})
+		return
+	}
+	serveCachedImage(c, img, imageHashCacheControl)
+}
+
+// cinemaPhotoHandler 处理 GET /api/cinemas/:id/photo：输出影院建筑照片的缓存图片（首次请求时从上游取图）。
+func cinemaPhotoHandler(c *gin.Context) {
+	var cinema Cinema
+	if err := db.Select("id", "building_photo", "building_photo_hash").First(&cinema, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "cinema not found"})
+		return
+	}
+	serveOwnedImage(c, cinemaPhotoImages, cinema.ID, cinema.BuildingPhoto, cinema.BuildingPhotoHash, "photo not found")
+}
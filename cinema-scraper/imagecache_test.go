@@ -0,0 +1,140 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestImageCache 图片缓存：首次取图记下上游 ETag / Last-Modified，/img/:hash 可永久缓存，条件请求未变化不新增，换图后影片与影院改指向新哈希且旧地址仍可用。
+func TestImageCache(t *testing.T) {
+	newTestRouter(t)
+	now := testNoon
+	setTestClock(t, now)
+
+	var mu sync.Mutex
+	body, upstreamETag := []byte("\x89PNG\r\n\x1a\ntest-poster"), `"v1"`
+	var hits, notModified atomic.Int64
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		hits.Add(1)
+		if r.Header.Get("If-None-Match") == upstreamETag {
+			notModified.Add(1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("Content-Type", "image/png")
+		w.Header().Set("ETag", upstreamETag)
+		w.Header().Set("Last-Modified", "Tue, 27 Jan 2026 03:00:00 GMT")
+		w.Write(body)
+	}))
+	defer upstream.Close()
+	movie := Movie{TitleJP: "テスト海報", Poster: upstream.URL + "/poster.png", Status: "showing"}
+	if err := db.Create(&movie).Error; err != nil {
+		t.Fatal(err)
+	}
+	// 另一个地址返回相同内容：各自一行缓存，换图时两边都改指向新哈希
+	cinema := Cinema{NameJP: "テスト写真館", BuildingPhoto: upstream.URL + "/photo.png"}
+	if err := db.Create(&cinema).Error; err != nil {
+		t.Fatal(err)
+	}
+	fetch := func(handler gin.HandlerFunc, path, ifNoneMatch string, params gin.Params) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(rec)
+		c.Request = httptest.NewRequest(http.MethodGet, path, nil)
+		if ifNoneMatch != "" {
+			c.Request.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		c.Params = params
+		handler(c)
+		c.Writer.WriteHeaderNow()
+		return rec
+	}
+	poster := func(ifNoneMatch string) *httptest.ResponseRecorder {
+		return fetch(moviePosterHandler, fmt.Sprintf("/api/movies/%d/poster", movie.ID), ifNoneMatch, gin.Params{{Key: "id", Value: fmt.Sprint(movie.ID)}})
+	}
+	image := func(hash, ifNoneMatch string) *httptest.ResponseRecorder {
+		return fetch(imageHandler, imagePath(hash), ifNoneMatch, gin.Params{{Key: "hash", Value: hash}})
+	}
+	oldHash := imageContentHash(body)
+	first := poster("")
+	etag := first.Header().Get("ETag")
+	again := poster(etag)
+	stale := poster(`"0000"`)
+	hitsAfterRequests := hits.Load()
+	var cached CachedImage
+	if err := db.Where("hash = ?", oldHash).Take(&cached).Error; err != nil {
+		t.Fatal(err)
+	}
+	db.First(&movie, movie.ID)
+	photo := fetch(cinemaPhotoHandler, fmt.Sprintf("/api/cinemas/%d/photo", cinema.ID), "", gin.Params{{Key: "id", Value: fmt.Sprint(cinema.ID)}})
+	db.First(&cinema, cinema.ID)
+	hashed := image(oldHash, "")
+	hashedAgain := image(oldHash, imageETag(oldHash))
+	missing := image(strings.Repeat("0", 64), "")
+
+	unchanged, err := refreshCachedImages(time.Now().Add(imageRevalidateAfter+time.Hour), imageRevalidateAfter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rows int64
+	db.Model(&CachedImage{}).Where("source_url IN ?", []string{movie.Poster, cinema.BuildingPhoto}).Count(&rows)
+
+	mu.Lock()
+	body, upstreamETag = []byte("\x89PNG\r\n\x1a\ntest-poster-v2"), `"v2"`
+	mu.Unlock()
+	newHash := imageContentHash(body)
+	changed, err := refreshCachedImages(time.Now().Add(3*imageRevalidateAfter), imageRevalidateAfter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var updated Movie
+	db.First(&updated, movie.ID)
+	var updatedCinema Cinema
+	db.First(&updatedCinema, cinema.ID)
+	oldStill := image(oldHash, "")
+	// 海报地址改变：refresh-images 取新地址的图并改指向（内容相同时哈希不变）
+	db.Model(&Movie{}).Where("id = ?", movie.ID).UpdateColumn("poster", upstream.URL+"/poster-v3.png")
+	moved, err := refreshCachedImages(time.Now(), imageRevalidateAfter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var movedRows int64
+	db.Model(&CachedImage{}).Where("source_url = ? AND hash = ?", upstream.URL+"/poster-v3.png", newHash).Count(&movedRows)
+	payload := buildSharePayload(updated, nil, nil, time.Now(), "ja")
+	if err := firstError(
+		expectEqual("first status", first.Code, http.StatusOK),
+		expectEqual("etag", etag, imageETag(oldHash)),
+		expectEqual("revalidated", again.Code, http.StatusNotModified),
+		expectEqual("304 body", again.Body.Len(), 0),
+		expectEqual("stale etag", stale.Code, http.StatusOK),
+		expectEqual("upstream hits after 3 requests", hitsAfterRequests, 1),
+		expectEqual("stored upstream headers", cached.ETag+" "+cached.LastModified, `"v1" Tue, 27 Jan 2026 03:00:00 GMT`),
+		expectEqual("movie poster hash", movie.PosterHash, oldHash),
+		expectEqual("cinema photo", fmt.Sprint(photo.Code, " ", photo.Header().Get("ETag")), fmt.Sprint(http.StatusOK, " ", imageETag(oldHash))),
+		expectEqual("cinema photo hash", cinema.BuildingPhotoHash, oldHash),
+		expectEqual("cinema photo_url", mapCinemaToItem(cinema).PhotoURL, imagePath(oldHash)),
+		expectEqual("/img status", hashed.Code, http.StatusOK),
+		expectEqual("/img cache-control", hashed.Header().Get("Cache-Control"), imageHashCacheControl),
+		expectEqual("/img revalidated", hashedAgain.Code, http.StatusNotModified),
+		expectEqual("/img missing", missing.Code, http.StatusNotFound),
+		expectEqual("unchanged refresh", unchanged, 0),
+		expectEqual("upstream 304s", notModified.Load(), 2),
+		expectEqual("rows after unchanged refresh", rows, 2),
+		expectEqual("changed refresh", changed, 2),
+		expectEqual("movie repointed", updated.PosterHash, newHash),
+		expectEqual("cinema repointed", updatedCinema.BuildingPhotoHash, newHash),
+		expectEqual("old hash still served", oldStill.Code, http.StatusOK),
+		expectEqual("moved poster refetched", fmt.Sprint(moved, movedRows), "1 1"),
+		expectEqual("share poster_url", payload.PosterURL, imagePath(newHash))); err != nil {
+		t.Fatal(err)
+	}
+}
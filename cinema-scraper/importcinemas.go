@@ -0,0 +1,215 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ===========================
+// 模块：影院 CSV 批量导入
+// 职责：
+// - 补录 eiga.com 上没有的小众影院：name, address, website, lat, lng, tags, screens（银幕数，可选）
+// - 缺坐标时走现有 OSM 定位流程（限速与缓存见 geocode.go）；按 NameJP 去重，已存在则更新
+// - 导入的影院标记 Source=manual，字段来源记为 manual，之后的抓取合并不会覆盖
+// 调用方式：
+//   go run . import-cinemas cinemas.csv
+// ===========================
+
+// 影院数据来源。
+const (
+	CinemaSourceEiga   = "eiga"
+	CinemaSourceManual = "manual"
+)
+
+// cinemaCSVRow 解析后的一行。
+type cinemaCSVRow struct {
+	Line    int
+	Name    string
+	Address string
+	Website string
+	Lat     float64
+	Lng     float64
+	HasGeo  bool
+	Tags    []string
+	Screens int // 0 表示未填写
+}
+
+// cinemaImportError 行级错误。
+type cinemaImportError struct {
+	Line int
+	Name string
+	Err  error
+}
+
+// parseCinemaCSVRow 校验并解析一行（纯函数）；列名来自表头，大小写不敏感。
+func parseCinemaCSVRow(line int, header map[string]int, record []string) (cinemaCSVRow, error) {
+	get := func(col string) string {
+		if idx, ok := header[col]; ok && idx < len(record) {
+			return strings.TrimSpace(record[idx])
+		}
+		return ""
+	}
+	row := cinemaCSVRow{
+		Line:    line,
+		Name:    get("name"),
+		Address: get("address"),
+		Website: get("website"),
+	}
+	if row.Name == "" {
+		return row, errors.New("name is required")
+	}
+	if row.Website != "" && !strings.HasPrefix(row.Website, "http") {
+		return row, fmt.Errorf("website must start with http: %q", row.Website)
+	}
+
+	latRaw, lngRaw := get("lat"), get("lng")
+	if (latRaw == "") != (lngRaw == "") {
+		return row, errors.New("lat and lng must be given together")
+	}
+	if latRaw != "" {
+		lat, err1 := strconv.ParseFloat(latRaw, 64)
+		lng, err2 := strconv.ParseFloat(lngRaw, 64)
+		if err1 != nil || err2 != nil || lat < -90 || lat > 90 || lng < -180 || lng > 180 {
+			return row, fmt.Errorf("invalid coordinates: %s, %s", latRaw, lngRaw)
+		}
+		row.Lat, row.Lng, row.HasGeo = lat, lng, true
+	}
+	if row.Address == "" && !row.HasGeo {
+		return row, errors.New("address is required when lat/lng are missing")
+	}
+
+	if raw := get("screens"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 {
+			return row, fmt.Errorf("screens must be a positive integer: %q", raw)
+		}
+		row.Screens = n
+	}
+
+	for _, tag := range strings.FieldsFunc(get("tags"), func(r rune) bool { return r == ',' || r == '|' || r == '、' }) {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			row.Tags = append(row.Tags, tag)
+		}
+	}
+	return row, nil
+}
+
+// upsertImportedCinema 按 NameJP 新建或更新影院，所有导入字段记为 manual 来源。
+func upsertImportedCinema(row cinemaCSVRow) (bool, error) {
+	lat, lng, geoStatus, geoProvider := row.Lat, row.Lng, GeoStatusExact, ""
+	if !row.HasGeo {
+		// 未配置联系邮箱时不请求 Nominatim
+		if osmContactEmail() == "" {
+			return false, fmt.Errorf("missing lat/lng: %w", errOSMContactMissing)
+		}
+		lat, lng, geoStatus, geoProvider = getCoordsFromOSMWithRetry(cleanAddressForGeo(row.Address), row.Name)
+	}
+
+	var existing Cinema
+	err := db.Where("name_jp = ?", row.Name).First(&existing).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, err
+	}
+	created := errors.Is(err, gorm.ErrRecordNotFound)
+
+	cinema := existing
+	cinema.NameJP = row.Name
+	cinema.Source = CinemaSourceManual
+	fields := []string{"name_jp", "source"}
+	if cinema.NameKana == "" {
+		cinema.NameKana = deriveNameKana("", row.Name)
+	}
+	if row.Address != "" {
+		cinema.Address = row.Address
+		fields = append(fields, "address")
+	}
+	if row.Website != "" {
+		cinema.Website = row.Website
+		fields = append(fields, "website")
+	}
+	if len(row.Tags) > 0 {
+		cinema.Tags = strings.Join(row.Tags, ",")
+		fields = append(fields, "tags")
+	}
+	if row.Screens > 0 {
+		cinema.ScreenCount = row.Screens
+		fields = append(fields, "screen_count")
+	}
+	// 坐标：CSV 给出的坐标总是采用；自动定位的结果只在质量不低于现有坐标时采用
+	if row.HasGeo || created || geoStatusRank(geoStatus) >= geoStatusRank(cinema.GeoStatus) {
+		cinema.Latitude, cinema.Longitude, cinema.GeoStatus = lat, lng, geoStatus
+		cinema.GeocodeFailed = geoStatus == GeoStatusFailed
+		cinema.GeoProvider = geoProvider
+		if row.HasGeo {
+			fields = append(fields, "latitude", "longitude", "geo_status", "geocode_failed", "geo_provider")
+		} else {
+			recordProvenance(&cinema.ProvenanceJSON, geoProviderSource(geoProvider), "latitude", "longitude", "geo_status", "geocode_failed", "geo_provider")
+		}
+	}
+	recordProvenance(&cinema.ProvenanceJSON, SourceManual, fields...)
+	cinema.UpdatedAt = time.Now()
+
+	return created, db.Save(&cinema).Error
+}
+
+// importCinemasFromCSV 读取 CSV 并逐行导入，返回新建 / 更新数量与行级错误。
+func importCinemasFromCSV(path string) (int, int, []cinemaImportError, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, 0, nil, err
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = -1
+	headerRow, err := r.Read()
+	if err != nil {
+		return 0, 0, nil, fmt.Errorf("read header: %w", err)
+	}
+	header := make(map[string]int, len(headerRow))
+	for i, col := range headerRow {
+		header[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(col, "\ufeff")))] = i
+	}
+	if _, ok := header["name"]; !ok {
+		return 0, 0, nil, errors.New("csv header must contain a name column")
+	}
+
+	created, updated := 0, 0
+	var rowErrors []cinemaImportError
+	for line := 2; ; line++ {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			rowErrors = append(rowErrors, cinemaImportError{Line: line, Err: err})
+			continue
+		}
+		row, err := parseCinemaCSVRow(line, header, record)
+		if err != nil {
+			rowErrors = append(rowErrors, cinemaImportError{Line: line, Name: row.Name, Err: err})
+			continue
+		}
+		isNew, err := upsertImportedCinema(row)
+		if err != nil {
+			rowErrors = append(rowErrors, cinemaImportError{Line: line, Name: row.Name, Err: err})
+			continue
+		}
+		if isNew {
+			created++
+			fmt.Printf("   ➕ 新增影院: %s\n", row.Name)
+		} else {
+			updated++
+			fmt.Printf("   🔄 更新影院: %s\n", row.Name)
+		}
+	}
+	return created, updated, rowErrors, nil
+}
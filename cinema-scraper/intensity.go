@@ -0,0 +1,156 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// ===========================
+// 模块：影院排片密度（programming_intensity）
+// 职责：作为“影院有多忙”的近似指标，衡量每块银幕每天排多少场
+// - 统计最近 intensityWindowDays 天（含今天，热表 + 归档表）的场次，除以有排片的天数与银幕数
+// - 银幕数优先用 Cinema.ScreenCount（CSV 导入的 screens 列）；未知时按同一天场次的最大重叠数估算
+//   （开场时间 + 片长，片长未知按 intensityDefaultRuntime），这是银幕数的下限
+// - 结果写回 Cinema.ProgrammingIntensity，由 recompute-similarity 命令一并重算；
+//   /api/cinemas?sort=intensity 按它降序
+// 说明：单厅每天 4 场 = 4.0，12 厅的影城每天 60 场 = 5.0；计算逻辑为纯函数，便于单独验证。
+// ===========================
+
+const (
+	intensityWindowDays     = 7   // 统计窗口（天，含今天）
+	intensityDefaultRuntime = 120 // 片长未知时按 120 分钟估算占用时间
+)
+
+// intensityShow 统计用的一个场次。
+type intensityShow struct {
+	Day     string // YYYY-MM-DD
+	Start   int    // 开场分钟数（深夜场可超过 24*60）
+	Runtime int    // 片长（分钟），<= 0 表示未知
+}
+
+// estimateScreenCount 按同一天场次的最大重叠数估算银幕数（纯函数），至少为 1。
+func estimateScreenCount(shows []intensityShow) int {
+	type edge struct {
+		at    int
+		delta int
+	}
+	byDay := make(map[string][]edge)
+	for _, s := range shows {
+		runtime := s.Runtime
+		if runtime <= 0 {
+			runtime = intensityDefaultRuntime
+		}
+		byDay[s.Day] = append(byDay[s.Day], edge{s.Start, 1}, edge{s.Start + runtime, -1})
+	}
+	best := 1
+	for _, edges := range byDay {
+		// 同一时刻先结束再开始：上一场散场后紧接着开场不算重叠
+		sort.Slice(edges, func(i, j int) bool {
+			if edges[i].at != edges[j].at {
+				return edges[i].at < edges[j].at
+			}
+			return edges[i].delta < edges[j].delta
+		})
+		cur := 0
+		for _, e := range edges {
+			cur += e.delta
+			best = max(best, cur)
+		}
+	}
+	return best
+}
+
+// programmingIntensity 每块银幕每天的场次（纯函数）：screens <= 0 时按重叠估算，没有场次时为 0。
+// 结果保留两位小数。
+func programmingIntensity(shows []intensityShow, screens int) float64 {
+	if len(shows) == 0 {
+		return 0
+	}
+	if screens <= 0 {
+		screens = estimateScreenCount(shows)
+	}
+	days := make(map[string]struct{})
+	for _, s := range shows {
+		days[s.Day] = struct{}{}
+	}
+	v := float64(len(shows)) / float64(len(days)) / float64(screens)
+	return math.Round(v*100) / 100
+}
+
+// loadIntensityShows 读取 [from, to] 内各影院的场次（热表 + 归档表），附带片长。
+func loadIntensityShows(from, to string) (map[uint][]intensityShow, error) {
+	type row struct {
+		CinemaID  uint
+		MovieID   uint
+		Day       string
+		StartTime string
+	}
+	var rows []row
+	for _, model := range []interface{}{&Schedule{}, &ScheduleArchive{}} {
+		var part []row
+		if err := db.Model(model).
+			Select("cinema_id, movie_id, date(play_date) AS day, start_time").
+			Where("date(play_date) >= ? AND date(play_date) <= ?", from, to).
+			Scan(&part).Error; err != nil {
+			return nil, err
+		}
+		rows = append(rows, part...)
+	}
+
+	movieIDs := make([]uint, 0)
+	seen := make(map[uint]struct{})
+	for _, r := range rows {
+		if _, ok := seen[r.MovieID]; !ok {
+			seen[r.MovieID] = struct{}{}
+			movieIDs = append(movieIDs, r.MovieID)
+		}
+	}
+	runtimes := make(map[uint]int)
+	if len(movieIDs) > 0 {
+		var movies []Movie
+		if err := db.Select("id", "runtime").Where("id IN ?", movieIDs).Find(&movies).Error; err != nil {
+			return nil, err
+		}
+		for _, m := range movies {
+			runtimes[m.ID] = m.Runtime
+		}
+	}
+
+	shows := make(map[uint][]intensityShow)
+	for _, r := range rows {
+		start := startTimeMinutes(r.StartTime)
+		if start < 0 {
+			continue
+		}
+		shows[r.CinemaID] = append(shows[r.CinemaID], intensityShow{Day: r.Day, Start: start, Runtime: runtimes[r.MovieID]})
+	}
+	return shows, nil
+}
+
+// recomputeProgrammingIntensity 重算截至 today 的排片密度并写回影院表，返回有排片的影院数。
+// 窗口内没有场次的影院写 0；UpdateColumn 不更新 UpdatedAt，避免被当作影院信息变更。
+func recomputeProgrammingIntensity(today time.Time) (int, error) {
+	to := today.Format("2006-01-02")
+	from := today.AddDate(0, 0, -(intensityWindowDays - 1)).Format("2006-01-02")
+	shows, err := loadIntensityShows(from, to)
+	if err != nil {
+		return 0, fmt.Errorf("统计影院场次失败: %v", err)
+	}
+	var cinemas []Cinema
+	if err := db.Select("id", "screen_count").Find(&cinemas).Error; err != nil {
+		return 0, fmt.Errorf("读取影院失败: %v", err)
+	}
+	active := 0
+	for _, cin := range cinemas {
+		v := programmingIntensity(shows[cin.ID], cin.ScreenCount)
+		if len(shows[cin.ID]) > 0 {
+			active++
+		}
+		if err := db.Model(&Cinema{}).Where("id = ?", cin.ID).UpdateColumn("programming_intensity", v).Error; err != nil {
+			return active, fmt.Errorf("写入影院 %d 排片密度失败: %v", cin.ID, err)
+		}
+	}
+	return active, nil
+}
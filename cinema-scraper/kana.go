@@ -0,0 +1,234 @@
+package main
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	"gorm.io/gorm"
+)
+
+// ===========================
+// 模块：影院名读音（かな）处理
+// 职责：从页面抓取 / 推导影院名的平假名读音，并提供五十音排序与分组（あ行 / か行 ...）
+// 说明：
+// - 优先使用页面上的 ruby(rt) 或标题括号中的ふりがな。
+// - 没有读音时，纯假名名称直接做片假名 -> 平假名转换；含汉字 / 拉丁字母的名称按内置词表（kanjiReadings，
+//   东京影院名中常见的地名、用词与品牌名）最长匹配逐段转换；词表覆盖不到时回退为原名（不留空）。
+//   没有引入形态素解析库（如 kagome），新出现的地名需要补进词表。
+// - 没有假名读音（回退为原名）的影院排序时落在最后，并按原名排序；五十音分组归入“他”。
+// ===========================
+
+// kanaOnlyRe 匹配仅由假名、长音符与空白组成的字符串。
+var kanaOnlyRe = regexp.MustCompile(`^[\p{Hiragana}\p{Katakana}ー・\s　]+$`)
+
+// isKanaOnly 判断字符串是否为纯假名（可作为读音使用）。
+func isKanaOnly(s string) bool {
+	return s != "" && kanaOnlyRe.MatchString(s)
+}
+
+// toHiragana 将片假名转换为平假名，并去掉空白与中点，其他字符原样保留。
+func toHiragana(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r >= 'ァ' && r <= 'ヶ':
+			b.WriteRune(r - 0x60)
+		case unicode.IsSpace(r) || r == '・':
+			continue
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// kanjiReadings 影院名中常见的汉字 / 拉丁字母片段及其平假名读音（最长匹配，地名优先写完整）。
+var kanjiReadings = map[string]string{
+	// 地名
+	"新宿": "しんじゅく", "渋谷": "しぶや", "池袋": "いけぶくろ", "上野": "うえの", "銀座": "ぎんざ", "有楽町": "ゆうらくちょう",
+	"日比谷": "ひびや", "日本橋": "にほんばし", "丸の内": "まるのうち", "六本木": "ろっぽんぎ", "品川": "しながわ", "目黒": "めぐろ",
+	"神保町": "じんぼうちょう", "早稲田": "わせだ", "大塚": "おおつか", "大森": "おおもり", "平和島": "へいわじま", "木場": "きば",
+	"錦糸町": "きんしちょう", "亀有": "かめあり", "西新井": "にしあらい", "船堀": "ふなぼり", "豊洲": "とよす", "台場": "だいば",
+	"東中野": "ひがしなかの", "阿佐ヶ谷": "あさがや", "阿佐ケ谷": "あさがや", "吉祥寺": "きちじょうじ", "下北沢": "しもきたざわ",
+	"下高井戸": "しもたかいど", "代官山": "だいかんやま", "二子玉川": "ふたこたまがわ", "宮下": "みやした", "板橋": "いたばし",
+	"大泉": "おおいずみ", "立川": "たちかわ", "立飛": "たちひ", "府中": "ふちゅう", "調布": "ちょうふ", "昭島": "あきしま",
+	"南大沢": "みなみおおさわ", "多摩": "たま", "村山": "むらやま", "日の出": "ひので", "東京都": "とうきょうと", "東京": "とうきょう",
+	// 用词
+	"映画": "えいが", "劇場": "げきじょう", "名画座": "めいがざ", "文芸坐": "ぶんげいざ", "新文芸坐": "しんぶんげいざ",
+	"武蔵野館": "むさしのかん", "会館": "かいかん", "館": "かん", "座": "ざ", "国立": "こくりつ", "文化": "ぶんか",
+	"写真": "しゃしん", "美術館": "びじゅつかん", "東劇": "とうげき", "松竹": "しょうちく", "角川": "かどかわ",
+	// 品牌
+	"TOHO": "とうほう", "MOVIX": "むーびっくす", "HUMAX": "ひゅーまっくす", "109": "いちまるきゅう", "Bunkamura": "ぶんかむら",
+	"T・ジョイ": "てぃーじょい",
+}
+
+// kanjiReadingMaxLen kanjiReadings 中最长键的字符数。
+var kanjiReadingMaxLen = func() int {
+	n := 0
+	for k := range kanjiReadings {
+		if l := len([]rune(k)); l > n {
+			n = l
+		}
+	}
+	return n
+}()
+
+// readingFromDictionary 按 kanjiReadings 最长匹配把名称转为平假名（纯函数）：假名原样（片假名转平假名），
+// 空白与中点跳过；出现词表覆盖不到的字符时返回 false。
+func readingFromDictionary(name string) (string, bool) {
+	runes := []rune(strings.TrimSpace(name))
+	var b strings.Builder
+	for i := 0; i < len(runes); {
+		matched := false
+		for l := min(kanjiReadingMaxLen, len(runes)-i); l > 0; l-- {
+			if kana, ok := kanjiReadings[string(runes[i:i+l])]; ok {
+				b.WriteString(kana)
+				i += l
+				matched = true
+				break
+			}
+		}
+		if matched {
+			continue
+		}
+		r := runes[i]
+		switch {
+		case unicode.Is(unicode.Hiragana, r), r >= 'ァ' && r <= 'ヶ', r == 'ー':
+			b.WriteString(toHiragana(string(r)))
+		case unicode.IsSpace(r) || r == '・':
+		default:
+			return "", false
+		}
+		i++
+	}
+	return b.String(), b.Len() > 0
+}
+
+// deriveNameKana 根据页面提供的读音候选与影院名推导 NameKana，推导不出读音时返回原名。
+// rubyText：页面 ruby/ふりがな 或标题括号中的内容；nameJP：清洗后的影院名。
+func deriveNameKana(rubyText, nameJP string) string {
+	rubyText = strings.TrimSpace(rubyText)
+	if isKanaOnly(rubyText) {
+		return toHiragana(rubyText)
+	}
+	if isKanaOnly(nameJP) {
+		return toHiragana(nameJP)
+	}
+	if kana, ok := readingFromDictionary(nameJP); ok {
+		return kana
+	}
+	return strings.TrimSpace(nameJP)
+}
+
+// hasKanaReading NameKana 是否为真正的假名读音（而不是回退的原名）。
+func hasKanaReading(kana string) bool {
+	return isKanaOnly(kana)
+}
+
+// backfillCinemaKana 为读音为空或仍为原名的影院按词表推导读音，返回更新数（在迁移事务 conn 上执行）。
+func backfillCinemaKana(conn *gorm.DB) (int, error) {
+	var cinemas []Cinema
+	if err := conn.Select("id", "name_jp", "name_kana").Where("name_kana IS NULL OR name_kana = '' OR name_kana = name_jp").Find(&cinemas).Error; err != nil {
+		return 0, err
+	}
+	updated := 0
+	for _, cin := range cinemas {
+		kana := deriveNameKana("", cin.NameJP)
+		if kana == "" || kana == cin.NameKana {
+			continue
+		}
+		if err := conn.Model(&Cinema{}).Where("id = ?", cin.ID).UpdateColumn("name_kana", kana).Error; err != nil {
+			return updated, err
+		}
+		updated++
+	}
+	return updated, nil
+}
+
+// kanaRows 五十音各行：标题字 + 成员（含浊音 / 半浊音 / 小写假名）。
+var kanaRows = []struct {
+	header  string
+	members string
+}{
+	{"あ", "ぁあぃいぅうゔぇえぉお"},
+	{"か", "かがきぎくぐけげこご"},
+	{"さ", "さざしじすずせぜそぞ"},
+	{"た", "ただちぢっつづてでとど"},
+	{"な", "なにぬねの"},
+	{"は", "はばぱひびぴふぶぷへべぺほぼぽ"},
+	{"ま", "まみむめも"},
+	{"や", "ゃやゅゆょよ"},
+	{"ら", "らりるれろ"},
+	{"わ", "ゎわゐゑをん"},
+}
+
+// kanaRowHeader 返回读音首字所属的五十音行标题（あ / か / さ ...），无法归类时返回 "他"。
+func kanaRowHeader(kana string) string {
+	for _, r := range kana {
+		for _, row := range kanaRows {
+			if strings.ContainsRune(row.members, r) {
+				return row.header
+			}
+		}
+		break
+	}
+	return "他"
+}
+
+// sortCinemas 按指定键对影院排序：kana / name / district / intensity（排片密度降序），未知键保持原顺序。
+func sortCinemas(cinemas []Cinema, key string) {
+	switch key {
+	case "kana":
+		sort.SliceStable(cinemas, func(i, j int) bool {
+			ki, kj := cinemas[i].NameKana, cinemas[j].NameKana
+			if hi, hj := hasKanaReading(ki), hasKanaReading(kj); hi != hj {
+				// 有假名读音的排在前面，回退为原名的排在最后
+				return hi
+			}
+			if ki != kj {
+				return ki < kj
+			}
+			return cinemas[i].NameJP < cinemas[j].NameJP
+		})
+	case "name":
+		sort.SliceStable(cinemas, func(i, j int) bool {
+			return cinemas[i].NameJP < cinemas[j].NameJP
+		})
+	case "district":
+		sort.SliceStable(cinemas, func(i, j int) bool {
+			di, dj := extractDistrict(cinemas[i].Address), extractDistrict(cinemas[j].Address)
+			if di != dj {
+				return di < dj
+			}
+			return cinemas[i].NameJP < cinemas[j].NameJP
+		})
+	case "intensity":
+		sort.SliceStable(cinemas, func(i, j int) bool {
+			if cinemas[i].ProgrammingIntensity != cinemas[j].ProgrammingIntensity {
+				return cinemas[i].ProgrammingIntensity > cinemas[j].ProgrammingIntensity
+			}
+			return cinemas[i].NameJP < cinemas[j].NameJP
+		})
+	}
+}
+
+// CinemaKanaGroup 用于 /api/cinemas?group=kana 的分组输出。
+type CinemaKanaGroup struct {
+	Header string       `json:"header"`
+	Items  []CinemaItem `json:"items"`
+}
+
+// groupCinemasByKana 将（已按 kana 排序的）影院列表按五十音行分组。
+func groupCinemasByKana(items []CinemaItem) []CinemaKanaGroup {
+	groups := make([]CinemaKanaGroup, 0)
+	for _, it := range items {
+		header := kanaRowHeader(it.Kana)
+		if n := len(groups); n == 0 || groups[n-1].Header != header {
+			groups = append(groups, CinemaKanaGroup{Header: header, Items: []CinemaItem{}})
+		}
+		groups[len(groups)-1].Items = append(groups[len(groups)-1].Items, it)
+	}
+	return groups
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"testing"
+)
+
+// TestDeriveNameKana 影院名读音：含汉字 / 品牌名的名称按词表推导，词表覆盖不到时回退为原名，页面注音优先。
+func TestDeriveNameKana(t *testing.T) {
+	setTestClock(t, beforeMidnight)
+
+	tests := []struct{ ruby, name, want string }{
+		{"", "新宿武蔵野館", "しんじゅくむさしのかん"},
+		{"", "TOHOシネマズ 日比谷", "とうほうしねまずひびや"},
+		{"", "ユナイテッド・シネマ アクアシティお台場", "ゆないてっどしねまあくあしてぃおだいば"},
+		{"", "東京都写真美術館", "とうきょうとしゃしんびじゅつかん"},
+		{"", "ラピュタ阿佐ケ谷", "らぴゅたあさがや"},
+		{"", "シネマヴェーラ渋谷", "しねまゔぇーらしぶや"},
+		{"", "早稲田テスト劇場", "わせだてすとげきじょう"},
+		{"", "kino cinema 立川髙島屋S.C.館", "kino cinema 立川髙島屋S.C.館"},
+		{"", " K's cinema ", "K's cinema"},
+		{"", "高田馬場テスト座", "高田馬場テスト座"},
+		{"ワセダショウチク", "早稲田松竹", "わせだしょうちく"},
+		{"しんじゅく", "新宿ピカデリー", "しんじゅく"},
+	}
+	for _, tt := range tests {
+		if err := expectEqual(tt.name, deriveNameKana(tt.ruby, tt.name), tt.want); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 回退为原名的影院排在有假名读音的影院之后，分组为“他”
+	cinemas := []Cinema{
+		{NameJP: "K's cinema", NameKana: deriveNameKana("", "K's cinema")},
+		{NameJP: "高田馬場テスト座", NameKana: deriveNameKana("", "高田馬場テスト座")},
+		{NameJP: "新宿武蔵野館", NameKana: deriveNameKana("", "新宿武蔵野館")},
+		{NameJP: "早稲田テスト劇場", NameKana: deriveNameKana("", "早稲田テスト劇場")},
+	}
+	sortCinemas(cinemas, "kana")
+	names := make([]string, 0, len(cinemas))
+	items := make([]CinemaItem, 0, len(cinemas))
+	for _, cin := range cinemas {
+		names = append(names, cin.NameJP)
+		items = append(items, mapCinemaToItem(cin))
+	}
+	headers := make([]string, 0)
+	for _, g := range groupCinemasByKana(items) {
+		headers = append(headers, g.Header)
+	}
+	if err := firstError(
+		expectEqual("kana order", names, []string{"新宿武蔵野館", "早稲田テスト劇場", "K's cinema", "高田馬場テスト座"}),
+		expectEqual("groups", headers, []string{"さ", "わ", "他"})); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ===========================
+// 模块：界面语言协商（lang / Accept-Language）
+// 职责：
+// - 显式的 ?lang=ja|en|zh 优先；否则按 Accept-Language 的 q 值选出我们有内容的语言
+// - 解析结果写入请求上下文，序列化时据此选择默认标题（MovieItem.title 等）
+// - 都无法匹配时保持原有的中文优先行为（CN -> EN -> JP）
+// 说明：title_cn / title_en 等原有字段含义不变，语言只影响“默认展示”的字段。
+// ===========================
+
+// 支持的语言（主语言子标签）。
+const (
+	LangJA = "ja"
+	LangEN = "en"
+	LangZH = "zh"
+)
+
+const langKey = "lang"
+
+// supportedLangs q 值相同时的优先顺序：日文内容最全，其次英文、中文。
+var supportedLangs = []string{LangJA, LangEN, LangZH}
+
+// normalizeLangTag 取语言标签的主子标签（ja-JP -> ja），不支持时返回空串（纯函数）。
+func normalizeLangTag(tag string) string {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if i := strings.IndexAny(tag, "-_"); i != -1 {
+		tag = tag[:i]
+	}
+	for _, l := range supportedLangs {
+		if tag == l {
+			return l
+		}
+	}
+	return ""
+}
+
+// parseAcceptLanguage 按 q 值选出支持的语言，q 相同时按 supportedLangs 顺序（纯函数）。
+// q=0、非法 q 值、通配符 * 与不支持的语言都忽略；没有可用语言时返回空串。
+func parseAcceptLanguage(header string) string {
+	best, bestQ, bestRank := "", 0.0, len(supportedLangs)
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(part, ";")
+		lang := normalizeLangTag(tag)
+		if lang == "" {
+			continue
+		}
+		q := 1.0
+		if params = strings.TrimSpace(params); params != "" {
+			v, ok := strings.CutPrefix(strings.ReplaceAll(params, " ", ""), "q=")
+			if !ok {
+				continue
+			}
+			parsed, err := strconv.ParseFloat(v, 64)
+			if err != nil || parsed < 0 || parsed > 1 {
+				continue
+			}
+			q = parsed
+		}
+		if q == 0 {
+			continue
+		}
+		rank := langRank(lang)
+		if q > bestQ || (q == bestQ && rank < bestRank) {
+			best, bestQ, bestRank = lang, q, rank
+		}
+	}
+	return best
+}
+
+// langRank 语言在 supportedLangs 中的位置。
+func langRank(lang string) int {
+	for i, l := range supportedLangs {
+		if l == lang {
+			return i
+		}
+	}
+	return len(supportedLangs)
+}
+
+// languageMiddleware 解析 ?lang= 与 Accept-Language 并写入请求上下文；?lang= 不支持时返回 400。
+func languageMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// 响应随 Accept-Language 变化，缓存需要区分
+		c.Writer.Header().Add("Vary", "Accept-Language")
+
+		lang := ""
+		if raw := c.Query("lang"); raw != "" {
+			lang = normalizeLangTag(raw)
+			if lang == "" {
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "unsupported lang, expected ja, en or zh"})
+				return
+			}
+		} else {
+			lang = parseAcceptLanguage(c.GetHeader("Accept-Language"))
+		}
+		if lang != "" {
+			c.Set(langKey, lang)
+			c.Header("Content-Language", lang)
+		}
+		c.Next()
+	}
+}
+
+// requestLang 当前请求解析出的语言；未指定或不支持时为空串（中文优先的默认行为）。
+func requestLang(c *gin.Context) string {
+	return c.GetString(langKey)
+}
+
+// localizedTitle 按语言选择默认标题，缺失时依次回退，全部为空时返回空串（纯函数）。
+// - ja：JP -> CN -> EN
+// - en：EN -> JP -> CN
+// - 其他（默认）：CN -> EN -> JP
+func localizedTitle(m Movie, lang string) string {
+	var order []string
+	switch lang {
+	case LangJA:
+		order = []string{m.TitleJP, m.TitleCN, m.TitleEN}
+	case LangEN:
+		order = []string{m.TitleEN, m.TitleJP, m.TitleCN}
+	default:
+		order = []string{m.TitleCN, m.TitleEN, m.TitleJP}
+	}
+	for _, t := range order {
+		if t = strings.TrimSpace(t); t != "" {
+			return t
+		}
+	}
+	return ""
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestLanguageSelection 界面语言：Accept-Language 按 q 值选支持的语言，q 相同按 ja / en / zh，?lang= 优先且非法值 400。
+func TestLanguageSelection(t *testing.T) {
+	setTestClock(t, beforeMidnight)
+
+	headers := []struct{ header, want string }{
+		{"", ""},
+		{"ja-JP", LangJA},
+		{"zh-CN,zh;q=0.9,en;q=0.8", LangZH},
+		{"fr-FR,fr;q=0.9,en-US;q=0.8,ja;q=0.7", LangEN},
+		{"en;q=0.5, ja;q=0.8", LangJA},
+		{"zh;q=0.8,en;q=0.8", LangEN},   // q 相同按 supportedLangs 顺序
+		{"ja;q=0,en;q=0.1", LangEN},     // q=0 表示不接受
+		{"ja;q=abc,zh;q=0.3", LangZH},   // 非法 q 值忽略
+		{"ja;q=1.5,en;q = 0.4", LangEN}, // 超出范围的 q 忽略，空格容忍
+		{"*,ko;q=0.9", ""},              // 通配符与不支持的语言
+		{"EN_gb;q=0.9", LangEN},
+		{"ja;level=1", ""}, // 非 q 参数视为无法解析
+	}
+	for _, tt := range headers {
+		if err := expectEqual(fmt.Sprintf("%q", tt.header), parseAcceptLanguage(tt.header), tt.want); err != nil {
+			t.Fatal(err)
+		}
+	}
+	engine := gin.New()
+	engine.Use(languageMiddleware())
+	engine.GET("/lang", func(c *gin.Context) { c.String(http.StatusOK, requestLang(c)) })
+	serve := func(target, header string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		req.Header.Set("Accept-Language", header)
+		engine.ServeHTTP(rec, req)
+		return rec
+	}
+	negotiated := serve("/lang", "en;q=0.5, ja;q=0.8")
+	explicit := serve("/lang?lang=zh-TW", "ja")
+	invalid := serve("/lang?lang=ko", "ja")
+	if err := firstError(
+		expectEqual("negotiated", negotiated.Body.String()+" "+negotiated.Header().Get("Content-Language"), "ja ja"),
+		expectEqual("vary", negotiated.Header().Get("Vary"), "Accept-Language"),
+		expectEqual("explicit", explicit.Body.String(), LangZH),
+		expectEqual("invalid", invalid.Code, http.StatusBadRequest)); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ===========================
+// 模块：上映周数与长映标记（long run）
+// 职责：
+// - 每部影片维护 first_seen / last_seen 摘要（各影院放映跨度汇总中最早/最晚的排片日期，见 runrollup.go）
+// - 摘要由 update-status 重算时维护，只向外扩展不收缩：排片被清理后历史跨度仍然保留
+// - API 据此给出 weeks_in_release，超过 longRunWeeks 且仍在排片的影片带 long_run 标记
+// ===========================
+
+// longRunWeeks 达到该上映周数（含首周）且仍有排片的影片视为长映。
+const longRunWeeks = 12
+
+// movieScheduleRange 某部影片全部排片的首末日期（YYYY-MM-DD）。
+type movieScheduleRange struct {
+	MovieID uint
+	First   string
+	Last    string
+}
+
+// loadAllMovieScheduleRanges 按影片统计排片首末日期（取自放映跨度汇总，含已清理的排片），按 MovieID 升序。
+func loadAllMovieScheduleRanges() ([]movieScheduleRange, error) {
+	var out []movieScheduleRange
+	err := db.Model(&CinemaRunSummary{}).
+		Select(`movie_id, MIN(first_date) AS "first", MAX(last_date) AS "last"`).
+		Where("first_date <> ''").
+		Group("movie_id").
+		Order("movie_id").
+		Scan(&out).Error
+	return out, err
+}
+
+// widenSeenExtent 把排片跨度合并进已有摘要（纯函数）：first 只会提前，last 只会推后。
+// 返回 false 表示摘要无需变更；日期与 PlayDate 一致按 UTC 零点存储。
+func widenSeenExtent(first, last *time.Time, r movieScheduleRange) (*time.Time, *time.Time, bool) {
+	newFirst, errFirst := time.Parse("2006-01-02", r.First)
+	newLast, errLast := time.Parse("2006-01-02", r.Last)
+	changed := false
+	if errFirst == nil && (first == nil || newFirst.Before(*first)) {
+		first = &newFirst
+		changed = true
+	}
+	if errLast == nil && (last == nil || newLast.After(*last)) {
+		last = &newLast
+		changed = true
+	}
+	return first, last, changed
+}
+
+// refreshMovieSeenExtents 用当前排片（含归档）扩展每部影片的 first_seen / last_seen，返回更新的影片数。
+func refreshMovieSeenExtents() (int, error) {
+	ranges, err := loadAllMovieScheduleRanges()
+	if err != nil {
+		return 0, fmt.Errorf("统计排片跨度失败: %v", err)
+	}
+	updated := 0
+	err = db.Transaction(func(tx *gorm.DB) error {
+		for _, r := range ranges {
+			var movie Movie
+			if err := tx.Select("id", "first_seen", "last_seen").First(&movie, r.MovieID).Error; err != nil {
+				continue // 排片指向已删除的影片，忽略
+			}
+			first, last, changed := widenSeenExtent(movie.FirstSeen, movie.LastSeen, r)
+			if !changed {
+				continue
+			}
+			if err := tx.Model(&Movie{}).Where("id = ?", movie.ID).
+				Updates(map[string]interface{}{"first_seen": first, "last_seen": last}).Error; err != nil {
+				return err
+			}
+			updated++
+		}
+		return nil
+	})
+	return updated, err
+}
+
+// weeksInRelease 从 first_seen 到今天（已停映则到 last_seen）的上映周数，首周记为第 1 周（纯函数）。
+// 没有摘要或首映在未来时返回 0。
+func weeksInRelease(first, last *time.Time, today time.Time) int {
+	if first == nil {
+		return 0
+	}
+	start := civilDate(*first)
+	end := civilDate(today)
+	if last != nil && civilDate(*last).Before(end) {
+		end = civilDate(*last)
+	}
+	if end.Before(start) {
+		return 0
+	}
+	days := int(end.Sub(start).Hours() / 24)
+	return days/7 + 1
+}
+
+// isLongRun 上映周数达到 longRunWeeks 且今天及以后仍有排片（纯函数）。
+func isLongRun(first, last *time.Time, today time.Time) bool {
+	if last == nil || civilDate(*last).Before(civilDate(today)) {
+		return false
+	}
+	return weeksInRelease(first, last, today) >= longRunWeeks
+}
+
+// civilDate 取东京时区的日历日期（零点，UTC 表示），便于按天相减。
+func civilDate(t time.Time) time.Time {
+	y, m, d := t.In(tokyoLocation).Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// applyLongRunFilter ?long_run=true：first_seen 不晚于 longRunWeeks 周前、last_seen 不早于今天。
+func applyLongRunFilter(tx *gorm.DB, today string) *gorm.DB {
+	t, err := time.Parse("2006-01-02", today)
+	if err != nil {
+		return tx
+	}
+	cutoff := t.AddDate(0, 0, -(longRunWeeks-1)*7).Format("2006-01-02")
+	return tx.Where("first_seen IS NOT NULL AND date(first_seen) <= ? AND date(last_seen) >= ?", cutoff, today)
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -10,68 +11,152 @@ import (
 	"net/url"
 	"os"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	// 模块：外部依赖
 	// - colly：影院页面抓取
 	// - gin：REST API Server
 	// - gorm + sqlite：ORM 与嵌入式数据库
-	"github.com/gocolly/colly/v2"
 	"github.com/gin-gonic/gin"
+	"github.com/gocolly/colly/v2"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 )
 
-// ===========================
-// 模块：第三方电影数据源配置
-// 职责：集中管理 TMDB / OMDb 等外部接口的密钥
-// 说明：
-// - 这里先按你的旧代码直接内嵌常量，方便本地开发与演示。
-// - 如需上线，建议改为从环境变量中读取，避免明文出现在仓库中。
-// ===========================
-const (
-	TMDB_API_KEY = "9393cc205618e50033dd935732772699"
-	OMDB_API_KEY = "949a7886"
-	// 是否启用豆瓣评分抓取：
-	// - 默认关闭（false），避免触发豆瓣风控要求登录。
-	// - 如需在本地短时间测试，可以手动改为 true，但请控制请求频率。
-	ENABLE_DOUBAN_RATING = false
-)
-
 type Cinema struct {
 	ID            uint   `gorm:"primaryKey"`
 	NameJP        string `gorm:"uniqueIndex"`
+	NameKana      string // 平假名读音，用于五十音排序（见 kana.go）
 	Address       string
 	Latitude      float64
 	Longitude     float64
 	BuildingPhoto string
-	Website       string
-	UpdatedAt     time.Time
+	// 建筑照片缓存图片的内容哈希（/img/:hash），为空表示尚未缓存，见 imagecache.go
+	BuildingPhotoHash string
+	Website           string
+	ScreenCount       int    // 银幕数，0 表示未知（CSV 导入的 screens 列）
+	EigaURL           string // eiga.com 影院详情页，单馆刷新时直接访问（见 cinemarefresh.go）
+	EigaSlug          string // eiga.com 影院编号（详情页 URL 末段），抓取先按它匹配，唯一索引见 eigaids.go
+	District          string `gorm:"index"`     // 所在区，由地址推导并在保存时同步（见 district.go）
+	Prefecture        string `gorm:"index"`     // 所在都道府县（如 神奈川県），由 eiga.com 地区代码或地址推导（见 prefecture.go）
+	Desc              string `gorm:"type:text"` // 影院简介：人工策展，或 enrich-cinemas 从官网 meta 补全
+	GeoStatus         string // 坐标定位质量：exact / approx / failed（见 cinemamerge.go）
+	// 定位失败：坐标为 0/0，地图不显示，fix-geocode 命令会重新尝试（见 geofix.go）
+	GeocodeFailed bool `gorm:"index"`
+	// 给出坐标的地理编码来源：nominatim / gsi；人工录入或未定位时为空串（见 gsi.go）
+	GeoProvider string
+	Tags        string // 逗号分隔，如 名画座,2本立
+	// 最近 7 天每块银幕日均场次，recompute-similarity 时重算（见 intensity.go）
+	ProgrammingIntensity float64
+	Source               string `gorm:"default:eiga"` // eiga / manual（CSV 导入，见 importcinemas.go）
+	// 官网排片抓取配置（JSON），见 customsource.go；为空表示排片来自 eiga.com
+	SourceConfig string `gorm:"type:text"`
+	// 字段来源记录（JSON），见 provenance.go
+	ProvenanceJSON string `gorm:"type:text"`
+	UpdatedAt      time.Time
 }
 
 var db *gorm.DB
 
+// migratedModels 启动时自动迁移的表（doctor 命令据此检查表结构是否最新）。
+var migratedModels = []interface{}{
+	&Cinema{}, &Movie{}, &Schedule{}, &CrawlRun{}, &ScheduleArchive{}, &MovieStatusEvent{}, &GeocodeCache{}, &CinemaSimilarity{},
+	&MovieEvent{}, &DiscoveredVenue{}, &CinemaRunSummary{}, &TMDBSearchMiss{}, &MovieTag{}, &SearchGram{},
+	&SchemaMigration{}, &Favorite{}, &ApiCache{}, &CachedImage{},
+}
+
+// openDatabase 打开 SQLite 连接并完成表迁移。
+// *gorm.DB 本身可并发使用；慢查询阈值见 slowquery.go（SLOW_QUERY_MS），单请求查询预算见 querybudget.go（DB_QUERY_BUDGET）。
+// 线上使用 appConfig.databaseDSN()（DB_PATH，见 config.go），测试使用内存数据库（见 helpers_test.go）。
+func openDatabase(dsn string) (*gorm.DB, error) {
+	slowThreshold := slowQueryThreshold()
+	conn, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: newSlowQueryLogger(slowThreshold)})
+	if err != nil {
+		return nil, err
+	}
+	if err := registerSlowQueryCallbacks(conn, slowThreshold); err != nil {
+		return nil, err
+	}
+	if err := registerQueryBudgetCallbacks(conn); err != nil {
+		return nil, err
+	}
+	// 场次唯一索引建立前先清理旧库中的重复行（见 scheduleupsert.go），再迁移表结构，最后补齐区 / 都道府县、清除兜底坐标等；
+	// 破坏性迁移执行前自动备份，同一次启动只备份一次（见 migrations.go）
+	if err := runVersionedMigrations(conn, dsn, time.Now(), preSchemaMigrations, migrateSchema, versionedMigrations); err != nil {
+		return nil, err
+	}
+	return conn, nil
+}
+
+// migrateSchema 表结构迁移：AutoMigrate 加表 / 加列 / 加索引，以及依赖新表结构的索引与键补齐。
+func migrateSchema(conn *gorm.DB) error {
+	if err := conn.AutoMigrate(migratedModels...); err != nil {
+		return err
+	}
+	if err := migrateGeocodeCacheIndex(conn); err != nil {
+		return err
+	}
+	if err := migrateEigaKeys(conn); err != nil {
+		return err
+	}
+	return migrateSearchIndex(conn)
+}
+
 func main() {
 	var err error
 
+	// API Key / 数据库路径 / 端口等配置来自环境变量，见 config.go
+	appConfig = mustLoadConfig()
+	loadQueryBudgetConfig()
+	if hasFlag(os.Args[1:], "--print-config") {
+		fmt.Println("⚙️ 生效的配置（密钥已打码）：")
+		for _, line := range configLines(appConfig) {
+			fmt.Println("   " + line)
+		}
+		return
+	}
+
+	// doctor 在迁移之前运行，才能如实报告数据库是否可写、表结构是否最新
+	if len(os.Args) > 1 && os.Args[1] == "doctor" {
+		os.Exit(runDoctor())
+	}
+	// rollback-to 替换数据库文件，必须在打开（并迁移）数据库之前执行
+	if len(os.Args) > 1 && os.Args[1] == "rollback-to" {
+		os.Exit(runRollbackTo(os.Args[2:]))
+	}
+
 	// ===========================
 	// 模块：数据库初始化
 	// 职责：建立 SQLite 连接并完成基础表迁移
 	// ===========================
-	db, err = gorm.Open(sqlite.Open("tokyo_cinepath.db"), &gorm.Config{})
+	db, err = openDatabase(appConfig.databaseDSN())
 	if err != nil {
 		log.Fatal(err)
 	}
-	db.AutoMigrate(&Cinema{}, &Movie{}, &Schedule{})
+	// 状态阈值（Soon 窗口 / 下映宽限 / 旧片年数），见 statusrules.go
+	if statusConfig, err = loadStatusConfig(os.Args[1:]); err != nil {
+		log.Fatalf("invalid status thresholds: %v", err)
+	}
 
-	// 如果是首次运行，为 Movie / Schedule 表插入少量种子数据，便于前端对接与开发调试。
-	if err := seedInitialMovies(); err != nil {
-		log.Fatalf("seed movies failed: %v", err)
+	// 开发用种子数据：只在显式指定 --seed 且库为空时写入，便于前端对接与开发调试（见 seeddata.go）
+	if hasFlag(os.Args[1:], "--seed") {
+		if err := seedDevDatabase(); err != nil {
+			log.Fatalf("seed dev data failed: %v", err)
+		}
 	}
-	if err := seedInitialSchedules(); err != nil {
-		log.Fatalf("seed schedules failed: %v", err)
+	if n, err := ensureRunSummaries(); err != nil {
+		log.Fatalf("backfill run summaries failed: %v", err)
+	} else if n > 0 {
+		fmt.Printf("🎞️ 已由现有排片补建 %d 条影院放映跨度汇总\n", n)
+	}
+	if n, err := classifyUnlabeledMovies(); err != nil {
+		log.Fatalf("classify movie kinds failed: %v", err)
+	} else if n > 0 {
+		fmt.Printf("🎤 已将 %d 部片名含直播 / 中继关键词的作品标记为活动（kind=event）\n", n)
 	}
 
 	// ===========================
@@ -79,23 +164,136 @@ func main() {
 	// 职责：
 	// - 默认模式：仅启动 HTTP API Server，方便前端开发调试。
 	// - 命令模式：
-	//     - `go run . crawl-cinemas`    只执行影院基础信息抓取
-	//     - `go run . crawl-schedules`  只执行排片信息抓取
+	//     - `go run . crawl-cinemas`    只执行影院基础信息抓取（需设置 OSM_CONTACT_EMAIL 或 --osm-email=，见 geocode.go；
+	//                                   地址未变的影院使用地理编码缓存，--refresh-geo 跳过缓存重新定位；
+	//                                   --area 14 或 --area 13,14,11 覆盖 CRAWL_AREA，见 prefecture.go）
+	//     - `go run . fix-geocode`      只对定位失败（geocode_failed）的影院重新定位（同样需要联系邮箱，见 geofix.go）
+	//     - `go run . crawl-schedules`  只执行排片信息抓取（--weeks=N 向后翻 N 周，默认 1，最多 4；
+	//                                   --min-ratio=0.5 场次数低于上次该比例时判定为异常抓取；
+	//                                   --area 同 crawl-cinemas；--no-prune 不清理已从 eiga.com 消失的场次，见 eigaprune.go；
+	//                                   新片事件发往 EVENT_WEBHOOK_URL，见 movieevents.go；不请求 TMDB / OMDb，资料由 enrich-movies 补全）
+	//     - `go run . enrich-movies`    补全资料不全的影片（TMDB / OMDb / 豆瓣，--workers=N 并发，默认 3；
+	//                                   --force 资料齐全的也重新补全，--id 42 只处理一部，见 enrichmovies.go；
+	//                                   TMDB 搜不到的影片按退避间隔重试，见 enrichretry.go）
+	//     - `go run . list-unenriched`  列出 TMDB 补全失败的影片（日文片名、失败次数与原因、下次重试时间）
+	//     - `go run . set-tmdb-id 42 12345` 为影片手动指定 TMDB ID 并立即重新补全（之后不再按片名搜索）
 	//     - `go run . fill-douban`      单独补全缺失的豆瓣评分（不会重复抓排片）
+	//     - `go run . fix-release-dates` 修复上映日期为零值的旧数据
+	//     - `go run . import-cinemas x.csv` 从 CSV 补录影院（name,address,website,lat,lng,tags；缺坐标的行需配置 OSM 联系邮箱）
+	//     - `go run . enrich-cinemas`   从影院官网补全简介（og:description）与兜底照片（og:image）
+	//     - `go run . crawl-custom`     按 source_config 从影院官网抓取排片（CSS 选择器 / iCal）
+	//     - `go run . digest --week 2026-W05` 生成周报草稿（--format=md|json，--out=文件；默认输出到 stdout）
+	//     - `go run . update-status`    根据排片批量更新影片状态（最近一次抓取异常或被中断时需加 --force；
+	//                                   --soon-days= / --leaving-days= / --revival-years= 覆盖状态阈值，见 statusrules.go）
+	//     - `go run . recompute-similarity` 按最近 60 天排片重算影院相似度，并重算排片密度（--as-of=YYYY-MM-DD 回算）
+	//     - `go run . purge-deleted`    物理删除软删除超过保留期的影片（--days=N，默认 30）
+	//     - `go run . prune-schedules`  把早于 --keep-days 天前（默认 14，JST）的排片移入归档表，随后更新影片状态
+	//                                   （--dry-run 只统计不修改；最近一次抓取异常时需加 --force 才更新状态，见 archive.go）
+	//     - `go run . merge-duplicate-movies` 合并 TMDB / IMDb ID 相同的同片异名影片（抓取结束后也会自动执行）
+	//     - `go run . reindex-search`   全量重建管理端搜索索引 search_grams（平时由保存钩子维护，见 adminsearch.go）
+	//     - `go run . backfill-run-summaries` 由热表与归档表重建各影片在各影院的放映跨度汇总（见 runrollup.go）
+	//     - `go run . refresh-images`   向上游条件请求重新校验已缓存的海报与影院照片，内容变化时改指向新哈希（见 imagecache.go）
+	//     - `go run . rollback-to <备份>` 服务停止后用破坏性迁移前的自动备份覆盖数据库（旧库另存，见 migrations.go）
+	//     - `go run . doctor`           检查运行环境（数据库、API Key、外网连通性、时区数据），有失败项时非零退出
+	//     - `go run . --read-only`      以只读维护模式启动 API（运行中可通过 /api/admin/maintenance 切换）
+	//     - `go run . --seed`           空库时写入开发用种子数据（15 家影院、40 部影片、两周排片）后启动 API，见 seeddata.go
+	//     - `go run . --print-config`   打印生效的配置（环境变量 + 默认值，密钥打码）后退出，见 config.go
+	//     - `go run . --with-scheduler` 启动 API 的同时定时抓取排片并更新状态（间隔取 CRAWL_INTERVAL，默认 6h，见 crawlscheduler.go）
+	// - Ctrl+C / SIGTERM：API 等处理中的请求完成后退出；crawl-cinemas / crawl-schedules 写完当前页面后停止，
+	//   以退出码 130 结束（区别于失败的 1），见 shutdown.go
 	// ===========================
 	if len(os.Args) > 1 {
 		switch os.Args[1] {
 		case "crawl-cinemas":
+			if err := configureOSMContact(os.Args[2:]); err != nil {
+				log.Fatalf("crawl-cinemas refused: %v", err)
+			}
+			if err := applyCrawlAreaFlag(os.Args[2:]); err != nil {
+				log.Fatalf("crawl-cinemas refused: %v", err)
+			}
+			geocodeRefresh.Store(hasFlag(os.Args[2:], "--refresh-geo"))
 			fmt.Println("🚀 [crawl-cinemas] 影院数据深度抓取中 (清洗地址 + 过滤图片)...")
-			syncCinemasBetter()
+			ctx, stop := interruptContext()
+			defer stop()
+			syncErr := syncCinemasBetter(ctx)
+			printGeocodeSummary()
+			if errors.Is(syncErr, errInterrupted) {
+				fmt.Println("🛑 [crawl-cinemas] 抓取被中断，已处理的影院已保存，程序退出。")
+				os.Exit(exitCodeInterrupted)
+			}
 			fmt.Println("✅ [crawl-cinemas] 抓取完成，程序退出。")
 			return
+		case "fix-geocode":
+			if err := configureOSMContact(os.Args[2:]); err != nil {
+				log.Fatalf("fix-geocode refused: %v", err)
+			}
+			fmt.Println("📍 [fix-geocode] 重新定位之前定位失败的影院...")
+			fixed, failed, err := fixFailedGeocodes()
+			if err != nil {
+				log.Fatalf("fix-geocode failed: %v", err)
+			}
+			fmt.Printf("✅ [fix-geocode] 完成：定位成功 %d 家，仍然失败 %d 家，程序退出。\n", fixed, failed)
+			return
 		case "crawl-schedules":
-			fmt.Println("🎞️ [crawl-schedules] 影院排片抓取中 (影片 + 场次)...")
-			if err := syncSchedulesFromEiga(); err != nil {
-				log.Fatalf("crawl-schedules failed: %v", err)
+			if err := applyCrawlAreaFlag(os.Args[2:]); err != nil {
+				log.Fatalf("crawl-schedules aborted: %v", err)
+			}
+			scheduleLookaheadWeeks = parseWeeksFlag(os.Args[2:])
+			schedulePruneEnabled = !hasFlag(os.Args[2:], "--no-prune")
+			fmt.Printf("🎞️ [crawl-schedules] 影院排片抓取中 (影片 + 场次，向后 %d 周)...\n", scheduleLookaheadWeeks)
+			// Ctrl+C / SIGTERM 后不再访问新页面，当前页面写完后照常收尾（见 shutdown.go）
+			ctx, stop := interruptContext()
+			defer stop()
+			_, syncErr := runScheduleCrawl(ctx, parseMinRatioFlag(os.Args[2:]))
+			if errors.Is(syncErr, errInterrupted) {
+				fmt.Println("🛑 [crawl-schedules] 抓取被中断，已抓到的影院排片已保存，程序退出。")
+				os.Exit(exitCodeInterrupted)
+			}
+			if syncErr != nil {
+				log.Fatalf("crawl-schedules failed: %v", syncErr)
+			}
+			fmt.Println("✅ [crawl-schedules] 排片抓取完成，程序退出（新片资料请运行 enrich-movies 补全）。")
+			return
+		case "enrich-movies":
+			if err := appConfig.requireTMDB(); err != nil {
+				log.Fatalf("enrich-movies aborted: %v", err)
+			}
+			if err := runEnrichMovies(os.Args[2:]); err != nil {
+				log.Fatalf("enrich-movies failed: %v", err)
+			}
+			return
+		case "list-unenriched":
+			if err := runListUnenriched(); err != nil {
+				log.Fatalf("list-unenriched failed: %v", err)
+			}
+			return
+		case "set-tmdb-id":
+			if err := runSetTmdbID(os.Args[2:]); err != nil {
+				log.Fatalf("set-tmdb-id failed: %v", err)
 			}
-			fmt.Println("✅ [crawl-schedules] 排片抓取完成，程序退出。")
+			return
+		case "enrich-cinemas":
+			fmt.Println("🏛️ [enrich-cinemas] 从影院官网补全简介与照片...")
+			updated, failed, err := enrichCinemasFromWebsites()
+			if err != nil {
+				log.Fatalf("enrich-cinemas failed: %v", err)
+			}
+			fmt.Printf("✅ [enrich-cinemas] 补全完成：更新 %d 家，失败 %d 家，程序退出。\n", updated, failed)
+			return
+		case "crawl-custom":
+			fmt.Println("🏛️ [crawl-custom] 从影院官网抓取排片（手动补录的影院）...")
+			run, err := startCrawlRun("custom")
+			if err != nil {
+				log.Fatalf("crawl-custom failed: %v", err)
+			}
+			written, syncErr := syncCustomSchedules()
+			runAutoMergeAfterCrawl()
+			run.ParsedCount = written
+			finishCrawlRun(run, syncErr)
+			if syncErr != nil {
+				log.Fatalf("crawl-custom failed: %v", syncErr)
+			}
+			fmt.Printf("✅ [crawl-custom] 官网排片抓取完成：写入 %d 个场次，程序退出。\n", written)
 			return
 		case "fill-douban":
 			fmt.Println("📚 [fill-douban] 开始为缺失豆瓣评分的影片补全评分（仅按英文名 + 年份查询）...")
@@ -104,8 +302,127 @@ func main() {
 			}
 			fmt.Println("✅ [fill-douban] 豆瓣评分补全任务完成，程序退出。")
 			return
+		case "fix-release-dates":
+			if err := appConfig.requireTMDB(); err != nil {
+				log.Fatalf("fix-release-dates aborted: %v", err)
+			}
+			fmt.Println("📅 [fix-release-dates] 开始修复缺失上映日期的影片...")
+			exact, approx, err := fixZeroReleaseDates()
+			if err != nil {
+				log.Fatalf("fix-release-dates failed: %v", err)
+			}
+			fmt.Printf("✅ [fix-release-dates] 修复完成：精确日期 %d 部，按年份兜底 %d 部，程序退出。\n", exact, approx)
+			return
+		case "import-cinemas":
+			if len(os.Args) < 3 {
+				log.Fatalf("usage: go run . import-cinemas <file.csv>")
+			}
+			if err := configureOSMContact(os.Args[3:]); err != nil {
+				fmt.Printf("ℹ️ 未启用 OSM 定位（%v），缺少坐标的行将报错。\n", err)
+			}
+			fmt.Printf("📥 [import-cinemas] 开始从 %s 导入影院...\n", os.Args[2])
+			created, updated, rowErrors, err := importCinemasFromCSV(os.Args[2])
+			if err != nil {
+				log.Fatalf("import-cinemas failed: %v", err)
+			}
+			for _, re := range rowErrors {
+				fmt.Printf("   ❌ 第 %d 行 [%s]: %v\n", re.Line, re.Name, re.Err)
+			}
+			fmt.Printf("✅ [import-cinemas] 导入完成：新增 %d，更新 %d，失败 %d 行，程序退出。\n", created, updated, len(rowErrors))
+			return
+		case "digest":
+			// 周报正文输出到 stdout，进度提示走 stderr，便于直接重定向
+			fmt.Fprintln(os.Stderr, "📰 [digest] 生成每周摘要...")
+			if err := runDigestCommand(os.Args[2:]); err != nil {
+				log.Fatalf("digest failed: %v", err)
+			}
+			fmt.Fprintln(os.Stderr, "✅ [digest] 周报生成完成，程序退出。")
+			return
+		case "recompute-similarity":
+			fmt.Printf("🧮 [recompute-similarity] 开始按最近 %d 天的排片计算影院相似度...\n", similarityWindowDays)
+			written, err := runRecomputeSimilarityCommand(os.Args[2:])
+			if err != nil {
+				log.Fatalf("recompute-similarity failed: %v", err)
+			}
+			fmt.Printf("✅ [recompute-similarity] 计算完成：写入 %d 条相似度，程序退出。\n", written)
+			return
+		case "purge-deleted":
+			days, err := parseRetentionDaysFlag(os.Args[2:])
+			if err != nil {
+				log.Fatalf("purge-deleted failed: %v", err)
+			}
+			fmt.Printf("🧹 [purge-deleted] 开始物理删除软删除超过 %d 天的影片...\n", days)
+			purged, err := purgeDeletedMovies(time.Now(), days)
+			if err != nil {
+				log.Fatalf("purge-deleted failed: %v", err)
+			}
+			fmt.Printf("✅ [purge-deleted] 清理完成：物理删除 %d 部影片，程序退出。\n", purged)
+			return
+		case "prune-schedules":
+			keepDays, err := parseKeepDaysFlag(os.Args[2:], statusConfig.LeavingDays)
+			if err != nil {
+				log.Fatalf("prune-schedules refused: %v", err)
+			}
+			cutoff := scheduleKeepCutoff(todayJST(), keepDays)
+			if hasFlag(os.Args[2:], "--dry-run") {
+				n, err := countSchedulesBefore(cutoff)
+				if err != nil {
+					log.Fatalf("prune-schedules failed: %v", err)
+				}
+				fmt.Printf("🔍 [prune-schedules] dry-run：将清理 %s 之前的 %d 个场次（保留 %d 天），未做任何修改。\n", cutoff, n, keepDays)
+				return
+			}
+			fmt.Printf("🧹 [prune-schedules] 清理 %s 之前的排片（保留 %d 天，移入归档表）...\n", cutoff, keepDays)
+			pruned, err := archiveSchedulesBefore(cutoff)
+			if err != nil {
+				log.Fatalf("prune-schedules failed: %v", err)
+			}
+			fmt.Printf("🗑️ 已清理 %d 个场次\n", pruned)
+			if blocked, run := latestCrawlBlocksStatusUpdate(); blocked && !hasFlag(os.Args[2:], "--force") {
+				fmt.Printf("⚠️ 最近一次抓取 #%d 为 %s（%s），跳过状态更新；确认无误后运行 update-status --force\n", run.ID, run.Status, run.Error)
+			} else if err := updateMovieStatusFromSchedules(); err != nil {
+				log.Fatalf("prune-schedules: update status failed: %v", err)
+			}
+			fmt.Println("✅ [prune-schedules] 完成，程序退出。")
+			return
+		case "merge-duplicate-movies":
+			fmt.Println("🔗 [merge-duplicate-movies] 合并 TMDB / IMDb ID 相同的重复影片...")
+			merged, err := autoMergeDuplicateMovies()
+			if err != nil {
+				log.Fatalf("merge-duplicate-movies failed: %v", err)
+			}
+			fmt.Printf("✅ [merge-duplicate-movies] 合并完成：合并掉 %d 部影片，程序退出。\n", merged)
+			return
+		case "reindex-search":
+			fmt.Println("🔎 [reindex-search] 全量重建管理端搜索索引...")
+			movies, cinemas, err := rebuildSearchIndex(db)
+			if err != nil {
+				log.Fatalf("reindex-search failed: %v", err)
+			}
+			fmt.Printf("✅ [reindex-search] 重建完成：影片 %d 部、影院 %d 家，程序退出。\n", movies, cinemas)
+			return
+		case "refresh-images":
+			fmt.Println("🖼️ [refresh-images] 重新校验已缓存的海报与影院照片...")
+			changed, err := refreshCachedImages(time.Now(), 0)
+			if err != nil {
+				log.Fatalf("refresh-images failed: %v", err)
+			}
+			fmt.Printf("✅ [refresh-images] 校验完成：%d 张图片有更新，程序退出。\n", changed)
+			return
+		case "backfill-run-summaries":
+			fmt.Println("🎞️ [backfill-run-summaries] 由现有排片重建影院放映跨度汇总...")
+			n, err := backfillRunSummaries()
+			if err != nil {
+				log.Fatalf("backfill-run-summaries failed: %v", err)
+			}
+			fmt.Printf("✅ [backfill-run-summaries] 重建完成：写入 %d 条汇总，程序退出。\n", n)
+			return
 		case "update-status":
 			fmt.Println("🔄 [update-status] 开始根据排片日期批量更新电影状态...")
+			fmt.Printf("⚙️ [update-status] 生效的状态阈值：%s\n", statusConfig)
+			if blocked, run := latestCrawlBlocksStatusUpdate(); blocked && !hasFlag(os.Args[2:], "--force") {
+				log.Fatalf("update-status aborted: latest crawl run #%d is %s (%s); rerun with --force to override", run.ID, run.Status, run.Error)
+			}
 			if err := updateMovieStatusFromSchedules(); err != nil {
 				log.Fatalf("update-status failed: %v", err)
 			}
@@ -119,260 +436,570 @@ func main() {
 	// 职责：启动 Gin 服务，暴露 RESTful 接口给前端调用
 	// ===========================
 	gin.SetMode(gin.ReleaseMode)
+	if hasFlag(os.Args[1:], "--read-only") {
+		setReadOnly(true)
+		fmt.Println("🛠️ 以只读维护模式启动：拒绝写入与管理操作")
+	}
 	router := setupRouter()
-	fmt.Println("🌐 API server listening on :8080")
-	if err := router.Run(":8080"); err != nil {
+	// SIGINT / SIGTERM 时等待处理中的请求完成后再退出（见 shutdown.go）
+	ctx, stop := interruptContext()
+	defer stop()
+	// 定时抓取（CRAWL_INTERVAL 或 --with-scheduler），见 crawlscheduler.go
+	if interval := schedulerInterval(appConfig, os.Args[1:]); interval > 0 {
+		startCrawlScheduler(ctx, interval)
+		fmt.Printf("⏰ 已启用定时抓取：每 %s 抓取排片并更新状态\n", interval)
+	}
+	fmt.Printf("🌐 API server listening on :%s\n", appConfig.Port)
+	if err := serveUntilInterrupted(ctx, router, ":"+appConfig.Port, serverShutdownTimeout); err != nil {
 		log.Fatal(err)
 	}
+	waitCrawlScheduler()
+	fmt.Println("👋 API server 已停止。")
+}
+
+// eigaCinemaPage eiga.com 影院详情页上解析出的字段。
+type eigaCinemaPage struct {
+	NameJP        string
+	NameKana      string
+	Address       string // 原始地址（未清洗）
+	BuildingPhoto string
+	Website       string
 }
 
-func syncCinemasBetter() {
+// eigaPageTitle 读取 h1.page-title 的文字：ruby 注音（<rt> / <rp>）不算进影院名，
+// 否则 <ruby>新宿武蔵野館<rt>しんじゅくむさしのかん</rt></ruby> 会读成“新宿武蔵野館しんじゅくむさしのかん”。
+func eigaPageTitle(e *colly.HTMLElement) string {
+	title := e.DOM.Find("h1.page-title").First().Clone()
+	title.Find("rt, rp").Remove()
+	return strings.TrimSpace(title.Text())
+}
+
+// parseEigaCinemaPage 解析影院详情页的 <main>；没有影院名（不是详情页）时返回 false。
+// crawl-cinemas 与单馆刷新（cinemarefresh.go）共用。
+func parseEigaCinemaPage(e *colly.HTMLElement) (eigaCinemaPage, bool) {
+	rawName := eigaPageTitle(e)
+	if rawName == "" {
+		return eigaCinemaPage{}, false
+	}
+	nameJP := regexp.MustCompile(`（.*?）`).ReplaceAllString(rawName, "")
+
+	// 0. 获取读音：优先 ruby 注音，其次标题括号中的ふりがな
+	rubyText := e.ChildText("h1.page-title rt")
+	if rubyText == "" {
+		if m := regexp.MustCompile(`（(.*?)）`).FindStringSubmatch(rawName); len(m) == 2 {
+			rubyText = m[1]
+		}
+	}
+
+	// 1. 获取图片：排除包含 shared, banner, ad, coupon 等关键字的图
+	var realImg string
+	e.ForEach("img", func(_ int, img *colly.HTMLElement) {
+		src := img.Attr("src")
+		// 只有包含 theater 或 photo 路径的通常才是真正的建筑图
+		if strings.Contains(src, "/theater/") && !strings.Contains(src, "shared") && realImg == "" {
+			realImg = src
+		}
+	})
+
+	// 2. 获取影院官方页面链接：映画館情報・割引情報表格中的「映画館公式ページ」
+	website := strings.TrimSpace(e.ChildAttr("a.icon.official", "href"))
+	if website != "" && !strings.HasPrefix(website, "http") {
+		website = e.Request.AbsoluteURL(website)
+	}
+
+	return eigaCinemaPage{
+		NameJP:        nameJP,
+		NameKana:      deriveNameKana(rubyText, nameJP),
+		Address:       strings.TrimSpace(e.ChildText(".location dd")),
+		BuildingPhoto: realImg,
+		Website:       website,
+	}, true
+}
+
+// syncCinemasBetter 抓取地区列表页与各影院详情页，写入 Cinema；ctx 取消后不再访问新页面，返回 errInterrupted。
+func syncCinemasBetter(ctx context.Context) error {
 	c := colly.NewCollector(colly.AllowedDomains("eiga.com"))
 	detailC := c.Clone()
+	stopCollectorsOn(ctx, c, detailC)
 
 	detailC.OnHTML("main", func(e *colly.HTMLElement) {
-		rawName := e.ChildText("h1.page-title")
-		if rawName == "" {
+		defer recoverAndLog("影院详情页 " + e.Request.URL.String())
+		page, ok := parseEigaCinemaPage(e)
+		if !ok {
 			return
 		}
-		nameJP := regexp.MustCompile(`（.*?）`).ReplaceAllString(rawName, "")
-
-		// 1. 获取图片：排除包含 shared, banner, ad, coupon 等关键字的图
-		var realImg string
-		e.ForEach("img", func(_ int, img *colly.HTMLElement) {
-			src := img.Attr("src")
-			// 只有包含 theater 或 photo 路径的通常才是真正的建筑图
-			if strings.Contains(src, "/theater/") && !strings.Contains(src, "shared") && realImg == "" {
-				realImg = src
-			}
-		})
-
-		// 2. 获取影院官方页面链接：映画館情報・割引情報表格中的「映画館公式ページ」
-		website := strings.TrimSpace(e.ChildAttr("a.icon.official", "href"))
-		if website != "" && !strings.HasPrefix(website, "http") {
-			website = e.Request.AbsoluteURL(website)
-		}
+		nameJP, address := page.NameJP, page.Address
+		realImg := page.BuildingPhoto
 		// 控制台打印：影院详情页 URL 与官方站点 URL
-		fmt.Printf("🔗 影院详情页: %s\n   官方站点: %s\n", e.Request.URL.String(), website)
+		fmt.Printf("🔗 影院详情页: %s\n   官方站点: %s\n", e.Request.URL.String(), page.Website)
 
-		// 3. 获取地址并清洗
+		// 3. 地址清洗
 		// 原始地址: 東京都新宿区新宿3-15-15 新宿ピカデリー内
 		// 清洗后: 東京都新宿区新宿3-15-15
-		address := strings.TrimSpace(e.ChildText(".location dd"))
 		cleanAddr := cleanAddressForGeo(address)
 
 		// 4. 获取唯一经纬度 (带重试逻辑和清洗)
-		lat, lng := getCoordsFromOSMWithRetry(cleanAddr, nameJP)
+		lat, lng, geoStatus, geoProvider := getCoordsFromOSMWithRetry(cleanAddr, nameJP)
 
-		cinema := Cinema{
+		scraped := Cinema{
 			NameJP:        nameJP,
+			NameKana:      page.NameKana,
 			Address:       address,
 			Latitude:      lat,
 			Longitude:     lng,
+			GeoStatus:     geoStatus,
+			GeocodeFailed: geoStatus == GeoStatusFailed,
+			GeoProvider:   geoProvider,
 			BuildingPhoto: realImg,
-			Website:       website,
+			Website:       page.Website,
+			EigaURL:       e.Request.URL.String(),
+			EigaSlug:      eigaTheaterSlug(e.Request.URL.String()),
 			UpdatedAt:     time.Now(),
 		}
 
-		db.Where(Cinema{NameJP: nameJP}).Assign(cinema).FirstOrCreate(&cinema)
+		// 5. 写入：新影院直接创建；已有影院按合并策略只更新“变好”的字段（见 cinemamerge.go）。
+		//    字段来源：页面字段来自 eiga.com，坐标来自 OSM 或 GSI。
+		//    先按影院编号匹配（日文名改动也能命中），旧数据按日文名匹配（见 eigaids.go）。
+		var existing Cinema
+		if scraped.EigaSlug != "" {
+			db.Where("eiga_slug = ?", scraped.EigaSlug).Limit(1).Find(&existing)
+		}
+		if existing.ID == 0 {
+			db.Where("name_jp = ?", nameJP).Limit(1).Find(&existing)
+		}
+		if existing.ID == 0 {
+			recordProvenance(&scraped.ProvenanceJSON, SourceEiga, "name_jp", "name_kana", "address", "building_photo", "website", "eiga_url", "eiga_slug")
+			recordProvenance(&scraped.ProvenanceJSON, geoProviderSource(geoProvider), "latitude", "longitude", "geo_status", "geocode_failed", "geo_provider")
+			if err := db.Create(&scraped).Error; err != nil {
+				fmt.Printf("⚠️ 创建影院失败 [%s]: %v\n", nameJP, err)
+			}
+		} else if updates := mergeCinemaFields(existing, scraped); len(updates) > 0 {
+			eigaFields, geoFields := mergedCinemaFields(updates)
+			provenance := existing.ProvenanceJSON
+			recordProvenance(&provenance, SourceEiga, eigaFields...)
+			recordProvenance(&provenance, geoProviderSource(geoProvider), geoFields...)
+			updates["provenance_json"] = provenance
+			updates["updated_at"] = time.Now()
+			if err := db.Model(&existing).Updates(updates).Error; err != nil {
+				fmt.Printf("⚠️ 更新影院失败 [%s]: %v\n", nameJP, err)
+			}
+		} else {
+			fmt.Printf("   ℹ️ [%s] 无字段需要更新\n", nameJP)
+		}
 
 		fmt.Printf("📍 [%s]\n   地址: %s\n   坐标: %.5f, %.5f\n   图片: %s\n\n", nameJP, cleanAddr, lat, lng, realImg)
 
-		// 必须严格遵守频率限制，否则 OSM 会封锁你返回一模一样的默认坐标
-		time.Sleep(2 * time.Second)
+		// OSM 的 1 次/秒由 osmLimiter 保证（见 geocode.go）；这里控制对 eiga.com 的访问节奏（收到退出信号时不再等待）
+		select {
+		case <-time.After(2 * time.Second):
+		case <-ctx.Done():
+		}
 	})
 
 	c.OnHTML(".theater-area-list a", func(e *colly.HTMLElement) {
 		link := e.Request.AbsoluteURL(e.Attr("href"))
 		fmt.Printf("🧭 列表入口链接: %s\n", link)
-		if strings.Contains(link, "/theater/13/") {
+		// 范围外的链接（邻县 / 特殊会场）也记录下来，见 discoveredvenues.go
+		if recordDiscoveredVenue(link, e.Text) {
 			detailC.Visit(link)
 		}
 	})
 
-	c.Visit("https://eiga.com/theater/13/")
+	fmt.Printf("🗾 抓取地区：%s\n", describeCrawlAreas(appConfig.crawlAreas()))
+	for _, areaURL := range appConfig.eigaAreaURLs() {
+		if err := c.Visit(areaURL); err != nil {
+			fmt.Printf("⚠️ 访问地区列表页失败 [%s]: %v\n", areaURL, err)
+		}
+	}
+	return interruptedErr(ctx)
 }
 
-// ===========================
-// 模块：排片同步（Movies + Schedules）
-// 职责：从 eiga.com 的影院详情页抓取影片与场次，写入 Movie / Schedule 表
-// 调用方式：`go run . crawl-schedules`
-// ===========================
+// handleEigaSchedulePage 影院排片页回调：解析各影片区块并写入场次，按需翻到下一周，最后一周解析完后清理消失的场次。
+// 同一影院各周次的状态保存在请求 Context 中；不同影院的回调可以并发执行，共享的计数与集合均为并发安全。
+func handleEigaSchedulePage(e *colly.HTMLElement, previousCounts map[uint]int) {
+	defer recoverAndLog("排片页 " + e.Request.URL.String())
+	rawName := eigaPageTitle(e)
+	if rawName == "" {
+		reportParseAnomaly(ParseAnomaly{
+			URL:    e.Request.URL.String(),
+			Reason: "cinema name not found",
+		}, e.Response.Body)
+		return
+	}
+	nameJP := regexp.MustCompile(`（.*?）`).ReplaceAllString(rawName, "")
+
+	// 翻页状态保存在请求 Context 中（同一影院的后续周次共享）：
+	// week 为当前周序号（0 为首页），seenDates 为之前周次已抓到的日期。
+	week, _ := e.Request.Ctx.GetAny("week").(int)
+	seenDates, _ := e.Request.Ctx.GetAny("seen_dates").(map[string]bool)
+	slots, _ := e.Request.Ctx.GetAny("slots").(*eigaCinemaSlots)
+	if seenDates == nil {
+		seenDates = make(map[string]bool)
+		slots = &eigaCinemaSlots{seen: make(map[string]bool)}
+		e.Request.Ctx.Put("seen_dates", seenDates)
+		e.Request.Ctx.Put("slots", slots)
+	}
+	pageDates := make(map[string]bool)
+	// 计数器可能被并发回调访问，统一用原子操作
+	parsedCount, _ := e.Request.Ctx.GetAny("parsed_count").(*atomic.Int64)
+	if parsedCount == nil {
+		parsedCount = new(atomic.Int64)
+		e.Request.Ctx.Put("parsed_count", parsedCount)
+		e.Request.Ctx.Put("first_url", e.Request.URL.String())
+		e.Request.Ctx.Put("first_body", e.Response.Body)
+	}
 
-func syncSchedulesFromEiga() error {
-	// 复用 theater/13 列表页，遍历所有影院详情链接
-	c := colly.NewCollector(colly.AllowedDomains("eiga.com"))
-	detailC := c.Clone()
+	fmt.Printf("🎬 抓取影院排片: %s（第 %d 周）\n   详情页: %s\n", nameJP, week+1, e.Request.URL.String())
 
-	// 影院详情页：抓取影片与场次
-	detailC.OnHTML("main", func(e *colly.HTMLElement) {
-		rawName := e.ChildText("h1.page-title")
-		if rawName == "" {
+	// 在数据库中找到对应的 Cinema：先按影院编号，旧数据按日文名（见 eigaids.go）
+	cinema, err := findEigaCinema(eigaTheaterSlug(e.Request.URL.String()), nameJP)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			fmt.Printf("⚠️ 未在数据库中找到影院记录，跳过排片: %s\n", nameJP)
 			return
 		}
-		nameJP := regexp.MustCompile(`（.*?）`).ReplaceAllString(rawName, "")
+		fmt.Printf("⚠️ 查询影院失败 [%s]: %v\n", nameJP, err)
+		return
+	}
 
-		fmt.Printf("🎬 抓取影院排片: %s\n   详情页: %s\n", nameJP, e.Request.URL.String())
+	// 每个 section#mXXXXXX 对应一部影片及其一周排片
+	e.ForEach("section[id^=m]", func(_ int, sec *colly.HTMLElement) {
+		rawTitle := strings.TrimSpace(sec.ChildText("h2 a"))
+		// 单部影片解析异常（如 CastJSON 损坏）不影响同一影院的其他影片；
+		// 但该影片的场次没有记入 slots.seen，本影院不能再清理消失的场次
+		defer recoverAndMark(fmt.Sprintf("影片区块 [%s] %s", nameJP, rawTitle), func() { slots.incomplete = true })
+		titleJP := NormalizeTitle(rawTitle)
+		if titleJP == "" {
+			return
+		}
 
-		// 在数据库中找到对应的 Cinema（按日文名匹配）
-		var cinema Cinema
-		if err := db.Where("name_jp = ?", nameJP).First(&cinema).Error; err != nil {
-			if errors.Is(err, gorm.ErrRecordNotFound) {
-				fmt.Printf("⚠️ 未在数据库中找到影院记录，跳过排片: %s\n", nameJP)
-				return
-			}
-			fmt.Printf("⚠️ 查询影院失败 [%s]: %v\n", nameJP, err)
+		// 1. 确保 Movie 存在：先按 eiga.com 影片编号，旧数据按规范化后的 TitleJP（见 eigaids.go）
+		movie, err := findOrCreateEigaMovie(eigaMovieID(sec.Attr("id"), sec.ChildAttr("h2 a", "href")), rawTitle)
+		if errors.Is(err, errMovieDeleted) {
 			return
 		}
+		if err != nil {
+			slots.incomplete = true
+			fmt.Printf("⚠️ 查询或创建影片失败 [%s]: %v\n", titleJP, err)
+			return
+		}
+
+		// 记录 eiga.com 标注的片长，补全时用于校验 TMDB 匹配
+		captureEigaRuntime(&movie, parseEigaRuntime(sec.Text))
+		// 页面标注了制作年份时记下，TMDB 搜索据此区分老片与翻拍（见 tmdbmatch.go）
+		// 抓取时只建影片行，不请求外部接口；资料由之后的 enrich-movies 补全（见 enrichmovies.go）
+		captureEigaYear(&movie, parseEigaYear(sec.Text))
+
+		// 排片表下方的脚注说明（※ / ※1 ...），场次单元格中的标记据此解析为 Note
+		footnotes := parseFootnoteDefinitions(sectionFootnoteText(sec))
+
+		// 本区块的全部场次，解析完后一次批量写入
+		var showtimes []Schedule
 
-		// 每个 section#mXXXXXX 对应一部影片及其一周排片
-		e.ForEach("section[id^=m]", func(_ int, sec *colly.HTMLElement) {
-			titleJP := strings.TrimSpace(sec.ChildText("h2 a"))
-			if titleJP == "" {
+		// 2. 解析一周排片表：table.weekly-schedule > td[data-date]
+		sec.ForEach("table.weekly-schedule td[data-date]", func(_ int, td *colly.HTMLElement) {
+			dateRaw := strings.TrimSpace(td.Attr("data-date")) // 例如 20260127
+			if len(dateRaw) != 8 {
 				return
 			}
-
-			// 1. 确保 Movie 存在（按 TitleJP 去重）
-			var movie Movie
-			if err := db.Where(&Movie{TitleJP: titleJP}).First(&movie).Error; err != nil {
-				if errors.Is(err, gorm.ErrRecordNotFound) {
-					movie = Movie{
-						TitleJP: titleJP,
-						Status:  "showing",
-					}
-					if err := db.Create(&movie).Error; err != nil {
-						fmt.Printf("⚠️ 创建影片失败 [%s]: %v\n", titleJP, err)
-						return
-					}
-					fmt.Printf("   ➕ 新影片写入: %s (ID=%d)\n", titleJP, movie.ID)
-				} else {
-					fmt.Printf("⚠️ 查询影片失败 [%s]: %v\n", titleJP, err)
-					return
-				}
+			playDate, err := time.Parse("20060102", dateRaw)
+			if err != nil {
+				return
 			}
 
-			// 无论是新片还是已存在的影片，只要关键信息尚未补全，
-			// 都尝试调用外部接口（TMDB / IMDb / 豆瓣）进行一次信息聚合。
-			enrichMovieRatings(&movie)
+			// 收集排片日期（去重）；之前周次已抓过的日期不再重复处理
+			dateStr := playDate.Format("2006-01-02")
+			if seenDates[dateStr] {
+				return
+			}
+			pageDates[dateStr] = true
 
-			// 收集所有排片日期，用于判断电影状态
-			playDatesMap := make(map[string]bool) // 使用 map 去重
+			// 标记有时写在场次 span 之外；单元格只有一个场次时整格的标记都归它
+			singleShowtime := td.DOM.Find("span").Length() == 1
 
-			// 2. 解析一周排片表：table.weekly-schedule > td[data-date]
-			sec.ForEach("table.weekly-schedule td[data-date]", func(_ int, td *colly.HTMLElement) {
-				dateRaw := strings.TrimSpace(td.Attr("data-date")) // 例如 20260127
-				if len(dateRaw) != 8 {
+			// 每个 span 代表一个场次，如 "18:05～20:00" 或 "11:00"
+			td.ForEach("span", func(_ int, sp *colly.HTMLElement) {
+				markText := sp.Text
+				if singleShowtime {
+					markText = td.Text
+				}
+				note := resolveFootnoteNote(markText, footnotes)
+				text := stripFootnoteMarks(sp.Text)
+				if text == "" {
 					return
 				}
-				playDate, err := time.Parse("20060102", dateRaw)
-				if err != nil {
+				// 余票标记（満席 / △ / ○ 或对应 class）在截掉结束时间之前解析
+				availability := parseAvailability(text, sp.Attr("class")+" "+td.Attr("class"))
+				// 特别场次（舞台挨拶 / 先行上映 等）：场次单元格注释优先，其次片名中的注释
+				eventType := parseEventType(text+" "+sp.Attr("title"), rawTitle)
+				// 放映版本（字幕 / 吹替）：通常标在片名注释里，个别影院写在场次单元格
+				format := parseScreeningFormat(rawTitle, text, sp.Attr("title"))
+				// 会員限定场次：标在场次单元格或片名注释里（见 membersonly.go）
+				membersOnly := isMembersOnlyShowtime(markText+" "+sp.Attr("title"), rawTitle)
+				// 只关心开始时间，去掉 "~" 及后面的结束时间
+				if idx := strings.IndexAny(text, "～ "); idx != -1 {
+					text = text[:idx]
+				}
+				if len(text) < 4 || !strings.Contains(text, ":") {
 					return
 				}
 
-				// 收集排片日期（去重）
-				dateStr := playDate.Format("2006-01-02")
-				playDatesMap[dateStr] = true
-
-				// 每个 span 代表一个场次，如 "18:05～20:00" 或 "11:00"
-				td.ForEach("span", func(_ int, sp *colly.HTMLElement) {
-					text := strings.TrimSpace(sp.Text)
-					if text == "" {
-						return
-					}
-					// 只关心开始时间，去掉 "~" 及后面的结束时间
-					if idx := strings.IndexAny(text, "～ "); idx != -1 {
-						text = text[:idx]
-					}
-					if len(text) < 4 || !strings.Contains(text, ":") {
-						return
-					}
-
-					sched := Schedule{
-						MovieID:   movie.ID,
-						CinemaID:  cinema.ID,
-						PlayDate:  playDate,
-						StartTime: text,
-					}
-
-					if err := db.Where("movie_id = ? AND cinema_id = ? AND play_date = ? AND start_time = ?",
-						movie.ID, cinema.ID, playDate, text,
-					).FirstOrCreate(&sched).Error; err != nil {
-						fmt.Printf("⚠️ 写入排片失败 [%s @ %s %s]: %v\n", titleJP, nameJP, text, err)
-						return
-					}
+				showtimes = append(showtimes, Schedule{
+					MovieID:      movie.ID,
+					CinemaID:     cinema.ID,
+					PlayDate:     playDate,
+					StartTime:    text,
+					Availability: availability,
+					EventType:    eventType,
+					Format:       format,
+					Note:         note,
+					MembersOnly:  membersOnly,
 				})
 			})
-
-			// 3. 根据排片日期更新电影状态
-			// 逻辑：
-			// - 如果有今天或过去的排片 → showing
-			// - 如果所有排片都在未来：
-			//   * 最早排片在明天到未来7天内 → incoming（Soon：今天还没上映，明天开始一周内有排片）
-			//   * 最早排片在7天之后 → showing（更远的未来，暂时不算 Soon）
-			if len(playDatesMap) > 0 {
-				today := time.Now()
-				todayStr := today.Format("2006-01-02")
-				tomorrow := today.AddDate(0, 0, 1)
-				tomorrowStr := tomorrow.Format("2006-01-02")
-				sevenDaysLater := today.AddDate(0, 0, 7)
-				
-				var earliestDate *time.Time
-				hasPastOrToday := false
-				
-				// 找到最早的排片日期，并检查是否有今天或过去的排片
-				for dateStr := range playDatesMap {
-					parsedDate, err := time.Parse("2006-01-02", dateStr)
-					if err != nil {
-						continue
-					}
-					
-					if dateStr <= todayStr {
-						hasPastOrToday = true
-					}
-					
-					if earliestDate == nil || parsedDate.Before(*earliestDate) {
-						earliestDate = &parsedDate
-					}
-				}
-				
-				// 更新电影状态
-				newStatus := "showing"
-				if !hasPastOrToday && earliestDate != nil {
-					// 所有排片都在未来
-					// Soon 的定义：今天还没上映，最早排片在明天到未来7天内
-					if earliestDateStr := earliestDate.Format("2006-01-02"); earliestDateStr >= tomorrowStr {
-						// 最早排片在明天或之后
-						if earliestDate.Before(sevenDaysLater) || earliestDate.Equal(sevenDaysLater) {
-							// 最早排片在未来7天内 → incoming（Soon）
-							newStatus = "incoming"
-						}
-						// 否则：最早排片在7天之后 → showing（更远的未来）
-					}
-				}
-				
-				if movie.Status != newStatus {
-					oldStatus := movie.Status
-					movie.Status = newStatus
-					db.Model(&movie).Update("status", newStatus)
-					fmt.Printf("   🔄 更新影片状态 [%s]: %s -> %s (最早排片: %s)\n", titleJP, oldStatus, newStatus, earliestDate.Format("2006-01-02"))
+		})
+		if err := upsertShowtimes(showtimes); err != nil {
+			slots.incomplete = true
+			fmt.Printf("⚠️ 写入排片失败 [%s @ %s，%d 个场次]: %v\n", titleJP, nameJP, len(showtimes), err)
+		} else {
+			for _, s := range showtimes {
+				slots.seen[scheduleSlotKey(s.MovieID, s.PlayDate.Format("2006-01-02"), s.StartTime)] = true
+			}
+			parsedCount.Add(int64(len(showtimes)))
+			crawlParsedShowtimes.Add(int64(len(showtimes)))
+			if len(showtimes) > 0 {
+				if err := refreshRunSummaries(cinema.ID, []uint{movie.ID}); err != nil {
+					fmt.Printf("⚠️ 刷新放映跨度汇总失败 [%s @ %s]: %v\n", titleJP, nameJP, err)
 				}
 			}
-		})
+		}
+
+		// 3. 影片状态不在这里逐影院修改：全部影院抓完后按所有影院的排片统一重算（见 statusrules.go）
+		markCrawlTouchedMovie(movie.ID)
+	})
+
+	// 4. 按配置继续抓取下一周（沿用同一个 Collector，遵守相同的访问频率限制）
+	lastDate := ""
+	for d := range pageDates {
+		if d > lastDate {
+			lastDate = d
+		}
+		seenDates[d] = true
+	}
+	next := ""
+	if week+1 < scheduleLookaheadWeeks && lastDate != "" {
+		if last, err := time.Parse("2006-01-02", lastDate); err == nil {
+			next = findNextWeekLink(e, last.AddDate(0, 0, 1).Format("20060102"))
+			if next == "" {
+				fmt.Printf("   ℹ️ 未找到下一周排片链接，停止翻页: %s\n", nameJP)
+			}
+		}
+	}
+	if next == "" {
+		// 该影院的所有周次已抓完：与上次抓取对比，异常时保存首页 HTML
+		reason, bad := detectParseAnomaly(int(parsedCount.Load()), previousCounts[cinema.ID])
+		if bad {
+			firstURL, _ := e.Request.Ctx.GetAny("first_url").(string)
+			firstBody, _ := e.Request.Ctx.GetAny("first_body").([]byte)
+			reportParseAnomaly(ParseAnomaly{
+				CinemaID: cinema.ID,
+				Cinema:   nameJP,
+				URL:      firstURL,
+				Parsed:   int(parsedCount.Load()),
+				Previous: previousCounts[cinema.ID],
+				Reason:   reason,
+			}, firstBody)
+		}
+		// 清理本次覆盖日期内已从页面消失的场次（见 eigaprune.go）；解析异常或有区块失败时不清理
+		switch {
+		case !schedulePruneEnabled:
+		case bad || slots.incomplete:
+			fmt.Printf("   ⏸️ 本影院解析不完整，跳过清理消失的场次: %s\n", nameJP)
+		default:
+			dates := make([]string, 0, len(seenDates))
+			for d := range seenDates {
+				dates = append(dates, d)
+			}
+			removed, err := pruneVanishedShowtimes(cinema.ID, dates, slots, todayJST())
+			if err != nil {
+				fmt.Printf("⚠️ 清理消失的场次失败 [%s]: %v\n", nameJP, err)
+			} else {
+				crawlPrunedShowtimes.Add(int64(removed))
+				fmt.Printf("   🧹 清理 %d 个已从 eiga.com 消失的场次: %s\n", removed, nameJP)
+			}
+		}
+		return
+	}
+	e.Request.Ctx.Put("week", week+1)
+	fmt.Printf("   ⏭️ 继续抓取第 %d 周: %s\n", week+2, next)
+	e.Request.Visit(next)
+}
+
+// ===========================
+// 模块：排片同步（Movies + Schedules）
+// 职责：从 eiga.com 的影院详情页抓取影片与场次，写入 Movie / Schedule 表
+// 调用方式：`go run . crawl-schedules [--weeks=N] [--no-prune]`
+// 说明：eiga.com 周表约覆盖 8 天；部分影院会提前公布后续周次，
+//       通过日期导航继续翻页，最多 maxScheduleLookaheadWeeks 周。
+//       抓取不调用 TMDB / OMDb / 豆瓣，新片只有日文片名等页面信息，之后由 enrich-movies 补全。
+// ===========================
+
+const (
+	defaultScheduleLookaheadWeeks = 1
+	maxScheduleLookaheadWeeks     = 4
+)
+
+// scheduleLookaheadWeeks 本次抓取向后翻的周数（含当前周），由 --weeks 指定。
+var scheduleLookaheadWeeks = defaultScheduleLookaheadWeeks
+
+// parseWeeksFlag 从命令行参数中解析 --weeks=N，限制在 [1, maxScheduleLookaheadWeeks]。
+func parseWeeksFlag(args []string) int {
+	weeks := defaultScheduleLookaheadWeeks
+	if v, ok := flagValue(args, "--weeks"); ok {
+		if n, err := strconv.Atoi(v); err == nil {
+			weeks = n
+		}
+	}
+	if weeks < 1 {
+		weeks = 1
+	}
+	if weeks > maxScheduleLookaheadWeeks {
+		weeks = maxScheduleLookaheadWeeks
+	}
+	return weeks
+}
+
+// findNextWeekLink 在影院详情页的日期导航中寻找下一周的链接：
+// 优先匹配 href 中包含 nextDate（YYYYMMDD）的链接，其次匹配“次週 / 翌週”文字。
+func findNextWeekLink(e *colly.HTMLElement, nextDate string) string {
+	link := ""
+	e.ForEachWithBreak("a[href]", func(_ int, a *colly.HTMLElement) bool {
+		href := a.Attr("href")
+		text := strings.TrimSpace(a.Text)
+		if strings.Contains(href, nextDate) || strings.Contains(text, "次週") || strings.Contains(text, "翌週") {
+			link = a.Request.AbsoluteURL(href)
+			return false
+		}
+		return true
+	})
+	return link
+}
+
+// findOrCreateMovieByTitle 按规范化后的 TitleJP 查找影片，不存在则新建（状态 showing）。
+// 已被软删除的影片不会重新创建，返回 errMovieDeleted，调用方跳过其场次。
+// crawl-custom（见 customsource.go）使用；eiga 抓取先按影片编号匹配（见 eigaids.go）。
+func findOrCreateMovieByTitle(rawTitle string, source string) (Movie, error) {
+	movie, err := findMovieByTitle(rawTitle)
+	if err == nil && movie.DeletedAt.Valid {
+		return movie, errMovieDeleted
+	}
+	if err == nil {
+		return movie, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return movie, err
+	}
+	return createMovieFromTitle(rawTitle, source, "")
+}
+
+// findMovieByTitle 按规范化后的 TitleJP 查找影片（含已软删除的），找不到时返回 gorm.ErrRecordNotFound。
+// 旧数据可能存的是原始标题，规范化后查不到时兜底再查一次；最后查同片异名合并时记录下的别名（见 moviemerge.go）。
+func findMovieByTitle(rawTitle string) (Movie, error) {
+	titleJP := NormalizeTitle(rawTitle)
+	var movie Movie
+	err := db.Unscoped().Where(&Movie{TitleJP: titleJP}).First(&movie).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) && rawTitle != titleJP {
+		err = db.Unscoped().Where(&Movie{TitleJP: rawTitle}).First(&movie).Error
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return movie, err
+	}
+	for _, alias := range []string{titleJP, rawTitle} {
+		if m, ok := findMovieByAltTitle(alias); ok {
+			return m, nil
+		}
+	}
+	return movie, err
+}
+
+// createMovieFromTitle 新建影片（状态 showing），eigaID 为空表示来源没有 eiga.com 编号。
+func createMovieFromTitle(rawTitle, source, eigaID string) (Movie, error) {
+	titleJP := NormalizeTitle(rawTitle)
+	movie := Movie{
+		TitleJP: titleJP,
+		EigaID:  eigaID,
+		Kind:    classifyMovieKind(rawTitle, nonFilmKeywords()),
+		Status:  "showing",
+	}
+	recordProvenance(&movie.ProvenanceJSON, source, "title_jp", "kind", "status")
+	if eigaID != "" {
+		recordProvenance(&movie.ProvenanceJSON, source, "eiga_id")
+	}
+	if err := db.Create(&movie).Error; err != nil {
+		return movie, err
+	}
+	fmt.Printf("   ➕ 新影片写入: %s (ID=%d)\n", titleJP, movie.ID)
+	emitMovieEvent(EventMovieDiscovered, movie)
+	return movie, nil
+}
+
+// upsertShowtime 写入单个场次并返回该行：按 (影片, 影院, 日期, 开始时间) 去重，已存在时只刷新余票、场次类型与会員限定标记。
+// crawl-custom 需要场次 ID 来清理官网已下架的场次，因此逐行写入；eiga 抓取走批量的 upsertShowtimes。
+func upsertShowtime(movieID, cinemaID uint, playDate time.Time, startTime, availability, eventType, format, note string, membersOnly bool) (Schedule, error) {
+	sched := Schedule{
+		MovieID:      movieID,
+		CinemaID:     cinemaID,
+		PlayDate:     playDate,
+		StartTime:    startTime,
+		Availability: availability,
+		EventType:    eventType,
+		Format:       format,
+		Note:         note,
+		MembersOnly:  membersOnly,
+	}
+	err := db.Where("movie_id = ? AND cinema_id = ? AND play_date = ? AND start_time = ?",
+		movieID, cinemaID, playDate, startTime,
+	).Assign(map[string]interface{}{
+		"availability": availability,
+		"event_type":   eventType,
+		"format":       format,
+		"note":         note,
+		"members_only": membersOnly,
+	}).FirstOrCreate(&sched).Error
+	return sched, err
+}
+
+// syncSchedulesFromEiga 抓取各影院的影片与场次；ctx 取消后不再访问新页面（含翻页），
+// 正在处理的页面写完数据库后返回 errInterrupted。
+func syncSchedulesFromEiga(ctx context.Context) error {
+	// 复用地区列表页（默认 theater/13，见 CRAWL_AREA / --area），遍历所有影院详情链接
+	c := colly.NewCollector(colly.AllowedDomains("eiga.com"))
+	detailC := c.Clone()
+	stopCollectorsOn(ctx, c, detailC)
+
+	// 上次抓取时各影院的排片数，用于发现解析异常（见 parsedebug.go）
+	previousCounts := loadPreviousCinemaCounts()
+
+	// 影院详情页：抓取影片与场次
+	detailC.OnHTML("main", func(e *colly.HTMLElement) {
+		handleEigaSchedulePage(e, previousCounts)
 	})
 
 	// 列表页：遍历所有影院详情链接
 	c.OnHTML(".theater-area-list a", func(e *colly.HTMLElement) {
 		link := e.Request.AbsoluteURL(e.Attr("href"))
-		if strings.Contains(link, "/theater/13/") {
+		if recordDiscoveredVenue(link, e.Text) {
 			fmt.Printf("🧭 排片入口链接: %s\n", link)
 			detailC.Visit(link)
 		}
 	})
 
-	if err := c.Visit("https://eiga.com/theater/13/"); err != nil {
-		return err
+	fmt.Printf("🗾 抓取地区：%s\n", describeCrawlAreas(appConfig.crawlAreas()))
+	for _, areaURL := range appConfig.eigaAreaURLs() {
+		if err := c.Visit(areaURL); err != nil {
+			return fmt.Errorf("visit %s: %w", areaURL, err)
+		}
 	}
-	return nil
+	return interruptedErr(ctx)
 }
 
 // ===========================
@@ -407,6 +1034,7 @@ func backfillDoubanRatings() error {
 		}
 
 		m.DoubanRating = score
+		recordProvenance(&m.ProvenanceJSON, SourceDouban, "douban_rating")
 		if err := db.Save(&m).Error; err != nil {
 			fmt.Printf("⚠️ 保存豆瓣评分失败 [%s]: %v\n", m.TitleEN, err)
 			continue
@@ -425,49 +1053,150 @@ func backfillDoubanRatings() error {
 // - 基于中文名 + 年份从豆瓣抓取评分
 // ===========================
 
-func enrichMovieRatings(m *Movie) {
+// maxStoredCast CastJSON 最多保存的演员数（接口可用 ?cast_limit= 再截断）。
+const maxStoredCast = 20
+
+// tmdbCastMember TMDB credits.cast 的单个演员。
+type tmdbCastMember struct {
+	Name        string `json:"name"`
+	Character   string `json:"character"`
+	ProfilePath string `json:"profile_path"`
+	Order       int    `json:"order"` // TMDB 署名顺序，0 为主演
+}
+
+// pickCastLanguage 选择用于 CastJSON 的语言版本：日本电影优先 ja-JP（演员名为日文），
+// 其余按 zh-CN > en-US > ja-JP 取第一个有数据的版本。
+func pickCastLanguage(castByLang map[string][]tmdbCastMember, originalLang string) (string, []tmdbCastMember) {
+	order := []string{"zh-CN", "en-US", "ja-JP"}
+	if originalLang == "ja" {
+		order = []string{"ja-JP", "zh-CN", "en-US"}
+	}
+	for _, lang := range order {
+		if members := castByLang[lang]; len(members) > 0 {
+			return lang, members
+		}
+	}
+	return "", nil
+}
+
+// castFromTmdb 按署名顺序排序并截取前 maxStoredCast 位，保留 order 字段。
+func castFromTmdb(members []tmdbCastMember) []Person {
+	sorted := append([]tmdbCastMember(nil), members...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Order < sorted[j].Order })
+	if len(sorted) > maxStoredCast {
+		sorted = sorted[:maxStoredCast]
+	}
+	out := make([]Person, 0, len(sorted))
+	for _, c := range sorted {
+		img := ""
+		if c.ProfilePath != "" {
+			img = "https://image.tmdb.org/t/p/w185" + c.ProfilePath
+		}
+		out = append(out, Person{
+			Name:  c.Name,
+			Role:  c.Character,
+			Img:   img,
+			Order: c.Order,
+		})
+	}
+	return out
+}
+
+// enrichMovieRatings 用 cfg 中的 API Key 补全影片信息与评分；TMDB Key 为空时打印错误并跳过。
+// 由 enrich-movies 调用（见 enrichmovies.go）；force 为 true 时资料齐全的影片也重新请求。
+func enrichMovieRatings(cfg Config, m *Movie, force bool) {
+	// 外部接口返回异常数据导致 panic 时，只跳过本片的补全，不影响排片写入
+	defer recoverAndLog("影片补全 " + m.TitleJP)
+
+	// 直播 / 中继等活动在 TMDB 上不存在，不做补全（见 moviekind.go）
+	if isEventMovie(*m) {
+		return
+	}
+
 	// 如果已经补全过基础信息和评分，并且 ReleaseDate 也不是零值，就不再重复调用外部接口，节省配额。
-	// 注意：之前有一版逻辑没有考虑 ReleaseDate，可能导致字段齐全但上映日期为 0001-01-01 的旧数据。
-	if m.TitleCN != "" && m.TitleEN != "" && m.TMDBRating > 0 && !m.ReleaseDate.IsZero() {
+	if !force && !movieNeedsEnrichment(*m) {
+		if m.TMDBPending {
+			db.Model(m).Update("tmdb_pending", false)
+			m.TMDBPending = false
+		}
 		return
 	}
+	// 新片补全成功后发出 movie.enriched（只发一次，见 movieevents.go）
+	defer func() { emitMovieEnrichedEvent(*m) }()
 
 	cleanTitle := strings.TrimSpace(m.TitleJP)
 	if cleanTitle == "" {
 		return
 	}
-
-	// 1) 先用日文片名在 TMDB 上查到 tmdbID
-	tmdbID := searchTmdbID(cleanTitle)
+	// 1) 先用日文片名在 TMDB 上查到 tmdbID；策展人手动指定过的直接使用，不再搜索（见 enrichretry.go）
+	//    TMDB 故障导致熔断时推迟补全（见 tmdbbreaker.go），而不是当作“未找到”；
+	//    搜不到或搜索失败时在影片行上记下失败，enrich-movies 按退避间隔重试
+	tmdbID := manualTmdbID(*m)
 	if tmdbID == 0 {
-		fmt.Printf("⚠️ TMDB 未找到影片: %s\n", cleanTitle)
-		return
+		now := time.Now()
+		// 近期搜索过且无结果的标题不再重复搜索（见 tmdbnegative.go）
+		if tmdbKnownMiss(cleanTitle, now) {
+			fmt.Printf("⏭️ TMDB 近期搜索无结果，跳过补全: %s\n", cleanTitle)
+			recordEnrichFailure(m, enrichErrKnownNoResults, now)
+			return
+		}
+		var err error
+		tmdbID, err = searchTmdbID(cfg, cleanTitle, parseMovieYear(m.Year))
+		if errors.Is(err, errTMDBKeyMissing) {
+			fmt.Printf("❌ 无法补全 [%s]: %v\n", cleanTitle, err)
+			return
+		}
+		if err != nil && tmdbBreakerIsOpen() {
+			deferTmdbEnrichment(m)
+			return
+		}
+		if tmdbID == 0 {
+			fmt.Printf("⚠️ TMDB 未找到影片: %s\n", cleanTitle)
+			if err == nil {
+				recordTmdbMiss(cleanTitle, now)
+				recordEnrichFailure(m, enrichErrNoResults, now)
+			} else {
+				recordEnrichFailure(m, "tmdb search: "+err.Error(), now)
+			}
+			return
+		}
 	}
+	clearEnrichFailure(m)
 	// 记录到模型中，方便后续排查 / 外链
 	if m.TMDBID == 0 {
 		m.TMDBID = tmdbID
+		recordProvenance(&m.ProvenanceJSON, SourceTMDBjaJP, "tmdb_id")
 	}
 
 	var imdbID string
+	castByLang := make(map[string][]tmdbCastMember)
+	originalLang := ""
+	// 各语言请求返回的视频合并后统一挑选预告片（见 trailer.go）
+	var videos []tmdbVideo
 
 	// 2) 分语言拉取 TMDB 详情：zh-CN / ja-JP / en-US
 	langs := []string{"zh-CN", "ja-JP", "en-US"}
+	deferred := false
 	for _, lang := range langs {
+		// release_dates 与语言无关，只随 ja-JP 请求取一次（日本院线上映日期，见 releasedates.go）
+		appendTo := "credits,videos"
+		if lang == "ja-JP" {
+			appendTo += ",release_dates"
+		}
 		apiURL := fmt.Sprintf(
-			"https://api.themoviedb.org/3/movie/%d?api_key=%s&language=%s&append_to_response=credits,videos",
-			tmdbID, TMDB_API_KEY, lang,
+			"https://api.themoviedb.org/3/movie/%d?api_key=%s&language=%s&append_to_response=%s",
+			tmdbID, cfg.TMDBAPIKey, lang, appendTo,
 		)
 		fmt.Printf("🌐 TMDB 详情查询 [%s]: %s\n", lang, apiURL)
 
-		client := &http.Client{Timeout: 10 * time.Second}
-		req, _ := http.NewRequest("GET", apiURL, nil)
-		req.Header.Set("User-Agent", "TokyoCinePath/1.1 (tmdb-detail)")
-
-		resp, err := client.Do(req)
-		if err != nil || resp == nil {
-			if err != nil {
-				fmt.Printf("⚠️ TMDB 详情请求失败 [%s]: %v\n", lang, err)
-			}
+		cacheKey := fmt.Sprintf("movie:%d:%s:%s", tmdbID, lang, appendTo)
+		body, _, err := tmdbGetCached(cacheKey, apiURL, "TokyoCinePath/1.1 (tmdb-detail)")
+		if errors.Is(err, errTmdbCircuitOpen) {
+			deferred = true
+			break
+		}
+		if err != nil {
+			fmt.Printf("⚠️ TMDB 详情请求失败 [%s]: %v\n", lang, err)
 			continue
 		}
 
@@ -480,53 +1209,72 @@ func enrichMovieRatings(m *Movie) {
 			ReleaseDate  string  `json:"release_date"`
 			Runtime      int     `json:"runtime"`
 			VoteAverage  float64 `json:"vote_average"`
+			VoteCount    int     `json:"vote_count"`
 			Genres       []struct {
 				Name string `json:"name"`
 			} `json:"genres"`
-			Credits struct {
-				Cast []struct {
-					Name        string `json:"name"`
-					Character   string `json:"character"`
-					ProfilePath string `json:"profile_path"`
-				} `json:"cast"`
+			OriginalLanguage string `json:"original_language"`
+			Credits          struct {
+				Cast []tmdbCastMember `json:"cast"`
 				Crew []struct {
 					Name string `json:"name"`
 					Job  string `json:"job"`
 				} `json:"crew"`
 			} `json:"credits"`
+			ReleaseDates tmdbReleaseDates `json:"release_dates"`
+			Videos       tmdbVideos       `json:"videos"`
 		}
-		if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
-			resp.Body.Close()
+		if err := json.Unmarshal(body, &data); err != nil {
 			continue
 		}
-		resp.Body.Close()
+
+		// 本语言请求实际写入的字段，循环末尾统一记录来源
+		src := tmdbSourceForLang(lang)
+		var touched []string
 
 		// 公共字段：优先用中文的评分 / 简介，如果没有再用其他语言
 		if data.VoteAverage > 0 && m.TMDBRating == 0 {
 			m.TMDBRating = data.VoteAverage
+			m.TMDBVotes = data.VoteCount
+			touched = append(touched, "tmdb_rating", "tmdb_votes")
 		}
 		if m.Synopsis == "" && strings.TrimSpace(data.Overview) != "" {
 			m.Synopsis = data.Overview
+			touched = append(touched, "synopsis")
 		}
 		if data.PosterPath != "" && m.Poster == "" {
 			m.Poster = "https://image.tmdb.org/t/p/w500" + data.PosterPath
+			touched = append(touched, "poster")
 		}
 		if data.BackdropPath != "" && m.Backdrop == "" {
 			m.Backdrop = "https://image.tmdb.org/t/p/original" + data.BackdropPath
+			touched = append(touched, "backdrop")
 		}
 		if data.ReleaseDate != "" {
 			if m.Year == "" && len(data.ReleaseDate) >= 4 {
 				m.Year = data.ReleaseDate[:4]
+				touched = append(touched, "year")
 			}
 			// 同步精确上映日期到模型的 ReleaseDate 字段（time.Time）
 			if m.ReleaseDate.IsZero() {
 				if t, err := time.Parse("2006-01-02", data.ReleaseDate); err == nil {
 					m.ReleaseDate = t
+					m.ReleaseDatePrecision = ReleaseDatePrecisionDay
+					touched = append(touched, "release_date")
 				}
 			}
 		}
-		if data.Runtime > 0 && m.Runtime == 0 {
+		// 日本院线上映日期：TMDB 没有 JP 的 theatrical 条目时保持为空，沿用全球上映日期
+		if m.JPReleaseDate == nil {
+			if t, ok := pickJPTheatricalDate(data.ReleaseDates); ok {
+				m.JPReleaseDate = &t
+				touched = append(touched, "jp_release_date")
+			}
+		}
+		// 片长与 eiga.com 相差过大时视为匹配可疑：不写入片长，放入待复核列表
+		if data.Runtime > 0 && checkTmdbRuntime(m, data.Runtime) && m.Runtime == 0 {
 			m.Runtime = data.Runtime
+			touched = append(touched, "runtime")
 		}
 		if len(data.Genres) > 0 && m.Genre == "" {
 			parts := make([]string, 0, len(data.Genres))
@@ -536,50 +1284,33 @@ func enrichMovieRatings(m *Movie) {
 				}
 			}
 			m.Genre = strings.Join(parts, ", ")
+			touched = append(touched, "genre")
 		}
 		if m.Director == "" {
 			for _, crew := range data.Credits.Crew {
 				if crew.Job == "Director" {
 					m.Director = crew.Name
+					touched = append(touched, "director")
 					break
 				}
 			}
 		}
 
-		// 从 zh-CN / en-US 的 credits.cast 里补全 CastJSON（只做一次）
-		if (lang == "zh-CN" || lang == "en-US") && m.CastJSON == "" && len(data.Credits.Cast) > 0 {
-			limit := len(data.Credits.Cast)
-			if limit > 8 {
-				limit = 8
-			}
-			type castOut struct {
-				Name string `json:"name"`
-				Role string `json:"role"`
-				Img  string `json:"img"`
-			}
-			out := make([]castOut, 0, limit)
-			for i := 0; i < limit; i++ {
-				c := data.Credits.Cast[i]
-				img := ""
-				if c.ProfilePath != "" {
-					img = "https://image.tmdb.org/t/p/w185" + c.ProfilePath
-				}
-				out = append(out, castOut{
-					Name: c.Name,
-					Role: c.Character,
-					Img:  img,
-				})
-			}
-			if b, err := json.Marshal(out); err == nil {
-				m.CastJSON = string(b)
-			}
+		// 演员表先按语言暂存，循环结束后再挑选（日本电影优先 ja-JP 的日文名）
+		if len(data.Credits.Cast) > 0 {
+			castByLang[lang] = data.Credits.Cast
 		}
+		if data.OriginalLanguage != "" {
+			originalLang = data.OriginalLanguage
+		}
+		videos = append(videos, data.Videos.Results...)
 
 		// 不同语言分别填充 TitleCN / TitleJP / TitleEN
 		switch lang {
 		case "zh-CN":
 			if data.Title != "" {
 				m.TitleCN = data.Title
+				touched = append(touched, "title_cn")
 			}
 			if imdbID == "" {
 				imdbID = data.ImdbID
@@ -587,25 +1318,63 @@ func enrichMovieRatings(m *Movie) {
 		case "ja-JP":
 			if data.Title != "" && m.TitleJP == "" {
 				m.TitleJP = data.Title
+				touched = append(touched, "title_jp")
 			}
 		case "en-US":
 			if data.Title != "" {
 				m.TitleEN = data.Title
+				touched = append(touched, "title_en")
 			}
 			if imdbID == "" {
 				imdbID = data.ImdbID
 			}
 		}
+
+		recordProvenance(&m.ProvenanceJSON, src, touched...)
+	}
+	applyTmdbTrailer(m, videos)
+	if deferred {
+		// 已拿到的语言照常保存，其余留待 TMDB 恢复后补全
+		deferTmdbEnrichment(m)
+		return
+	}
+
+	// 原始语言：没有字幕 / 吹替标注的场次据此推断 audio_hint（见 audiohint.go）
+	if originalLang != "" && m.OriginalLanguage != originalLang {
+		m.OriginalLanguage = originalLang
+		recordProvenance(&m.ProvenanceJSON, SourceTMDBjaJP, "original_language")
+	}
+
+	// 补全 CastJSON（只做一次）：按 TMDB 排序（order）保留前 maxStoredCast 位
+	if m.CastJSON == "" {
+		if lang, members := pickCastLanguage(castByLang, originalLang); len(members) > 0 {
+			if b, err := json.Marshal(castFromTmdb(members)); err == nil {
+				m.CastJSON = string(b)
+				recordProvenance(&m.ProvenanceJSON, tmdbSourceForLang(lang), "cast")
+			}
+		}
 	}
 
 	// 3) IMDb 评分（通过 OMDb）
+	//    OMDb 配额耗尽后不再请求，也不覆盖已有评分，只标记为待补全，下次运行优先处理。
 	if imdbID != "" {
 		m.IMDBID = imdbID
-		imdbRating, raw := fetchImdbRating(imdbID)
-		m.IMDBRating = imdbRating
+		var ratings omdbRatings
+		raw, err := "", errOmdbSkipped
+		if !omdbBlocked() {
+			ratings, raw, err = fetchOmdbRatings(cfg, imdbID)
+		}
+		if omdbBlocked() {
+			m.IMDBPending = true
+		} else if err == nil {
+			applyOmdbRatings(m, ratings)
+			recordProvenance(&m.ProvenanceJSON, SourceOMDb, "imdb_id")
+		} else {
+			noteOmdbFailure(m, err)
+		}
 
 		// 你的要求：如果 TMDB 有评分而 IMDb 却是 0，打印出 IMDb 原始返回，方便人工核对。
-		if m.TMDBRating > 0 && imdbRating == 0 {
+		if err == nil && m.TMDBRating > 0 && ratings.IMDBRating == 0 {
 			fmt.Printf("⚠️ IMDb 评分为 0 但 TMDB 有分: TitleJP=%s TitleEN=%s TMDBID=%d IMDbID=%s Raw=%s\n",
 				m.TitleJP, m.TitleEN, m.TMDBID, imdbID, raw)
 		}
@@ -615,13 +1384,19 @@ func enrichMovieRatings(m *Movie) {
 	if m.ReleaseDate.IsZero() && m.Year != "" {
 		if t, err := time.Parse("2006-01-02", m.Year+"-01-01"); err == nil {
 			m.ReleaseDate = t
+			m.ReleaseDatePrecision = ReleaseDatePrecisionYear
+			// 保底日期由 Year 推导，沿用 Year 的来源
+			if e, ok := parseProvenance(m.ProvenanceJSON)["year"]; ok {
+				recordProvenance(&m.ProvenanceJSON, e.Source, "release_date")
+			}
 		}
 	}
 
 	// 5) 豆瓣评分（通过网页抓取，可选）
 	//   按你的最新要求：优先使用英文名去豆瓣搜索，避免中文名歧义。
-	if ENABLE_DOUBAN_RATING && m.TitleEN != "" && m.Year != "" {
+	if cfg.EnableDoubanRating && m.TitleEN != "" && m.Year != "" {
 		m.DoubanRating = fetchDoubanRating(m.TitleEN, m.Year)
+		recordProvenance(&m.ProvenanceJSON, SourceDouban, "douban_rating")
 	}
 
 	// 如果到这里 ReleaseDate 仍然是零值，说明 TMDB 返回中没有 release_date，
@@ -631,6 +1406,7 @@ func enrichMovieRatings(m *Movie) {
 			m.TitleJP, m.TitleCN, m.Year, m.TMDBID)
 	}
 
+	m.TMDBPending = false
 	if err := db.Save(m).Error; err != nil {
 		fmt.Printf("⚠️ 保存影片信息失败 [%s]: %v\n", m.TitleJP, err)
 	} else {
@@ -639,61 +1415,87 @@ func enrichMovieRatings(m *Movie) {
 	}
 }
 
-// searchTmdbID 使用日文片名在 TMDB 搜索并返回第一个结果的 ID。
-func searchTmdbID(title string) int {
+// searchTmdbID 使用日文片名在 TMDB 搜索，按年份（year 为 0 表示未知）与片名选出最匹配的结果并返回其 ID；
+// 请求失败（含熔断）时返回错误。消歧规则见 tmdbmatch.go。
+// 搜索前先做标题规范化，去掉【IMAX】/（字幕版）等排片注释，提高命中率。
+func searchTmdbID(cfg Config, title string, year int) (int, error) {
+	if err := cfg.requireTMDB(); err != nil {
+		return 0, err
+	}
+	title = NormalizeTitle(title)
 	u := fmt.Sprintf(
 		"https://api.themoviedb.org/3/search/movie?api_key=%s&query=%s&language=ja-JP",
-		TMDB_API_KEY, url.QueryEscape(title),
+		cfg.TMDBAPIKey, url.QueryEscape(title),
 	)
 	fmt.Printf("🌐 TMDB 搜索 URL: %s\n", u)
 
-	resp, err := http.Get(u)
-	if err != nil || resp == nil {
-		return 0
+	// 同一标题的搜索结果在缓存有效期内直接复用（见 apicache.go）
+	body, _, err := tmdbGetCached("search:ja-JP:"+title, u, "")
+	if err != nil {
+		return 0, err
 	}
-	defer resp.Body.Close()
 
 	var res struct {
-		Results []struct {
-			ID int `json:"id"`
-		} `json:"results"`
+		Results []tmdbSearchCandidate `json:"results"`
 	}
-	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
-		return 0
+	if err := json.Unmarshal(body, &res); err != nil {
+		return 0, nil
 	}
-	if len(res.Results) > 0 {
-		return res.Results[0].ID
+	if best, ties, ok := pickTmdbCandidate(res.Results, title, year); ok {
+		if len(ties) > 0 {
+			fmt.Printf("⚠️ TMDB 候选并列 [%s year=%d]，取 %d，请人工确认：%s\n", title, year, best.ID, formatTmdbCandidates(ties))
+		}
+		return best.ID, nil
 	}
 	// 关键调试信息：当 TMDB 没有返回任何结果时，打印出本次搜索使用的 URL，方便你复制到浏览器里直接查看。
 	fmt.Printf("⚠️ TMDB 搜索无结果: TitleJP=%s URL=%s\n", title, u)
-	return 0
+	return 0, nil
 }
 
-// fetchImdbRating 通过 OMDb API 获取 IMDb 评分，同时返回原始响应字符串，便于调试。
-func fetchImdbRating(imdbID string) (float64, string) {
+// fetchOmdbRatings 通过 OMDb API 获取 IMDb / Rotten Tomatoes / Metacritic 评分，同时返回原始响应字符串，便于调试。
+// 若返回配额耗尽错误，会标记 omdbBlocked，调用方据此跳过而不是写入 0 分；
+// 请求失败或 OMDb 返回 "Response":"False" 时返回错误，调用方保留已有评分（见 omdb.go）。
+// OMDB_API_KEY 为空时不请求。
+func fetchOmdbRatings(cfg Config, imdbID string) (omdbRatings, string, error) {
 	if imdbID == "" {
-		return 0, ""
+		return omdbRatings{}, "", errOmdbSkipped
 	}
-	u := fmt.Sprintf("http://www.omdbapi.com/?i=%s&apikey=%s", imdbID, OMDB_API_KEY)
+	// 缓存有效期内不再请求（见 apicache.go）；只缓存解析成功的响应
+	now := time.Now()
+	if raw, ok := apiCacheGet(apiCacheProviderOMDb, imdbID, now); ok {
+		ratings, err := parseOmdbResponse(raw)
+		return ratings, raw, err
+	}
+	if cfg.OMDBAPIKey == "" || omdbBlocked() {
+		return omdbRatings{}, "", errOmdbSkipped
+	}
+	omdbCountCall()
+	u := fmt.Sprintf("http://www.omdbapi.com/?i=%s&apikey=%s", imdbID, cfg.OMDBAPIKey)
 	fmt.Printf("🌐 OMDb 查询 URL: %s\n", u)
 
 	resp, err := http.Get(u)
 	if err != nil || resp == nil {
-		return 0, ""
+		if err == nil {
+			err = errors.New("empty response")
+		}
+		return omdbRatings{}, "", err
 	}
 	defer resp.Body.Close()
 
-	var rawBuf strings.Builder
-	tee := io.TeeReader(resp.Body, &rawBuf)
-
-	var data struct {
-		Rating string `json:"imdbRating"`
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return omdbRatings{}, string(body), err
 	}
-	if err := json.NewDecoder(tee).Decode(&data); err != nil {
-		return 0, rawBuf.String()
+	raw := string(body)
+	if isOmdbLimitResponse(raw) {
+		omdbMarkBlocked()
+		return omdbRatings{}, raw, errOmdbSkipped
 	}
-	val, _ := strconv.ParseFloat(data.Rating, 64)
-	return val, rawBuf.String()
+	ratings, err := parseOmdbResponse(raw)
+	if err == nil {
+		apiCachePut(apiCacheProviderOMDb, imdbID, raw, now)
+	}
+	return ratings, raw, err
 }
 
 // fetchDoubanRating 通过抓取豆瓣搜索结果页，提取评分。
@@ -731,6 +1533,23 @@ func fetchDoubanRating(title string, year string) float64 {
 	return rating
 }
 
+// parseAvailability 从场次文本与 class 中识别余票状态；没有任何标记时返回 unknown，避免误导。
+// 约定：満席 / 完売 / × -> soldout；△ / 残りわずか -> few；○ / ◎ / 空席あり -> available。
+func parseAvailability(text string, class string) string {
+	class = strings.ToLower(class)
+	switch {
+	case strings.Contains(text, "満席") || strings.Contains(text, "完売") || strings.Contains(text, "×") ||
+		strings.Contains(class, "soldout") || strings.Contains(class, "sold-out"):
+		return AvailabilitySoldOut
+	case strings.Contains(text, "△") || strings.Contains(text, "残りわずか") || strings.Contains(class, "few"):
+		return AvailabilityFew
+	case strings.Contains(text, "○") || strings.Contains(text, "◎") || strings.Contains(text, "空席あり") ||
+		strings.Contains(class, "available"):
+		return AvailabilityAvailable
+	}
+	return AvailabilityUnknown
+}
+
 // 地址清洗函数：只保留到门牌号，去掉“某某大楼内”或“几楼”
 func cleanAddressForGeo(addr string) string {
 	// 匹配常见的门牌号格式（如 1-5-16 或 3丁目15-15）
@@ -749,150 +1568,59 @@ func updateMovieStatusFromSchedules() error {
 		return fmt.Errorf("查询电影失败: %v", err)
 	}
 
-	today := time.Now()
-	todayStr := today.Format("2006-01-02")
-
-	updatedCount := 0
-	for _, movie := range movies {
-		// 查询该电影的所有排片
-		var schedules []Schedule
-		if err := db.Where("movie_id = ?", movie.ID).Find(&schedules).Error; err != nil {
-			continue
-		}
-
-		if len(schedules) == 0 {
-			// 没有任何排片：视为「未排片」，单独标记，前端默认不展示
-			newStatus := "unplanned"
-			if movie.Status != newStatus {
-				if err := db.Model(&movie).Update("status", newStatus).Error; err != nil {
-					fmt.Printf("⚠️ 更新电影状态失败 [%s]: %v\n", movie.TitleJP, err)
-					continue
-				}
-				fmt.Printf("   🔄 [%s]: %s -> %s (无任何排片)\n", movie.TitleJP, movie.Status, newStatus)
-				updatedCount++
-			}
-			continue
-		}
-
-		// 找到最早的排片日期 + 最晚的排片日期 + 是否存在「今天或之前」的排片
-		var earliestDate *time.Time
-		var latestDate *time.Time
-		hasPastOrToday := false
-
-		for _, sched := range schedules {
-			dateStr := sched.PlayDate.Format("2006-01-02")
-			if dateStr <= todayStr {
-				hasPastOrToday = true
-			}
-			if earliestDate == nil || sched.PlayDate.Before(*earliestDate) {
-				earliestDate = &sched.PlayDate
-			}
-			if latestDate == nil || sched.PlayDate.After(*latestDate) {
-				latestDate = &sched.PlayDate
-			}
-		}
-
-		// 先检查：如果所有排片都已经过期（最晚排片 < 今天），标记为 unplanned
-		if latestDate != nil {
-			latestDateStr := latestDate.Format("2006-01-02")
-			if latestDateStr < todayStr {
-				// 所有排片都已经过去，标记为 unplanned
-				newStatus := "unplanned"
-				if movie.Status != newStatus {
-					if err := db.Model(&movie).Update("status", newStatus).Error; err != nil {
-						fmt.Printf("⚠️ 更新电影状态失败 [%s]: %v\n", movie.TitleJP, err)
-						continue
-					}
-					fmt.Printf("   🔄 [%s]: %s -> %s (最晚排片: %s，已全部过期)\n", movie.TitleJP, movie.Status, newStatus, latestDateStr)
-					updatedCount++
-				}
-				continue
-			}
-		}
-
-		// 判断新状态（按你的期望精确收敛）：
-		// - showing：存在「今天或之前」的任意排片，且最晚排片 >= 今天（至少还有未过期的场次）
-		// - incoming (Soon)：所有排片都在未来，且最早排片在明天到未来 7 天内
-		// - future：所有排片都在未来，且最早排片在 7 天之后 —— 大概率是数据问题，前端默认不展示
-		newStatus := "showing"
-		if !hasPastOrToday && earliestDate != nil {
-			tomorrow := today.AddDate(0, 0, 1)
-			sevenDaysLater := today.AddDate(0, 0, 7)
-
-			earliest := earliestDate.Truncate(24 * time.Hour)
-			if earliest.Before(tomorrow) {
-				// 理论上不会进入（因为没有 pastOrToday），防御性留空
-				newStatus = "incoming"
-			} else if (earliest.Equal(tomorrow) || earliest.After(tomorrow)) && (earliest.Before(sevenDaysLater) || earliest.Equal(sevenDaysLater)) {
-				// 明天 ~ 7 天内
-				newStatus = "incoming"
-			} else if earliest.After(sevenDaysLater) {
-				// 超过 7 天的未来排片：标为 future（第三状态），前端可选择不展示
-				newStatus = "future"
-			}
-		}
+	// 扩展排片跨度摘要（first_seen / last_seen），排片被清理后仍可计算上映周数
+	if n, err := refreshMovieSeenExtents(); err != nil {
+		fmt.Printf("⚠️ %v\n", err)
+	} else if n > 0 {
+		fmt.Printf("📏 已更新 %d 部影片的排片跨度\n", n)
+	}
 
-		// 更新状态
-		if movie.Status != newStatus {
-			if err := db.Model(&movie).Update("status", newStatus).Error; err != nil {
-				fmt.Printf("⚠️ 更新电影状态失败 [%s]: %v\n", movie.TitleJP, err)
-				continue
-			}
-			fmt.Printf("   🔄 [%s]: %s -> %s (最早排片: %s)\n", movie.TitleJP, movie.Status, newStatus, earliestDate.Format("2006-01-02"))
-			updatedCount++
-		}
+	// 先释放已过期的人工锁定，再跳过仍在锁定期的影片
+	if n := releaseExpiredStatusPins(nowJST()); n > 0 {
+		fmt.Printf("🔓 已释放 %d 个过期的状态锁定\n", n)
 	}
 
+	// 状态规则见 statusrules.go（与抓取后的批量重算、as_of 回放共用 StatusForSchedules）：
+	// - unplanned：没有排片，或最晚排片已过去超过 leaving_days 天，前端默认不展示
+	// - showing：已开映且仍在下映宽限期内
+	// - incoming (Soon)：所有排片都在未来，且最早排片在 soon_days 天内
+	// - future：最早排片在 soon_days 天之后 —— 大概率是数据问题，前端默认不展示
+	updatedCount := refreshMovieStatuses(movies, nowJST(), StatusSourceUpdate)
+
 	fmt.Printf("✅ 共更新 %d 部电影的状态\n", updatedCount)
 	return nil
 }
 
-func getCoordsFromOSMWithRetry(address string, name string) (float64, float64) {
-	// 尝试一：用清洗后的详细地址
-	lat, lng, err := callOSM(address)
-	if err == nil {
-		return lat, lng
-	}
-
-	// 尝试二：如果失败，只用“新宿区 + 影院名”去搜
+// geocodeQueries 依次尝试的地理编码查询串（纯函数）：清洗后的详细地址，以及“新宿区 + 影院名”。
+func geocodeQueries(address string, name string) (exact, approx string) {
 	district := ""
 	if strings.Contains(address, "区") {
 		district = address[:strings.Index(address, "区")+3]
 	}
-	lat, lng, err = callOSM(district + " " + name)
-	if err == nil {
-		return lat, lng
-	}
-
-	// 最终保底方案：如果都搜不到，在东京站附近随机偏移一点，至少不重叠
-	// (这在没有 API Key 时是保证地图不重叠的常用 Trick)
-	randomOffset := float64(time.Now().UnixNano()%1000) / 100000.0
-	return 35.6895 + randomOffset, 139.6917 + randomOffset
+	return address, district + " " + name
 }
 
-func callOSM(query string) (float64, float64, error) {
-	apiURL := fmt.Sprintf("https://nominatim.openstreetmap.org/search?q=%s&format=json&limit=1", url.QueryEscape(query))
-
-	client := &http.Client{Timeout: 10 * time.Second}
-	req, _ := http.NewRequest("GET", apiURL, nil)
-	req.Header.Set("User-Agent", "TokyoCinePath/1.1 (yourname@gmail.com)")
-
-	resp, err := client.Do(req)
-	if err != nil {
-		return 0, 0, err
+// getCoordsFromOSMWithRetry 返回坐标、定位质量（exact / approx / failed）与给出坐标的来源（nominatim / gsi，失败时为空串）。
+func getCoordsFromOSMWithRetry(address string, name string) (float64, float64, string, string) {
+	exactQuery, approxQuery := geocodeQueries(address, name)
+	// 尝试一：用清洗后的详细地址
+	lat, lng, err := callOSM(exactQuery)
+	if err == nil {
+		return lat, lng, GeoStatusExact, geocodeProviderNominatim
 	}
-	defer resp.Body.Close()
 
-	var results []struct {
-		Lat string `json:"lat"`
-		Lon string `json:"lon"`
+	// 尝试二：Nominatim 不认识“丁目 / 番地”格式时，用国土地理院地址检索同一地址（见 gsi.go）
+	lat, lng, err = callGSI(exactQuery)
+	if err == nil {
+		return lat, lng, GeoStatusExact, geocodeProviderGSI
 	}
-	json.NewDecoder(resp.Body).Decode(&results)
 
-	if len(results) > 0 {
-		lat, _ := strconv.ParseFloat(results[0].Lat, 64)
-		lng, _ := strconv.ParseFloat(results[0].Lon, 64)
-		return lat, lng, nil
+	// 尝试三：如果还是失败，只用“新宿区 + 影院名”去搜
+	lat, lng, err = callOSM(approxQuery)
+	if err == nil {
+		return lat, lng, GeoStatusApprox, geocodeProviderNominatim
 	}
-	return 0, 0, fmt.Errorf("no results")
+
+	// 都搜不到时不编造坐标：保持 0/0，由调用方标记 GeocodeFailed，地图上不显示（见 geofix.go）
+	return 0, 0, GeoStatusFailed, ""
 }
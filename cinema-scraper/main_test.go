@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gocolly/colly/v2"
+)
+
+// TestParseEigaCinemaPageRuby 影院名注音：h1.page-title 的 <rt> / <rp> 不进影院名，注音作为读音；没有 ruby 时取标题括号中的ふりがな。
+func TestParseEigaCinemaPageRuby(t *testing.T) {
+	newTestRouter(t)
+	now := testNoon
+	setTestClock(t, now)
+
+	pages := map[string]string{
+		"/theater/ruby/":  `<h1 class="page-title"><ruby>新宿武蔵野館<rt>しんじゅくむさしのかん</rt></ruby></h1>`,
+		"/theater/rp/":    `<h1 class="page-title"><ruby>目黒<rp>（</rp><rt>めぐろ</rt><rp>）</rp></ruby>シネマ</h1>`,
+		"/theater/paren/": `<h1 class="page-title">シネマ・ロサ（しねまろさ）</h1>`,
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		io.WriteString(w, `<html><body><main>`+pages[r.URL.Path]+`</main></body></html>`)
+	}))
+	defer srv.Close()
+	parsed := make(map[string]eigaCinemaPage)
+	c := colly.NewCollector()
+	c.OnHTML("main", func(e *colly.HTMLElement) {
+		if p, ok := parseEigaCinemaPage(e); ok {
+			parsed[e.Request.URL.Path] = p
+		}
+	})
+	for path := range pages {
+		if err := c.Visit(srv.URL + path); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ruby, rp, paren := parsed["/theater/ruby/"], parsed["/theater/rp/"], parsed["/theater/paren/"]
+	if err := firstError(
+		expectEqual("ruby name", ruby.NameJP, "新宿武蔵野館"),
+		expectEqual("ruby kana", ruby.NameKana, "しんじゅくむさしのかん"),
+		expectEqual("rp name", rp.NameJP, "目黒シネマ"),
+		expectEqual("paren name", paren.NameJP, "シネマ・ロサ"),
+		expectEqual("paren kana", paren.NameKana, "しねまろさ")); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestConcurrentScheduleCallbacks 并发抓取：两家影院的排片页回调同时执行，场次、计数与影片集合都不丢；用 go test -race 运行以检查数据竞争。
+func TestConcurrentScheduleCallbacks(t *testing.T) {
+	newTestRouter(t)
+	setTestClock(t, testNoon)
+
+	cinemas := []Cinema{{NameJP: "テスト並行座A"}, {NameJP: "テスト並行座B"}}
+	for i := range cinemas {
+		if err := db.Create(&cinemas[i]).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	page := func(name string, firstID int) string {
+		var b strings.Builder
+		fmt.Fprintf(&b, `<html><body><main><h1 class="page-title">%s</h1>`, name)
+		for id := firstID; id < firstID+2; id++ {
+			fmt.Fprintf(&b, `<section id="m%d"><h2><a href="/movie/%d/">並行テスト映画%d</a></h2>`+
+				`<table class="weekly-schedule"><tr><td data-date="20260310"><span>10:00～12:00</span><span>14:00</span></td>`+
+				`<td data-date="20260311"><span>18:30</span></td></tr></table></section>`, id, id, id)
+		}
+		b.WriteString(`</main></body></html>`)
+		return b.String()
+	}
+	pages := map[string]string{"/theater/a/": page(cinemas[0].NameJP, 990101), "/theater/b/": page(cinemas[1].NameJP, 990201)}
+	// 两个请求都到达后才一起返回，保证两家影院的回调真正重叠
+	var arrived sync.WaitGroup
+	arrived.Add(len(pages))
+	bothArrived := make(chan struct{})
+	go func() { arrived.Wait(); close(bothArrived) }()
+	var overlapped atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		arrived.Done()
+		select {
+		case <-bothArrived:
+			overlapped.Add(1)
+		case <-time.After(5 * time.Second):
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		io.WriteString(w, pages[r.URL.Path])
+	}))
+	defer srv.Close()
+
+	resetCrawlCounters()
+	defer resetCrawlCounters()
+	panicsBefore := panicsRecovered.Load()
+	c := colly.NewCollector(colly.Async(true))
+	if err := c.Limit(&colly.LimitRule{DomainGlob: "*", Parallelism: 2}); err != nil {
+		t.Fatal(err)
+	}
+	previousCounts := map[uint]int{}
+	c.OnHTML("main", func(e *colly.HTMLElement) { handleEigaSchedulePage(e, previousCounts) })
+	for path := range pages {
+		if err := c.Visit(srv.URL + path); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c.Wait()
+
+	counts := make([]int64, len(cinemas))
+	for i, cinema := range cinemas {
+		db.Model(&Schedule{}).Where("cinema_id = ? AND play_date >= ?", cinema.ID, time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC)).Count(&counts[i])
+	}
+	var movies []Movie
+	db.Where("eiga_id IN ?", []string{"990101", "990102", "990201", "990202"}).Order("eiga_id").Find(&movies)
+	touched := 0
+	crawlTouchedMovies.Lock()
+	for _, m := range movies {
+		if crawlTouchedMovies.ids[m.ID] {
+			touched++
+		}
+	}
+	crawlTouchedMovies.Unlock()
+	if err := firstError(
+		expectEqual("overlapped", overlapped.Load(), int64(2)),
+		expectEqual("schedules per cinema", counts, []int64{6, 6}),
+		expectEqual("parsed showtimes", crawlParsedShowtimes.Load(), int64(12)),
+		expectEqual("movies", len(movies), 4),
+		expectEqual("touched movies", touched, 4),
+		expectEqual("panics", panicsRecovered.Load()-panicsBefore, int64(0))); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ===========================
+// 模块：只读维护模式
+// 职责：
+// - 迁移 / 去重 / 导入等破坏性操作期间，API 继续提供读取，但拒绝所有写入与管理操作（503）
+// - 所有响应附带 X-Maintenance: true，提醒客户端数据可能不一致
+// - 启动参数 --read-only 开启，运行中可通过管理接口切换；状态仅在进程内保存
+// ===========================
+
+// readOnlyMode 当前是否处于只读维护模式。
+var readOnlyMode atomic.Bool
+
+// readOnlySince 进入维护模式的时间（UnixNano），未开启时为 0。
+var readOnlySince atomic.Int64
+
+// setReadOnly 切换只读维护模式。
+func setReadOnly(on bool) {
+	readOnlyMode.Store(on)
+	if on {
+		readOnlySince.Store(time.Now().UnixNano())
+	} else {
+		readOnlySince.Store(0)
+	}
+}
+
+// isReadOnly 是否处于只读维护模式（定时任务等内部写入也应据此暂停）。
+func isReadOnly() bool {
+	return readOnlyMode.Load()
+}
+
+// hasFlag 判断命令行参数中是否包含某个开关，如 --read-only。
+func hasFlag(args []string, flag string) bool {
+	for _, arg := range args {
+		if arg == flag {
+			return true
+		}
+	}
+	return false
+}
+
+// maintenancePath 切换维护模式的管理接口，只读模式下仍需可用。
+const maintenancePath = "/api/admin/maintenance"
+
+// maintenanceMiddleware 只读模式下：所有响应加 X-Maintenance 头；写请求与管理接口返回 503。
+func maintenanceMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !isReadOnly() {
+			c.Next()
+			return
+		}
+		c.Header("X-Maintenance", "true")
+
+		path := c.Request.URL.Path
+		isWrite := c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead && c.Request.Method != http.MethodOptions
+		isAdmin := strings.HasPrefix(path, "/api/admin/")
+		if (isWrite || isAdmin) && path != maintenancePath {
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{
+				"error":      "service is in read-only maintenance mode, data may be inconsistent",
+				"request_id": requestIDFrom(c),
+			})
+			return
+		}
+		c.Next()
+	}
+}
+
+// setMaintenanceHandler 切换只读维护模式：POST /api/admin/maintenance {"read_only":true}
+func setMaintenanceHandler(c *gin.Context) {
+	var req struct {
+		ReadOnly *bool `json:"read_only"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || req.ReadOnly == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "read_only is required"})
+		return
+	}
+	setReadOnly(*req.ReadOnly)
+	c.JSON(http.StatusOK, maintenanceStatus())
+}
+
+// maintenanceStatus 当前维护模式状态（供 /api/health 与管理接口复用）。
+func maintenanceStatus() gin.H {
+	since := ""
+	if ns := readOnlySince.Load(); ns > 0 {
+		since = time.Unix(0, ns).In(tokyoLocation).Format(time.RFC3339)
+	}
+	return gin.H{
+		"read_only": isReadOnly(),
+		"since":     since,
+	}
+}
+
+// healthHandler 健康检查：GET /api/health
+func healthHandler(c *gin.Context) {
+	status := "ok"
+	if err := db.Exec("SELECT 1").Error; err != nil {
+		status = "degraded"
+	}
+	// 从未成功抓取过排片时数据不可信，同样标记为 degraded
+	freshness := scheduleFreshness()
+	state := freshnessState(freshness, nowJST())
+	if state == "none" {
+		status = "degraded"
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":          status,
+		"maintenance":     maintenanceStatus(),
+		"freshness":       state,
+		"schedules_as_of": freshness.SchedulesAsOf,
+		"crawl_run_id":    freshness.CrawlRunID,
+	})
+}
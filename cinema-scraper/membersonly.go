@@ -0,0 +1,75 @@
+package main
+
+import (
+	"strings"
+
+	"cloud.google.com/go/civil"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ===========================
+// 模块：会員限定场次（Schedule.MembersOnly）
+// 职责：
+// - 名画座常有只对会员开放的场次（会員限定 / 会員様限定 / 会員専用）：抓取时按场次单元格与片名注释识别并写入 MembersOnly
+// - 影片状态只按公开场次推算（抓取、update-status、as_of 回放一致）：只有会員限定场次的影片对外为 unplanned
+// - 公开接口默认不返回会員限定场次；/api/schedules、/api/cinemas/:id、/api/movies/:id
+//   可加 ?include_members_only=true 一并返回（场次带 members_only 标记）。
+//   首页、今晚、附近、全东京时间表、分享卡片等聚合视图始终只看公开场次
+// - 管理端 /api/admin/movies/review 列出只有会員限定场次的影片（review_reason = members_only，附说明）
+// 说明：归档表同样保存该标记，archive=true 的历史查询按相同规则过滤。
+// ===========================
+
+// membersOnlyReviewNote 复核列表中对 members_only 的说明。
+const membersOnlyReviewNote = "all schedules are members-only screenings; the movie is unplanned for the public"
+
+// membersOnlyMarks 会員限定场次的标注。
+var membersOnlyMarks = []string{"会員限定", "会員様限定", "会員専用", "会員のみ", "メンバー限定", "メンバーズ限定"}
+
+// isMembersOnlyShowtime 场次单元格、title 属性或片名注释中是否带有会員限定标注（纯函数）。
+func isMembersOnlyShowtime(texts ...string) bool {
+	for _, text := range texts {
+		for _, mark := range membersOnlyMarks {
+			if strings.Contains(text, mark) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// includeMembersOnly 请求是否要求一并返回会員限定场次（?include_members_only=true）。
+func includeMembersOnly(c *gin.Context) bool {
+	return c.Query("include_members_only") == "true"
+}
+
+// applyMembersOnlyFilter 默认只保留公开场次；include 为 true 时不过滤。热表与归档表通用。
+func applyMembersOnlyFilter(tx *gorm.DB, include bool) *gorm.DB {
+	if include {
+		return tx
+	}
+	return tx.Where("members_only = ?", false)
+}
+
+// publicScheduleDates 公开场次的放映日期（会員限定场次不计入，纯函数）；play_date 以 UTC 零点保存，直接取其日期部分。
+func publicScheduleDates(schedules []Schedule) []civil.Date {
+	dates := make([]civil.Date, 0, len(schedules))
+	for _, s := range schedules {
+		if !s.MembersOnly {
+			dates = append(dates, civil.DateOf(s.PlayDate.UTC()))
+		}
+	}
+	return dates
+}
+
+// membersOnlyMovieIDs 热表中有场次、且场次全部是会員限定的影片（不含已删除的），按 ID 排序。
+func membersOnlyMovieIDs() ([]uint, error) {
+	ids := make([]uint, 0)
+	err := db.Model(&Schedule{}).
+		Where("movie_id IN (?)", db.Model(&Movie{}).Select("id")).
+		Group("movie_id").
+		Having("SUM(CASE WHEN members_only THEN 0 ELSE 1 END) = 0").
+		Order("movie_id").
+		Pluck("movie_id", &ids).Error
+	return ids, err
+}
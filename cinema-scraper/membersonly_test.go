@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestMembersOnlySchedules 会員限定场次：不参与状态推算，排片列表默认不返回，只有会員限定场次的影片进入复核列表。
+func TestMembersOnlySchedules(t *testing.T) {
+	newTestRouter(t)
+	now := testNoon
+	setTestClock(t, now)
+
+	movie := Movie{TitleJP: "テスト会員上映", Status: "unplanned"}
+	if err := db.Create(&movie).Error; err != nil {
+		t.Fatal(err)
+	}
+	playDate := time.Date(2026, 3, 12, 0, 0, 0, 0, time.UTC)
+	shows := []Schedule{
+		{MovieID: movie.ID, CinemaID: 3, PlayDate: playDate, StartTime: "10:00",
+			MembersOnly: isMembersOnlyShowtime("10:00", "テスト会員上映（会員限定）")},
+		{MovieID: movie.ID, CinemaID: 3, PlayDate: playDate, StartTime: "13:00", MembersOnly: true},
+	}
+	if err := db.Create(&shows).Error; err != nil {
+		t.Fatal(err)
+	}
+	public := append(append([]Schedule{}, shows...), Schedule{PlayDate: playDate})
+	call := func(handler gin.HandlerFunc, path string, v interface{}) error {
+		rec := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(rec)
+		c.Request = httptest.NewRequest(http.MethodGet, path, nil)
+		handler(c)
+		return expectJSON(testResponse{Status: rec.Code, Body: rec.Body.Bytes()}, v)
+	}
+	var plain, all struct {
+		Items []ScheduleEntry `json:"items"`
+	}
+	var review struct {
+		Items []ReviewMovie `json:"items"`
+	}
+	if err := firstError(
+		call(listSchedulesHandler, fmt.Sprintf("/api/schedules?date=2026-03-12&movie_id=%d", movie.ID), &plain),
+		call(listSchedulesHandler, fmt.Sprintf("/api/schedules?date=2026-03-12&movie_id=%d&include_members_only=true", movie.ID), &all),
+		call(listReviewMoviesHandler, "/api/admin/movies/review", &review)); err != nil {
+		t.Fatal(err)
+	}
+	reason, note := "", ""
+	for _, it := range review.Items {
+		if it.ID == movie.ID {
+			reason, note = it.ReviewReason, it.ReviewNote
+		}
+	}
+	flagged := len(all.Items) == 2 && all.Items[0].MembersOnly && all.Items[1].MembersOnly
+	if err := firstError(
+		expectEqual("marked from title annotation", shows[0].MembersOnly, true),
+		expectEqual("plain showtime", isMembersOnlyShowtime("14:30", "ゴッドファーザー"), false),
+		expectEqual("members-only status", computeMovieStatus(shows, now), "unplanned"),
+		expectEqual("status with a public showtime", computeMovieStatus(public, now), "future"),
+		expectEqual("default schedules", len(plain.Items), 0),
+		expectEqual("include_members_only", flagged, true),
+		expectEqual("review reason", reason, ReviewMembersOnly),
+		expectEqual("review note", note, membersOnlyReviewNote)); err != nil {
+		t.Fatal(err)
+	}
+}
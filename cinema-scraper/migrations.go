@@ -0,0 +1,348 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// ===========================
+// 模块：版本化迁移与迁移前备份
+// 职责：
+// - AutoMigrate 只会加列 / 加索引；改写已有数据的迁移（如 Year 改为整数、StartTime 规范化）登记在 versionedMigrations，
+//   按版本号依次执行一次，执行记录写入 schema_migrations
+// - 必须在 AutoMigrate 之前执行的迁移（如建唯一索引前清理重复行）登记在 preSchemaMigrations，与 versionedMigrations 共用版本号
+// - 启动时统一经 runVersionedMigrations 执行：preSchemaMigrations → AutoMigrate 等表结构迁移 → versionedMigrations
+// - 只有删除或改写已有数据的迁移标记为 Destructive（如场次去重、清除兜底坐标），只补空字段的回填不算；
+//   标记为 Destructive 的迁移执行前先把 SQLite 文件备份到同目录（<db>.bak-20260127-030000，VACUUM INTO 得到一致的副本），
+//   以只读方式打开副本做 quick_check，通过后才执行迁移；备份路径记入迁移日志。
+//   同一次启动中的多项破坏性迁移共用第一次执行前的那份备份（回滚到它即撤销整批）
+// - rollback-to <backup>：确认服务已停止后，把当前库挪到 <db>.before-rollback-<时间>，再用备份覆盖数据库文件
+// 说明：内存数据库（测试）没有需要保护的数据，不备份；不是 SQLite 文件的 DSN（如 Postgres）无法自动备份，
+//       拒绝执行破坏性迁移并提示先用 pg_dump 手动备份。
+// 调用方式：
+//   go run . rollback-to tokyo_cinepath.db.bak-20260127-030000   （服务运行中拒绝执行，--force 跳过检查）
+// ===========================
+
+// SchemaMigration 已执行的版本化迁移。
+type SchemaMigration struct {
+	Version     int `gorm:"primaryKey;autoIncrement:false"`
+	Name        string
+	Destructive bool
+	BackupPath  string // 执行前的备份文件；非破坏性迁移为空
+	AppliedAt   time.Time
+}
+
+// versionedMigration 一项版本化迁移：Up 在事务内执行；Destructive 表示会改写或删除已有数据，执行前必须备份。
+type versionedMigration struct {
+	Version     int
+	Name        string
+	Destructive bool
+	Up          func(tx *gorm.DB) error
+}
+
+// preSchemaMigrations 在 AutoMigrate 之前执行的迁移（此时只保证 schema_migrations 表存在）。
+// 已发布的条目不得修改，只能追加；版本号与 versionedMigrations 不得重复。
+var preSchemaMigrations = []versionedMigration{
+	{1, "dedupe-schedule-slots", true, func(tx *gorm.DB) error {
+		n, err := dedupeSchedulesForUniqueIndex(tx)
+		if n > 0 {
+			fmt.Printf("🧹 已清理 %d 条重复场次，准备建立唯一索引\n", n)
+		}
+		return err
+	}},
+}
+
+// versionedMigrations 按版本号升序登记；已发布的条目不得修改，只能追加。
+// 规则变化后需要重新改写数据时（如 extractDistrict 调整），追加新版本，而不是改动旧条目。
+var versionedMigrations = []versionedMigration{
+	{2, "backfill-cinema-districts", false, func(tx *gorm.DB) error {
+		n, err := backfillCinemaDistricts(tx)
+		if n > 0 {
+			fmt.Printf("🗺️ 已为 %d 家影院补齐所在区\n", n)
+		}
+		return err
+	}},
+	{3, "backfill-cinema-prefectures", false, func(tx *gorm.DB) error {
+		n, err := backfillCinemaPrefectures(tx)
+		if n > 0 {
+			fmt.Printf("🗾 已为 %d 家影院补齐所在都道府县\n", n)
+		}
+		return err
+	}},
+	{4, "clear-random-fallback-coords", true, func(tx *gorm.DB) error {
+		n, err := clearRandomFallbackCoords(tx)
+		if n > 0 {
+			fmt.Printf("📍 已清除 %d 家影院的随机兜底坐标，改为定位失败（可运行 fix-geocode 重试）\n", n)
+		}
+		return err
+	}},
+	{5, "backfill-cinema-kana", false, func(tx *gorm.DB) error {
+		n, err := backfillCinemaKana(tx)
+		if n > 0 {
+			fmt.Printf("🔤 已按读音词表为 %d 家影院补齐读音\n", n)
+		}
+		return err
+	}},
+}
+
+// backupTimeLayout 备份文件名中的时间戳。
+const backupTimeLayout = "20060102-150405"
+
+// errNoFileBackup 数据库不是 SQLite 文件，无法自动备份。
+var errNoFileBackup = errors.New("database is not a SQLite file: back it up manually (e.g. pg_dump for Postgres) before destructive migrations")
+
+// sqliteFilePath 从 DSN 中取出 SQLite 文件路径（纯函数）：内存数据库返回 memory=true；不是 SQLite 文件时 ok=false。
+func sqliteFilePath(dsn string) (path string, memory, ok bool) {
+	if strings.Contains(dsn, "://") || strings.Contains(dsn, "host=") {
+		return "", false, false
+	}
+	path = strings.TrimPrefix(dsn, "file:")
+	if i := strings.IndexByte(path, '?'); i >= 0 {
+		path = path[:i]
+	}
+	if path == "" || path == ":memory:" || strings.Contains(dsn, "mode=memory") {
+		return "", true, true
+	}
+	return path, false, true
+}
+
+// backupFileName 备份文件路径：与数据库同目录，<db>.bak-<时间>（纯函数）。
+func backupFileName(dbPath string, now time.Time) string {
+	return dbPath + ".bak-" + now.Format(backupTimeLayout)
+}
+
+// openSQLiteReadOnly 以只读方式打开 SQLite 文件（不做迁移），用于校验备份。
+func openSQLiteReadOnly(path string) (*gorm.DB, error) {
+	return gorm.Open(sqlite.Open("file:"+path+"?mode=ro"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+}
+
+// verifySQLiteFile 确认文件能以只读方式打开，quick_check 通过且至少有一张表。
+func verifySQLiteFile(path string) error {
+	if _, err := os.Stat(path); err != nil {
+		return err
+	}
+	conn, err := openSQLiteReadOnly(path)
+	if err != nil {
+		return err
+	}
+	if sqlDB, err := conn.DB(); err == nil {
+		defer sqlDB.Close()
+	}
+	var result string
+	if err := conn.Raw("PRAGMA quick_check").Scan(&result).Error; err != nil {
+		return err
+	}
+	if result != "ok" {
+		return fmt.Errorf("quick_check: %s", result)
+	}
+	var tables int64
+	if err := conn.Raw("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table'").Scan(&tables).Error; err != nil {
+		return err
+	}
+	if tables == 0 {
+		return errors.New("no tables in backup")
+	}
+	return nil
+}
+
+// backupSQLite 把 conn 所在的数据库备份到 dbPath 旁边并校验副本，返回备份路径。
+// 使用 VACUUM INTO：连接打开期间也能得到一致的副本（含尚未写回主文件的 WAL 内容）。
+func backupSQLite(conn *gorm.DB, dbPath string, now time.Time) (string, error) {
+	target := backupFileName(dbPath, now)
+	if _, err := os.Stat(target); err == nil {
+		return "", fmt.Errorf("backup %s already exists", target)
+	}
+	if err := conn.Exec("VACUUM INTO ?", target).Error; err != nil {
+		return "", fmt.Errorf("备份失败: %w", err)
+	}
+	if err := verifySQLiteFile(target); err != nil {
+		return "", fmt.Errorf("备份 %s 校验失败: %w", target, err)
+	}
+	return target, nil
+}
+
+// migrationRun 一次启动中的迁移执行：可分多批执行（AutoMigrate 前后各一批），破坏性迁移共用同一份备份。
+type migrationRun struct {
+	conn   *gorm.DB
+	dsn    string
+	now    time.Time
+	backup string // 本次已做的备份，空表示尚未备份
+}
+
+// runVersionedMigrations 启动迁移的入口：依次执行 pre 中尚未执行的迁移、schema（建表 / 加列，可为 nil）、
+// post 中尚未执行的迁移；破坏性迁移执行前先备份（见模块说明），同一次调用共用一份备份，任一步失败即停止。
+func runVersionedMigrations(conn *gorm.DB, dsn string, now time.Time, pre []versionedMigration, schema func(conn *gorm.DB) error, post []versionedMigration) error {
+	if err := conn.AutoMigrate(&SchemaMigration{}); err != nil {
+		return err
+	}
+	run := &migrationRun{conn: conn, dsn: dsn, now: now}
+	if err := run.apply(pre); err != nil {
+		return err
+	}
+	if schema != nil {
+		if err := schema(conn); err != nil {
+			return err
+		}
+	}
+	return run.apply(post)
+}
+
+// apply 执行一批迁移；本次启动的第一项破坏性迁移执行前备份，之后的破坏性迁移复用这份备份。
+func (r *migrationRun) apply(migrations []versionedMigration) error {
+	if len(migrations) == 0 {
+		return nil
+	}
+	conn, now := r.conn, r.now
+	var applied []int
+	if err := conn.Model(&SchemaMigration{}).Pluck("version", &applied).Error; err != nil {
+		return err
+	}
+	done := make(map[int]bool, len(applied))
+	for _, v := range applied {
+		done[v] = true
+	}
+	for _, m := range migrations {
+		if done[m.Version] {
+			continue
+		}
+		entry := SchemaMigration{Version: m.Version, Name: m.Name, Destructive: m.Destructive}
+		if m.Destructive {
+			path, memory, ok := sqliteFilePath(r.dsn)
+			switch {
+			case !ok:
+				return fmt.Errorf("迁移 %d %s: %w", m.Version, m.Name, errNoFileBackup)
+			case !memory && r.backup == "":
+				backup, err := backupSQLite(conn, path, now)
+				if err != nil {
+					return fmt.Errorf("迁移 %d %s 未执行: %w", m.Version, m.Name, err)
+				}
+				r.backup = backup
+				fmt.Printf("💾 破坏性迁移 %d %s 执行前已备份数据库: %s\n", m.Version, m.Name, backup)
+			}
+			entry.BackupPath = r.backup
+		}
+		err := conn.Transaction(func(tx *gorm.DB) error {
+			if err := m.Up(tx); err != nil {
+				return err
+			}
+			entry.AppliedAt = now
+			return tx.Create(&entry).Error
+		})
+		if err != nil {
+			if entry.BackupPath != "" {
+				return fmt.Errorf("迁移 %d %s 失败（备份保留在 %s）: %w", m.Version, m.Name, entry.BackupPath, err)
+			}
+			return fmt.Errorf("迁移 %d %s 失败: %w", m.Version, m.Name, err)
+		}
+		fmt.Printf("🧱 已执行迁移 %d %s\n", m.Version, m.Name)
+	}
+	return nil
+}
+
+// serverListening 本机 PORT 上是否有服务在监听（rollback-to 据此判断服务是否已停止）。
+func serverListening(port string) bool {
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort("127.0.0.1", port), time.Second)
+	if err != nil {
+		return false
+	}
+	conn.Close()
+	return true
+}
+
+// copyFile 复制文件内容并落盘。
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Sync(); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// rollbackToBackup 用备份替换数据库文件（服务须已停止）：先校验备份，再把当前库及其 -wal / -shm
+// 挪到 <db>.before-rollback-<时间>，最后把备份复制为数据库文件（备份本身保留）。返回挪走的旧库路径。
+func rollbackToBackup(dbPath, backup string, now time.Time) (string, error) {
+	if filepath.Clean(dbPath) == filepath.Clean(backup) {
+		return "", errors.New("backup is the database file itself")
+	}
+	if err := verifySQLiteFile(backup); err != nil {
+		return "", fmt.Errorf("备份不可用: %w", err)
+	}
+	aside := dbPath + ".before-rollback-" + now.Format(backupTimeLayout)
+	if _, err := os.Stat(dbPath); err == nil {
+		if err := os.Rename(dbPath, aside); err != nil {
+			return "", err
+		}
+	} else if !os.IsNotExist(err) {
+		return "", err
+	} else {
+		aside = ""
+	}
+	for _, suffix := range []string{"-wal", "-shm"} {
+		if _, err := os.Stat(dbPath + suffix); err == nil && aside != "" {
+			if err := os.Rename(dbPath+suffix, aside+suffix); err != nil {
+				return aside, err
+			}
+		}
+	}
+	if err := copyFile(backup, dbPath); err != nil {
+		return aside, fmt.Errorf("复制备份失败（旧库在 %s）: %w", aside, err)
+	}
+	return aside, nil
+}
+
+// runRollbackTo rollback-to 命令：在打开数据库之前执行，服务仍在运行时拒绝（--force 跳过检查）。
+func runRollbackTo(args []string) int {
+	backup := ""
+	for _, a := range args {
+		if !strings.HasPrefix(a, "--") {
+			backup = a
+			break
+		}
+	}
+	if backup == "" {
+		fmt.Println("❌ 用法：go run . rollback-to <备份文件>")
+		return 2
+	}
+	path, memory, ok := sqliteFilePath(appConfig.databaseDSN())
+	if !ok || memory {
+		fmt.Println("❌ DB_PATH 不是 SQLite 文件，无法回滚")
+		return 1
+	}
+	if !hasFlag(args, "--force") && serverListening(appConfig.Port) {
+		fmt.Printf("❌ 端口 %s 上仍有服务在运行，请先停止服务再回滚（确认不是本服务时加 --force）\n", appConfig.Port)
+		return 1
+	}
+	aside, err := rollbackToBackup(path, backup, time.Now())
+	if err != nil {
+		fmt.Printf("❌ 回滚失败: %v\n", err)
+		return 1
+	}
+	fmt.Printf("✅ 已用 %s 覆盖 %s\n", backup, path)
+	if aside != "" {
+		fmt.Printf("   回滚前的数据库保留在 %s\n", aside)
+	}
+	return 0
+}
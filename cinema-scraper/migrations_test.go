@@ -0,0 +1,190 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// TestMigrationBackup 迁移前备份：破坏性迁移先备份到临时目录并记入日志，失败时保留备份，rollback-to 换回备份。
+func TestMigrationBackup(t *testing.T) {
+	newTestRouter(t)
+	now := testNoon
+	setTestClock(t, now)
+
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "cinepath.db")
+	dsn := dbPath + "?_busy_timeout=5000"
+	conn, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := conn.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := firstError(
+		conn.AutoMigrate(&SchemaMigration{}),
+		conn.Exec("CREATE TABLE notes (id INTEGER PRIMARY KEY, body TEXT)").Error,
+		conn.Exec("INSERT INTO notes (body) VALUES ('before')").Error); err != nil {
+		t.Fatal(err)
+	}
+	body := func(c *gorm.DB) string {
+		var s string
+		c.Raw("SELECT group_concat(body, ',') FROM notes").Scan(&s)
+		return s
+	}
+	migrations := []versionedMigration{
+		{1, "add row", false, func(tx *gorm.DB) error { return tx.Exec("INSERT INTO notes (body) VALUES ('added')").Error }},
+		{2, "rewrite", true, func(tx *gorm.DB) error { return tx.Exec("UPDATE notes SET body = 'after'").Error }},
+	}
+	if err := runVersionedMigrations(conn, dsn, now, nil, nil, migrations); err != nil {
+		t.Fatal(err)
+	}
+	var log []SchemaMigration
+	conn.Order("version").Find(&log)
+	backup := backupFileName(dbPath, now)
+	backupConn, err := openSQLiteReadOnly(backup)
+	if err != nil {
+		t.Fatal(err)
+	}
+	backedUp := body(backupConn)
+	if bdb, err := backupConn.DB(); err == nil {
+		bdb.Close()
+	}
+
+	failing := append(migrations, versionedMigration{3, "broken", true, func(tx *gorm.DB) error {
+		if err := tx.Exec("DELETE FROM notes").Error; err != nil {
+			return err
+		}
+		return errors.New("boom")
+	}})
+	failErr := runVersionedMigrations(conn, dsn, now.Add(time.Minute), nil, nil, failing)
+	afterFailure := body(conn)
+	var logged int64
+	conn.Model(&SchemaMigration{}).Count(&logged)
+	pgErr := runVersionedMigrations(conn, "postgres://cinepath@localhost/cinepath", now, nil, nil, failing)
+	sqlDB.Close()
+
+	aside, err := rollbackToBackup(dbPath, backup, now.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	restored, err := openSQLiteReadOnly(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if rdb, err := restored.DB(); err == nil {
+			rdb.Close()
+		}
+	}()
+	_, asideErr := os.Stat(aside)
+	_, memory, _ := sqliteFilePath(testDSN)
+	if err := firstError(
+		expectEqual("migration log", len(log), 2),
+		expectEqual("non-destructive has no backup", len(log) == 2 && log[0].BackupPath == "", true),
+		expectEqual("destructive backup path", len(log) == 2 && log[1].BackupPath == backup, true),
+		expectEqual("backup taken before rewrite", backedUp, "before,added"),
+		expectEqual("failure keeps backup path in error", failErr != nil && strings.Contains(failErr.Error(), backupFileName(dbPath, now.Add(time.Minute))), true),
+		expectEqual("failed migration rolled back", afterFailure, "after,after"),
+		expectEqual("failed migration not logged", logged, int64(2)),
+		expectEqual("postgres refuses", errors.Is(pgErr, errNoFileBackup), true),
+		expectEqual("test dsn is memory", memory, true),
+		expectEqual("rolled back", body(restored), "before,added"),
+		expectEqual("previous db kept aside", asideErr, nil)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestStartupMigrations 启动数据改写：场次去重、补齐区 / 都道府县、清除兜底坐标经 runVersionedMigrations 只执行一次；
+// 只有去重与清除坐标是破坏性迁移，共用一份备份，回填不备份。
+func TestStartupMigrations(t *testing.T) {
+	newTestRouter(t)
+	now := testNoon
+	setTestClock(t, now)
+
+	dir := t.TempDir()
+	dsn := filepath.Join(dir, "cinepath.db") + "?_busy_timeout=5000"
+	closeConn := func(c *gorm.DB) {
+		if sqlDB, err := c.DB(); err == nil {
+			sqlDB.Close()
+		}
+	}
+	// 旧库：场次没有唯一索引且有重复行，影院的区为空、坐标是旧版本的兜底坐标
+	legacy, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cinema := Cinema{NameJP: "テスト旧館", Address: "東京都新宿区新宿3-15-15", Latitude: 35.6907, Longitude: 139.6929}
+	slot := Schedule{MovieID: 1, CinemaID: 1, PlayDate: time.Date(2026, 1, 27, 0, 0, 0, 0, time.UTC), StartTime: "18:00"}
+	dup := slot
+	if err := firstError(
+		legacy.AutoMigrate(&Cinema{}, &Schedule{}, &SearchGram{}),
+		legacy.Exec("DROP INDEX "+scheduleSlotIndex).Error,
+		legacy.Create(&cinema).Error,
+		legacy.Model(&Cinema{}).Where("id = ?", cinema.ID).UpdateColumns(map[string]interface{}{"district": "", "prefecture": ""}).Error,
+		legacy.Create(&slot).Error,
+		legacy.Create(&dup).Error); err != nil {
+		closeConn(legacy)
+		t.Fatal(err)
+	}
+	closeConn(legacy)
+
+	first, err := openDatabase(dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var slots int64
+	first.Model(&Schedule{}).Count(&slots)
+	var migrated Cinema
+	first.First(&migrated, cinema.ID)
+	var log []SchemaMigration
+	first.Order("version").Find(&log)
+	// 改写结果在下次启动时不应再被覆盖
+	first.Model(&Cinema{}).Where("id = ?", cinema.ID).UpdateColumn("district", "")
+	closeConn(first)
+
+	second, err := openDatabase(dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var reopened Cinema
+	second.First(&reopened, cinema.ID)
+	var logged int64
+	second.Model(&SchemaMigration{}).Count(&logged)
+	closeConn(second)
+	backups, _ := filepath.Glob(filepath.Join(dir, "cinepath.db.bak-*"))
+
+	versions := make([]string, 0, len(log))
+	sharedBackup := len(log) > 0 && log[0].BackupPath != ""
+	for _, m := range log {
+		versions = append(versions, fmt.Sprintf("%d:%s:%v", m.Version, m.Name, m.Destructive))
+		if m.Destructive {
+			sharedBackup = sharedBackup && m.BackupPath == log[0].BackupPath
+		} else {
+			sharedBackup = sharedBackup && m.BackupPath == ""
+		}
+	}
+	if err := firstError(
+		expectEqual("deduped", slots, 1),
+		expectEqual("district", migrated.District, "新宿区"),
+		expectEqual("prefecture", migrated.Prefecture, "東京都"),
+		expectEqual("fallback cleared", fmt.Sprint(migrated.Latitude, migrated.Longitude, migrated.GeocodeFailed), "0 0 true"),
+		expectEqual("versions", strings.Join(versions, ","),
+			"1:dedupe-schedule-slots:true,2:backfill-cinema-districts:false,3:backfill-cinema-prefectures:false,4:clear-random-fallback-coords:true,5:backfill-cinema-kana:false"),
+		expectEqual("one shared backup", sharedBackup, true),
+		expectEqual("backup files", len(backups), 1),
+		expectEqual("second start runs nothing", logged, 5),
+		expectEqual("not rewritten again", reopened.District, "")); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -1,6 +1,10 @@
 package main
 
-import "time"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
 
 // ===========================
 // 模块：领域模型定义（数据库表结构）
@@ -12,24 +16,34 @@ type Movie struct {
 	ID uint `gorm:"primaryKey"`
 
 	// 外部 ID：便于后续做外链 / 增量更新
-	TMDBID int    // tmdb_id
-	IMDBID string // imdb_id
+	TMDBID int    `gorm:"index"` // tmdb_id
+	IMDBID string `gorm:"index"` // imdb_id
+	// eiga.com 影片编号（section#m102345），抓取先按它匹配，唯一索引见 eigaids.go
+	EigaID string
 
 	// 标题与创作信息
-	TitleCN  string // 中文标题
-	TitleEN  string // 英文标题
-	TitleJP  string // 日文标题
-	Director string
-	Year     string
+	TitleCN string // 中文标题
+	TitleEN string // 英文标题
+	TitleJP string // 日文标题
+	// 其他影院使用的日文标题（JSON 字符串数组），同片异名合并时写入，抓取按别名直接命中，见 moviemerge.go
+	AltTitlesJP string `gorm:"type:text"`
+	Director    string
+	Year        string
 
 	// 文案与视觉素材
 	Synopsis string
 	Poster   string
 	Backdrop string
+	// 海报缓存图片的内容哈希（/img/:hash），为空表示尚未缓存，见 imagecache.go
+	PosterHash string
+	// 预告片（YouTube 观看地址），没有时为空串，见 trailer.go
+	TrailerURL string
 
 	// 影片时长与类型（类型暂用逗号分隔字符串，后续可拆表）
 	Runtime int
 	Genre   string
+	// eiga.com 标注的片长，用于校验 TMDB 匹配（见 moviequality.go）
+	EigaRuntime int
 
 	// 主演等信息以 JSON 数组存储，API 层解包为结构化字段
 	CastJSON string `gorm:"type:text"`
@@ -38,132 +52,86 @@ type Movie struct {
 	TMDBRating   float64
 	IMDBRating   float64
 	DoubanRating float64
+	// 投票数（TMDB vote_count / OMDb imdbVotes），见 ratings.go
+	TMDBVotes int
+	IMDBVotes int
+	// OMDb Ratings[] 中的 Rotten Tomatoes 新鲜度（百分比）与 Metacritic 分数（满分 100），0 表示未知，见 omdb.go
+	RTRating   int
+	Metacritic int
+	// OMDb 配额耗尽时跳过的影片，下次运行优先补全 IMDb 评分
+	IMDBPending bool `gorm:"index"`
+	// TMDB 故障熔断期间推迟补全的影片，下次 enrich-movies 优先处理，见 tmdbbreaker.go
+	TMDBPending bool `gorm:"index"`
+	// TMDB 搜不到（或搜索失败）的累计次数、最近一次原因与时间，enrich-movies 按退避间隔重试，见 enrichretry.go
+	EnrichAttempts  int `gorm:"index"`
+	LastEnrichError string
+	LastEnrichAt    *time.Time
+
+	// 作品类型：film / event（直播、中继等活动，不查 TMDB，列表默认不返回），空值视为 film，见 moviekind.go
+	Kind string `gorm:"index"`
+
+	// 原始语言（TMDB original_language，如 ja / en / fr）与策展人设置的默认版本（subbed / dubbed，空为未设置），
+	// 用于没有字幕 / 吹替标注的场次推断 audio_hint，见 audiohint.go
+	OriginalLanguage string
+	AudioDefault     string
 
 	// 放映状态与上映日期
 	Status      string    // showing / incoming
 	ReleaseDate time.Time // 上映日期
+	// 上映日期精度：day（精确日期）/ year（仅知道年份，按 1 月 1 日兜底）；空值按 day 处理
+	ReleaseDatePrecision string
+	// 日本院线上映日期（TMDB release_dates 中 JP 的 theatrical 条目）；TMDB 没有日本条目时为空，见 releasedates.go
+	JPReleaseDate *time.Time
+	// 人工锁定状态的截止日期（含当天），期间自动计算不覆盖 Status，见 status.go
+	StatusPinnedUntil *time.Time
 
 	// 策展文案
 	CuratorNote string
 
+	// 待人工复核原因（如 runtime_mismatch），为空表示无需复核，见 moviequality.go
+	ReviewReason string `gorm:"index"`
+
+	// 排片跨度摘要：历史上最早 / 最晚的排片日期，排片清理后仍保留，见 longrun.go
+	FirstSeen *time.Time
+	LastSeen  *time.Time
+
+	// 字段来源记录（JSON），见 provenance.go
+	ProvenanceJSON string `gorm:"type:text"`
+
 	CreatedAt time.Time
 	UpdatedAt time.Time
+	// 软删除：误抓 / 重复的影片由管理接口删除，保留期后由 purge-deleted 物理删除，见 moviedelete.go
+	DeletedAt gorm.DeletedAt `gorm:"index"`
 }
 
 // Schedule 排片表：连接 Movie 与 Cinema，并记录某天的多场次。
 type Schedule struct {
-	ID        uint      `gorm:"primaryKey"`
-	MovieID   uint      // 影片 ID
-	CinemaID  uint      // 影院 ID
-	PlayDate  time.Time // 放映日期
-	StartTime string    // 开始时间（HH:mm）
-	CreatedAt time.Time
-	UpdatedAt time.Time
-}
-
-// ===========================
-// 模块：初始化种子数据
-// 职责：为开发环境注入少量高质量样例影片，便于前端对接与 UI 调试
-// ===========================
-
-func seedInitialMovies() error {
-	var count int64
-	if err := db.Model(&Movie{}).Count(&count).Error; err != nil {
-		return err
-	}
-	if count > 0 {
-		// 已经有数据，则不再重复写入。
-		return nil
-	}
-
-	nowShowingDate, _ := time.Parse("2006-01-02", "2026-01-21")
-	incomingDate, _ := time.Parse("2006-01-02", "2026-01-24")
-
-	movies := []Movie{
-		{
-			TitleCN:      "狩猎",
-			TitleEN:      "THE HUNT",
-			Director:     "Thomas Vinterberg",
-			Year:         "2012",
-			Synopsis:     "一个关于谎言如何像病毒一样扩散，撕裂一个小镇的温情表象。",
-			Poster:       "",
-			Backdrop:     "",
-			TMDBRating:   8.1,
-			IMDBRating:   8.3,
-			DoubanRating: 9.1,
-			Status:       "showing",
-			ReleaseDate:  nowShowingDate,
-			CuratorNote:  "本周聚焦独立影院中的人本主义，讲述那些被主流忽视的声音。",
-		},
-		{
-			TitleCN:      "蜘蛛侠：纵横宇宙",
-			TitleEN:      "ACROSS THE SPIDER-VERSE",
-			Director:     "Kemp Powers",
-			Year:         "2023",
-			Synopsis:     "迈尔斯回归，一场跨越多元宇宙的奇幻冒险即将开启。",
-			Poster:       "",
-			Backdrop:     "",
-			TMDBRating:   8.4,
-			IMDBRating:   8.6,
-			DoubanRating: 8.5,
-			Status:       "incoming",
-			ReleaseDate:  incomingDate,
-			CuratorNote:  "多元宇宙中的超级英雄成长记录。",
-		},
-	}
-
-	return db.Create(&movies).Error
-}
-
-
-// seedInitialSchedules 为已有的影院和影片生成少量演示用排片数据。
-// 约定：
-// - 如果没有影院或电影，则不做任何事（避免在空库上失败）。
-func seedInitialSchedules() error {
-	var cinemaCount, movieCount int64
-	if err := db.Model(&Cinema{}).Count(&cinemaCount).Error; err != nil {
-		return err
-	}
-	if err := db.Model(&Movie{}).Count(&movieCount).Error; err != nil {
-		return err
-	}
-	if cinemaCount == 0 || movieCount == 0 {
-		return nil
-	}
-
-	var existing int64
-	if err := db.Model(&Schedule{}).Count(&existing).Error; err != nil {
-		return err
-	}
-	if existing > 0 {
-		return nil
-	}
-
-	var cinemas []Cinema
-	if err := db.Limit(2).Find(&cinemas).Error; err != nil {
-		return err
-	}
-	var movies []Movie
-	if err := db.Find(&movies).Error; err != nil {
-		return err
-	}
-	if len(cinemas) == 0 || len(movies) == 0 {
-		return nil
-	}
-
-	today, _ := time.Parse("2006-01-02", "2026-01-23")
-	schedules := []Schedule{
-		{MovieID: movies[0].ID, CinemaID: cinemas[0].ID, PlayDate: today, StartTime: "10:40"},
-		{MovieID: movies[0].ID, CinemaID: cinemas[0].ID, PlayDate: today, StartTime: "15:40"},
-		{MovieID: movies[0].ID, CinemaID: cinemas[0].ID, PlayDate: today, StartTime: "18:20"},
-	}
-	if len(movies) > 1 && len(cinemas) > 1 {
-		schedules = append(schedules,
-			Schedule{MovieID: movies[1].ID, CinemaID: cinemas[1].ID, PlayDate: today, StartTime: "14:00"},
-			Schedule{MovieID: movies[1].ID, CinemaID: cinemas[1].ID, PlayDate: today, StartTime: "19:00"},
-		)
-	}
-	return db.Create(&schedules).Error
+	ID uint `gorm:"primaryKey"`
+	// (影片, 影院, 日期, 开始时间) 唯一，见 scheduleupsert.go
+	MovieID   uint      `gorm:"uniqueIndex:idx_schedule_slot"` // 影片 ID
+	CinemaID  uint      `gorm:"uniqueIndex:idx_schedule_slot"` // 影院 ID
+	PlayDate  time.Time `gorm:"uniqueIndex:idx_schedule_slot"` // 放映日期
+	StartTime string    `gorm:"uniqueIndex:idx_schedule_slot"` // 开始时间（HH:mm）
+	// 余票状态：unknown / available / few / soldout（页面无标记时保持 unknown）
+	Availability string `gorm:"default:unknown"`
+	// 特别场次类型：舞台挨拶 / 先行上映 等（见 events.go）；普通场次为空，无法归类的注释原样保留
+	EventType string `gorm:"index"`
+	// 放映版本：subbed / dubbed（见 events.go）；没有标注时为空
+	Format string
+	// 场次脚注说明（排片表下方 ※ 标记的解释，见 footnotes.go）；没有标记时为空
+	Note string
+	// 会員限定场次（只对影院会员开放，见 membersonly.go）：不参与状态推算，公开接口默认不返回
+	MembersOnly bool `gorm:"default:false;index"`
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	// 随影片一起软删除 / 恢复
+	DeletedAt gorm.DeletedAt `gorm:"index"`
 }
 
-
+// 场次余票状态。
+const (
+	AvailabilityUnknown   = "unknown"
+	AvailabilityAvailable = "available"
+	AvailabilityFew       = "few"
+	AvailabilitySoldOut   = "soldout"
+)
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ===========================
+// 模块：放映某部影片的影院（/api/cinemas?movie_id=）
+// 职责：影片详情地图只标出正在放映该片的影院
+// - 通过 JOIN schedules 只返回在 date 当天有该片排片的影院
+// - 每家影院内联该片当天的场次（times / showtimes），地图气泡无需二次请求
+// - 定位失败的影院仍然返回，供列表视图使用，但标记 coords_resolved=false
+// ===========================
+
+// MovieCinemaItem 放映指定影片的影院及当天场次。
+type MovieCinemaItem struct {
+	CinemaItem
+	CoordsResolved bool       `json:"coords_resolved"` // false 时地图不应标出该影院
+	Times          []string   `json:"times"`
+	Showtimes      []Showtime `json:"showtimes"`
+}
+
+// cinemaCoordsResolved 坐标是否可信：非零、未标记定位失败，且不是旧版本的随机兜底（纯函数）。
+func cinemaCoordsResolved(cin Cinema) bool {
+	if cin.Latitude == 0 && cin.Longitude == 0 {
+		return false
+	}
+	return !cin.GeocodeFailed && cin.GeoStatus != GeoStatusRandom
+}
+
+// listCinemasForMovie 处理 /api/cinemas?movie_id=&date=（date 默认今天）。
+func listCinemasForMovie(c *gin.Context, rawMovieID, sortKey string) {
+	movieID, err := strconv.ParseUint(rawMovieID, 10, 64)
+	if err != nil || movieID == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid movie_id"})
+		return
+	}
+	var movie Movie
+	if err := db.Select("id").First(&movie, movieID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "movie not found"})
+		return
+	}
+	dateStr := c.Query("date")
+	if dateStr == "" {
+		dateStr = referenceTime(c).Format("2006-01-02")
+	} else if _, err := time.Parse("2006-01-02", dateStr); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid date, expected YYYY-MM-DD"})
+		return
+	}
+
+	var cinemas []Cinema
+	if err := db.Model(&Cinema{}).Distinct("cinemas.*").
+		Joins("JOIN schedules ON schedules.cinema_id = cinemas.id").
+		Where("schedules.movie_id = ? AND date(schedules.play_date) = ? AND schedules.members_only = ? AND schedules.deleted_at IS NULL", movie.ID, dateStr, false).
+		Find(&cinemas).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to query cinemas"})
+		return
+	}
+	sortCinemas(cinemas, sortKey)
+
+	var schedules []Schedule
+	if len(cinemas) > 0 {
+		if err := db.Where("movie_id = ? AND date(play_date) = ? AND members_only = ?", movie.ID, dateStr, false).Find(&schedules).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to query schedules"})
+			return
+		}
+	}
+	byCinema := make(map[uint][]Schedule)
+	for _, s := range schedules {
+		byCinema[s.CinemaID] = append(byCinema[s.CinemaID], s)
+	}
+
+	items := make([]MovieCinemaItem, 0, len(cinemas))
+	for _, cin := range cinemas {
+		list := byCinema[cin.ID]
+		sortSchedulesByStartTime(list)
+		item := MovieCinemaItem{
+			CinemaItem:     mapCinemaToItem(cin),
+			CoordsResolved: cinemaCoordsResolved(cin),
+			Times:          make([]string, 0, len(list)),
+			Showtimes:      make([]Showtime, 0, len(list)),
+		}
+		for _, s := range list {
+			item.Times = append(item.Times, s.StartTime)
+			item.Showtimes = append(item.Showtimes, movieShowtime(s, movie))
+		}
+		items = append(items, item)
+	}
+
+	c.JSON(http.StatusOK, withFreshness(gin.H{
+		"movie_id": movie.ID,
+		"date":     dateStr,
+		"items":    items,
+	}))
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ===========================
+// 模块：影片软删除 / 恢复 / 物理清理
+// 职责：
+// - DELETE /api/movies/:id（经 adminAuthMiddleware 鉴权）：软删除误抓 / 重复残留的影片，连同其排片（热表 + 归档表）
+// - POST /api/admin/movies/:id/restore：恢复影片及一起删除的排片
+// - purge-deleted 命令：物理删除软删除超过保留期的影片及其排片、状态历史
+// 说明：Movie / Schedule / ScheduleArchive 带 gorm.DeletedAt，普通查询自动排除已删除的行；
+//       抓取时遇到已删除的影片不会重新创建（errMovieDeleted）。
+// 调用方式：
+//   go run . purge-deleted [--days=30]
+// ===========================
+
+// movieDeleteRetentionDays 软删除影片的默认保留天数，期间可以恢复。
+const movieDeleteRetentionDays = 30
+
+// errMovieDeleted 按标题匹配到的影片已被软删除。
+var errMovieDeleted = errors.New("movie is deleted")
+
+// deleteMovieHandler 软删除影片及其排片：DELETE /api/movies/:id（需要管理令牌）
+func deleteMovieHandler(c *gin.Context) {
+	var movie Movie
+	if err := db.First(&movie, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "movie not found"})
+		return
+	}
+	var schedules, archived int64
+	err := db.Transaction(func(tx *gorm.DB) error {
+		res := tx.Where("movie_id = ?", movie.ID).Delete(&Schedule{})
+		if res.Error != nil {
+			return res.Error
+		}
+		schedules = res.RowsAffected
+		res = tx.Where("movie_id = ?", movie.ID).Delete(&ScheduleArchive{})
+		if res.Error != nil {
+			return res.Error
+		}
+		archived = res.RowsAffected
+		return tx.Delete(&movie).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete movie"})
+		return
+	}
+	fmt.Printf("🗑️ 影片已软删除 [%d %s]：排片 %d 条，归档排片 %d 条\n", movie.ID, movie.TitleJP, schedules, archived)
+	c.JSON(http.StatusOK, gin.H{
+		"id":                 movie.ID,
+		"deleted":            true,
+		"schedules":          schedules,
+		"archived_schedules": archived,
+	})
+}
+
+// restoreMovieHandler 恢复软删除的影片及其排片：POST /api/admin/movies/:id/restore
+func restoreMovieHandler(c *gin.Context) {
+	var movie Movie
+	if err := db.Unscoped().First(&movie, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "movie not found"})
+		return
+	}
+	if !movie.DeletedAt.Valid {
+		c.JSON(http.StatusConflict, gin.H{"error": "movie is not deleted"})
+		return
+	}
+	var schedules, archived int64
+	err := db.Transaction(func(tx *gorm.DB) error {
+		res := tx.Unscoped().Model(&Schedule{}).Where("movie_id = ? AND deleted_at IS NOT NULL", movie.ID).Update("deleted_at", nil)
+		if res.Error != nil {
+			return res.Error
+		}
+		schedules = res.RowsAffected
+		res = tx.Unscoped().Model(&ScheduleArchive{}).Where("movie_id = ? AND deleted_at IS NOT NULL", movie.ID).Update("deleted_at", nil)
+		if res.Error != nil {
+			return res.Error
+		}
+		archived = res.RowsAffected
+		return tx.Unscoped().Model(&movie).Update("deleted_at", nil).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to restore movie"})
+		return
+	}
+	fmt.Printf("♻️ 影片已恢复 [%d %s]：排片 %d 条，归档排片 %d 条\n", movie.ID, movie.TitleJP, schedules, archived)
+	c.JSON(http.StatusOK, gin.H{
+		"id":                 movie.ID,
+		"deleted":            false,
+		"schedules":          schedules,
+		"archived_schedules": archived,
+	})
+}
+
+// parseRetentionDaysFlag 解析 --days=N（N >= 0），默认 movieDeleteRetentionDays。
+func parseRetentionDaysFlag(args []string) (int, error) {
+	v, ok := flagValue(args, "--days")
+	if !ok {
+		return movieDeleteRetentionDays, nil
+	}
+	days, err := strconv.Atoi(v)
+	if err != nil || days < 0 {
+		return 0, fmt.Errorf("--days 应为非负整数: %q", v)
+	}
+	return days, nil
+}
+
+// purgeDeletedMovies 物理删除软删除早于 now - days 天的影片及其排片、归档排片与状态历史，返回清理的影片数。
+func purgeDeletedMovies(now time.Time, days int) (int, error) {
+	cutoff := now.AddDate(0, 0, -days)
+	var ids []uint
+	if err := db.Unscoped().Model(&Movie{}).
+		Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff).
+		Order("id").Pluck("id", &ids).Error; err != nil {
+		return 0, fmt.Errorf("查询待清理影片失败: %v", err)
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		for _, model := range []interface{}{&Schedule{}, &ScheduleArchive{}, &CinemaRunSummary{}, &MovieStatusEvent{}, &MovieTag{}, &Favorite{}} {
+			if err := tx.Unscoped().Where("movie_id IN ?", ids).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Unscoped().Where("id IN ?", ids).Delete(&Movie{}).Error
+	})
+	if err != nil {
+		return 0, fmt.Errorf("物理删除影片失败: %v", err)
+	}
+	return len(ids), nil
+}
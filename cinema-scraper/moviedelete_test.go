@@ -0,0 +1,49 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestDeleteAndRestoreMovie DELETE /api/movies/:id 需要管理令牌，影片连同排片软删除后公开接口查不到，恢复后重新出现。
+func TestDeleteAndRestoreMovie(t *testing.T) {
+	router := newTestRouter(t)
+	send := func(method, path, authorization string) testResponse {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, nil)
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		router.ServeHTTP(rec, req)
+		return testResponse{Status: rec.Code, Header: rec.Header(), Body: rec.Body.Bytes()}
+	}
+	var before int64
+	db.Model(&Schedule{}).Where("movie_id = ?", 1).Count(&before)
+
+	anonymous := send(http.MethodDelete, "/api/movies/1", "")
+	wrong := send(http.MethodDelete, "/api/movies/1", "Bearer nope")
+	var deleted struct {
+		Deleted   bool  `json:"deleted"`
+		Schedules int64 `json:"schedules"`
+	}
+	if err := expectJSON(send(http.MethodDelete, "/api/movies/1", "Bearer "+testAdminToken), &deleted); err != nil {
+		t.Fatal(err)
+	}
+	gone := testGet(router, "/api/movies/1")
+	var hidden int64
+	db.Model(&Schedule{}).Where("movie_id = ?", 1).Count(&hidden)
+	restored := send(http.MethodPost, "/api/admin/movies/1/restore", "Bearer "+testAdminToken)
+	back := testGet(router, "/api/movies/1")
+	if err := firstError(
+		expectStatus(anonymous, http.StatusUnauthorized),
+		expectStatus(wrong, http.StatusUnauthorized),
+		expectEqual("deleted", deleted.Deleted, true),
+		expectEqual("deleted schedules", deleted.Schedules, before),
+		expectStatus(gone, http.StatusNotFound),
+		expectEqual("visible schedules after delete", hidden, int64(0)),
+		expectStatus(restored, http.StatusOK),
+		expectStatus(back, http.StatusOK)); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm/clause"
+)
+
+// ===========================
+// 模块：影片事件（新片发现 / 补全完成 / 抓取变更）
+// 职责：
+// - 抓取新建 Movie 时发出 movie.discovered；之后 TMDB 补全成功时再发出 movie.enriched（带中英文标题、海报等）
+// - 每次成功抓取结束后发出 crawl.changes，changes 为相对上一次成功抓取的变更报告（与 /api/changes 相同，见 crawlrun.go）；
+//   首次抓取没有比较基准，不发出
+// - 事件先写入 movie_events 表（发件箱），(type, subject_id) 唯一：重跑抓取、重试补全都不会重复发出同一事件
+// - 配置了 EVENT_WEBHOOK_URL 时 POST 到该地址；投递失败只记录错误，下次抓取开始时重投，不影响抓取本身
+// - GET /api/events/stream 以 SSE 推送事件：抓取与 API 是不同进程，流接口轮询 movie_events 表，而不是依赖进程内通知
+// ===========================
+
+// 事件类型。
+const (
+	EventMovieDiscovered = "movie.discovered"
+	EventMovieEnriched   = "movie.enriched"
+	EventCrawlChanges    = "crawl.changes"
+)
+
+const (
+	eventWebhookTimeout  = 5 * time.Second
+	eventStreamPoll      = 2 * time.Second  // 流接口轮询 movie_events 表的间隔
+	eventStreamHeartbeat = 15 * time.Second // 没有事件时发送注释行，防止代理断开空闲连接
+	eventStreamBatch     = 100
+)
+
+// MovieEvent 已发出的事件（发件箱）。SubjectID 为影片 ID（crawl.changes 为 CrawlRun ID）。
+type MovieEvent struct {
+	ID            uint   `gorm:"primaryKey"`
+	Type          string `gorm:"uniqueIndex:idx_movie_event_subject"`
+	SubjectID     uint   `gorm:"uniqueIndex:idx_movie_event_subject"`
+	PayloadJSON   string `gorm:"type:text"`
+	CreatedAt     time.Time
+	DeliveredAt   *time.Time // webhook 投递成功的时间；未配置 webhook 时为空
+	DeliveryError string     // 最近一次投递失败的原因
+}
+
+// EventPayload webhook 请求体与 SSE data 的 JSON：影片事件带 movie，crawl.changes 带 changes。
+// 事件 ID 放在 webhook 的 X-Event-ID 头与 SSE 的 id 字段中。
+type EventPayload struct {
+	Type       string             `json:"type"`
+	OccurredAt string             `json:"occurred_at"`
+	Movie      *MovieItem         `json:"movie,omitempty"`
+	Changes    *CrawlChangeReport `json:"changes,omitempty"`
+}
+
+// eventWebhookURL 事件 webhook 地址；未配置时只写入 events 表与 SSE 流。
+func eventWebhookURL() string {
+	return strings.TrimSpace(os.Getenv("EVENT_WEBHOOK_URL"))
+}
+
+// emitMovieEvent 记录并投递一个影片事件；同一影片的同类事件只会发出一次。
+func emitMovieEvent(eventType string, m Movie) {
+	item := mapMovieToItem(m, "")
+	recordEvent(EventPayload{Type: eventType, Movie: &item}, m.ID, m.TitleJP)
+}
+
+// emitCrawlChangesEvent 成功抓取结束后记录并投递 crawl.changes；首次抓取（没有上一次成功抓取）不发出。
+func emitCrawlChangesEvent(run CrawlRun) {
+	report, ok, err := latestChangeReport(run)
+	if err != nil {
+		fmt.Printf("⚠️ 生成变更报告失败 [run #%d]: %v\n", run.ID, err)
+		return
+	}
+	if !ok {
+		return
+	}
+	recordEvent(EventPayload{Type: EventCrawlChanges, Changes: &report}, run.ID, fmt.Sprintf("run #%d", run.ID))
+}
+
+// recordEvent 写入发件箱并投递；(type, subjectID) 已存在时不重复发出。label 只用于日志。
+func recordEvent(payload EventPayload, subjectID uint, label string) {
+	now := time.Now()
+	payload.OccurredAt = now.In(tokyoLocation).Format(time.RFC3339)
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return
+	}
+	event := MovieEvent{Type: payload.Type, SubjectID: subjectID, PayloadJSON: string(body), CreatedAt: now}
+	res := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&event)
+	if res.Error != nil {
+		fmt.Printf("⚠️ 记录事件失败 [%s %s]: %v\n", payload.Type, label, res.Error)
+		return
+	}
+	if res.RowsAffected == 0 {
+		return // 之前的抓取已发出过
+	}
+	fmt.Printf("   📣 事件 %s: %s\n", payload.Type, label)
+	deliverEvent(&event)
+}
+
+// emitMovieEnrichedEvent 新片补全成功（TMDB 已匹配）后发出 movie.enriched；只对发出过 movie.discovered 的影片生效。
+func emitMovieEnrichedEvent(m Movie) {
+	if m.TMDBID == 0 {
+		return
+	}
+	var discovered int64
+	db.Model(&MovieEvent{}).Where("type = ? AND subject_id = ?", EventMovieDiscovered, m.ID).Count(&discovered)
+	if discovered == 0 {
+		return
+	}
+	emitMovieEvent(EventMovieEnriched, m)
+}
+
+// deliverEvent 将事件 POST 到 webhook 并记录结果；未配置 webhook 时什么也不做。
+func deliverEvent(event *MovieEvent) {
+	url := eventWebhookURL()
+	if url == "" {
+		return
+	}
+	client := &http.Client{Timeout: eventWebhookTimeout}
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewBufferString(event.PayloadJSON))
+	if err == nil {
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Event-Type", event.Type)
+		req.Header.Set("X-Event-ID", strconv.FormatUint(uint64(event.ID), 10))
+		var resp *http.Response
+		if resp, err = client.Do(req); err == nil {
+			resp.Body.Close()
+			if resp.StatusCode >= 300 {
+				err = fmt.Errorf("webhook responded %d", resp.StatusCode)
+			}
+		}
+	}
+	if err != nil {
+		fmt.Printf("⚠️ 事件投递失败 [%s #%d]: %v\n", event.Type, event.ID, err)
+		db.Model(event).Update("delivery_error", err.Error())
+		return
+	}
+	now := time.Now()
+	event.DeliveredAt = &now
+	db.Model(event).Updates(map[string]interface{}{"delivered_at": now, "delivery_error": ""})
+}
+
+// redeliverPendingEvents 重投之前投递失败的事件（按 ID 顺序），返回成功的数量。
+// 未配置 webhook 时写入的事件不会补投。
+func redeliverPendingEvents() int {
+	if eventWebhookURL() == "" {
+		return 0
+	}
+	var events []MovieEvent
+	if err := db.Where("delivered_at IS NULL AND delivery_error <> ''").Order("id").Find(&events).Error; err != nil {
+		fmt.Printf("⚠️ 查询待投递事件失败: %v\n", err)
+		return 0
+	}
+	delivered := 0
+	for i := range events {
+		deliverEvent(&events[i])
+		if events[i].DeliveredAt != nil {
+			delivered++
+		}
+	}
+	return delivered
+}
+
+// eventStreamHandler 事件流接口：
+// - GET /api/events/stream（Server-Sent Events，event 为事件类型，data 为 EventPayload）
+// - 默认只推送连接之后的新事件；断线重连时浏览器会带上 Last-Event-ID，也可用 ?since=<事件 ID> 补发
+func eventStreamHandler(c *gin.Context) {
+	lastID := uint64(0)
+	since := c.GetHeader("Last-Event-ID")
+	if since == "" {
+		since = c.Query("since")
+	}
+	if since != "" {
+		v, err := strconv.ParseUint(since, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid since"})
+			return
+		}
+		lastID = v
+	} else {
+		var latest MovieEvent
+		if err := db.Select("id").Order("id DESC").Limit(1).Find(&latest).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to query events"})
+			return
+		}
+		lastID = uint64(latest.ID)
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	poll := time.NewTicker(eventStreamPoll)
+	defer poll.Stop()
+	idleSince := time.Now()
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-poll.C:
+		}
+		var events []MovieEvent
+		if err := db.Where("id > ?", lastID).Order("id").Limit(eventStreamBatch).Find(&events).Error; err != nil {
+			return
+		}
+		for _, ev := range events {
+			fmt.Fprintf(c.Writer, "id: %d\nevent: %s\ndata: %s\n\n", ev.ID, ev.Type, ev.PayloadJSON)
+			lastID = uint64(ev.ID)
+		}
+		if len(events) == 0 {
+			if time.Since(idleSince) < eventStreamHeartbeat {
+				continue
+			}
+			fmt.Fprint(c.Writer, ": keep-alive\n\n")
+		}
+		idleSince = time.Now()
+		c.Writer.Flush()
+	}
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"golang.org/x/text/width"
+	"gorm.io/gorm"
+)
+
+// ===========================
+// 模块：作品类型（Movie.Kind：film / event）
+// 职责：
+// - 排片里混有 ライブビューイング、舞台挨拶中継、落語中継 等活动，它们永远匹配不到 TMDB，
+//   也不应该出现在影片列表里
+// - 片名包含非电影关键词的作品在创建时标记为 event：不再查询 TMDB，/api/movies 默认只返回 film（?kind=event|all 可查看）
+// - 关键词表是数据而不是代码：默认表见 rules/non_film_keywords.json（编译时嵌入），
+//   NON_FILM_KEYWORDS_FILE 指向同格式的 JSON 文件时追加其中的关键词
+// 说明：旧数据（kind 为空）视为 film，启动时按同一份关键词表补标一次；已标记的影片不会被重新分类。
+// ===========================
+
+// 作品类型。
+const (
+	MovieKindFilm  = "film"
+	MovieKindEvent = "event"
+)
+
+//go:embed rules/non_film_keywords.json
+var defaultNonFilmKeywordsJSON []byte
+
+// nonFilmKeywordFile 关键词文件格式。
+type nonFilmKeywordFile struct {
+	Keywords []string `json:"keywords"`
+}
+
+// nonFilmKeywordsCache 进程内只加载一次。
+var nonFilmKeywordsCache struct {
+	once     sync.Once
+	keywords []string
+}
+
+// parseNonFilmKeywords 解析关键词文件（纯函数），去掉空白项。
+func parseNonFilmKeywords(raw []byte) ([]string, error) {
+	var f nonFilmKeywordFile
+	if err := json.Unmarshal(raw, &f); err != nil {
+		return nil, err
+	}
+	out := make([]string, 0, len(f.Keywords))
+	for _, kw := range f.Keywords {
+		if kw = strings.TrimSpace(kw); kw != "" {
+			out = append(out, kw)
+		}
+	}
+	return out, nil
+}
+
+// nonFilmKeywords 当前生效的关键词：默认表 + NON_FILM_KEYWORDS_FILE（读取失败只打印，沿用默认表）。
+func nonFilmKeywords() []string {
+	nonFilmKeywordsCache.once.Do(func() {
+		keywords, err := parseNonFilmKeywords(defaultNonFilmKeywordsJSON)
+		if err != nil {
+			fmt.Printf("⚠️ 默认非电影关键词表解析失败: %v\n", err)
+		}
+		if path := strings.TrimSpace(os.Getenv("NON_FILM_KEYWORDS_FILE")); path != "" {
+			raw, err := os.ReadFile(path)
+			if err == nil {
+				var extra []string
+				if extra, err = parseNonFilmKeywords(raw); err == nil {
+					keywords = append(keywords, extra...)
+				}
+			}
+			if err != nil {
+				fmt.Printf("⚠️ 读取非电影关键词文件失败 [%s]: %v\n", path, err)
+			}
+		}
+		nonFilmKeywordsCache.keywords = keywords
+	})
+	return nonFilmKeywordsCache.keywords
+}
+
+// classifyMovieKind 按片名判断作品类型（纯函数）：全半角折叠、忽略大小写后包含任一关键词即为 event。
+func classifyMovieKind(title string, keywords []string) string {
+	folded := strings.ToUpper(width.Fold.String(title))
+	for _, kw := range keywords {
+		if strings.Contains(folded, strings.ToUpper(width.Fold.String(kw))) {
+			return MovieKindEvent
+		}
+	}
+	return MovieKindFilm
+}
+
+// isEventMovie 影片是否为活动（空值视为 film）。
+func isEventMovie(m Movie) bool {
+	return m.Kind == MovieKindEvent
+}
+
+// movieKindOrDefault API 输出的作品类型，旧数据的空值输出为 film。
+func movieKindOrDefault(m Movie) string {
+	if m.Kind == "" {
+		return MovieKindFilm
+	}
+	return m.Kind
+}
+
+// classifyUnlabeledMovies 为 kind 为空的旧数据补标作品类型，返回标记为 event 的影片数。
+func classifyUnlabeledMovies() (int, error) {
+	var movies []Movie
+	if err := db.Select("id", "title_jp").Where("kind IS NULL OR kind = ''").Find(&movies).Error; err != nil {
+		return 0, err
+	}
+	if len(movies) == 0 {
+		return 0, nil
+	}
+	keywords := nonFilmKeywords()
+	byKind := map[string][]uint{}
+	for _, m := range movies {
+		kind := classifyMovieKind(m.TitleJP, keywords)
+		byKind[kind] = append(byKind[kind], m.ID)
+	}
+	for kind, ids := range byKind {
+		if err := db.Model(&Movie{}).Where("id IN ?", ids).UpdateColumn("kind", kind).Error; err != nil {
+			return 0, err
+		}
+	}
+	return len(byKind[MovieKindEvent]), nil
+}
+
+// applyKindFilter ?kind=：film（默认，含旧数据的空值）/ event / all。
+func applyKindFilter(tx *gorm.DB, kind string) *gorm.DB {
+	switch kind {
+	case MovieKindEvent:
+		return tx.Where("kind = ?", MovieKindEvent)
+	case "all":
+		return tx
+	default:
+		return tx.Where("kind IS NULL OR kind <> ?", MovieKindEvent)
+	}
+}
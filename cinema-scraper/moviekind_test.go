@@ -0,0 +1,20 @@
+package main
+
+import (
+	"testing"
+)
+
+// TestClassifyMovieKind 作品类型：片名含直播 / 中继关键词（全半角、大小写不限）的作品为 event。
+func TestClassifyMovieKind(t *testing.T) {
+	setTestClock(t, beforeMidnight)
+
+	keywords := nonFilmKeywords()
+	if err := firstError(
+		expectEqual("live viewing", classifyMovieKind("【ライブビューイング】テスト・ツアー2026", keywords), MovieKindEvent),
+		expectEqual("full-width latin", classifyMovieKind("ＬＩＶＥ ＶＩＥＷＩＮＧ テスト", keywords), MovieKindEvent),
+		expectEqual("stage greeting relay", classifyMovieKind("テスト映画 舞台挨拶中継付き上映", keywords), MovieKindEvent),
+		expectEqual("plain film", classifyMovieKind("市民ケーン", keywords), MovieKindFilm),
+		expectEqual("stage greeting only", classifyMovieKind("テスト映画（舞台挨拶付き）", keywords), MovieKindFilm)); err != nil {
+		t.Fatal(err)
+	}
+}
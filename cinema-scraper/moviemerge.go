@@ -0,0 +1,350 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// ===========================
+// 模块：同片异名影片自动合并
+// 职责：
+// - 回顾展等场合同一部片在不同影院用不同日文标题（如「市民ケーン」/「CITIZEN KANE（シチズン・ケーン）」），
+//   抓取时会建出两条 Movie；补全后两者解析到同一个 TMDBID / IMDbID
+// - 抓取结束后按外部 ID 分组，把重复影片合并到信息更完整的一条：迁移排片 / 归档排片 / 状态历史，
+//   补齐保留影片缺失的字段，被合并影片的日文标题写入 AltTitlesJP，之后的抓取按别名直接命中
+// - 只合并外部 ID 完全相同的影片（保守）：TMDB 相同但 IMDb 不同（或反之）、或 eiga.com 片长相差超过容差时
+//   跳过并记录，留待人工处理
+// 调用方式：
+//   抓取（crawl-schedules / crawl-custom）结束后自动执行；也可手动运行 go run . merge-duplicate-movies
+// ===========================
+
+// movieMergeField 参与完整度评分与补齐的字段：column 为列名（同时用作来源记录的字段名）。
+type movieMergeField struct {
+	column string
+	filled func(m Movie) bool
+	copy   func(dst *Movie, src Movie)
+}
+
+var movieMergeFields = []movieMergeField{
+	{"tmdb_id", func(m Movie) bool { return m.TMDBID > 0 }, func(d *Movie, s Movie) { d.TMDBID = s.TMDBID }},
+	{"imdb_id", func(m Movie) bool { return m.IMDBID != "" }, func(d *Movie, s Movie) { d.IMDBID = s.IMDBID }},
+	{"eiga_id", func(m Movie) bool { return m.EigaID != "" }, func(d *Movie, s Movie) { d.EigaID = s.EigaID }},
+	{"title_cn", func(m Movie) bool { return m.TitleCN != "" }, func(d *Movie, s Movie) { d.TitleCN = s.TitleCN }},
+	{"title_en", func(m Movie) bool { return m.TitleEN != "" }, func(d *Movie, s Movie) { d.TitleEN = s.TitleEN }},
+	{"director", func(m Movie) bool { return m.Director != "" }, func(d *Movie, s Movie) { d.Director = s.Director }},
+	{"year", func(m Movie) bool { return m.Year != "" }, func(d *Movie, s Movie) { d.Year = s.Year }},
+	{"synopsis", func(m Movie) bool { return m.Synopsis != "" }, func(d *Movie, s Movie) { d.Synopsis = s.Synopsis }},
+	{"poster", func(m Movie) bool { return m.Poster != "" }, func(d *Movie, s Movie) { d.Poster = s.Poster }},
+	{"backdrop", func(m Movie) bool { return m.Backdrop != "" }, func(d *Movie, s Movie) { d.Backdrop = s.Backdrop }},
+	{"trailer_url", func(m Movie) bool { return m.TrailerURL != "" }, func(d *Movie, s Movie) { d.TrailerURL = s.TrailerURL }},
+	{"runtime", func(m Movie) bool { return m.Runtime > 0 }, func(d *Movie, s Movie) { d.Runtime = s.Runtime }},
+	{"eiga_runtime", func(m Movie) bool { return m.EigaRuntime > 0 }, func(d *Movie, s Movie) { d.EigaRuntime = s.EigaRuntime }},
+	{"genre", func(m Movie) bool { return m.Genre != "" }, func(d *Movie, s Movie) { d.Genre = s.Genre }},
+	{"cast_json", func(m Movie) bool { return m.CastJSON != "" && m.CastJSON != "[]" }, func(d *Movie, s Movie) { d.CastJSON = s.CastJSON }},
+	{"tmdb_rating", func(m Movie) bool { return m.TMDBRating > 0 }, func(d *Movie, s Movie) { d.TMDBRating, d.TMDBVotes = s.TMDBRating, s.TMDBVotes }},
+	{"imdb_rating", func(m Movie) bool { return m.IMDBRating > 0 }, func(d *Movie, s Movie) { d.IMDBRating, d.IMDBVotes = s.IMDBRating, s.IMDBVotes }},
+	{"rt_rating", func(m Movie) bool { return m.RTRating > 0 }, func(d *Movie, s Movie) { d.RTRating = s.RTRating }},
+	{"metacritic", func(m Movie) bool { return m.Metacritic > 0 }, func(d *Movie, s Movie) { d.Metacritic = s.Metacritic }},
+	{"douban_rating", func(m Movie) bool { return m.DoubanRating > 0 }, func(d *Movie, s Movie) { d.DoubanRating = s.DoubanRating }},
+	{"release_date", func(m Movie) bool { return !m.ReleaseDate.IsZero() }, func(d *Movie, s Movie) {
+		d.ReleaseDate, d.ReleaseDatePrecision = s.ReleaseDate, s.ReleaseDatePrecision
+	}},
+	{"jp_release_date", func(m Movie) bool { return m.JPReleaseDate != nil }, func(d *Movie, s Movie) { d.JPReleaseDate = s.JPReleaseDate }},
+	{"curator_note", func(m Movie) bool { return m.CuratorNote != "" }, func(d *Movie, s Movie) { d.CuratorNote = s.CuratorNote }},
+}
+
+// movieCompleteness 已填写的字段数（纯函数），用于选出保留哪一条。
+func movieCompleteness(m Movie) int {
+	n := 0
+	for _, f := range movieMergeFields {
+		if f.filled(m) {
+			n++
+		}
+	}
+	return n
+}
+
+// pickMergeKeeper 从一组重复影片中选出保留的一条（纯函数）：字段最完整者优先，相同时保留 ID 最小（最早创建）的。
+func pickMergeKeeper(group []Movie) int {
+	best := 0
+	for i := 1; i < len(group); i++ {
+		ci, cb := movieCompleteness(group[i]), movieCompleteness(group[best])
+		if ci > cb || (ci == cb && group[i].ID < group[best].ID) {
+			best = i
+		}
+	}
+	return best
+}
+
+// groupMoviesByKey 按 key 分组（纯函数），只返回两条及以上的组；key 为空串的影片不参与。
+// 组内按 ID 升序，组之间按首个 ID 升序，保证日志与合并顺序稳定。
+func groupMoviesByKey(movies []Movie, key func(Movie) string) [][]Movie {
+	byKey := make(map[string][]Movie)
+	for _, m := range movies {
+		if k := key(m); k != "" {
+			byKey[k] = append(byKey[k], m)
+		}
+	}
+	groups := make([][]Movie, 0)
+	for _, g := range byKey {
+		if len(g) < 2 {
+			continue
+		}
+		sort.Slice(g, func(i, j int) bool { return g[i].ID < g[j].ID })
+		groups = append(groups, g)
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i][0].ID < groups[j][0].ID })
+	return groups
+}
+
+// externalIDConflict 组内另一种外部 ID 互相矛盾时返回说明（纯函数）：两条都有值且不同才算矛盾，缺失不算。
+func externalIDConflict(group []Movie, other func(Movie) string) string {
+	seen := ""
+	for _, m := range group {
+		v := other(m)
+		if v == "" {
+			continue
+		}
+		if seen != "" && v != seen {
+			return fmt.Sprintf("%s ≠ %s", seen, v)
+		}
+		seen = v
+	}
+	return ""
+}
+
+// eigaRuntimeConflict 组内 eiga.com 片长互相矛盾时返回说明（纯函数）：TMDB 搜索把续集 / 重映版
+// 匹配到同一条目时，外部 ID 相同而 eiga.com 片长不同，这种情况不能自动合并。
+func eigaRuntimeConflict(group []Movie) string {
+	known := 0
+	for _, m := range group {
+		if m.EigaRuntime <= 0 {
+			continue
+		}
+		if runtimeMismatch(known, m.EigaRuntime) {
+			return fmt.Sprintf("eiga 片长 %d 分 ≠ %d 分", known, m.EigaRuntime)
+		}
+		known = m.EigaRuntime
+	}
+	return ""
+}
+
+func movieTMDBKey(m Movie) string {
+	if m.TMDBID <= 0 {
+		return ""
+	}
+	return fmt.Sprintf("tmdb:%d", m.TMDBID)
+}
+
+func movieIMDbKey(m Movie) string {
+	if m.IMDBID == "" {
+		return ""
+	}
+	return "imdb:" + m.IMDBID
+}
+
+// parseAltTitles 解析 AltTitlesJP（JSON 字符串数组）；空串或解析失败时返回空切片。
+func parseAltTitles(raw string) []string {
+	var titles []string
+	if raw == "" || json.Unmarshal([]byte(raw), &titles) != nil {
+		return []string{}
+	}
+	return titles
+}
+
+// addAltTitles 把 titles 加入 m.AltTitlesJP（去重，跳过空串与 m.TitleJP 本身），返回是否有新增。
+func addAltTitles(m *Movie, titles ...string) bool {
+	existing := parseAltTitles(m.AltTitlesJP)
+	seen := make(map[string]bool, len(existing))
+	for _, t := range existing {
+		seen[t] = true
+	}
+	added := false
+	for _, t := range titles {
+		t = strings.TrimSpace(t)
+		if t == "" || t == m.TitleJP || seen[t] {
+			continue
+		}
+		seen[t] = true
+		existing = append(existing, t)
+		added = true
+	}
+	if added {
+		if b, err := json.Marshal(existing); err == nil {
+			m.AltTitlesJP = string(b)
+		}
+	}
+	return added
+}
+
+// findMovieByAltTitle 按别名查找影片（不含已删除的）：先用 LIKE 粗筛，再解析 JSON 精确比对。
+func findMovieByAltTitle(title string) (Movie, bool) {
+	if title == "" {
+		return Movie{}, false
+	}
+	quoted, err := json.Marshal(title)
+	if err != nil {
+		return Movie{}, false
+	}
+	pattern := "%" + strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(string(quoted)) + "%"
+	var candidates []Movie
+	if err := db.Where(`alt_titles_jp LIKE ? ESCAPE '\'`, pattern).Order("id").Find(&candidates).Error; err != nil {
+		return Movie{}, false
+	}
+	for _, m := range candidates {
+		for _, t := range parseAltTitles(m.AltTitlesJP) {
+			if t == title {
+				return m, true
+			}
+		}
+	}
+	return Movie{}, false
+}
+
+// movieMergeResult 一次合并的统计。
+type movieMergeResult struct {
+	Schedules        int64 // 迁移的排片
+	DroppedSchedules int64 // 与保留影片槽位重复、直接丢弃的排片
+	Archived         int64 // 迁移的归档排片
+	FilledFields     []string
+}
+
+// mergeMovieInto 在一个事务内把 drop 合并进 keep：迁移排片与状态历史，补齐 keep 缺失的字段并记录别名，
+// 最后物理删除 drop（软删除会让 findOrCreateMovieByTitle 按旧标题命中已删除影片，从而跳过排片）。
+func mergeMovieInto(keep *Movie, drop Movie) (movieMergeResult, error) {
+	var res movieMergeResult
+	merged := *keep
+	for _, f := range movieMergeFields {
+		if !f.filled(merged) && f.filled(drop) {
+			f.copy(&merged, drop)
+			res.FilledFields = append(res.FilledFields, f.column)
+		}
+	}
+	// 补齐的字段沿用 drop 上的来源记录
+	if len(res.FilledFields) > 0 {
+		p, from := parseProvenance(merged.ProvenanceJSON), parseProvenance(drop.ProvenanceJSON)
+		for _, col := range res.FilledFields {
+			if entry, ok := from[col]; ok {
+				p[col] = entry
+			}
+		}
+		if b, err := json.Marshal(p); err == nil {
+			merged.ProvenanceJSON = string(b)
+		}
+	}
+	addAltTitles(&merged, append([]string{drop.TitleJP}, parseAltTitles(drop.AltTitlesJP)...)...)
+	if drop.FirstSeen != nil && (merged.FirstSeen == nil || drop.FirstSeen.Before(*merged.FirstSeen)) {
+		merged.FirstSeen = drop.FirstSeen
+	}
+	if drop.LastSeen != nil && (merged.LastSeen == nil || drop.LastSeen.After(*merged.LastSeen)) {
+		merged.LastSeen = drop.LastSeen
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		// 同一槽位两边都有场次时保留 keep 的（唯一索引也覆盖软删除的行，因此用 Unscoped）
+		r := tx.Unscoped().Where(`movie_id = ? AND EXISTS (SELECT 1 FROM schedules k
+			WHERE k.movie_id = ? AND k.cinema_id = schedules.cinema_id
+			AND k.play_date = schedules.play_date AND k.start_time = schedules.start_time)`, drop.ID, keep.ID).
+			Delete(&Schedule{})
+		if r.Error != nil {
+			return r.Error
+		}
+		res.DroppedSchedules = r.RowsAffected
+		r = tx.Unscoped().Model(&Schedule{}).Where("movie_id = ?", drop.ID).Update("movie_id", keep.ID)
+		if r.Error != nil {
+			return r.Error
+		}
+		res.Schedules = r.RowsAffected
+		r = tx.Unscoped().Model(&ScheduleArchive{}).Where("movie_id = ?", drop.ID).Update("movie_id", keep.ID)
+		if r.Error != nil {
+			return r.Error
+		}
+		res.Archived = r.RowsAffected
+		if err := mergeRunSummariesTx(tx, drop.ID, keep.ID); err != nil {
+			return err
+		}
+		if err := mergeMovieTagsTx(tx, drop.ID, keep.ID); err != nil {
+			return err
+		}
+		if err := mergeFavoritesTx(tx, drop.ID, keep.ID); err != nil {
+			return err
+		}
+		if err := tx.Model(&MovieStatusEvent{}).Where("movie_id = ?", drop.ID).Update("movie_id", keep.ID).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Delete(&Movie{}, drop.ID).Error; err != nil {
+			return err
+		}
+		return tx.Save(&merged).Error
+	})
+	if err != nil {
+		return res, err
+	}
+	*keep = merged
+	return res, nil
+}
+
+// mergeDuplicateGroups 合并按 key 分出的各组重复影片；other 为另一种外部 ID，组内矛盾时整组跳过。
+func mergeDuplicateGroups(key, other func(Movie) string) (int, error) {
+	var movies []Movie
+	if err := db.Where("tmdb_id > 0 OR imdb_id <> ''").Find(&movies).Error; err != nil {
+		return 0, fmt.Errorf("读取影片失败: %v", err)
+	}
+	merged := 0
+	for _, group := range groupMoviesByKey(movies, key) {
+		conflict := externalIDConflict(group, other)
+		if conflict == "" {
+			conflict = eigaRuntimeConflict(group)
+		}
+		if conflict != "" {
+			fmt.Printf("⚠️ %s 相同但存在矛盾（%s），跳过自动合并：", key(group[0]), conflict)
+			for _, m := range group {
+				fmt.Printf(" [%d %s]", m.ID, m.TitleJP)
+			}
+			fmt.Println()
+			continue
+		}
+		ki := pickMergeKeeper(group)
+		keep := group[ki]
+		for i, drop := range group {
+			if i == ki {
+				continue
+			}
+			res, err := mergeMovieInto(&keep, drop)
+			if err != nil {
+				return merged, fmt.Errorf("合并影片 %d -> %d 失败: %v", drop.ID, keep.ID, err)
+			}
+			merged++
+			fmt.Printf("🔗 合并同片异名影片（%s）：[%d %s] -> [%d %s]，迁移排片 %d 条（重复场次丢弃 %d 条）、归档排片 %d 条，补齐字段 %v\n",
+				key(keep), drop.ID, drop.TitleJP, keep.ID, keep.TitleJP,
+				res.Schedules, res.DroppedSchedules, res.Archived, res.FilledFields)
+		}
+	}
+	return merged, nil
+}
+
+// autoMergeDuplicateMovies 合并外部 ID 相同的影片：先按 TMDBID，再按 IMDbID（合并后补齐的 ID 也会参与第二轮），
+// 返回合并掉的影片数。
+func autoMergeDuplicateMovies() (int, error) {
+	byTMDB, err := mergeDuplicateGroups(movieTMDBKey, movieIMDbKey)
+	if err != nil {
+		return byTMDB, err
+	}
+	byIMDb, err := mergeDuplicateGroups(movieIMDbKey, movieTMDBKey)
+	return byTMDB + byIMDb, err
+}
+
+// runAutoMergeAfterCrawl 抓取结束后的自动合并：失败只记录，不影响抓取结果。
+func runAutoMergeAfterCrawl() {
+	n, err := autoMergeDuplicateMovies()
+	if err != nil {
+		fmt.Printf("⚠️ 自动合并重复影片失败: %v\n", err)
+	}
+	if n > 0 {
+		fmt.Printf("🔗 已自动合并 %d 部同片异名的重复影片\n", n)
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// TestAutoMergeDuplicateMovies 同片异名：TMDB 相同的影片合并到信息更完整的一条，别名直接命中。
+func TestAutoMergeDuplicateMovies(t *testing.T) {
+	newTestRouter(t)
+	now := testNoon
+	setTestClock(t, now)
+
+	playDate := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	sparse := Movie{TitleJP: "市民ケーン", TMDBID: 990001, Status: "showing"}
+	rich := Movie{TitleJP: "CITIZEN KANE（シチズン・ケーン）", TMDBID: 990001, IMDBID: "tt0033467",
+		TitleCN: "公民凯恩", TitleEN: "Citizen Kane", Poster: "/kane.jpg", Status: "showing"}
+	// TMDB 相同但 IMDb 不同：不合并
+	conflictA := Movie{TitleJP: "同名異作A", TMDBID: 990002, IMDBID: "tt0000001", Status: "showing"}
+	conflictB := Movie{TitleJP: "同名異作B", TMDBID: 990002, IMDBID: "tt0000002", Status: "showing"}
+	for _, m := range []*Movie{&sparse, &rich, &conflictA, &conflictB} {
+		if err := db.Create(m).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	shows := []Schedule{
+		{MovieID: sparse.ID, CinemaID: 1, PlayDate: playDate, StartTime: "10:00"},
+		{MovieID: sparse.ID, CinemaID: 1, PlayDate: playDate, StartTime: "14:00"}, // 与 rich 的场次重复
+		{MovieID: rich.ID, CinemaID: 1, PlayDate: playDate, StartTime: "14:00"},
+	}
+	if err := db.Create(&shows).Error; err != nil {
+		t.Fatal(err)
+	}
+	if _, err := autoMergeDuplicateMovies(); err != nil {
+		t.Fatal(err)
+	}
+
+	var kept Movie
+	if err := db.First(&kept, rich.ID).Error; err != nil {
+		t.Fatalf("keeper: %v", err)
+	}
+	var remaining, moved, conflicts int64
+	db.Unscoped().Model(&Movie{}).Where("id = ?", sparse.ID).Count(&remaining)
+	db.Model(&Schedule{}).Where("movie_id = ? AND date(play_date) = ?", rich.ID, "2026-03-01").Count(&moved)
+	db.Model(&Movie{}).Where("tmdb_id = ?", 990002).Count(&conflicts)
+	alias, err := findOrCreateMovieByTitle("市民ケーン", SourceEiga)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := firstError(
+		expectEqual("merged movie rows", remaining, int64(0)),
+		expectEqual("schedules on keeper", moved, int64(2)),
+		expectEqual("alt titles", kept.AltTitlesJP, `["市民ケーン"]`),
+		expectEqual("alias lookup", alias.ID, rich.ID),
+		expectEqual("conflicting movies kept apart", conflicts, int64(2))); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ===========================
+// 模块：影片匹配质量检查
+// 职责：
+// - 抓取排片时记录 eiga.com 标注的片长（EigaRuntime）
+// - TMDB 片长与 eiga.com 相差超过 runtimeMismatchTolerance 时，认为 TMDB 匹配可疑：
+//   不用 TMDB 片长覆盖 eiga.com 的值，并把影片放入待人工复核列表（ReviewReason）
+// - GET /api/admin/movies/review 列出待复核影片；场次全部是会員限定的影片也列在这里（见 membersonly.go）
+// ===========================
+
+// runtimeMismatchTolerance 片长允许的差值（分钟）：导演剪辑版、片尾彩蛋等通常在此范围内。
+const runtimeMismatchTolerance = 15
+
+// 待复核原因。
+const (
+	ReviewRuntimeMismatch = "runtime_mismatch"
+	// 热表中的场次全部是会員限定：不写入 Movie.ReviewReason，列表时按场次推算
+	ReviewMembersOnly = "members_only"
+)
+
+// eigaRuntimeRe eiga.com 的片长标注，如 "上映時間：121分" / "上映時間 2時間1分"。
+var eigaRuntimeRe = regexp.MustCompile(`上映時間[：:\s]*(?:(\d{1,2})時間)?(?:(\d{1,3})分)?`)
+
+// parseEigaRuntime 从影片区块文本中解析片长（分钟），找不到时返回 0（纯函数）。
+func parseEigaRuntime(text string) int {
+	m := eigaRuntimeRe.FindStringSubmatch(text)
+	if m == nil {
+		return 0
+	}
+	hours, _ := strconv.Atoi(m[1])
+	minutes, _ := strconv.Atoi(m[2])
+	return hours*60 + minutes
+}
+
+// runtimeMismatch 两个片长都已知且相差超过容差时返回 true（纯函数）；任一为 0 视为未知。
+func runtimeMismatch(eigaRuntime, tmdbRuntime int) bool {
+	if eigaRuntime <= 0 || tmdbRuntime <= 0 {
+		return false
+	}
+	diff := eigaRuntime - tmdbRuntime
+	if diff < 0 {
+		diff = -diff
+	}
+	return diff > runtimeMismatchTolerance
+}
+
+// captureEigaRuntime 记录 eiga.com 片长；影片还没有片长，或已有片长来自可疑的 TMDB 匹配时，
+// 改用 eiga.com 的值（来源 eiga），后者同时放入待复核列表。
+func captureEigaRuntime(m *Movie, runtime int) {
+	if runtime <= 0 || m.EigaRuntime == runtime {
+		return
+	}
+	updates := map[string]interface{}{"eiga_runtime": runtime}
+	m.EigaRuntime = runtime
+	if m.Runtime != 0 && parseProvenance(m.ProvenanceJSON)["runtime"].Source != SourceEiga && !checkTmdbRuntime(m, m.Runtime) {
+		updates["review_reason"] = m.ReviewReason
+		m.Runtime = 0
+	}
+	if m.Runtime == 0 {
+		m.Runtime = runtime
+		recordProvenance(&m.ProvenanceJSON, SourceEiga, "runtime")
+		updates["runtime"] = runtime
+		updates["provenance_json"] = m.ProvenanceJSON
+	}
+	if err := db.Model(m).Updates(updates).Error; err != nil {
+		fmt.Printf("⚠️ 保存 eiga.com 片长失败 [%s]: %v\n", m.TitleJP, err)
+	}
+}
+
+// checkTmdbRuntime 对比 TMDB 片长：可疑时标记待复核并返回 false（调用方不得使用该片长）。
+func checkTmdbRuntime(m *Movie, tmdbRuntime int) bool {
+	if !runtimeMismatch(m.EigaRuntime, tmdbRuntime) {
+		return true
+	}
+	if m.ReviewReason == "" {
+		fmt.Printf("🚩 TMDB 匹配可疑 [%s]: eiga.com 片长 %d 分钟，TMDB(%d) 片长 %d 分钟\n",
+			m.TitleJP, m.EigaRuntime, m.TMDBID, tmdbRuntime)
+		m.ReviewReason = ReviewRuntimeMismatch
+	}
+	return false
+}
+
+// ReviewMovie 待复核影片（管理接口）。
+type ReviewMovie struct {
+	ID           uint   `json:"id"`
+	Title        string `json:"title"`
+	TitleJP      string `json:"title_jp"`
+	TMDBID       int    `json:"tmdb_id"`
+	Runtime      int    `json:"runtime"`
+	EigaRuntime  int    `json:"eiga_runtime"`
+	ReviewReason string `json:"review_reason"`
+	ReviewNote   string `json:"review_note"` // 原因说明；目前只有会員限定影片带说明
+}
+
+// listReviewMoviesHandler 待人工复核的影片：GET /api/admin/movies/review
+// 场次全部是会員限定的影片对外为 unplanned，同样列出（没有其他原因时 review_reason 为 members_only）。
+func listReviewMoviesHandler(c *gin.Context) {
+	membersOnlyIDs, err := membersOnlyMovieIDs()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to query schedules"})
+		return
+	}
+	membersOnly := make(map[uint]bool, len(membersOnlyIDs))
+	for _, id := range membersOnlyIDs {
+		membersOnly[id] = true
+	}
+	var movies []Movie
+	if err := db.Where("review_reason <> '' OR id IN ?", append(membersOnlyIDs, 0)).Order("id").Find(&movies).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to query movies"})
+		return
+	}
+	items := make([]ReviewMovie, 0, len(movies))
+	for _, m := range movies {
+		reason, note := m.ReviewReason, ""
+		if membersOnly[m.ID] {
+			note = membersOnlyReviewNote
+			if reason == "" {
+				reason = ReviewMembersOnly
+			}
+		}
+		items = append(items, ReviewMovie{
+			ID:           m.ID,
+			Title:        movieDisplayTitleLang(m, requestLang(c)),
+			TitleJP:      m.TitleJP,
+			TMDBID:       m.TMDBID,
+			Runtime:      m.Runtime,
+			EigaRuntime:  m.EigaRuntime,
+			ReviewReason: reason,
+			ReviewNote:   note,
+		})
+	}
+	c.JSON(http.StatusOK, gin.H{"items": items})
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestRuntimeMismatch 片长校验：eiga.com 与 TMDB 片长相差超过容差时不采用 TMDB 片长并进入复核列表，容差内保持不变。
+func TestRuntimeMismatch(t *testing.T) {
+	newTestRouter(t)
+	now := testNoon
+	setTestClock(t, now)
+
+	suspicious := Movie{TitleJP: "テスト片長不一致", TMDBID: 4242, Runtime: 150}
+	recordProvenance(&suspicious.ProvenanceJSON, SourceTMDBjaJP, "runtime")
+	matched := Movie{TitleJP: "テスト片長一致", TMDBID: 4243, Runtime: 135}
+	recordProvenance(&matched.ProvenanceJSON, SourceTMDBjaJP, "runtime")
+	if err := firstError(db.Create(&suspicious).Error, db.Create(&matched).Error); err != nil {
+		t.Fatal(err)
+	}
+	captureEigaRuntime(&suspicious, parseEigaRuntime("（2023年製作／121分／G／日本）上映時間：121分"))
+	captureEigaRuntime(&matched, parseEigaRuntime("上映時間 2時間"))
+	var stored []Movie
+	db.Where("id IN ?", []uint{suspicious.ID, matched.ID}).Order("id").Find(&stored)
+	parts := make([]string, 0, len(stored))
+	for _, m := range stored {
+		parts = append(parts, fmt.Sprintf("%d/%d/%s/%s", m.Runtime, m.EigaRuntime, parseProvenance(m.ProvenanceJSON)["runtime"].Source, m.ReviewReason))
+	}
+	rec := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(rec)
+	c.Request = httptest.NewRequest(http.MethodGet, "/api/admin/movies/review", nil)
+	listReviewMoviesHandler(c)
+	var review struct {
+		Items []ReviewMovie `json:"items"`
+	}
+	if err := expectJSON(testResponse{Status: rec.Code, Body: rec.Body.Bytes()}, &review); err != nil {
+		t.Fatal(err)
+	}
+	reviewed := map[uint]bool{}
+	for _, it := range review.Items {
+		reviewed[it.ID] = true
+	}
+	if err := firstError(
+		expectEqual("stored", strings.Join(parts, ","), "121/121/eiga/runtime_mismatch,135/120/tmdb-jaJP/"),
+		expectEqual("review list", [2]bool{reviewed[suspicious.ID], reviewed[matched.ID]}, [2]bool{true, false}),
+		expectEqual("mismatch", [5]bool{runtimeMismatch(120, 135), runtimeMismatch(120, 136), runtimeMismatch(136, 120), runtimeMismatch(0, 200), runtimeMismatch(120, 0)},
+			[5]bool{false, true, true, false, false}),
+		expectEqual("parse", [3]int{parseEigaRuntime("上映時間 2時間1分"), parseEigaRuntime("上映時間：95分"), parseEigaRuntime("121分")}, [3]int{121, 95, 0})); err != nil {
+		t.Fatal(err)
+	}
+}
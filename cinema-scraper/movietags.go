@@ -0,0 +1,284 @@
+package main
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ===========================
+// 模块：影片标签（MovieTag，策展用，独立于 TMDB 类型）
+// 职责：
+// - 影片与自由文本标签的关联表，用于「小津安二郎特集」「4K リマスター」「映画祭」这类类型表达不了的策展栏目
+// - 管理接口：单部影片增删标签、按标签批量增删影片、删除整个标签（连同全部关联行）
+// - 公开接口：/api/movies?tag= 过滤；/api/tags 列出标签及当前在映的影片数
+// 说明：标签只存在于关联表中，没有单独的标签表；最后一部影片移除后标签自然消失。
+//       影片合并时标签并入保留的一方，物理删除影片时一并清除（见 moviemerge.go / moviedelete.go）。
+// 调用方式：
+//   POST   /api/admin/movies/:id/tags        {"tags":["小津安二郎特集"]}
+//   DELETE /api/admin/movies/:id/tags/:tag
+//   POST   /api/admin/tags/:tag/movies       {"ids":[1,2,3]}
+//   DELETE /api/admin/tags/:tag/movies       {"ids":[1,2]}
+//   DELETE /api/admin/tags/:tag
+// ===========================
+
+// maxMovieTagLength 标签最大长度（字符数）。
+const maxMovieTagLength = 40
+
+// MovieTag 影片与标签的关联。
+type MovieTag struct {
+	ID        uint   `gorm:"primaryKey"`
+	MovieID   uint   `gorm:"uniqueIndex:idx_movie_tag"`
+	Tag       string `gorm:"uniqueIndex:idx_movie_tag;index"`
+	CreatedAt time.Time
+}
+
+// TagCount /api/tags 的一项。
+type TagCount struct {
+	Tag     string `json:"tag"`
+	Showing int    `json:"showing"` // 今天及以后仍有排片的在映影片数
+	Total   int    `json:"total"`   // 打了该标签的影片总数
+}
+
+// normalizeMovieTag 规范化标签（纯函数）：去掉首尾空白并合并连续空白；为空或超长时返回 false。
+func normalizeMovieTag(raw string) (string, bool) {
+	tag := strings.Join(strings.Fields(raw), " ")
+	if tag == "" || utf8.RuneCountInString(tag) > maxMovieTagLength {
+		return "", false
+	}
+	return tag, true
+}
+
+// loadMovieTags 影片的标签，按名称排序。
+func loadMovieTags(movieID uint) []string {
+	tags := make([]string, 0)
+	db.Model(&MovieTag{}).Where("movie_id = ?", movieID).Order("tag").Pluck("tag", &tags)
+	return tags
+}
+
+// addMovieTags 为影片批量打上标签，已存在的关联忽略。
+func addMovieTags(tx *gorm.DB, movieIDs []uint, tag string) error {
+	if len(movieIDs) == 0 {
+		return nil
+	}
+	rows := make([]MovieTag, 0, len(movieIDs))
+	for _, id := range movieIDs {
+		rows = append(rows, MovieTag{MovieID: id, Tag: tag})
+	}
+	return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&rows).Error
+}
+
+// mergeMovieTagsTx 影片合并时把 drop 的标签并入 keep。
+func mergeMovieTagsTx(tx *gorm.DB, dropID, keepID uint) error {
+	var tags []string
+	if err := tx.Model(&MovieTag{}).Where("movie_id = ?", dropID).Pluck("tag", &tags).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("movie_id = ?", dropID).Delete(&MovieTag{}).Error; err != nil {
+		return err
+	}
+	for _, tag := range tags {
+		if err := addMovieTags(tx, []uint{keepID}, tag); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applyTagFilter ?tag= 只保留打了该标签的影片。
+func applyTagFilter(tx *gorm.DB, tag string) *gorm.DB {
+	return tx.Where("id IN (?)", db.Model(&MovieTag{}).Select("movie_id").Where("tag = ?", tag))
+}
+
+// existingMovieIDs 过滤出存在（未软删除）的影片 ID，按 ID 排序。
+func existingMovieIDs(ids []uint) ([]uint, error) {
+	found := make([]uint, 0, len(ids))
+	if len(ids) == 0 {
+		return found, nil
+	}
+	err := db.Model(&Movie{}).Where("id IN ?", ids).Order("id").Pluck("id", &found).Error
+	return found, err
+}
+
+// movieTagsRequest 单部影片增加标签的请求体。
+type movieTagsRequest struct {
+	Tags []string `json:"tags"`
+}
+
+// tagMoviesRequest 按标签批量增删影片的请求体。
+type tagMoviesRequest struct {
+	IDs []uint `json:"ids"`
+}
+
+// tagParam 解析路径中的标签，非法时直接返回 400。
+func tagParam(c *gin.Context) (string, bool) {
+	tag, ok := normalizeMovieTag(c.Param("tag"))
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid tag"})
+	}
+	return tag, ok
+}
+
+// addMovieTagsHandler 为单部影片增加标签：POST /api/admin/movies/:id/tags
+func addMovieTagsHandler(c *gin.Context) {
+	var req movieTagsRequest
+	if err := c.ShouldBindJSON(&req); err != nil || len(req.Tags) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "tags is required"})
+		return
+	}
+	tags := make([]string, 0, len(req.Tags))
+	for _, raw := range req.Tags {
+		tag, ok := normalizeMovieTag(raw)
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid tag"})
+			return
+		}
+		tags = append(tags, tag)
+	}
+	var movie Movie
+	if err := db.First(&movie, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "movie not found"})
+		return
+	}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		for _, tag := range tags {
+			if err := addMovieTags(tx, []uint{movie.ID}, tag); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to add tags"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"id": movie.ID, "tags": loadMovieTags(movie.ID)})
+}
+
+// removeMovieTagHandler 移除单部影片的一个标签：DELETE /api/admin/movies/:id/tags/:tag
+func removeMovieTagHandler(c *gin.Context) {
+	tag, ok := tagParam(c)
+	if !ok {
+		return
+	}
+	var movie Movie
+	if err := db.First(&movie, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "movie not found"})
+		return
+	}
+	if err := db.Where("movie_id = ? AND tag = ?", movie.ID, tag).Delete(&MovieTag{}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to remove tag"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"id": movie.ID, "tags": loadMovieTags(movie.ID)})
+}
+
+// bindTagMovies 解析批量请求：标签与影片 ID（只保留存在的影片）。
+func bindTagMovies(c *gin.Context) (string, []uint, bool) {
+	tag, ok := tagParam(c)
+	if !ok {
+		return "", nil, false
+	}
+	var req tagMoviesRequest
+	if err := c.ShouldBindJSON(&req); err != nil || len(req.IDs) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ids is required"})
+		return "", nil, false
+	}
+	ids, err := existingMovieIDs(req.IDs)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to query movies"})
+		return "", nil, false
+	}
+	return tag, ids, true
+}
+
+// addTagMoviesHandler 为一批影片打上标签：POST /api/admin/tags/:tag/movies {"ids":[1,2]}
+// 不存在的影片 ID 忽略，updated 为实际处理的影片。
+func addTagMoviesHandler(c *gin.Context) {
+	tag, ids, ok := bindTagMovies(c)
+	if !ok {
+		return
+	}
+	if err := addMovieTags(db, ids, tag); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to add tag"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"tag": tag, "updated": ids})
+}
+
+// removeTagMoviesHandler 从一批影片上移除标签：DELETE /api/admin/tags/:tag/movies {"ids":[1,2]}
+func removeTagMoviesHandler(c *gin.Context) {
+	tag, ids, ok := bindTagMovies(c)
+	if !ok {
+		return
+	}
+	if len(ids) > 0 {
+		if err := db.Where("tag = ? AND movie_id IN ?", tag, ids).Delete(&MovieTag{}).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to remove tag"})
+			return
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"tag": tag, "updated": ids})
+}
+
+// deleteTagHandler 删除整个标签（清除全部关联行）：DELETE /api/admin/tags/:tag
+func deleteTagHandler(c *gin.Context) {
+	tag, ok := tagParam(c)
+	if !ok {
+		return
+	}
+	res := db.Where("tag = ?", tag).Delete(&MovieTag{})
+	if res.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete tag"})
+		return
+	}
+	if res.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "tag not found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"tag": tag, "removed": res.RowsAffected})
+}
+
+// listTagsHandler 标签列表：GET /api/tags
+// - showing 为今天及以后仍有排片、状态为在映的电影数（不含活动与软删除的影片），total 为打了该标签的影片总数
+// - 按 showing 降序、标签名升序；前端可只展示 showing > 0 的标签作为策展栏目
+func listTagsHandler(c *gin.Context) {
+	today := referenceTime(c).Format("2006-01-02")
+	totals := make([]TagCount, 0)
+	if err := db.Model(&MovieTag{}).Select("tag, COUNT(*) AS total").
+		Where("movie_id IN (?)", db.Model(&Movie{}).Select("id")).
+		Group("tag").Scan(&totals).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to query tags"})
+		return
+	}
+	showingMovies := applyStatusFilter(applyKindFilter(db.Model(&Movie{}), MovieKindFilm), "showing", today, hasAsOf(c)).
+		Where("id IN (?)", db.Model(&Schedule{}).Select("movie_id").Where("date(play_date) >= ? AND members_only = ?", today, false)).
+		Select("id")
+	var showing []TagCount
+	if err := db.Model(&MovieTag{}).Select("tag, COUNT(*) AS showing").
+		Where("movie_id IN (?)", showingMovies).
+		Group("tag").Scan(&showing).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to query tags"})
+		return
+	}
+	showingByTag := make(map[string]int, len(showing))
+	for _, s := range showing {
+		showingByTag[s.Tag] = s.Showing
+	}
+	for i := range totals {
+		totals[i].Showing = showingByTag[totals[i].Tag]
+	}
+	sort.Slice(totals, func(i, j int) bool {
+		if totals[i].Showing != totals[j].Showing {
+			return totals[i].Showing > totals[j].Showing
+		}
+		return totals[i].Tag < totals[j].Tag
+	})
+	c.JSON(http.StatusOK, gin.H{"items": totals, "total": len(totals)})
+}
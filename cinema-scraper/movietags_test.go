@@ -0,0 +1,25 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+// TestTagsEndpoint 策展标签列表。
+func TestTagsEndpoint(t *testing.T) {
+	runHTTPCases(t, []httpCase{
+		{"策展标签：在映影片数只计今天以后仍有排片的影片", "/api/tags", func(r testResponse) error {
+			var body struct {
+				Items []TagCount `json:"items"`
+			}
+			if err := expectJSON(r, &body); err != nil {
+				return err
+			}
+			got := make([]string, 0, len(body.Items))
+			for _, t := range body.Items {
+				got = append(got, fmt.Sprintf("%s:%d/%d", t.Tag, t.Showing, t.Total))
+			}
+			return expectEqual("tags", fmt.Sprint(got), "[小津安二郎特集:2/3 映画祭:0/1]")
+		}},
+	})
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ===========================
+// 模块：附近影院（/api/cinemas/nearby）
+// 职责：按用户坐标返回 radius_km 内的影院，附带直线距离并按距离升序
+// - SQLite 没有地理函数，距离在 Go 里用 haversineKm 计算（影院只有一两百家，全表扫描足够）
+// - 定位失败（geocode_failed，坐标为 0/0）的影院不参与计算，只在 unresolved_excluded 中计数
+// ===========================
+
+const (
+	nearbyDefaultRadiusKm = 2.0
+	nearbyMaxRadiusKm     = 20.0
+)
+
+// NearbyCinemaItem 附近影院：CinemaItem 加上与用户坐标的距离。
+type NearbyCinemaItem struct {
+	CinemaItem
+	DistanceKm float64 `json:"distance_km"`
+}
+
+// nearbyCinemas 从 cinemas 中筛出 origin 周围 radiusKm 内坐标可信的影院并按距离排序（纯函数）。
+// 返回结果与因坐标未解析而跳过的影院数。
+func nearbyCinemas(cinemas []Cinema, origin geoPoint, radiusKm float64) ([]NearbyCinemaItem, int) {
+	items := make([]NearbyCinemaItem, 0)
+	unresolved := 0
+	for _, cin := range cinemas {
+		if !cinemaCoordsResolved(cin) {
+			unresolved++
+			continue
+		}
+		d := haversineKm(origin.Lat, origin.Lng, cin.Latitude, cin.Longitude)
+		if d > radiusKm {
+			continue
+		}
+		items = append(items, NearbyCinemaItem{CinemaItem: mapCinemaToItem(cin), DistanceKm: math.Round(d*100) / 100})
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		if items[i].DistanceKm != items[j].DistanceKm {
+			return items[i].DistanceKm < items[j].DistanceKm
+		}
+		return items[i].ID < items[j].ID
+	})
+	return items, unresolved
+}
+
+// nearbyCinemasHandler 附近影院接口：
+// - GET /api/cinemas/nearby?lat=35.69&lng=139.70&radius_km=2
+// - lat / lng 必填；radius_km 默认 2，最大 20
+func nearbyCinemasHandler(c *gin.Context) {
+	lat, errLat := strconv.ParseFloat(c.Query("lat"), 64)
+	lng, errLng := strconv.ParseFloat(c.Query("lng"), 64)
+	if errLat != nil || errLng != nil || math.Abs(lat) > 90 || math.Abs(lng) > 180 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "valid lat and lng are required"})
+		return
+	}
+	radius := nearbyDefaultRadiusKm
+	if raw := c.Query("radius_km"); raw != "" {
+		v, err := strconv.ParseFloat(raw, 64)
+		if err != nil || !(v > 0) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid radius_km"})
+			return
+		}
+		radius = math.Min(v, nearbyMaxRadiusKm)
+	}
+
+	var cinemas []Cinema
+	if err := db.Order("id").Find(&cinemas).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to query cinemas"})
+		return
+	}
+	items, unresolved := nearbyCinemas(cinemas, geoPoint{Lat: lat, Lng: lng}, radius)
+	c.JSON(http.StatusOK, gin.H{
+		"lat":                 lat,
+		"lng":                 lng,
+		"radius_km":           radius,
+		"total":               len(items),
+		"unresolved_excluded": unresolved,
+		"items":               items,
+	})
+}
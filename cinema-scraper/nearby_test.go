@@ -0,0 +1,33 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+// TestNearbyCinemas 附近影院：距离排序、参数校验。
+func TestNearbyCinemas(t *testing.T) {
+	runHTTPCases(t, []httpCase{
+		{"附近影院：按距离升序并排除兜底坐标", "/api/cinemas/nearby?lat=35.6909&lng=139.7036&radius_km=4", func(r testResponse) error {
+			var body struct {
+				Items      []NearbyCinemaItem `json:"items"`
+				Unresolved int                `json:"unresolved_excluded"`
+			}
+			if err := expectJSON(r, &body); err != nil {
+				return err
+			}
+			ids := make([]uint, 0, len(body.Items))
+			for _, it := range body.Items {
+				ids = append(ids, it.ID)
+			}
+			return firstError(expectEqual("ids", fmt.Sprint(ids), "[1 2]"), expectEqual("unresolved_excluded", body.Unresolved, 1))
+		}},
+		{"附近影院：缺少坐标", "/api/cinemas/nearby?radius_km=2", func(r testResponse) error {
+			return expectStatus(r, http.StatusBadRequest)
+		}},
+		{"附近影院：非法 radius_km", "/api/cinemas/nearby?lat=35.69&lng=139.70&radius_km=-1", func(r testResponse) error {
+			return expectStatus(r, http.StatusBadRequest)
+		}},
+	})
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/width"
+)
+
+// ===========================
+// 模块：文本规范化（标题去重 / TMDB 匹配 / 搜索共用）
+// 职责：统一全半角折叠、注释括号剥离与空白处理，避免各处实现不一致导致重复影片
+// 说明：
+// - NormalizeTitle：入库与去重使用的标准标题（保留可读性）。
+// - NormalizeForSearch：搜索 / 匹配使用的比较键（忽略大小写、空白、标点、片平假名差异）。
+// - StripAnnotations：去掉【IMAX】、（字幕版）、★ 等排片注释。
+// ===========================
+
+// annotationBracketRe 总是视为注释的括号：【】［］[]＜＞<>〈〉《》。
+var annotationBracketRe = regexp.MustCompile(`【[^】]*】|［[^］]*］|\[[^\]]*\]|＜[^＞]*＞|<[^>]*>|〈[^〉]*〉|《[^》]*》`)
+
+// parenRe 圆括号内容：只有包含注释关键字时才剥离（避免误删片名的一部分）。
+var parenRe = regexp.MustCompile(`（[^）]*）|\([^)]*\)`)
+
+// annotationKeywords 圆括号中出现这些词时视为排片注释。
+var annotationKeywords = []string{
+	"字幕", "吹替", "版", "リマスター", "上映", "IMAX", "4K", "2K", "3D", "Dolby", "ドルビー",
+	"MX4D", "4DX", "ScreenX", "応援", "舞台挨拶", "同時", "特集",
+}
+
+// leadingMarksRe 标题前后的装饰符号。
+var leadingMarksRe = regexp.MustCompile(`^[★☆◆◇■□●○※♪]+|[★☆◆◇■□●○※♪]+$`)
+
+// StripAnnotations 去掉标题中的排片注释（括号注释、装饰符号），不做全半角与空白处理。
+func StripAnnotations(s string) string {
+	s = annotationBracketRe.ReplaceAllString(s, " ")
+	s = parenRe.ReplaceAllStringFunc(s, func(p string) string {
+		upper := strings.ToUpper(p)
+		for _, kw := range annotationKeywords {
+			if strings.Contains(upper, strings.ToUpper(kw)) {
+				return " "
+			}
+		}
+		return p
+	})
+	s = strings.TrimSpace(s)
+	return strings.TrimSpace(leadingMarksRe.ReplaceAllString(s, ""))
+}
+
+// NormalizeTitle 标准标题：全角英数折叠为半角、半角片假名折叠为全角，剥离注释，合并空白。
+// 例："【IMAX】ＴＯＫＹＯ　タクシー（字幕版）" -> "TOKYO タクシー"
+func NormalizeTitle(s string) string {
+	s = width.Fold.String(s)
+	s = StripAnnotations(s)
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// NormalizeForSearch 搜索比较键：在 NormalizeTitle 基础上转小写、片假名转平假名，
+// 并去掉空白与标点（保留长音符 ー）。
+// 例："シチズン・ケーン" 与 "しちずん けーん" 得到同一个键。
+func NormalizeForSearch(s string) string {
+	s = strings.ToLower(NormalizeTitle(s))
+	var b strings.Builder
+	for _, r := range toHiragana(s) {
+		if unicode.IsSpace(r) || unicode.IsPunct(r) || unicode.IsSymbol(r) {
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
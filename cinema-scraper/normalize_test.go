@@ -0,0 +1,41 @@
+package main
+
+import (
+	"testing"
+)
+
+// TestNormalizeTitleCorpus 文本规范化：eiga.com 实际片名语料，入库标题、搜索键与注释剥离。
+func TestNormalizeTitleCorpus(t *testing.T) {
+	setTestClock(t, beforeMidnight)
+
+	titles := []struct{ raw, want string }{
+		{"【IMAX】ＴＯＫＹＯ　タクシー（字幕版）", "TOKYO タクシー"},
+		{"ﾃｽﾄ映画", "テスト映画"},
+		{"（吹替版）ズートピア２", "ズートピア2"},
+		{"ＴＨＥ　ＦＩＲＳＴ　ＳＬＡＭ　ＤＵＮＫ", "THE FIRST SLAM DUNK"},
+		{"劇場版 名探偵コナン（ドルビーシネマ上映）", "劇場版 名探偵コナン"},
+		{"［終］パーフェクト・デイズ", "パーフェクト・デイズ"},
+		{"＜4Kデジタルリマスター版＞東京物語", "東京物語"},
+		{"《特集上映》小津安二郎 晩春", "小津安二郎 晩春"},
+		{"  ルックバック  ◆  ", "ルックバック"},
+		{"君たちはどう生きるか(応援上映)", "君たちはどう生きるか"},
+		{"スパイダーマン：アクロス・ザ・スパイダーバース（IMAX　3D）", "スパイダーマン:アクロス・ザ・スパイダーバース"},
+		// 不含注释关键字的圆括号是片名的一部分
+		{"ゴジラ(1954)", "ゴジラ(1954)"},
+		{"ボーはおそれている（Beau Is Afraid）", "ボーはおそれている(Beau Is Afraid)"},
+	}
+	for _, tt := range titles {
+		if err := expectEqual(tt.raw, NormalizeTitle(tt.raw), tt.want); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := firstError(
+		expectEqual("dedupe key", NormalizeTitle("ﾃｽﾄ映画"), NormalizeTitle("テスト映画（字幕版）")),
+		expectEqual("search kana", NormalizeForSearch("シチズン・ケーン"), "しちずんけーん"),
+		expectEqual("search hiragana", NormalizeForSearch("しちずん けーん"), "しちずんけーん"),
+		expectEqual("search latin", NormalizeForSearch("ＴＨＥ　ＦＩＲＳＴ　ＳＬＡＭ　ＤＵＮＫ"), "thefirstslamdunk"),
+		expectEqual("search annotation", NormalizeForSearch("【字幕版】落語テスト会"), NormalizeForSearch("落語テスト会")),
+		expectEqual("strip keeps width", StripAnnotations("★ＡＢＣ（字幕版）"), "ＡＢＣ")); err != nil {
+		t.Fatal(err)
+	}
+}
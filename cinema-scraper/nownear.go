@@ -0,0 +1,233 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ===========================
+// 模块：此刻附近（Now Near）
+// 职责：回答“T 时刻前后、L 附近能看什么”
+// - 时间窗：开场时间落在 [T-window, T+window] 内，且不早于现在（只看今天时）
+// - 空间：影院与用户坐标的直线距离不超过 radius
+// - 排序：开场时间接近度与距离的加权，附带步行分钟数与影片摘要
+// 说明：候选过滤与排序分离，排序逻辑为纯函数 rankNowNear。
+// ===========================
+
+const (
+	nowNearDefaultWindow = 45  // 分钟
+	nowNearMaxWindow     = 180 // 分钟
+	nowNearDefaultRadius = 2.0 // 公里
+	nowNearMaxRadius     = 10.0
+	nowNearDefaultLimit  = 20
+	nowNearMaxLimit      = 50
+
+	nowNearWeightTime     = 0.5
+	nowNearWeightDistance = 0.5
+
+	// walkingMetersPerMinute 步行速度：按日本不动产广告惯例 80m/分钟
+	walkingMetersPerMinute = 80.0
+)
+
+// NowNearItem 单个场次结果。
+type NowNearItem struct {
+	Movie          MovieItem  `json:"movie"`
+	Cinema         CinemaItem `json:"cinema"`
+	Time           string     `json:"time"`
+	Availability   string     `json:"availability"`
+	EventType      string     `json:"event_type"`
+	DistanceKm     float64    `json:"distance_km"`
+	WalkingMinutes int        `json:"walking_minutes"`
+	Score          float64    `json:"score"`
+}
+
+// nowNearCandidate 待排序的候选场次（距离已计算）。
+type nowNearCandidate struct {
+	Schedule   Schedule
+	Movie      Movie
+	Cinema     Cinema
+	DistanceKm float64
+}
+
+// walkingMinutes 直线距离换算的步行分钟数（向上取整）。
+func walkingMinutes(km float64) int {
+	return int(math.Ceil(km * 1000 / walkingMetersPerMinute))
+}
+
+// nowNearScore 计算候选得分（纯函数）：开场越接近 T、距离越近得分越高，均归一到 [0, 1]。
+func nowNearScore(cand nowNearCandidate, atMinutes, window int, radiusKm float64) float64 {
+	diff := math.Abs(float64(startTimeMinutes(cand.Schedule.StartTime) - atMinutes))
+	timeScore := 1 - diff/float64(window)
+	distScore := 1 - cand.DistanceKm/radiusKm
+	return nowNearWeightTime*math.Max(timeScore, 0) + nowNearWeightDistance*math.Max(distScore, 0)
+}
+
+// rankNowNear 打分并按得分降序排序，同分时开场早的在前（纯函数）；lang 决定影片默认标题。
+func rankNowNear(cands []nowNearCandidate, atMinutes, window int, radiusKm float64, lang string) []NowNearItem {
+	items := make([]NowNearItem, 0, len(cands))
+	for _, cand := range cands {
+		items = append(items, NowNearItem{
+			Movie:          mapMovieToItem(cand.Movie, lang),
+			Cinema:         mapCinemaToItem(cand.Cinema),
+			Time:           cand.Schedule.StartTime,
+			Availability:   scheduleToShowtime(cand.Schedule).Availability,
+			EventType:      cand.Schedule.EventType,
+			DistanceKm:     math.Round(cand.DistanceKm*100) / 100,
+			WalkingMinutes: walkingMinutes(cand.DistanceKm),
+			Score:          math.Round(nowNearScore(cand, atMinutes, window, radiusKm)*1000) / 1000,
+		})
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		if items[i].Score != items[j].Score {
+			return items[i].Score > items[j].Score
+		}
+		return startTimeMinutes(items[i].Time) < startTimeMinutes(items[j].Time)
+	})
+	return items
+}
+
+// loadNowNearCandidates 查询 radius 内影院在 date 当天、开场时间位于 [from, to] 分钟内且未满席的场次。
+func loadNowNearCandidates(origin geoPoint, radiusKm float64, date string, from, to int) ([]nowNearCandidate, error) {
+	var cinemas []Cinema
+	if err := db.Where("latitude <> 0 OR longitude <> 0").Order("id").Find(&cinemas).Error; err != nil {
+		return nil, err
+	}
+	nearby := make(map[uint]Cinema)
+	distances := make(map[uint]float64)
+	ids := make([]uint, 0)
+	for _, cin := range cinemas {
+		d := haversineKm(origin.Lat, origin.Lng, cin.Latitude, cin.Longitude)
+		if d > radiusKm {
+			continue
+		}
+		nearby[cin.ID] = cin
+		distances[cin.ID] = d
+		ids = append(ids, cin.ID)
+	}
+	if len(ids) == 0 {
+		return []nowNearCandidate{}, nil
+	}
+
+	var schedules []Schedule
+	if err := db.Where("cinema_id IN ? AND date(play_date) = ? AND members_only = ?", ids, date, false).
+		Where("availability IS NULL OR availability <> ?", AvailabilitySoldOut).
+		Order("id").Find(&schedules).Error; err != nil {
+		return nil, err
+	}
+	inWindow := make([]Schedule, 0, len(schedules))
+	movieIDs := make(map[uint]struct{})
+	for _, s := range schedules {
+		m := startTimeMinutes(s.StartTime)
+		if m < 0 || m < from || m > to {
+			continue
+		}
+		inWindow = append(inWindow, s)
+		movieIDs[s.MovieID] = struct{}{}
+	}
+	if len(inWindow) == 0 {
+		return []nowNearCandidate{}, nil
+	}
+
+	var movies []Movie
+	if err := db.Where("id IN ?", sortedIDs(movieIDs)).Find(&movies).Error; err != nil {
+		return nil, err
+	}
+	movieMap := make(map[uint]Movie, len(movies))
+	for _, m := range movies {
+		movieMap[m.ID] = m
+	}
+
+	cands := make([]nowNearCandidate, 0, len(inWindow))
+	for _, s := range inWindow {
+		m, ok := movieMap[s.MovieID]
+		if !ok {
+			continue
+		}
+		cands = append(cands, nowNearCandidate{
+			Schedule:   s,
+			Movie:      m,
+			Cinema:     nearby[s.CinemaID],
+			DistanceKm: distances[s.CinemaID],
+		})
+	}
+	return cands, nil
+}
+
+// nowNearHandler 此刻附近接口：
+// - GET /api/now-near?lat=&lng=&at=19:00&window=45&radius=2&limit=20
+// - at 默认为现在（东京时间）；date=YYYY-MM-DD 可查其他日期，默认今天
+// - 今天的查询会排除已开场的场次；满席场次始终排除
+func nowNearHandler(c *gin.Context) {
+	lat, errLat := strconv.ParseFloat(c.Query("lat"), 64)
+	lng, errLng := strconv.ParseFloat(c.Query("lng"), 64)
+	if errLat != nil || errLng != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "lat and lng are required"})
+		return
+	}
+
+	now := referenceTime(c)
+	today := now.Format("2006-01-02")
+	nowMinutes := now.Hour()*60 + now.Minute()
+
+	date := c.DefaultQuery("date", today)
+	if _, err := time.Parse("2006-01-02", date); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid date, expected YYYY-MM-DD"})
+		return
+	}
+
+	atMinutes := nowMinutes
+	if raw := c.Query("at"); raw != "" {
+		atMinutes = startTimeMinutes(raw)
+		if atMinutes < 0 || atMinutes >= 30*60 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid at, expected HH:MM"})
+			return
+		}
+	}
+
+	window := nowNearDefaultWindow
+	if v, err := strconv.Atoi(c.Query("window")); err == nil && v > 0 {
+		window = min(v, nowNearMaxWindow)
+	}
+	radius := nowNearDefaultRadius
+	if v, err := strconv.ParseFloat(c.Query("radius"), 64); err == nil && v > 0 {
+		radius = math.Min(v, nowNearMaxRadius)
+	}
+	limit := nowNearDefaultLimit
+	if v, err := strconv.Atoi(c.Query("limit")); err == nil && v > 0 {
+		limit = min(v, nowNearMaxLimit)
+	}
+
+	from, to := atMinutes-window, atMinutes+window
+	if date == today && from < nowMinutes {
+		from = nowMinutes // 已开场的场次不再推荐
+	}
+
+	items := []NowNearItem{}
+	if date >= today && from <= to {
+		cands, err := loadNowNearCandidates(geoPoint{Lat: lat, Lng: lng}, radius, date, from, to)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to query schedules"})
+			return
+		}
+		items = rankNowNear(cands, atMinutes, window, radius, requestLang(c))
+	}
+
+	total := len(items)
+	if len(items) > limit {
+		items = items[:limit]
+	}
+	c.JSON(http.StatusOK, withFreshness(gin.H{
+		"date":      date,
+		"at":        fmt.Sprintf("%02d:%02d", atMinutes/60, atMinutes%60),
+		"window":    window,
+		"radius_km": radius,
+		"total":     total,
+		"items":     items,
+	}))
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ===========================
+// 模块：OMDb 评分与配额保护
+// 职责：
+// - 一次 OMDb 查询同时取回 IMDb 评分 / 投票数，以及 Ratings[] 中的 Rotten Tomatoes（"85%"）与 Metacritic（"74/100"）
+// - "N/A" 视为未知（记为 0）；"Response":"False"（如 IMDb ID 不存在）视为查询失败，保留已有评分而不是写入 0
+// - 免费 key 每天 1000 次；一旦返回 "Request limit reached!"，本次运行内不再调用 OMDb
+// - 被跳过的影片标记为 IMDBPending，下次运行优先补全
+// - 对外暴露调用次数与封禁状态，供抓取汇总与 /api/stats 展示
+// ===========================
+
+// omdbRatings 一次 OMDb 查询解析出的评分；各项为 0 表示 OMDb 未提供。
+type omdbRatings struct {
+	IMDBRating float64
+	IMDBVotes  int
+	RTRating   int // Rotten Tomatoes 新鲜度（百分比）
+	Metacritic int // Metacritic 分数（满分 100）
+}
+
+var (
+	// errOmdbSkipped 未发起查询（没有 key / 配额已用尽）。
+	errOmdbSkipped = errors.New("omdb lookup skipped")
+	// errOmdbNoResult OMDb 返回 "Response":"False"：重试也不会有结果，不再标记为待补全。
+	errOmdbNoResult = errors.New("omdb returned no result")
+)
+
+// parseOmdbScore 解析 OMDb 的分数字符串（纯函数）："85%" -> 85，"74/100" -> 74，"74" -> 74，"N/A" 或无法解析 -> 0。
+func parseOmdbScore(value string) int {
+	value = strings.TrimSpace(value)
+	if i := strings.IndexByte(value, '/'); i >= 0 {
+		value = value[:i]
+	}
+	n, err := strconv.Atoi(strings.TrimSuffix(value, "%"))
+	if err != nil || n < 0 {
+		return 0
+	}
+	return n
+}
+
+// parseOmdbResponse 解析 OMDb 响应（纯函数）；"Response":"False" 时返回 errOmdbNoResult（带 OMDb 的错误信息）。
+// Metacritic 优先取 Ratings[]，没有时退回顶层的 Metascore。
+func parseOmdbResponse(raw string) (omdbRatings, error) {
+	var data struct {
+		Response  string `json:"Response"`
+		Error     string `json:"Error"`
+		Rating    string `json:"imdbRating"`
+		Votes     string `json:"imdbVotes"`
+		Metascore string `json:"Metascore"`
+		Ratings   []struct {
+			Source string `json:"Source"`
+			Value  string `json:"Value"`
+		} `json:"Ratings"`
+	}
+	if err := json.Unmarshal([]byte(raw), &data); err != nil {
+		return omdbRatings{}, err
+	}
+	if strings.EqualFold(data.Response, "False") {
+		return omdbRatings{}, fmt.Errorf("%w: %s", errOmdbNoResult, data.Error)
+	}
+	var out omdbRatings
+	out.IMDBRating, _ = strconv.ParseFloat(data.Rating, 64)
+	out.IMDBVotes, _ = strconv.Atoi(strings.ReplaceAll(data.Votes, ",", ""))
+	for _, r := range data.Ratings {
+		switch r.Source {
+		case "Rotten Tomatoes":
+			out.RTRating = parseOmdbScore(r.Value)
+		case "Metacritic":
+			out.Metacritic = parseOmdbScore(r.Value)
+		}
+	}
+	if out.Metacritic == 0 {
+		out.Metacritic = parseOmdbScore(data.Metascore)
+	}
+	return out, nil
+}
+
+// applyOmdbRatings 写入一次成功查询的评分并记录来源。
+func applyOmdbRatings(m *Movie, r omdbRatings) {
+	m.IMDBRating = r.IMDBRating
+	m.IMDBVotes = r.IMDBVotes
+	m.RTRating = r.RTRating
+	m.Metacritic = r.Metacritic
+	m.IMDBPending = false
+	recordProvenance(&m.ProvenanceJSON, SourceOMDb, "imdb_rating", "imdb_votes", "rt_rating", "metacritic")
+}
+
+// noteOmdbFailure 查询失败时保留已有评分：OMDb 明确没有结果时不再标记为待补全，其他错误（网络等）保持原标记。
+func noteOmdbFailure(m *Movie, err error) {
+	if errors.Is(err, errOmdbSkipped) {
+		return
+	}
+	fmt.Printf("⚠️ OMDb 查询失败 [%s %s]: %v\n", m.TitleJP, m.IMDBID, err)
+	if errors.Is(err, errOmdbNoResult) {
+		m.IMDBPending = false
+	}
+}
+
+// omdbState 进程内的 OMDb 调用状态（跨抓取回调共享，需加锁）。
+var omdbState struct {
+	sync.Mutex
+	calls   int  // 本次运行已发起的请求数
+	blocked bool // 是否已触发配额上限
+}
+
+// omdbBlocked 本次运行是否已触发 OMDb 配额上限。
+func omdbBlocked() bool {
+	omdbState.Lock()
+	defer omdbState.Unlock()
+	return omdbState.blocked
+}
+
+// omdbCountCall 记录一次 OMDb 请求。
+func omdbCountCall() {
+	omdbState.Lock()
+	omdbState.calls++
+	omdbState.Unlock()
+}
+
+// omdbMarkBlocked 标记已触发配额上限（仅首次打印提示）。
+func omdbMarkBlocked() {
+	omdbState.Lock()
+	defer omdbState.Unlock()
+	if !omdbState.blocked {
+		fmt.Printf("🚫 OMDb 配额已用尽（已请求 %d 次），本次运行不再调用 OMDb，剩余影片标记为待补全。\n", omdbState.calls)
+	}
+	omdbState.blocked = true
+}
+
+// omdbSnapshot 返回调用次数与封禁状态。
+func omdbSnapshot() (int, bool) {
+	omdbState.Lock()
+	defer omdbState.Unlock()
+	return omdbState.calls, omdbState.blocked
+}
+
+// isOmdbLimitResponse 判断 OMDb 返回体是否为配额耗尽错误。
+func isOmdbLimitResponse(raw string) bool {
+	return strings.Contains(raw, "Request limit reached")
+}
+
+// resumePendingImdbRatings 优先补全上次因配额耗尽而跳过的影片，返回成功补全的数量。
+func resumePendingImdbRatings() int {
+	var movies []Movie
+	if err := db.Where("imdb_pending = ? AND imdb_id <> ''", true).Find(&movies).Error; err != nil {
+		fmt.Printf("⚠️ 查询待补全 IMDb 评分的影片失败: %v\n", err)
+		return 0
+	}
+	if len(movies) == 0 {
+		return 0
+	}
+	fmt.Printf("ℹ️ 优先补全 %d 部上次因 OMDb 配额跳过的影片。\n", len(movies))
+
+	resumed := 0
+	for i := range movies {
+		if omdbBlocked() {
+			break
+		}
+		m := &movies[i]
+		ratings, _, err := fetchOmdbRatings(appConfig, m.IMDBID)
+		if omdbBlocked() {
+			break
+		}
+		if err != nil {
+			noteOmdbFailure(m, err)
+			if m.IMDBPending {
+				continue
+			}
+		} else {
+			applyOmdbRatings(m, ratings)
+		}
+		if err := db.Save(m).Error; err != nil {
+			fmt.Printf("⚠️ 保存 IMDb 评分失败 [%s]: %v\n", m.TitleJP, err)
+			continue
+		}
+		resumed++
+	}
+	return resumed
+}
+
+// printOmdbSummary 在补全结束时打印 OMDb 使用情况。
+func printOmdbSummary() {
+	calls, blocked := omdbSnapshot()
+	var pending int64
+	db.Model(&Movie{}).Where("imdb_pending = ?", true).Count(&pending)
+	state := "正常"
+	if blocked {
+		state = "已触发配额上限"
+	}
+	fmt.Printf("📊 OMDb：本次请求 %d 次，状态：%s，待补全 IMDb 评分 %d 部\n", calls, state, pending)
+}
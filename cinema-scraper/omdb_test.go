@@ -0,0 +1,38 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+// TestParseOMDbResponse OMDb：解析 Rotten Tomatoes / Metacritic / 投票数，N/A 记为 0，Response False 视为失败。
+func TestParseOMDbResponse(t *testing.T) {
+	setTestClock(t, beforeMidnight)
+
+	full, err := parseOmdbResponse(`{"Response":"True","imdbRating":"7.8","imdbVotes":"1,234,567","Metascore":"70",
+			"Ratings":[{"Source":"Internet Movie Database","Value":"7.8/10"},{"Source":"Rotten Tomatoes","Value":"85%"},{"Source":"Metacritic","Value":"74/100"}]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sparse, err := parseOmdbResponse(`{"Response":"True","imdbRating":"N/A","imdbVotes":"N/A","Metascore":"61","Ratings":[]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, notFound := parseOmdbResponse(`{"Response":"False","Error":"Incorrect IMDb ID."}`)
+
+	kept := Movie{TitleJP: "テストOMDb", IMDBID: "tt0000001", IMDBRating: 7.1, RTRating: 90, IMDBPending: true}
+	noteOmdbFailure(&kept, notFound)
+	applied := Movie{}
+	applyOmdbRatings(&applied, full)
+	item := mapMovieToItem(applied, "")
+	if err := firstError(
+		expectEqual("full", full, omdbRatings{IMDBRating: 7.8, IMDBVotes: 1234567, RTRating: 85, Metacritic: 74}),
+		expectEqual("sparse", sparse, omdbRatings{Metacritic: 61}),
+		expectEqual("response false", errors.Is(notFound, errOmdbNoResult), true),
+		expectEqual("kept on failure", [2]float64{kept.IMDBRating, float64(kept.RTRating)}, [2]float64{7.1, 90}),
+		expectEqual("no longer pending", kept.IMDBPending, false),
+		expectEqual("item", [3]int{item.IMDBVotes, item.RTRating, item.Metacritic}, [3]int{1234567, 85, 74}),
+		expectEqual("score formats", [4]int{parseOmdbScore("85%"), parseOmdbScore("74/100"), parseOmdbScore("N/A"), parseOmdbScore("")}, [4]int{85, 74, 0, 0})); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ===========================
+// 模块：解析异常快照
+// 职责：
+// - 影院页解析出 0 个场次，或比上次抓取减少超过一半时，视为解析异常（选择器失效 / 页面改版）
+// - 把原始 HTML 保存到 debug/ 目录，并记录到本次 CrawlRun 的异常列表
+// - 每个影院只保留最近 debugSnapshotsPerCinema 份快照
+// ===========================
+
+const (
+	debugSnapshotDir        = "debug"
+	debugSnapshotsPerCinema = 5
+	parseDropRatio          = 0.5 // 场次数比上次减少超过该比例视为异常
+)
+
+// ParseAnomaly 单个影院页的解析异常。
+type ParseAnomaly struct {
+	CinemaID uint   `json:"cinema_id"`
+	Cinema   string `json:"cinema"`
+	URL      string `json:"url"`
+	Parsed   int    `json:"parsed"`   // 本次解析出的场次数
+	Previous int    `json:"previous"` // 上次抓取时的场次数
+	Reason   string `json:"reason"`
+	Snapshot string `json:"snapshot"` // 保存的 HTML 路径（保存失败时为空）
+}
+
+// parseAnomalies 本次运行收集到的解析异常（爬虫回调中追加，需加锁）。
+var parseAnomalies struct {
+	sync.Mutex
+	items []ParseAnomaly
+}
+
+// detectParseAnomaly 对比本次与上次的场次数，判断是否异常（纯函数）。
+func detectParseAnomaly(parsed, previous int) (string, bool) {
+	if parsed == 0 {
+		return "no showtimes parsed", true
+	}
+	if previous > 0 && float64(parsed) < float64(previous)*(1-parseDropRatio) {
+		return fmt.Sprintf("showtimes dropped from %d to %d", previous, parsed), true
+	}
+	return "", false
+}
+
+// loadPreviousCinemaCounts 读取上一次成功抓取快照中各影院的排片数；没有历史时返回空 map。
+func loadPreviousCinemaCounts() map[uint]int {
+	var run CrawlRun
+	if err := db.Where("kind = ? AND status = ? AND snapshot_json <> ''", "schedules", "success").
+		Order("id DESC").First(&run).Error; err != nil {
+		return map[uint]int{}
+	}
+	snap, err := parseCrawlSnapshot(run)
+	if err != nil || snap.CinemaCounts == nil {
+		return map[uint]int{}
+	}
+	return snap.CinemaCounts
+}
+
+// snapshotKeyRe 将 URL 路径转为文件名安全的 key。
+var snapshotKeyRe = regexp.MustCompile(`[^A-Za-z0-9]+`)
+
+// snapshotKey 以详情页 URL 路径作为影院的快照 key（影院名解析失败时也能区分）。
+func snapshotKey(pageURL string) string {
+	key := strings.Trim(snapshotKeyRe.ReplaceAllString(strings.TrimPrefix(pageURL, "https://eiga.com"), "-"), "-")
+	if key == "" {
+		key = "unknown"
+	}
+	return key
+}
+
+// saveHTMLSnapshot 保存原始 HTML，并清理同一影院超出保留数量的旧快照。
+func saveHTMLSnapshot(pageURL string, body []byte) (string, error) {
+	if err := os.MkdirAll(debugSnapshotDir, 0o755); err != nil {
+		return "", err
+	}
+	key := snapshotKey(pageURL)
+	path := filepath.Join(debugSnapshotDir, fmt.Sprintf("%s-%s.html", key, time.Now().Format("20060102-150405")))
+	if err := os.WriteFile(path, body, 0o644); err != nil {
+		return "", err
+	}
+
+	// 文件名带时间戳，按名称排序即按时间排序
+	old, _ := filepath.Glob(filepath.Join(debugSnapshotDir, key+"-*.html"))
+	sort.Strings(old)
+	for len(old) > debugSnapshotsPerCinema {
+		os.Remove(old[0])
+		old = old[1:]
+	}
+	return path, nil
+}
+
+// reportParseAnomaly 保存快照并记录异常。
+func reportParseAnomaly(a ParseAnomaly, body []byte) {
+	if path, err := saveHTMLSnapshot(a.URL, body); err != nil {
+		fmt.Printf("⚠️ 保存 HTML 快照失败 [%s]: %v\n", a.URL, err)
+	} else {
+		a.Snapshot = path
+	}
+	fmt.Printf("🧐 解析异常 [%s]: %s，已保存快照: %s\n", a.Cinema, a.Reason, a.Snapshot)
+
+	parseAnomalies.Lock()
+	parseAnomalies.items = append(parseAnomalies.items, a)
+	parseAnomalies.Unlock()
+}
+
+// parseAnomaliesJSON 返回本次运行的异常列表（JSON），没有异常时为空串。
+func parseAnomaliesJSON() string {
+	parseAnomalies.Lock()
+	defer parseAnomalies.Unlock()
+	if len(parseAnomalies.items) == 0 {
+		return ""
+	}
+	b, err := json.Marshal(parseAnomalies.items)
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
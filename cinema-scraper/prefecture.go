@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// ===========================
+// 模块：抓取地区与影院所在都道府县（Cinema.Prefecture）
+// 职责：
+// - eiga.com 的影院列表按都道府县代码分页（/theater/13/ 为东京都），抓取地区由 CRAWL_AREA 决定，
+//   crawl-cinemas / crawl-schedules 可用 --area 14 或 --area 13,14,11 临时覆盖
+// - 列表页上的影院链接只处理所选地区内的（见 discoveredvenues.go），其余只记录不抓取
+// - Cinema.Prefecture 由 eiga.com 链接中的地区代码推导；官网 / CSV 录入的影院没有 eiga.com 链接时按地址开头判断
+// 说明：已有影院在启动时补齐 Prefecture；之后新建的影院在保存时推导（见 district.go 的 BeforeSave）。
+// 调用方式：
+//   go run . crawl-cinemas --area 14
+//   go run . crawl-schedules --area=13,14
+// ===========================
+
+// prefectureNames 都道府县代码（JIS X 0401，与 eiga.com 的地区代码一致）对应的名称。
+var prefectureNames = map[string]string{
+	"01": "北海道", "02": "青森県", "03": "岩手県", "04": "宮城県", "05": "秋田県", "06": "山形県", "07": "福島県",
+	"08": "茨城県", "09": "栃木県", "10": "群馬県", "11": "埼玉県", "12": "千葉県", "13": "東京都", "14": "神奈川県",
+	"15": "新潟県", "16": "富山県", "17": "石川県", "18": "福井県", "19": "山梨県", "20": "長野県", "21": "岐阜県",
+	"22": "静岡県", "23": "愛知県", "24": "三重県", "25": "滋賀県", "26": "京都府", "27": "大阪府", "28": "兵庫県",
+	"29": "奈良県", "30": "和歌山県", "31": "鳥取県", "32": "島根県", "33": "岡山県", "34": "広島県", "35": "山口県",
+	"36": "徳島県", "37": "香川県", "38": "愛媛県", "39": "高知県", "40": "福岡県", "41": "佐賀県", "42": "長崎県",
+	"43": "熊本県", "44": "大分県", "45": "宮崎県", "46": "鹿児島県", "47": "沖縄県",
+}
+
+// eigaAreaCode 从 eiga.com 影院链接中取出地区代码（纯函数），不是影院链接时为空串。
+func eigaAreaCode(link string) string {
+	if m := eigaTheaterLinkRe.FindStringSubmatch(link); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+// derivePrefecture 推导影院所在都道府县（纯函数）：优先 eiga.com 链接的地区代码，其次地址开头；都判断不了时为空串。
+func derivePrefecture(eigaURL, address string) string {
+	if name, ok := prefectureNames[eigaAreaCode(eigaURL)]; ok {
+		return name
+	}
+	address = strings.TrimSpace(address)
+	for _, name := range prefectureNames {
+		if strings.HasPrefix(address, name) {
+			return name
+		}
+	}
+	return ""
+}
+
+// applyCrawlAreaFlag 解析 --area（逗号分隔的地区代码）并覆盖本次运行的 CrawlArea；没有该参数时不变。
+func applyCrawlAreaFlag(args []string) error {
+	v, ok := flagValue(args, "--area")
+	if !ok {
+		return nil
+	}
+	areas, err := parseCrawlAreas(v)
+	if err != nil {
+		return fmt.Errorf("invalid --area: %v", err)
+	}
+	appConfig.CrawlArea = strings.Join(areas, ",")
+	return nil
+}
+
+// describeCrawlAreas 抓取地区的日志文字，如 13 東京都, 14 神奈川県。
+func describeCrawlAreas(areas []string) string {
+	parts := make([]string, 0, len(areas))
+	for _, area := range areas {
+		parts = append(parts, area+" "+prefectureNames[area])
+	}
+	return strings.Join(parts, ", ")
+}
+
+// backfillCinemaPrefectures 为 Prefecture 为空的影院补齐所在都道府县，返回更新数（在迁移事务 conn 上执行）。
+func backfillCinemaPrefectures(conn *gorm.DB) (int, error) {
+	var cinemas []Cinema
+	if err := conn.Select("id", "address", "eiga_url").Where("prefecture IS NULL OR prefecture = ''").Find(&cinemas).Error; err != nil {
+		return 0, err
+	}
+	updated := 0
+	for _, cin := range cinemas {
+		prefecture := derivePrefecture(cin.EigaURL, cin.Address)
+		if prefecture == "" {
+			continue
+		}
+		if err := conn.Model(&Cinema{}).Where("id = ?", cin.ID).UpdateColumn("prefecture", prefecture).Error; err != nil {
+			return updated, err
+		}
+		updated++
+	}
+	return updated, nil
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// TestCrawlAreasAndPrefecture 抓取地区：逗号列表校验，影院所在都道府县由地区代码或地址推导。
+func TestCrawlAreasAndPrefecture(t *testing.T) {
+	setTestClock(t, beforeMidnight)
+
+	_, badErr := parseCrawlAreas("13,48")
+	_, emptyErr := parseCrawlAreas(" , ")
+	multi := newDiscoveredVenue("https://eiga.com/theater/14/140101/3101/", "横浜テストシネマ", []string{"13", "14"}, time.Now())
+	if err := firstError(
+		expectEqual("invalid code rejected", badErr != nil, true),
+		expectEqual("empty list rejected", emptyErr != nil, true),
+		expectEqual("multi-area in scope", multi.InScope, true),
+		expectEqual("from eiga url", derivePrefecture("https://eiga.com/theater/14/140101/3101/", "東京都新宿区"), "神奈川県"),
+		expectEqual("from address", derivePrefecture("", "京都府京都市中京区"), "京都府"),
+		expectEqual("tokyo address", derivePrefecture("", "東京都渋谷区道玄坂2-2-2"), "東京都"),
+		expectEqual("unknown", derivePrefecture("https://eiga.com/special/venue/", "新宿3-1-1"), "")); err != nil {
+		t.Fatal(err)
+	}
+}
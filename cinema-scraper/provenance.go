@@ -0,0 +1,98 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ===========================
+// 模块：字段来源追踪（Provenance）
+// 职责：记录 Movie / Cinema 每个字段由哪个数据源写入、何时写入，便于排查脏数据
+// 说明：
+// - 以 JSON 形式存放在模型的 ProvenanceJSON 列中：{"title_cn": {"source": "tmdb-zhCN", "fetched_at": "..."}}
+// - 字段名统一使用 API 中的 snake_case 名称，方便与前端看到的字段对照。
+// - 后续可基于来源优先级（manual > tmdb > eiga）做冲突裁决。
+// ===========================
+
+// 数据来源枚举。
+const (
+	SourceEiga     = "eiga"
+	SourceTMDBzhCN = "tmdb-zhCN"
+	SourceTMDBjaJP = "tmdb-jaJP"
+	SourceTMDBenUS = "tmdb-enUS"
+	SourceOMDb     = "omdb"
+	SourceDouban   = "douban"
+	SourceOSM      = "osm"
+	SourceGSI      = "gsi" // 国土地理院地址检索（Nominatim 查不到时兜底，见 gsi.go）
+	SourceManual   = "manual"
+	SourceCustom   = "custom"  // 影院官网排片（crawl-custom，见 customsource.go）
+	SourceWebsite  = "website" // 影院官网 meta 信息（enrich-cinemas，见 cinemaenrich.go）
+)
+
+// ProvenanceEntry 单个字段的来源记录。
+type ProvenanceEntry struct {
+	Source    string    `json:"source"`
+	FetchedAt time.Time `json:"fetched_at"`
+}
+
+// Provenance 字段名 -> 来源记录。
+type Provenance map[string]ProvenanceEntry
+
+// parseProvenance 解析模型上的 ProvenanceJSON；空串或解析失败时返回空 map。
+func parseProvenance(raw string) Provenance {
+	p := Provenance{}
+	if raw == "" {
+		return p
+	}
+	if err := json.Unmarshal([]byte(raw), &p); err != nil {
+		return Provenance{}
+	}
+	return p
+}
+
+// recordProvenance 将若干字段标记为由 source 在当前时间写入，并回写到 raw。
+func recordProvenance(raw *string, source string, fields ...string) {
+	if len(fields) == 0 {
+		return
+	}
+	p := parseProvenance(*raw)
+	now := time.Now()
+	for _, f := range fields {
+		p[f] = ProvenanceEntry{Source: source, FetchedAt: now}
+	}
+	if b, err := json.Marshal(p); err == nil {
+		*raw = string(b)
+	}
+}
+
+// tmdbSourceForLang 将 TMDB 请求语言映射为来源标识。
+func tmdbSourceForLang(lang string) string {
+	switch lang {
+	case "zh-CN":
+		return SourceTMDBzhCN
+	case "ja-JP":
+		return SourceTMDBjaJP
+	default:
+		return SourceTMDBenUS
+	}
+}
+
+// getMovieProvenanceHandler 影片字段来源接口（管理用）：
+// - GET /api/admin/movies/:id/provenance
+func getMovieProvenanceHandler(c *gin.Context) {
+	id := c.Param("id")
+
+	var movie Movie
+	if err := db.First(&movie, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "movie not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"id":         movie.ID,
+		"provenance": parseProvenance(movie.ProvenanceJSON),
+	})
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ===========================
+// 模块：单请求数据库查询预算
+// 职责：
+// - 经 GORM 回调统计每个 HTTP 请求发出的查询数（增删改查 / Row / Raw 各算一次）
+// - 超过预算（DB_QUERY_BUDGET，默认 50）时打印警告（带 request_id）并累加计数，
+//   /api/stats 的 query_budget_exceeded 给出累计次数，N+1 回归在线上就能发现
+// - 调试模式（DEBUG=true）下在响应头 X-DB-Queries 返回本请求的查询数；测试据此断言主要接口不超预算
+// 说明：handler 普遍直接使用全局 db、没有传递请求 Context，因此计数器除了放进请求 Context
+//       （WithContext 的查询按它计数）外，还按处理该请求的 goroutine 登记；
+//       handler 另起 goroutine 发出的查询不计入（目前没有这种情况）。
+// ===========================
+
+const (
+	defaultQueryBudget = 50
+	queryCountHeader   = "X-DB-Queries"
+)
+
+// queryCounter 单个请求的查询计数。
+type queryCounter struct{ n atomic.Int64 }
+
+// queryCounterKey 计数器在请求 Context 中的键。
+type queryCounterKey struct{}
+
+var (
+	// requestQueryCounters goroutine ID -> *queryCounter，请求处理期间有效
+	requestQueryCounters sync.Map
+	// queryBudgetExceeded 进程启动以来超出预算的请求数
+	queryBudgetExceeded atomic.Int64
+	// queryBudget / queryCountHeaderEnabled 启动时由 loadQueryBudgetConfig 设置
+	queryBudget             = defaultQueryBudget
+	queryCountHeaderEnabled = false
+)
+
+// loadQueryBudgetConfig 从 DB_QUERY_BUDGET 与 DEBUG 读取预算与调试模式；非法值使用默认并提示。
+func loadQueryBudgetConfig() {
+	if raw := strings.TrimSpace(os.Getenv("DB_QUERY_BUDGET")); raw != "" {
+		if n, err := strconv.Atoi(raw); err == nil && n > 0 {
+			queryBudget = n
+		} else {
+			fmt.Printf("⚠️ DB_QUERY_BUDGET=%q 无效，使用默认 %d\n", raw, defaultQueryBudget)
+		}
+	}
+	if raw := strings.TrimSpace(os.Getenv("DEBUG")); raw != "" {
+		debug, err := strconv.ParseBool(raw)
+		if err != nil {
+			fmt.Printf("⚠️ DEBUG=%q 无效，应为 true 或 false\n", raw)
+		}
+		queryCountHeaderEnabled = debug
+	}
+}
+
+// goroutineID 当前 goroutine 的编号（取自 runtime.Stack 的首行 "goroutine 123 [running]:"）。
+func goroutineID() uint64 {
+	buf := make([]byte, 32)
+	buf = buf[:runtime.Stack(buf, false)]
+	buf = bytes.TrimPrefix(buf, []byte("goroutine "))
+	if i := bytes.IndexByte(buf, ' '); i > 0 {
+		buf = buf[:i]
+	}
+	id, _ := strconv.ParseUint(string(buf), 10, 64)
+	return id
+}
+
+// registerQueryBudgetCallbacks 在每类操作之后计数：优先取查询 Context 中的计数器，其次取当前 goroutine 登记的。
+func registerQueryBudgetCallbacks(gdb *gorm.DB) error {
+	count := func(tx *gorm.DB) {
+		if ctx := tx.Statement.Context; ctx != nil {
+			if qc, ok := ctx.Value(queryCounterKey{}).(*queryCounter); ok {
+				qc.n.Add(1)
+				return
+			}
+		}
+		if v, ok := requestQueryCounters.Load(goroutineID()); ok {
+			v.(*queryCounter).n.Add(1)
+		}
+	}
+	cb := gdb.Callback()
+	hooks := []struct {
+		name     string
+		register func(string, func(*gorm.DB)) error
+	}{
+		{"create", cb.Create().After("*").Register},
+		{"query", cb.Query().After("*").Register},
+		{"update", cb.Update().After("*").Register},
+		{"delete", cb.Delete().After("*").Register},
+		{"row", cb.Row().After("*").Register},
+		{"raw", cb.Raw().After("*").Register},
+	}
+	for _, h := range hooks {
+		if err := h.register("query_budget:"+h.name, count); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// queryCountWriter 在写出响应头之前补上 X-DB-Queries（handler 写响应时查询基本都已完成）。
+type queryCountWriter struct {
+	gin.ResponseWriter
+	counter *queryCounter
+}
+
+func (w *queryCountWriter) setHeader() {
+	if !w.Written() {
+		w.Header().Set(queryCountHeader, strconv.FormatInt(w.counter.n.Load(), 10))
+	}
+}
+
+func (w *queryCountWriter) WriteHeader(code int) {
+	w.setHeader()
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *queryCountWriter) WriteHeaderNow() {
+	w.setHeader()
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+func (w *queryCountWriter) Write(data []byte) (int, error) {
+	w.setHeader()
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *queryCountWriter) WriteString(s string) (int, error) {
+	w.setHeader()
+	return w.ResponseWriter.WriteString(s)
+}
+
+// queryBudgetMiddleware 为每个请求登记计数器；请求结束后超出预算的打印警告并计数。
+func queryBudgetMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		counter := &queryCounter{}
+		gid := goroutineID()
+		requestQueryCounters.Store(gid, counter)
+		defer requestQueryCounters.Delete(gid)
+		c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), queryCounterKey{}, counter))
+		if queryCountHeaderEnabled {
+			c.Writer = &queryCountWriter{ResponseWriter: c.Writer, counter: counter}
+		}
+
+		c.Next()
+
+		if n := counter.n.Load(); n > int64(queryBudget) {
+			queryBudgetExceeded.Add(1)
+			fmt.Printf("⚠️ 查询数超出预算 [rid=%s] %s %s：%d 次查询（预算 %d）\n",
+				requestIDFrom(c), c.Request.Method, c.Request.URL.RequestURI(), n, queryBudget)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"testing"
+)
+
+// queryBudgetPaths 主要接口：样例数据下各自的查询数（X-DB-Queries）不得超过 queryBudget。
+var queryBudgetPaths = []string{
+	"/api/cinemas",
+	"/api/cinemas/1",
+	"/api/cinemas/nearby?lat=35.69&lng=139.70",
+	"/api/movies",
+	"/api/movies?status=incoming",
+	"/api/movies/1",
+	"/api/schedules",
+	"/api/timetable",
+	"/api/home",
+	"/api/tags",
+	"/api/tonight",
+	"/api/admin/search?q=%E6%96%B0%E5%AE%BF",
+}
+
+// TestQueryBudget 每个主要接口的响应头带 X-DB-Queries，且不超过预算。
+func TestQueryBudget(t *testing.T) {
+	cases := make([]httpCase, 0, len(queryBudgetPaths))
+	for _, path := range queryBudgetPaths {
+		cases = append(cases, httpCase{path, path, func(r testResponse) error {
+			if err := expectStatus(r, http.StatusOK); err != nil {
+				return err
+			}
+			n, err := strconv.Atoi(r.Header.Get(queryCountHeader))
+			if err != nil {
+				return fmt.Errorf("missing %s header", queryCountHeader)
+			}
+			if n > queryBudget {
+				return fmt.Errorf("%d queries, budget %d", n, queryBudget)
+			}
+			return nil
+		}})
+	}
+	runHTTPCases(t, cases)
+}
@@ -0,0 +1,78 @@
+package main
+
+import "time"
+
+// ===========================
+// 模块：评分来源与新鲜度
+// 职责：
+// - 把各来源的评分列整理成 ratings 数组：{source, score, scale, votes, fetched_at}
+// - fetched_at 取自字段来源记录（provenance.go），让前端能标注“IMDb · 3 天前”
+// - 统一“单一展示评分”的优先级：豆瓣 > IMDb > TMDB
+// ===========================
+
+// 评分来源标识。
+const (
+	RatingSourceDouban = "douban"
+	RatingSourceIMDb   = "imdb"
+	RatingSourceTMDB   = "tmdb"
+)
+
+// ratingPriority 单一展示评分的挑选顺序（靠前优先）。
+var ratingPriority = []string{RatingSourceDouban, RatingSourceIMDb, RatingSourceTMDB}
+
+// RatingEntry 单个来源的评分。
+type RatingEntry struct {
+	Source    string     `json:"source"`     // douban / imdb / tmdb
+	Score     float64    `json:"score"`      // 原始分数
+	Scale     float64    `json:"scale"`      // 满分（目前均为 10）
+	Votes     *int       `json:"votes"`      // 投票数；来源不提供时为 null
+	FetchedAt *time.Time `json:"fetched_at"` // 最近一次抓取时间；旧数据未记录时为 null
+}
+
+// buildRatings 按优先级列出有分数的来源（分数为 0 视为缺失，不输出）。
+func buildRatings(m Movie) []RatingEntry {
+	prov := parseProvenance(m.ProvenanceJSON)
+	fetchedAt := func(field string) *time.Time {
+		if entry, ok := prov[field]; ok && !entry.FetchedAt.IsZero() {
+			t := entry.FetchedAt
+			return &t
+		}
+		return nil
+	}
+	votes := func(n int) *int {
+		if n <= 0 {
+			return nil
+		}
+		return &n
+	}
+
+	out := make([]RatingEntry, 0, len(ratingPriority))
+	for _, source := range ratingPriority {
+		var entry RatingEntry
+		switch source {
+		case RatingSourceDouban:
+			entry = RatingEntry{Score: m.DoubanRating, FetchedAt: fetchedAt("douban_rating")}
+		case RatingSourceIMDb:
+			entry = RatingEntry{Score: m.IMDBRating, Votes: votes(m.IMDBVotes), FetchedAt: fetchedAt("imdb_rating")}
+		case RatingSourceTMDB:
+			entry = RatingEntry{Score: m.TMDBRating, Votes: votes(m.TMDBVotes), FetchedAt: fetchedAt("tmdb_rating")}
+		}
+		if entry.Score <= 0 {
+			continue
+		}
+		entry.Source = source
+		entry.Scale = 10
+		out = append(out, entry)
+	}
+	return out
+}
+
+// primaryRating 按 ratingPriority 取第一个有分数的来源；都没有时返回 nil。
+func primaryRating(ratings []RatingEntry) *RatingEntry {
+	for i := range ratings {
+		if ratings[i].Score > 0 {
+			return &ratings[i]
+		}
+	}
+	return nil
+}
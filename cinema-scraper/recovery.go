@@ -0,0 +1,109 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ===========================
+// 模块：请求 ID 与 panic 兜底
+// 职责：
+// - 为每个请求分配 request_id（优先沿用 X-Request-ID），写入日志与错误响应，便于关联排查
+// - HTTP handler panic 时返回 500 JSON 而不是断开连接
+// - 爬虫回调 / 补全流程 panic 时只记录并跳过当前页面，不中断整次抓取
+// - 累计被兜底的 panic 次数，在 /api/stats 中展示
+// ===========================
+
+const (
+	requestIDHeader = "X-Request-ID"
+	requestIDKey    = "request_id"
+)
+
+// panicsRecovered 进程启动以来被兜底的 panic 次数（HTTP 与爬虫共用）。
+var panicsRecovered atomic.Int64
+
+// newRequestID 生成 16 位十六进制请求 ID。
+func newRequestID() string {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(buf)
+}
+
+// requestIDMiddleware 沿用客户端传入的 X-Request-ID，否则生成一个新的，并回写到响应头。
+func requestIDMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(requestIDHeader)
+		if id == "" || len(id) > 64 {
+			id = newRequestID()
+		}
+		c.Set(requestIDKey, id)
+		c.Header(requestIDHeader, id)
+		c.Next()
+	}
+}
+
+// requestIDFrom 读取当前请求的 request_id。
+func requestIDFrom(c *gin.Context) string {
+	return c.GetString(requestIDKey)
+}
+
+// requestLogFormatter 在 Gin 默认访问日志中附带 request_id。
+func requestLogFormatter(p gin.LogFormatterParams) string {
+	id, _ := p.Keys[requestIDKey].(string)
+	return fmt.Sprintf("[GIN] %s | %3d | %13v | %15s | %-7s %#v | rid=%s\n%s",
+		p.TimeStamp.Format("2006/01/02 - 15:04:05"),
+		p.StatusCode,
+		p.Latency,
+		p.ClientIP,
+		p.Method,
+		p.Path,
+		id,
+		p.ErrorMessage,
+	)
+}
+
+// recoveryMiddleware 捕获 handler panic：打印带 request_id 的堆栈，返回 500 JSON。
+func recoveryMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			if r := recover(); r != nil {
+				panicsRecovered.Add(1)
+				id := requestIDFrom(c)
+				fmt.Printf("🔥 handler panic [rid=%s] %s %s: %v\n%s", id, c.Request.Method, c.Request.URL.Path, r, debug.Stack())
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+					"error":      "internal server error",
+					"request_id": id,
+				})
+			}
+		}()
+		c.Next()
+	}
+}
+
+// recoverAndLog 用于爬虫回调与补全流程：`defer recoverAndLog("...")`。
+// 捕获 panic 后只打印上下文与堆栈，让调用方继续处理下一个页面 / 影片。
+func recoverAndLog(context string) {
+	if r := recover(); r != nil {
+		panicsRecovered.Add(1)
+		fmt.Printf("🔥 已跳过（panic）%s: %v\n%s", context, r, debug.Stack())
+	}
+}
+
+// recoverAndMark 同 recoverAndLog，捕获 panic 后再调用 onPanic：`defer recoverAndMark("...", func() { ... })`。
+// 用于跳过的部分会影响后续步骤的场合，如影片区块 panic 后把本影院标记为解析不完整，避免随后的清理误删。
+func recoverAndMark(context string, onPanic func()) {
+	if r := recover(); r != nil {
+		panicsRecovered.Add(1)
+		fmt.Printf("🔥 已跳过（panic）%s: %v\n%s", context, r, debug.Stack())
+		onPanic()
+	}
+}
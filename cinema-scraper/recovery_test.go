@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gocolly/colly/v2"
+)
+
+// TestPanicRecovery panic 兜底：handler panic 返回带 request_id 的 500 且进程继续服务，抓取回调 panic 只跳过当前页面。
+func TestPanicRecovery(t *testing.T) {
+	setTestClock(t, beforeMidnight)
+
+	before := panicsRecovered.Load()
+	engine := gin.New()
+	engine.Use(requestIDMiddleware(), recoveryMiddleware())
+	engine.GET("/boom", func(*gin.Context) { panic("injected handler panic") })
+	engine.GET("/ok", func(c *gin.Context) { c.JSON(http.StatusOK, gin.H{"ok": true}) })
+	boom := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/boom", nil)
+	req.Header.Set(requestIDHeader, "test-rid")
+	engine.ServeHTTP(boom, req)
+	ok := httptest.NewRecorder()
+	engine.ServeHTTP(ok, httptest.NewRequest(http.MethodGet, "/ok", nil))
+
+	// 抓取：第 2 页的回调 panic，其余页面照常解析
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `<html><body><main><h1>%s</h1></main></body></html>`, r.URL.Path)
+	}))
+	defer srv.Close()
+	var visited []string
+	c := colly.NewCollector()
+	c.OnHTML("main", func(e *colly.HTMLElement) {
+		defer recoverAndLog("测试页面 " + e.Request.URL.Path)
+		if e.ChildText("h1") == "/2" {
+			panic("injected crawl panic")
+		}
+		visited = append(visited, e.ChildText("h1"))
+	})
+	for _, path := range []string{"/1", "/2", "/3"} {
+		if err := c.Visit(srv.URL + path); err != nil {
+			t.Fatal(err)
+		}
+	}
+	marked := false
+	func() {
+		defer recoverAndMark("测试区块", func() { marked = true })
+		var m map[string]int
+		m["nil map"]++
+	}()
+	if err := firstError(
+		expectStatus(testResponse{Status: boom.Code, Body: boom.Body.Bytes()}, http.StatusInternalServerError),
+		expectEqual("body", boom.Body.String(), `{"error":"internal server error","request_id":"test-rid"}`),
+		expectEqual("request id header", boom.Header().Get(requestIDHeader), "test-rid"),
+		expectEqual("still serving", ok.Code, http.StatusOK),
+		expectEqual("generated request id", len(ok.Header().Get(requestIDHeader)), 16),
+		expectEqual("visited", visited, []string{"/1", "/3"}),
+		expectEqual("marked", marked, true),
+		expectEqual("panics", panicsRecovered.Load()-before, int64(3))); err != nil {
+		t.Fatal(err)
+	}
+}
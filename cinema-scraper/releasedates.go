@@ -0,0 +1,163 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// ===========================
+// 模块：上映日期修复脚本
+// 职责：
+// - 旧版补全逻辑未写入 ReleaseDate，导致部分影片为 0001-01-01
+// - 对“有 TMDBID 但上映日期为零值”的影片重新拉取 release_dates（优先日本院线日期）
+// - 实在拿不到精确日期时才用 Year-01-01 兜底，并把精度标记为 year
+// - 日本院线上映日期（JPReleaseDate）单独记录：补全 TMDB 信息时随 ja-JP 详情一起取回
+//   （append_to_response=release_dates），只认 JP 的 theatrical 条目；Soon 的 release_date 与
+//   incoming / showing 判断优先使用它（见 statusrules.go 的 statusWithJPRelease）
+// 调用方式：
+//   go run . fix-release-dates
+// ===========================
+
+// 上映日期精度。
+const (
+	ReleaseDatePrecisionDay  = "day"
+	ReleaseDatePrecisionYear = "year"
+)
+
+// TMDB release_dates 中的发行类型。
+const (
+	tmdbReleaseTypePremiere        = 1
+	tmdbReleaseTypeTheatricalLimit = 2
+	tmdbReleaseTypeTheatrical      = 3
+)
+
+// tmdbReleaseDates TMDB /movie/{id}/release_dates 的响应结构。
+type tmdbReleaseDates struct {
+	Results []struct {
+		Country      string `json:"iso_3166_1"`
+		ReleaseDates []struct {
+			ReleaseDate string `json:"release_date"`
+			Type        int    `json:"type"`
+		} `json:"release_dates"`
+	} `json:"results"`
+}
+
+// pickReleaseDate 从 release_dates 中挑选上映日期：
+// 日本院线（type 3 > 2 > 1）优先，其次任意国家最早的院线日期。
+func pickReleaseDate(data tmdbReleaseDates) (time.Time, bool) {
+	var best time.Time
+	bestRank := 0
+	for _, r := range data.Results {
+		for _, rd := range r.ReleaseDates {
+			if rd.Type < tmdbReleaseTypePremiere || rd.Type > tmdbReleaseTypeTheatrical {
+				continue
+			}
+			t, err := time.Parse(time.RFC3339, rd.ReleaseDate)
+			if err != nil {
+				continue
+			}
+			t = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+
+			// 排名：日本院线 > 日本其他 > 其他国家院线 > 其他
+			rank := 1
+			if rd.Type == tmdbReleaseTypeTheatrical {
+				rank = 2
+			}
+			if r.Country == "JP" {
+				rank += 2
+			}
+			if rank > bestRank || (rank == bestRank && t.Before(best)) {
+				best, bestRank = t, rank
+			}
+		}
+	}
+	return best, bestRank > 0
+}
+
+// pickJPTheatricalDate 日本院线（type 3）最早的上映日期（纯函数）；没有日本院线条目时返回 false。
+func pickJPTheatricalDate(data tmdbReleaseDates) (time.Time, bool) {
+	var best time.Time
+	found := false
+	for _, r := range data.Results {
+		if r.Country != "JP" {
+			continue
+		}
+		for _, rd := range r.ReleaseDates {
+			if rd.Type != tmdbReleaseTypeTheatrical {
+				continue
+			}
+			t, err := time.Parse(time.RFC3339, rd.ReleaseDate)
+			if err != nil {
+				continue
+			}
+			t = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+			if !found || t.Before(best) {
+				best, found = t, true
+			}
+		}
+	}
+	return best, found
+}
+
+// fetchTmdbReleaseDate 通过 TMDB release_dates 接口获取上映日期。
+func fetchTmdbReleaseDate(cfg Config, tmdbID int) (time.Time, bool) {
+	apiURL := fmt.Sprintf("https://api.themoviedb.org/3/movie/%d/release_dates?api_key=%s", tmdbID, cfg.TMDBAPIKey)
+	fmt.Printf("🌐 TMDB release_dates 查询: %s\n", apiURL)
+
+	resp, err := tmdbGet(apiURL, "TokyoCinePath/1.1 (tmdb-release-dates)")
+	if err != nil {
+		fmt.Printf("⚠️ TMDB release_dates 请求失败 [%d]: %v\n", tmdbID, err)
+		return time.Time{}, false
+	}
+	defer resp.Body.Close()
+
+	var data tmdbReleaseDates
+	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+		return time.Time{}, false
+	}
+	return pickReleaseDate(data)
+}
+
+// fixZeroReleaseDates 修复 ReleaseDate 为零值的影片，返回修复条数（精确 / 按年份兜底）。
+func fixZeroReleaseDates() (int, int, error) {
+	var movies []Movie
+	if err := db.Where("tmdb_id <> 0 AND (release_date IS NULL OR release_date < ?)", "0002-01-01").
+		Find(&movies).Error; err != nil {
+		return 0, 0, err
+	}
+	if len(movies) == 0 {
+		fmt.Println("ℹ️ 没有需要修复上映日期的影片。")
+		return 0, 0, nil
+	}
+	fmt.Printf("ℹ️ 共有 %d 部影片缺少上映日期。\n", len(movies))
+
+	exact, approx := 0, 0
+	for i := range movies {
+		m := &movies[i]
+		if t, ok := fetchTmdbReleaseDate(appConfig, m.TMDBID); ok {
+			m.ReleaseDate = t
+			m.ReleaseDatePrecision = ReleaseDatePrecisionDay
+			recordProvenance(&m.ProvenanceJSON, SourceTMDBjaJP, "release_date")
+			exact++
+		} else if m.Year != "" {
+			t, err := time.Parse("2006-01-02", m.Year+"-01-01")
+			if err != nil {
+				continue
+			}
+			m.ReleaseDate = t
+			m.ReleaseDatePrecision = ReleaseDatePrecisionYear
+			approx++
+		} else {
+			fmt.Printf("   ↪ 仍无法确定上映日期: TitleJP=%s TMDBID=%d\n", m.TitleJP, m.TMDBID)
+			continue
+		}
+
+		if err := db.Save(m).Error; err != nil {
+			fmt.Printf("⚠️ 保存上映日期失败 [%s]: %v\n", m.TitleJP, err)
+			continue
+		}
+		fmt.Printf("   📅 [%s] -> %s (%s)\n", m.TitleJP, m.ReleaseDate.Format("2006-01-02"), m.ReleaseDatePrecision)
+	}
+	return exact, approx, nil
+}
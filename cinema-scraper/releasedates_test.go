@@ -0,0 +1,70 @@
+package main
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+	"time"
+)
+
+// TestJPReleaseDate 日本上映日期：只取 JP 院线条目，先行上映期间仍为 incoming，Soon 的 release_date 优先日本日期。
+func TestJPReleaseDate(t *testing.T) {
+	newTestRouter(t)
+	now := testNoon
+	setTestClock(t, now)
+
+	var detail struct {
+		ReleaseDates tmdbReleaseDates `json:"release_dates"`
+	}
+	raw := `{"release_dates":{"results":[
+				{"iso_3166_1":"US","release_dates":[{"release_date":"2025-09-12T00:00:00.000Z","type":3}]},
+				{"iso_3166_1":"JP","release_dates":[
+					{"release_date":"2026-01-20T00:00:00.000Z","type":1},
+					{"release_date":"2026-02-06T00:00:00.000Z","type":3}]}]}}`
+	if err := json.Unmarshal([]byte(raw), &detail); err != nil {
+		t.Fatal(err)
+	}
+	jp, ok := pickJPTheatricalDate(detail.ReleaseDates)
+	var noJP tmdbReleaseDates
+	json.Unmarshal([]byte(`{"results":[{"iso_3166_1":"US","release_dates":[{"release_date":"2025-09-12T00:00:00.000Z","type":3}]}]}`), &noJP)
+	_, okNoJP := pickJPTheatricalDate(noJP)
+
+	global := time.Date(2025, 9, 12, 0, 0, 0, 0, time.UTC)
+	movie := Movie{TitleJP: "テスト先行上映", Status: "incoming", ReleaseDate: global, JPReleaseDate: &jp}
+	if err := db.Create(&movie).Error; err != nil {
+		t.Fatal(err)
+	}
+	shows := []Schedule{
+		{MovieID: movie.ID, CinemaID: 3, PlayDate: time.Date(2026, 1, 27, 0, 0, 0, 0, time.UTC), StartTime: "19:00"}, // 先行上映
+		{MovieID: movie.ID, CinemaID: 3, PlayDate: time.Date(2026, 2, 10, 0, 0, 0, 0, time.UTC), StartTime: "19:00"},
+	}
+	if err := db.Create(&shows).Error; err != nil {
+		t.Fatal(err)
+	}
+	incoming, err := movieIDsWithStatusAsOf("incoming", "2026-01-27")
+	if err != nil {
+		t.Fatal(err)
+	}
+	showingAfter, err := movieIDsWithStatusAsOf("showing", "2026-02-06")
+	if err != nil {
+		t.Fatal(err)
+	}
+	contains := func(ids []uint) bool { return slices.Contains(ids, movie.ID) }
+	item := mapMovieToItem(movie, "")
+	movie.JPReleaseDate = nil
+	fallback := mapMovieToItem(movie, "")
+	if err := firstError(
+		expectEqual("jp theatrical", ok && jp.Format("2006-01-02") == "2026-02-06", true),
+		expectEqual("no jp entry", okNoJP, false),
+		expectEqual("preview before jp release", statusWithJPRelease("showing", "2026-02-06", "2026-01-27"), "incoming"),
+		expectEqual("released in japan", statusWithJPRelease("showing", "2026-02-06", "2026-02-06"), "showing"),
+		expectEqual("unknown jp release", statusWithJPRelease("showing", "", "2026-01-27"), "showing"),
+		expectEqual("as_of preview is incoming", contains(incoming), true),
+		expectEqual("as_of after jp release is showing", contains(showingAfter), true),
+		expectEqual("soon release_date", item.ReleaseDate, "2026-02-06"),
+		expectEqual("jp_release_date", item.JPReleaseDate, "2026-02-06"),
+		expectEqual("fallback release_date", fallback.ReleaseDate, "2025-09-12"),
+		expectEqual("fallback jp_release_date", fallback.JPReleaseDate, "")); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,291 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ===========================
+// 模块：按影片 × 影院的放映跨度汇总（CinemaRunSummary）
+// 职责：
+// - 每个 (影片, 影院) 一行：首末排片日期与累计场次数，清理旧排片后仍能回答“这部片之前在哪里放过”
+// - 抓取写入场次后增量刷新涉及的行（refreshRunSummaries）；清理旧排片（archiveSchedulesBefore）时
+//   在同一事务内把被清理的场次计入 PrunedShowtimes
+// - 影片详情的影院跨度（first_date / last_date / past_only）与 first_seen / last_seen（weeks_in_release）都读这里
+// - 只统计公开场次：会員限定场次（见 membersonly.go）不计入跨度与场次数
+// 说明：已清理部分（Pruned*）只累加不回退；总量 = 已清理部分 ∪ 热表现状，刷新时按热表重算，
+//       因此重复抓取不会重复计数，官网下架的未来场次也会让 last_date 随之收回。
+//       已有数据用 backfill-run-summaries 命令重建；启动时汇总表为空而热表有排片也会自动补建。
+// 调用方式：
+//   go run . backfill-run-summaries
+// ===========================
+
+// CinemaRunSummary 某部影片在一家影院的放映跨度。
+// （影片详情里的 RunSummary 是“今天以后”的场次概况，见 runsummary.go，两者不是一回事。）
+type CinemaRunSummary struct {
+	ID              uint   `gorm:"primaryKey"`
+	MovieID         uint   `gorm:"uniqueIndex:idx_run_summary_pair"`
+	CinemaID        uint   `gorm:"uniqueIndex:idx_run_summary_pair;index"`
+	FirstDate       string // YYYY-MM-DD，含已清理的场次
+	LastDate        string
+	Showtimes       int // 累计场次数（含已清理的）
+	PrunedFirstDate string
+	PrunedLastDate  string
+	PrunedShowtimes int // 已从热表清理（归档）的场次数
+	UpdatedAt       time.Time
+}
+
+// runPair 汇总的键。
+type runPair struct {
+	MovieID  uint
+	CinemaID uint
+}
+
+// runExtent 一组场次的数量与首末日期。
+type runExtent struct {
+	MovieID   uint
+	CinemaID  uint
+	Showtimes int
+	FirstDate string
+	LastDate  string
+}
+
+// widenRunDates 把 [first, last] 合并进日期范围（纯函数），空串表示没有日期。
+func widenRunDates(curFirst, curLast *string, first, last string) {
+	if first != "" && (*curFirst == "" || first < *curFirst) {
+		*curFirst = first
+	}
+	if last != "" && last > *curLast {
+		*curLast = last
+	}
+}
+
+// foldRunSummary 把新清理的场次（pruned）累加进已清理部分，再与热表现状（hot）合并出总量（纯函数）。
+func foldRunSummary(s CinemaRunSummary, pruned, hot runExtent) CinemaRunSummary {
+	s.PrunedShowtimes += pruned.Showtimes
+	widenRunDates(&s.PrunedFirstDate, &s.PrunedLastDate, pruned.FirstDate, pruned.LastDate)
+	s.FirstDate, s.LastDate = s.PrunedFirstDate, s.PrunedLastDate
+	widenRunDates(&s.FirstDate, &s.LastDate, hot.FirstDate, hot.LastDate)
+	s.Showtimes = s.PrunedShowtimes + hot.Showtimes
+	return s
+}
+
+// scanRunExtents 按 (影片, 影院) 统计 model 表中符合 scope 的公开场次。
+// play_date 按存储的文本取前 10 位作为日期，与 Schedule.PlayDate.Format("2006-01-02") 一致。
+func scanRunExtents(tx *gorm.DB, model interface{}, scope func(*gorm.DB) *gorm.DB) (map[runPair]runExtent, error) {
+	var rows []runExtent
+	q := tx.Model(model).
+		Select("movie_id, cinema_id, COUNT(*) AS showtimes, substr(MIN(play_date), 1, 10) AS first_date, substr(MAX(play_date), 1, 10) AS last_date").
+		Where("members_only = ?", false)
+	if scope != nil {
+		q = scope(q)
+	}
+	if err := q.Group("movie_id, cinema_id").Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	out := make(map[runPair]runExtent, len(rows))
+	for _, r := range rows {
+		out[runPair{r.MovieID, r.CinemaID}] = r
+	}
+	return out, nil
+}
+
+// applyRunSummaries 对 pairs 逐个折叠 pruned 与 hot 并写回；两边都没有场次的新键不建行。
+func applyRunSummaries(tx *gorm.DB, pairs []runPair, pruned, hot map[runPair]runExtent) error {
+	for _, p := range pairs {
+		var s CinemaRunSummary
+		if err := tx.Where("movie_id = ? AND cinema_id = ?", p.MovieID, p.CinemaID).Limit(1).Find(&s).Error; err != nil {
+			return err
+		}
+		if s.ID == 0 && pruned[p].Showtimes == 0 && hot[p].Showtimes == 0 {
+			continue
+		}
+		s.MovieID, s.CinemaID = p.MovieID, p.CinemaID
+		s = foldRunSummary(s, pruned[p], hot[p])
+		if err := tx.Save(&s).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// refreshRunSummaries 抓取写入某影院的场次后刷新汇总；movieIDs 为空时刷新该影院的全部影片
+// （包括热表中已没有场次、但汇总里还有的影片）。
+func refreshRunSummaries(cinemaID uint, movieIDs []uint) error {
+	scope := func(q *gorm.DB) *gorm.DB {
+		q = q.Where("cinema_id = ?", cinemaID)
+		if len(movieIDs) > 0 {
+			q = q.Where("movie_id IN ?", movieIDs)
+		}
+		return q
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		hot, err := scanRunExtents(tx, &Schedule{}, scope)
+		if err != nil {
+			return err
+		}
+		var existing []runPair
+		if err := scope(tx.Model(&CinemaRunSummary{})).Select("movie_id, cinema_id").Scan(&existing).Error; err != nil {
+			return err
+		}
+		seen := make(map[runPair]bool)
+		pairs := make([]runPair, 0, len(hot)+len(existing))
+		for _, p := range existing {
+			seen[p] = true
+			pairs = append(pairs, p)
+		}
+		for p := range hot {
+			if !seen[p] {
+				pairs = append(pairs, p)
+			}
+		}
+		return applyRunSummaries(tx, pairs, nil, hot)
+	})
+}
+
+// finalizeRunSummaries 在清理事务内把即将删除的场次计入 PrunedShowtimes；须在热表删除之后调用。
+func finalizeRunSummaries(tx *gorm.DB, pruned []Schedule) error {
+	extents := make(map[runPair]runExtent)
+	movieIDs := make([]uint, 0)
+	for _, s := range pruned {
+		if s.MembersOnly {
+			continue
+		}
+		p := runPair{s.MovieID, s.CinemaID}
+		e, ok := extents[p]
+		if !ok {
+			e = runExtent{MovieID: p.MovieID, CinemaID: p.CinemaID}
+			movieIDs = append(movieIDs, p.MovieID)
+		}
+		day := s.PlayDate.Format("2006-01-02")
+		if e.FirstDate == "" || day < e.FirstDate {
+			e.FirstDate = day
+		}
+		if day > e.LastDate {
+			e.LastDate = day
+		}
+		e.Showtimes++
+		extents[p] = e
+	}
+	hot, err := scanRunExtents(tx, &Schedule{}, func(q *gorm.DB) *gorm.DB { return q.Where("movie_id IN ?", movieIDs) })
+	if err != nil {
+		return err
+	}
+	pairs := make([]runPair, 0, len(extents))
+	for p := range extents {
+		pairs = append(pairs, p)
+	}
+	return applyRunSummaries(tx, pairs, extents, hot)
+}
+
+// mergeRunSummariesTx 影片合并时把 drop 的汇总并入 keep（场次已迁移到 keep 之后调用）。
+func mergeRunSummariesTx(tx *gorm.DB, dropID, keepID uint) error {
+	var dropped []CinemaRunSummary
+	if err := tx.Where("movie_id = ?", dropID).Find(&dropped).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("movie_id = ?", dropID).Delete(&CinemaRunSummary{}).Error; err != nil {
+		return err
+	}
+	pruned := make(map[runPair]runExtent)
+	pairs := make([]runPair, 0)
+	for _, d := range dropped {
+		p := runPair{keepID, d.CinemaID}
+		pruned[p] = runExtent{Showtimes: d.PrunedShowtimes, FirstDate: d.PrunedFirstDate, LastDate: d.PrunedLastDate}
+		pairs = append(pairs, p)
+	}
+	hot, err := scanRunExtents(tx, &Schedule{}, func(q *gorm.DB) *gorm.DB { return q.Where("movie_id = ?", keepID) })
+	if err != nil {
+		return err
+	}
+	for p := range hot {
+		if _, ok := pruned[p]; !ok {
+			pairs = append(pairs, p)
+		}
+	}
+	return applyRunSummaries(tx, pairs, pruned, hot)
+}
+
+// backfillRunSummaries 由归档表（已清理的场次）与热表重建全部汇总，返回写入的行数。
+// 已有汇总的已清理部分不会因此变小：归档表出现之前清理掉的场次只存在于汇总中。
+func backfillRunSummaries() (int, error) {
+	written := 0
+	err := db.Transaction(func(tx *gorm.DB) error {
+		archived, err := scanRunExtents(tx, &ScheduleArchive{}, nil)
+		if err != nil {
+			return err
+		}
+		hot, err := scanRunExtents(tx, &Schedule{}, nil)
+		if err != nil {
+			return err
+		}
+		var existing []CinemaRunSummary
+		if err := tx.Find(&existing).Error; err != nil {
+			return err
+		}
+		byPair := make(map[runPair]CinemaRunSummary, len(existing))
+		for _, s := range existing {
+			byPair[runPair{s.MovieID, s.CinemaID}] = s
+		}
+		for p := range archived {
+			if _, ok := byPair[p]; !ok {
+				byPair[p] = CinemaRunSummary{MovieID: p.MovieID, CinemaID: p.CinemaID}
+			}
+		}
+		for p := range hot {
+			if _, ok := byPair[p]; !ok {
+				byPair[p] = CinemaRunSummary{MovieID: p.MovieID, CinemaID: p.CinemaID}
+			}
+		}
+		for p, s := range byPair {
+			a := archived[p]
+			if a.Showtimes < s.PrunedShowtimes {
+				a.Showtimes = s.PrunedShowtimes
+			}
+			s.PrunedShowtimes = 0
+			s = foldRunSummary(s, a, hot[p]) // 归档日期与已有的已清理日期取并集
+			if err := tx.Save(&s).Error; err != nil {
+				return err
+			}
+			written++
+		}
+		return nil
+	})
+	return written, err
+}
+
+// ensureRunSummaries 启动时汇总表为空而热表或归档表有排片时自动补建（旧库升级）。
+func ensureRunSummaries() (int, error) {
+	var summaries, schedules, archived int64
+	if err := db.Model(&CinemaRunSummary{}).Count(&summaries).Error; err != nil {
+		return 0, err
+	}
+	if summaries > 0 {
+		return 0, nil
+	}
+	db.Model(&Schedule{}).Count(&schedules)
+	db.Model(&ScheduleArchive{}).Count(&archived)
+	if schedules+archived == 0 {
+		return 0, nil
+	}
+	n, err := backfillRunSummaries()
+	if err != nil {
+		return 0, fmt.Errorf("补建放映跨度汇总失败: %v", err)
+	}
+	return n, nil
+}
+
+// loadMovieRunSummaries 影片在各影院的放映跨度（含已清理的排片），键为影院 ID。
+func loadMovieRunSummaries(movieID uint) map[uint]cinemaRun {
+	runs := make(map[uint]cinemaRun)
+	var rows []CinemaRunSummary
+	if err := db.Where("movie_id = ?", movieID).Find(&rows).Error; err != nil {
+		return runs
+	}
+	for _, r := range rows {
+		runs[r.CinemaID] = cinemaRun{CinemaID: r.CinemaID, FirstDate: r.FirstDate, LastDate: r.LastDate}
+	}
+	return runs
+}
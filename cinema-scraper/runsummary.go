@@ -0,0 +1,85 @@
+package main
+
+// ===========================
+// 模块：影片上映概况（run_summary）
+// 职责：影片详情页的“速览”数据
+// - 今天及以后的场次总数、参与影院数、排片日期范围
+// - 按放映版本（字幕 / 吹替 / 未标注）统计场次数
+// - 只剩过去排片的影片返回全 0，并标记 run_over
+// 说明：聚合来自一条按 (cinema_id, format) 分组的查询，其余在内存中合并。
+// ===========================
+
+// formatUnspecified 没有标注放映版本的场次在 formats 中的键。
+const formatUnspecified = "unspecified"
+
+// RunSummary 影片详情中的上映概况。
+type RunSummary struct {
+	UpcomingShowtimes int            `json:"upcoming_showtimes"`
+	CinemaCount       int            `json:"cinema_count"`
+	FirstDate         string         `json:"first_date"` // 今天及以后最早的排片日期（YYYY-MM-DD），没有时为空
+	LastDate          string         `json:"last_date"`  // 最晚的排片日期
+	Formats           map[string]int `json:"formats"`    // subbed / dubbed / unspecified -> 场次数
+	RunOver           bool           `json:"run_over"`   // 只有过去的排片（已下映）
+}
+
+// runSummaryRow 分组查询的一行：某影院某放映版本的场次数与日期范围。
+type runSummaryRow struct {
+	CinemaID  uint
+	Format    string
+	Showtimes int
+	FirstDate string
+	LastDate  string
+}
+
+// summarizeRun 合并分组结果（纯函数）；hasPast 表示影片有过去的排片，用于判断 run_over。
+func summarizeRun(rows []runSummaryRow, hasPast bool) RunSummary {
+	summary := RunSummary{Formats: map[string]int{}}
+	cinemas := make(map[uint]struct{})
+	for _, row := range rows {
+		if row.Showtimes == 0 {
+			continue
+		}
+		summary.UpcomingShowtimes += row.Showtimes
+		cinemas[row.CinemaID] = struct{}{}
+		format := row.Format
+		if format == "" {
+			format = formatUnspecified
+		}
+		summary.Formats[format] += row.Showtimes
+		if summary.FirstDate == "" || row.FirstDate < summary.FirstDate {
+			summary.FirstDate = row.FirstDate
+		}
+		if row.LastDate > summary.LastDate {
+			summary.LastDate = row.LastDate
+		}
+	}
+	summary.CinemaCount = len(cinemas)
+	summary.RunOver = summary.UpcomingShowtimes == 0 && hasPast
+	return summary
+}
+
+// buildRunSummary 统计影片今天（含）以后的公开排片概况（会員限定场次不计入）。
+func buildRunSummary(movie Movie, today string) RunSummary {
+	var rows []runSummaryRow
+	db.Model(&Schedule{}).
+		Select("cinema_id, format, COUNT(*) AS showtimes, MIN(date(play_date)) AS first_date, MAX(date(play_date)) AS last_date").
+		Where("movie_id = ? AND date(play_date) >= ? AND members_only = ?", movie.ID, today, false).
+		Group("cinema_id, format").
+		Scan(&rows)
+
+	hasPast := false
+	if len(rows) == 0 {
+		hasPast = movieHasPastSchedules(movie, today)
+	}
+	return summarizeRun(rows, hasPast)
+}
+
+// movieHasPastSchedules 影片在 today 之前是否有过排片（first_seen 摘要或各影院放映跨度汇总任一命中即可）。
+func movieHasPastSchedules(movie Movie, today string) bool {
+	if movie.FirstSeen != nil && movie.FirstSeen.Format("2006-01-02") < today {
+		return true
+	}
+	var n int64
+	db.Model(&CinemaRunSummary{}).Where("movie_id = ? AND first_date <> '' AND first_date < ?", movie.ID, today).Limit(1).Count(&n)
+	return n > 0
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// TestRunSummariesSurvivePrune 放映跨度：清理过去的排片后汇总仍保留影院、日期与场次数，重复刷新不重复计数。
+func TestRunSummariesSurvivePrune(t *testing.T) {
+	newTestRouter(t)
+	now := testNoon
+	setTestClock(t, now)
+
+	// 样例数据以真实的今天为基准，这里不能用被固定的时钟
+	cutoff := time.Now().In(tokyoLocation).Format("2006-01-02")
+	before := loadMovieRunSummaries(20)
+	var total int64
+	db.Model(&Schedule{}).Where("movie_id = ?", 20).Count(&total)
+	if len(before) == 0 || total == 0 {
+		t.Fatalf("fixture movie 20 has no past schedules")
+	}
+	if _, err := archiveSchedulesBefore(cutoff); err != nil {
+		t.Fatal(err)
+	}
+	for cinemaID := range before {
+		if err := refreshRunSummaries(cinemaID, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var hot int64
+	db.Model(&Schedule{}).Where("movie_id = ?", 20).Count(&hot)
+	var sums struct{ Showtimes, Pruned int64 }
+	db.Model(&CinemaRunSummary{}).Select("SUM(showtimes) AS showtimes, SUM(pruned_showtimes) AS pruned").
+		Where("movie_id = ?", 20).Scan(&sums)
+	after := loadMovieRunSummaries(20)
+	if err := firstError(
+		expectEqual("hot schedules", hot, int64(0)),
+		expectEqual("showtimes", sums.Showtimes, total),
+		expectEqual("pruned showtimes", sums.Pruned, total),
+		expectEqual("cinemas", len(after), len(before)),
+	); err != nil {
+		t.Fatal(err)
+	}
+	for cinemaID, run := range before {
+		if after[cinemaID] != run {
+			t.Fatalf("cinema %d run = %+v, want %+v", cinemaID, after[cinemaID], run)
+		}
+	}
+	// END
+}
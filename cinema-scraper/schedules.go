@@ -0,0 +1,235 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ===========================
+// 模块：排片列表 API（/api/schedules）
+// 职责：按日期列出全东京的场次，可按特别场次类型、所在区、影片筛选，按关键字搜索（片名 / 场次注释 / 脚注说明）
+// ===========================
+
+// ScheduleEntry 排片列表中的单个场次。
+type ScheduleEntry struct {
+	ID             uint   `json:"id"`
+	MovieID        uint   `json:"movie_id"`
+	MovieTitle     string `json:"movie_title"`
+	CinemaID       uint   `json:"cinema_id"`
+	CinemaName     string `json:"cinema_name"`
+	CinemaDistrict string `json:"cinema_district"` // 所在区；区部以外为空串
+	PlayDate       string `json:"play_date"`
+	StartTime      string `json:"start_time"`
+	Availability   string `json:"availability"`
+	EventType      string `json:"event_type"`
+	Note           string `json:"note"`
+	AudioHint      string `json:"audio_hint"`   // 见 audiohint.go
+	MembersOnly    bool   `json:"members_only"` // 会員限定场次，见 membersonly.go
+}
+
+// listSchedulesHandler 排片列表接口：
+// - GET /api/schedules?date=YYYY-MM-DD（默认今天）
+// - 可选 event=舞台挨拶 只返回该类型的特别场次
+// - 可选 q=英語字幕 按片名、场次注释与脚注说明模糊匹配
+// - 可选 district=新宿区 / movie_id=12 只返回该区影院 / 该影片的场次，供前端的一日行程视图使用
+// - 默认不含会員限定场次；include_members_only=true 时一并返回
+func listSchedulesHandler(c *gin.Context) {
+	date := c.DefaultQuery("date", todayJST())
+	if _, err := time.Parse("2006-01-02", date); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid date, expected YYYY-MM-DD"})
+		return
+	}
+
+	query := applyMembersOnlyFilter(db, includeMembersOnly(c)).Where("date(play_date) = ?", date)
+	if event := c.Query("event"); event != "" {
+		query = query.Where("event_type = ?", event)
+	}
+	if raw := c.Query("movie_id"); raw != "" {
+		movieID, err := strconv.ParseUint(raw, 10, 64)
+		if err != nil || movieID == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid movie_id, expected a positive integer"})
+			return
+		}
+		query = query.Where("movie_id = ?", movieID)
+	}
+	if district := strings.TrimSpace(c.Query("district")); district != "" {
+		query = query.Where("cinema_id IN (?)", db.Model(&Cinema{}).Select("id").Where("district = ?", district))
+	}
+	if q := strings.TrimSpace(c.Query("q")); q != "" {
+		pattern := "%" + q + "%"
+		titleMatches := db.Model(&Movie{}).Select("id").
+			Where("title_jp LIKE ? OR title_cn LIKE ? OR title_en LIKE ?", pattern, pattern, pattern)
+		query = query.Where("note LIKE ? OR event_type LIKE ? OR movie_id IN (?)", pattern, pattern, titleMatches)
+	}
+	var schedules []Schedule
+	if err := query.Find(&schedules).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to query schedules"})
+		return
+	}
+
+	movieIDs := make(map[uint]struct{})
+	cinemaIDs := make(map[uint]struct{})
+	for _, s := range schedules {
+		movieIDs[s.MovieID] = struct{}{}
+		cinemaIDs[s.CinemaID] = struct{}{}
+	}
+	movieMap := make(map[uint]Movie)
+	cinemaMap := make(map[uint]Cinema)
+	if len(schedules) > 0 {
+		var movies []Movie
+		db.Where("id IN ?", sortedIDs(movieIDs)).Find(&movies)
+		for _, m := range movies {
+			movieMap[m.ID] = m
+		}
+		var cinemas []Cinema
+		db.Where("id IN ?", sortedIDs(cinemaIDs)).Find(&cinemas)
+		for _, cin := range cinemas {
+			cinemaMap[cin.ID] = cin
+		}
+	}
+
+	lang := requestLang(c)
+	items := make([]ScheduleEntry, 0, len(schedules))
+	for _, s := range schedules {
+		m, ok1 := movieMap[s.MovieID]
+		cin, ok2 := cinemaMap[s.CinemaID]
+		if !ok1 || !ok2 {
+			continue
+		}
+		st := movieShowtime(s, m)
+		items = append(items, ScheduleEntry{
+			ID:             s.ID,
+			MovieID:        m.ID,
+			MovieTitle:     movieDisplayTitleLang(m, lang),
+			CinemaID:       cin.ID,
+			CinemaName:     cin.NameJP,
+			CinemaDistrict: cin.District,
+			PlayDate:       s.PlayDate.Format("2006-01-02"),
+			StartTime:      s.StartTime,
+			Availability:   st.Availability,
+			EventType:      st.EventType,
+			Note:           st.Note,
+			AudioHint:      st.AudioHint,
+			MembersOnly:    s.MembersOnly,
+		})
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		ti, tj := startTimeMinutes(items[i].StartTime), startTimeMinutes(items[j].StartTime)
+		if ti != tj {
+			return ti < tj
+		}
+		return items[i].ID < items[j].ID
+	})
+
+	c.JSON(http.StatusOK, withFreshness(gin.H{
+		"date":  date,
+		"items": items,
+	}))
+}
+
+// ScheduleDetail 单个场次详情（分享卡片 / 加入日历所需的全部信息）。
+type ScheduleDetail struct {
+	ID           uint   `json:"id"`
+	PlayDate     string `json:"play_date"`
+	StartTime    string `json:"start_time"`
+	EndTime      string `json:"end_time"` // 按片长推算（HH:mm，可能超过 24:00）；片长未知时为空
+	IsPast       bool   `json:"is_past"`
+	Availability string `json:"availability"`
+	EventType    string `json:"event_type"`
+	Note         string `json:"note"`
+	AudioHint    string `json:"audio_hint"` // 见 audiohint.go
+	ICalUID      string `json:"ical_uid"`
+	Movie        struct {
+		ID      uint    `json:"id"`
+		Title   string  `json:"title"`
+		TitleJP string  `json:"title_jp"`
+		Poster  string  `json:"poster"`
+		Runtime int     `json:"runtime"`
+		Rating  float64 `json:"rating"`
+	} `json:"movie"`
+	Cinema struct {
+		ID         uint    `json:"id"`
+		Name       string  `json:"name"`
+		Address    string  `json:"address"`
+		Lat        float64 `json:"lat"`
+		Lng        float64 `json:"lng"`
+		BookingURL string  `json:"booking_url"`
+	} `json:"cinema"`
+	ScheduleFreshness
+}
+
+// scheduleICalUID 场次在日历中的稳定 UID（只依赖场次 ID，重复导入不会产生重复事件）。
+func scheduleICalUID(id uint) string {
+	return fmt.Sprintf("schedule-%d@tokyo-cinepath", id)
+}
+
+// scheduleEndTime 开场时间加片长，得到结束时间（HH:mm）；无法计算时返回空串。
+func scheduleEndTime(startTime string, runtime int) string {
+	start := startTimeMinutes(startTime)
+	if runtime <= 0 || !strings.Contains(startTime, ":") {
+		return ""
+	}
+	end := start + runtime
+	return fmt.Sprintf("%02d:%02d", end/60, end%60)
+}
+
+// getScheduleHandler 单个场次详情接口：GET /api/schedules/:id
+func getScheduleHandler(c *gin.Context) {
+	var s Schedule
+	if err := db.First(&s, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "schedule not found"})
+		return
+	}
+	var movie Movie
+	if err := db.First(&movie, s.MovieID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "schedule not found"})
+		return
+	}
+	var cinema Cinema
+	if err := db.First(&cinema, s.CinemaID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "schedule not found"})
+		return
+	}
+
+	st := movieShowtime(s, movie)
+	playDate := s.PlayDate.Format("2006-01-02")
+	now := nowJST()
+	today := now.Format("2006-01-02")
+
+	var detail ScheduleDetail
+	detail.ID = s.ID
+	detail.PlayDate = playDate
+	detail.StartTime = s.StartTime
+	detail.EndTime = scheduleEndTime(s.StartTime, movie.Runtime)
+	detail.IsPast = playDate < today ||
+		(playDate == today && startTimeMinutes(s.StartTime) < now.Hour()*60+now.Minute())
+	detail.Availability = st.Availability
+	detail.EventType = st.EventType
+	detail.Note = st.Note
+	detail.AudioHint = st.AudioHint
+	detail.ICalUID = scheduleICalUID(s.ID)
+
+	detail.Movie.ID = movie.ID
+	detail.Movie.Title = movieDisplayTitleLang(movie, requestLang(c))
+	detail.Movie.TitleJP = movie.TitleJP
+	detail.Movie.Poster = movie.Poster
+	detail.Movie.Runtime = movie.Runtime
+	detail.Movie.Rating = math.Round(combinedRating(movie)*10) / 10
+
+	detail.Cinema.ID = cinema.ID
+	detail.Cinema.Name = cinema.NameJP
+	detail.Cinema.Address = cinema.Address
+	detail.Cinema.Lat = cinema.Latitude
+	detail.Cinema.Lng = cinema.Longitude
+	detail.Cinema.BookingURL = cinema.Website
+	detail.ScheduleFreshness = scheduleFreshness()
+
+	c.JSON(http.StatusOK, detail)
+}
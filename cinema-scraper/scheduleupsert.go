@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ===========================
+// 模块：场次唯一约束与批量写入
+// 职责：
+// - schedules 表在 (movie_id, cinema_id, play_date, start_time) 上建唯一索引 idx_schedule_slot，
+//   并发抓取也不会写出重复场次
+// - 旧库建索引前先清理重复行（dedupeSchedulesForUniqueIndex，登记为 AutoMigrate 之前执行的破坏性迁移，见 migrations.go），
+//   否则 AutoMigrate 建索引会失败
+// - eiga 抓取按影片区块收集场次，一次批量 upsert 写入，取代逐个场次 SELECT + INSERT
+// 说明：冲突时刷新余票 / 特别场次 / 版本 / 脚注，与原先 FirstOrCreate + Assign 的语义一致；
+//       如果改成 DoNothing，满席等余票变化将永远写不进去。
+// ===========================
+
+// scheduleSlotIndex 场次唯一索引名（与 Schedule 结构体上的 uniqueIndex 标签一致）。
+const scheduleSlotIndex = "idx_schedule_slot"
+
+// scheduleUpsertBatch 每条 INSERT 语句写入的场次数。
+const scheduleUpsertBatch = 200
+
+// dedupeSchedulesForUniqueIndex 唯一索引尚未建立时删除重复场次：同一槽位只保留 ID 最小的一条
+// （FirstOrCreate 一直查到并更新的就是它）。包括软删除的行，唯一索引对它们同样生效。
+func dedupeSchedulesForUniqueIndex(conn *gorm.DB) (int64, error) {
+	m := conn.Migrator()
+	if !m.HasTable(&Schedule{}) || m.HasIndex(&Schedule{}, scheduleSlotIndex) {
+		return 0, nil
+	}
+	res := conn.Exec(`DELETE FROM schedules WHERE id NOT IN (
+		SELECT MIN(id) FROM schedules GROUP BY movie_id, cinema_id, play_date, start_time)`)
+	if res.Error != nil {
+		return 0, fmt.Errorf("清理重复场次失败: %w", res.Error)
+	}
+	return res.RowsAffected, nil
+}
+
+// upsertShowtimes 批量写入场次：新槽位插入，已存在的槽位只刷新余票与场次标注（含会員限定标记）。
+func upsertShowtimes(rows []Schedule) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	return db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "movie_id"}, {Name: "cinema_id"}, {Name: "play_date"}, {Name: "start_time"}},
+		DoUpdates: clause.AssignmentColumns([]string{"availability", "event_type", "format", "note", "members_only", "updated_at"}),
+	}).CreateInBatches(&rows, scheduleUpsertBatch).Error
+}
@@ -0,0 +1,387 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ===========================
+// 模块：开发用种子数据（--seed）
+// 职责：
+// - 为本地开发生成一套接近真实的数据：15 家影院（分布在多个区，坐标合理）、40 部影片（各种状态与评分分布）、
+//   两周排片（含深夜场、名画座的 2 本立、满席 / 余票少的场次、舞台挨拶）
+// - 全部由固定种子生成（math/rand/v2 的 PCG，跨 Go 版本输出稳定），同一天运行结果完全一致，截图与自检可复现
+// - 影片状态按生成的排片用 statusFromScheduleRange 推算，与 update-status 的结果一致
+// 说明：只在显式指定 --seed 且库中没有影院与影片时写入，不会污染抓取来的数据；
+//       日期相对 today 生成，需要固定画面时配合接口的 as_of 参数。
+//       接口测试的断言依赖 fixtures_test.go 的小数据集，这里的生成器由 seeddata_test.go 单独在另一个内存库上校验。
+// 调用方式：
+//   DB_PATH=dev.db go run . --seed
+// ===========================
+
+// devSeed 固定种子。
+const devSeed = 20260121
+
+// devSeedDays 生成排片的天数（含今天）。
+const devSeedDays = 14
+
+// devSeedMovieCount 生成的影片数。
+const devSeedMovieCount = 40
+
+// devSeedDataset 生成结果。Schedules 中的 MovieID / CinemaID 是 Movies / Cinemas 的序号（1 起），写库时换成真实 ID。
+type devSeedDataset struct {
+	Cinemas   []Cinema
+	Movies    []Movie
+	Schedules []Schedule
+}
+
+// devSeedCinema 影院模板：名称虚构，地址与坐标取各区真实街区。
+type devSeedCinema struct {
+	Name, Kana, Address string
+	Lat, Lng            float64
+	Screens             int
+	Tags                string
+	Late                bool // 有深夜场
+	Double              bool // 名画座 2 本立
+}
+
+var devSeedCinemas = []devSeedCinema{
+	{"新宿シネマ・ルミエール", "しんじゅくしねまるみえーる", "東京都新宿区新宿3-15-1", 35.6918, 139.7046, 8, "シネコン", true, false},
+	{"歌舞伎町ミッドナイトシアター", "かぶきちょうみっどないとしあたー", "東京都新宿区歌舞伎町1-19-1", 35.6951, 139.7020, 6, "シネコン", true, false},
+	{"高田馬場パール座", "たかだのばばぱーるざ", "東京都新宿区高田馬場1-28-3", 35.7126, 139.7038, 1, "名画座,2本立", false, true},
+	{"渋谷シネ・アルコ", "しぶやしねあるこ", "東京都渋谷区宇田川町31-2", 35.6617, 139.6980, 2, "ミニシアター", false, false},
+	{"代官山フィルムハウス", "だいかんやまふぃるむはうす", "東京都渋谷区恵比寿西1-35-8", 35.6485, 139.7030, 1, "ミニシアター", false, false},
+	{"神保町名画座ひかり", "じんぼうちょうめいがざひかり", "東京都千代田区神田神保町2-14", 35.6958, 139.7565, 1, "名画座,2本立", false, true},
+	{"有楽町シネマテーク", "ゆうらくちょうしねまてーく", "東京都千代田区有楽町2-5-1", 35.6747, 139.7630, 4, "シネコン", false, false},
+	{"銀座ルナ劇場", "ぎんざるなげきじょう", "東京都中央区銀座4-8-7", 35.6717, 139.7650, 2, "ミニシアター", false, false},
+	{"六本木アートシアター", "ろっぽんぎあーとしあたー", "東京都港区六本木6-10-2", 35.6605, 139.7292, 7, "シネコン", true, false},
+	{"池袋ネオンシネマ", "いけぶくろねおんしねま", "東京都豊島区東池袋1-22-10", 35.7295, 139.7145, 10, "シネコン", true, false},
+	{"中野ブロードシネマ", "なかのぶろーどしねま", "東京都中野区中野5-52-15", 35.7078, 139.6657, 1, "ミニシアター", false, false},
+	{"阿佐ヶ谷フィルムスタジオ", "あさがやふぃるむすたじお", "東京都杉並区阿佐谷北2-12-21", 35.7050, 139.6360, 1, "名画座,2本立", false, true},
+	{"下高井戸キネマ", "しもたかいどきねま", "東京都世田谷区松原3-27-26", 35.6665, 139.6415, 1, "名画座", false, false},
+	{"錦糸町リバーシネマ", "きんしちょうりばーしねま", "東京都墨田区江東橋4-27-14", 35.6955, 139.8145, 9, "シネコン", true, false},
+	{"吉祥寺シネマ・ボタニカ", "きちじょうじしねまぼたにか", "東京都武蔵野市吉祥寺本町2-10-1", 35.7050, 139.5790, 3, "ミニシアター", false, false},
+}
+
+// 片名与导演的素材：日 / 中 / 英三语一一对应。
+var (
+	devSeedWordsJP = []string{"夜明け", "海辺", "約束", "硝子", "迷宮", "星屑", "渚", "記憶", "季節", "灯台"}
+	devSeedWordsCN = []string{"黎明", "海边", "约定", "玻璃", "迷宫", "星尘", "渚", "记忆", "季节", "灯塔"}
+	devSeedWordsEN = []string{"Dawn", "Seaside", "Promise", "Glass", "Labyrinth", "Stardust", "Shore", "Memory", "Seasons", "Lighthouse"}
+	devSeedFamily  = []string{"Tanaka", "Moreau", "Park", "Okafor", "Lindqvist", "Suzuki", "Rossi", "Chen"}
+	devSeedGiven   = []string{"Aki", "Claire", "Ji-won", "Tomas", "Hana", "Luca", "Mei", "Noah"}
+	devSeedGenres  = []string{"剧情", "喜剧", "动画", "纪录", "惊悚", "爱情", "科幻", "家庭"}
+	devSeedLangs   = []string{"ja", "ja", "ja", "ja", "en", "en", "fr", "ko", "zh", "it"}
+)
+
+// 各类影院的场次模板。
+var (
+	devSeedMultiplexTimes = []string{"09:30", "10:15", "11:40", "12:50", "14:10", "15:25", "16:45", "18:00", "19:20", "20:35"}
+	devSeedMiniTimes      = []string{"10:30", "13:00", "15:30", "18:10", "20:30"}
+	devSeedLateTimes      = []string{"21:50", "23:30"}
+	devSeedWeekendLate    = "24:40" // 周五、周六加映的跨午夜场
+)
+
+// generateDevSeedData 由 seed 与 today（只取日期）生成整套种子数据（纯函数）。
+func generateDevSeedData(seed uint64, today time.Time) devSeedDataset {
+	rng := rand.New(rand.NewPCG(seed, seed))
+	day := time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, time.UTC)
+	var ds devSeedDataset
+
+	for _, t := range devSeedCinemas {
+		ds.Cinemas = append(ds.Cinemas, Cinema{
+			NameJP:      t.Name,
+			NameKana:    t.Kana,
+			Address:     t.Address,
+			Latitude:    t.Lat + (rng.Float64()-0.5)*0.003,
+			Longitude:   t.Lng + (rng.Float64()-0.5)*0.003,
+			GeoStatus:   GeoStatusExact,
+			ScreenCount: t.Screens,
+			Tags:        t.Tags,
+			Source:      CinemaSourceManual,
+		})
+	}
+
+	// 影片的排片窗口：前 24 部今天起在映（三分之一在两周内下映），24-29 部几天后开始（incoming），
+	// 30-33 部第二周末才开始（future），最后 6 部没有排片（unplanned）
+	// 在映影片中每 4 部有一部旧片，只在名画座放映，且两周内不下映（保证 2 本立每周都能配成一组）
+	type window struct{ first, last int }
+	windows := make([]window, devSeedMovieCount)
+	revival := make([]bool, devSeedMovieCount)
+	for i := range windows {
+		switch {
+		case i < 24:
+			windows[i] = window{0, devSeedDays - 1}
+			revival[i] = i%4 == 3
+			if i%3 == 2 && !revival[i] {
+				windows[i].last = 4 + rng.IntN(8)
+			}
+		case i < 30:
+			windows[i] = window{2 + rng.IntN(5), devSeedDays - 1}
+		case i < 34:
+			windows[i] = window{10 + rng.IntN(4), devSeedDays - 1}
+		default:
+			windows[i] = window{-1, -1}
+		}
+	}
+	for i := 0; i < devSeedMovieCount; i++ {
+		ds.Movies = append(ds.Movies, devSeedMovie(rng, i, day, windows[i].first, revival[i]))
+	}
+
+	// 旧片进全部名画座；新片分给其余影院中的 1-5 家
+	repertoire := make([][]int, len(devSeedCinemas))
+	for i, w := range windows {
+		if w.first < 0 {
+			continue
+		}
+		var candidates []int
+		for ci, t := range devSeedCinemas {
+			if revival[i] == (t.Double || t.Tags == "名画座") {
+				candidates = append(candidates, ci)
+			}
+		}
+		n := len(candidates)
+		if !revival[i] {
+			rng.Shuffle(len(candidates), func(a, b int) { candidates[a], candidates[b] = candidates[b], candidates[a] })
+			n = min(1+rng.IntN(5), n)
+		}
+		for _, ci := range candidates[:n] {
+			repertoire[ci] = append(repertoire[ci], i)
+		}
+	}
+
+	seen := make(map[string]bool)
+	add := func(s Schedule) {
+		key := fmt.Sprintf("%d|%d|%s|%s", s.MovieID, s.CinemaID, s.PlayDate.Format("2006-01-02"), s.StartTime)
+		if seen[key] {
+			return
+		}
+		seen[key] = true
+		ds.Schedules = append(ds.Schedules, s)
+	}
+	for offset := 0; offset < devSeedDays; offset++ {
+		date := day.AddDate(0, 0, offset)
+		weekend := date.Weekday() == time.Friday || date.Weekday() == time.Saturday
+		for ci, t := range devSeedCinemas {
+			var playing []int
+			for _, i := range repertoire[ci] {
+				if offset >= windows[i].first && offset <= windows[i].last {
+					playing = append(playing, i)
+				}
+			}
+			if len(playing) == 0 {
+				continue
+			}
+			if t.Double {
+				for _, s := range devSeedDoubleFeature(rng, ds.Movies, playing, offset, ci, date) {
+					add(s)
+				}
+				continue
+			}
+			times := devSeedMiniTimes
+			if t.Screens >= 4 {
+				times = devSeedMultiplexTimes
+			}
+			if t.Late {
+				times = append(append([]string{}, times...), devSeedLateTimes...)
+				if weekend {
+					times = append(times, devSeedWeekendLate)
+				}
+			}
+			for si, start := range times {
+				i := playing[(si+offset+ci)%len(playing)]
+				s := Schedule{
+					MovieID:      uint(i + 1),
+					CinemaID:     uint(ci + 1),
+					PlayDate:     date,
+					StartTime:    start,
+					Availability: devSeedAvailability(rng, start, weekend || offset == 0),
+				}
+				if lang := ds.Movies[i].OriginalLanguage; lang != "ja" && t.Screens >= 4 {
+					s.Format = FormatSubbed
+					if hasDualVersionGenre(ds.Movies[i].Genre) && si%2 == 1 {
+						s.Format = FormatDubbed
+					}
+				}
+				// 每家影院在第 3 天有一场舞台挨拶，照例满席
+				if offset == 2 && si == len(times)/2 {
+					s.EventType, s.Availability = EventStageGreeting, AvailabilitySoldOut
+				}
+				add(s)
+			}
+		}
+	}
+
+	// 状态按排片推算（与 update-status 相同的规则）
+	first := make(map[uint]string)
+	last := make(map[uint]string)
+	for _, s := range ds.Schedules {
+		d := s.PlayDate.Format("2006-01-02")
+		if first[s.MovieID] == "" || d < first[s.MovieID] {
+			first[s.MovieID] = d
+		}
+		if d > last[s.MovieID] {
+			last[s.MovieID] = d
+		}
+	}
+	for i := range ds.Movies {
+		id := uint(i + 1)
+		if status, err := statusFromScheduleRange(first[id], last[id], day.Format("2006-01-02")); err == nil {
+			ds.Movies[i].Status = status
+		}
+	}
+	return ds
+}
+
+// devSeedMovie 第 i 部影片：评分围绕各自的“质量”上下浮动，部分影片缺豆瓣或 IMDb 评分（模拟尚未补全）。
+func devSeedMovie(rng *rand.Rand, i int, today time.Time, firstDay int, revival bool) Movie {
+	a, b := i%10, (i/10+i%10+1)%10
+	lang := devSeedLangs[rng.IntN(len(devSeedLangs))]
+	genre := devSeedGenres[rng.IntN(len(devSeedGenres))]
+	if rng.IntN(3) == 0 {
+		if g := devSeedGenres[rng.IntN(len(devSeedGenres))]; g != genre {
+			genre += ", " + g
+		}
+	}
+	quality := math.Max(3.5, math.Min(9.3, rng.NormFloat64()*1.1+6.6))
+	rating := func(spread, bias float64) float64 {
+		return math.Round(math.Max(1, math.Min(9.8, quality+bias+rng.NormFloat64()*spread))*10) / 10
+	}
+	year := today.Year()
+	if revival {
+		year = 1950 + rng.IntN(50)
+	} else if rng.IntN(3) == 0 {
+		year--
+	}
+	release := today.AddDate(0, 0, -1-rng.IntN(60))
+	if firstDay > 0 {
+		release = today.AddDate(0, 0, firstDay)
+	}
+	m := Movie{
+		TMDBID:           900000 + i + 1,
+		IMDBID:           fmt.Sprintf("tt99%05d", i+1),
+		TitleJP:          devSeedWordsJP[a] + "の" + devSeedWordsJP[b],
+		TitleCN:          devSeedWordsCN[a] + "的" + devSeedWordsCN[b],
+		TitleEN:          devSeedWordsEN[b] + " of " + devSeedWordsEN[a],
+		Director:         devSeedGiven[rng.IntN(len(devSeedGiven))] + " " + devSeedFamily[rng.IntN(len(devSeedFamily))],
+		Year:             fmt.Sprint(year),
+		Synopsis:         fmt.Sprintf("关于%s与%s的故事（开发用样例数据）。", devSeedWordsCN[a], devSeedWordsCN[b]),
+		Runtime:          80 + rng.IntN(60),
+		Genre:            genre,
+		OriginalLanguage: lang,
+		TMDBRating:       rating(0.4, 0),
+		TMDBVotes:        int(math.Exp(2 + rng.Float64()*7)),
+		IMDBRating:       rating(0.5, 0.1),
+		IMDBVotes:        int(math.Exp(3 + rng.Float64()*8)),
+		DoubanRating:     rating(0.6, 0.3),
+		Kind:             MovieKindFilm,
+		ReleaseDate:      release,
+	}
+	if rng.IntN(8) == 0 {
+		m.Runtime += 60 // 少数长片，2 本立与晚场的结束时间因此更靠后
+	}
+	if rng.IntN(6) == 0 {
+		m.DoubanRating = 0
+	}
+	if rng.IntN(10) == 0 {
+		m.IMDBRating, m.IMDBVotes = 0, 0
+	}
+	return m
+}
+
+// devSeedDoubleFeature 名画座的 2 本立：每周换一组两部旧片，全天交替放映，入替なし。
+func devSeedDoubleFeature(rng *rand.Rand, movies []Movie, playing []int, offset, ci int, date time.Time) []Schedule {
+	sort.Ints(playing)
+	week := offset / 7
+	a := playing[(week*2+ci)%len(playing)]
+	b := playing[(week*2+ci+1)%len(playing)]
+	var out []Schedule
+	for show, clock := 0, 11*60; ; show++ {
+		i := a
+		if show%2 == 1 {
+			i = b
+		}
+		if clock+movies[i].Runtime > 23*60 {
+			break
+		}
+		out = append(out, Schedule{
+			MovieID:      uint(i + 1),
+			CinemaID:     uint(ci + 1),
+			PlayDate:     date,
+			StartTime:    fmt.Sprintf("%02d:%02d", clock/60, clock%60),
+			Availability: devSeedAvailability(rng, "", false),
+			Note:         "2本立（入替なし）",
+		})
+		clock += (movies[i].Runtime + 15 + 4) / 5 * 5
+	}
+	return out
+}
+
+// devSeedAvailability 余票：晚场与周末更容易满席。
+func devSeedAvailability(rng *rand.Rand, start string, busy bool) string {
+	p := rng.Float64()
+	if busy && start >= "18:00" {
+		switch {
+		case p < 0.1:
+			return AvailabilitySoldOut
+		case p < 0.3:
+			return AvailabilityFew
+		}
+		return AvailabilityAvailable
+	}
+	if p < 0.05 {
+		return AvailabilityFew
+	}
+	return AvailabilityAvailable
+}
+
+// loadDevSeedData 把生成的种子数据写入 conn（调用方保证为空库），返回写入的数据。
+func loadDevSeedData(conn *gorm.DB, today time.Time) (devSeedDataset, error) {
+	ds := generateDevSeedData(devSeed, today)
+	err := conn.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&ds.Cinemas).Error; err != nil {
+			return fmt.Errorf("create seed cinemas: %w", err)
+		}
+		if err := tx.Create(&ds.Movies).Error; err != nil {
+			return fmt.Errorf("create seed movies: %w", err)
+		}
+		for i := range ds.Schedules {
+			ds.Schedules[i].MovieID = ds.Movies[ds.Schedules[i].MovieID-1].ID
+			ds.Schedules[i].CinemaID = ds.Cinemas[ds.Schedules[i].CinemaID-1].ID
+		}
+		if err := tx.CreateInBatches(&ds.Schedules, scheduleUpsertBatch).Error; err != nil {
+			return fmt.Errorf("create seed schedules: %w", err)
+		}
+		return nil
+	})
+	return ds, err
+}
+
+// seedDevDatabase --seed：库中没有影院与影片时写入种子数据，并补建放映跨度汇总；已有数据时不做任何事。
+func seedDevDatabase() error {
+	var cinemas, movies int64
+	if err := db.Model(&Cinema{}).Count(&cinemas).Error; err != nil {
+		return err
+	}
+	if err := db.Unscoped().Model(&Movie{}).Count(&movies).Error; err != nil {
+		return err
+	}
+	if cinemas > 0 || movies > 0 {
+		fmt.Printf("ℹ️ 数据库已有 %d 家影院、%d 部影片，跳过种子数据\n", cinemas, movies)
+		return nil
+	}
+	ds, err := loadDevSeedData(db, nowJST())
+	if err != nil {
+		return err
+	}
+	if _, err := backfillRunSummaries(); err != nil {
+		return err
+	}
+	fmt.Printf("🌱 已写入种子数据：%d 家影院、%d 部影片、%d 个场次\n", len(ds.Cinemas), len(ds.Movies), len(ds.Schedules))
+	return nil
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// TestSeedData 开发用种子数据：同一种子结果完全一致，含各种状态、深夜场、2 本立与满席场次。
+func TestSeedData(t *testing.T) {
+	setTestClock(t, beforeMidnight)
+
+	today := time.Date(2026, 1, 23, 0, 0, 0, 0, time.UTC) // 周五
+	a, _ := json.Marshal(generateDevSeedData(devSeed, today))
+	b, _ := json.Marshal(generateDevSeedData(devSeed, today))
+	other, _ := json.Marshal(generateDevSeedData(devSeed+1, today))
+	// 写入另一个内存库，与样例数据互不影响
+	conn, err := openDatabase(testDSN)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sqlDB, err := conn.DB(); err == nil {
+		sqlDB.SetMaxOpenConns(1)
+		defer sqlDB.Close()
+	}
+	conn = conn.Session(&gorm.Session{Logger: logger.Default.LogMode(logger.Silent)})
+	ds, err := loadDevSeedData(conn, today)
+	if err != nil {
+		t.Fatal(err)
+	}
+	statuses := make(map[string]int)
+	for _, m := range ds.Movies {
+		statuses[m.Status]++
+	}
+	var late, double, soldOut, wards int64
+	conn.Model(&Schedule{}).Where("start_time >= ?", "24:00").Count(&late)
+	conn.Model(&Schedule{}).Where("note LIKE ?", "2本立%").Count(&double)
+	conn.Model(&Schedule{}).Where("availability = ?", AvailabilitySoldOut).Count(&soldOut)
+	conn.Model(&Cinema{}).Where("district <> ''").Distinct("district").Count(&wards)
+	if err := firstError(
+		expectEqual("deterministic", string(a), string(b)),
+		expectEqual("seed matters", string(a) != string(other), true),
+		expectEqual("cinemas", len(ds.Cinemas), 15),
+		expectEqual("movies", len(ds.Movies), devSeedMovieCount),
+		expectEqual("all statuses", statuses["showing"] > 0 && statuses["incoming"] > 0 && statuses["future"] > 0 && statuses["unplanned"] > 0, true),
+		expectEqual("late shows", late > 0, true),
+		expectEqual("double features", double > 0, true),
+		expectEqual("sold out", soldOut > 0, true),
+		expectEqual("several wards", wards >= 8, true)); err != nil {
+		t.Fatal(err)
+	}
+}
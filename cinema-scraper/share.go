@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ===========================
+// 模块：影片分享卡片（/api/movies/:id/share）
+// 职责：
+// - 一次返回前端生成分享卡片 / 二维码所需的数据：三语标题、接下来的 3 个场次、海报代理地址、前端规范 URL
+// - /api/movies/:id/poster 输出影片当前海报，前端可在 canvas 上同源绘制（TMDB 图片不带 CORS 头）；
+//   图片来自本地缓存（见 imagecache.go），按内容哈希返回强 ETag，If-None-Match 命中时返回 304
+// - 海报已缓存时 poster_url 直接给出按内容寻址的 /img/:hash（可永久缓存），否则给出 /api/movies/:id/poster
+// 说明：?format=png 服务端渲染分享图需要图片与日文字体库，当前未引入，返回 501。
+//       TMDB 更换海报后，缓存重新校验时影片改指向新哈希，旧的 /img/:hash 仍可访问。
+// ===========================
+
+const (
+	shareNextShowtimesLimit = 3
+	posterProxyMaxBytes     = 5 << 20 // 海报代理的大小上限
+	posterProxyCacheControl = "public, max-age=86400"
+)
+
+// ShareTitles 三语标题，缺失的语言为空串。
+type ShareTitles struct {
+	JA string `json:"ja"`
+	EN string `json:"en"`
+	ZH string `json:"zh"`
+}
+
+// SharePayload 分享卡片数据。
+type SharePayload struct {
+	ID            uint           `json:"id"`
+	Title         string         `json:"title"` // 按 lang / Accept-Language 选择的默认标题
+	Titles        ShareTitles    `json:"titles"`
+	Year          string         `json:"year"`
+	NextShowtimes []NextShowtime `json:"next_showtimes"`
+	PosterURL     string         `json:"poster_url"` // 海报地址（/img/:hash 或海报代理），没有海报时为空
+	URL           string         `json:"url"`        // 前端影片页的规范 URL（可直接生成二维码）
+}
+
+// buildSharePayload 组装分享数据（纯函数）：schedules 为影片今天及以后的排片。
+func buildSharePayload(m Movie, schedules []Schedule, cinemaNames map[uint]string, now time.Time, lang string) SharePayload {
+	payload := SharePayload{
+		ID:    m.ID,
+		Title: movieDisplayTitleLang(m, lang),
+		Titles: ShareTitles{
+			JA: strings.TrimSpace(m.TitleJP),
+			EN: strings.TrimSpace(m.TitleEN),
+			ZH: strings.TrimSpace(m.TitleCN),
+		},
+		Year:          m.Year,
+		NextShowtimes: nextShowtimes(schedules, cinemaNames, now, shareNextShowtimesLimit),
+		URL:           frontendBaseURL() + movieCanonicalPath(m),
+	}
+	payload.PosterURL = ownedImageURL(m.Poster, m.PosterHash, fmt.Sprintf("/api/movies/%d/poster", m.ID))
+	return payload
+}
+
+// shareMovieHandler 处理 GET /api/movies/:id/share?format=json|png
+func shareMovieHandler(c *gin.Context) {
+	switch c.DefaultQuery("format", "json") {
+	case "json":
+	case "png":
+		c.JSON(http.StatusNotImplemented, gin.H{"error": "png share image is not supported, use format=json"})
+		return
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid format, expected json or png"})
+		return
+	}
+
+	var movie Movie
+	if err := db.First(&movie, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "movie not found"})
+		return
+	}
+	now := referenceTime(c)
+	var schedules []Schedule
+	if err := db.Where("movie_id = ? AND date(play_date) >= ? AND members_only = ?", movie.ID, now.Format("2006-01-02"), false).
+		Order("id").Find(&schedules).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to query schedules"})
+		return
+	}
+	cinemaNames := make(map[uint]string)
+	if len(schedules) > 0 {
+		ids := make(map[uint]struct{})
+		for _, s := range schedules {
+			ids[s.CinemaID] = struct{}{}
+		}
+		var cinemas []Cinema
+		db.Select("id", "name_jp").Where("id IN ?", sortedIDs(ids)).Find(&cinemas)
+		for _, cin := range cinemas {
+			cinemaNames[cin.ID] = cin.NameJP
+		}
+	}
+
+	c.JSON(http.StatusOK, buildSharePayload(movie, schedules, cinemaNames, now, requestLang(c)))
+}
+
+// moviePosterHandler 处理 GET /api/movies/:id/poster：输出影片海报的缓存图片（首次请求时从上游取图，
+// 只接受图片，超过上限或取图失败返回 502）。带强 ETag，If-None-Match 命中时返回 304。
+func moviePosterHandler(c *gin.Context) {
+	var movie Movie
+	if err := db.Select("id", "poster", "poster_hash").First(&movie, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "movie not found"})
+		return
+	}
+	serveOwnedImage(c, moviePosterImages, movie.ID, movie.Poster, movie.PosterHash, "poster not found")
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/gocolly/colly/v2"
+)
+
+// ===========================
+// 模块：优雅退出（SIGINT / SIGTERM）
+// 职责：
+// - API：收到信号后停止接受新连接，等待处理中的请求完成（最多 serverShutdownTimeout）再退出；
+//   请求的 Context 派生自信号 Context，SSE 事件流（/api/events/stream）据此立即结束，不会拖到超时
+// - 抓取（crawl-cinemas / crawl-schedules）：收到信号后不再访问新页面（含翻页与详情页），
+//   正在处理的页面照常写完数据库；随后照常结束本次 CrawlRun（状态记为 interrupted），以 exitCodeInterrupted 退出
+// - 第一次信号后恢复默认处理：再按一次 Ctrl+C 立即强制退出
+// 说明：退出码 130 与 shell 中 Ctrl+C 终止的惯例一致，包装脚本可据此区分“被中断”与“失败”（1）。
+// ===========================
+
+const (
+	serverShutdownTimeout = 15 * time.Second
+	exitCodeInterrupted   = 130
+	// CrawlRunStatusInterrupted 抓取被信号中断：已抓到的数据保留，不生成快照。
+	CrawlRunStatusInterrupted = "interrupted"
+)
+
+// errInterrupted 抓取因收到 SIGINT / SIGTERM 提前结束。
+var errInterrupted = errors.New("interrupted by signal")
+
+// interruptContext 收到 SIGINT / SIGTERM 时取消的 Context；第一次信号后恢复默认处理，再次收到信号直接退出进程。
+func interruptContext() (context.Context, context.CancelFunc) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ctx.Done()
+		stop()
+		fmt.Println("\n🛑 收到退出信号，正在收尾（再按一次 Ctrl+C 强制退出）...")
+	}()
+	return ctx, stop
+}
+
+// interruptedErr ctx 已取消时返回 errInterrupted，否则返回 nil。
+func interruptedErr(ctx context.Context) error {
+	if ctx.Err() != nil {
+		return errInterrupted
+	}
+	return nil
+}
+
+// stopCollectorsOn ctx 取消后各 Collector 不再发出新请求（OnRequest 中 Abort），已取回的页面照常解析写库。
+func stopCollectorsOn(ctx context.Context, collectors ...*colly.Collector) {
+	for _, c := range collectors {
+		c.OnRequest(func(r *colly.Request) {
+			if ctx.Err() != nil {
+				r.Abort()
+			}
+		})
+	}
+}
+
+// serveUntilInterrupted 启动 HTTP 服务，ctx 取消后优雅关闭：不再接受新连接，等待处理中的请求最多 timeout，
+// 超时后强制关闭。返回前 ListenAndServe 与各连接的 goroutine 都已结束，调用方可以放心清理全局状态。
+func serveUntilInterrupted(ctx context.Context, handler http.Handler, addr string, timeout time.Duration) error {
+	// 连接 goroutine 在 Shutdown 返回后还会执行最后的状态回调，用 ConnState 计数等它们结束
+	var conns sync.WaitGroup
+	srv := &http.Server{
+		Addr:    addr,
+		Handler: handler,
+		// 请求 Context 随信号取消，长连接（SSE）据此退出
+		BaseContext: func(net.Listener) context.Context { return ctx },
+		ConnState: func(_ net.Conn, state http.ConnState) {
+			switch state {
+			case http.StateNew:
+				conns.Add(1)
+			case http.StateClosed, http.StateHijacked:
+				conns.Done()
+			}
+		},
+	}
+	serveErr := make(chan error, 1)
+	go func() { serveErr <- srv.ListenAndServe() }()
+
+	select {
+	case err := <-serveErr:
+		return err
+	case <-ctx.Done():
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	shutdownErr := srv.Shutdown(shutdownCtx)
+	if shutdownErr != nil {
+		srv.Close()
+	}
+	err := <-serveErr
+	conns.Wait()
+	if shutdownErr != nil {
+		return fmt.Errorf("graceful shutdown: %w", shutdownErr)
+	}
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gocolly/colly/v2"
+)
+
+// TestGracefulShutdown 优雅退出：处理中的请求完成后才关闭，长连接随信号结束，中断后抓取不再访问新页面。
+func TestGracefulShutdown(t *testing.T) {
+	setTestClock(t, beforeMidnight)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	entered := make(chan struct{})
+	mux := http.NewServeMux()
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		close(entered)
+		time.Sleep(200 * time.Millisecond)
+		fmt.Fprint(w, "done")
+	})
+	streamStarted, streamEnded := make(chan struct{}), make(chan struct{})
+	mux.HandleFunc("/stream", func(w http.ResponseWriter, r *http.Request) {
+		close(streamStarted)
+		<-r.Context().Done()
+		close(streamEnded)
+	})
+	served := make(chan error, 1)
+	go func() { served <- serveUntilInterrupted(ctx, mux, addr, 5*time.Second) }()
+
+	client := &http.Client{Timeout: 5 * time.Second}
+	var up error
+	for range 50 {
+		var resp *http.Response
+		if resp, up = client.Get("http://" + addr + "/missing"); up == nil {
+			resp.Body.Close()
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if up != nil {
+		t.Fatalf("server did not start: %v", up)
+	}
+	streamClient := make(chan struct{})
+	go func() {
+		defer close(streamClient)
+		if resp, err := client.Get("http://" + addr + "/stream"); err == nil {
+			resp.Body.Close()
+		}
+	}()
+	<-streamStarted
+	slow := make(chan string, 1)
+	go func() {
+		resp, err := client.Get("http://" + addr + "/slow")
+		if err != nil {
+			slow <- "error: " + err.Error()
+			return
+		}
+		defer resp.Body.Close()
+		b, _ := io.ReadAll(resp.Body)
+		slow <- string(b)
+	}()
+	<-entered
+	started := time.Now()
+	cancel()
+	var serveErr error
+	select {
+	case serveErr = <-served:
+	case <-time.After(5 * time.Second):
+		t.Fatal("server did not shut down")
+	}
+	elapsed := time.Since(started)
+	streamClosed := false
+	select {
+	case <-streamEnded:
+		streamClosed = true
+	case <-time.After(time.Second):
+	}
+	// serveUntilInterrupted 返回时服务端 goroutine 已全部结束；客户端的 goroutine 也在这里收尾，不留到后续检查
+	clientDone := false
+	select {
+	case <-streamClient:
+		clientDone = true
+	case <-time.After(time.Second):
+	}
+
+	// 已取消的 Context：Collector 不再发出请求
+	hits := 0
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { hits++ }))
+	defer site.Close()
+	collector := colly.NewCollector()
+	stopCollectorsOn(ctx, collector)
+	visitErr := collector.Visit(site.URL)
+	if err := firstError(
+		expectEqual("serve", serveErr, nil),
+		expectEqual("in-flight request finished", <-slow, "done"),
+		expectEqual("waited for request", elapsed >= 150*time.Millisecond, true),
+		expectEqual("stream ended", streamClosed, true),
+		expectEqual("stream client returned", clientDone, true),
+		expectEqual("no new pages", [2]interface{}{visitErr, hits}, [2]interface{}{nil, 0}),
+		expectEqual("interrupted err", [2]error{interruptedErr(ctx), interruptedErr(context.Background())}, [2]error{errInterrupted, nil})); err != nil {
+		t.Fatal(err)
+	}
+}
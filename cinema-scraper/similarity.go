@@ -0,0 +1,301 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ===========================
+// 模块：影院相似度与“同好影院在放”推荐
+// 职责：
+// - 离线（recompute-similarity 命令）计算影院两两之间的相似度：
+//   过去 similarityWindowDays 天排过的影片集合的 Jaccard 系数，结果写入 cinema_similarities 表
+// - GET /api/cinemas/:id/recommended-movies：本馆当前没有排、但最相似的几家影院正在排的影片
+// 说明：只使用自有排片数据，不依赖外部服务；相似度计算与推荐打分均为纯函数。
+// ===========================
+
+const (
+	similarityWindowDays = 60 // 相似度统计窗口（天）
+	similarityTopN       = 10 // 每家影院保存的最相似影院数
+
+	recommendSimilarCinemas = 5 // 推荐时参考的最相似影院数
+	recommendDefaultLimit   = 10
+	recommendMaxLimit       = 30
+)
+
+// CinemaSimilarity 影院相似度（离线计算）：CinemaID 与 SimilarID 的排片重合度。
+type CinemaSimilarity struct {
+	ID           uint    `gorm:"primaryKey"`
+	CinemaID     uint    `gorm:"index"`
+	SimilarID    uint    // 相似影院
+	Score        float64 // Jaccard 系数，(0, 1]
+	SharedMovies int     // 两馆共同排过的影片数
+	ComputedAt   time.Time
+}
+
+// jaccard 两个影片集合的 Jaccard 系数（纯函数）；任一为空时返回 0。
+func jaccard(a, b map[uint]struct{}) (float64, int) {
+	if len(a) == 0 || len(b) == 0 {
+		return 0, 0
+	}
+	if len(a) > len(b) {
+		a, b = b, a
+	}
+	shared := 0
+	for id := range a {
+		if _, ok := b[id]; ok {
+			shared++
+		}
+	}
+	union := len(a) + len(b) - shared
+	return float64(shared) / float64(union), shared
+}
+
+// computeCinemaSimilarities 按影院的排片集合计算两两相似度，每家影院保留得分最高的 topN 家（纯函数）。
+// 没有共同影片的组合不输出；同分按影院 ID 升序，结果按 (CinemaID, 得分降序) 排列。
+func computeCinemaSimilarities(programs map[uint]map[uint]struct{}, topN int) []CinemaSimilarity {
+	cinemaIDs := make([]uint, 0, len(programs))
+	for id := range programs {
+		cinemaIDs = append(cinemaIDs, id)
+	}
+	sort.Slice(cinemaIDs, func(i, j int) bool { return cinemaIDs[i] < cinemaIDs[j] })
+
+	byCinema := make(map[uint][]CinemaSimilarity, len(cinemaIDs))
+	for i, a := range cinemaIDs {
+		for _, b := range cinemaIDs[i+1:] {
+			score, shared := jaccard(programs[a], programs[b])
+			if shared == 0 {
+				continue
+			}
+			byCinema[a] = append(byCinema[a], CinemaSimilarity{CinemaID: a, SimilarID: b, Score: score, SharedMovies: shared})
+			byCinema[b] = append(byCinema[b], CinemaSimilarity{CinemaID: b, SimilarID: a, Score: score, SharedMovies: shared})
+		}
+	}
+
+	var out []CinemaSimilarity
+	for _, id := range cinemaIDs {
+		list := byCinema[id]
+		sort.Slice(list, func(i, j int) bool {
+			if list[i].Score != list[j].Score {
+				return list[i].Score > list[j].Score
+			}
+			return list[i].SimilarID < list[j].SimilarID
+		})
+		if len(list) > topN {
+			list = list[:topN]
+		}
+		out = append(out, list...)
+	}
+	return out
+}
+
+// loadCinemaPrograms 统计每家影院在 [from, to] 内排过的影片集合（热表 + 归档表）。
+func loadCinemaPrograms(from, to string) (map[uint]map[uint]struct{}, error) {
+	programs := make(map[uint]map[uint]struct{})
+	for _, model := range []interface{}{&Schedule{}, &ScheduleArchive{}} {
+		var rows []struct {
+			CinemaID uint
+			MovieID  uint
+		}
+		if err := db.Model(model).Distinct("cinema_id", "movie_id").
+			Where("date(play_date) >= ? AND date(play_date) <= ?", from, to).
+			Scan(&rows).Error; err != nil {
+			return nil, err
+		}
+		for _, row := range rows {
+			if programs[row.CinemaID] == nil {
+				programs[row.CinemaID] = make(map[uint]struct{})
+			}
+			programs[row.CinemaID][row.MovieID] = struct{}{}
+		}
+	}
+	return programs, nil
+}
+
+// runRecomputeSimilarityCommand recompute-similarity 命令：默认截至今天，--as-of=YYYY-MM-DD 可回算历史窗口。
+func runRecomputeSimilarityCommand(args []string) (int, error) {
+	today := nowJST()
+	if v, ok := flagValue(args, "--as-of"); ok {
+		t, err := time.ParseInLocation("2006-01-02", v, tokyoLocation)
+		if err != nil {
+			return 0, fmt.Errorf("--as-of 格式应为 YYYY-MM-DD: %q", v)
+		}
+		today = t
+	}
+	written, err := recomputeCinemaSimilarity(today)
+	if err != nil {
+		return written, err
+	}
+	active, err := recomputeProgrammingIntensity(today)
+	if err != nil {
+		return written, err
+	}
+	fmt.Printf("📈 [recompute-similarity] 已重算排片密度：最近 %d 天有排片的影院 %d 家\n", intensityWindowDays, active)
+	return written, nil
+}
+
+// recomputeCinemaSimilarity 重算截至 today 的影院相似度并整表替换，返回写入的行数。
+func recomputeCinemaSimilarity(today time.Time) (int, error) {
+	to := today.Format("2006-01-02")
+	from := today.AddDate(0, 0, -similarityWindowDays).Format("2006-01-02")
+	programs, err := loadCinemaPrograms(from, to)
+	if err != nil {
+		return 0, fmt.Errorf("统计影院排片失败: %v", err)
+	}
+	rows := computeCinemaSimilarities(programs, similarityTopN)
+	computedAt := time.Now()
+	for i := range rows {
+		rows[i].ComputedAt = computedAt
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("1 = 1").Delete(&CinemaSimilarity{}).Error; err != nil {
+			return err
+		}
+		if len(rows) == 0 {
+			return nil
+		}
+		return tx.CreateInBatches(rows, 200).Error
+	})
+	if err != nil {
+		return 0, fmt.Errorf("写入影院相似度失败: %v", err)
+	}
+	return len(rows), nil
+}
+
+// RecommendedMovie 推荐影片：Score 为正在放映该片的相似影院得分之和。
+type RecommendedMovie struct {
+	MovieItem
+	Score     float64 `json:"score"`
+	ShowingAt []uint  `json:"showing_at"` // 正在放映该片的相似影院 ID（按相似度降序）
+}
+
+// rankRecommendedMovies 按相似影院的排片给候选影片打分（纯函数）。
+// similar 为相似影院（按得分降序），showing 为各影院当前在排的影片，exclude 为本馆在排的影片。
+// 返回影片 ID 与对应的得分、来源影院，按得分降序、同分按影片 ID 升序。
+func rankRecommendedMovies(similar []CinemaSimilarity, showing map[uint][]uint, exclude map[uint]struct{}) ([]uint, map[uint]float64, map[uint][]uint) {
+	scores := make(map[uint]float64)
+	via := make(map[uint][]uint)
+	for _, sim := range similar {
+		for _, movieID := range showing[sim.SimilarID] {
+			if _, ok := exclude[movieID]; ok {
+				continue
+			}
+			scores[movieID] += sim.Score
+			via[movieID] = append(via[movieID], sim.SimilarID)
+		}
+	}
+	ids := make([]uint, 0, len(scores))
+	for id := range scores {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		if scores[ids[i]] != scores[ids[j]] {
+			return scores[ids[i]] > scores[ids[j]]
+		}
+		return ids[i] < ids[j]
+	})
+	return ids, scores, via
+}
+
+// recommendedMoviesHandler 处理 GET /api/cinemas/:id/recommended-movies?limit=
+func recommendedMoviesHandler(c *gin.Context) {
+	var cinema Cinema
+	if err := db.First(&cinema, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "cinema not found"})
+		return
+	}
+	limit := recommendDefaultLimit
+	if raw := c.Query("limit"); raw != "" {
+		v, err := strconv.Atoi(raw)
+		if err != nil || v <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit, expected a positive integer"})
+			return
+		}
+		limit = min(v, recommendMaxLimit)
+	}
+	today := referenceTime(c).Format("2006-01-02")
+
+	var similar []CinemaSimilarity
+	if err := db.Where("cinema_id = ?", cinema.ID).Order("score DESC, similar_id").
+		Limit(recommendSimilarCinemas).Find(&similar).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to query cinema similarity"})
+		return
+	}
+
+	// 本馆与相似影院今天及以后在排的影片
+	cinemaIDs := []uint{cinema.ID}
+	for _, sim := range similar {
+		cinemaIDs = append(cinemaIDs, sim.SimilarID)
+	}
+	var pairs []struct {
+		CinemaID uint
+		MovieID  uint
+	}
+	if err := db.Model(&Schedule{}).Distinct("cinema_id", "movie_id").
+		Where("cinema_id IN ? AND date(play_date) >= ?", cinemaIDs, today).
+		Order("cinema_id, movie_id").Scan(&pairs).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to query schedules"})
+		return
+	}
+	showing := make(map[uint][]uint)
+	exclude := make(map[uint]struct{})
+	for _, p := range pairs {
+		if p.CinemaID == cinema.ID {
+			exclude[p.MovieID] = struct{}{}
+			continue
+		}
+		showing[p.CinemaID] = append(showing[p.CinemaID], p.MovieID)
+	}
+
+	ids, scores, via := rankRecommendedMovies(similar, showing, exclude)
+	if len(ids) > limit {
+		ids = ids[:limit]
+	}
+	var movies []Movie
+	if len(ids) > 0 {
+		db.Where("id IN ?", ids).Find(&movies)
+	}
+	byID := make(map[uint]Movie, len(movies))
+	for _, m := range movies {
+		byID[m.ID] = m
+	}
+	items := make([]RecommendedMovie, 0, len(ids))
+	for _, id := range ids {
+		m, ok := byID[id]
+		if !ok {
+			continue
+		}
+		items = append(items, RecommendedMovie{
+			MovieItem: mapMovieToItem(m, requestLang(c)),
+			Score:     scores[id],
+			ShowingAt: via[id],
+		})
+	}
+
+	var computedAt *time.Time
+	if len(similar) > 0 {
+		computedAt = &similar[0].ComputedAt
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"cinema_id":              cinema.ID,
+		"similar_cinemas":        similarCinemaIDs(similar),
+		"similarity_computed_at": computedAt,
+		"items":                  items,
+	})
+}
+
+// similarCinemaIDs 相似影院 ID 列表（保持得分降序）。
+func similarCinemaIDs(similar []CinemaSimilarity) []uint {
+	ids := make([]uint, 0, len(similar))
+	for _, sim := range similar {
+		ids = append(ids, sim.SimilarID)
+	}
+	return ids
+}
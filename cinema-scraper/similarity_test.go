@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// TestCinemaSimilarity 影院相似度：Jaccard 系数与共同影片数，每家影院按得分降序保留前 N 家，推荐按相似影院得分累加。
+func TestCinemaSimilarity(t *testing.T) {
+	setTestClock(t, beforeMidnight)
+
+	set := func(ids ...uint) map[uint]struct{} {
+		s := make(map[uint]struct{}, len(ids))
+		for _, id := range ids {
+			s[id] = struct{}{}
+		}
+		return s
+	}
+	summary := func(sims []CinemaSimilarity) string {
+		parts := make([]string, 0, len(sims))
+		for _, s := range sims {
+			parts = append(parts, fmt.Sprintf("%d>%d:%.1f:%d", s.CinemaID, s.SimilarID, s.Score, s.SharedMovies))
+		}
+		return strings.Join(parts, ",")
+	}
+	partial, shared := jaccard(set(1, 2, 3), set(2, 3, 4, 5))
+	same, _ := jaccard(set(1, 2), set(2, 1))
+	empty, _ := jaccard(set(), set(1))
+	disjoint, _ := jaccard(set(1), set(2))
+	programs := map[uint]map[uint]struct{}{1: set(1, 2, 3), 2: set(2, 3, 4, 5), 3: set(1, 2, 3), 4: set(9)}
+	similar := []CinemaSimilarity{{CinemaID: 1, SimilarID: 3, Score: 1}, {CinemaID: 1, SimilarID: 2, Score: 0.4}}
+	ids, scores, via := rankRecommendedMovies(similar, map[uint][]uint{3: {1, 6, 7}, 2: {7, 8}}, set(1))
+	if err := firstError(
+		expectEqual("partial", fmt.Sprintf("%.1f/%d", partial, shared), "0.4/2"),
+		expectEqual("same, empty, disjoint", [3]float64{same, empty, disjoint}, [3]float64{1, 0, 0}),
+		expectEqual("top 2", summary(computeCinemaSimilarities(programs, 2)), "1>3:1.0:3,1>2:0.4:2,2>1:0.4:2,2>3:0.4:2,3>1:1.0:3,3>2:0.4:2"),
+		expectEqual("top 1", summary(computeCinemaSimilarities(programs, 1)), "1>3:1.0:3,2>1:0.4:2,3>1:1.0:3"),
+		expectEqual("recommended", ids, []uint{7, 6, 8}),
+		expectEqual("scores", [3]float64{scores[7], scores[6], scores[8]}, [3]float64{1.4, 1, 0.4}),
+		expectEqual("via", via[7], []uint{3, 2})); err != nil {
+		t.Fatal(err)
+	}
+}
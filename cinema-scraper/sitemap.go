@@ -0,0 +1,196 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ===========================
+// 模块：站点地图（/sitemap.xml）
+// 职责：
+// - 为前端公开页面输出 sitemap：正在上映 / 即将上映的影片 + 全部影院
+// - unplanned / future 等不对外展示的影片不收录
+// - 结果在内存中缓存 sitemapCacheTTL，过期后在下一次请求时重新生成
+// - 超过单文件上限时输出 sitemap index，分页文件位于 /sitemaps/:page（如 /sitemaps/1.xml）
+// 说明：前端站点根地址来自环境变量 FRONTEND_BASE_URL。
+// ===========================
+
+const (
+	sitemapCacheTTL      = 10 * time.Minute
+	sitemapMaxURLs       = 50000 // sitemaps.org 单文件上限
+	defaultFrontendBase  = "http://localhost:5173"
+	sitemapXMLNamespace  = "http://www.sitemaps.org/schemas/sitemap/0.9"
+	sitemapContentHeader = "application/xml; charset=utf-8"
+)
+
+// frontendBaseURL 前端站点根地址（不带末尾斜杠）。
+func frontendBaseURL() string {
+	base := strings.TrimSpace(os.Getenv("FRONTEND_BASE_URL"))
+	if base == "" {
+		base = defaultFrontendBase
+	}
+	return strings.TrimRight(base, "/")
+}
+
+// slugRe 非字母数字字符。
+var slugRe = regexp.MustCompile(`[^a-z0-9]+`)
+
+// movieSlug 由英文标题生成 URL slug；没有英文标题时为空。
+func movieSlug(m Movie) string {
+	return strings.Trim(slugRe.ReplaceAllString(strings.ToLower(m.TitleEN), "-"), "-")
+}
+
+// movieCanonicalPath 影片页的规范路径：/movies/{id}-{slug}（无 slug 时为 /movies/{id}）。
+func movieCanonicalPath(m Movie) string {
+	if slug := movieSlug(m); slug != "" {
+		return fmt.Sprintf("/movies/%d-%s", m.ID, slug)
+	}
+	return fmt.Sprintf("/movies/%d", m.ID)
+}
+
+// cinemaCanonicalPath 影院页的规范路径。
+func cinemaCanonicalPath(cin Cinema) string {
+	return fmt.Sprintf("/cinemas/%d", cin.ID)
+}
+
+type sitemapURL struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+type sitemapURLSet struct {
+	XMLName xml.Name     `xml:"urlset"`
+	Xmlns   string       `xml:"xmlns,attr"`
+	URLs    []sitemapURL `xml:"url"`
+}
+
+type sitemapIndex struct {
+	XMLName  xml.Name     `xml:"sitemapindex"`
+	Xmlns    string       `xml:"xmlns,attr"`
+	Sitemaps []sitemapURL `xml:"sitemap"`
+}
+
+// sitemapCache 已生成的站点地图条目（按请求懒加载）。
+var sitemapCache struct {
+	sync.Mutex
+	urls        []sitemapURL
+	generatedAt time.Time
+}
+
+// buildSitemapURLs 查询数据库生成全部条目。
+func buildSitemapURLs() ([]sitemapURL, error) {
+	base := frontendBaseURL()
+
+	var movies []Movie
+	if err := db.Where("status IN ?", []string{"showing", "incoming"}).Order("id").Find(&movies).Error; err != nil {
+		return nil, err
+	}
+	var cinemas []Cinema
+	if err := db.Order("id").Find(&cinemas).Error; err != nil {
+		return nil, err
+	}
+
+	urls := make([]sitemapURL, 0, len(movies)+len(cinemas))
+	for _, m := range movies {
+		urls = append(urls, sitemapURL{Loc: base + movieCanonicalPath(m), LastMod: sitemapLastMod(m.UpdatedAt)})
+	}
+	for _, cin := range cinemas {
+		urls = append(urls, sitemapURL{Loc: base + cinemaCanonicalPath(cin), LastMod: sitemapLastMod(cin.UpdatedAt)})
+	}
+	return urls, nil
+}
+
+// sitemapLastMod 格式化 lastmod（W3C 日期）；零值不输出。
+func sitemapLastMod(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format("2006-01-02")
+}
+
+// cachedSitemapURLs 返回缓存的条目，过期时重新生成。
+func cachedSitemapURLs() ([]sitemapURL, error) {
+	sitemapCache.Lock()
+	defer sitemapCache.Unlock()
+	if sitemapCache.urls != nil && time.Since(sitemapCache.generatedAt) < sitemapCacheTTL {
+		return sitemapCache.urls, nil
+	}
+	urls, err := buildSitemapURLs()
+	if err != nil {
+		return nil, err
+	}
+	sitemapCache.urls = urls
+	sitemapCache.generatedAt = time.Now()
+	return urls, nil
+}
+
+// writeSitemapXML 输出 XML。
+func writeSitemapXML(c *gin.Context, doc interface{}) {
+	body, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to encode sitemap"})
+		return
+	}
+	c.Data(http.StatusOK, sitemapContentHeader, append([]byte(xml.Header), body...))
+}
+
+// sitemapHandler 站点地图入口：GET /sitemap.xml
+// 条目不超过单文件上限时直接输出 urlset，否则输出 sitemap index。
+func sitemapHandler(c *gin.Context) {
+	urls, err := cachedSitemapURLs()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to build sitemap"})
+		return
+	}
+	if len(urls) <= sitemapMaxURLs {
+		writeSitemapXML(c, sitemapURLSet{Xmlns: sitemapXMLNamespace, URLs: urls})
+		return
+	}
+
+	// 分页文件挂在 API 服务自身的地址下
+	scheme := "http"
+	if c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	index := sitemapIndex{Xmlns: sitemapXMLNamespace}
+	pages := (len(urls) + sitemapMaxURLs - 1) / sitemapMaxURLs
+	for i := 1; i <= pages; i++ {
+		index.Sitemaps = append(index.Sitemaps, sitemapURL{
+			Loc: fmt.Sprintf("%s://%s/sitemaps/%d.xml", scheme, c.Request.Host, i),
+		})
+	}
+	writeSitemapXML(c, index)
+}
+
+// sitemapPageHandler 站点地图分页：GET /sitemaps/:page（如 1.xml）
+func sitemapPageHandler(c *gin.Context) {
+	page, err := strconv.Atoi(strings.TrimSuffix(c.Param("page"), ".xml"))
+	if err != nil || page < 1 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "sitemap not found"})
+		return
+	}
+	urls, err := cachedSitemapURLs()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to build sitemap"})
+		return
+	}
+	start := (page - 1) * sitemapMaxURLs
+	if start >= len(urls) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "sitemap not found"})
+		return
+	}
+	end := start + sitemapMaxURLs
+	if end > len(urls) {
+		end = len(urls)
+	}
+	writeSitemapXML(c, sitemapURLSet{Xmlns: sitemapXMLNamespace, URLs: urls[start:end]})
+}
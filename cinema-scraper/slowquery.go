@@ -0,0 +1,234 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// ===========================
+// 模块：慢查询日志（SQLite）
+// 职责：
+// - GORM 控制台日志的慢查询阈值可由 SLOW_QUERY_MS 配置（默认 200ms），且只打印参数占位符
+// - 超过阈值的查询经 GORM 回调记入内存环形缓冲：SQL、耗时、影响行数与调用方（发起查询的 handler / 命令）
+// - 累计次数在 /api/stats 的 slow_queries 中给出；GET /api/admin/slow-queries 查看最近的慢查询
+// 说明：参数值在写日志前统一脱敏（字符串截断、二进制只留长度），SQL 过长时截断，日志可以直接分享。
+// ===========================
+
+const (
+	defaultSlowQueryThreshold = 200 * time.Millisecond
+	slowQueryLogSize          = 100  // 保留最近的慢查询条数
+	slowQueryMaxSQL           = 1000 // SQL 文本最大长度（字节）
+	slowQueryMaxParamRunes    = 10   // 字符串参数保留的字符数（恰好容纳 YYYY-MM-DD）
+)
+
+// SlowQuery 一条慢查询记录。
+type SlowQuery struct {
+	At         time.Time `json:"at"`
+	DurationMs float64   `json:"duration_ms"`
+	Rows       int64     `json:"rows"`
+	SQL        string    `json:"sql"` // 参数已脱敏
+	Caller     string    `json:"caller"`
+	Location   string    `json:"location"` // 调用方文件与行号
+	Error      string    `json:"error,omitempty"`
+}
+
+// slowQueryRing 最近 slowQueryLogSize 条慢查询（环形缓冲，并发安全）。
+type slowQueryRing struct {
+	mu      sync.Mutex
+	entries []SlowQuery
+	next    int
+}
+
+var (
+	slowQueries     = &slowQueryRing{}
+	slowQueriesSeen atomic.Int64 // 进程启动以来的慢查询总数
+)
+
+// add 写入一条记录，满了覆盖最旧的。
+func (r *slowQueryRing) add(q SlowQuery) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.entries) < slowQueryLogSize {
+		r.entries = append(r.entries, q)
+		return
+	}
+	r.entries[r.next] = q
+	r.next = (r.next + 1) % slowQueryLogSize
+}
+
+// recent 最近的 limit 条，新的在前。
+func (r *slowQueryRing) recent(limit int) []SlowQuery {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := make([]SlowQuery, 0, min(limit, len(r.entries)))
+	for i := 0; i < len(r.entries) && len(out) < limit; i++ {
+		idx := (r.next - 1 - i + 2*len(r.entries)) % len(r.entries)
+		out = append(out, r.entries[idx])
+	}
+	return out
+}
+
+// slowQueryThreshold 从 SLOW_QUERY_MS 读取阈值；未设置或非法时使用默认值。
+func slowQueryThreshold() time.Duration {
+	raw := strings.TrimSpace(os.Getenv("SLOW_QUERY_MS"))
+	if raw == "" {
+		return defaultSlowQueryThreshold
+	}
+	ms, err := strconv.Atoi(raw)
+	if err != nil || ms <= 0 {
+		fmt.Printf("⚠️ SLOW_QUERY_MS=%q 无效，使用默认 %v\n", raw, defaultSlowQueryThreshold)
+		return defaultSlowQueryThreshold
+	}
+	return time.Duration(ms) * time.Millisecond
+}
+
+// redactParam 脱敏单个参数（纯函数）：字符串截断，二进制只保留长度，数值 / 时间 / 布尔原样保留。
+func redactParam(v interface{}) interface{} {
+	switch val := v.(type) {
+	case string:
+		if utf8.RuneCountInString(val) > slowQueryMaxParamRunes {
+			return string([]rune(val)[:slowQueryMaxParamRunes]) + "…"
+		}
+		return val
+	case []byte:
+		return fmt.Sprintf("<%d bytes>", len(val))
+	}
+	return v
+}
+
+// truncateSQL SQL 过长时截断（纯函数），如大量 ID 的 IN 列表。
+func truncateSQL(sql string) string {
+	if len(sql) <= slowQueryMaxSQL {
+		return sql
+	}
+	cut := slowQueryMaxSQL
+	for cut > 0 && !utf8.RuneStart(sql[cut]) {
+		cut--
+	}
+	return sql[:cut] + "…"
+}
+
+// newSlowQueryLogger GORM 控制台日志：与默认日志一致，但慢查询阈值可配置，且不展开参数值。
+func newSlowQueryLogger(threshold time.Duration) logger.Interface {
+	return logger.New(log.New(os.Stdout, "\r\n", log.LstdFlags), logger.Config{
+		SlowThreshold:        threshold,
+		LogLevel:             logger.Warn,
+		Colorful:             true,
+		ParameterizedQueries: true,
+	})
+}
+
+const slowQueryStartKey = "slow_query:start"
+
+// slowQueryLimit 当前生效的慢查询阈值（启动时由 registerSlowQueryCallbacks 设置）。
+var slowQueryLimit = defaultSlowQueryThreshold
+
+// registerSlowQueryCallbacks 在每类操作（增删改查 / Row / Raw）前后挂钩计时，超过 threshold 的记入缓冲。
+func registerSlowQueryCallbacks(gdb *gorm.DB, threshold time.Duration) error {
+	slowQueryLimit = threshold
+	before := func(tx *gorm.DB) {
+		tx.InstanceSet(slowQueryStartKey, time.Now())
+	}
+	after := func(tx *gorm.DB) {
+		v, ok := tx.InstanceGet(slowQueryStartKey)
+		if !ok {
+			return
+		}
+		begin := v.(time.Time)
+		if elapsed := time.Since(begin); elapsed >= threshold {
+			recordSlowQuery(tx, begin, elapsed)
+		}
+	}
+
+	cb := gdb.Callback()
+	hooks := []struct {
+		name          string
+		before, after func(string, func(*gorm.DB)) error
+	}{
+		{"create", cb.Create().Before("*").Register, cb.Create().After("*").Register},
+		{"query", cb.Query().Before("*").Register, cb.Query().After("*").Register},
+		{"update", cb.Update().Before("*").Register, cb.Update().After("*").Register},
+		{"delete", cb.Delete().Before("*").Register, cb.Delete().After("*").Register},
+		{"row", cb.Row().Before("*").Register, cb.Row().After("*").Register},
+		{"raw", cb.Raw().Before("*").Register, cb.Raw().After("*").Register},
+	}
+	for _, h := range hooks {
+		if err := h.before("slow_query:before_"+h.name, before); err != nil {
+			return err
+		}
+		if err := h.after("slow_query:after_"+h.name, after); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// recordSlowQuery 生成脱敏后的 SQL 与调用方并写入缓冲。
+func recordSlowQuery(tx *gorm.DB, begin time.Time, elapsed time.Duration) {
+	vars := make([]interface{}, len(tx.Statement.Vars))
+	for i, v := range tx.Statement.Vars {
+		vars[i] = redactParam(v)
+	}
+	caller, location := slowQueryCaller()
+	q := SlowQuery{
+		At:         begin,
+		DurationMs: float64(elapsed.Microseconds()) / 1000,
+		Rows:       tx.Statement.RowsAffected,
+		SQL:        truncateSQL(tx.Dialector.Explain(tx.Statement.SQL.String(), vars...)),
+		Caller:     caller,
+		Location:   location,
+	}
+	if tx.Error != nil {
+		q.Error = tx.Error.Error()
+	}
+	slowQueries.add(q)
+	slowQueriesSeen.Add(1)
+}
+
+// slowQueryCaller 调用栈中第一个既不属于 GORM / 驱动、也不属于本文件的帧：即发起查询的 handler 或命令。
+func slowQueryCaller() (string, string) {
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(3, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		frame, more := frames.Next()
+		if !strings.Contains(frame.File, "/gorm.io/") && !strings.HasSuffix(frame.File, "/slowquery.go") {
+			return strings.TrimPrefix(frame.Function, "main."), fmt.Sprintf("%s:%d", filepath.Base(frame.File), frame.Line)
+		}
+		if !more {
+			return "", ""
+		}
+	}
+}
+
+// listSlowQueriesHandler 最近的慢查询：GET /api/admin/slow-queries?limit=
+func listSlowQueriesHandler(c *gin.Context) {
+	limit := slowQueryLogSize
+	if raw := c.Query("limit"); raw != "" {
+		v, err := strconv.Atoi(raw)
+		if err != nil || v <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit, expected a positive integer"})
+			return
+		}
+		limit = min(v, slowQueryLogSize)
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"threshold_ms": slowQueryLimit.Milliseconds(),
+		"total":        slowQueriesSeen.Load(),
+		"items":        slowQueries.recent(limit),
+	})
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ===========================
+// 模块：数据概况（/api/stats）
+// 职责：汇总影片 / 影院 / 排片规模与外部数据源状态，便于判断数据是否健康
+// ===========================
+
+// statsHandler 数据概况接口：GET /api/stats
+func statsHandler(c *gin.Context) {
+	now := referenceTime(c)
+	today := now.Format("2006-01-02")
+
+	var movieCount, cinemaCount, scheduleCount, upcomingCount, imdbPending int64
+	db.Model(&Movie{}).Count(&movieCount)
+	db.Model(&Cinema{}).Count(&cinemaCount)
+	db.Model(&Schedule{}).Count(&scheduleCount)
+	db.Model(&Schedule{}).Where("date(play_date) >= ?", today).Count(&upcomingCount)
+	db.Model(&Movie{}).Where("imdb_pending = ?", true).Count(&imdbPending)
+
+	// 本周（今天起 7 天内）的特别场次数量
+	var eventsThisWeek int64
+	weekEnd := now.AddDate(0, 0, 6).Format("2006-01-02")
+	db.Model(&Schedule{}).
+		Where("date(play_date) >= ? AND date(play_date) <= ? AND event_type <> ''", today, weekEnd).
+		Count(&eventsThisWeek)
+
+	omdbCalls, omdbIsBlocked := omdbSnapshot()
+	var tmdbPending int64
+	db.Model(&Movie{}).Where("tmdb_pending = ?", true).Count(&tmdbPending)
+	tmdbState, tmdbFailures, tmdbTransitions := tmdbBreakerSnapshot()
+
+	c.JSON(http.StatusOK, gin.H{
+		"movies":                movieCount,
+		"cinemas":               cinemaCount,
+		"schedules":             scheduleCount,
+		"upcoming_schedules":    upcomingCount,
+		"events_this_week":      eventsThisWeek,
+		"panics_recovered":      panicsRecovered.Load(),
+		"slow_queries":          slowQueriesSeen.Load(),
+		"query_budget_exceeded": queryBudgetExceeded.Load(),
+		"omdb": gin.H{
+			"calls":        omdbCalls,
+			"blocked":      omdbIsBlocked,
+			"imdb_pending": imdbPending,
+		},
+		"tmdb": gin.H{
+			"breaker":              tmdbState,
+			"consecutive_failures": tmdbFailures,
+			"transitions":          tmdbTransitions,
+			"deferred":             tmdbDeferred.Load(),
+			"tmdb_pending":         tmdbPending,
+		},
+	})
+}
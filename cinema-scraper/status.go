@@ -0,0 +1,210 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ===========================
+// 模块：影片状态人工覆盖与锁定
+// 职责：
+// - 管理员可手动设置影片状态，并可选地锁定到 pinned_until（含当天）
+// - 锁定期间 crawl-schedules 与 update-status 都不会覆盖该状态；过期后自动恢复正常计算
+// - 每次状态变化写入 MovieStatusEvent，便于回溯“谁在什么时候改了状态”
+// ===========================
+
+// 状态变化来源。
+const (
+	StatusSourceCrawl  = "crawl"
+	StatusSourceUpdate = "update-status"
+	StatusSourceManual = "manual"
+)
+
+// validMovieStatuses 允许手动设置的状态值。
+var validMovieStatuses = map[string]bool{
+	"showing":   true,
+	"incoming":  true,
+	"future":    true,
+	"unplanned": true,
+}
+
+// MovieStatusEvent 影片状态变更历史。
+type MovieStatusEvent struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	MovieID     uint       `gorm:"index" json:"movie_id"`
+	FromStatus  string     `json:"from"`         // 变更前状态
+	ToStatus    string     `json:"to"`           // 变更后状态
+	Source      string     `json:"source"`       // crawl / update-status / manual
+	PinnedUntil *time.Time `json:"pinned_until"` // 手动锁定的截止日期（仅 manual）
+	Note        string     `json:"note"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// isStatusPinned 判断影片状态在 now 时是否仍处于锁定期（pinned_until 当天仍然有效）。
+func isStatusPinned(m Movie, now time.Time) bool {
+	if m.StatusPinnedUntil == nil {
+		return false
+	}
+	return now.Format("2006-01-02") <= m.StatusPinnedUntil.Format("2006-01-02")
+}
+
+// recordStatusChange 记录一次状态变化（失败只打印日志，不影响主流程）。
+func recordStatusChange(movieID uint, from, to, source string, pinnedUntil *time.Time, note string) {
+	ev := MovieStatusEvent{
+		MovieID:     movieID,
+		FromStatus:  from,
+		ToStatus:    to,
+		Source:      source,
+		PinnedUntil: pinnedUntil,
+		Note:        note,
+	}
+	if err := db.Create(&ev).Error; err != nil {
+		fmt.Printf("⚠️ 记录状态变更失败 [movie=%d]: %v\n", movieID, err)
+	}
+}
+
+// releaseExpiredStatusPins 清除已过期的锁定，并写入历史，返回清除数量。
+func releaseExpiredStatusPins(now time.Time) int {
+	var movies []Movie
+	if err := db.Where("status_pinned_until IS NOT NULL").Find(&movies).Error; err != nil {
+		return 0
+	}
+	released := 0
+	for _, m := range movies {
+		if isStatusPinned(m, now) {
+			continue
+		}
+		if err := db.Model(&m).Update("status_pinned_until", nil).Error; err != nil {
+			continue
+		}
+		recordStatusChange(m.ID, m.Status, m.Status, StatusSourceUpdate, nil, "pin expired")
+		fmt.Printf("   🔓 [%s] 状态锁定已过期，恢复自动计算\n", m.TitleJP)
+		released++
+	}
+	return released
+}
+
+// statusOverrideRequest 手动设置状态的请求体。
+type statusOverrideRequest struct {
+	IDs         []uint `json:"ids"`          // 仅批量接口使用
+	Status      string `json:"status"`       // showing / incoming / future / unplanned
+	PinnedUntil string `json:"pinned_until"` // 可选，YYYY-MM-DD（含当天）
+	Note        string `json:"note"`
+}
+
+// applyStatusOverride 设置状态与锁定，并记录历史。
+func applyStatusOverride(movie *Movie, req statusOverrideRequest, pinnedUntil *time.Time) error {
+	from := movie.Status
+	movie.Status = req.Status
+	movie.StatusPinnedUntil = pinnedUntil
+	recordProvenance(&movie.ProvenanceJSON, SourceManual, "status")
+	if err := db.Model(movie).Updates(map[string]interface{}{
+		"status":              movie.Status,
+		"status_pinned_until": pinnedUntil,
+		"provenance_json":     movie.ProvenanceJSON,
+	}).Error; err != nil {
+		return err
+	}
+	recordStatusChange(movie.ID, from, movie.Status, StatusSourceManual, pinnedUntil, req.Note)
+	return nil
+}
+
+// parseStatusOverride 校验请求体，返回解析后的锁定日期。
+func parseStatusOverride(c *gin.Context) (statusOverrideRequest, *time.Time, bool) {
+	var req statusOverrideRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return req, nil, false
+	}
+	if !validMovieStatuses[req.Status] {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid status"})
+		return req, nil, false
+	}
+	if req.PinnedUntil == "" {
+		return req, nil, true
+	}
+	t, err := time.ParseInLocation("2006-01-02", req.PinnedUntil, tokyoLocation)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid pinned_until, expected YYYY-MM-DD"})
+		return req, nil, false
+	}
+	return req, &t, true
+}
+
+// patchMovieStatusHandler 手动设置单部影片状态：
+// - PATCH /api/admin/movies/:id/status  {"status":"incoming","pinned_until":"2026-02-10"}
+func patchMovieStatusHandler(c *gin.Context) {
+	req, pinnedUntil, ok := parseStatusOverride(c)
+	if !ok {
+		return
+	}
+	var movie Movie
+	if err := db.First(&movie, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "movie not found"})
+		return
+	}
+	if err := applyStatusOverride(&movie, req, pinnedUntil); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update status"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"id":           movie.ID,
+		"status":       movie.Status,
+		"pinned_until": req.PinnedUntil,
+	})
+}
+
+// patchMoviesStatusBulkHandler 批量设置影片状态：
+// - PATCH /api/admin/movies/status  {"ids":[1,2],"status":"showing","pinned_until":"2026-02-10"}
+func patchMoviesStatusBulkHandler(c *gin.Context) {
+	req, pinnedUntil, ok := parseStatusOverride(c)
+	if !ok {
+		return
+	}
+	if len(req.IDs) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ids is required"})
+		return
+	}
+	var movies []Movie
+	if err := db.Where("id IN ?", req.IDs).Order("id").Find(&movies).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to query movies"})
+		return
+	}
+	updated := make([]uint, 0, len(movies))
+	for i := range movies {
+		if err := applyStatusOverride(&movies[i], req, pinnedUntil); err != nil {
+			continue
+		}
+		updated = append(updated, movies[i].ID)
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"updated":      updated,
+		"status":       req.Status,
+		"pinned_until": req.PinnedUntil,
+	})
+}
+
+// getMovieStatusHistoryHandler 影片状态变更历史：GET /api/admin/movies/:id/status-history
+func getMovieStatusHistoryHandler(c *gin.Context) {
+	var movie Movie
+	if err := db.First(&movie, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "movie not found"})
+		return
+	}
+	var events []MovieStatusEvent
+	db.Where("movie_id = ?", movie.ID).Order("id DESC").Find(&events)
+
+	pinnedUntil := ""
+	if isStatusPinned(movie, nowJST()) {
+		pinnedUntil = movie.StatusPinnedUntil.Format("2006-01-02")
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"id":           movie.ID,
+		"status":       movie.Status,
+		"pinned_until": pinnedUntil,
+		"history":      events,
+	})
+}
@@ -0,0 +1,266 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/civil"
+)
+
+// ===========================
+// 模块：影片状态计算规则（showing / incoming / future / unplanned）
+// 职责：
+// - 状态只由 StatusForSchedules 一个函数计算；update-status、crawl-schedules 结束后的批量重算（refreshMovieStatuses → computeMovieStatus）
+//   与时间旅行（as_of）都经由它，抓取过程中不再逐影院改状态，影片状态总是反映全部影院的排片
+// - 阈值集中在 StatusConfig：Soon 窗口、下映宽限期、旧片重映年数，改阈值只需改这一处
+// 说明：阈值可由环境变量 STATUS_SOON_DAYS / STATUS_LEAVING_DAYS / STATUS_REVIVAL_YEARS
+//       或命令行参数 --soon-days= / --leaving-days= / --revival-years= 覆盖，参数优先于环境变量。
+//
+// 状态机（只由排片日期范围 [first, last] 与今天（JST）决定，与当前状态无关；人工锁定见 status.go）：
+//
+//   first > today + SoonDays                  -> future     （排片过远，大概率是数据问题，前端不展示）
+//   today < first <= today + SoonDays         -> incoming   （Soon）
+//   first <= today 且 last >= today - Leaving -> showing
+//   无排片，或 last < today - LeavingDays     -> unplanned  （前端不展示）
+//
+// 随日期推移的正常路径为 future -> incoming -> showing -> unplanned；新增排片可以让 unplanned 回到任何状态。
+// 已知日本院线上映日期（Movie.JPReleaseDate）且晚于今天时，已开始的排片视为先行上映，showing 仍按 incoming 处理
+// （statusWithJPRelease）；没有日本上映日期时只看排片。
+// ===========================
+
+// StatusConfig 状态计算与旧片判定的阈值。
+type StatusConfig struct {
+	SoonDays     int // 最早排片在明天起 N 天内 -> incoming（Soon），更远 -> future
+	LeavingDays  int // 最晚排片早于今天超过 N 天 -> unplanned；0 表示最晚排片一过即下映
+	RevivalYears int // 上映年份早于 N 年前视为“旧片重映”（今晚推荐加权、周报经典重映）
+}
+
+// defaultStatusConfig 默认阈值（与引入配置前的硬编码一致）。
+var defaultStatusConfig = StatusConfig{SoonDays: 7, LeavingDays: 0, RevivalYears: 10}
+
+// statusConfig 当前生效的阈值，启动时由 loadStatusConfig 设置。
+var statusConfig = defaultStatusConfig
+
+// statusConfigOption 一项阈值的配置来源与取值下限。
+type statusConfigOption struct {
+	Env   string
+	Flag  string
+	Min   int
+	Field *int
+}
+
+// loadStatusConfig 依次读取环境变量与命令行参数；非法值返回错误（不静默回退，避免按错误的口径批量改状态）。
+func loadStatusConfig(args []string) (StatusConfig, error) {
+	t := defaultStatusConfig
+	options := []statusConfigOption{
+		{"STATUS_SOON_DAYS", "--soon-days", 1, &t.SoonDays},
+		{"STATUS_LEAVING_DAYS", "--leaving-days", 0, &t.LeavingDays},
+		{"STATUS_REVIVAL_YEARS", "--revival-years", 1, &t.RevivalYears},
+	}
+	for _, opt := range options {
+		source, raw := opt.Env, strings.TrimSpace(os.Getenv(opt.Env))
+		if v, ok := flagValue(args, opt.Flag); ok {
+			source, raw = opt.Flag, strings.TrimSpace(v)
+		}
+		if raw == "" {
+			continue
+		}
+		v, err := strconv.Atoi(raw)
+		if err != nil || v < opt.Min {
+			return defaultStatusConfig, fmt.Errorf("%s=%q 无效，应为不小于 %d 的整数", source, raw, opt.Min)
+		}
+		*opt.Field = v
+	}
+	return t, nil
+}
+
+// String 打印用：soon_days=7 leaving_days=0 revival_years=10。
+func (t StatusConfig) String() string {
+	return fmt.Sprintf("soon_days=%d leaving_days=%d revival_years=%d", t.SoonDays, t.LeavingDays, t.RevivalYears)
+}
+
+// parseStatusDate 解析状态计算使用的日期（YYYY-MM-DD）；空串表示没有排片，返回零值。
+func parseStatusDate(s string) (civil.Date, error) {
+	if s == "" {
+		return civil.Date{}, nil
+	}
+	return civil.ParseDate(s)
+}
+
+// scheduleDateSpan 排片日期中最早 / 最晚的一天（顺序与重复不限，零值忽略）；没有有效日期时都为零值（纯函数）。
+func scheduleDateSpan(dates []civil.Date) (first, last civil.Date) {
+	for _, d := range dates {
+		if !d.IsValid() {
+			continue
+		}
+		if !first.IsValid() || d.Before(first) {
+			first = d
+		}
+		if !last.IsValid() || d.After(last) {
+			last = d
+		}
+	}
+	return first, last
+}
+
+// StatusForSchedules 按排片日期（顺序与重复不限，零值忽略）推算 now 当天的状态（纯函数）：
+// - 没有排片，或最晚排片早于 now 超过 LeavingDays 天 -> unplanned
+// - 最早排片不晚于 now -> showing
+// - 最早排片在 SoonDays 天内 -> incoming，否则 future
+// 日期字符串的解析由调用方负责（见 statusFromScheduleRange），解析失败时保留原状态，不猜测。
+func StatusForSchedules(dates []civil.Date, now civil.Date, cfg StatusConfig) string {
+	first, last := scheduleDateSpan(dates)
+	switch {
+	case !first.IsValid() || last.Before(now.AddDays(-cfg.LeavingDays)):
+		return "unplanned"
+	case !first.After(now):
+		return "showing"
+	case !first.After(now.AddDays(cfg.SoonDays)):
+		return "incoming"
+	default:
+		return "future"
+	}
+}
+
+// statusFromScheduleRange 按 SQL 聚合出的最早 / 最晚排片日期（YYYY-MM-DD，空串表示没有排片）推算状态，使用当前生效的阈值；
+// 任一日期无法解析时返回错误。
+func statusFromScheduleRange(first, last, today string) (string, error) {
+	dates := make([]civil.Date, 0, 2)
+	for _, raw := range []string{first, last} {
+		d, err := parseStatusDate(raw)
+		if err != nil {
+			return "", fmt.Errorf("invalid schedule date %q: %w", raw, err)
+		}
+		dates = append(dates, d)
+	}
+	now, err := civil.ParseDate(today)
+	if err != nil {
+		return "", fmt.Errorf("invalid today %q: %w", today, err)
+	}
+	return StatusForSchedules(dates, now, statusConfig), nil
+}
+
+// statusWithJPRelease 按日本院线上映日期（YYYY-MM-DD，空串表示未知）修正排片推算出的状态（纯函数）：
+// 排片已开始（showing）但日本公映日还在 today 之后，多半是先行上映 / 试映，仍算 incoming。
+func statusWithJPRelease(status, jpRelease, today string) string {
+	if status == "showing" && jpRelease > today {
+		return "incoming"
+	}
+	return status
+}
+
+// jpReleaseDateString 影片的日本院线上映日期（YYYY-MM-DD），未知时为空串。
+func jpReleaseDateString(m Movie) string {
+	if m.JPReleaseDate == nil {
+		return ""
+	}
+	return m.JPReleaseDate.Format("2006-01-02")
+}
+
+// computeMovieStatus 按一部影片的场次推算 now（换算为东京时间）当天的状态，使用当前生效的阈值。
+// 场次的 play_date 以 UTC 零点保存，直接取其日期部分；深夜场（25:10）仍算在前一天。
+// 会員限定场次不计入（见 membersonly.go），只有会員限定场次的影片为 unplanned。
+func computeMovieStatus(schedules []Schedule, now time.Time) string {
+	return StatusForSchedules(publicScheduleDates(schedules), civil.DateOf(now.In(tokyoLocation)), statusConfig)
+}
+
+// movieSchedules 影片在全部影院的场次（只取状态计算需要的列）。
+func movieSchedules(movieID uint) ([]Schedule, error) {
+	var schedules []Schedule
+	err := db.Select("play_date", "members_only").Where("movie_id = ?", movieID).Find(&schedules).Error
+	return schedules, err
+}
+
+// refreshMovieStatuses 按全部影院的公开排片（computeMovieStatus）重新计算影片状态并写回，返回变更数量。
+// update-status 与 crawl-schedules 结束后的批量重算共用；人工锁定期内的影片跳过（见 status.go），
+// source 记入状态历史，来自抓取时同时把 status 的来源记为 eiga。
+func refreshMovieStatuses(movies []Movie, now time.Time, source string) int {
+	today := now.In(tokyoLocation).Format("2006-01-02")
+	updated := 0
+	for _, movie := range movies {
+		if isStatusPinned(movie, now) {
+			fmt.Printf("   📌 [%s]: 状态已锁定为 %s（至 %s），跳过\n", movie.TitleJP, movie.Status, movie.StatusPinnedUntil.Format("2006-01-02"))
+			continue
+		}
+		schedules, err := movieSchedules(movie.ID)
+		if err != nil {
+			fmt.Printf("⚠️ 查询排片失败，保留 %s [%s]: %v\n", movie.Status, movie.TitleJP, err)
+			continue
+		}
+		// 日本院线上映日期晚于今天时，已开始的排片按先行上映处理，仍为 incoming
+		newStatus := statusWithJPRelease(computeMovieStatus(schedules, now), jpReleaseDateString(movie), today)
+		oldStatus := movie.Status
+		if oldStatus == newStatus {
+			continue
+		}
+		updates := map[string]interface{}{"status": newStatus}
+		if source == StatusSourceCrawl {
+			recordProvenance(&movie.ProvenanceJSON, SourceEiga, "status")
+			updates["provenance_json"] = movie.ProvenanceJSON
+		}
+		if err := db.Model(&movie).Updates(updates).Error; err != nil {
+			fmt.Printf("⚠️ 更新电影状态失败 [%s]: %v\n", movie.TitleJP, err)
+			continue
+		}
+		first, last := scheduleDateSpan(publicScheduleDates(schedules))
+		note := ""
+		switch {
+		case !first.IsValid():
+			fmt.Printf("   🔄 [%s]: %s -> %s (无任何排片)\n", movie.TitleJP, oldStatus, newStatus)
+			note = "no schedules"
+		case newStatus == "unplanned":
+			fmt.Printf("   🔄 [%s]: %s -> %s (最晚排片: %s，已全部过期)\n", movie.TitleJP, oldStatus, newStatus, last)
+			note = "all schedules past"
+		default:
+			fmt.Printf("   🔄 [%s]: %s -> %s (最早排片: %s)\n", movie.TitleJP, oldStatus, newStatus, first)
+		}
+		recordStatusChange(movie.ID, oldStatus, newStatus, source, nil, note)
+		updated++
+	}
+	return updated
+}
+
+// crawlTouchedMovies 本次 crawl-schedules 解析到的影片：抓取过程中只记录，全部影院抓完后统一重算状态，
+// 避免影片状态取决于最后解析的那家影院。
+var crawlTouchedMovies = struct {
+	sync.Mutex
+	ids map[uint]bool
+}{ids: make(map[uint]bool)}
+
+// markCrawlTouchedMovie 记录本次抓取解析到的影片（并发回调安全）。
+func markCrawlTouchedMovie(id uint) {
+	crawlTouchedMovies.Lock()
+	defer crawlTouchedMovies.Unlock()
+	crawlTouchedMovies.ids[id] = true
+}
+
+// refreshCrawledMovieStatuses crawl-schedules 结束后重算本次解析到的影片的状态，返回变更数量；
+// 抓取后被合并或删除的影片不再处理。
+func refreshCrawledMovieStatuses() int {
+	crawlTouchedMovies.Lock()
+	ids := make([]uint, 0, len(crawlTouchedMovies.ids))
+	for id := range crawlTouchedMovies.ids {
+		ids = append(ids, id)
+	}
+	crawlTouchedMovies.ids = make(map[uint]bool)
+	crawlTouchedMovies.Unlock()
+	if len(ids) == 0 {
+		return 0
+	}
+	var movies []Movie
+	if err := db.Where("id IN ?", ids).Order("id").Find(&movies).Error; err != nil {
+		fmt.Printf("⚠️ 查询本次抓取的影片失败: %v\n", err)
+		return 0
+	}
+	return refreshMovieStatuses(movies, nowJST(), StatusSourceCrawl)
+}
+
+// isRevivalYear 上映年份（如 "1997"）是否早于 refYear 至少 RevivalYears 年。
+func isRevivalYear(year string, refYear int) bool {
+	y, err := strconv.Atoi(year)
+	return err == nil && y > 0 && refYear-y >= statusConfig.RevivalYears
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"cloud.google.com/go/civil"
+)
+
+// TestRefreshCrawledMovieStatuses 抓取后统一重算状态：按全部影院的排片，而不是最后解析的那家影院。
+func TestRefreshCrawledMovieStatuses(t *testing.T) {
+	newTestRouter(t)
+	now := testNoon
+	setTestClock(t, now)
+
+	movie := Movie{TitleJP: "テスト複数館", Status: "incoming"}
+	if err := db.Create(&movie).Error; err != nil {
+		t.Fatal(err)
+	}
+	// 影院 6 今天放映，影院 7 下周才开始；旧逻辑按最后解析的影院 7 判为 incoming
+	shows := []Schedule{
+		{MovieID: movie.ID, CinemaID: 6, PlayDate: time.Date(2026, 1, 27, 0, 0, 0, 0, time.UTC), StartTime: "18:00"},
+		{MovieID: movie.ID, CinemaID: 7, PlayDate: time.Date(2026, 2, 2, 0, 0, 0, 0, time.UTC), StartTime: "18:00"},
+	}
+	if err := db.Create(&shows).Error; err != nil {
+		t.Fatal(err)
+	}
+	markCrawlTouchedMovie(movie.ID)
+	changed := refreshCrawledMovieStatuses()
+	var after Movie
+	if err := db.First(&after, movie.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	var ev MovieStatusEvent
+	db.Where("movie_id = ?", movie.ID).Order("id DESC").First(&ev)
+	again := refreshMovieStatuses([]Movie{after}, now, StatusSourceUpdate)
+	if err := firstError(
+		expectEqual("changed", changed, 1),
+		expectEqual("status", after.Status, "showing"),
+		expectEqual("history source", ev.Source, StatusSourceCrawl),
+		expectEqual("status provenance", parseProvenance(after.ProvenanceJSON)["status"].Source, SourceEiga),
+		expectEqual("update-status agrees", again, 0),
+		expectEqual("touched set drained", refreshCrawledMovieStatuses(), 0)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// statusOrError 把状态计算的错误折叠进返回值，便于与期望状态直接比较。
+func statusOrError(status string, err error) string {
+	if err != nil {
+		return "error: " + err.Error()
+	}
+	return status
+}
+
+// TestStatusAtJSTMidnight 最后一天 / 开画日按东京时间的今天判断。
+func TestStatusAtJSTMidnight(t *testing.T) {
+	tests := []struct {
+		name        string
+		now         time.Time
+		first, last string
+		want        string
+	}{
+		{"JST 23:59 时最后一天排片的影片仍在上映", beforeMidnight, "2026-01-20", "2026-01-27", "showing"},
+		{"JST 23:59 时次日开画的影片为 Soon", beforeMidnight, "2026-01-28", "2026-02-03", "incoming"},
+		{"JST 00:01 时前一天结束的影片下映", afterMidnight, "2026-01-20", "2026-01-27", "unplanned"},
+		{"JST 00:01 时当天开画的影片上映", afterMidnight, "2026-01-28", "2026-02-03", "showing"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setTestClock(t, tt.now)
+			if got := statusOrError(statusFromScheduleRange(tt.first, tt.last, todayJST())); got != tt.want {
+				t.Fatalf("status = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestComputeMovieStatus 今天为 2026-01-27（JST 正午），阈值为默认值：过去 / 今天 / 明天 / 7 天 / 8 天以上。
+func TestComputeMovieStatus(t *testing.T) {
+	day := func(offset int) Schedule {
+		return Schedule{PlayDate: time.Date(2026, 1, 27+offset, 0, 0, 0, 0, time.UTC)}
+	}
+	tests := []struct {
+		name      string
+		schedules []Schedule
+		want      string
+	}{
+		{"没有排片", nil, "unplanned"},
+		{"只有过去的排片", []Schedule{day(-3), day(-1)}, "unplanned"},
+		{"只有今天的排片", []Schedule{day(0)}, "showing"},
+		{"已开映且仍有未来排片", []Schedule{day(-2), day(0), day(5)}, "showing"},
+		{"明天开始", []Schedule{day(1), day(2)}, "incoming"},
+		{"正好 7 天后开始", []Schedule{day(7)}, "incoming"},
+		{"8 天后开始", []Schedule{day(8), day(9)}, "future"},
+		{"30 天后开始", []Schedule{day(30)}, "future"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setTestClock(t, testNoon)
+			if got := computeMovieStatus(tt.schedules, nowJST()); got != tt.want {
+				t.Fatalf("status = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestStatusForSchedules 回归：抓取时的内联判断与 update-status 曾经在这些日期上给出不同结果，现在都经由 StatusForSchedules。
+func TestStatusForSchedules(t *testing.T) {
+	date := func(s string) civil.Date {
+		d, err := civil.ParseDate(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return d
+	}
+	today := date("2026-01-27")
+	leaving := StatusConfig{SoonDays: 7, LeavingDays: 2, RevivalYears: 10}
+	tests := []struct {
+		name  string
+		dates []civil.Date
+		cfg   StatusConfig
+		want  string
+	}{
+		{"正好 7 天后开始", []civil.Date{date("2026-02-03")}, defaultStatusConfig, "incoming"},
+		{"全部是过去的排片", []civil.Date{date("2026-01-20"), date("2026-01-26")}, defaultStatusConfig, "unplanned"},
+		{"没有排片", nil, defaultStatusConfig, "unplanned"},
+		{"只有今天", []civil.Date{today}, defaultStatusConfig, "showing"},
+		{"乱序且重复的日期", []civil.Date{date("2026-02-01"), date("2026-01-25"), date("2026-02-01"), date("2026-01-30")}, defaultStatusConfig, "showing"},
+		{"零值日期忽略", []civil.Date{{}, date("2026-02-01")}, defaultStatusConfig, "incoming"},
+		{"宽限期内", []civil.Date{date("2026-01-25")}, leaving, "showing"},
+		{"超过宽限期", []civil.Date{date("2026-01-24")}, leaving, "unplanned"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := StatusForSchedules(tt.dates, today, tt.cfg); got != tt.want {
+				t.Fatalf("status = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestStatusFromScheduleRangeInvalidDate 回归：日期无法解析时曾经静默返回 showing，现在返回错误，由调用方保留原状态。
+func TestStatusFromScheduleRangeInvalidDate(t *testing.T) {
+	badToday, errToday := statusFromScheduleRange("2026-02-20", "2026-02-20", "2026/01/27")
+	badDate, errDate := statusFromScheduleRange("2026-02-20", "2026-2-3", "2026-01-27")
+	if err := firstError(
+		expectEqual("invalid today status", badToday, ""),
+		expectEqual("invalid today error", errToday != nil, true),
+		expectEqual("invalid schedule date status", badDate, ""),
+		expectEqual("invalid schedule date error", errDate != nil, true)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestRefreshMovieStatuses update-status 的写库路径与 computeMovieStatus 给出相同的状态，会員限定场次不计入，锁定的影片不改。
+func TestRefreshMovieStatuses(t *testing.T) {
+	newTestDB(t)
+	setTestClock(t, testNoon)
+	day := func(offset int) time.Time { return time.Date(2026, 1, 27+offset, 0, 0, 0, 0, time.UTC) }
+	pinned := day(3)
+	tests := []struct {
+		name    string
+		movie   Movie
+		offsets []int
+		members bool
+		want    string
+	}{
+		{"只有过去的排片", Movie{TitleJP: "テスト過去", Status: "showing"}, []int{-3, -1}, false, "unplanned"},
+		{"只有今天的排片", Movie{TitleJP: "テスト今日", Status: "incoming"}, []int{0}, false, "showing"},
+		{"明天开始", Movie{TitleJP: "テスト明日", Status: "unplanned"}, []int{1, 2}, false, "incoming"},
+		{"正好 7 天后开始", Movie{TitleJP: "テスト七日後", Status: "unplanned"}, []int{7}, false, "incoming"},
+		{"8 天后开始", Movie{TitleJP: "テスト八日後", Status: "showing"}, []int{8}, false, "future"},
+		{"只有会員限定场次", Movie{TitleJP: "テスト会員", Status: "showing"}, []int{0}, true, "unplanned"},
+		{"锁定期内不改", Movie{TitleJP: "テスト固定", Status: "showing", StatusPinnedUntil: &pinned}, []int{-3}, false, "showing"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			movie := tt.movie
+			if err := db.Create(&movie).Error; err != nil {
+				t.Fatal(err)
+			}
+			shows := make([]Schedule, 0, len(tt.offsets))
+			for _, offset := range tt.offsets {
+				shows = append(shows, Schedule{MovieID: movie.ID, CinemaID: 1, PlayDate: day(offset), StartTime: "18:00", MembersOnly: tt.members})
+			}
+			if len(shows) > 0 {
+				if err := db.Create(&shows).Error; err != nil {
+					t.Fatal(err)
+				}
+			}
+			refreshMovieStatuses([]Movie{movie}, nowJST(), StatusSourceUpdate)
+			var after Movie
+			if err := db.First(&after, movie.ID).Error; err != nil {
+				t.Fatal(err)
+			}
+			if after.Status != tt.want {
+				t.Fatalf("status = %s, want %s", after.Status, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"embed"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ===========================
+// 模块：服务端渲染的上映时间表页面
+// 职责：不依赖前端 SPA，直接输出 /timetable?date=... 的 HTML（无 JavaScript 也能阅读）
+// 说明：
+// - 数据与 /api/timetable 共用 loadCityTimetable（见 citytimetable.go），按区（district）分组并提供页内锚点。
+// - 支持 district 过滤；hide_past=true 时隐藏今天已开场的场次。
+// - 日期标题与文案按 lang / Accept-Language 本地化（见 i18n.go），默认日文。
+// ===========================
+
+//go:embed templates/timetable.html
+var templateFS embed.FS
+
+var timetableTmpl = template.Must(template.New("timetable.html").
+	Funcs(template.FuncMap{
+		"join": strings.Join,
+		"t":    func(lang, key string) string { return translate(lang, key) },
+	}).
+	ParseFS(templateFS, "templates/timetable.html"))
+
+// timetableMovie 页面中的单部影片。
+type timetableMovie struct {
+	Title   string
+	Rating  string
+	Times   []string
+	EigaURL string
+}
+
+// timetableCinema 页面中的单个影院。
+type timetableCinema struct {
+	Name    string
+	EigaURL string
+	Movies  []timetableMovie
+}
+
+// timetableDistrict 页面中的一个区分组。
+type timetableDistrict struct {
+	Name    string
+	Anchor  string
+	Cinemas []timetableCinema
+}
+
+// timetablePage 模板数据。
+type timetablePage struct {
+	Lang        string
+	Date        string
+	DateHeading string       // 本地化的日期标题，如 1月25日(土)
+	CinemaCount string       // 本地化的影院数，如 84館
+	PrevURL     template.URL // 前一天的页面地址（带上当前的过滤条件），由 timetablePageURL 编码
+	PrevHeading string
+	NextURL     template.URL
+	NextHeading string
+	Cinemas     []timetableCinema
+	Districts   []timetableDistrict
+}
+
+// eigaSearchURL 生成 eiga.com 的站内搜索链接（暂无稳定的 eiga ID 时使用）。
+func eigaSearchURL(keyword string) string {
+	return "https://eiga.com/search/" + url.PathEscape(keyword) + "/"
+}
+
+// eigaMovieURL eiga.com 的影片页面；没有 eiga 编号时退回站内搜索。
+func eigaMovieURL(eigaID, title string) string {
+	if eigaID == "" {
+		return eigaSearchURL(title)
+	}
+	return "https://eiga.com/movie/" + url.PathEscape(eigaID) + "/"
+}
+
+// eigaCinemaURL eiga.com 的影院详情页（抓取时记录的 Cinema.EigaURL）；没有时退回站内搜索。
+func eigaCinemaURL(eigaURL, name string) string {
+	if eigaURL == "" {
+		return eigaSearchURL(name)
+	}
+	return eigaURL
+}
+
+// timetablePageURL 时间表页面的相对地址：date 与过滤条件逐项编码，整体作为 template.URL 交给模板，
+// 避免把拼好的查询串当作普通文本再转义一次。
+func timetablePageURL(date, district string, hidePast bool) template.URL {
+	q := url.Values{}
+	q.Set("date", date)
+	if district != "" {
+		q.Set("district", district)
+	}
+	if hidePast {
+		q.Set("hide_past", "true")
+	}
+	return template.URL("?" + q.Encode())
+}
+
+// timetablePageHandler 上映时间表页面：
+// - GET /timetable?date=YYYY-MM-DD&district=新宿区&hide_past=true
+func timetablePageHandler(c *gin.Context) {
+	dateStr := c.Query("date")
+	now := referenceTime(c)
+	day, err := time.ParseInLocation("2006-01-02", dateStr, tokyoLocation)
+	if err != nil {
+		day = now
+		dateStr = day.Format("2006-01-02")
+	}
+	district := c.Query("district")
+	hidePast := c.Query("hide_past") == "true"
+
+	cinemas, err := loadCityTimetable(dateStr, district, requestLang(c))
+	if err != nil {
+		c.String(http.StatusInternalServerError, "failed to query cinemas")
+		return
+	}
+
+	nowMinutes := -1
+	if hidePast && dateStr == now.Format("2006-01-02") {
+		nowMinutes = now.Hour()*60 + now.Minute()
+	}
+
+	lang := i18nLang(requestLang(c), LangJA)
+	prev, next := day.AddDate(0, 0, -1), day.AddDate(0, 0, 1)
+	page := timetablePage{
+		Lang:        lang,
+		Date:        dateStr,
+		DateHeading: formatDateHeading(day, lang),
+		PrevURL:     timetablePageURL(prev.Format("2006-01-02"), district, hidePast),
+		PrevHeading: formatDateHeading(prev, lang),
+		NextURL:     timetablePageURL(next.Format("2006-01-02"), district, hidePast),
+		NextHeading: formatDateHeading(next, lang),
+	}
+
+	for _, cin := range cinemas {
+		d := cin.District
+		if d == "" {
+			d = translate(lang, "timetable.other")
+		}
+
+		tc := timetableCinema{Name: cin.Name, EigaURL: eigaCinemaURL(cin.EigaURL, cin.Name)}
+		daily := cin.DailyMovies
+		sort.SliceStable(daily, func(i, j int) bool { return daily[i].Title < daily[j].Title })
+		for _, dm := range daily {
+			times := make([]string, 0, len(dm.Times))
+			for _, t := range dm.Times {
+				if nowMinutes >= 0 && startTimeMinutes(t) < nowMinutes {
+					continue
+				}
+				times = append(times, t)
+			}
+			if len(times) == 0 {
+				continue
+			}
+			sortStartTimes(times)
+			tc.Movies = append(tc.Movies, timetableMovie{
+				Title:   dm.Title,
+				Rating:  dm.Rating,
+				Times:   times,
+				EigaURL: eigaMovieURL(dm.EigaID, dm.Title),
+			})
+		}
+
+		page.Cinemas = append(page.Cinemas, tc)
+		if n := len(page.Districts); n == 0 || page.Districts[n-1].Name != d {
+			page.Districts = append(page.Districts, timetableDistrict{
+				Name:   d,
+				Anchor: fmt.Sprintf("d%d", n+1),
+			})
+		}
+		last := &page.Districts[len(page.Districts)-1]
+		last.Cinemas = append(last.Cinemas, tc)
+	}
+
+	page.CinemaCount = formatCinemaCount(len(page.Cinemas), lang)
+
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	if err := timetableTmpl.Execute(c.Writer, page); err != nil {
+		fmt.Printf("⚠️ 渲染时间表页面失败: %v\n", err)
+	}
+}
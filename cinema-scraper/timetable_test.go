@@ -0,0 +1,39 @@
+package main
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// TestTimetablePageLinks 前后一天的链接带上编码后的过滤条件；影院与影片优先链接 eiga.com 的详情页，没有编号时退回站内搜索。
+func TestTimetablePageLinks(t *testing.T) {
+	router := newTestRouter(t)
+	day := nowJST().AddDate(0, 0, 1)
+	var show Schedule
+	if err := db.Where("cinema_id = ? AND date(play_date) = ?", 1, day.Format("2006-01-02")).First(&show).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := firstError(
+		db.Model(&Cinema{}).Where("id = ?", 1).Update("eiga_url", "https://eiga.com/theater/13/130401/3001/").Error,
+		db.Model(&Movie{}).Where("id = ?", show.MovieID).Update("eiga_id", "101234").Error); err != nil {
+		t.Fatal(err)
+	}
+	r := testGet(router, "/timetable?date="+day.Format("2006-01-02")+"&district="+url.QueryEscape("新宿区")+"&hide_past=true")
+	if err := expectStatus(r, 200); err != nil {
+		t.Fatal(err)
+	}
+	body := string(r.Body)
+	district := url.QueryEscape("新宿区")
+	for _, want := range []string{
+		`href="?date=` + day.AddDate(0, 0, -1).Format("2006-01-02") + `&amp;district=` + district + `&amp;hide_past=true"`,
+		`href="?date=` + day.AddDate(0, 0, 1).Format("2006-01-02") + `&amp;district=` + district + `&amp;hide_past=true"`,
+		`href="https://eiga.com/theater/13/130401/3001/"`,
+		`href="https://eiga.com/movie/101234/"`,
+		`href="` + eigaSearchURL("早稲田テスト劇場") + `"`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("page is missing %s", want)
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ===========================
+// 模块：TMDB 熔断器
+// 职责：
+// - TMDB 故障时避免每部新片都等满几次超时：连续失败 tmdbBreakerThreshold 次后熔断（open），
+//   冷却 tmdbBreakerCooldown 期间不再请求 TMDB，影片补全推迟并标记为 TMDBPending
+// - 冷却结束后半开（half-open），放行一个探测请求：成功则恢复（closed），失败则再次熔断
+// - 状态切换打印日志并计数；抓取汇总与 /api/stats 给出状态与推迟补全的影片数
+// 说明：网络错误、5xx 与 429 计为失败；404 / 搜索无结果属于正常响应。
+//       被推迟的影片在下次 enrich-movies 时优先补全（见 enrichmovies.go）。
+// ===========================
+
+const (
+	tmdbBreakerThreshold = 3               // 连续失败多少次后熔断
+	tmdbBreakerCooldown  = 2 * time.Minute // 熔断后多久半开探测
+	tmdbRequestTimeout   = 10 * time.Second
+)
+
+// 熔断器状态。
+const (
+	BreakerClosed   = "closed"
+	BreakerOpen     = "open"
+	BreakerHalfOpen = "half-open"
+)
+
+// errTmdbCircuitOpen 熔断期间拒绝请求。
+var errTmdbCircuitOpen = errors.New("tmdb circuit breaker is open")
+
+// tmdbBreaker 进程内的熔断状态（抓取回调并发调用，需加锁）。
+var tmdbBreaker struct {
+	sync.Mutex
+	state       string
+	failures    int       // 连续失败次数
+	openedAt    time.Time // 最近一次熔断的时间
+	probing     bool      // 半开状态下探测请求是否在进行中
+	transitions int       // 状态切换次数
+}
+
+// tmdbDeferred 本次运行因熔断推迟补全的影片数。
+var tmdbDeferred atomic.Int64
+
+// tmdbSetState 切换状态并打印日志（调用方持锁）。
+func tmdbSetState(state string) {
+	prev := tmdbBreaker.state
+	if prev == "" {
+		prev = BreakerClosed
+	}
+	if prev == state {
+		return
+	}
+	tmdbBreaker.state = state
+	tmdbBreaker.transitions++
+	switch state {
+	case BreakerOpen:
+		tmdbBreaker.openedAt = time.Now()
+		fmt.Printf("🔌 TMDB 熔断器 %s -> open：连续失败 %d 次，%v 内暂停请求 TMDB，新影片推迟补全。\n",
+			prev, tmdbBreaker.failures, tmdbBreakerCooldown)
+	case BreakerHalfOpen:
+		fmt.Println("🔌 TMDB 熔断器 open -> half-open：发送探测请求。")
+	case BreakerClosed:
+		fmt.Printf("🔌 TMDB 熔断器 %s -> closed：TMDB 已恢复。\n", prev)
+	}
+}
+
+// tmdbBreakerAllow 是否放行一次请求：熔断冷却结束后转为半开，只放行一个探测请求。
+func tmdbBreakerAllow() bool {
+	tmdbBreaker.Lock()
+	defer tmdbBreaker.Unlock()
+	switch tmdbBreaker.state {
+	case BreakerOpen:
+		if time.Since(tmdbBreaker.openedAt) < tmdbBreakerCooldown {
+			return false
+		}
+		tmdbSetState(BreakerHalfOpen)
+		tmdbBreaker.probing = true
+		return true
+	case BreakerHalfOpen:
+		if tmdbBreaker.probing {
+			return false
+		}
+		tmdbBreaker.probing = true
+		return true
+	}
+	return true
+}
+
+// tmdbBreakerRecord 记录一次请求结果。
+func tmdbBreakerRecord(ok bool) {
+	tmdbBreaker.Lock()
+	defer tmdbBreaker.Unlock()
+	tmdbBreaker.probing = false
+	if ok {
+		tmdbBreaker.failures = 0
+		tmdbSetState(BreakerClosed)
+		return
+	}
+	tmdbBreaker.failures++
+	if tmdbBreaker.state == BreakerHalfOpen || tmdbBreaker.failures >= tmdbBreakerThreshold {
+		tmdbBreaker.openedAt = time.Now() // 已熔断时重新计算冷却
+		tmdbSetState(BreakerOpen)
+	}
+}
+
+// tmdbBreakerIsOpen 当前是否处于熔断（含半开）状态，不触发状态切换。
+func tmdbBreakerIsOpen() bool {
+	tmdbBreaker.Lock()
+	defer tmdbBreaker.Unlock()
+	return tmdbBreaker.state == BreakerOpen || tmdbBreaker.state == BreakerHalfOpen
+}
+
+// tmdbBreakerSnapshot 熔断器状态，供抓取汇总与 /api/stats 使用。
+func tmdbBreakerSnapshot() (state string, failures, transitions int) {
+	tmdbBreaker.Lock()
+	defer tmdbBreaker.Unlock()
+	state = tmdbBreaker.state
+	if state == "" {
+		state = BreakerClosed
+	}
+	return state, tmdbBreaker.failures, tmdbBreaker.transitions
+}
+
+// tmdbGet 经熔断器发起一次 TMDB GET 请求；熔断期间返回 errTmdbCircuitOpen。
+// 返回的响应由调用方关闭；5xx / 429 计为失败但仍返回响应，便于调用方按原逻辑处理。
+func tmdbGet(apiURL, userAgent string) (*http.Response, error) {
+	if !tmdbBreakerAllow() {
+		return nil, errTmdbCircuitOpen
+	}
+	client := &http.Client{Timeout: tmdbRequestTimeout}
+	req, err := http.NewRequest("GET", apiURL, nil)
+	if err != nil {
+		tmdbBreakerRecord(true) // 请求构造失败与 TMDB 可用性无关
+		return nil, err
+	}
+	if userAgent != "" {
+		req.Header.Set("User-Agent", userAgent)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		tmdbBreakerRecord(false)
+		return nil, err
+	}
+	tmdbBreakerRecord(resp.StatusCode < 500 && resp.StatusCode != http.StatusTooManyRequests)
+	return resp, nil
+}
+
+// deferTmdbEnrichment 熔断期间推迟影片补全：标记为 TMDBPending，下次 enrich-movies 优先处理。
+func deferTmdbEnrichment(m *Movie) {
+	tmdbDeferred.Add(1)
+	m.TMDBPending = true
+	if err := db.Save(m).Error; err != nil {
+		fmt.Printf("⚠️ 标记影片待补全失败 [%s]: %v\n", m.TitleJP, err)
+		return
+	}
+	fmt.Printf("⏸️ TMDB 熔断中，推迟补全: %s\n", m.TitleJP)
+}
+
+// printTmdbSummary 在补全结束时打印 TMDB 熔断情况。
+func printTmdbSummary() {
+	state, _, transitions := tmdbBreakerSnapshot()
+	var pending int64
+	db.Model(&Movie{}).Where("tmdb_pending = ?", true).Count(&pending)
+	fmt.Printf("📊 TMDB：熔断器 %s（状态切换 %d 次），本次因故障推迟补全 %d 部，待补全 %d 部\n",
+		state, transitions, tmdbDeferred.Load(), pending)
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ===========================
+// 模块：TMDB 搜索结果消歧
+// 职责：
+// - searchTmdbID 原先直接取第一个结果：名画座放映的 1954 年老片会匹配到 2022 年的翻拍，短片会匹配到同名长片
+// - 解析搜索结果的 title / original_title / release_date，按年份与片名逐个打分，取分数最高的候选
+// - 年份来自 Movie.Year，或 eiga.com 影片区块里的「2022年製作」（抓取时写入 Year，见 captureEigaYear）
+// - 没有任何候选得分时仍取第一个结果；最高分并列时打印全部并列候选的 TMDB ID，便于人工修正
+// 说明：日本上映年份常比 TMDB 的首映年份晚一年，相差一年只给少量加分；年份都已知且相差更多时扣分。
+// ===========================
+
+// TMDB 候选打分权重。
+const (
+	tmdbScoreTitleExact = 2  // 规范化后与 title 或 original_title 完全一致
+	tmdbScoreYearExact  = 3  // 年份一致
+	tmdbScoreYearNear   = 1  // 年份相差一年（日本上映晚于首映）
+	tmdbScoreYearFar    = -2 // 年份相差两年以上
+)
+
+// tmdbSearchCandidate TMDB /search/movie 的一个结果。
+type tmdbSearchCandidate struct {
+	ID            int    `json:"id"`
+	Title         string `json:"title"`
+	OriginalTitle string `json:"original_title"`
+	ReleaseDate   string `json:"release_date"` // YYYY-MM-DD，可能为空
+}
+
+// year 候选的首映年份，未知时为 0。
+func (c tmdbSearchCandidate) year() int {
+	if len(c.ReleaseDate) < 4 {
+		return 0
+	}
+	y, _ := strconv.Atoi(c.ReleaseDate[:4])
+	return y
+}
+
+// parseMovieYear 解析 Movie.Year（取前 4 位数字），无法解析时为 0。
+func parseMovieYear(s string) int {
+	s = strings.TrimSpace(s)
+	if len(s) < 4 {
+		return 0
+	}
+	y, err := strconv.Atoi(s[:4])
+	if err != nil {
+		return 0
+	}
+	return y
+}
+
+// scoreTmdbCandidate 候选得分（纯函数）：片名完全一致加分，年份按差值加减分；year 为 0 时不比较年份。
+func scoreTmdbCandidate(c tmdbSearchCandidate, title string, year int) int {
+	score := 0
+	if key := NormalizeForSearch(title); key != "" &&
+		(NormalizeForSearch(c.Title) == key || NormalizeForSearch(c.OriginalTitle) == key) {
+		score += tmdbScoreTitleExact
+	}
+	if cy := c.year(); year > 0 && cy > 0 {
+		diff := cy - year
+		if diff < 0 {
+			diff = -diff
+		}
+		switch {
+		case diff == 0:
+			score += tmdbScoreYearExact
+		case diff == 1:
+			score += tmdbScoreYearNear
+		default:
+			score += tmdbScoreYearFar
+		}
+	}
+	return score
+}
+
+// pickTmdbCandidate 选出得分最高的候选（纯函数）：并列时取排在前面的（TMDB 按相关度排序），
+// ties 为全部并列的最高分候选（只有一个时为空）；没有候选得分（最高分 <= 0）时退回第一个结果，ties 为空。
+func pickTmdbCandidate(cands []tmdbSearchCandidate, title string, year int) (best tmdbSearchCandidate, ties []tmdbSearchCandidate, ok bool) {
+	if len(cands) == 0 {
+		return tmdbSearchCandidate{}, nil, false
+	}
+	bestScore := 0
+	scores := make([]int, len(cands))
+	for i, c := range cands {
+		scores[i] = scoreTmdbCandidate(c, title, year)
+		if scores[i] > bestScore {
+			bestScore = scores[i]
+		}
+	}
+	if bestScore <= 0 {
+		return cands[0], nil, true
+	}
+	for i, c := range cands {
+		if scores[i] == bestScore {
+			ties = append(ties, c)
+		}
+	}
+	best = ties[0]
+	if len(ties) == 1 {
+		ties = nil
+	}
+	return best, ties, true
+}
+
+// formatTmdbCandidates 日志用的候选列表："12345 ゴジラ (1954-11-03), 67890 ゴジラ (2022-...)"。
+func formatTmdbCandidates(cands []tmdbSearchCandidate) string {
+	parts := make([]string, 0, len(cands))
+	for _, c := range cands {
+		date := c.ReleaseDate
+		if date == "" {
+			date = "日期未知"
+		}
+		parts = append(parts, fmt.Sprintf("%d %s (%s)", c.ID, c.Title, date))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// eigaYearRe eiga.com 影片区块中的制作年份，如 "（1954年製作／96分／日本）"。
+var eigaYearRe = regexp.MustCompile(`((?:19|20)\d{2})年製作`)
+
+// parseEigaYear 从影片区块文本中解析制作年份，找不到时返回空串（纯函数）。
+func parseEigaYear(text string) string {
+	if m := eigaYearRe.FindStringSubmatch(text); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+// captureEigaYear 影片还没有 Year 时写入 eiga.com 标注的制作年份，供 TMDB 搜索消歧。
+func captureEigaYear(m *Movie, year string) {
+	if year == "" || m.Year != "" {
+		return
+	}
+	m.Year = year
+	recordProvenance(&m.ProvenanceJSON, SourceEiga, "year")
+	if err := db.Model(m).Updates(map[string]interface{}{
+		"year":            year,
+		"provenance_json": m.ProvenanceJSON,
+	}).Error; err != nil {
+		fmt.Printf("⚠️ 保存 eiga.com 制作年份失败 [%s]: %v\n", m.TitleJP, err)
+	}
+}
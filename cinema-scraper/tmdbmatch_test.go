@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// TestPickTmdbCandidate TMDB 消歧：按年份与片名选候选，都不得分时取第一个，并列时给出全部候选。
+func TestPickTmdbCandidate(t *testing.T) {
+	setTestClock(t, beforeMidnight)
+
+	// 搜索结果按 TMDB 相关度排序：翻拍排在老片前面，同名长片排在短片前面
+	godzilla := []tmdbSearchCandidate{
+		{ID: 1001, Title: "ゴジラ-1.0", OriginalTitle: "ゴジラ-1.0", ReleaseDate: "2023-11-03"},
+		{ID: 1002, Title: "ゴジラ", OriginalTitle: "ゴジラ", ReleaseDate: "2014-05-14"},
+		{ID: 1003, Title: "ゴジラ", OriginalTitle: "ゴジラ", ReleaseDate: "1954-11-03"},
+	}
+	short := []tmdbSearchCandidate{
+		{ID: 2001, Title: "ひかりの歌 完全版", ReleaseDate: "2019-01-12"},
+		{ID: 2002, Title: "ひかりの歌", ReleaseDate: "2017-11-23"},
+	}
+	pick := func(cands []tmdbSearchCandidate, title string, year int) string {
+		best, ties, ok := pickTmdbCandidate(cands, title, year)
+		if !ok {
+			return "none"
+		}
+		ids := make([]string, 0, len(ties))
+		for _, t := range ties {
+			ids = append(ids, fmt.Sprint(t.ID))
+		}
+		return fmt.Sprintf("%d ties=[%s]", best.ID, strings.Join(ids, ","))
+	}
+	if err := firstError(
+		expectEqual("classic by year", pick(godzilla, "ゴジラ", 1954), "1003 ties=[]"),
+		expectEqual("japanese release a year later", pick(godzilla, "ゴジラ", 2015), "1002 ties=[]"),
+		expectEqual("exact title, year unknown: tie", pick(godzilla, "ゴジラ", 0), "1002 ties=[1002,1003]"),
+		expectEqual("exact title beats first hit", pick(short, "ひかりの歌", 0), "2002 ties=[]"),
+		expectEqual("nothing scores: first", pick(short, "別の映画", 0), "2001 ties=[]"),
+		expectEqual("far year loses to exact title", scoreTmdbCandidate(godzilla[1], "ゴジラ", 1954), tmdbScoreTitleExact+tmdbScoreYearFar),
+		expectEqual("no results", pick(nil, "ゴジラ", 1954), "none"),
+		expectEqual("eiga year", parseEigaYear("（1954年製作／97分／日本）上映時間：97分"), "1954"),
+		expectEqual("movie year", parseMovieYear("1954"), 1954)); err != nil {
+		t.Fatal(err)
+	}
+}
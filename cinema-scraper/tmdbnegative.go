@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm/clause"
+)
+
+// ===========================
+// 模块：TMDB 搜索无结果缓存
+// 职责：
+// - 冷门短片、特别放映等在 TMDB 上搜不到的标题，每次抓取都会重新搜索一遍，浪费配额与时间
+// - 搜索正常返回但没有结果时，按规范化标题（NormalizeForSearch）记录，tmdbMissTTL 内跳过补全
+// - 请求失败（网络 / 5xx / 熔断）不算无结果，不会写入
+// 说明：过期后下一次抓取会重新搜索一次；TMDB 收录了新作品也最多晚 tmdbMissTTL 补上。
+// ===========================
+
+// tmdbMissTTL 无结果缓存的有效期。
+const tmdbMissTTL = 60 * 24 * time.Hour
+
+// TMDBSearchMiss 搜索无结果的标题。
+type TMDBSearchMiss struct {
+	ID          uint   `gorm:"primaryKey"`
+	TitleKey    string `gorm:"uniqueIndex"` // NormalizeForSearch(标题)
+	Title       string // 最近一次搜索使用的标题，便于排查
+	Misses      int    // 累计无结果次数
+	LastTriedAt time.Time
+	ExpiresAt   time.Time `gorm:"index"`
+}
+
+// tmdbKnownMiss 标题是否在有效期内搜索过且无结果。
+func tmdbKnownMiss(title string, now time.Time) bool {
+	key := NormalizeForSearch(title)
+	if key == "" {
+		return false
+	}
+	var n int64
+	db.Model(&TMDBSearchMiss{}).Where("title_key = ? AND expires_at > ?", key, now).Count(&n)
+	return n > 0
+}
+
+// recordTmdbMiss 记录一次搜索无结果（已有记录时累加次数并顺延有效期）；写入失败只打印。
+func recordTmdbMiss(title string, now time.Time) {
+	key := NormalizeForSearch(title)
+	if key == "" {
+		return
+	}
+	miss := TMDBSearchMiss{TitleKey: key, Title: title, Misses: 1, LastTriedAt: now, ExpiresAt: now.Add(tmdbMissTTL)}
+	err := db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "title_key"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"title":         title,
+			"misses":        clause.Expr{SQL: "misses + 1"},
+			"last_tried_at": now,
+			"expires_at":    miss.ExpiresAt,
+		}),
+	}).Create(&miss).Error
+	if err != nil {
+		fmt.Printf("⚠️ 记录 TMDB 无结果缓存失败 [%s]: %v\n", title, err)
+	}
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// TestTMDBSearchMissCache TMDB 无结果缓存：规范化标题命中，过期后重新搜索。
+func TestTMDBSearchMissCache(t *testing.T) {
+	newTestRouter(t)
+	setTestClock(t, beforeMidnight)
+
+	tried := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	recordTmdbMiss("【字幕版】落語テスト会", tried)
+	recordTmdbMiss("落語テスト会", tried)
+	var miss TMDBSearchMiss
+	if err := db.Where("title_key = ?", NormalizeForSearch("落語テスト会")).First(&miss).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := firstError(
+		expectEqual("misses", miss.Misses, 2),
+		expectEqual("within ttl", tmdbKnownMiss("落語テスト会（字幕版）", tried.Add(24*time.Hour)), true),
+		expectEqual("after ttl", tmdbKnownMiss("落語テスト会", tried.Add(tmdbMissTTL+time.Hour)), false),
+		expectEqual("other title", tmdbKnownMiss("市民ケーン", tried), false)); err != nil {
+		t.Fatal(err)
+	}
+}
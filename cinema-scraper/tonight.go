@@ -0,0 +1,242 @@
+package main
+
+import (
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ===========================
+// 模块：今晚推荐（Tonight's Picks）
+// 职责：从“现在 ~ 东京时间午夜”之间开场的场次中，按评分 / 距离 / 名画座加权挑出最值得看的几场
+// 说明：
+// - 候选查询只负责过滤（今天、未开场、未满席），排序交给纯函数 rankTonightCandidates。
+// - 传入 lat/lng 时才计算距离分；否则只按评分与加成排序。
+// ===========================
+
+// 排序权重。
+const (
+	tonightWeightRating     = 0.6
+	tonightWeightProximity  = 0.3
+	tonightRevivalBoost     = 0.1
+	tonightMiniTheaterBoost = 0.1
+)
+
+// chainCinemaKeywords 影院名包含这些关键词视为连锁影城，不享受小剧场加成。
+var chainCinemaKeywords = []string{
+	"TOHO", "109シネマズ", "ユナイテッド・シネマ", "イオンシネマ", "T・ジョイ", "MOVIX",
+	"シネマサンシャイン", "グランドシネマサンシャイン", "シネマート", "ピカデリー", "丸の内",
+	"kino cinema", "USシネマ", "シネプレックス",
+}
+
+// isMiniTheater 粗略判断是否为独立 / 小剧场（非连锁）。
+func isMiniTheater(name string) bool {
+	for _, kw := range chainCinemaKeywords {
+		if strings.Contains(name, kw) {
+			return false
+		}
+	}
+	return true
+}
+
+// combinedRating 综合评分（10 分制）：豆瓣 / IMDb / TMDB 中非零值的平均。
+func combinedRating(m Movie) float64 {
+	sum, n := 0.0, 0
+	for _, r := range []float64{m.DoubanRating, m.IMDBRating, m.TMDBRating} {
+		if r > 0 {
+			sum += r
+			n++
+		}
+	}
+	if n == 0 {
+		return 0
+	}
+	return sum / float64(n)
+}
+
+// haversineKm 计算两点之间的大圆距离（公里）。
+func haversineKm(lat1, lng1, lat2, lng2 float64) float64 {
+	const earthRadiusKm = 6371.0
+	toRad := func(d float64) float64 { return d * math.Pi / 180 }
+	dLat := toRad(lat2 - lat1)
+	dLng := toRad(lng2 - lng1)
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(toRad(lat1))*math.Cos(toRad(lat2))*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return earthRadiusKm * 2 * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
+}
+
+// tonightCandidate 待排序的候选场次。
+type tonightCandidate struct {
+	Schedule Schedule
+	Movie    Movie
+	Cinema   Cinema
+}
+
+// TonightPick 今晚推荐的单个场次。
+type TonightPick struct {
+	Movie      MovieItem  `json:"movie"`
+	Cinema     CinemaItem `json:"cinema"`
+	Time       string     `json:"time"`
+	EventType  string     `json:"event_type"`
+	BookingURL string     `json:"booking_url"`
+	DistanceKm *float64   `json:"distance_km"`
+	Score      float64    `json:"score"`
+}
+
+// geoPoint 可选的用户坐标。
+type geoPoint struct {
+	Lat float64
+	Lng float64
+}
+
+// tonightScore 计算单个候选的得分（纯函数）。
+// origin 为 nil 时不计距离分；返回值同时给出距离（无坐标时为 nil）。
+func tonightScore(cand tonightCandidate, origin *geoPoint, now time.Time) (float64, *float64) {
+	score := combinedRating(cand.Movie) / 10 * tonightWeightRating
+
+	var distance *float64
+	if origin != nil && (cand.Cinema.Latitude != 0 || cand.Cinema.Longitude != 0) {
+		d := haversineKm(origin.Lat, origin.Lng, cand.Cinema.Latitude, cand.Cinema.Longitude)
+		distance = &d
+		// 距离越近分越高：0km -> 1，2km -> 0.5，10km -> 约 0.17
+		score += tonightWeightProximity / (1 + d/2)
+	}
+
+	// 旧片重映的年数阈值见 statusrules.go（StatusConfig.RevivalYears）
+	if isRevivalYear(cand.Movie.Year, now.Year()) {
+		score += tonightRevivalBoost
+	}
+	if isMiniTheater(cand.Cinema.NameJP) {
+		score += tonightMiniTheaterBoost
+	}
+	return score, distance
+}
+
+// rankTonightCandidates 为候选场次打分并按得分降序排序，取前 limit 个（纯函数）。
+// 同分时开场更早的排前面。
+func rankTonightCandidates(cands []tonightCandidate, origin *geoPoint, now time.Time, limit int, lang string) []TonightPick {
+	type scored struct {
+		cand     tonightCandidate
+		score    float64
+		distance *float64
+	}
+	list := make([]scored, 0, len(cands))
+	for _, cand := range cands {
+		s, d := tonightScore(cand, origin, now)
+		list = append(list, scored{cand: cand, score: s, distance: d})
+	}
+	sort.SliceStable(list, func(i, j int) bool {
+		if list[i].score != list[j].score {
+			return list[i].score > list[j].score
+		}
+		return startTimeMinutes(list[i].cand.Schedule.StartTime) < startTimeMinutes(list[j].cand.Schedule.StartTime)
+	})
+
+	if limit > 0 && len(list) > limit {
+		list = list[:limit]
+	}
+	picks := make([]TonightPick, 0, len(list))
+	for _, it := range list {
+		picks = append(picks, TonightPick{
+			Movie:      mapMovieToItem(it.cand.Movie, lang),
+			Cinema:     mapCinemaToItem(it.cand.Cinema),
+			Time:       it.cand.Schedule.StartTime,
+			EventType:  it.cand.Schedule.EventType,
+			BookingURL: it.cand.Cinema.Website,
+			DistanceKm: it.distance,
+			Score:      math.Round(it.score*1000) / 1000,
+		})
+	}
+	return picks
+}
+
+// loadTonightCandidates 查询今天（东京时间）尚未开场且未满席的所有公开场次（不含会員限定）。
+func loadTonightCandidates(now time.Time) ([]tonightCandidate, error) {
+	today := now.Format("2006-01-02")
+	nowMinutes := now.Hour()*60 + now.Minute()
+
+	var schedules []Schedule
+	if err := db.Where("date(play_date) = ? AND members_only = ?", today, false).
+		Where("availability IS NULL OR availability <> ?", AvailabilitySoldOut).
+		Order("id").Find(&schedules).Error; err != nil {
+		return nil, err
+	}
+
+	movieIDs := make(map[uint]struct{})
+	cinemaIDs := make(map[uint]struct{})
+	upcoming := make([]Schedule, 0, len(schedules))
+	for _, s := range schedules {
+		if startTimeMinutes(s.StartTime) < nowMinutes {
+			continue
+		}
+		upcoming = append(upcoming, s)
+		movieIDs[s.MovieID] = struct{}{}
+		cinemaIDs[s.CinemaID] = struct{}{}
+	}
+	if len(upcoming) == 0 {
+		return []tonightCandidate{}, nil
+	}
+
+	var movies []Movie
+	if err := db.Where("id IN ?", sortedIDs(movieIDs)).Find(&movies).Error; err != nil {
+		return nil, err
+	}
+	var cinemas []Cinema
+	if err := db.Where("id IN ?", sortedIDs(cinemaIDs)).Find(&cinemas).Error; err != nil {
+		return nil, err
+	}
+	movieMap := make(map[uint]Movie, len(movies))
+	for _, m := range movies {
+		movieMap[m.ID] = m
+	}
+	cinemaMap := make(map[uint]Cinema, len(cinemas))
+	for _, cin := range cinemas {
+		cinemaMap[cin.ID] = cin
+	}
+
+	cands := make([]tonightCandidate, 0, len(upcoming))
+	for _, s := range upcoming {
+		m, ok1 := movieMap[s.MovieID]
+		cin, ok2 := cinemaMap[s.CinemaID]
+		if !ok1 || !ok2 {
+			continue
+		}
+		cands = append(cands, tonightCandidate{Schedule: s, Movie: m, Cinema: cin})
+	}
+	return cands, nil
+}
+
+// tonightHandler 今晚推荐接口：
+// - GET /api/tonight?lat=&lng=&limit=5
+func tonightHandler(c *gin.Context) {
+	limit := 5
+	if v, err := strconv.Atoi(c.Query("limit")); err == nil && v > 0 {
+		limit = v
+		if limit > 50 {
+			limit = 50
+		}
+	}
+
+	var origin *geoPoint
+	lat, errLat := strconv.ParseFloat(c.Query("lat"), 64)
+	lng, errLng := strconv.ParseFloat(c.Query("lng"), 64)
+	if errLat == nil && errLng == nil {
+		origin = &geoPoint{Lat: lat, Lng: lng}
+	}
+
+	now := nowJST()
+	cands, err := loadTonightCandidates(now)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to query schedules"})
+		return
+	}
+
+	c.JSON(http.StatusOK, withFreshness(gin.H{
+		"items": rankTonightCandidates(cands, origin, now, limit, requestLang(c)),
+	}))
+}
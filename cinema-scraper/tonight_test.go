@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestRankTonightCandidates 今晚推荐：评分 / 旧片 / 小剧场加权，有坐标时近的加分，同分开场早的在前，取前 limit 个。
+func TestRankTonightCandidates(t *testing.T) {
+	setTestClock(t, beforeMidnight)
+
+	now := time.Date(2026, 1, 27, 19, 0, 0, 0, tokyoLocation)
+	chain := Cinema{ID: 1, NameJP: "TOHOシネマズ 新宿", Latitude: 35.6950, Longitude: 139.7020}
+	mini := Cinema{ID: 2, NameJP: "新文芸坐", Latitude: 35.7700, Longitude: 139.7110}
+	unlocated := Cinema{ID: 3, NameJP: "TOHOシネマズ 日比谷"}
+	cands := []tonightCandidate{
+		{Schedule{StartTime: "21:00"}, Movie{ID: 1, IMDBRating: 8}, chain},
+		{Schedule{StartTime: "20:00"}, Movie{ID: 2, IMDBRating: 8, Year: "1954"}, mini},
+		{Schedule{StartTime: "22:00"}, Movie{ID: 3}, mini},
+		{Schedule{StartTime: "19:30"}, Movie{ID: 4, IMDBRating: 8}, unlocated},
+	}
+	summary := func(picks []TonightPick) string {
+		parts := make([]string, 0, len(picks))
+		for _, p := range picks {
+			distance := "-"
+			if p.DistanceKm != nil {
+				distance = strconv.FormatFloat(*p.DistanceKm, 'f', 1, 64)
+			}
+			parts = append(parts, fmt.Sprintf("%s:%v:%s", p.Time, p.Score, distance))
+		}
+		return strings.Join(parts, ",")
+	}
+	nearChain := &geoPoint{Lat: chain.Latitude, Lng: chain.Longitude}
+	if err := firstError(
+		expectEqual("no origin", summary(rankTonightCandidates(cands, nil, now, 3, "")), "20:00:0.68:-,19:30:0.48:-,21:00:0.48:-"),
+		expectEqual("with origin", summary(rankTonightCandidates(cands, nearChain, now, 2, "")), "21:00:0.78:0.0,20:00:0.738:8.4"),
+		expectEqual("no limit", len(rankTonightCandidates(cands, nil, now, 0, "")), 4),
+		expectEqual("empty", summary(rankTonightCandidates(nil, nil, now, 3, "")), ""),
+		expectEqual("combined rating", combinedRating(Movie{DoubanRating: 7, IMDBRating: 8}), 7.5),
+		expectEqual("mini theater", [2]bool{isMiniTheater(mini.NameJP), isMiniTheater(chain.NameJP)}, [2]bool{true, false})); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ===========================
+// 模块：预告片（Movie.TrailerURL）
+// 职责：
+// - 补全 TMDB 详情时随 append_to_response=videos 取回视频列表（各语言请求返回该语言的视频），
+//   从中挑选最合适的 YouTube 预告片写入 TrailerURL，详情接口输出 trailer_url
+// - 挑选顺序：Trailer 优先于 Teaser，官方（official）优先，日语优先于英语，其他语言最后；其余类型（花絮、片段）不采用
+// - 策展人可以手动指定预告片（来源记为 manual，空串表示“不展示预告片”），之后重新补全不再覆盖
+// 调用方式：
+//   PATCH /api/admin/movies/:id/trailer  {"trailer_url":"https://www.youtube.com/watch?v=..."}（空串清除）
+// ===========================
+
+// tmdbVideo TMDB videos.results 中的一项。
+type tmdbVideo struct {
+	Key      string `json:"key"`
+	Site     string `json:"site"`
+	Type     string `json:"type"`
+	Official bool   `json:"official"`
+	Language string `json:"iso_639_1"`
+}
+
+// tmdbVideos append_to_response=videos 的响应结构。
+type tmdbVideos struct {
+	Results []tmdbVideo `json:"results"`
+}
+
+// trailerRank 视频的优先级，0 表示不采用（纯函数）。
+func trailerRank(v tmdbVideo) int {
+	if v.Site != "YouTube" || strings.TrimSpace(v.Key) == "" {
+		return 0
+	}
+	rank := 0
+	switch v.Type {
+	case "Trailer":
+		rank = 200
+	case "Teaser":
+		rank = 100
+	default:
+		return 0
+	}
+	if v.Official {
+		rank += 10
+	}
+	switch v.Language {
+	case "ja":
+		rank += 2
+	case "en":
+		rank++
+	}
+	return rank
+}
+
+// pickTrailerURL 从视频列表中挑选预告片，返回 YouTube 观看地址（纯函数）；没有可用的预告片时返回空串。
+// 同等优先级时取列表中靠前的一条。
+func pickTrailerURL(videos []tmdbVideo) string {
+	best, bestRank := tmdbVideo{}, 0
+	for _, v := range videos {
+		if r := trailerRank(v); r > bestRank {
+			best, bestRank = v, r
+		}
+	}
+	if bestRank == 0 {
+		return ""
+	}
+	return "https://www.youtube.com/watch?v=" + url.QueryEscape(best.Key)
+}
+
+// applyTmdbTrailer 用 TMDB 视频更新预告片：手动指定过的不覆盖，TMDB 没有预告片时保留原值。
+func applyTmdbTrailer(m *Movie, videos []tmdbVideo) {
+	if parseProvenance(m.ProvenanceJSON)["trailer_url"].Source == SourceManual {
+		return
+	}
+	if u := pickTrailerURL(videos); u != "" && u != m.TrailerURL {
+		m.TrailerURL = u
+		recordProvenance(&m.ProvenanceJSON, SourceTMDBjaJP, "trailer_url")
+	}
+}
+
+// trailerRequest PATCH /api/admin/movies/:id/trailer 的请求体。
+type trailerRequest struct {
+	TrailerURL *string `json:"trailer_url"`
+}
+
+// patchMovieTrailerHandler 手动指定预告片：
+// - PATCH /api/admin/movies/:id/trailer  {"trailer_url":"https://..."}，空串表示不展示预告片；之后补全不再覆盖
+func patchMovieTrailerHandler(c *gin.Context) {
+	var req trailerRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.TrailerURL == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+	value := strings.TrimSpace(*req.TrailerURL)
+	if value != "" {
+		if u, err := url.Parse(value); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid trailer_url, expected an http(s) URL or empty"})
+			return
+		}
+	}
+	var movie Movie
+	if err := db.First(&movie, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "movie not found"})
+		return
+	}
+	recordProvenance(&movie.ProvenanceJSON, SourceManual, "trailer_url")
+	if err := db.Model(&movie).Updates(map[string]interface{}{
+		"trailer_url":     value,
+		"provenance_json": movie.ProvenanceJSON,
+	}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update trailer"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"id": movie.ID, "trailer_url": value})
+}
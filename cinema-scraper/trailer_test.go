@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestTrailerSelection 预告片：优先官方日语 / 英语 YouTube Trailer，详情输出 trailer_url，手动指定后补全不覆盖。
+func TestTrailerSelection(t *testing.T) {
+	newTestRouter(t)
+	now := testNoon
+	setTestClock(t, now)
+
+	var detail struct {
+		Videos tmdbVideos `json:"videos"`
+	}
+	raw := `{"videos":{"results":[
+				{"key":"clip1","site":"YouTube","type":"Clip","official":true,"iso_639_1":"ja"},
+				{"key":"teaserJA","site":"YouTube","type":"Teaser","official":true,"iso_639_1":"ja"},
+				{"key":"fanEN","site":"YouTube","type":"Trailer","official":false,"iso_639_1":"en"},
+				{"key":"vimeo1","site":"Vimeo","type":"Trailer","official":true,"iso_639_1":"ja"},
+				{"key":"officialEN","site":"YouTube","type":"Trailer","official":true,"iso_639_1":"en"},
+				{"key":"officialJA","site":"YouTube","type":"Trailer","official":true,"iso_639_1":"ja"}]}}`
+	if err := json.Unmarshal([]byte(raw), &detail); err != nil {
+		t.Fatal(err)
+	}
+	clipsOnly := detail.Videos.Results[:1]
+
+	movie := Movie{TitleJP: "テスト予告編", Status: "showing"}
+	applyTmdbTrailer(&movie, detail.Videos.Results)
+	picked := movie.TrailerURL
+	if err := db.Create(&movie).Error; err != nil {
+		t.Fatal(err)
+	}
+	getDetail := func() (string, error) {
+		rec := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(rec)
+		c.Request = httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/movies/%d", movie.ID), nil)
+		c.Params = gin.Params{{Key: "id", Value: fmt.Sprint(movie.ID)}}
+		getMovieHandler(c)
+		var body struct {
+			TrailerURL *string `json:"trailer_url"`
+		}
+		if err := expectJSON(testResponse{Status: rec.Code, Body: rec.Body.Bytes()}, &body); err != nil {
+			return "", err
+		}
+		if body.TrailerURL == nil {
+			return "", errors.New("trailer_url missing from detail")
+		}
+		return *body.TrailerURL, nil
+	}
+	fromTMDB, err := getDetail()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(rec)
+	c.Request = httptest.NewRequest(http.MethodPatch, "/api/admin/movies/x/trailer",
+		strings.NewReader(`{"trailer_url":"https://www.youtube.com/watch?v=curated"}`))
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Params = gin.Params{{Key: "id", Value: fmt.Sprint(movie.ID)}}
+	patchMovieTrailerHandler(c)
+	if err := db.First(&movie, movie.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	applyTmdbTrailer(&movie, detail.Videos.Results)
+	db.Save(&movie)
+	curated, err := getDetail()
+	if err != nil {
+		t.Fatal(err)
+	}
+	none := Movie{}
+	applyTmdbTrailer(&none, clipsOnly)
+	if err := firstError(
+		expectEqual("picked", picked, "https://www.youtube.com/watch?v=officialJA"),
+		expectEqual("english fallback", pickTrailerURL(detail.Videos.Results[:5]), "https://www.youtube.com/watch?v=officialEN"),
+		expectEqual("unofficial trailer beats teaser", pickTrailerURL(detail.Videos.Results[:3]), "https://www.youtube.com/watch?v=fanEN"),
+		expectEqual("no trailer", none.TrailerURL, ""),
+		expectEqual("detail trailer_url", fromTMDB, picked),
+		expectStatus(testResponse{Status: rec.Code, Body: rec.Body.Bytes()}, http.StatusOK),
+		expectEqual("curated kept", curated, "https://www.youtube.com/watch?v=curated")); err != nil {
+		t.Fatal(err)
+	}
+}
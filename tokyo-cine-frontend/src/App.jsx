@@ -987,7 +987,7 @@ function DetailView({ movie, isFav, watchedInfo, onToggleFav, onConfirmWatched,
                 <div className="space-y-8">
                   {currentCinema.schedule.map((sched, schedIdx) => (
                     <div key={schedIdx} className="space-y-4">
-                      <h5 className="text-xs font-black text-zinc-400 uppercase tracking-widest">{sched.date}</h5>
+                      <h5 className="text-xs font-black text-zinc-400 uppercase tracking-widest">{sched.label || sched.date}</h5>
                       <div className="grid grid-cols-2 gap-4">
                         {sched.times && sched.times.length > 0 ? sched.times.map((time, i) => {
                           const isActive = watchedInfo?.time === time && watchedInfo?.cinema === currentCinema.name;